@@ -0,0 +1,88 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentlessPoolSpec defines the desired state of AgentlessPool
+type AgentlessPoolSpec struct {
+	// The hosts to apply the PowerProfile to, as address or address:port, IPv6 addresses with a port in brackets
+	Hosts []string `json:"hosts"`
+
+	// Secret in the intel-power namespace with the SSH username and a password or privateKey, and the knownHosts
+	CredentialsSecret string `json:"credentialsSecret"`
+
+	// Accept any host key when the Secret has no knownHosts
+	InsecureIgnoreHostKey bool `json:"insecureIgnoreHostKey,omitempty"`
+
+	// Run the changes through sudo, for users other than root
+	Sudo bool `json:"sudo,omitempty"`
+
+	// The PowerProfile to apply
+	PowerProfile string `json:"powerProfile"`
+
+	// The CPUs to apply the PowerProfile to, e.g. 2-15, all CPUs if not set
+	Cpus string `json:"cpus,omitempty"`
+}
+
+// AgentlessHostStatus is the outcome of applying the PowerProfile to one host
+type AgentlessHostStatus struct {
+	Host string `json:"host"`
+
+	// Whether the PowerProfile was applied the last time it was tried
+	Applied bool `json:"applied"`
+
+	// The error applying the PowerProfile
+	Message string `json:"message,omitempty"`
+
+	// When the PowerProfile was last applied successfully
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+}
+
+// AgentlessPoolStatus defines the observed state of AgentlessPool
+type AgentlessPoolStatus struct {
+	Hosts []AgentlessHostStatus `json:"hosts,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.spec.powerProfile`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentlessPool is the Schema for the agentlesspools API
+type AgentlessPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentlessPoolSpec   `json:"spec,omitempty"`
+	Status AgentlessPoolStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AgentlessPoolList contains a list of AgentlessPool
+type AgentlessPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentlessPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentlessPool{}, &AgentlessPoolList{})
+}
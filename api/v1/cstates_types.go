@@ -41,6 +41,7 @@ type CStatesStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // CStates is the Schema for the cstates API
 type CStates struct {
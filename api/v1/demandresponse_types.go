@@ -0,0 +1,107 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DemandResponsePending   = "Pending"
+	DemandResponseActive    = "Active"
+	DemandResponseCompleted = "Completed"
+)
+
+// DemandResponseSpec defines the desired state of DemandResponse
+type DemandResponseSpec struct {
+	// When the reduction starts
+	Start metav1.Time `json:"start"`
+
+	// When the reduction ends and the Nodes are restored
+	End metav1.Time `json:"end"`
+
+	// The labels of the Nodes to reduce, every Node if empty
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// The PowerProfiles whose capacity and frequencies are reduced, every exclusive PowerProfile if empty
+	PowerProfiles []string `json:"powerProfiles,omitempty"`
+
+	// The percentage of the PowerProfiles' advertised capacity withheld during the event, Pods already
+	// running keep their CPUs
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	CapacityReductionPercent int `json:"capacityReductionPercent,omitempty"`
+
+	// The max frequency in MHz the PowerProfiles are capped at during the event, not capped if 0
+	// +kubebuilder:validation:Minimum=0
+	MaxFrequency int `json:"maxFrequency,omitempty"`
+
+	// The reduction in watts committed to the utility, reported against the achieved reduction
+	// +kubebuilder:validation:Minimum=0
+	TargetReductionWatts int `json:"targetReductionWatts,omitempty"`
+}
+
+// DemandResponseStatus defines the observed state of DemandResponse
+type DemandResponseStatus struct {
+	// Pending, Active or Completed
+	Phase string `json:"phase,omitempty"`
+
+	// The Nodes the event applies to
+	Nodes []string `json:"nodes,omitempty"`
+
+	// The power the Nodes drew just before the event started
+	BaselineWatts int `json:"baselineWatts,omitempty"`
+
+	// The average power the Nodes drew during the event
+	AverageWatts int `json:"averageWatts,omitempty"`
+
+	// How many times the Nodes' power was sampled during the event
+	Samples int `json:"samples,omitempty"`
+
+	// The baseline less the average power during the event
+	AchievedReductionWatts int `json:"achievedReductionWatts,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Start",type=string,JSONPath=`.spec.start`
+//+kubebuilder:printcolumn:name="End",type=string,JSONPath=`.spec.end`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Target",type=integer,JSONPath=`.spec.targetReductionWatts`
+//+kubebuilder:printcolumn:name="Achieved",type=integer,JSONPath=`.status.achievedReductionWatts`
+
+// DemandResponse is the Schema for the demandresponses API
+type DemandResponse struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DemandResponseSpec   `json:"spec,omitempty"`
+	Status DemandResponseStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DemandResponseList contains a list of DemandResponse
+type DemandResponseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DemandResponse `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DemandResponse{}, &DemandResponseList{})
+}
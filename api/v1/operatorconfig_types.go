@@ -0,0 +1,81 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperatorConfigName is the name the OperatorConfig in the intel-power namespace needs, others are ignored
+const OperatorConfigName = "operator-config"
+
+// OperatorConfigSpec defines the desired state of OperatorConfig. Unset fields keep the manager's flag values
+type OperatorConfigSpec struct {
+	// Seconds between the manager's full resyncs of every object it watches, applied when the manager restarts
+	// +kubebuilder:validation:Minimum=0
+	ResyncPeriodSeconds int `json:"resyncPeriodSeconds,omitempty"`
+
+	// How many objects of each kind the manager's controllers reconcile at once, applied when the manager restarts
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// Sends every change the manager's controllers make to the API server as a dry run, so they are validated and
+	// logged without being persisted. Applied straight away
+	DryRun *bool `json:"dryRun,omitempty"`
+
+	// The address the manager's metrics endpoint binds to, applied when the manager restarts
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+}
+
+// OperatorConfigStatus defines the observed state of OperatorConfig
+type OperatorConfigStatus struct {
+	// The generation of the OperatorConfig last applied
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Whether the manager's controllers are currently only dry running their changes
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// The settings changed since the manager started that only take effect once it restarts
+	PendingRestart []string `json:"pendingRestart,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Dry Run",type=boolean,JSONPath=`.status.dryRun`
+//+kubebuilder:printcolumn:name="Pending Restart",type=string,JSONPath=`.status.pendingRestart`
+
+// OperatorConfig is the Schema for the operatorconfigs API
+type OperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OperatorConfigSpec   `json:"spec,omitempty"`
+	Status OperatorConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OperatorConfigList contains a list of OperatorConfig
+type OperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatorConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorConfig{}, &OperatorConfigList{})
+}
@@ -31,13 +31,185 @@ type PowerConfigSpec struct {
 	// The label on the Nodes you the Operator will look for to deploy the Node Agent
 	PowerNodeSelector map[string]string `json:"powerNodeSelector,omitempty"`
 
+	// The PowerNodeGroups the Node Agent is deployed to, by name. When set they replace the PowerNodeSelector:
+	// the Nodes of every group are selected, and each group adds its PowerProfiles, policy overrides and rollout
+	// to its own Nodes
+	NodeGroups []string `json:"nodeGroups,omitempty"`
+
 	// The PowerProfiles that will be created by the Operator
 	PowerProfiles []string `json:"powerProfiles,omitempty"`
 
+	// Settings for the PowerProfiles, keyed by name. Profiles not named after an EPP value
+	// (performance, balance-performance, balance-power) must have an entry
+	ProfilePolicies map[string]ProfilePolicy `json:"profilePolicies,omitempty"`
+
+	// Overrides of ProfilePolicies for the Nodes matching each group's selector, applied in order over the
+	// cluster-wide ones
+	NodeGroupPolicies []NodeGroupPolicy `json:"nodeGroupPolicies,omitempty"`
+
+	// Overrides of ProfilePolicies for single Nodes, applied over those of their node groups
+	NodePolicies []NodePolicy `json:"nodePolicies,omitempty"`
+
 	// The CustomDevices include alternative devices that represents CPU resources
 	CustomDevices []string `json:"customDevices,omitempty"`
+
+	// How the Node Agents advertise each PowerProfile's capacity. NodeStatus adds Extended Resources to the
+	// Node's status, NodeLabels labels the Node instead for clusters that don't allow third parties to change
+	// Node status, scheduling then relies on the Operator's scheduler extender
+	// +kubebuilder:validation:Enum=NodeStatus;NodeLabels
+	// +kubebuilder:default=NodeStatus
+	ResourceAdvertisement string `json:"resourceAdvertisement,omitempty"`
+
+	// PowerProfiles from the fastest to the slowest. When the Operator's webhooks are enabled, a PowerProfile's
+	// max and min frequency can't be below those of a profile after it, defaults to gold, silver and bronze
+	ProfileOrdering []string `json:"profileOrdering,omitempty"`
+
+	// Taints the selected Nodes with power.intel.com/unconfigured:NoSchedule until their Node Agent has applied
+	// every PowerProfile, so Pods that don't tolerate it never land on a Node before it is power configured
+	UnconfiguredTaint bool `json:"unconfiguredTaint,omitempty"`
+
+	// Caps how much of each PowerProfile's capacity the Nodes of a failure domain advertise together, so the
+	// workloads requesting it spread over racks or PDUs instead of piling onto one
+	FailureDomainCapacity *FailureDomainCapacity `json:"failureDomainCapacity,omitempty"`
+
+	// Withholds capacity of the fastest PowerProfiles on Nodes that are throttling, so the scheduler stops
+	// sending latency-critical Pods to them until they recover
+	ThrottleDemotion *ThrottleDemotion `json:"throttleDemotion,omitempty"`
+
+	// Labels the selected Nodes with a score per PowerProfile, from the profile's remaining capacity and the
+	// Node's thermal margin, for Pods to prefer the better Nodes through nodeAffinity without a scheduler extender
+	DesirabilityScores *DesirabilityScores `json:"desirabilityScores,omitempty"`
+
+	// What the Node Agents do while they can't reach the API server. They keep the settings last applied, and
+	// with a safeProfile give it to the exclusive pools once the outage has lasted revertAfterSeconds, until they
+	// reconnect
+	ControlPlaneOutage *ControlPlaneOutagePolicy `json:"controlPlaneOutage,omitempty"`
+
+	// Reverts every Node to its default power settings and removes everything the Power Manager added to the
+	// cluster when the PowerConfig is deleted: the Power CRs, the Node Agent DaemonSet, and the Extended
+	// Resources, labels and taints on the Nodes
+	CleanUpAll bool `json:"cleanUpAll,omitempty"`
+}
+
+// FailureDomainCapacity groups the Nodes into failure domains by the value of a label. Each domain's cap is
+// shared out between its Nodes by their CPU count
+type FailureDomainCapacity struct {
+	// The Node label whose value names the Node's failure domain, e.g. a rack or PDU label. Nodes without the
+	// label aren't capped
+	TopologyKey string `json:"topologyKey"`
+
+	// The most Extended Resources of each PowerProfile, keyed by name, the Nodes of a domain advertise together
+	MaxPerDomain map[string]int `json:"maxPerDomain"`
+}
+
+// ThrottleDemotion is when a Node that is throttling advertises less capacity. The Node Agent counts a Node as
+// throttling while its thermal or power limit throttle counters keep rising
+type ThrottleDemotion struct {
+	// The PowerProfiles whose capacity is withheld, defaults to the first of the profileOrdering
+	PowerProfiles []string `json:"powerProfiles,omitempty"`
+
+	// Percentage of the PowerProfiles' capacity withheld while the Node is demoted
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	//+kubebuilder:default=100
+	CapacityReductionPercent int `json:"capacityReductionPercent,omitempty"`
+
+	// How long the Node has to keep throttling before it is demoted
+	// +kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=60
+	SustainedSeconds int `json:"sustainedSeconds,omitempty"`
+
+	// How long the Node has to go without throttling before its capacity is restored
+	// +kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=300
+	RecoverySeconds int `json:"recoverySeconds,omitempty"`
+}
+
+// DesirabilityScores is how the Node Agents score their Nodes. A score runs from 0 to 100 and is the share of
+// the PowerProfile's capacity left on the Node, scaled down as the Node's hottest core nears maxTemperature
+type DesirabilityScores struct {
+	// Seconds between updates of the scores
+	// +kubebuilder:validation:Minimum=10
+	//+kubebuilder:default=60
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+
+	// The core temperature in degrees Celsius the thermal margin is measured up to
+	// +kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=100
+	MaxTemperature int `json:"maxTemperature,omitempty"`
+
+	// The thermal margin in degrees Celsius from which a Node's score isn't scaled down
+	// +kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=30
+	FullMarginDegrees int `json:"fullMarginDegrees,omitempty"`
+}
+
+// ControlPlaneOutagePolicy is how a Node Agent falls back when the API server is unreachable
+type ControlPlaneOutagePolicy struct {
+	// The PowerProfile the exclusive pools are given during a long outage, they keep their own if empty. It must
+	// be applied on the Node before the outage, as the Node Agent can't read it during one
+	SafeProfile string `json:"safeProfile,omitempty"`
+
+	// How long the API server has to be unreachable before the pools are given the safeProfile
+	// +kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=300
+	RevertAfterSeconds int `json:"revertAfterSeconds,omitempty"`
+}
+
+const (
+	AdvertiseNodeStatus = "NodeStatus"
+	AdvertiseNodeLabels = "NodeLabels"
+)
+
+// ProfilePolicy is what the Operator creates a PowerProfile requested in the PowerConfig with
+type ProfilePolicy struct {
+	// The EPP value, defaults to the profile name for profiles named after one
+	Epp string `json:"epp,omitempty"`
+
+	// Max frequency cores can run at
+	Max int `json:"max,omitempty"`
+
+	// Min frequency cores can run at
+	Min int `json:"min,omitempty"`
+
+	// Governor to be used
+	Governor string `json:"governor,omitempty"`
+
+	// How many of each Node's CPUs can be requested with the PowerProfile
+	Capacity *ProfileCapacity `json:"capacity,omitempty"`
 }
 
+// NodeGroupPolicy overrides ProfilePolicies for the Nodes with the given labels. Only the fields set in an
+// override replace those of the policy it applies over
+type NodeGroupPolicy struct {
+	// The name shown as the source of the PowerProfiles it overrides
+	Name string `json:"name"`
+
+	// The labels of the Nodes in the group, an empty selector matches every Node
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// The overrides, keyed by PowerProfile name
+	ProfilePolicies map[string]ProfilePolicy `json:"profilePolicies"`
+}
+
+// NodePolicy overrides ProfilePolicies for a single Node. Only the fields set in an override replace those of
+// the policy it applies over
+type NodePolicy struct {
+	NodeName string `json:"nodeName"`
+
+	// The overrides, keyed by PowerProfile name
+	ProfilePolicies map[string]ProfilePolicy `json:"profilePolicies"`
+}
+
+const (
+	// EffectiveSourceCluster is the source of a PowerProfile no node group or Node policy overrides
+	EffectiveSourceCluster = "Cluster"
+	// EffectiveSourceNodeGroup prefixes the name of the last node group that overrode a PowerProfile
+	EffectiveSourceNodeGroup = "NodeGroup/"
+	// EffectiveSourceNode is the source of a PowerProfile overridden by a Node policy
+	EffectiveSourceNode = "Node"
+)
+
 // PowerConfigStatus defines the observed state of PowerConfig
 type PowerConfigStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -45,10 +217,89 @@ type PowerConfigStatus struct {
 
 	// The Nodes that the Node Agent has been deployed to
 	Nodes []string `json:"nodes,omitempty"`
+
+	// How many Nodes the Node Agent has been deployed to
+	NodeCount int `json:"nodeCount,omitempty"`
+
+	// The Nodes whose Node Agent has reverted them to their default power settings for a clean up
+	CleanedNodes []string `json:"cleanedNodes,omitempty"`
+
+	// Conditions of the PowerConfig: Ready, Degraded, NodesConfigured, MissingProfiles and BlockedDeletion
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// SHA-256 checksum of the power state the Power CRs ask of every PowerNode, changes whenever the desired
+	// state does
+	DesiredStateChecksum string `json:"desiredStateChecksum,omitempty"`
+
+	// SHA-256 checksum of the power state the Node Agents report in their PowerNodes
+	ObservedStateChecksum string `json:"observedStateChecksum,omitempty"`
+
+	// Whether every PowerNode has the desired state, with no setting drifted or still being applied
+	Converged bool `json:"converged"`
+
+	// Why the Nodes that keep the PowerConfig from being Ready aren't configured or have failed
+	// +listType=map
+	// +listMapKey=node
+	NodeFailures []NodeFailure `json:"nodeFailures,omitempty"`
 }
 
+// NodeFailure is why a Node selected by the PowerConfig isn't configured or has failed
+type NodeFailure struct {
+	Node string `json:"node"`
+
+	// PowerNodeMissing, ProfilesNotApplied or SettingsDrifted
+	Reason string `json:"reason"`
+
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	// ConditionMissingProfiles is True while the PowerConfig lists PowerProfiles that don't exist and can't be
+	// created from a profile policy, their capacity is never advertised
+	ConditionMissingProfiles = "MissingProfiles"
+	// ReasonProfilesNotFound is the reason of a True MissingProfiles condition
+	ReasonProfilesNotFound = "ProfilesNotFound"
+	// ReasonProfilesFound is the reason of a False MissingProfiles condition
+	ReasonProfilesFound = "ProfilesFound"
+	// ConditionBlockedDeletion is True while PowerProfiles the PowerConfig no longer lists are kept because running
+	// Pods still request them
+	ConditionBlockedDeletion = "BlockedDeletion"
+	// ReasonPodsRequestProfiles is the reason of a True BlockedDeletion condition
+	ReasonPodsRequestProfiles = "PodsRequestProfiles"
+	// ReasonNoPodsRequestProfiles is the reason of a False BlockedDeletion condition
+	ReasonNoPodsRequestProfiles = "NoPodsRequestProfiles"
+	// ConditionNodesConfigured is True once the Node Agent of every selected Node has applied every PowerProfile
+	ConditionNodesConfigured = "NodesConfigured"
+	// ConditionReady is True while the Nodes are configured and nothing is degraded
+	ConditionReady = "Ready"
+	// ReasonAllNodesConfigured is the reason of a True NodesConfigured condition
+	ReasonAllNodesConfigured = "AllNodesConfigured"
+	// ReasonNodesNotConfigured is the reason of a False NodesConfigured or Ready condition
+	ReasonNodesNotConfigured = "NodesNotConfigured"
+	// ReasonNodesFailed is the reason of a PowerConfig's True Degraded condition, which shares its type with the
+	// PowerWorkloads', or of a False Ready condition when Nodes have drifted
+	ReasonNodesFailed = "NodesFailed"
+	// ReasonNoFailures is the reason of a False Degraded condition
+	ReasonNoFailures = "NoFailures"
+	// ReasonConfigured is the reason of a True Ready condition
+	ReasonConfigured = "Configured"
+	// FailurePowerNodeMissing is a Node without a PowerNode yet
+	FailurePowerNodeMissing = "PowerNodeMissing"
+	// FailureProfilesNotApplied is a Node whose Node Agent hasn't applied every PowerProfile yet
+	FailureProfilesNotApplied = "ProfilesNotApplied"
+	// FailureSettingsDrifted is a Node with settings that differ from the Power CRs and aren't being applied
+	FailureSettingsDrifted = "SettingsDrifted"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.status.nodeCount`
+// +kubebuilder:printcolumn:name="Missing Profiles",type=string,JSONPath=`.status.conditions[?(@.type=="MissingProfiles")].status`
+// +kubebuilder:printcolumn:name="Converged",type=boolean,JSONPath=`.status.converged`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PowerConfig is the Schema for the powerconfigs API
 type PowerConfig struct {
@@ -0,0 +1,70 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PowerMaintenanceSpec defines the desired state of PowerMaintenance
+type PowerMaintenanceSpec struct {
+	// The Node to put into maintenance
+	NodeName string `json:"nodeName,omitempty"`
+
+	// The labels of the Nodes to put into maintenance, used when NodeName is empty
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Mark the Nodes unschedulable for the duration of the maintenance
+	Cordon bool `json:"cordon,omitempty"`
+
+	// Why the Nodes are in maintenance, e.g. a firmware update
+	Reason string `json:"reason,omitempty"`
+}
+
+// PowerMaintenanceStatus defines the observed state of PowerMaintenance
+type PowerMaintenanceStatus struct {
+	// The Nodes that have reverted their cores and suspended enforcement
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.nodeName`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.spec.reason`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PowerMaintenance is the Schema for the powermaintenances API
+type PowerMaintenance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PowerMaintenanceSpec   `json:"spec,omitempty"`
+	Status PowerMaintenanceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PowerMaintenanceList contains a list of PowerMaintenance
+type PowerMaintenanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PowerMaintenance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PowerMaintenance{}, &PowerMaintenanceList{})
+}
@@ -45,6 +45,25 @@ type PowerNodeSpec struct {
 	// The CustomDevices include alternative devices that represents CPU resources
 	CustomDevices []string `json:"customDevices,omitempty"`
 
+	// Lowers the Shared pool's max frequency while the exclusive pools are heavily subscribed
+	SharedPoolStepDown *SharedPoolStepDown `json:"sharedPoolStepDown,omitempty"`
+
+	// Caps how many of the Shared pool's cores per package run above the base frequency at once, so the exclusive
+	// pools keep the package's turbo headroom
+	SharedPoolTurboBudget *SharedPoolTurboBudget `json:"sharedPoolTurboBudget,omitempty"`
+
+	// Raises the pools handling network interrupts to their max frequency while the Node drops packets
+	NetworkBoost *NetworkBoost `json:"networkBoost,omitempty"`
+
+	// The Intel Speed Select - Performance Profile config level to run the Node's packages in. Levels trade
+	// core count for base frequency, switching is refused while exclusive PowerWorkloads have cores
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4
+	PerformanceProfileLevel *int `json:"performanceProfileLevel,omitempty"`
+
+	// Caps how many cores change frequency at once when PowerWorkloads move cores between pools
+	FrequencyTransitionLimit *FrequencyTransitionLimit `json:"frequencyTransitionLimit,omitempty"`
+
 	// The PowerProfiles in the cluster that are currently being used by Pods
 	//ActiveProfiles map[string]bool `json:"activeProfiles,omitempty"`
 
@@ -65,6 +84,249 @@ type PowerNodeStatus struct {
 
 	// The state of the Guaranteed Pods and Shared Pool in a cluster
 	PowerNodeCPUState `json:"powerNodeCPUState,omitempty"`
+
+	// How many steps the Shared pool's max frequency is currently lowered by
+	SharedPoolStep int `json:"sharedPoolStep,omitempty"`
+
+	// The Shared pool's max frequency after the step down
+	SharedPoolMaxFrequency int `json:"sharedPoolMaxFrequency,omitempty"`
+
+	// The pools currently raised to their max frequency by the networkBoost
+	NetworkBoostedPools []string `json:"networkBoostedPools,omitempty"`
+
+	// The SST-PP config level the Node's packages are currently in
+	PerformanceProfileLevel *int `json:"performanceProfileLevel,omitempty"`
+
+	// Why the requested SST-PP config level has not been applied
+	PerformanceProfileMessage string `json:"performanceProfileMessage,omitempty"`
+
+	// Power drawn by the Node's packages in watts, read from RAPL
+	PackagePowerWatts int `json:"packagePowerWatts,omitempty"`
+
+	// Power drawn by the Node's chassis in watts, read from its BMC over Redfish, or over IPMI DCMI where RAPL
+	// is unavailable
+	ChassisPowerWatts int `json:"chassisPowerWatts,omitempty"`
+
+	// BareMetal, or Virtualized when the Node Agent runs in a VM and only applies the controls it has
+	CapabilityProfile string `json:"capabilityProfile,omitempty"`
+
+	// Power controls the Node doesn't provide, PowerProfiles are applied without them
+	UnavailableControls []string `json:"unavailableControls,omitempty"`
+
+	// The model of the Node's CPUs
+	CPUModel *CPUModel `json:"cpuModel,omitempty"`
+
+	// The CPUs scaled together by one cpufreq policy, such as an E-core cluster, as CPU lists. They run at one
+	// frequency, so their PowerProfiles can't be set independently
+	FrequencyDomains []string `json:"frequencyDomains,omitempty"`
+
+	// What perf counters measured on each PowerProfile's CPUs after it was applied, when verification is enabled
+	ProfileVerifications []ProfileVerification `json:"profileVerifications,omitempty"`
+
+	// The BIOS settings that affect power management
+	BIOSSettings *BIOSSettings `json:"biosSettings,omitempty"`
+
+	// The settings each PowerProfile from the PowerConfig has on this Node once node group and Node policies
+	// are applied
+	EffectiveProfiles []EffectiveProfile `json:"effectiveProfiles,omitempty"`
+
+	// When the Node Agent first had every PowerProfile applied, the Node isn't tainted as unconfigured after it
+	ConfiguredTime *metav1.Time `json:"configuredTime,omitempty"`
+
+	// The PowerProfiles the Node Agent hasn't applied yet, checked again whenever the PowerProfiles change
+	PendingProfiles []string `json:"pendingProfiles,omitempty"`
+
+	// The most Extended Resources the Node advertises for each PowerProfile, its share of the cap of its failure
+	// domain
+	DomainCapacity map[string]int `json:"domainCapacity,omitempty"`
+
+	// Set while the Node is demoted for throttling under the PowerConfig's throttleDemotion
+	Throttling *ThrottlingStatus `json:"throttling,omitempty"`
+
+	// Set while the Node is suspended for a PowerMaintenance, what the Node Agent restores once it ends
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"`
+
+	// The generation of each PowerProfile the Node Agent last applied, keyed by name
+	AppliedProfiles map[string]int64 `json:"appliedProfiles,omitempty"`
+
+	// The PowerNodeGroups of the PowerConfig the Node is in, only the PowerProfiles they offer are applied
+	NodeGroups []string `json:"nodeGroups,omitempty"`
+}
+
+// CPUModel identifies a CPU model by its family and model numbers from /proc/cpuinfo
+type CPUModel struct {
+	Family int `json:"family"`
+	Model  int `json:"model"`
+
+	// The model name the CPU reports, for reading only as it includes the SKU
+	Name string `json:"name,omitempty"`
+}
+
+// ThrottlingStatus is why and since when a Node has been demoted for throttling
+type ThrottlingStatus struct {
+	// Thermal or PowerLimit
+	Reason string `json:"reason"`
+
+	// When the Node was demoted
+	Since metav1.Time `json:"since"`
+}
+
+// MaintenanceStatus is the power management a PowerMaintenance suspended on the Node, kept in the PowerNode so a
+// restarted Node Agent still restores it
+type MaintenanceStatus struct {
+	// The Shared pool's PowerProfile from before the maintenance, none if the pool had none
+	SharedProfile *PoolProfile `json:"sharedProfile,omitempty"`
+}
+
+// PoolProfile is a PowerProfile as the Power Library applied it to a pool, frequencies in MHz
+type PoolProfile struct {
+	Name     string `json:"name"`
+	Min      int    `json:"min"`
+	Max      int    `json:"max"`
+	Governor string `json:"governor,omitempty"`
+	Epp      string `json:"epp,omitempty"`
+}
+
+const (
+	ThrottlingThermal    = "Thermal"
+	ThrottlingPowerLimit = "PowerLimit"
+)
+
+// EffectiveProfile is a PowerProfile's settings on a Node and the policy level they come from
+type EffectiveProfile struct {
+	Name string `json:"name"`
+
+	// Cluster, NodeGroup/<name> or Node
+	Source string `json:"source"`
+
+	ProfilePolicy `json:",inline"`
+}
+
+// BIOSSettings are the BIOS settings that affect power management as the Node Agent found them, a setting it
+// couldn't read is left empty
+type BIOSSettings struct {
+	// The BIOS vendor and version
+	Version string `json:"version,omitempty"`
+
+	// Whether Hardware P-States are Enabled or Disabled
+	HWP string `json:"hwp,omitempty"`
+
+	// Whether turbo is Enabled or Disabled
+	Turbo string `json:"turbo,omitempty"`
+
+	// The platform's power profile as name=value of its BIOS attribute, read over Redfish
+	PowerProfile string `json:"powerProfile,omitempty"`
+
+	// Settings that keep PowerProfiles from taking full effect
+	Advisories []string `json:"advisories,omitempty"`
+}
+
+const (
+	CapabilityProfileBareMetal   = "BareMetal"
+	CapabilityProfileVirtualized = "Virtualized"
+)
+
+// ProfileVerification compares the frequency a PowerProfile's CPUs ran at, sampled with perf counters, with the
+// range the profile sets. BIOS settings such as a locked frequency silently override the profile
+type ProfileVerification struct {
+	// The PowerProfile that was verified
+	Profile string `json:"profile"`
+
+	// The CPUs that were sampled
+	CPUs []uint `json:"cpus,omitempty"`
+
+	// The frequency range the PowerProfile sets in MHz
+	ExpectedMinFrequency int `json:"expectedMinFrequency,omitempty"`
+	ExpectedMaxFrequency int `json:"expectedMaxFrequency,omitempty"`
+
+	// The average frequency in MHz of the sampled CPUs while they were busy
+	MeasuredFrequency int `json:"measuredFrequency,omitempty"`
+
+	// The average instructions per cycle of the sampled CPUs while they were busy
+	MeasuredIPC string `json:"measuredIPC,omitempty"`
+
+	// Verified, Overridden when the measured frequency is outside the expected range, or Inconclusive when
+	// none of the sampled CPUs was busy enough to tell
+	Result string `json:"result"`
+
+	// When the CPUs were sampled
+	LastVerified metav1.Time `json:"lastVerified,omitempty"`
+}
+
+const (
+	VerificationVerified     = "Verified"
+	VerificationOverridden   = "Overridden"
+	VerificationInconclusive = "Inconclusive"
+)
+
+// SharedPoolStepDown trades Shared pool frequency for headroom in the exclusive pools. While the exclusive pools
+// hold more than SubscriptionThreshold percent of the Node's pooled CPUs and package power is over the budget, the
+// Shared pool's max frequency is lowered one step at a time; it is raised again as exclusive demand drops
+type SharedPoolStepDown struct {
+	// Percentage of the Node's Shared and exclusive CPUs in exclusive pools above which the Shared pool is stepped down
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	//+kubebuilder:default=50
+	SubscriptionThreshold int `json:"subscriptionThreshold,omitempty"`
+
+	// Package power in watts to stay under, without a budget the Shared pool is stepped down whenever the exclusive
+	// pools are over the threshold
+	PowerBudgetWatts int `json:"powerBudgetWatts,omitempty"`
+
+	// How much the Shared pool's max frequency is lowered per step in MHz
+	//+kubebuilder:default=100
+	StepMHz int `json:"stepMHz,omitempty"`
+
+	// The most steps the Shared pool is lowered by, it never goes below its PowerProfile's min frequency
+	//+kubebuilder:default=10
+	MaxSteps int `json:"maxSteps,omitempty"`
+}
+
+// SharedPoolTurboBudget approximates the per-package turbo budget the Shared pool spends. Only MaxTurboCores of its
+// physical cores per package keep the Shared PowerProfile's max frequency, the others are capped at the base
+// frequency, and which cores those are rotates every RotationSeconds so no Shared workload is held back for long
+type SharedPoolTurboBudget struct {
+	// How many of the Shared pool's physical cores per package may run above the base frequency at once
+	// +kubebuilder:validation:Minimum=0
+	MaxTurboCores int `json:"maxTurboCores"`
+
+	// How often the cores allowed to turbo move on to the next ones
+	// +kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=10
+	RotationSeconds int `json:"rotationSeconds,omitempty"`
+}
+
+// NetworkBoost keeps the CPUs handling the interrupts of network interfaces at full speed while packets are
+// dropped. When the interfaces, or the backlog of the CPUs their interrupts go to, drop more than DropsPerSecond,
+// the pools holding those CPUs run at their PowerProfile's max frequency until the drops have stayed under the
+// threshold for HoldSeconds
+type NetworkBoost struct {
+	// The network interfaces to watch, such as the NICs of an NFV data plane
+	// +kubebuilder:validation:MinItems=1
+	Interfaces []string `json:"interfaces"`
+
+	// Packets dropped per second across the interfaces above which their pools are boosted
+	// +kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=100
+	DropsPerSecond int `json:"dropsPerSecond,omitempty"`
+
+	// How long in seconds the pools stay boosted once the drops are back under the threshold
+	// +kubebuilder:validation:Minimum=0
+	//+kubebuilder:default=60
+	HoldSeconds int `json:"holdSeconds,omitempty"`
+}
+
+// FrequencyTransitionLimit ramps the cores a PowerWorkload moves between pools in batches, as many cores changing
+// frequency at once draws current spikes that can trip the PSU limits of dense Nodes
+type FrequencyTransitionLimit struct {
+	// The most cores moved, and so changing frequency, at once
+	// +kubebuilder:validation:Minimum=1
+	MaxCores int `json:"maxCores"`
+
+	// Milliseconds between batches
+	// +kubebuilder:validation:Minimum=0
+	//+kubebuilder:default=100
+	IntervalMilliseconds int `json:"intervalMilliseconds,omitempty"`
 }
 
 type PowerNodeCPUState struct {
@@ -107,6 +369,9 @@ type Container struct {
 
 	// The PowerWorkload that the Container is utilizing
 	Workload string `json:"workload,omitempty"`
+
+	// Whether the Container busy-polls its exclusive CPUs, such as a DPDK data plane
+	Fastpath bool `json:"fastpath,omitempty"`
 }
 
 type WorkloadInfo struct {
@@ -127,6 +392,11 @@ type SharedPoolInfo struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Package Watts",type=integer,JSONPath=`.status.packagePowerWatts`
+// +kubebuilder:printcolumn:name="Chassis Watts",type=integer,JSONPath=`.status.chassisPowerWatts`
+// +kubebuilder:printcolumn:name="Capability",type=string,JSONPath=`.status.capabilityProfile`
+// +kubebuilder:printcolumn:name="Shared Max",type=integer,JSONPath=`.status.sharedPoolMaxFrequency`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PowerNode is the Schema for the powernodes API
 type PowerNode struct {
@@ -0,0 +1,109 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PowerNodeGroupSpec defines the desired state of PowerNodeGroup
+type PowerNodeGroupSpec struct {
+	// The labels of the Nodes in the group, an empty selector matches every Node
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// The PowerProfiles offered on the group's Nodes on top of those the PowerConfig lists. A Node in several
+	// groups offers the PowerProfiles of all of them
+	PowerProfiles []string `json:"powerProfiles,omitempty"`
+
+	// Overrides of the PowerConfig's ProfilePolicies for the group's Nodes, keyed by PowerProfile name. A
+	// PowerProfile offered by the group that the PowerConfig has no policy for takes its policy from here
+	ProfilePolicies map[string]ProfilePolicy `json:"profilePolicies,omitempty"`
+
+	// Power in watts the group's Nodes are meant to draw together, the group is OverBudget while they draw more
+	// +kubebuilder:validation:Minimum=0
+	PowerBudgetWatts int `json:"powerBudgetWatts,omitempty"`
+
+	// How changes to the PowerProfiles the group offers are rolled out, replacing the apply deadline of the
+	// PowerProfiles it creates. Without a rollout they keep their own
+	Rollout *NodeGroupRollout `json:"rollout,omitempty"`
+}
+
+// NodeGroupRollout is the apply deadline given to the PowerProfiles created for the group
+type NodeGroupRollout struct {
+	// Seconds every Node is given to apply a change to the PowerProfile before it is marked Failed
+	// +kubebuilder:validation:Minimum=1
+	ApplyDeadlineSeconds int `json:"applyDeadlineSeconds,omitempty"`
+
+	// Restores the spec last applied on every Node when the applyDeadlineSeconds passes
+	RollbackOnDeadline bool `json:"rollbackOnDeadline,omitempty"`
+}
+
+// PowerNodeGroupStatus defines the observed state of PowerNodeGroup
+type PowerNodeGroupStatus struct {
+	// The Nodes in the group that run the Node Agent
+	Nodes []string `json:"nodes,omitempty"`
+
+	// How many Nodes are in the group
+	NodeCount int `json:"nodeCount,omitempty"`
+
+	// The power drawn by the group's Nodes together, chassis power where the Node reports it and package power
+	// where it doesn't
+	PowerWatts int `json:"powerWatts,omitempty"`
+
+	// Conditions of the PowerNodeGroup, such as OverBudget
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionOverBudget is True while the group's Nodes draw more than its powerBudgetWatts
+	ConditionOverBudget = "OverBudget"
+	// ReasonBudgetExceeded is the reason of a True OverBudget condition
+	ReasonBudgetExceeded = "BudgetExceeded"
+	// ReasonWithinBudget is the reason of a False OverBudget condition
+	ReasonWithinBudget = "WithinBudget"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.status.nodeCount`
+//+kubebuilder:printcolumn:name="Watts",type=integer,JSONPath=`.status.powerWatts`
+//+kubebuilder:printcolumn:name="Budget",type=integer,JSONPath=`.spec.powerBudgetWatts`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PowerNodeGroup is the Schema for the powernodegroups API
+type PowerNodeGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PowerNodeGroupSpec   `json:"spec,omitempty"`
+	Status PowerNodeGroupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PowerNodeGroupList contains a list of PowerNodeGroup
+type PowerNodeGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PowerNodeGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PowerNodeGroup{}, &PowerNodeGroupList{})
+}
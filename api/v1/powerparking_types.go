@@ -0,0 +1,100 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PowerParkingSpec defines the desired state of PowerParking
+type PowerParkingSpec struct {
+	// The labels of the Nodes that may be parked, at least one as an empty selector would select every Node
+	// +kubebuilder:validation:MinProperties=1
+	NodeSelector map[string]string `json:"nodeSelector"`
+
+	// How long a Node has to run nothing but DaemonSet Pods before it is parked
+	// +kubebuilder:validation:Minimum=60
+	//+kubebuilder:default=1800
+	IdleSeconds int `json:"idleSeconds,omitempty"`
+
+	// How many of the selected Nodes are always kept awake
+	// +kubebuilder:validation:Minimum=0
+	//+kubebuilder:default=1
+	MinAwake int `json:"minAwake,omitempty"`
+
+	// The state parked Nodes are put into, off (S5) or suspend (S3)
+	// +kubebuilder:validation:Enum=off;suspend
+	//+kubebuilder:default=off
+	PowerState string `json:"powerState,omitempty"`
+
+	// Park and wake Nodes through the Redfish service of their BMCs
+	Redfish *RedfishPowerControl `json:"redfish,omitempty"`
+
+	// Park and wake Nodes by calling an external service
+	Webhook *WebhookPowerControl `json:"webhook,omitempty"`
+}
+
+// RedfishPowerControl reaches each Node's BMC at the address in its power.intel.com/bmc-address annotation
+type RedfishPowerControl struct {
+	// Secret in the intel-power namespace holding the username and password of the BMCs
+	CredentialsSecret string `json:"credentialsSecret"`
+
+	// Skip verifying the BMCs' TLS certificates
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// WebhookPowerControl posts the Node, the action (park or wake) and the power state to the URL
+type WebhookPowerControl struct {
+	URL string `json:"url"`
+}
+
+// PowerParkingStatus defines the observed state of PowerParking
+type PowerParkingStatus struct {
+	// The Nodes that are currently parked
+	ParkedNodes []string `json:"parkedNodes,omitempty"`
+
+	// The last error parking or waking a Node
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Power State",type=string,JSONPath=`.spec.powerState`
+//+kubebuilder:printcolumn:name="Parked",type=string,JSONPath=`.status.parkedNodes`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PowerParking is the Schema for the powerparkings API
+type PowerParking struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PowerParkingSpec   `json:"spec,omitempty"`
+	Status PowerParkingStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PowerParkingList contains a list of PowerParking
+type PowerParkingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PowerParking `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PowerParking{}, &PowerParkingList{})
+}
@@ -37,6 +37,7 @@ type PowerPodStatus struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PowerPod is the Schema for the powerpods API
 type PowerPod struct {
@@ -30,6 +30,11 @@ type PowerProfileSpec struct {
 	// Max frequency cores can run at
 	Max int `json:"max,omitempty"`
 
+	// Symbolic max frequency resolved by each Node from its turbo ratio table, takes precedence over Max.
+	// auto takes the max and min frequency from the defaults for the Node's CPU model and the EPP value
+	// +kubebuilder:validation:Enum=allCoreTurbo;singleCoreTurbo;auto
+	MaxPreset string `json:"maxPreset,omitempty"`
+
 	// Min frequency cores can run at
 	Min int `json:"min,omitempty"`
 
@@ -39,16 +44,140 @@ type PowerProfileSpec struct {
 	// Governor to be used
 	//+kubebuilder:default=powersave
 	Governor string `json:"governor,omitempty"`
+
+	// How many of each Node's CPUs can be requested with this PowerProfile, if not set the share is based on the EPP value
+	Capacity *ProfileCapacity `json:"capacity,omitempty"`
+
+	// Tunes the profile's cores for realtime workloads: a fixed frequency no higher than the base frequency, the
+	// performance governor and no C-states deeper than C1. Only applied on Nodes running a PREEMPT_RT kernel
+	Realtime bool `json:"realtime,omitempty"`
+
+	// Tunes the profile's cores for DPDK and other busy-polling data planes: a fixed frequency at the max, the
+	// performance governor and no C-state but POLL. The cores are left out of utilization based tuning, which
+	// would take the polling for demand
+	Fastpath bool `json:"fastpath,omitempty"`
+
+	// Temperature in degrees Celsius the profile's cores are kept under by lowering their max frequency, for
+	// enclosures that can't shed the heat of the full frequency
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=125
+	TemperatureTarget int `json:"temperatureTarget,omitempty"`
+
+	// Seconds every Node with a Node Agent is given to apply a change to the profile before it is marked Failed
+	// +kubebuilder:validation:Minimum=1
+	ApplyDeadlineSeconds int `json:"applyDeadlineSeconds,omitempty"`
+
+	// Restores the spec last applied on every Node when the applyDeadlineSeconds passes
+	RollbackOnDeadline bool `json:"rollbackOnDeadline,omitempty"`
+
+	// Whether the profile sets the cores' min frequency, when false the floor is left at the hardware minimum
+	// +kubebuilder:default=true
+	ManageMin *bool `json:"manageMin,omitempty"`
+
+	// Whether the profile sets the cores' max frequency, when false the ceiling is left at the hardware maximum
+	// for the governor to move under
+	// +kubebuilder:default=true
+	ManageMax *bool `json:"manageMax,omitempty"`
+}
+
+// ProfileCapacity is the number of a PowerProfile's Extended Resources advertised on each Node
+type ProfileCapacity struct {
+	// Percentage of the Node's CPUs
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percent int `json:"percent,omitempty"`
+
+	// Absolute number of CPUs, takes precedence over Percent
+	// +kubebuilder:validation:Minimum=0
+	Count int `json:"count,omitempty"`
+
+	// CEL expression giving the number of CPUs on each Node from its facts, takes precedence over Count and
+	// Percent. It can read the Node's cores and sockets, its cpuModel name, which includes the SKU, and its
+	// labels, e.g. `sockets * 8`. The result is capped at the Node's CPUs
+	Expression string `json:"expression,omitempty"`
+
+	// Percentage of the capacity held back as burst headroom, it is only advertised while lent out with
+	// the power.intel.com/lend-headroom-until annotation
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	HeadroomPercent int `json:"headroomPercent,omitempty"`
 }
 
 // PowerProfileStatus defines the observed state of PowerProfile
 type PowerProfileStatus struct {
 	// The ID given to the power profile
 	ID int `json:"id"`
+
+	// The generation the applyDeadlineSeconds is counting down for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// When the Node Agents started applying the observed generation
+	ApplyStarted *metav1.Time `json:"applyStarted,omitempty"`
+
+	// The Nodes that haven't applied the observed generation yet
+	PendingNodes []string `json:"pendingNodes,omitempty"`
+
+	// The spec last applied on every Node, restored by rollbackOnDeadline
+	LastAppliedSpec *PowerProfileSpec `json:"lastAppliedSpec,omitempty"`
+
+	// Conditions of the PowerProfile, Applied and Failed, kept while it has an applyDeadlineSeconds, and
+	// PresetResolved for profiles with a maxPreset
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// The CPU models of the Nodes the PowerProfile has been applied on successfully, kept after those Nodes leave
+	// +listType=map
+	// +listMapKey=family
+	// +listMapKey=model
+	ValidatedCPUModels []ValidatedCPUModel `json:"validatedCPUModels,omitempty"`
 }
 
+// ValidatedCPUModel is a CPU model a PowerProfile has been applied on
+type ValidatedCPUModel struct {
+	CPUModel `json:",inline"`
+
+	// The latest generation of the PowerProfile applied on a Node with the CPU model
+	Generation int64 `json:"generation"`
+
+	// When that generation was first seen applied on the CPU model
+	LastValidated metav1.Time `json:"lastValidated"`
+}
+
+const (
+	// ConditionApplied is True once every Node has applied the PowerProfile's generation
+	ConditionApplied = "Applied"
+	// ConditionFailed is True when the Nodes didn't apply the PowerProfile's generation within its deadline
+	ConditionFailed = "Failed"
+	// ReasonApplying is the reason of a False Applied condition
+	ReasonApplying = "Applying"
+	// ReasonAppliedOnAllNodes is the reason of a True Applied condition
+	ReasonAppliedOnAllNodes = "AppliedOnAllNodes"
+	// ReasonApplyDeadlineExceeded is the reason of a True Failed condition without a rollback
+	ReasonApplyDeadlineExceeded = "ApplyDeadlineExceeded"
+	// ReasonRolledBack is the reason of a True Failed condition once the last applied spec was restored
+	ReasonRolledBack = "RolledBack"
+	// ReasonWithinDeadline is the reason of a False Failed condition
+	ReasonWithinDeadline = "WithinDeadline"
+	// ConditionPresetResolved is False when a Node Agent couldn't resolve the PowerProfile's maxPreset
+	ConditionPresetResolved = "PresetResolved"
+	// ReasonPresetResolved is the reason of a True PresetResolved condition
+	ReasonPresetResolved = "PresetResolved"
+	// ReasonTurboPresetsUnsupported is the reason of a False PresetResolved condition when the Node's turbo ratio
+	// table can't be read
+	ReasonTurboPresetsUnsupported = "TurboPresetsUnsupported"
+	// ReasonPresetUnresolvable is the reason of a False PresetResolved condition when the preset is unknown or the
+	// turbo ratio table has no frequency for it
+	ReasonPresetUnresolvable = "PresetUnresolvable"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Max",type=integer,JSONPath=`.spec.max`
+// +kubebuilder:printcolumn:name="Min",type=integer,JSONPath=`.spec.min`
+// +kubebuilder:printcolumn:name="EPP",type=string,JSONPath=`.spec.epp`
+// +kubebuilder:printcolumn:name="Governor",type=string,JSONPath=`.spec.governor`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PowerProfile is the Schema for the powerprofiles API
 type PowerProfile struct {
@@ -0,0 +1,85 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PowerRecommendationSpec defines the desired state of PowerRecommendation
+type PowerRecommendationSpec struct {
+	// The Node the utilization was recorded on
+	NodeName string `json:"nodeName"`
+
+	// The PowerProfile the recommendation is for
+	PowerProfile string `json:"powerProfile"`
+}
+
+// PowerRecommendationStatus defines the observed state of PowerRecommendation
+type PowerRecommendationStatus struct {
+	// The max frequency currently set by the PowerProfile
+	CurrentMax int `json:"currentMax,omitempty"`
+
+	// The lowest max frequency that still covers the recorded demand
+	RecommendedMax int `json:"recommendedMax,omitempty"`
+
+	// The percentile of demand the recommendation covers, e.g. 0.99
+	Percentile string `json:"percentile,omitempty"`
+
+	// The frequency demand at the percentile
+	PercentileDemand int `json:"percentileDemand,omitempty"`
+
+	// How many core samples the recommendation is based on
+	Samples int64 `json:"samples,omitempty"`
+
+	// How far back the recorded utilization goes
+	Window string `json:"window,omitempty"`
+
+	// Human readable summary of the recommendation
+	Message string `json:"message,omitempty"`
+
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.nodeName`
+//+kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.spec.powerProfile`
+//+kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.currentMax`
+//+kubebuilder:printcolumn:name="Recommended",type=integer,JSONPath=`.status.recommendedMax`
+
+// PowerRecommendation is the Schema for the powerrecommendations API
+type PowerRecommendation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PowerRecommendationSpec   `json:"spec,omitempty"`
+	Status PowerRecommendationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PowerRecommendationList contains a list of PowerRecommendation
+type PowerRecommendationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PowerRecommendation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PowerRecommendation{}, &PowerRecommendationList{})
+}
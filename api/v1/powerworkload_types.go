@@ -56,19 +56,285 @@ type PowerWorkloadSpec struct {
 
 	// PowerProfile is the Profile that this PowerWorkload is based on
 	PowerProfile string `json:"powerProfile,omitempty"`
+
+	// Validation is checked after the PowerProfile is applied, if it fails the previous PowerProfile is restored
+	Validation *WorkloadValidation `json:"validation,omitempty"`
+
+	// PodSelector gives the exclusive CPUs of the selected Pods' containers the PowerProfile on whichever Node they
+	// run. Such a PowerWorkload is not assigned to a Node itself, the CPUs join the PowerProfile's PowerWorkload there
+	PodSelector *WorkloadPodSelector `json:"podSelector,omitempty"`
+
+	// Hooks are run by the Node Agent around each change to the PowerWorkload's cores or PowerProfile
+	Hooks *WorkloadHooks `json:"hooks,omitempty"`
+
+	// Verification runs a short latency probe on a CPU of the PowerWorkload's pool each time a PowerProfile is
+	// applied to it
+	Verification *WorkloadVerification `json:"verification,omitempty"`
+}
+
+// WorkloadVerification is a latency probe run as a Job whose container gets an exclusive CPU in the PowerWorkload's
+// pool, proving the isolation and frequencies of the pool before real traffic lands on it
+type WorkloadVerification struct {
+	// The probe, cyclictest or the cyclic stressor of stress-ng
+	//+kubebuilder:validation:Enum=cyclictest;stress-ng
+	//+kubebuilder:default=cyclictest
+	Tool string `json:"tool,omitempty"`
+
+	// Image with the tool, a shell and awk
+	Image string `json:"image"`
+
+	// Seconds the probe runs for
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=10
+	DurationSeconds int `json:"durationSeconds,omitempty"`
+
+	// The verification fails when the 99th percentile latency is above it, the latencies are only recorded if not set
+	//+kubebuilder:validation:Minimum=0
+	MaxP99Microseconds int `json:"maxP99Microseconds,omitempty"`
+}
+
+const (
+	VerificationToolCyclictest = "cyclictest"
+	VerificationToolStressNg   = "stress-ng"
+)
+
+// WorkloadHooks are site specific steps run around a change to a PowerWorkload, such as flushing a workload's
+// caches once its frequencies have changed
+type WorkloadHooks struct {
+	// Run before the change, a failing hook holds the change back until it passes unless its failurePolicy is Ignore
+	PreApply *WorkloadHook `json:"preApply,omitempty"`
+
+	// Run once the change is made, a failing hook is only logged
+	PostApply *WorkloadHook `json:"postApply,omitempty"`
+}
+
+// WorkloadHook is either a command run in one of the workload's containers or a callout to a webhook
+type WorkloadHook struct {
+	// Command executed in a container, the hook passes if it exits with 0
+	Exec *ExecProbe `json:"exec,omitempty"`
+
+	// Address the change is POSTed to as JSON, the hook passes if it answers with a 2xx status. It has to start with
+	// one of the prefixes the Node Agent's --hook-url-prefixes allows
+	URL string `json:"url,omitempty"`
+
+	// Seconds the hook is given to complete
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=10
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+
+	// Whether a failing preApply hook holds the change back, Fail, or is only logged, Ignore
+	//+kubebuilder:validation:Enum=Fail;Ignore
+	//+kubebuilder:default=Fail
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+const (
+	HookFailurePolicyFail   = "Fail"
+	HookFailurePolicyIgnore = "Ignore"
+)
+
+// WorkloadPodSelector selects Pods, and optionally only some of their containers, for a PowerWorkload
+type WorkloadPodSelector struct {
+	// The namespace of the Pods, all namespaces if not set
+	Namespace string `json:"namespace,omitempty"`
+
+	// The labels the Pods need
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// The containers whose exclusive CPUs are given the PowerProfile, all containers with exclusive CPUs if not
+	// set. Other containers, such as sidecars, keep their CPUs in the Shared pool
+	Containers []string `json:"containers,omitempty"`
+}
+
+// WorkloadValidation is a probe that has to keep passing for a window after a PowerProfile is applied
+type WorkloadValidation struct {
+	// PromQL query, the validation passes while the query returns a non-zero value
+	PrometheusQuery string `json:"prometheusQuery,omitempty"`
+
+	// Address of the Prometheus server the query is sent to, it has to be the one the Node Agent is configured
+	// with in its --prometheus-url, which is used when it isn't set
+	PrometheusURL string `json:"prometheusURL,omitempty"`
+
+	// Command executed in a container of a Pod on the PowerWorkload's Node, in one of the namespaces the Node
+	// Agent's --exec-namespaces allows. The validation passes while it exits with 0
+	Exec *ExecProbe `json:"exec,omitempty"`
+
+	// Seconds after the PowerProfile is applied during which the validation has to pass
+	//+kubebuilder:default=60
+	WindowSeconds int `json:"windowSeconds,omitempty"`
+
+	// Seconds between validation attempts
+	//+kubebuilder:default=10
+	PeriodSeconds int `json:"periodSeconds,omitempty"`
+}
+
+type ExecProbe struct {
+	Namespace string   `json:"namespace"`
+	Pod       string   `json:"pod"`
+	Container string   `json:"container,omitempty"`
+	Command   []string `json:"command"`
+}
+
+const (
+	WorkloadPhaseValidating = "Validating"
+	WorkloadPhaseSucceeded  = "Succeeded"
+	WorkloadPhaseFailed     = "Failed"
+)
+
+const (
+	// ConditionDegraded is True while the frequency of the pool's busy CPUs jitters although its PowerProfile pins it
+	ConditionDegraded = "Degraded"
+	// ReasonFrequencyJitter is the reason of a True Degraded condition
+	ReasonFrequencyJitter = "FrequencyJitter"
+	// ReasonFrequencySteady is the reason of a False Degraded condition
+	ReasonFrequencySteady = "FrequencySteady"
+)
+
+// WorkloadTransition is a change to the frequencies of the PowerWorkload's pool that hasn't settled yet
+type WorkloadTransition struct {
+	// The PowerProfile the pool is changing to
+	Profile string `json:"profile,omitempty"`
+
+	// When the change started
+	Started *metav1.Time `json:"started,omitempty"`
+
+	// The Pods protected from eviction, as namespace/name
+	Pods []string `json:"pods,omitempty"`
+
+	// The PodDisruptionBudgets created for the Pods, as namespace/name
+	PodDisruptionBudgets []string `json:"podDisruptionBudgets,omitempty"`
 }
 
 // PowerWorkloadStatus defines the observed state of PowerWorkload
+// CoreApplyResult is how applying the PowerWorkload went on one of its CPUs
+type CoreApplyResult struct {
+	CPU uint `json:"cpu"`
+
+	// Ok, Clamped when the CPU runs with other frequency limits than its PowerProfile's, or Failed when it isn't
+	// in the PowerProfile's pool
+	// +kubebuilder:validation:Enum=Ok;Clamped;Failed
+	Result string `json:"result"`
+
+	// Why the CPU was clamped or failed
+	Reason string `json:"reason,omitempty"`
+}
+
+const (
+	CoreApplyOk      = "Ok"
+	CoreApplyClamped = "Clamped"
+	CoreApplyFailed  = "Failed"
+)
+
 type PowerWorkloadStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
 	// The Node that this Shared PowerWorkload is associated with
 	Node string `json:"node:,omitempty"`
+
+	// Validating, Succeeded or Failed while the PowerWorkload has a Validation
+	Phase string `json:"phase,omitempty"`
+
+	// The PowerProfile last applied to the cores of the PowerWorkload
+	AppliedProfile string `json:"appliedProfile,omitempty"`
+
+	// The PowerProfile applied before AppliedProfile, used for rollback
+	PreviousProfile string `json:"previousProfile,omitempty"`
+
+	// When validation of AppliedProfile started
+	ValidationStarted *metav1.Time `json:"validationStarted,omitempty"`
+
+	// The change of PowerProfile in progress, its Pods are protected from eviction until it settles
+	Transition *WorkloadTransition `json:"transition,omitempty"`
+
+	// Containers whose cgroup cpuset isn't the CPUs the PowerWorkload manages for them, such as when the CPU
+	// Manager gave them other cores, so the PowerProfile isn't on the cores they run on
+	PinningMismatches []PinningMismatch `json:"pinningMismatches,omitempty"`
+
+	// Frequency domains the PowerWorkload's CPUs share with CPUs of other PowerProfiles, the domain runs them all
+	// at one frequency so not every PowerProfile takes effect
+	FrequencyDomainConflicts []FrequencyDomainConflict `json:"frequencyDomainConflicts,omitempty"`
+
+	// How many of the PowerWorkload's CPUs are in its pool out of the CPUs it manages, such as 4/4
+	AppliedCPUs string `json:"appliedCPUs,omitempty"`
+
+	// Conditions of the PowerWorkload, such as Degraded
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Why the last apply plan failed, cleared once a plan applies in full
+	LastError string `json:"lastError,omitempty"`
+
+	// The outcome of the last apply on each of the PowerWorkload's CPUs
+	// +listType=map
+	// +listMapKey=cpu
+	CoreResults []CoreApplyResult `json:"coreResults,omitempty"`
+
+	// The latency probe of the PowerProfile last applied, while the PowerWorkload has a Verification
+	Verification *VerificationResult `json:"verification,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+// VerificationResult holds the latencies a verification probe measured on the PowerWorkload's pool
+type VerificationResult struct {
+	// Running, Passed or Failed
+	Phase string `json:"phase"`
+
+	// The PowerProfile the probe ran with
+	Profile string `json:"profile"`
+
+	// The probe Job, removed once its result is recorded
+	Job string `json:"job,omitempty"`
+
+	P50Microseconds int `json:"p50Microseconds,omitempty"`
+	P99Microseconds int `json:"p99Microseconds,omitempty"`
+	MaxMicroseconds int `json:"maxMicroseconds,omitempty"`
+
+	Started   *metav1.Time `json:"started,omitempty"`
+	Completed *metav1.Time `json:"completed,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	VerificationPhaseRunning = "Running"
+	VerificationPhasePassed  = "Passed"
+	VerificationPhaseFailed  = "Failed"
+)
+
+// PinningMismatch is a container whose cgroup cpuset differs from the exclusive CPUs given the PowerProfile
+type PinningMismatch struct {
+	Pod string `json:"pod"`
+
+	Container string `json:"container"`
+
+	// The CPUs the PowerWorkload manages for the container
+	Expected string `json:"expected"`
+
+	// The CPUs the container's cgroup cpuset lets it run on
+	Actual string `json:"actual"`
+}
+
+// FrequencyDomainConflict is a frequency domain whose CPUs are in pools of different PowerProfiles
+type FrequencyDomainConflict struct {
+	// The CPUs of the domain
+	CPUs string `json:"cpus"`
+
+	// The PowerProfiles of the domain's CPUs, the Shared pool's as its PowerProfile and CPUs in no pool as reserved
+	Profiles []string `json:"profiles"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.workloadNodes.name`
+// +kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.spec.powerProfile`
+// +kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.appliedProfile`
+// +kubebuilder:printcolumn:name="CPUs",type=string,JSONPath=`.status.appliedCPUs`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Last Error",type=string,JSONPath=`.status.lastError`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PowerWorkload is the Schema for the powerworkloads API
 type PowerWorkload struct {
@@ -0,0 +1,90 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PowerWorkloadSetSpec defines the desired state of PowerWorkloadSet
+type PowerWorkloadSetSpec struct {
+	// The PowerProfile every PowerWorkload of the set uses
+	PowerProfile string `json:"powerProfile"`
+
+	// The PowerWorkloads to create, one per Node
+	// +listType=map
+	// +listMapKey=node
+	Workloads []PowerWorkloadSetEntry `json:"workloads,omitempty"`
+
+	// How many PowerWorkloads are created, updated or deleted a second, the rest wait for the next second
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=20
+	WritesPerSecond int `json:"writesPerSecond,omitempty"`
+}
+
+// PowerWorkloadSetEntry is one PowerWorkload of a set
+type PowerWorkloadSetEntry struct {
+	// The Node the PowerWorkload's CPUs are on
+	Node string `json:"node"`
+
+	// The CPUs given the PowerProfile
+	CpuIds []uint `json:"cpuIds,omitempty"`
+}
+
+// PowerWorkloadSetStatus defines the observed state of PowerWorkloadSet
+type PowerWorkloadSetStatus struct {
+	// The generation of the PowerWorkloadSet last reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// How many PowerWorkloads the set has
+	Workloads int `json:"workloads,omitempty"`
+
+	// How many of them are in line with the set, the others are waiting for their write
+	ReadyWorkloads int `json:"readyWorkloads,omitempty"`
+
+	// PowerWorkloads of the set's names that exist but weren't created by it, they are left alone
+	Conflicting []string `json:"conflicting,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.spec.powerProfile`
+//+kubebuilder:printcolumn:name="Workloads",type=integer,JSONPath=`.status.workloads`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyWorkloads`
+
+// PowerWorkloadSet is the Schema for the powerworkloadsets API, it expands into a PowerWorkload per Node so batch
+// systems can give many Nodes' CPUs a PowerProfile with a single write
+type PowerWorkloadSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PowerWorkloadSetSpec   `json:"spec,omitempty"`
+	Status PowerWorkloadSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PowerWorkloadSetList contains a list of PowerWorkloadSet
+type PowerWorkloadSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PowerWorkloadSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PowerWorkloadSet{}, &PowerWorkloadSetList{})
+}
@@ -0,0 +1,58 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PowerWorkloadTemplateSpec defines the desired state of PowerWorkloadTemplate
+type PowerWorkloadTemplateSpec struct {
+	// The PowerProfile given to the exclusive CPUs of Pods using the template
+	PowerProfile string `json:"powerProfile"`
+
+	// The containers the PowerProfile is given to, all containers with exclusive CPUs if not set
+	Containers []string `json:"containers,omitempty"`
+
+	// The labels a Node needs for the template to apply there, Pods on other Nodes run without the PowerProfile
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.spec.powerProfile`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PowerWorkloadTemplate is the Schema for the powerworkloadtemplates API
+type PowerWorkloadTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PowerWorkloadTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PowerWorkloadTemplateList contains a list of PowerWorkloadTemplate
+type PowerWorkloadTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PowerWorkloadTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PowerWorkloadTemplate{}, &PowerWorkloadTemplateList{})
+}
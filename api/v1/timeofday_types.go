@@ -61,6 +61,10 @@ type TimeOfDayStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Time Zone",type=string,JSONPath=`.spec.timeZone`
+//+kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.status.powerProfile`
+//+kubebuilder:printcolumn:name="Last",type=string,JSONPath=`.status.lastSchedule`
+//+kubebuilder:printcolumn:name="Next",type=string,JSONPath=`.status.nextSchedule`
 
 // TimeOfDay is the Schema for the timeofdays API
 type TimeOfDay struct {
@@ -50,6 +50,10 @@ type TimeOfDayCronJobStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Hour",type=integer,JSONPath=`.spec.hour`
+//+kubebuilder:printcolumn:name="Minute",type=integer,JSONPath=`.spec.minute`
+//+kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.spec.profile`
+//+kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
 
 // TimeOfDayCronJob is the Schema for the timeofdaycronjobs API
 type TimeOfDayCronJob struct {
@@ -48,6 +48,9 @@ type UncoreStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Sys Max",type=integer,JSONPath=`.spec.sysMax`
+//+kubebuilder:printcolumn:name="Sys Min",type=integer,JSONPath=`.spec.sysMin`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Uncore is the Schema for the uncores API
 type Uncore struct {
@@ -23,9 +23,165 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentlessHostStatus) DeepCopyInto(out *AgentlessHostStatus) {
+	*out = *in
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentlessHostStatus.
+func (in *AgentlessHostStatus) DeepCopy() *AgentlessHostStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentlessHostStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentlessPool) DeepCopyInto(out *AgentlessPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentlessPool.
+func (in *AgentlessPool) DeepCopy() *AgentlessPool {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentlessPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AgentlessPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentlessPoolList) DeepCopyInto(out *AgentlessPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AgentlessPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentlessPoolList.
+func (in *AgentlessPoolList) DeepCopy() *AgentlessPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentlessPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AgentlessPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentlessPoolSpec) DeepCopyInto(out *AgentlessPoolSpec) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentlessPoolSpec.
+func (in *AgentlessPoolSpec) DeepCopy() *AgentlessPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentlessPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentlessPoolStatus) DeepCopyInto(out *AgentlessPoolStatus) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]AgentlessHostStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentlessPoolStatus.
+func (in *AgentlessPoolStatus) DeepCopy() *AgentlessPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentlessPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIOSSettings) DeepCopyInto(out *BIOSSettings) {
+	*out = *in
+	if in.Advisories != nil {
+		in, out := &in.Advisories, &out.Advisories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIOSSettings.
+func (in *BIOSSettings) DeepCopy() *BIOSSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(BIOSSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CPUModel) DeepCopyInto(out *CPUModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CPUModel.
+func (in *CPUModel) DeepCopy() *CPUModel {
+	if in == nil {
+		return nil
+	}
+	out := new(CPUModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CStates) DeepCopyInto(out *CStates) {
 	*out = *in
@@ -177,64 +333,37 @@ func (in *Container) DeepCopy() *Container {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DieSelector) DeepCopyInto(out *DieSelector) {
+func (in *ControlPlaneOutagePolicy) DeepCopyInto(out *ControlPlaneOutagePolicy) {
 	*out = *in
-	if in.Package != nil {
-		in, out := &in.Package, &out.Package
-		*out = new(uint)
-		**out = **in
-	}
-	if in.Die != nil {
-		in, out := &in.Die, &out.Die
-		*out = new(uint)
-		**out = **in
-	}
-	if in.Min != nil {
-		in, out := &in.Min, &out.Min
-		*out = new(uint)
-		**out = **in
-	}
-	if in.Max != nil {
-		in, out := &in.Max, &out.Max
-		*out = new(uint)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DieSelector.
-func (in *DieSelector) DeepCopy() *DieSelector {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneOutagePolicy.
+func (in *ControlPlaneOutagePolicy) DeepCopy() *ControlPlaneOutagePolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(DieSelector)
+	out := new(ControlPlaneOutagePolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GuaranteedPod) DeepCopyInto(out *GuaranteedPod) {
+func (in *CoreApplyResult) DeepCopyInto(out *CoreApplyResult) {
 	*out = *in
-	if in.Containers != nil {
-		in, out := &in.Containers, &out.Containers
-		*out = make([]Container, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuaranteedPod.
-func (in *GuaranteedPod) DeepCopy() *GuaranteedPod {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoreApplyResult.
+func (in *CoreApplyResult) DeepCopy() *CoreApplyResult {
 	if in == nil {
 		return nil
 	}
-	out := new(GuaranteedPod)
+	out := new(CoreApplyResult)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerConfig) DeepCopyInto(out *PowerConfig) {
+func (in *DemandResponse) DeepCopyInto(out *DemandResponse) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -242,18 +371,18 @@ func (in *PowerConfig) DeepCopyInto(out *PowerConfig) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerConfig.
-func (in *PowerConfig) DeepCopy() *PowerConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DemandResponse.
+func (in *DemandResponse) DeepCopy() *DemandResponse {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerConfig)
+	out := new(DemandResponse)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerConfig) DeepCopyObject() runtime.Object {
+func (in *DemandResponse) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -261,31 +390,31 @@ func (in *PowerConfig) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerConfigList) DeepCopyInto(out *PowerConfigList) {
+func (in *DemandResponseList) DeepCopyInto(out *DemandResponseList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]PowerConfig, len(*in))
+		*out = make([]DemandResponse, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerConfigList.
-func (in *PowerConfigList) DeepCopy() *PowerConfigList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DemandResponseList.
+func (in *DemandResponseList) DeepCopy() *DemandResponseList {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerConfigList)
+	out := new(DemandResponseList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerConfigList) DeepCopyObject() runtime.Object {
+func (in *DemandResponseList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -293,10 +422,12 @@ func (in *PowerConfigList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerConfigSpec) DeepCopyInto(out *PowerConfigSpec) {
+func (in *DemandResponseSpec) DeepCopyInto(out *DemandResponseSpec) {
 	*out = *in
-	if in.PowerNodeSelector != nil {
-		in, out := &in.PowerNodeSelector, &out.PowerNodeSelector
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
@@ -309,18 +440,18 @@ func (in *PowerConfigSpec) DeepCopyInto(out *PowerConfigSpec) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerConfigSpec.
-func (in *PowerConfigSpec) DeepCopy() *PowerConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DemandResponseSpec.
+func (in *DemandResponseSpec) DeepCopy() *DemandResponseSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerConfigSpec)
+	out := new(DemandResponseSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerConfigStatus) DeepCopyInto(out *PowerConfigStatus) {
+func (in *DemandResponseStatus) DeepCopyInto(out *DemandResponseStatus) {
 	*out = *in
 	if in.Nodes != nil {
 		in, out := &in.Nodes, &out.Nodes
@@ -329,117 +460,164 @@ func (in *PowerConfigStatus) DeepCopyInto(out *PowerConfigStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerConfigStatus.
-func (in *PowerConfigStatus) DeepCopy() *PowerConfigStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DemandResponseStatus.
+func (in *DemandResponseStatus) DeepCopy() *DemandResponseStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerConfigStatus)
+	out := new(DemandResponseStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerNode) DeepCopyInto(out *PowerNode) {
+func (in *DesirabilityScores) DeepCopyInto(out *DesirabilityScores) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNode.
-func (in *PowerNode) DeepCopy() *PowerNode {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DesirabilityScores.
+func (in *DesirabilityScores) DeepCopy() *DesirabilityScores {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerNode)
+	out := new(DesirabilityScores)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerNode) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerNodeCPUState) DeepCopyInto(out *PowerNodeCPUState) {
+func (in *DieSelector) DeepCopyInto(out *DieSelector) {
 	*out = *in
-	if in.SharedPool != nil {
-		in, out := &in.SharedPool, &out.SharedPool
-		*out = make([]uint, len(*in))
-		copy(*out, *in)
+	if in.Package != nil {
+		in, out := &in.Package, &out.Package
+		*out = new(uint)
+		**out = **in
 	}
-	if in.GuaranteedPods != nil {
-		in, out := &in.GuaranteedPods, &out.GuaranteedPods
-		*out = make([]GuaranteedPod, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Die != nil {
+		in, out := &in.Die, &out.Die
+		*out = new(uint)
+		**out = **in
+	}
+	if in.Min != nil {
+		in, out := &in.Min, &out.Min
+		*out = new(uint)
+		**out = **in
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(uint)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeCPUState.
-func (in *PowerNodeCPUState) DeepCopy() *PowerNodeCPUState {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DieSelector.
+func (in *DieSelector) DeepCopy() *DieSelector {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerNodeCPUState)
+	out := new(DieSelector)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerNodeList) DeepCopyInto(out *PowerNodeList) {
+func (in *EffectiveProfile) DeepCopyInto(out *EffectiveProfile) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]PowerNode, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
+	in.ProfilePolicy.DeepCopyInto(&out.ProfilePolicy)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeList.
-func (in *PowerNodeList) DeepCopy() *PowerNodeList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveProfile.
+func (in *EffectiveProfile) DeepCopy() *EffectiveProfile {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerNodeList)
+	out := new(EffectiveProfile)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerNodeList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerNodeSpec) DeepCopyInto(out *PowerNodeSpec) {
+func (in *ExecProbe) DeepCopyInto(out *ExecProbe) {
 	*out = *in
-	if in.PowerProfiles != nil {
-		in, out := &in.PowerProfiles, &out.PowerProfiles
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.PowerWorkloads != nil {
-		in, out := &in.PowerWorkloads, &out.PowerWorkloads
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecProbe.
+func (in *ExecProbe) DeepCopy() *ExecProbe {
+	if in == nil {
+		return nil
 	}
-	if in.PowerContainers != nil {
-		in, out := &in.PowerContainers, &out.PowerContainers
+	out := new(ExecProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureDomainCapacity) DeepCopyInto(out *FailureDomainCapacity) {
+	*out = *in
+	if in.MaxPerDomain != nil {
+		in, out := &in.MaxPerDomain, &out.MaxPerDomain
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureDomainCapacity.
+func (in *FailureDomainCapacity) DeepCopy() *FailureDomainCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureDomainCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FrequencyDomainConflict) DeepCopyInto(out *FrequencyDomainConflict) {
+	*out = *in
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrequencyDomainConflict.
+func (in *FrequencyDomainConflict) DeepCopy() *FrequencyDomainConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(FrequencyDomainConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FrequencyTransitionLimit) DeepCopyInto(out *FrequencyTransitionLimit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrequencyTransitionLimit.
+func (in *FrequencyTransitionLimit) DeepCopy() *FrequencyTransitionLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(FrequencyTransitionLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuaranteedPod) DeepCopyInto(out *GuaranteedPod) {
+	*out = *in
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
 		*out = make([]Container, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
@@ -447,53 +625,1270 @@ func (in *PowerNodeSpec) DeepCopyInto(out *PowerNodeSpec) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeSpec.
-func (in *PowerNodeSpec) DeepCopy() *PowerNodeSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuaranteedPod.
+func (in *GuaranteedPod) DeepCopy() *GuaranteedPod {
+	if in == nil {
+		return nil
+	}
+	out := new(GuaranteedPod)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceStatus) DeepCopyInto(out *MaintenanceStatus) {
+	*out = *in
+	if in.SharedProfile != nil {
+		in, out := &in.SharedProfile, &out.SharedProfile
+		*out = new(PoolProfile)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceStatus.
+func (in *MaintenanceStatus) DeepCopy() *MaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkBoost) DeepCopyInto(out *NetworkBoost) {
+	*out = *in
+	if in.Interfaces != nil {
+		in, out := &in.Interfaces, &out.Interfaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkBoost.
+func (in *NetworkBoost) DeepCopy() *NetworkBoost {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkBoost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFailure) DeepCopyInto(out *NodeFailure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFailure.
+func (in *NodeFailure) DeepCopy() *NodeFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroupPolicy) DeepCopyInto(out *NodeGroupPolicy) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ProfilePolicies != nil {
+		in, out := &in.ProfilePolicies, &out.ProfilePolicies
+		*out = make(map[string]ProfilePolicy, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroupPolicy.
+func (in *NodeGroupPolicy) DeepCopy() *NodeGroupPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroupPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroupRollout) DeepCopyInto(out *NodeGroupRollout) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroupRollout.
+func (in *NodeGroupRollout) DeepCopy() *NodeGroupRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroupRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePolicy) DeepCopyInto(out *NodePolicy) {
+	*out = *in
+	if in.ProfilePolicies != nil {
+		in, out := &in.ProfilePolicies, &out.ProfilePolicies
+		*out = make(map[string]ProfilePolicy, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePolicy.
+func (in *NodePolicy) DeepCopy() *NodePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfig.
+func (in *OperatorConfig) DeepCopy() *OperatorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigList) DeepCopyInto(out *OperatorConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OperatorConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigList.
+func (in *OperatorConfigList) DeepCopy() *OperatorConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigSpec) DeepCopyInto(out *OperatorConfigSpec) {
+	*out = *in
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigSpec.
+func (in *OperatorConfigSpec) DeepCopy() *OperatorConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigStatus) DeepCopyInto(out *OperatorConfigStatus) {
+	*out = *in
+	if in.PendingRestart != nil {
+		in, out := &in.PendingRestart, &out.PendingRestart
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigStatus.
+func (in *OperatorConfigStatus) DeepCopy() *OperatorConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PinningMismatch) DeepCopyInto(out *PinningMismatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PinningMismatch.
+func (in *PinningMismatch) DeepCopy() *PinningMismatch {
+	if in == nil {
+		return nil
+	}
+	out := new(PinningMismatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PoolProfile) DeepCopyInto(out *PoolProfile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PoolProfile.
+func (in *PoolProfile) DeepCopy() *PoolProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(PoolProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerConfig) DeepCopyInto(out *PowerConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerConfig.
+func (in *PowerConfig) DeepCopy() *PowerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerConfigList) DeepCopyInto(out *PowerConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerConfigList.
+func (in *PowerConfigList) DeepCopy() *PowerConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerConfigSpec) DeepCopyInto(out *PowerConfigSpec) {
+	*out = *in
+	if in.PowerNodeSelector != nil {
+		in, out := &in.PowerNodeSelector, &out.PowerNodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeGroups != nil {
+		in, out := &in.NodeGroups, &out.NodeGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PowerProfiles != nil {
+		in, out := &in.PowerProfiles, &out.PowerProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProfilePolicies != nil {
+		in, out := &in.ProfilePolicies, &out.ProfilePolicies
+		*out = make(map[string]ProfilePolicy, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.NodeGroupPolicies != nil {
+		in, out := &in.NodeGroupPolicies, &out.NodeGroupPolicies
+		*out = make([]NodeGroupPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodePolicies != nil {
+		in, out := &in.NodePolicies, &out.NodePolicies
+		*out = make([]NodePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CustomDevices != nil {
+		in, out := &in.CustomDevices, &out.CustomDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProfileOrdering != nil {
+		in, out := &in.ProfileOrdering, &out.ProfileOrdering
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailureDomainCapacity != nil {
+		in, out := &in.FailureDomainCapacity, &out.FailureDomainCapacity
+		*out = new(FailureDomainCapacity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ThrottleDemotion != nil {
+		in, out := &in.ThrottleDemotion, &out.ThrottleDemotion
+		*out = new(ThrottleDemotion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DesirabilityScores != nil {
+		in, out := &in.DesirabilityScores, &out.DesirabilityScores
+		*out = new(DesirabilityScores)
+		**out = **in
+	}
+	if in.ControlPlaneOutage != nil {
+		in, out := &in.ControlPlaneOutage, &out.ControlPlaneOutage
+		*out = new(ControlPlaneOutagePolicy)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerConfigSpec.
+func (in *PowerConfigSpec) DeepCopy() *PowerConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerConfigStatus) DeepCopyInto(out *PowerConfigStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CleanedNodes != nil {
+		in, out := &in.CleanedNodes, &out.CleanedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeFailures != nil {
+		in, out := &in.NodeFailures, &out.NodeFailures
+		*out = make([]NodeFailure, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerConfigStatus.
+func (in *PowerConfigStatus) DeepCopy() *PowerConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerMaintenance) DeepCopyInto(out *PowerMaintenance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerMaintenance.
+func (in *PowerMaintenance) DeepCopy() *PowerMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerMaintenance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerMaintenanceList) DeepCopyInto(out *PowerMaintenanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerMaintenance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerMaintenanceList.
+func (in *PowerMaintenanceList) DeepCopy() *PowerMaintenanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerMaintenanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerMaintenanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerMaintenanceSpec) DeepCopyInto(out *PowerMaintenanceSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerMaintenanceSpec.
+func (in *PowerMaintenanceSpec) DeepCopy() *PowerMaintenanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerMaintenanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerMaintenanceStatus) DeepCopyInto(out *PowerMaintenanceStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerMaintenanceStatus.
+func (in *PowerMaintenanceStatus) DeepCopy() *PowerMaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerMaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNode) DeepCopyInto(out *PowerNode) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNode.
+func (in *PowerNode) DeepCopy() *PowerNode {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerNode) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNodeCPUState) DeepCopyInto(out *PowerNodeCPUState) {
+	*out = *in
+	if in.SharedPool != nil {
+		in, out := &in.SharedPool, &out.SharedPool
+		*out = make([]uint, len(*in))
+		copy(*out, *in)
+	}
+	if in.GuaranteedPods != nil {
+		in, out := &in.GuaranteedPods, &out.GuaranteedPods
+		*out = make([]GuaranteedPod, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeCPUState.
+func (in *PowerNodeCPUState) DeepCopy() *PowerNodeCPUState {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNodeCPUState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNodeGroup) DeepCopyInto(out *PowerNodeGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeGroup.
+func (in *PowerNodeGroup) DeepCopy() *PowerNodeGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNodeGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerNodeGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNodeGroupList) DeepCopyInto(out *PowerNodeGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerNodeGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeGroupList.
+func (in *PowerNodeGroupList) DeepCopy() *PowerNodeGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNodeGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerNodeGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNodeGroupSpec) DeepCopyInto(out *PowerNodeGroupSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PowerProfiles != nil {
+		in, out := &in.PowerProfiles, &out.PowerProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProfilePolicies != nil {
+		in, out := &in.ProfilePolicies, &out.ProfilePolicies
+		*out = make(map[string]ProfilePolicy, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(NodeGroupRollout)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeGroupSpec.
+func (in *PowerNodeGroupSpec) DeepCopy() *PowerNodeGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNodeGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNodeGroupStatus) DeepCopyInto(out *PowerNodeGroupStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeGroupStatus.
+func (in *PowerNodeGroupStatus) DeepCopy() *PowerNodeGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNodeGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNodeList) DeepCopyInto(out *PowerNodeList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeList.
+func (in *PowerNodeList) DeepCopy() *PowerNodeList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNodeList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerNodeList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNodeSpec) DeepCopyInto(out *PowerNodeSpec) {
+	*out = *in
+	if in.PowerProfiles != nil {
+		in, out := &in.PowerProfiles, &out.PowerProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PowerWorkloads != nil {
+		in, out := &in.PowerWorkloads, &out.PowerWorkloads
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PowerContainers != nil {
+		in, out := &in.PowerContainers, &out.PowerContainers
+		*out = make([]Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CustomDevices != nil {
+		in, out := &in.CustomDevices, &out.CustomDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SharedPoolStepDown != nil {
+		in, out := &in.SharedPoolStepDown, &out.SharedPoolStepDown
+		*out = new(SharedPoolStepDown)
+		**out = **in
+	}
+	if in.SharedPoolTurboBudget != nil {
+		in, out := &in.SharedPoolTurboBudget, &out.SharedPoolTurboBudget
+		*out = new(SharedPoolTurboBudget)
+		**out = **in
+	}
+	if in.NetworkBoost != nil {
+		in, out := &in.NetworkBoost, &out.NetworkBoost
+		*out = new(NetworkBoost)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PerformanceProfileLevel != nil {
+		in, out := &in.PerformanceProfileLevel, &out.PerformanceProfileLevel
+		*out = new(int)
+		**out = **in
+	}
+	if in.FrequencyTransitionLimit != nil {
+		in, out := &in.FrequencyTransitionLimit, &out.FrequencyTransitionLimit
+		*out = new(FrequencyTransitionLimit)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeSpec.
+func (in *PowerNodeSpec) DeepCopy() *PowerNodeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNodeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerNodeStatus) DeepCopyInto(out *PowerNodeStatus) {
+	*out = *in
+	in.PowerNodeCPUState.DeepCopyInto(&out.PowerNodeCPUState)
+	if in.NetworkBoostedPools != nil {
+		in, out := &in.NetworkBoostedPools, &out.NetworkBoostedPools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PerformanceProfileLevel != nil {
+		in, out := &in.PerformanceProfileLevel, &out.PerformanceProfileLevel
+		*out = new(int)
+		**out = **in
+	}
+	if in.UnavailableControls != nil {
+		in, out := &in.UnavailableControls, &out.UnavailableControls
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CPUModel != nil {
+		in, out := &in.CPUModel, &out.CPUModel
+		*out = new(CPUModel)
+		**out = **in
+	}
+	if in.FrequencyDomains != nil {
+		in, out := &in.FrequencyDomains, &out.FrequencyDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProfileVerifications != nil {
+		in, out := &in.ProfileVerifications, &out.ProfileVerifications
+		*out = make([]ProfileVerification, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BIOSSettings != nil {
+		in, out := &in.BIOSSettings, &out.BIOSSettings
+		*out = new(BIOSSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EffectiveProfiles != nil {
+		in, out := &in.EffectiveProfiles, &out.EffectiveProfiles
+		*out = make([]EffectiveProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ConfiguredTime != nil {
+		in, out := &in.ConfiguredTime, &out.ConfiguredTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PendingProfiles != nil {
+		in, out := &in.PendingProfiles, &out.PendingProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DomainCapacity != nil {
+		in, out := &in.DomainCapacity, &out.DomainCapacity
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Throttling != nil {
+		in, out := &in.Throttling, &out.Throttling
+		*out = new(ThrottlingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppliedProfiles != nil {
+		in, out := &in.AppliedProfiles, &out.AppliedProfiles
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeGroups != nil {
+		in, out := &in.NodeGroups, &out.NodeGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeStatus.
+func (in *PowerNodeStatus) DeepCopy() *PowerNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerParking) DeepCopyInto(out *PowerParking) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerParking.
+func (in *PowerParking) DeepCopy() *PowerParking {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerParking)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerParking) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerParkingList) DeepCopyInto(out *PowerParkingList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerParking, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerParkingList.
+func (in *PowerParkingList) DeepCopy() *PowerParkingList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerParkingList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerParkingList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerParkingSpec) DeepCopyInto(out *PowerParkingSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Redfish != nil {
+		in, out := &in.Redfish, &out.Redfish
+		*out = new(RedfishPowerControl)
+		**out = **in
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(WebhookPowerControl)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerParkingSpec.
+func (in *PowerParkingSpec) DeepCopy() *PowerParkingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerParkingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerParkingStatus) DeepCopyInto(out *PowerParkingStatus) {
+	*out = *in
+	if in.ParkedNodes != nil {
+		in, out := &in.ParkedNodes, &out.ParkedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerParkingStatus.
+func (in *PowerParkingStatus) DeepCopy() *PowerParkingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerParkingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerPod) DeepCopyInto(out *PowerPod) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPod.
+func (in *PowerPod) DeepCopy() *PowerPod {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerPod)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerPod) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerPodList) DeepCopyInto(out *PowerPodList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerPod, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPodList.
+func (in *PowerPodList) DeepCopy() *PowerPodList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerPodList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerPodList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerPodSpec) DeepCopyInto(out *PowerPodSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPodSpec.
+func (in *PowerPodSpec) DeepCopy() *PowerPodSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerPodSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerPodStatus) DeepCopyInto(out *PowerPodStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPodStatus.
+func (in *PowerPodStatus) DeepCopy() *PowerPodStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerPodStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerProfile) DeepCopyInto(out *PowerProfile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfile.
+func (in *PowerProfile) DeepCopy() *PowerProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerProfileList) DeepCopyInto(out *PowerProfileList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfileList.
+func (in *PowerProfileList) DeepCopy() *PowerProfileList {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerNodeSpec)
+	out := new(PowerProfileList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerProfileList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerNodeStatus) DeepCopyInto(out *PowerNodeStatus) {
+func (in *PowerProfileSpec) DeepCopyInto(out *PowerProfileSpec) {
 	*out = *in
-	in.PowerNodeCPUState.DeepCopyInto(&out.PowerNodeCPUState)
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(ProfileCapacity)
+		**out = **in
+	}
+	if in.ManageMin != nil {
+		in, out := &in.ManageMin, &out.ManageMin
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ManageMax != nil {
+		in, out := &in.ManageMax, &out.ManageMax
+		*out = new(bool)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerNodeStatus.
-func (in *PowerNodeStatus) DeepCopy() *PowerNodeStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfileSpec.
+func (in *PowerProfileSpec) DeepCopy() *PowerProfileSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerNodeStatus)
+	out := new(PowerProfileSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerPod) DeepCopyInto(out *PowerPod) {
+func (in *PowerProfileStatus) DeepCopyInto(out *PowerProfileStatus) {
+	*out = *in
+	if in.ApplyStarted != nil {
+		in, out := &in.ApplyStarted, &out.ApplyStarted
+		*out = (*in).DeepCopy()
+	}
+	if in.PendingNodes != nil {
+		in, out := &in.PendingNodes, &out.PendingNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastAppliedSpec != nil {
+		in, out := &in.LastAppliedSpec, &out.LastAppliedSpec
+		*out = new(PowerProfileSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ValidatedCPUModels != nil {
+		in, out := &in.ValidatedCPUModels, &out.ValidatedCPUModels
+		*out = make([]ValidatedCPUModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfileStatus.
+func (in *PowerProfileStatus) DeepCopy() *PowerProfileStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerProfileStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerRecommendation) DeepCopyInto(out *PowerRecommendation) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPod.
-func (in *PowerPod) DeepCopy() *PowerPod {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerRecommendation.
+func (in *PowerRecommendation) DeepCopy() *PowerRecommendation {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerPod)
+	out := new(PowerRecommendation)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerPod) DeepCopyObject() runtime.Object {
+func (in *PowerRecommendation) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -501,31 +1896,31 @@ func (in *PowerPod) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerPodList) DeepCopyInto(out *PowerPodList) {
+func (in *PowerRecommendationList) DeepCopyInto(out *PowerRecommendationList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]PowerPod, len(*in))
+		*out = make([]PowerRecommendation, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPodList.
-func (in *PowerPodList) DeepCopy() *PowerPodList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerRecommendationList.
+func (in *PowerRecommendationList) DeepCopy() *PowerRecommendationList {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerPodList)
+	out := new(PowerRecommendationList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerPodList) DeepCopyObject() runtime.Object {
+func (in *PowerRecommendationList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -533,56 +1928,57 @@ func (in *PowerPodList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerPodSpec) DeepCopyInto(out *PowerPodSpec) {
+func (in *PowerRecommendationSpec) DeepCopyInto(out *PowerRecommendationSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPodSpec.
-func (in *PowerPodSpec) DeepCopy() *PowerPodSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerRecommendationSpec.
+func (in *PowerRecommendationSpec) DeepCopy() *PowerRecommendationSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerPodSpec)
+	out := new(PowerRecommendationSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerPodStatus) DeepCopyInto(out *PowerPodStatus) {
+func (in *PowerRecommendationStatus) DeepCopyInto(out *PowerRecommendationStatus) {
 	*out = *in
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPodStatus.
-func (in *PowerPodStatus) DeepCopy() *PowerPodStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerRecommendationStatus.
+func (in *PowerRecommendationStatus) DeepCopy() *PowerRecommendationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerPodStatus)
+	out := new(PowerRecommendationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerProfile) DeepCopyInto(out *PowerProfile) {
+func (in *PowerWorkload) DeepCopyInto(out *PowerWorkload) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfile.
-func (in *PowerProfile) DeepCopy() *PowerProfile {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkload.
+func (in *PowerWorkload) DeepCopy() *PowerWorkload {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerProfile)
+	out := new(PowerWorkload)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerProfile) DeepCopyObject() runtime.Object {
+func (in *PowerWorkload) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -590,31 +1986,31 @@ func (in *PowerProfile) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerProfileList) DeepCopyInto(out *PowerProfileList) {
+func (in *PowerWorkloadList) DeepCopyInto(out *PowerWorkloadList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]PowerProfile, len(*in))
+		*out = make([]PowerWorkload, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfileList.
-func (in *PowerProfileList) DeepCopy() *PowerProfileList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadList.
+func (in *PowerWorkloadList) DeepCopy() *PowerWorkloadList {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerProfileList)
+	out := new(PowerWorkloadList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerProfileList) DeepCopyObject() runtime.Object {
+func (in *PowerWorkloadList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -622,56 +2018,247 @@ func (in *PowerProfileList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerProfileSpec) DeepCopyInto(out *PowerProfileSpec) {
+func (in *PowerWorkloadSet) DeepCopyInto(out *PowerWorkloadSet) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfileSpec.
-func (in *PowerProfileSpec) DeepCopy() *PowerProfileSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadSet.
+func (in *PowerWorkloadSet) DeepCopy() *PowerWorkloadSet {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerProfileSpec)
+	out := new(PowerWorkloadSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerWorkloadSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerWorkloadSetEntry) DeepCopyInto(out *PowerWorkloadSetEntry) {
+	*out = *in
+	if in.CpuIds != nil {
+		in, out := &in.CpuIds, &out.CpuIds
+		*out = make([]uint, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadSetEntry.
+func (in *PowerWorkloadSetEntry) DeepCopy() *PowerWorkloadSetEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerWorkloadSetEntry)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerProfileStatus) DeepCopyInto(out *PowerProfileStatus) {
+func (in *PowerWorkloadSetList) DeepCopyInto(out *PowerWorkloadSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerWorkloadSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadSetList.
+func (in *PowerWorkloadSetList) DeepCopy() *PowerWorkloadSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerWorkloadSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerWorkloadSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerWorkloadSetSpec) DeepCopyInto(out *PowerWorkloadSetSpec) {
+	*out = *in
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make([]PowerWorkloadSetEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadSetSpec.
+func (in *PowerWorkloadSetSpec) DeepCopy() *PowerWorkloadSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerWorkloadSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerWorkloadSetStatus) DeepCopyInto(out *PowerWorkloadSetStatus) {
+	*out = *in
+	if in.Conflicting != nil {
+		in, out := &in.Conflicting, &out.Conflicting
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadSetStatus.
+func (in *PowerWorkloadSetStatus) DeepCopy() *PowerWorkloadSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerWorkloadSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerWorkloadSpec) DeepCopyInto(out *PowerWorkloadSpec) {
+	*out = *in
+	if in.ReservedCPUs != nil {
+		in, out := &in.ReservedCPUs, &out.ReservedCPUs
+		*out = make([]uint, len(*in))
+		copy(*out, *in)
+	}
+	if in.PowerNodeSelector != nil {
+		in, out := &in.PowerNodeSelector, &out.PowerNodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Node.DeepCopyInto(&out.Node)
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(WorkloadValidation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(WorkloadPodSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(WorkloadHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(WorkloadVerification)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadSpec.
+func (in *PowerWorkloadSpec) DeepCopy() *PowerWorkloadSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerWorkloadSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerWorkloadStatus) DeepCopyInto(out *PowerWorkloadStatus) {
 	*out = *in
+	if in.ValidationStarted != nil {
+		in, out := &in.ValidationStarted, &out.ValidationStarted
+		*out = (*in).DeepCopy()
+	}
+	if in.Transition != nil {
+		in, out := &in.Transition, &out.Transition
+		*out = new(WorkloadTransition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PinningMismatches != nil {
+		in, out := &in.PinningMismatches, &out.PinningMismatches
+		*out = make([]PinningMismatch, len(*in))
+		copy(*out, *in)
+	}
+	if in.FrequencyDomainConflicts != nil {
+		in, out := &in.FrequencyDomainConflicts, &out.FrequencyDomainConflicts
+		*out = make([]FrequencyDomainConflict, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CoreResults != nil {
+		in, out := &in.CoreResults, &out.CoreResults
+		*out = make([]CoreApplyResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(VerificationResult)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfileStatus.
-func (in *PowerProfileStatus) DeepCopy() *PowerProfileStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadStatus.
+func (in *PowerWorkloadStatus) DeepCopy() *PowerWorkloadStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerProfileStatus)
+	out := new(PowerWorkloadStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerWorkload) DeepCopyInto(out *PowerWorkload) {
+func (in *PowerWorkloadTemplate) DeepCopyInto(out *PowerWorkloadTemplate) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkload.
-func (in *PowerWorkload) DeepCopy() *PowerWorkload {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadTemplate.
+func (in *PowerWorkloadTemplate) DeepCopy() *PowerWorkloadTemplate {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerWorkload)
+	out := new(PowerWorkloadTemplate)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerWorkload) DeepCopyObject() runtime.Object {
+func (in *PowerWorkloadTemplate) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -679,31 +2266,31 @@ func (in *PowerWorkload) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerWorkloadList) DeepCopyInto(out *PowerWorkloadList) {
+func (in *PowerWorkloadTemplateList) DeepCopyInto(out *PowerWorkloadTemplateList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]PowerWorkload, len(*in))
+		*out = make([]PowerWorkloadTemplate, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadList.
-func (in *PowerWorkloadList) DeepCopy() *PowerWorkloadList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadTemplateList.
+func (in *PowerWorkloadTemplateList) DeepCopy() *PowerWorkloadTemplateList {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerWorkloadList)
+	out := new(PowerWorkloadTemplateList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PowerWorkloadList) DeepCopyObject() runtime.Object {
+func (in *PowerWorkloadTemplateList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -711,44 +2298,99 @@ func (in *PowerWorkloadList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerWorkloadSpec) DeepCopyInto(out *PowerWorkloadSpec) {
+func (in *PowerWorkloadTemplateSpec) DeepCopyInto(out *PowerWorkloadTemplateSpec) {
 	*out = *in
-	if in.ReservedCPUs != nil {
-		in, out := &in.ReservedCPUs, &out.ReservedCPUs
-		*out = make([]uint, len(*in))
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.PowerNodeSelector != nil {
-		in, out := &in.PowerNodeSelector, &out.PowerNodeSelector
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
-	in.Node.DeepCopyInto(&out.Node)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadSpec.
-func (in *PowerWorkloadSpec) DeepCopy() *PowerWorkloadSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadTemplateSpec.
+func (in *PowerWorkloadTemplateSpec) DeepCopy() *PowerWorkloadTemplateSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerWorkloadSpec)
+	out := new(PowerWorkloadTemplateSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PowerWorkloadStatus) DeepCopyInto(out *PowerWorkloadStatus) {
+func (in *ProfileCapacity) DeepCopyInto(out *ProfileCapacity) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerWorkloadStatus.
-func (in *PowerWorkloadStatus) DeepCopy() *PowerWorkloadStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileCapacity.
+func (in *ProfileCapacity) DeepCopy() *ProfileCapacity {
 	if in == nil {
 		return nil
 	}
-	out := new(PowerWorkloadStatus)
+	out := new(ProfileCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfilePolicy) DeepCopyInto(out *ProfilePolicy) {
+	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(ProfileCapacity)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfilePolicy.
+func (in *ProfilePolicy) DeepCopy() *ProfilePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfilePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileVerification) DeepCopyInto(out *ProfileVerification) {
+	*out = *in
+	if in.CPUs != nil {
+		in, out := &in.CPUs, &out.CPUs
+		*out = make([]uint, len(*in))
+		copy(*out, *in)
+	}
+	in.LastVerified.DeepCopyInto(&out.LastVerified)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileVerification.
+func (in *ProfileVerification) DeepCopy() *ProfileVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedfishPowerControl) DeepCopyInto(out *RedfishPowerControl) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedfishPowerControl.
+func (in *RedfishPowerControl) DeepCopy() *RedfishPowerControl {
+	if in == nil {
+		return nil
+	}
+	out := new(RedfishPowerControl)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -819,6 +2461,72 @@ func (in *SharedPoolInfo) DeepCopy() *SharedPoolInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedPoolStepDown) DeepCopyInto(out *SharedPoolStepDown) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedPoolStepDown.
+func (in *SharedPoolStepDown) DeepCopy() *SharedPoolStepDown {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedPoolStepDown)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedPoolTurboBudget) DeepCopyInto(out *SharedPoolTurboBudget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedPoolTurboBudget.
+func (in *SharedPoolTurboBudget) DeepCopy() *SharedPoolTurboBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedPoolTurboBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThrottleDemotion) DeepCopyInto(out *ThrottleDemotion) {
+	*out = *in
+	if in.PowerProfiles != nil {
+		in, out := &in.PowerProfiles, &out.PowerProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThrottleDemotion.
+func (in *ThrottleDemotion) DeepCopy() *ThrottleDemotion {
+	if in == nil {
+		return nil
+	}
+	out := new(ThrottleDemotion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThrottlingStatus) DeepCopyInto(out *ThrottlingStatus) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThrottlingStatus.
+func (in *ThrottlingStatus) DeepCopy() *ThrottlingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ThrottlingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TimeOfDay) DeepCopyInto(out *TimeOfDay) {
 	*out = *in
@@ -1181,6 +2889,61 @@ func (in *UncoreStatus) DeepCopy() *UncoreStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValidatedCPUModel) DeepCopyInto(out *ValidatedCPUModel) {
+	*out = *in
+	out.CPUModel = in.CPUModel
+	in.LastValidated.DeepCopyInto(&out.LastValidated)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValidatedCPUModel.
+func (in *ValidatedCPUModel) DeepCopy() *ValidatedCPUModel {
+	if in == nil {
+		return nil
+	}
+	out := new(ValidatedCPUModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerificationResult) DeepCopyInto(out *VerificationResult) {
+	*out = *in
+	if in.Started != nil {
+		in, out := &in.Started, &out.Started
+		*out = (*in).DeepCopy()
+	}
+	if in.Completed != nil {
+		in, out := &in.Completed, &out.Completed
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerificationResult.
+func (in *VerificationResult) DeepCopy() *VerificationResult {
+	if in == nil {
+		return nil
+	}
+	out := new(VerificationResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookPowerControl) DeepCopyInto(out *WebhookPowerControl) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookPowerControl.
+func (in *WebhookPowerControl) DeepCopy() *WebhookPowerControl {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookPowerControl)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadInfo) DeepCopyInto(out *WorkloadInfo) {
 	*out = *in
@@ -1227,3 +2990,139 @@ func (in *WorkloadNode) DeepCopy() *WorkloadNode {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadHook) DeepCopyInto(out *WorkloadHook) {
+	*out = *in
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(ExecProbe)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadHook.
+func (in *WorkloadHook) DeepCopy() *WorkloadHook {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadHooks) DeepCopyInto(out *WorkloadHooks) {
+	*out = *in
+	if in.PreApply != nil {
+		in, out := &in.PreApply, &out.PreApply
+		*out = new(WorkloadHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostApply != nil {
+		in, out := &in.PostApply, &out.PostApply
+		*out = new(WorkloadHook)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadHooks.
+func (in *WorkloadHooks) DeepCopy() *WorkloadHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadPodSelector) DeepCopyInto(out *WorkloadPodSelector) {
+	*out = *in
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadPodSelector.
+func (in *WorkloadPodSelector) DeepCopy() *WorkloadPodSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadPodSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadTransition) DeepCopyInto(out *WorkloadTransition) {
+	*out = *in
+	if in.Started != nil {
+		in, out := &in.Started, &out.Started
+		*out = (*in).DeepCopy()
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodDisruptionBudgets != nil {
+		in, out := &in.PodDisruptionBudgets, &out.PodDisruptionBudgets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadTransition.
+func (in *WorkloadTransition) DeepCopy() *WorkloadTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadValidation) DeepCopyInto(out *WorkloadValidation) {
+	*out = *in
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(ExecProbe)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadValidation.
+func (in *WorkloadValidation) DeepCopy() *WorkloadValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadVerification) DeepCopyInto(out *WorkloadVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadVerification.
+func (in *WorkloadVerification) DeepCopy() *WorkloadVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadVerification)
+	in.DeepCopyInto(out)
+	return out
+}
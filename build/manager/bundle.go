@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/intel/kubernetes-power-manager/controllers"
+	"github.com/intel/kubernetes-power-manager/pkg/supportbundle"
+)
+
+// runSupportBundleCommand handles the support-bundle subcommand, which collects the Power CRs, the Nodes'
+// capability matrix, recent events and sanitized logs into one archive to attach to a bug report
+func runSupportBundleCommand(args []string) error {
+	var file string
+	options := supportbundle.Options{}
+
+	flags := flag.NewFlagSet("support-bundle", flag.ExitOnError)
+	flags.StringVar(&options.Namespace, "namespace", controllers.IntelPowerNamespace,
+		"The namespace holding the Power CRs and the Power Manager's Pods.")
+	flags.StringVar(&file, "o", "", "File to write the bundle to. Defaults to power-support-bundle-<time>.tar.gz.")
+	flags.DurationVar(&options.Since, "since", time.Hour, "How far back events and logs go, all of them if 0.")
+	flags.Int64Var(&options.TailLines, "tail-lines", 5000, "The most log lines kept per container, all of them if 0.")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		file = fmt.Sprintf("power-support-bundle-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	config := ctrl.GetConfigOrDie()
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = supportbundle.Write(context.Background(), out, c, clientset, options)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(os.Stdout, "Support bundle written to %s\n", file)
+
+	return err
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/controllers"
+)
+
+// runCleanupCommand handles the cleanup subcommand, which deletes the PowerConfig with cleanUpAll so the Node
+// Agents revert their Nodes and then removes everything the Power Manager added to the cluster. The clean up is
+// finished from here if the manager isn't running. Without a PowerConfig there are no Node Agents, so only the
+// cluster is cleaned up and the Nodes keep their power settings
+func runCleanupCommand(args []string) error {
+	var namespace string
+	var timeout time.Duration
+
+	flags := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	flags.StringVar(&namespace, "namespace", controllers.IntelPowerNamespace, "The namespace holding the Power CRs.")
+	flags.DurationVar(&timeout, "timeout", controllers.DefaultCleanupTimeout,
+		"How long to wait for the Node Agents to revert their Nodes before cleaning up without them.")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	logger := ctrl.Log.WithName("cleanup")
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	configs := &powerv1.PowerConfigList{}
+	err = c.List(ctx, configs, client.InNamespace(namespace))
+	if err != nil {
+		return err
+	}
+	if len(configs.Items) == 0 {
+		logger.Info("no PowerConfig found, the Nodes keep their current power settings")
+		return controllers.CleanUpCluster(ctx, c, namespace, logger, nil)
+	}
+
+	for i := range configs.Items {
+		config := &configs.Items[i]
+		if config.DeletionTimestamp.IsZero() {
+			// the finalizer is added here as well, the manager may not be running to add it
+			patch := client.MergeFrom(config.DeepCopy())
+			config.Spec.CleanUpAll = true
+			controllerutil.AddFinalizer(config, controllers.CleanupFinalizer)
+			err = c.Patch(ctx, config, patch)
+			if err != nil {
+				return err
+			}
+			err = c.Delete(ctx, config)
+			if err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			logger.Info("deleted PowerConfig with cleanUpAll", "name", config.Name)
+		}
+
+		for {
+			err = c.Get(ctx, client.ObjectKeyFromObject(config), config)
+			if errors.IsNotFound(err) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if !controllerutil.ContainsFinalizer(config, controllers.CleanupFinalizer) {
+				// cleaned up, waiting on other finalizers
+				break
+			}
+			waiting, err := controllers.CleanUp(ctx, c, config, timeout, time.Now(), logger, nil)
+			if err != nil {
+				return err
+			}
+			if len(waiting) > 0 {
+				logger.Info("waiting for the Node Agents to revert their Nodes", "nodes", waiting)
+			}
+			time.Sleep(5 * time.Second)
+		}
+	}
+	logger.Info("clean up finished")
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/intel/kubernetes-power-manager/controllers"
+	"github.com/intel/kubernetes-power-manager/pkg/drift"
+)
+
+// runDiffCommand handles the diff subcommand, which prints where the PowerNodes differ from
+// what the Power CRs ask for
+func runDiffCommand(args []string) error {
+	var namespace string
+	var node string
+	var format string
+
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.StringVar(&namespace, "namespace", controllers.IntelPowerNamespace, "The namespace holding the Power CRs.")
+	flags.StringVar(&node, "node", "", "Only compare the given Node. Defaults to every PowerNode.")
+	flags.StringVar(&format, "format", "table", "Output format, table or json.")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	drifts, err := drift.Compute(context.Background(), c, namespace, node)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "table":
+		return drift.Print(os.Stdout, drifts)
+	case "json":
+		data, err := json.MarshalIndent(drifts, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	}
+
+	return fmt.Errorf("unknown format '%s'", format)
+}
@@ -17,21 +17,22 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"go.uber.org/zap/zapcore"
 	"os"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
-	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
-
-	"github.com/intel/kubernetes-power-manager/controllers"
-	"github.com/intel/kubernetes-power-manager/pkg/state"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	"github.com/intel/kubernetes-power-manager/pkg/operator"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -41,22 +42,72 @@ var (
 )
 
 func init() {
-	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
-
-	utilruntime.Must(powerv1.AddToScheme(scheme))
+	utilruntime.Must(operator.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "export" || os.Args[1] == "import") {
+		if err := runSnapshotCommand(os.Args[1], os.Args[2:]); err != nil {
+			setupLog.Error(err, "snapshot command failed", "command", os.Args[1])
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := runDiffCommand(os.Args[2:]); err != nil {
+			setupLog.Error(err, "diff command failed")
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cleanup" {
+		if err := runCleanupCommand(os.Args[2:]); err != nil {
+			setupLog.Error(err, "cleanup command failed")
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "preflight" {
+		if err := runPreflightCommand(os.Args[2:]); err != nil {
+			setupLog.Error(err, "preflight command failed")
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "support-bundle" {
+		if err := runSupportBundleCommand(os.Args[2:]); err != nil {
+			setupLog.Error(err, "support-bundle command failed")
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
+	var gracefulShutdownTimeout time.Duration
+	var webhookPort int
+	var webhookCertDir string
+	var kubeAPIQPS float64
+	var kubeAPIBurst int
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", true,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 30*time.Second,
+		"How long to wait for running reconciles to finish before the manager exits.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server binds to.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "",
+		"The directory holding the webhook server's tls.crt and tls.key. Defaults to the controller-runtime location.")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", 20, "Maximum queries per second from this client to the Kubernetes API.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", 30, "Maximum burst of requests from this client to the Kubernetes API.")
+	operatorOpts := operator.DefaultOptions()
+	operatorOpts.BindFlags(flag.CommandLine)
 
 	logOpts := zap.Options{}
 	logOpts.BindFlags(flag.CommandLine)
+	samplingOpts := logging.SamplingOptions{}
+	samplingOpts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(
@@ -65,30 +116,56 @@ func main() {
 			opts.TimeEncoder = zapcore.ISO8601TimeEncoder
 		},
 		zap.UseFlagOptions(&logOpts),
+		logging.UseSampling(&samplingOpts),
 	),
 	)
+	// route client-go and other klog users through the same sampled logger
+	klog.SetLogger(ctrl.Log.WithName("klog"))
+
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.QPS = float32(kubeAPIQPS)
+	restConfig.Burst = kubeAPIBurst
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "power-operator-6846766c",
+	// the OperatorConfig overrides the flags, the manager isn't running yet so it's read without the cache
+	operatorOpts.MetricsBindAddress = metricsAddr
+	configReader, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create a client for the OperatorConfig")
+		os.Exit(1)
+	}
+	operatorConfig, err := operator.LoadConfig(context.Background(), configReader)
+	if err != nil {
+		setupLog.Error(err, "unable to read the OperatorConfig")
+		os.Exit(1)
+	}
+	if operatorConfig != nil {
+		setupLog.Info("applying the OperatorConfig", "generation", operatorConfig.Generation)
+		operatorOpts.ApplyConfig(operatorConfig.Spec)
+	}
+
+	newCache, err := operator.NewCache(operatorOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to set up the cache")
+		os.Exit(1)
+	}
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme:                  scheme,
+		NewCache:                newCache,
+		MetricsBindAddress:      operatorOpts.MetricsBindAddress,
+		SyncPeriod:              &operatorOpts.SyncPeriod,
+		Port:                    webhookPort,
+		CertDir:                 webhookCertDir,
+		GracefulShutdownTimeout: &gracefulShutdownTimeout,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        "power-operator-6846766c",
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	state := state.NewPowerNodeData()
-
-	if err = (&controllers.PowerConfigReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("PowerConfig"),
-		Scheme: mgr.GetScheme(),
-		State:  state,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PowerConfig")
+	if err = operator.AddToManager(mgr, operatorOpts); err != nil {
+		setupLog.Error(err, "unable to set up the Power Operator")
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
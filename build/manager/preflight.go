@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/intel/kubernetes-power-manager/controllers"
+	"github.com/intel/kubernetes-power-manager/pkg/preflight"
+)
+
+// errPreflightFailed is returned once the report is printed when a check failed, so the command exits non-zero
+var errPreflightFailed = errors.New("preflight checks failed")
+
+// runPreflightCommand handles the preflight subcommand, which checks the cluster has what the Power Manager needs
+// and prints a pass/fail report, for users to run before enabling enforcement
+func runPreflightCommand(args []string) error {
+	var format string
+	options := preflight.Options{
+		AgentSelector: labels.SelectorFromSet(labels.Set{controllers.NodeAgentPodLabel: controllers.NodeAgentPodLabelValue}),
+	}
+
+	flags := flag.NewFlagSet("preflight", flag.ExitOnError)
+	flags.StringVar(&options.Namespace, "namespace", controllers.IntelPowerNamespace, "The namespace the Power Manager runs in.")
+	flags.StringVar(&options.OperatorServiceAccount, "operator-service-account", "intel-power-operator",
+		"The manager's service account, whose RBAC is checked.")
+	flags.StringVar(&options.AgentServiceAccount, "agent-service-account", "intel-power-node-agent",
+		"The Node Agents' service account, whose RBAC is checked.")
+	flags.IntVar(&options.SampleSize, "sample", 3, "How many of the selected Nodes have their drivers and kubelet checked, all of them if 0.")
+	flags.StringVar(&format, "format", "table", "Output format, table or json.")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config := ctrl.GetConfigOrDie()
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	options.KubeletConfig = preflight.KubeletConfigz(clientset)
+
+	checks, err := preflight.Run(context.Background(), c, options)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "table":
+		err = preflight.Print(os.Stdout, checks)
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(checks, "", "  ")
+		if err == nil {
+			_, err = fmt.Fprintln(os.Stdout, string(data))
+		}
+	default:
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+	if err != nil {
+		return err
+	}
+	if !preflight.Passed(checks) {
+		return errPreflightFailed
+	}
+
+	return nil
+}
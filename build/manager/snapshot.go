@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/intel/kubernetes-power-manager/controllers"
+	"github.com/intel/kubernetes-power-manager/pkg/snapshot"
+)
+
+// runSnapshotCommand handles the export and import subcommands, which back up or restore
+// every Power CR in the cluster as a single document
+func runSnapshotCommand(command string, args []string) error {
+	var file string
+	var format string
+	var namespace string
+	var overwrite bool
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.StringVar(&namespace, "namespace", controllers.IntelPowerNamespace, "The namespace holding the Power CRs.")
+	switch command {
+	case "export":
+		flags.StringVar(&file, "o", "", "File to write the snapshot to. Defaults to stdout.")
+		flags.StringVar(&format, "format", snapshot.FormatYAML, "Output format, yaml or json.")
+	case "import":
+		flags.StringVar(&file, "f", "", "File to read the snapshot from, yaml or json.")
+		flags.BoolVar(&overwrite, "overwrite", false, "Replace CRs that already exist instead of skipping them.")
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "export":
+		powerSnapshot, err := snapshot.Export(ctx, c, namespace)
+		if err != nil {
+			return err
+		}
+		data, err := snapshot.Marshal(powerSnapshot, format)
+		if err != nil {
+			return err
+		}
+		if file == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		return os.WriteFile(file, data, 0600)
+	case "import":
+		if file == "" {
+			return fmt.Errorf("a snapshot file must be provided with -f")
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		powerSnapshot, err := snapshot.Unmarshal(data)
+		if err != nil {
+			return err
+		}
+		return snapshot.Import(ctx, c, powerSnapshot, namespace, overwrite)
+	}
+
+	return fmt.Errorf("unknown command '%s'", command)
+}
@@ -18,21 +18,20 @@ package main
 
 import (
 	"flag"
+	"os"
+	"time"
+
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
-	"os"
+
+	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
-	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
-	"github.com/intel/kubernetes-power-manager/pkg/podresourcesclient"
-
-	"github.com/intel/kubernetes-power-manager/controllers"
-	"github.com/intel/kubernetes-power-manager/pkg/podstate"
-	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	"github.com/intel/kubernetes-power-manager/pkg/operator"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -42,18 +41,32 @@ var (
 )
 
 func init() {
-	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
-
-	utilruntime.Must(powerv1.AddToScheme(scheme))
+	utilruntime.Must(operator.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
+	var gracefulShutdownTimeout time.Duration
+	var webhookPort int
+	var webhookCertDir string
+	var kubeAPIQPS float64
+	var kubeAPIBurst int
 	flag.StringVar(&metricsAddr, "metrics-addr", ":10001", "The address the metric endpoint binds to.")
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 30*time.Second,
+		"How long to wait for running reconciles to finish before the manager exits.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server binds to.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "",
+		"The directory holding the webhook server's tls.crt and tls.key. Defaults to the controller-runtime location.")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", 20, "Maximum queries per second from this client to the Kubernetes API.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", 30, "Maximum burst of requests from this client to the Kubernetes API.")
+	agentOpts := operator.DefaultAgentOptions()
+	agentOpts.BindFlags(flag.CommandLine)
 
 	logOpts := zap.Options{}
 	logOpts.BindFlags(flag.CommandLine)
+	samplingOpts := logging.SamplingOptions{}
+	samplingOpts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(
@@ -62,117 +75,30 @@ func main() {
 			o.TimeEncoder = zapcore.ISO8601TimeEncoder
 		},
 		zap.UseFlagOptions(&logOpts),
+		logging.UseSampling(&samplingOpts),
 	),
 	)
-	nodeName := os.Getenv("NODE_NAME")
-
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
+	// route client-go and other klog users through the same sampled logger
+	klog.SetLogger(ctrl.Log.WithName("klog"))
+
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.QPS = float32(kubeAPIQPS)
+	restConfig.Burst = kubeAPIBurst
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme:                  scheme,
+		NewCache:                operator.NewAgentCache(agentOpts),
+		MetricsBindAddress:      metricsAddr,
+		Port:                    webhookPort,
+		CertDir:                 webhookCertDir,
+		GracefulShutdownTimeout: &gracefulShutdownTimeout,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
-	power.SetLogger(ctrl.Log.WithName("powerLibrary"))
-	powerLibrary, err := power.CreateInstance(nodeName)
-	if powerLibrary == nil {
-		setupLog.Error(err, "unable to create Power Library instance")
-		os.Exit(1)
-	}
-
-	for id, feature := range powerLibrary.GetFeaturesInfo() {
-		setupLog.Info(
-			"feature status",
-			"feature", feature.Name(),
-			"driver", feature.Driver(),
-			"error", feature.FeatureError(),
-			"available", power.IsFeatureSupported(id))
-	}
-
-	powerNodeState, err := podstate.NewState()
-	if err != nil {
-		setupLog.Error(err, "unable to create internal state")
-		os.Exit(1)
-	}
-
-	podResourcesClient, err := podresourcesclient.NewPodResourcesClient()
-	if err != nil {
-		setupLog.Error(err, "unable to create internal client")
-		os.Exit(1)
-	}
-
-	if err = (&controllers.PowerProfileReconciler{
-		Client:       mgr.GetClient(),
-		Log:          ctrl.Log.WithName("controllers").WithName("PowerProfile"),
-		Scheme:       mgr.GetScheme(),
-		PowerLibrary: powerLibrary,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PowerProfile")
-		os.Exit(1)
-	}
-	if err = (&controllers.PowerWorkloadReconciler{
-		Client:       mgr.GetClient(),
-		Log:          ctrl.Log.WithName("controllers").WithName("PowerWorkload"),
-		Scheme:       mgr.GetScheme(),
-		PowerLibrary: powerLibrary,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PowerWorkload")
-		os.Exit(1)
-	}
-	if err = (&controllers.PowerNodeReconciler{
-		Client:       mgr.GetClient(),
-		Log:          ctrl.Log.WithName("controllers").WithName("PowerNode"),
-		Scheme:       mgr.GetScheme(),
-		PowerLibrary: powerLibrary,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PowerNode")
-		os.Exit(1)
-	}
-	if err = (&controllers.PowerPodReconciler{
-		Client:             mgr.GetClient(),
-		Log:                ctrl.Log.WithName("controllers").WithName("PowerPod"),
-		Scheme:             mgr.GetScheme(),
-		State:              *powerNodeState,
-		PodResourcesClient: *podResourcesClient,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PowerPod")
-		os.Exit(1)
-	}
-	if err = (&controllers.CStatesReconciler{
-		Client:       mgr.GetClient(),
-		Log:          ctrl.Log.WithName("controllers").WithName("CState"),
-		Scheme:       mgr.GetScheme(),
-		PowerLibrary: powerLibrary,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "CStates")
-		os.Exit(1)
-	}
-	if err = (&controllers.TimeOfDayReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("TimeOfDay"),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "TimeOfDay")
-		os.Exit(1)
-	}
-	if err = (&controllers.TimeOfDayCronJobReconciler{
-		Client:       mgr.GetClient(),
-		Log:          ctrl.Log.WithName("controllers").WithName("TimeOfDayCronJob"),
-		Scheme:       mgr.GetScheme(),
-		PowerLibrary: powerLibrary,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "TimeOfDayCronJob")
-		os.Exit(1)
-	}
-	if err = (&controllers.UncoreReconciler{
-		Client:       mgr.GetClient(),
-		Log:          ctrl.Log.WithName("controllers").WithName("Uncore"),
-		Scheme:       mgr.GetScheme(),
-		PowerLibrary: powerLibrary,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Uncore")
+	if err = operator.AddAgentToManager(mgr, agentOpts); err != nil {
+		setupLog.Error(err, "unable to set up the Node Agent")
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
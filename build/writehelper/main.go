@@ -0,0 +1,78 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The write helper runs beside the Node Agent in its restricted mode and makes the sysfs writes the agent sends it
+// over a unix socket, when they are on its allow list
+package main
+
+import (
+	"errors"
+	"flag"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/intel/kubernetes-power-manager/pkg/hostwrite"
+)
+
+func main() {
+	var socket string
+	var cpuPath string
+	flag.StringVar(&socket, "socket", "/var/run/power-write-helper/helper.sock", "The unix socket the Node Agent sends its writes to.")
+	flag.StringVar(&cpuPath, "cpu-path", hostwrite.CpuPath, "The sysfs directory the allowed files are under.")
+	logOpts := zap.Options{}
+	logOpts.BindFlags(flag.CommandLine)
+	flag.Parse()
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&logOpts)))
+	log := ctrl.Log.WithName("write-helper")
+
+	// a socket left by a previous run
+	err := os.Remove(socket)
+	if err != nil && !os.IsNotExist(err) {
+		log.Error(err, "unable to remove the old socket", "socket", socket)
+		os.Exit(1)
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		log.Error(err, "unable to listen", "socket", socket)
+		os.Exit(1)
+	}
+	// only the Node Agent, running as the same user, may connect
+	err = os.Chmod(socket, 0600)
+	if err != nil {
+		log.Error(err, "unable to restrict the socket", "socket", socket)
+		os.Exit(1)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	log.Info("serving writes", "socket", socket, "cpuPath", cpuPath)
+	server := &hostwrite.Server{CpuPath: cpuPath, Log: log}
+	err = server.Serve(listener)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Error(err, "stopped serving writes")
+		os.Exit(1)
+	}
+}
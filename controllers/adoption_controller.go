@@ -0,0 +1,144 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/adopt"
+	"github.com/intel/kubernetes-power-manager/pkg/poollock"
+)
+
+// AdoptionReconciler takes over the settings a Node was hand tuned with when the Power Manager is first
+// installed on it. Each group of cores with the same settings becomes an adopted PowerProfile and PowerWorkload,
+// and a matching exclusive pool so the cores are never written anything they don't already have
+type AdoptionReconciler struct {
+	// Client creates the adopted objects, Reader lists PowerWorkloads before the manager's cache has started
+	Client       client.Client
+	Reader       client.Reader
+	Log          logr.Logger
+	PowerLibrary power.Host
+	NodeName     string
+	Settings     *adopt.Reader
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloads,verbs=get;list;watch;create
+
+// Adopt adopts the Node's settings unless it already has PowerWorkloads, in which case it isn't a first
+// install. It must run before the manager is started
+func (r *AdoptionReconciler) Adopt(c context.Context) error {
+	workloads := &powerv1.PowerWorkloadList{}
+	err := r.Reader.List(c, workloads, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return err
+	}
+	for _, workload := range workloads.Items {
+		if workload.Spec.Node.Name == r.NodeName {
+			r.Log.Info("Node already has PowerWorkloads, nothing to adopt", "powerworkload", workload.Name)
+			return nil
+		}
+	}
+
+	groups, err := r.Settings.Groups((*r.PowerLibrary.GetAllCpus()).IDs())
+	if err != nil {
+		return fmt.Errorf("error reading the Node's settings: %w", err)
+	}
+	if len(groups) == 0 {
+		r.Log.Info("no hand tuned cores to adopt")
+		return nil
+	}
+
+	for _, group := range groups {
+		err = r.create(c, adoptedProfile(group.Settings))
+		if err != nil {
+			return err
+		}
+		err = r.create(c, adoptedWorkload(group, r.NodeName))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = poollock.Change(func() error { return adopt.Restore(r.PowerLibrary, groups) })
+	if err != nil {
+		return err
+	}
+	r.Log.Info("adopted the Node's existing settings", "profiles", len(groups))
+	return nil
+}
+
+// create leaves an object made by another Node's agent adopting the same settings as it is
+func (r *AdoptionReconciler) create(c context.Context, obj client.Object) error {
+	err := r.Client.Create(c, obj)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("error creating %s: %w", obj.GetName(), err)
+	}
+
+	return nil
+}
+
+func adoptedProfile(settings adopt.Settings) *powerv1.PowerProfile {
+	epp := settings.Epp
+	// an EPP of power would make it the Shared profile, and the EPP stays on the cores either way
+	if _, exists := eppDefaults[epp]; !exists || epp == "power" {
+		epp = ""
+	}
+
+	return &powerv1.PowerProfile{
+		ObjectMeta: adoptedMeta(settings.Name()),
+		Spec: powerv1.PowerProfileSpec{
+			Name:     settings.Name(),
+			Min:      int(settings.Min / 1000),
+			Max:      int(settings.Max / 1000),
+			Governor: settings.Governor,
+			Epp:      epp,
+		},
+	}
+}
+
+func adoptedWorkload(group adopt.Group, nodeName string) *powerv1.PowerWorkload {
+	name := fmt.Sprintf("%s-%s", group.Name(), nodeName)
+	return &powerv1.PowerWorkload{
+		ObjectMeta: adoptedMeta(name),
+		Spec: powerv1.PowerWorkloadSpec{
+			Name: name,
+			Node: powerv1.WorkloadNode{
+				Name:   nodeName,
+				CpuIds: group.Cpus,
+			},
+			PowerProfile: group.Name(),
+		},
+	}
+}
+
+// adoptedMeta annotates an object with its first generation, the one it's created at
+func adoptedMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   IntelPowerNamespace,
+		Annotations: map[string]string{adopt.Annotation: "1"},
+	}
+}
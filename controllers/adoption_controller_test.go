@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/adopt"
+)
+
+func writeCpuFreq(t *testing.T, dir string, cpu int, values map[string]string) {
+	cpufreq := filepath.Join(dir, fmt.Sprintf("cpu%d", cpu), "cpufreq")
+	assert.NoError(t, os.MkdirAll(cpufreq, 0755))
+	for file, value := range values {
+		assert.NoError(t, os.WriteFile(filepath.Join(cpufreq, file), []byte(value+"\n"), 0644))
+	}
+}
+
+func TestAdoptionReconciler_Adopt(t *testing.T) {
+	dir := t.TempDir()
+	// cpu0 runs across its whole range and isn't adopted, cpus 1-2 were pinned at 2GHz by hand
+	writeCpuFreq(t, dir, 0, map[string]string{
+		"cpuinfo_min_freq": "800000", "cpuinfo_max_freq": "3600000",
+		"scaling_min_freq": "800000", "scaling_max_freq": "3600000",
+		"scaling_governor": "powersave", "energy_performance_preference": "balance_power",
+	})
+	for _, cpu := range []int{1, 2} {
+		writeCpuFreq(t, dir, cpu, map[string]string{
+			"cpuinfo_min_freq": "800000", "cpuinfo_max_freq": "3600000",
+			"scaling_min_freq": "2000000", "scaling_max_freq": "2000000",
+			"scaling_governor": "performance", "energy_performance_preference": "performance",
+		})
+	}
+
+	cpus := power.CpuList{}
+	for id := uint(0); id < 3; id++ {
+		cpu := new(coreMock)
+		cpu.On("GetID").Return(id)
+		cpus = append(cpus, cpu)
+	}
+	sharedPool := new(poolMock)
+	sharedPool.On("SetPowerProfile", mock.Anything).Return(nil)
+	sharedPool.On("MoveCpuIDs", mock.Anything).Return(nil)
+	adoptedPool := new(poolMock)
+	adoptedPool.On("SetPowerProfile", mock.Anything).Run(func(args mock.Arguments) {
+		profile := args.Get(0).(power.Profile)
+		assert.Equal(t, uint(2000000), profile.MinFreq())
+		assert.Equal(t, "performance", profile.Governor())
+	}).Return(nil)
+	adoptedPool.On("MoveCpuIDs", []uint{1, 2}).Return(nil)
+	host := new(hostMock)
+	host.On("GetAllCpus").Return(&cpus)
+	host.On("GetSharedPool").Return(sharedPool)
+	host.On("GetExclusivePool", "adopted-2000-2000-performance-performance").Return(nil)
+	host.On("AddExclusivePool", "adopted-2000-2000-performance-performance").Return(adoptedPool, nil)
+
+	assert.NoError(t, powerv1.AddToScheme(scheme.Scheme))
+	otherNode := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance-OtherNode", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerWorkloadSpec{Node: powerv1.WorkloadNode{Name: "OtherNode"}},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(otherNode).Build()
+	r := &AdoptionReconciler{
+		Client:       cl,
+		Reader:       cl,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: host,
+		NodeName:     "TestNode",
+		Settings:     &adopt.Reader{CpuPath: dir},
+	}
+	assert.NoError(t, r.Adopt(context.TODO()))
+	adoptedPool.AssertExpectations(t)
+
+	profile := &powerv1.PowerProfile{}
+	assert.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Name: "adopted-2000-2000-performance-performance", Namespace: IntelPowerNamespace}, profile))
+	assert.Equal(t, 2000, profile.Spec.Min)
+	assert.Equal(t, 2000, profile.Spec.Max)
+	assert.Equal(t, "performance", profile.Spec.Epp)
+	assert.Equal(t, "1", profile.Annotations[adopt.Annotation])
+	workload := &powerv1.PowerWorkload{}
+	assert.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Name: "adopted-2000-2000-performance-performance-TestNode", Namespace: IntelPowerNamespace}, workload))
+	assert.Equal(t, []uint{1, 2}, workload.Spec.Node.CpuIds)
+	assert.Equal(t, "adopted-2000-2000-performance-performance", workload.Spec.PowerProfile)
+
+	// a Node that already has PowerWorkloads isn't a first install, its settings are left to them
+	host = new(hostMock)
+	r.PowerLibrary = host
+	r.NodeName = "OtherNode"
+	assert.NoError(t, r.Adopt(context.TODO()))
+	host.AssertNotCalled(t, "GetAllCpus")
+}
+
+func TestPowerProfileReconciler_AdoptedProfile(t *testing.T) {
+	t.Setenv("NODE_NAME", "TestNode")
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "adopted-2000-2000-performance",
+			Namespace:   IntelPowerNamespace,
+			Generation:  1,
+			Annotations: map[string]string{adopt.Annotation: "1"},
+		},
+		Spec: powerv1.PowerProfileSpec{Name: "adopted-2000-2000-performance", Min: 2000, Max: 2000, Governor: "performance"},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{profile})
+	assert.NoError(t, err)
+	// nothing is asked of the Power Library while the profile is unedited
+	host := new(hostMock)
+	r.PowerLibrary = host
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(profile)}
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	host.AssertExpectations(t)
+	assert.Empty(t, host.Calls)
+
+	assert.True(t, adopt.Pending(profile))
+	profile.Generation = 2
+	assert.False(t, adopt.Pending(profile))
+	profile.Generation = 1
+	delete(profile.Annotations, adopt.Annotation)
+	assert.False(t, adopt.Pending(profile))
+}
@@ -0,0 +1,200 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/agentless"
+	"github.com/intel/kubernetes-power-manager/pkg/dryrun"
+)
+
+// how often the PowerProfile is applied again to undo changes made on the hosts in the meantime
+const agentlessReapplyInterval = 10 * time.Minute
+
+// AgentlessPoolReconciler applies a PowerProfile over SSH to hosts that cannot run the Node Agent. Only the
+// governor, frequency range and EPP are set, there are no exclusive pools, C-States or uncore settings
+type AgentlessPoolReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// APIReader reads the SSH credentials without caching every Secret in the cluster
+	APIReader client.Reader
+	// Runner overrides the SSH runner built from the AgentlessPool's spec
+	Runner agentless.Runner
+	// DryRun keeps the PowerProfile from being applied on the hosts while it is enabled
+	DryRun *dryrun.Client
+	// MaxConcurrentReconciles is how many objects are reconciled at once, 1 if 0
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=agentlesspools,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=power.intel.com,resources=agentlesspools/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+func (r *AgentlessPoolReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("agentlesspool", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+
+	pool := &powerv1.AgentlessPool{}
+	err := r.Client.Get(c, req.NamespacedName, pool)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error retrieving the AgentlessPool")
+		return ctrl.Result{}, err
+	}
+
+	// problems with the pool itself are reported against every host
+	script, credentials, err := r.prepare(c, pool)
+	if err != nil {
+		logger.Error(err, "error preparing the AgentlessPool")
+		statuses := make([]powerv1.AgentlessHostStatus, 0, len(pool.Spec.Hosts))
+		for _, host := range pool.Spec.Hosts {
+			statuses = append(statuses, powerv1.AgentlessHostStatus{Host: host, Message: err.Error()})
+		}
+		return ctrl.Result{RequeueAfter: agentlessReapplyInterval}, r.updateStatus(c, pool, statuses)
+	}
+
+	if dryRunning(r.DryRun) {
+		for _, host := range pool.Spec.Hosts {
+			logger.Info("Dry run, would apply PowerProfile", "host", host, "profile", pool.Spec.PowerProfile, "script", script)
+		}
+		return ctrl.Result{RequeueAfter: agentlessReapplyInterval}, nil
+	}
+
+	runner := r.Runner
+	if runner == nil {
+		runner = &agentless.SSHRunner{Sudo: pool.Spec.Sudo}
+	}
+
+	previous := make(map[string]powerv1.AgentlessHostStatus)
+	for _, status := range pool.Status.Hosts {
+		previous[status.Host] = status
+	}
+	statuses := make([]powerv1.AgentlessHostStatus, 0, len(pool.Spec.Hosts))
+	for _, host := range pool.Spec.Hosts {
+		status := powerv1.AgentlessHostStatus{Host: host, LastApplied: previous[host].LastApplied}
+		output, err := runner.Run(c, host, credentials, script)
+		if err != nil {
+			logger.Error(err, "error applying PowerProfile", "host", host, "output", output)
+			status.Message = err.Error()
+			if output != "" {
+				status.Message = fmt.Sprintf("%v: %s", err, output)
+			}
+		} else {
+			logger.V(5).Info("Applied PowerProfile", "host", host, "profile", pool.Spec.PowerProfile)
+			status.Applied = true
+			now := metav1.Now()
+			status.LastApplied = &now
+		}
+		statuses = append(statuses, status)
+	}
+
+	return ctrl.Result{RequeueAfter: agentlessReapplyInterval}, r.updateStatus(c, pool, statuses)
+}
+
+// prepare builds the script for the pool's PowerProfile and reads its SSH credentials
+func (r *AgentlessPoolReconciler) prepare(c context.Context, pool *powerv1.AgentlessPool) (string, *agentless.Credentials, error) {
+	profile := &powerv1.PowerProfile{}
+	err := r.Client.Get(c, client.ObjectKey{Name: pool.Spec.PowerProfile, Namespace: IntelPowerNamespace}, profile)
+	if err != nil {
+		return "", nil, fmt.Errorf("retrieving PowerProfile %s: %w", pool.Spec.PowerProfile, err)
+	}
+	script, err := agentless.ProfileScript(&profile.Spec, pool.Spec.Cpus)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret := &corev1.Secret{}
+	err = r.APIReader.Get(c, client.ObjectKey{Name: pool.Spec.CredentialsSecret, Namespace: IntelPowerNamespace}, secret)
+	if err != nil {
+		return "", nil, fmt.Errorf("retrieving SSH credentials: %w", err)
+	}
+
+	return script, &agentless.Credentials{
+		Username:              string(secret.Data["username"]),
+		Password:              string(secret.Data["password"]),
+		PrivateKey:            secret.Data["privateKey"],
+		KnownHosts:            secret.Data["knownHosts"],
+		InsecureIgnoreHostKey: pool.Spec.InsecureIgnoreHostKey,
+	}, nil
+}
+
+func (r *AgentlessPoolReconciler) updateStatus(c context.Context, pool *powerv1.AgentlessPool, statuses []powerv1.AgentlessHostStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &powerv1.AgentlessPool{}
+		err := r.Client.Get(c, client.ObjectKeyFromObject(pool), latest)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		latest.Status.Hosts = statuses
+		return r.Client.Status().Update(c, latest)
+	})
+}
+
+// profilePoolRequests reapplies the AgentlessPools using a PowerProfile when it changes
+func (r *AgentlessPoolReconciler) profilePoolRequests(obj client.Object) []reconcile.Request {
+	pools := &powerv1.AgentlessPoolList{}
+	err := r.Client.List(context.TODO(), pools, client.InNamespace(obj.GetNamespace()))
+	if err != nil {
+		r.Log.Error(err, "error listing AgentlessPools")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0)
+	for _, pool := range pools.Items {
+		if pool.Spec.PowerProfile == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pool)})
+		}
+	}
+
+	return requests
+}
+
+func (r *AgentlessPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.AgentlessPool{}).
+		Watches(&source.Kind{Type: &powerv1.PowerProfile{}}, handler.EnqueueRequestsFromMapFunc(r.profilePoolRequests)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
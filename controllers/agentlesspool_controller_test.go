@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/agentless"
+	"github.com/intel/kubernetes-power-manager/pkg/dryrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type runnerMock struct {
+	mock.Mock
+}
+
+func (m *runnerMock) Run(ctx context.Context, host string, credentials *agentless.Credentials, script string) (string, error) {
+	args := m.Called(host, credentials, script)
+	return args.String(0), args.Error(1)
+}
+
+func createAgentlessPoolReconcilerObject(objs []runtime.Object) (*AgentlessPoolReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &AgentlessPoolReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s, APIReader: cl}, nil
+}
+
+func TestAgentlessPoolReconciler(t *testing.T) {
+	pool := &powerv1.AgentlessPool{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "appliances",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.AgentlessPoolSpec{
+			Hosts:             []string{"10.0.0.10", "10.0.0.11:2222"},
+			CredentialsSecret: "appliance-ssh",
+			PowerProfile:      "balance-power",
+			Cpus:              "2-3",
+		},
+	}
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "balance-power",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{
+			Name:     "balance-power",
+			Max:      2400,
+			Min:      1200,
+			Epp:      "balance_power",
+			Governor: "powersave",
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "appliance-ssh",
+			Namespace: IntelPowerNamespace,
+		},
+		Data: map[string][]byte{"username": []byte("root"), "password": []byte("secret")},
+	}
+
+	tcases := []struct {
+		testCase string
+		objs     []runtime.Object
+		runErr   error
+		dryRun   bool
+		expected []powerv1.AgentlessHostStatus
+	}{
+		{
+			testCase: "Test Case 1 - PowerProfile applied to every host",
+			objs:     []runtime.Object{pool, profile, secret},
+			expected: []powerv1.AgentlessHostStatus{
+				{Host: "10.0.0.10", Applied: true},
+				{Host: "10.0.0.11:2222", Applied: true},
+			},
+		},
+		{
+			testCase: "Test Case 2 - one host failing doesn't stop the others",
+			objs:     []runtime.Object{pool, profile, secret},
+			runErr:   fmt.Errorf("connection refused"),
+			expected: []powerv1.AgentlessHostStatus{
+				{Host: "10.0.0.10", Applied: true},
+				{Host: "10.0.0.11:2222", Message: "connection refused"},
+			},
+		},
+		{
+			testCase: "Test Case 3 - missing PowerProfile reported against every host",
+			objs:     []runtime.Object{pool, secret},
+			expected: []powerv1.AgentlessHostStatus{
+				{Host: "10.0.0.10", Message: `retrieving PowerProfile balance-power: powerprofiles.power.intel.com "balance-power" not found`},
+				{Host: "10.0.0.11:2222", Message: `retrieving PowerProfile balance-power: powerprofiles.power.intel.com "balance-power" not found`},
+			},
+		},
+		{
+			testCase: "Test Case 4 - dry run never runs the script on the hosts",
+			objs:     []runtime.Object{pool, profile, secret},
+			dryRun:   true,
+			expected: []powerv1.AgentlessHostStatus{},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		r, err := createAgentlessPoolReconcilerObject(tc.objs)
+		assert.NoError(t, err)
+
+		runner := new(runnerMock)
+		runner.On("Run", "10.0.0.10", mock.Anything, mock.Anything).Return("", nil)
+		runner.On("Run", "10.0.0.11:2222", mock.Anything, mock.Anything).Return("", tc.runErr)
+		r.Runner = runner
+		if tc.dryRun {
+			r.DryRun = dryrun.NewClient(r.Client)
+			r.DryRun.SetEnabled(true)
+		}
+
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pool)}
+		result, err := r.Reconcile(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, agentlessReapplyInterval, result.RequeueAfter)
+		if tc.dryRun {
+			assert.Empty(t, runner.Calls)
+		}
+
+		for _, call := range runner.Calls {
+			credentials := call.Arguments.Get(1).(*agentless.Credentials)
+			assert.Equal(t, "root", credentials.Username)
+			script := call.Arguments.String(2)
+			assert.Contains(t, script, "for cpu in /sys/devices/system/cpu/cpu2 /sys/devices/system/cpu/cpu3; do")
+			assert.Contains(t, script, "echo 2400000 > $freq/scaling_max_freq")
+			assert.Contains(t, script, "echo 'balance_power' > $freq/energy_performance_preference")
+		}
+
+		updated := &powerv1.AgentlessPool{}
+		assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+		assert.Len(t, updated.Status.Hosts, len(tc.expected))
+		for i, expected := range tc.expected {
+			status := updated.Status.Hosts[i]
+			assert.Equal(t, expected.Host, status.Host)
+			assert.Equal(t, expected.Applied, status.Applied)
+			assert.Equal(t, expected.Message, status.Message)
+			assert.Equal(t, expected.Applied, status.LastApplied != nil)
+		}
+	}
+}
+
+func TestAgentlessProfileScript(t *testing.T) {
+	script, err := agentless.ProfileScript(&powerv1.PowerProfileSpec{
+		Name:     "performance",
+		Max:      3600,
+		Min:      3200,
+		Epp:      "performance",
+		Governor: "performance",
+	}, "")
+	assert.NoError(t, err)
+	assert.Contains(t, script, "for cpu in /sys/devices/system/cpu/cpu[0-9]*; do")
+	assert.Contains(t, script, "echo 'performance' > $freq/scaling_governor")
+	// widened before narrowing so the writes are never rejected
+	assert.Less(t, strings.Index(script, "cpuinfo_min_freq"), strings.Index(script, "echo 3200000"))
+	// no EPP with the performance governor
+	assert.NotContains(t, script, "energy_performance_preference")
+
+	_, err = agentless.ProfileScript(&powerv1.PowerProfileSpec{Name: "turbo", MaxPreset: "allCoreTurbo"}, "")
+	assert.Error(t, err)
+}
+
+func TestAgentlessAddress(t *testing.T) {
+	tcases := []struct {
+		host     string
+		expected string
+	}{
+		{host: "10.0.0.5", expected: "10.0.0.5:22"},
+		{host: "10.0.0.5:2222", expected: "10.0.0.5:2222"},
+		{host: "worker-1.example.com", expected: "worker-1.example.com:22"},
+		{host: "fd00::5", expected: "[fd00::5]:22"},
+		{host: "[fd00::5]", expected: "[fd00::5]:22"},
+		{host: "[fd00::5]:2222", expected: "[fd00::5]:2222"},
+		{host: "::1", expected: "[::1]:22"},
+	}
+
+	for _, tc := range tcases {
+		assert.Equal(t, tc.expected, agentless.Address(tc.host), tc.host)
+	}
+}
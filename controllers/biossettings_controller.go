@@ -0,0 +1,150 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/bios"
+)
+
+// BiosAttributeSource reads the host's BIOS attributes, as its BMC reports them
+type BiosAttributeSource interface {
+	BiosAttributes(ctx context.Context) (map[string]interface{}, error)
+}
+
+// BIOSSettingsReconciler periodically reports the BIOS settings that affect power management in the PowerNode's
+// status, along with advisories for the ones that keep PowerProfiles from taking full effect
+type BIOSSettingsReconciler struct {
+	client.Client
+	Log       logr.Logger
+	APIReader client.Reader
+	Interval  time.Duration
+
+	// ReadLocal reads the settings the Node itself shows, bios.ReadLocal when not set
+	ReadLocal func() bios.Settings
+	// AttributeSource is built from the Node's BMC address annotation and the credentials Secret when not set
+	AttributeSource BiosAttributeSource
+	// Secret in the intel-power namespace with the BMC username and password, Redfish is not used without it
+	RedfishCredentialsSecret string
+	RedfishInsecure          bool
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+
+// Start collects the settings right away, then on every interval until the context is cancelled
+func (r *BIOSSettingsReconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := r.Collect(ctx)
+		if err != nil {
+			r.Log.Error(err, "error collecting BIOS settings")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent reads its own Node's BIOS
+func (r *BIOSSettingsReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Collect reads the settings once. The power profile is only known when Redfish is configured, failing to
+// read it is logged and the local settings are still reported
+func (r *BIOSSettingsReconciler) Collect(ctx context.Context) error {
+	logger := r.Log.WithName("biosSettings")
+	nodeName := os.Getenv("NODE_NAME")
+
+	readLocal := r.ReadLocal
+	if readLocal == nil {
+		readLocal = bios.ReadLocal
+	}
+	settings := readLocal()
+
+	attributeSource, err := r.attributeSource(ctx, nodeName)
+	if err != nil {
+		logger.Error(err, "error setting up Redfish")
+	} else if attributeSource != nil {
+		attributes, err := attributeSource.BiosAttributes(ctx)
+		if err != nil {
+			logger.Error(err, "error reading BIOS attributes")
+		} else {
+			settings.PowerProfile = bios.PowerProfileFromAttributes(attributes)
+		}
+	}
+
+	status := &powerv1.BIOSSettings{
+		Version:      settings.Version,
+		HWP:          settings.HWP,
+		Turbo:        settings.Turbo,
+		PowerProfile: settings.PowerProfile,
+		Advisories:   bios.Advisories(settings),
+	}
+	for _, advisory := range status.Advisories {
+		logger.Info("BIOS setting affects power management", "advisory", advisory)
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(ctx, client.ObjectKey{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		}, powerNode)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if reflect.DeepEqual(powerNode.Status.BIOSSettings, status) {
+			return nil
+		}
+
+		powerNode.Status.BIOSSettings = status
+		return r.Client.Status().Update(ctx, powerNode)
+	})
+}
+
+// attributeSource returns nil without an error when Redfish is not configured
+func (r *BIOSSettingsReconciler) attributeSource(ctx context.Context, nodeName string) (BiosAttributeSource, error) {
+	if r.AttributeSource != nil || r.RedfishCredentialsSecret == "" {
+		return r.AttributeSource, nil
+	}
+
+	redfishClient, err := newRedfishClient(ctx, r.Client, r.APIReader, nodeName, r.RedfishCredentialsSecret, r.RedfishInsecure)
+	if err != nil {
+		return nil, err
+	}
+	r.AttributeSource = redfishClient
+	return r.AttributeSource, nil
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/bios"
+	"github.com/intel/kubernetes-power-manager/pkg/redfish"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type attributeSourceMock struct {
+	attributes map[string]interface{}
+	err        error
+}
+
+func (m *attributeSourceMock) BiosAttributes(ctx context.Context) (map[string]interface{}, error) {
+	return m.attributes, m.err
+}
+
+func createBIOSSettingsReconcilerObject(objs []runtime.Object) (*BIOSSettingsReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &BIOSSettingsReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), APIReader: cl}, nil
+}
+
+func TestBIOSSettingsReconciler_Collect(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+	}
+	tcases := []struct {
+		testCase           string
+		local              bios.Settings
+		attributes         *attributeSourceMock
+		expectedProfile    string
+		expectedAdvisories []string
+	}{
+		{
+			testCase:           "Test Case 1 - nothing to advise",
+			local:              bios.Settings{Version: "Dell Inc. 2.17.1", HWP: bios.Enabled, Turbo: bios.Enabled},
+			attributes:         &attributeSourceMock{attributes: map[string]interface{}{"SysProfile": "PerfPerWattOptimizedOs"}},
+			expectedProfile:    "SysProfile=PerfPerWattOptimizedOs",
+			expectedAdvisories: []string{},
+		},
+		{
+			testCase:        "Test Case 2 - turbo disabled and a BIOS controlled profile",
+			local:           bios.Settings{HWP: bios.Enabled, Turbo: bios.Disabled},
+			attributes:      &attributeSourceMock{attributes: map[string]interface{}{"WorkloadProfile": "LowLatency", "ProcTurbo": "Disabled"}},
+			expectedProfile: "WorkloadProfile=LowLatency",
+			expectedAdvisories: []string{
+				"Turbo is disabled in the BIOS, PowerProfiles can't go above the base frequency",
+				"the BIOS power profile WorkloadProfile=LowLatency may override PowerProfiles, set it to an OS controlled or custom profile",
+			},
+		},
+		{
+			testCase:           "Test Case 3 - BMC unreachable",
+			local:              bios.Settings{HWP: bios.Disabled},
+			attributes:         &attributeSourceMock{err: fmt.Errorf("BMC unreachable")},
+			expectedAdvisories: []string{"HWP is disabled in the BIOS, the EPP values of PowerProfiles have no effect"},
+		},
+	}
+
+	for _, tc := range tcases {
+		r, err := createBIOSSettingsReconcilerObject([]runtime.Object{powerNode.DeepCopy()})
+		assert.NoError(t, err)
+		local := tc.local
+		r.ReadLocal = func() bios.Settings { return local }
+		r.AttributeSource = tc.attributes
+
+		assert.NoError(t, r.Collect(context.TODO()), tc.testCase)
+
+		updated := &powerv1.PowerNode{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), updated))
+		settings := updated.Status.BIOSSettings
+		if assert.NotNil(t, settings, tc.testCase) {
+			assert.Equal(t, tc.local.Version, settings.Version, tc.testCase)
+			assert.Equal(t, tc.local.HWP, settings.HWP, tc.testCase)
+			assert.Equal(t, tc.local.Turbo, settings.Turbo, tc.testCase)
+			assert.Equal(t, tc.expectedProfile, settings.PowerProfile, tc.testCase)
+			assert.ElementsMatch(t, tc.expectedAdvisories, settings.Advisories, tc.testCase)
+		}
+	}
+}
+
+func TestBIOSSettingsReconciler_Redfish(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	bmc := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/redfish/v1/Systems":
+			fmt.Fprint(w, `{"Members": [{"@odata.id": "/redfish/v1/Systems/1"}]}`)
+		case "/redfish/v1/Systems/1/Bios":
+			fmt.Fprint(w, `{"Attributes": {"PowerRegulator": "StaticHighPerf", "BootMode": "Uefi"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer bmc.Close()
+
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        nodeName,
+			Annotations: map[string]string{redfish.BMCAddressAnnotation: bmc.URL},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bmc-credentials",
+			Namespace: IntelPowerNamespace,
+		},
+		Data: map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+	}
+	r, err := createBIOSSettingsReconcilerObject([]runtime.Object{powerNode, node, secret})
+	assert.NoError(t, err)
+	r.ReadLocal = func() bios.Settings { return bios.Settings{} }
+	r.RedfishCredentialsSecret = "bmc-credentials"
+	r.RedfishInsecure = true
+
+	assert.NoError(t, r.Collect(context.TODO()))
+
+	updated := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), updated))
+	assert.Equal(t, "PowerRegulator=StaticHighPerf", updated.Status.BIOSSettings.PowerProfile)
+	assert.Len(t, updated.Status.BIOSSettings.Advisories, 1)
+}
@@ -0,0 +1,396 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	"github.com/intel/kubernetes-power-manager/pkg/util"
+)
+
+const (
+	// CleanupFinalizer holds a PowerConfig with cleanUpAll until the clean up has finished
+	CleanupFinalizer = "power.intel.com/cleanup"
+	// DefaultCleanupTimeout is how long a clean up waits for the Node Agents to revert their Nodes
+	DefaultCleanupTimeout = 5 * time.Minute
+
+	// how often a clean up checks on the Node Agents
+	cleanupRequeueInterval = 5 * time.Second
+)
+
+// cleanupKinds are the Power CRs a clean up removes besides the PowerConfig, the PowerWorkloads and PowerProfiles
+// first so the Node Agents still running give their pools and Extended Resources up
+var cleanupKinds = []struct {
+	kind string
+	list func() client.ObjectList
+}{
+	{"PowerWorkload", func() client.ObjectList { return &powerv1.PowerWorkloadList{} }},
+	{"PowerProfile", func() client.ObjectList { return &powerv1.PowerProfileList{} }},
+	{"PowerNode", func() client.ObjectList { return &powerv1.PowerNodeList{} }},
+	{"PowerPod", func() client.ObjectList { return &powerv1.PowerPodList{} }},
+	{"CStates", func() client.ObjectList { return &powerv1.CStatesList{} }},
+	{"Uncore", func() client.ObjectList { return &powerv1.UncoreList{} }},
+	{"TimeOfDay", func() client.ObjectList { return &powerv1.TimeOfDayList{} }},
+	{"TimeOfDayCronJob", func() client.ObjectList { return &powerv1.TimeOfDayCronJobList{} }},
+	{"PowerMaintenance", func() client.ObjectList { return &powerv1.PowerMaintenanceList{} }},
+	{"PowerRecommendation", func() client.ObjectList { return &powerv1.PowerRecommendationList{} }},
+	{"PowerWorkloadTemplate", func() client.ObjectList { return &powerv1.PowerWorkloadTemplateList{} }},
+	{"PowerParking", func() client.ObjectList { return &powerv1.PowerParkingList{} }},
+	{"AgentlessPool", func() client.ObjectList { return &powerv1.AgentlessPoolList{} }},
+	{"DemandResponse", func() client.ObjectList { return &powerv1.DemandResponseList{} }},
+}
+
+// NodeCleanupReconciler reverts this Node to its default power settings when the PowerConfig is deleted with
+// cleanUpAll: every CPU goes back to the Reserved pool with the default C-states and uncore frequencies, as
+// the Node Agent found them, and the Node is listed in the PowerConfig's cleanedNodes
+type NodeCleanupReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	// Handoff's state file is discarded, nil if the handoff is disabled
+	Handoff *PoolHandoffReconciler
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerconfigs/status,verbs=get;update;patch
+
+func (r *NodeCleanupReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("powerconfig", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+	nodeName := os.Getenv("NODE_NAME")
+	changes := logging.NewChangeSummary()
+	defer changes.Log(logger)
+
+	config := &powerv1.PowerConfig{}
+	err := r.Client.Get(c, req.NamespacedName, config)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if config.DeletionTimestamp.IsZero() || !controllerutil.ContainsFinalizer(config, CleanupFinalizer) ||
+		util.StringInStringList(nodeName, config.Status.CleanedNodes) {
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("PowerConfig deleted with cleanUpAll, reverting the Node to its default power settings")
+	err = r.revert(c, nodeName, changes)
+	if err != nil {
+		logger.Error(err, "error reverting the Node")
+		return ctrl.Result{}, err
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := r.Client.Get(c, req.NamespacedName, config)
+		if err != nil {
+			return err
+		}
+		if util.StringInStringList(nodeName, config.Status.CleanedNodes) {
+			return nil
+		}
+		config.Status.CleanedNodes = append(config.Status.CleanedNodes, nodeName)
+		return r.Client.Status().Update(c, config)
+	})
+	if err != nil {
+		logger.Error(err, "error recording the Node as cleaned up")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// revert removes the exclusive pools, takes the PowerProfile and C-states off the Shared pool and moves every CPU
+// to the Reserved pool, then resets the uncore frequencies and ends the transitions of this Node's PowerWorkloads.
+// The cores are moved within the Node's frequency transition limit
+func (r *NodeCleanupReconciler) revert(c context.Context, nodeName string, changes *logging.ChangeSummary) error {
+	limit, err := transitionLimit(c, r.Client, nodeName)
+	if err != nil {
+		return err
+	}
+
+	results := new(multierror.Error)
+	// removing a pool changes the list
+	pools := append(power.PoolList{}, *r.PowerLibrary.GetAllExclusivePools()...)
+	for _, pool := range pools {
+		err := removeInBatches(c, r.PowerLibrary, pool, limit)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("removing pool %s: %w", pool.Name(), err))
+			continue
+		}
+		changes.PoolRemoved(pool.Name())
+	}
+
+	sharedPool := r.PowerLibrary.GetSharedPool()
+	err = limitTransition(c, limit, func() error { return sharedPool.SetPowerProfile(nil) })
+	if err != nil {
+		results = multierror.Append(results, fmt.Errorf("removing Shared pool profile: %w", err))
+	}
+	err = sharedPool.SetCStates(nil)
+	if err != nil {
+		results = multierror.Append(results, fmt.Errorf("resetting Shared pool C-states: %w", err))
+	}
+	for _, cpu := range *r.PowerLibrary.GetAllCpus() {
+		err = cpu.SetCStates(nil)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("resetting C-states of cpu %d: %w", cpu.GetID(), err))
+		}
+	}
+	err = moveInBatches(c, r.PowerLibrary.GetAllCpus().IDs(), limit, r.PowerLibrary.GetReservedPool().MoveCpuIDs)
+	if err != nil {
+		results = multierror.Append(results, fmt.Errorf("moving cpus to the Reserved pool: %w", err))
+	}
+	changes.PoolModified("reserved", nil, nil)
+
+	if topology := r.PowerLibrary.Topology(); topology != nil {
+		results = multierror.Append(results, topology.SetUncore(nil))
+		for _, pkg := range *topology.Packages() {
+			results = multierror.Append(results, pkg.SetUncore(nil))
+			for _, die := range *pkg.Dies() {
+				results = multierror.Append(results, die.SetUncore(nil))
+			}
+		}
+	}
+
+	if r.Handoff != nil {
+		err = r.Handoff.Discard()
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("discarding the pool handoff state: %w", err))
+		}
+	}
+
+	workloads := &powerv1.PowerWorkloadList{}
+	err = r.Client.List(c, workloads, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return multierror.Append(results, err).ErrorOrNil()
+	}
+	workloadReconciler := &PowerWorkloadReconciler{Client: r.Client}
+	for _, workload := range workloads.Items {
+		if workload.Spec.Node.Name != nodeName || workload.Status.Transition == nil {
+			continue
+		}
+		err = workloadReconciler.endTransition(c, workload.Name, changes)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("ending the transition of %s: %w", workload.Name, err))
+		}
+	}
+
+	return results.ErrorOrNil()
+}
+
+func (r *NodeCleanupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.PowerConfig{}).
+		Complete(r)
+}
+
+// CleanUp carries out the clean up of a PowerConfig deleted with cleanUpAll. Until every Node Agent has reverted
+// its Node, or the timeout since the deletion has passed, the Nodes still waited on are returned. Everything the
+// Power Manager added to the cluster is then removed and the PowerConfig's finalizers last
+func CleanUp(c context.Context, cl client.Client, config *powerv1.PowerConfig, timeout time.Duration, now time.Time, logger logr.Logger, changes *logging.ChangeSummary) ([]string, error) {
+	if config.DeletionTimestamp.IsZero() {
+		return nil, fmt.Errorf("PowerConfig %s is not being deleted", config.Name)
+	}
+
+	waiting := make([]string, 0)
+	for _, node := range config.Status.Nodes {
+		if !util.StringInStringList(node, config.Status.CleanedNodes) {
+			waiting = append(waiting, node)
+		}
+	}
+	if len(waiting) > 0 {
+		if now.Sub(config.DeletionTimestamp.Time) < timeout {
+			return waiting, nil
+		}
+		logger.Info("Node Agents did not revert their Nodes in time, their power settings are left as they are", "nodes", waiting)
+	}
+
+	err := CleanUpCluster(c, cl, config.Namespace, logger, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := client.MergeFrom(config.DeepCopy())
+	controllerutil.RemoveFinalizer(config, CleanupFinalizer)
+	controllerutil.RemoveFinalizer(config, ExtendedResourcesFinalizer)
+	err = cl.Patch(c, config, patch)
+	if err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	logger.Info("Clean up finished")
+
+	return nil, nil
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloadtemplates;timeofdaycronjobs,verbs=get;list;watch;patch;delete
+
+// CleanUpCluster removes the Power CRs in the namespace along with any finalizers holding them, the Node Agent
+// DaemonSet, and the Extended Resources, labels, taints and annotations the Power Manager put on the Nodes.
+// It doesn't change the power settings of the Nodes, that is left to their Node Agents
+func CleanUpCluster(c context.Context, cl client.Client, namespace string, logger logr.Logger, changes *logging.ChangeSummary) error {
+	for _, cleanupKind := range cleanupKinds {
+		list := cleanupKind.list()
+		err := cl.List(c, list, client.InNamespace(namespace))
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return fmt.Errorf("listing %s: %w", cleanupKind.kind, err)
+		}
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, object := range objects {
+			obj, ok := object.(client.Object)
+			if !ok {
+				continue
+			}
+			err = removeObject(c, cl, obj)
+			if err != nil {
+				return fmt.Errorf("removing %s %s: %w", cleanupKind.kind, obj.GetName(), err)
+			}
+			changes.ResourceRemoved(cleanupKind.kind, obj.GetName())
+		}
+	}
+
+	daemonSet := &appsv1.DaemonSet{}
+	err := cl.Get(c, client.ObjectKey{Name: NodeAgentDSName, Namespace: namespace}, daemonSet)
+	if err == nil {
+		err = cl.Delete(c, daemonSet)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("deleting the Node Agent DaemonSet: %w", err)
+		}
+		changes.ResourceRemoved("DaemonSet", daemonSet.Name)
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	nodes := &corev1.NodeList{}
+	err = cl.List(c, nodes)
+	if err != nil {
+		return err
+	}
+	results := new(multierror.Error)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		cleaned, err := cleanUpNode(c, cl, node)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("cleaning up Node %s: %w", node.Name, err))
+			continue
+		}
+		if cleaned {
+			logger.V(5).Info("Removed the Power Manager's resources and labels from the Node", "node", node.Name)
+			changes.NodeTouched(node.Name)
+		}
+	}
+
+	return results.ErrorOrNil()
+}
+
+// removeObject deletes the object after taking off any finalizers that would keep it around without the
+// controllers that handle them
+func removeObject(c context.Context, cl client.Client, obj client.Object) error {
+	if len(obj.GetFinalizers()) > 0 {
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		obj.SetFinalizers(nil)
+		err := cl.Patch(c, obj, patch)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+	}
+
+	return client.IgnoreNotFound(cl.Delete(c, obj))
+}
+
+// cleanUpNode removes the PowerProfile capacity and score labels, the unconfigured taint, the idle-since annotation and a
+// PowerMaintenance's cordon from the Node, then the PowerProfile Extended Resources from its status. Parked Nodes
+// are left as they are. It reports whether anything was removed
+func cleanUpNode(c context.Context, cl client.Client, node *corev1.Node) (bool, error) {
+	patch := client.StrategicMergeFrom(node.DeepCopy())
+	changed := false
+	for label := range node.Labels {
+		if strings.HasPrefix(label, CapacityLabelPrefix) || strings.HasPrefix(label, ScoreLabelPrefix) {
+			delete(node.Labels, label)
+			changed = true
+		}
+	}
+	if _, found := node.Annotations[IdleSinceAnnotation]; found {
+		delete(node.Annotations, IdleSinceAnnotation)
+		changed = true
+	}
+	if _, cordoned := node.Annotations[CordonedByMaintenanceAnnotation]; cordoned {
+		node.Spec.Unschedulable = false
+		delete(node.Annotations, CordonedByMaintenanceAnnotation)
+		changed = true
+	}
+	if hasUnconfiguredTaint(node) {
+		taints := make([]corev1.Taint, 0, len(node.Spec.Taints))
+		for _, taint := range node.Spec.Taints {
+			if taint.Key != UnconfiguredTaintKey {
+				taints = append(taints, taint)
+			}
+		}
+		node.Spec.Taints = taints
+		changed = true
+	}
+	if changed {
+		err := cl.Patch(c, node, patch)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	operations := make([]map[string]interface{}, 0)
+	for name := range node.Status.Capacity {
+		if strings.HasPrefix(string(name), ExtendedResourcePrefix) {
+			operations = append(operations, map[string]interface{}{
+				"op":   "remove",
+				"path": "/status/capacity/" + jsonPointerEscape(string(name)),
+			})
+		}
+	}
+	if len(operations) == 0 {
+		return changed, nil
+	}
+	data, err := json.Marshal(operations)
+	if err != nil {
+		return changed, err
+	}
+
+	return true, cl.Status().Patch(c, node, client.RawPatch(types.JSONPatchType, data))
+}
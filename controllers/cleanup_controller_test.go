@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func createCleanupClient(objs []runtime.Object) (client.Client, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+
+	return fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build(), nil
+}
+
+func TestCleanUp(t *testing.T) {
+	deleted := metav1.NewTime(time.Now().Add(-time.Minute))
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "power-config",
+			Namespace:         IntelPowerNamespace,
+			DeletionTimestamp: &deleted,
+			Finalizers:        []string{CleanupFinalizer},
+		},
+		Spec: powerv1.PowerConfigSpec{CleanUpAll: true},
+		Status: powerv1.PowerConfigStatus{
+			Nodes:        []string{"node1", "node2"},
+			CleanedNodes: []string{"node1"},
+		},
+	}
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "performance",
+			Namespace:  IntelPowerNamespace,
+			Finalizers: []string{"power.intel.com/finalizer"},
+		},
+	}
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance-node1", Namespace: IntelPowerNamespace},
+	}
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: NodeAgentDSName, Namespace: IntelPowerNamespace},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+			Labels: map[string]string{
+				CapacityLabelPrefix + "performance": "true",
+				ScoreLabelPrefix + "performance":    "80",
+				"rack":                              "a",
+			},
+		},
+	}
+
+	cl, err := createCleanupClient([]runtime.Object{config, profile, workload, daemonSet, node})
+	assert.NoError(t, err)
+	logger := ctrl.Log.WithName("testing")
+
+	// node2 hasn't reverted its Node yet
+	waiting, err := CleanUp(context.TODO(), cl, config, DefaultCleanupTimeout, time.Now(), logger, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node2"}, waiting)
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(profile), &powerv1.PowerProfile{})
+	assert.NoError(t, err)
+
+	// past the timeout everything is removed without node2
+	waiting, err = CleanUp(context.TODO(), cl, config, DefaultCleanupTimeout, deleted.Add(DefaultCleanupTimeout), logger, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, waiting)
+
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(profile), &powerv1.PowerProfile{})
+	assert.True(t, errors.IsNotFound(err))
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(workload), &powerv1.PowerWorkload{})
+	assert.True(t, errors.IsNotFound(err))
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(daemonSet), &appsv1.DaemonSet{})
+	assert.True(t, errors.IsNotFound(err))
+
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(node), node)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"rack": "a"}, node.Labels)
+
+	// without its finalizer the deleted PowerConfig is gone
+	assert.False(t, controllerutil.ContainsFinalizer(config, CleanupFinalizer))
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(config), &powerv1.PowerConfig{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// a PowerConfig that isn't being deleted is not cleaned up
+	_, err = CleanUp(context.TODO(), cl, &powerv1.PowerConfig{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		DefaultCleanupTimeout, time.Now(), logger, nil)
+	assert.Error(t, err)
+}
+
+func TestCleanUpNode(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+			Annotations: map[string]string{
+				IdleSinceAnnotation:             time.Now().Format(time.RFC3339),
+				CordonedByMaintenanceAnnotation: "firmware-update",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Unschedulable: true,
+			Taints: []corev1.Taint{
+				{Key: UnconfiguredTaintKey, Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{
+				ExtendedResourcePrefix + "performance": resource.MustParse("40"),
+				corev1.ResourceCPU:                     resource.MustParse("64"),
+			},
+		},
+	}
+	parked := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node2",
+			Annotations: map[string]string{ParkedAnnotation: "S5"},
+		},
+		Spec: corev1.NodeSpec{Unschedulable: true},
+	}
+
+	cl, err := createCleanupClient([]runtime.Object{node, parked})
+	assert.NoError(t, err)
+
+	cleaned, err := cleanUpNode(context.TODO(), cl, node)
+	assert.NoError(t, err)
+	assert.True(t, cleaned)
+
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(node), node)
+	assert.NoError(t, err)
+	assert.Empty(t, node.Annotations)
+	assert.False(t, node.Spec.Unschedulable)
+	assert.Equal(t, []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}, node.Spec.Taints)
+	_, found := node.Status.Capacity[ExtendedResourcePrefix+"performance"]
+	assert.False(t, found)
+	_, found = node.Status.Capacity[corev1.ResourceCPU]
+	assert.True(t, found)
+
+	cleaned, err = cleanUpNode(context.TODO(), cl, parked)
+	assert.NoError(t, err)
+	assert.False(t, cleaned)
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(parked), parked)
+	assert.NoError(t, err)
+	assert.True(t, parked.Spec.Unschedulable)
+	assert.Contains(t, parked.Annotations, ParkedAnnotation)
+}
@@ -0,0 +1,159 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/poollock"
+)
+
+// ControlPlaneOutageReconciler keeps the Node power managed while the API server can't be reached. The settings
+// last applied are left as they are, and when the PowerConfig has a controlPlaneOutage with a safeProfile the
+// exclusive pools are given it once the outage has lasted long enough. Their own PowerProfiles are put back as
+// soon as the API server answers again, and the other controllers catch up with what changed as their caches
+// resync
+type ControlPlaneOutageReconciler struct {
+	// Reader must not be cached, as the cache keeps answering during an outage
+	Reader       client.Reader
+	Log          logr.Logger
+	PowerLibrary power.Host
+	Interval     time.Duration
+
+	policy      *powerv1.ControlPlaneOutagePolicy
+	safeProfile power.Profile
+	lastContact time.Time
+	// reverted holds the PowerProfiles the pools had before they were given the safe profile
+	reverted map[string]power.Profile
+}
+
+// Start checks the API server on every interval until the context is cancelled
+func (r *ControlPlaneOutageReconciler) Start(ctx context.Context) error {
+	r.lastContact = time.Now()
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.Check(ctx, time.Now())
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent has to look after its own Node
+func (r *ControlPlaneOutageReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Check reads the PowerConfigs straight from the API server. While it answers the policy and the safe profile's
+// settings are kept for an outage, when it doesn't the policy is applied
+func (r *ControlPlaneOutageReconciler) Check(ctx context.Context, now time.Time) {
+	probeCtx, cancel := context.WithTimeout(ctx, r.Interval)
+	defer cancel()
+	configs := &powerv1.PowerConfigList{}
+	err := r.Reader.List(probeCtx, configs, client.InNamespace(IntelPowerNamespace))
+	if err == nil {
+		r.connected(configs, now)
+		return
+	}
+
+	outage := now.Sub(r.lastContact)
+	r.Log.Info("API server unreachable, keeping the settings last applied", "outage", outage.String(), "error", err.Error())
+	if r.reverted != nil || r.policy == nil || r.safeProfile == nil {
+		return
+	}
+	if outage < time.Duration(r.policy.RevertAfterSeconds)*time.Second {
+		return
+	}
+	r.revert()
+}
+
+// connected restores the pools reverted during an outage and refreshes the policy
+func (r *ControlPlaneOutageReconciler) connected(configs *powerv1.PowerConfigList, now time.Time) {
+	r.lastContact = now
+	if r.reverted != nil {
+		r.Log.Info("API server reachable again, restoring the pools' PowerProfiles", "pools", len(r.reverted))
+		for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+			profile, exists := r.reverted[pool.Name()]
+			if !exists {
+				continue
+			}
+			err := poollock.Change(func() error { return pool.SetPowerProfile(profile) })
+			if err != nil {
+				r.Log.Error(err, "error restoring the pool's PowerProfile", "pool", pool.Name())
+			}
+		}
+		r.reverted = nil
+	}
+
+	r.policy = nil
+	r.safeProfile = nil
+	for _, config := range configs.Items {
+		if config.Spec.ControlPlaneOutage != nil {
+			r.policy = config.Spec.ControlPlaneOutage.DeepCopy()
+			break
+		}
+	}
+	if r.policy == nil || r.policy.SafeProfile == "" {
+		return
+	}
+	r.safeProfile = r.localProfile(r.policy.SafeProfile)
+	if r.safeProfile == nil {
+		r.Log.V(5).Info("safe profile isn't applied on this Node, the pools will keep their PowerProfiles during an outage", "profile", r.policy.SafeProfile)
+	}
+}
+
+// localProfile is the PowerProfile applied on this Node under the name, from its exclusive pool or the Shared pool
+func (r *ControlPlaneOutageReconciler) localProfile(name string) power.Profile {
+	pool := r.PowerLibrary.GetExclusivePool(name)
+	if pool != nil && pool.GetPowerProfile() != nil {
+		return pool.GetPowerProfile()
+	}
+	shared := r.PowerLibrary.GetSharedPool()
+	if shared != nil && shared.GetPowerProfile() != nil && shared.GetPowerProfile().Name() == name {
+		return shared.GetPowerProfile()
+	}
+
+	return nil
+}
+
+// revert gives every exclusive pool with cores the safe profile, remembering the PowerProfile it had
+func (r *ControlPlaneOutageReconciler) revert() {
+	r.Log.Info("API server unreachable for too long, giving the exclusive pools the safe profile", "profile", r.safeProfile.Name())
+	r.reverted = make(map[string]power.Profile)
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		profile := pool.GetPowerProfile()
+		if profile == nil || profile.Name() == r.safeProfile.Name() || len(*pool.Cpus()) == 0 {
+			continue
+		}
+		err := poollock.Change(func() error { return pool.SetPowerProfile(r.safeProfile) })
+		if err != nil {
+			r.Log.Error(err, "error giving the pool the safe profile", "pool", pool.Name())
+			continue
+		}
+		r.reverted[pool.Name()] = profile
+	}
+}
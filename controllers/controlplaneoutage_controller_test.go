@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+// outageReader fails every List while the API server is down
+type outageReader struct {
+	client.Reader
+	down bool
+}
+
+func (r *outageReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if r.down {
+		return fmt.Errorf("connection refused")
+	}
+	return r.Reader.List(ctx, list, opts...)
+}
+
+func TestControlPlaneOutageReconciler(t *testing.T) {
+	s := scheme.Scheme
+	assert.NoError(t, powerv1.AddToScheme(s))
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "power-config", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerConfigSpec{
+			ControlPlaneOutage: &powerv1.ControlPlaneOutagePolicy{SafeProfile: "balance-power", RevertAfterSeconds: 60},
+		},
+	}
+	reader := &outageReader{Reader: fake.NewClientBuilder().WithScheme(s).WithObjects(config).Build()}
+
+	safe := new(profMock)
+	safe.On("Name").Return("balance-power")
+	performance := new(profMock)
+	performance.On("Name").Return("performance")
+	core := new(coreMock)
+	core.On("GetID").Return(uint(2))
+	safePool := new(poolMock)
+	safePool.On("Name").Return("balance-power")
+	safePool.On("GetPowerProfile").Return(safe)
+	safePool.On("Cpus").Return(&power.CpuList{})
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("GetPowerProfile").Return(performance)
+	performancePool.On("Cpus").Return(&power.CpuList{core})
+	performancePool.On("SetPowerProfile", safe).Return(nil)
+	performancePool.On("SetPowerProfile", performance).Return(nil)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetExclusivePool", "balance-power").Return(safePool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{safePool, performancePool})
+
+	r := &ControlPlaneOutageReconciler{
+		Reader:       reader,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Interval:     10 * time.Second,
+	}
+	start := time.Now()
+	r.Check(context.TODO(), start)
+
+	// the settings last applied are kept until the outage has lasted revertAfterSeconds
+	reader.down = true
+	r.Check(context.TODO(), start.Add(30*time.Second))
+	performancePool.AssertNotCalled(t, "SetPowerProfile", safe)
+	r.Check(context.TODO(), start.Add(61*time.Second))
+	performancePool.AssertCalled(t, "SetPowerProfile", safe)
+	safePool.AssertNotCalled(t, "SetPowerProfile", safe)
+	r.Check(context.TODO(), start.Add(90*time.Second))
+	performancePool.AssertNumberOfCalls(t, "SetPowerProfile", 1)
+
+	// the pools get their PowerProfiles back on reconnect
+	reader.down = false
+	r.Check(context.TODO(), start.Add(100*time.Second))
+	performancePool.AssertCalled(t, "SetPowerProfile", performance)
+	performancePool.AssertNumberOfCalls(t, "SetPowerProfile", 2)
+
+	// without a policy the settings are kept however long the outage
+	config.Spec.ControlPlaneOutage = nil
+	assert.NoError(t, reader.Reader.(client.Client).Update(context.TODO(), config))
+	r.Check(context.TODO(), start.Add(110*time.Second))
+	reader.down = true
+	r.Check(context.TODO(), start.Add(time.Hour))
+	performancePool.AssertNumberOfCalls(t, "SetPowerProfile", 2)
+}
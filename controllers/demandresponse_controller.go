@@ -0,0 +1,287 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+const (
+	DemandResponseStartedReason   = "DemandResponseStarted"
+	DemandResponseCompletedReason = "DemandResponseCompleted"
+
+	// how often the Nodes' power is sampled while a DemandResponse is pending or active
+	demandResponseInterval = time.Minute
+)
+
+// DemandResponseReconciler tracks DemandResponse events through their window and reports the power reduction
+// they achieved. The Node Agents reduce the capacity and frequencies of their PowerProfiles themselves while an
+// event is active and restore them once it ends
+type DemandResponseReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// MaxConcurrentReconciles is how many objects are reconciled at once, 1 if 0
+	MaxConcurrentReconciles int
+}
+
+// demandReduction is what the active DemandResponses take off one PowerProfile on one Node
+type demandReduction struct {
+	capacityPercent int
+	maxFrequency    int
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=demandresponses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=power.intel.com,resources=demandresponses/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+func (r *DemandResponseReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("demandresponse", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+
+	demandResponse := &powerv1.DemandResponse{}
+	err := r.Client.Get(c, req.NamespacedName, demandResponse)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error retrieving the DemandResponse")
+		return ctrl.Result{}, err
+	}
+
+	now := time.Now()
+	phase := demandResponsePhase(&demandResponse.Spec, now)
+	if phase == powerv1.DemandResponseCompleted && demandResponse.Status.Phase == powerv1.DemandResponseCompleted {
+		return ctrl.Result{}, nil
+	}
+
+	nodeList := &corev1.NodeList{}
+	err = r.Client.List(c, nodeList, client.MatchingLabels(demandResponse.Spec.NodeSelector))
+	if err != nil {
+		logger.Error(err, "error retrieving the selected Nodes")
+		return ctrl.Result{}, err
+	}
+	nodeNames := make([]string, 0, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	sort.Strings(nodeNames)
+	watts, err := r.nodesPower(c, nodeNames)
+	if err != nil {
+		logger.Error(err, "error retrieving the PowerNodes")
+		return ctrl.Result{}, err
+	}
+
+	var status powerv1.DemandResponseStatus
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &powerv1.DemandResponse{}
+		err := r.Client.Get(c, req.NamespacedName, latest)
+		if err != nil {
+			return err
+		}
+		status = nextDemandResponseStatus(latest.Status, phase, nodeNames, watts)
+		latest.Status = status
+		return r.Client.Status().Update(c, latest)
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error updating the DemandResponse status")
+		return ctrl.Result{}, err
+	}
+
+	if phase != demandResponse.Status.Phase {
+		logger.Info("DemandResponse phase changed", "phase", phase, "nodes", len(nodeNames),
+			"baselineWatts", status.BaselineWatts, "achievedReductionWatts", status.AchievedReductionWatts)
+		switch phase {
+		case powerv1.DemandResponseActive:
+			r.recordEvent(demandResponse, DemandResponseStartedReason, fmt.Sprintf("Reducing %d Nodes from a baseline of %dW", len(nodeNames), status.BaselineWatts))
+		case powerv1.DemandResponseCompleted:
+			r.recordEvent(demandResponse, DemandResponseCompletedReason, fmt.Sprintf("Restored %d Nodes, achieved a %dW reduction against a %dW target",
+				len(nodeNames), status.AchievedReductionWatts, demandResponse.Spec.TargetReductionWatts))
+		}
+	}
+
+	switch phase {
+	case powerv1.DemandResponsePending:
+		return ctrl.Result{RequeueAfter: minDuration(demandResponseInterval, demandResponse.Spec.Start.Sub(now))}, nil
+	case powerv1.DemandResponseActive:
+		return ctrl.Result{RequeueAfter: minDuration(demandResponseInterval, demandResponse.Spec.End.Sub(now))}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// nodesPower is the power the Nodes currently draw, taking each Node's chassis power where it reports it and
+// its package power otherwise
+func (r *DemandResponseReconciler) nodesPower(c context.Context, nodeNames []string) (int, error) {
+	powerNodes := &powerv1.PowerNodeList{}
+	err := r.Client.List(c, powerNodes, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return 0, err
+	}
+	selected := make(map[string]bool, len(nodeNames))
+	for _, name := range nodeNames {
+		selected[name] = true
+	}
+
+	watts := 0
+	for _, powerNode := range powerNodes.Items {
+		if !selected[powerNode.Name] {
+			continue
+		}
+		if powerNode.Status.ChassisPowerWatts > 0 {
+			watts += powerNode.Status.ChassisPowerWatts
+		} else {
+			watts += powerNode.Status.PackagePowerWatts
+		}
+	}
+
+	return watts, nil
+}
+
+func (r *DemandResponseReconciler) recordEvent(demandResponse *powerv1.DemandResponse, reason string, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(demandResponse, corev1.EventTypeNormal, reason, message)
+	}
+}
+
+// demandResponsePhase is where now falls in the event's window
+func demandResponsePhase(spec *powerv1.DemandResponseSpec, now time.Time) string {
+	switch {
+	case now.Before(spec.Start.Time):
+		return powerv1.DemandResponsePending
+	case now.Before(spec.End.Time):
+		return powerv1.DemandResponseActive
+	}
+
+	return powerv1.DemandResponseCompleted
+}
+
+// nextDemandResponseStatus takes one power sample into the status. The baseline is the last sample before the
+// event starts, the samples during the event make up its average. An event created after its start has no
+// baseline and so reports no reduction
+func nextDemandResponseStatus(status powerv1.DemandResponseStatus, phase string, nodeNames []string, watts int) powerv1.DemandResponseStatus {
+	status.Phase = phase
+	status.Nodes = nodeNames
+	switch phase {
+	case powerv1.DemandResponsePending:
+		status.BaselineWatts = watts
+	case powerv1.DemandResponseActive:
+		status.AverageWatts = (status.AverageWatts*status.Samples + watts) / (status.Samples + 1)
+		status.Samples++
+	}
+	if status.BaselineWatts > 0 && status.Samples > 0 {
+		status.AchievedReductionWatts = status.BaselineWatts - status.AverageWatts
+	}
+
+	return status
+}
+
+// activeDemandReduction combines the DemandResponses active on the Node for the PowerProfile, taking the largest
+// capacity reduction and the lowest frequency cap. It also returns how long until one of them starts or ends,
+// zero if none will
+func activeDemandReduction(c context.Context, cl client.Client, nodeName string, profileName string, now time.Time) (demandReduction, time.Duration, error) {
+	reduction := demandReduction{}
+	demandResponses := &powerv1.DemandResponseList{}
+	err := cl.List(c, demandResponses, client.InNamespace(IntelPowerNamespace))
+	if err != nil || len(demandResponses.Items) == 0 {
+		return reduction, 0, err
+	}
+	node := &corev1.Node{}
+	err = cl.Get(c, client.ObjectKey{Name: nodeName}, node)
+	if err != nil {
+		return reduction, 0, err
+	}
+
+	var next time.Duration
+	for _, demandResponse := range demandResponses.Items {
+		spec := demandResponse.Spec
+		if !labels.SelectorFromSet(spec.NodeSelector).Matches(labels.Set(node.Labels)) || !reducesProfile(&spec, profileName) {
+			continue
+		}
+
+		switch demandResponsePhase(&spec, now) {
+		case powerv1.DemandResponsePending:
+			next = minDuration(next, spec.Start.Sub(now))
+		case powerv1.DemandResponseActive:
+			next = minDuration(next, spec.End.Sub(now))
+			if spec.CapacityReductionPercent > reduction.capacityPercent {
+				reduction.capacityPercent = spec.CapacityReductionPercent
+			}
+			if spec.MaxFrequency > 0 && (reduction.maxFrequency == 0 || spec.MaxFrequency < reduction.maxFrequency) {
+				reduction.maxFrequency = spec.MaxFrequency
+			}
+		}
+	}
+
+	return reduction, next, nil
+}
+
+// reducesProfile reports whether the DemandResponse names the PowerProfile, or names none and so reduces them all
+func reducesProfile(spec *powerv1.DemandResponseSpec, profileName string) bool {
+	if len(spec.PowerProfiles) == 0 {
+		return true
+	}
+	for _, name := range spec.PowerProfiles {
+		if name == profileName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// minDuration is the shorter of two durations, ignoring zero ones
+func minDuration(a time.Duration, b time.Duration) time.Duration {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+
+	return a
+}
+
+func (r *DemandResponseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.DemandResponse{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createDemandResponseReconcilerObject(objs []runtime.Object) (*DemandResponseReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &DemandResponseReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s}, nil
+}
+
+func TestDemandResponseReconciler(t *testing.T) {
+	now := time.Now()
+	objs := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"zone": "east"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"zone": "east"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"zone": "west"}}},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{PackagePowerWatts: 200, ChassisPowerWatts: 400},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{PackagePowerWatts: 300},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node3", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{PackagePowerWatts: 1000},
+		},
+		&powerv1.DemandResponse{
+			ObjectMeta: metav1.ObjectMeta{Name: "peak", Namespace: IntelPowerNamespace},
+			Spec: powerv1.DemandResponseSpec{
+				Start:                metav1.NewTime(now.Add(10 * time.Second)),
+				End:                  metav1.NewTime(now.Add(time.Hour)),
+				NodeSelector:         map[string]string{"zone": "east"},
+				TargetReductionWatts: 100,
+			},
+		},
+	}
+	r, err := createDemandResponseReconcilerObject(objs)
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "peak", Namespace: IntelPowerNamespace}}
+	getDemandResponse := func() *powerv1.DemandResponse {
+		demandResponse := &powerv1.DemandResponse{}
+		assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, demandResponse))
+		return demandResponse
+	}
+	setPower := func(name string, watts int) {
+		powerNode := &powerv1.PowerNode{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: IntelPowerNamespace}, powerNode))
+		powerNode.Status.ChassisPowerWatts = watts
+		assert.NoError(t, r.Client.Status().Update(context.TODO(), powerNode))
+	}
+	setWindow := func(start time.Time, end time.Time) {
+		demandResponse := getDemandResponse()
+		demandResponse.Spec.Start = metav1.NewTime(start)
+		demandResponse.Spec.End = metav1.NewTime(end)
+		assert.NoError(t, r.Client.Update(context.TODO(), demandResponse))
+	}
+
+	// the baseline is taken from the selected Nodes, chassis power where they report it
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= 10*time.Second)
+	status := getDemandResponse().Status
+	assert.Equal(t, powerv1.DemandResponsePending, status.Phase)
+	assert.Equal(t, []string{"node1", "node2"}, status.Nodes)
+	assert.Equal(t, 700, status.BaselineWatts)
+
+	// samples during the event are averaged against the baseline
+	setWindow(now.Add(-time.Minute), now.Add(time.Hour))
+	setPower("node1", 300)
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	setPower("node1", 200)
+	result, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, demandResponseInterval, result.RequeueAfter)
+	status = getDemandResponse().Status
+	assert.Equal(t, powerv1.DemandResponseActive, status.Phase)
+	assert.Equal(t, 2, status.Samples)
+	assert.Equal(t, 550, status.AverageWatts)
+	assert.Equal(t, 150, status.AchievedReductionWatts)
+
+	// the report is kept once the event is over
+	setWindow(now.Add(-time.Hour), now.Add(-time.Minute))
+	setPower("node1", 400)
+	result, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	status = getDemandResponse().Status
+	assert.Equal(t, powerv1.DemandResponseCompleted, status.Phase)
+	assert.Equal(t, 2, status.Samples)
+	assert.Equal(t, 150, status.AchievedReductionWatts)
+}
+
+func TestActiveDemandReduction(t *testing.T) {
+	// the API only keeps whole seconds
+	now := time.Now().Truncate(time.Second)
+	demandResponse := func(name string, start time.Time, end time.Time, spec powerv1.DemandResponseSpec) *powerv1.DemandResponse {
+		spec.Start = metav1.NewTime(start)
+		spec.End = metav1.NewTime(end)
+		return &powerv1.DemandResponse{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: IntelPowerNamespace},
+			Spec:       spec,
+		}
+	}
+	objs := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "TestNode", Labels: map[string]string{"zone": "east"}}},
+		demandResponse("fleet", now.Add(-time.Hour), now.Add(2*time.Hour), powerv1.DemandResponseSpec{
+			CapacityReductionPercent: 25,
+			MaxFrequency:             2400,
+		}),
+		demandResponse("east", now.Add(-time.Hour), now.Add(time.Hour), powerv1.DemandResponseSpec{
+			NodeSelector:             map[string]string{"zone": "east"},
+			PowerProfiles:            []string{"performance"},
+			CapacityReductionPercent: 50,
+			MaxFrequency:             2800,
+		}),
+		demandResponse("west", now.Add(-time.Hour), now.Add(time.Hour), powerv1.DemandResponseSpec{
+			NodeSelector:             map[string]string{"zone": "west"},
+			CapacityReductionPercent: 100,
+		}),
+		demandResponse("tonight", now.Add(30*time.Minute), now.Add(3*time.Hour), powerv1.DemandResponseSpec{
+			MaxFrequency: 1800,
+		}),
+		demandResponse("yesterday", now.Add(-48*time.Hour), now.Add(-24*time.Hour), powerv1.DemandResponseSpec{
+			MaxFrequency: 1000,
+		}),
+	}
+	r, err := createDemandResponseReconcilerObject(objs)
+	assert.NoError(t, err)
+
+	reduction, next, err := activeDemandReduction(context.TODO(), r.Client, "TestNode", "performance", now)
+	assert.NoError(t, err)
+	assert.Equal(t, demandReduction{capacityPercent: 50, maxFrequency: 2400}, reduction)
+	assert.Equal(t, 30*time.Minute, next)
+
+	reduction, next, err = activeDemandReduction(context.TODO(), r.Client, "TestNode", "balance-performance", now)
+	assert.NoError(t, err)
+	assert.Equal(t, demandReduction{capacityPercent: 25, maxFrequency: 2400}, reduction)
+	assert.Equal(t, 30*time.Minute, next)
+
+	// the reductions are lifted once the events end
+	reduction, next, err = activeDemandReduction(context.TODO(), r.Client, "TestNode", "performance", now.Add(4*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, demandReduction{}, reduction)
+	assert.Zero(t, next)
+}
+
+func TestDemandResponseCapacity(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeName,
+		},
+	}
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{
+			Name:     "performance",
+			Epp:      "performance",
+			Capacity: &powerv1.ProfileCapacity{Count: 1},
+		},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{node})
+	assert.NoError(t, err)
+	logger := r.Log
+	capacity := func() int64 {
+		updated := &corev1.Node{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, updated))
+		quantity := updated.Status.Capacity[corev1.ResourceName(ExtendedResourcePrefix+"performance")]
+		return quantity.Value()
+	}
+
+	// the withheld share is rounded in favour of the reduction
+	assert.NoError(t, r.createExtendedResources(context.TODO(), nodeName, profile, 50, &logger, nil))
+	assert.Equal(t, int64(0), capacity())
+
+	// and restored once the event ends
+	assert.NoError(t, r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, nil))
+	assert.Equal(t, int64(1), capacity())
+}
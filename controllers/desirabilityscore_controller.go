@@ -0,0 +1,221 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/thermal"
+)
+
+const (
+	// ScoreLabelPrefix is followed by the PowerProfile's name in the labels holding the Node's score for it
+	ScoreLabelPrefix = "score.power.intel.com/"
+
+	defaultDesirabilityInterval = time.Minute
+	defaultMaxTemperature       = 100
+	defaultFullMarginDegrees    = 30
+)
+
+// DesirabilityScoreReconciler labels this Node, under the PowerConfig's desirabilityScores, with a score from 0 to
+// 100 for each PowerProfile it has a pool for. Pods that prefer Nodes with a high score through nodeAffinity land
+// where the profile has the most room left and the cores run coolest
+type DesirabilityScoreReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	Sensor       thermal.Sensor
+}
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerconfigs,verbs=get;list;watch
+
+// Start scores the Node on the interval of the desirabilityScores until the context is cancelled
+func (r *DesirabilityScoreReconciler) Start(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			interval, err := r.Score(ctx)
+			if err != nil {
+				r.Log.Error(err, "error scoring the Node")
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent scores its own Node
+func (r *DesirabilityScoreReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Score updates the Node's score labels once and returns how long to wait before the next update. The labels are
+// removed when no PowerConfig asks for them
+func (r *DesirabilityScoreReconciler) Score(ctx context.Context) (time.Duration, error) {
+	logger := r.Log.WithName("desirabilityScore")
+	nodeName := os.Getenv("NODE_NAME")
+
+	policy, err := r.desirabilityConfig(ctx)
+	if err != nil {
+		return defaultDesirabilityInterval, err
+	}
+	node := &corev1.Node{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: nodeName}, node)
+	if err != nil {
+		return defaultDesirabilityInterval, err
+	}
+	if policy == nil {
+		return defaultDesirabilityInterval, r.setScoreLabels(ctx, node, nil)
+	}
+	interval := defaultDesirabilityInterval
+	if policy.IntervalSeconds > 0 {
+		interval = time.Duration(policy.IntervalSeconds) * time.Second
+	}
+
+	thermalScale := 1.0
+	temperatures, err := r.Sensor.Temperatures(r.PowerLibrary.GetAllCpus().IDs())
+	if err != nil {
+		logger.Error(err, "error reading core temperatures, scoring without them")
+	} else if len(temperatures) > 0 {
+		hottest := 0.0
+		for _, temperature := range temperatures {
+			hottest = math.Max(hottest, temperature)
+		}
+		thermalScale = thermalMarginScale(policy, hottest)
+	}
+
+	scores := make(map[string]string)
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		capacity, advertised := advertisedCores(node, pool.Name())
+		if !advertised {
+			continue
+		}
+		score := desirabilityScore(capacity, int64(len(pool.Cpus().IDs())), thermalScale)
+		scores[pool.Name()] = strconv.Itoa(score)
+	}
+	logger.V(5).Info("Scored the Node", "thermalScale", thermalScale, "scores", scores)
+
+	return interval, r.setScoreLabels(ctx, node, scores)
+}
+
+// desirabilityConfig returns the desirabilityScores of the PowerConfig that has them, nil if none does
+func (r *DesirabilityScoreReconciler) desirabilityConfig(ctx context.Context) (*powerv1.DesirabilityScores, error) {
+	configs := &powerv1.PowerConfigList{}
+	err := r.Client.List(ctx, configs, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs.Items {
+		if config.Spec.DesirabilityScores != nil {
+			return config.Spec.DesirabilityScores, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// setScoreLabels makes the Node's score labels match the scores, removing those of PowerProfiles that have none
+func (r *DesirabilityScoreReconciler) setScoreLabels(ctx context.Context, node *corev1.Node, scores map[string]string) error {
+	patch := client.MergeFrom(node.DeepCopy())
+	changed := false
+	for label := range node.Labels {
+		if !strings.HasPrefix(label, ScoreLabelPrefix) {
+			continue
+		}
+		if _, scored := scores[strings.TrimPrefix(label, ScoreLabelPrefix)]; !scored {
+			delete(node.Labels, label)
+			changed = true
+		}
+	}
+	for profile, score := range scores {
+		label := ScoreLabelPrefix + profile
+		if node.Labels[label] == score {
+			continue
+		}
+		if node.Labels == nil {
+			node.Labels = make(map[string]string)
+		}
+		node.Labels[label] = score
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	return r.Client.Patch(ctx, node, patch)
+}
+
+// advertisedCores is how many CPUs of the PowerProfile the Node advertises, from its Extended Resource or its
+// capacity label, and whether it advertises the profile at all
+func advertisedCores(node *corev1.Node, profile string) (int64, bool) {
+	var capacity int64
+	if quantity, found := node.Status.Capacity[corev1.ResourceName(ExtendedResourcePrefix+profile)]; found {
+		capacity = quantity.Value()
+	} else if label, found := node.Labels[CapacityLabelPrefix+profile]; found {
+		value, err := strconv.ParseInt(label, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		capacity = value
+	} else {
+		return 0, false
+	}
+
+	return capacity, true
+}
+
+// thermalMarginScale is 1 while the hottest core is at least fullMarginDegrees under maxTemperature, falling to 0
+// as it reaches maxTemperature
+func thermalMarginScale(policy *powerv1.DesirabilityScores, hottest float64) float64 {
+	maxTemperature := policy.MaxTemperature
+	if maxTemperature <= 0 {
+		maxTemperature = defaultMaxTemperature
+	}
+	fullMargin := policy.FullMarginDegrees
+	if fullMargin <= 0 {
+		fullMargin = defaultFullMarginDegrees
+	}
+
+	margin := float64(maxTemperature) - hottest
+	return math.Min(math.Max(margin/float64(fullMargin), 0), 1)
+}
+
+// desirabilityScore is the share of the advertised CPUs not yet taken by the PowerProfile's pool, out of 100 and
+// scaled by the thermal margin
+func desirabilityScore(capacity int64, used int64, thermalScale float64) int {
+	if capacity <= 0 || used >= capacity {
+		return 0
+	}
+
+	return int(math.Round(100 * float64(capacity-used) / float64(capacity) * thermalScale))
+}
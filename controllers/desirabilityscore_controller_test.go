@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+func TestDesirabilityScoreReconciler(t *testing.T) {
+	t.Setenv("NODE_NAME", "TestNode")
+	s := scheme.Scheme
+	assert.NoError(t, powerv1.AddToScheme(s))
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "power-config", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerConfigSpec{
+			DesirabilityScores: &powerv1.DesirabilityScores{IntervalSeconds: 30, MaxTemperature: 100, FullMarginDegrees: 40},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "TestNode",
+			Labels: map[string]string{ScoreLabelPrefix + "removed": "50", "rack": "a"},
+		},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{
+				ExtendedResourcePrefix + "performance": resource.MustParse("8"),
+			},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects([]runtime.Object{config, node}...).Build()
+
+	cores := make([]*coreMock, 4)
+	for i := range cores {
+		cores[i] = new(coreMock)
+		cores[i].On("GetID").Return(uint(i))
+	}
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{cores[2], cores[3]})
+	// a pool whose PowerProfile isn't advertised on the Node isn't scored
+	unadvertisedPool := new(poolMock)
+	unadvertisedPool.On("Name").Return("unadvertised")
+	allCpus := power.CpuList{cores[0], cores[1], cores[2], cores[3]}
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetAllCpus").Return(&allCpus)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool, unadvertisedPool})
+
+	sensor := &fakeThermal{temperatures: map[uint]float64{0: 60, 1: 80, 2: 70, 3: 65}}
+	r := &DesirabilityScoreReconciler{
+		Client:       cl,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Sensor:       sensor,
+	}
+
+	// 6 of 8 CPUs left, with the hottest core 20 degrees under the max of a 40 degree full margin
+	interval, err := r.Score(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, interval)
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(node), node))
+	assert.Equal(t, map[string]string{ScoreLabelPrefix + "performance": "38", "rack": "a"}, node.Labels)
+
+	// without desirabilityScores the labels are removed
+	config.Spec.DesirabilityScores = nil
+	assert.NoError(t, cl.Update(context.TODO(), config))
+	interval, err = r.Score(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, defaultDesirabilityInterval, interval)
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(node), node))
+	assert.Equal(t, map[string]string{"rack": "a"}, node.Labels)
+}
+
+func TestDesirabilityScore(t *testing.T) {
+	policy := &powerv1.DesirabilityScores{}
+	assert.Equal(t, 1.0, thermalMarginScale(policy, 50))
+	assert.Equal(t, 0.5, thermalMarginScale(policy, 85))
+	assert.Equal(t, 0.0, thermalMarginScale(policy, 105))
+
+	assert.Equal(t, 100, desirabilityScore(10, 0, 1))
+	assert.Equal(t, 25, desirabilityScore(10, 5, 0.5))
+	assert.Equal(t, 0, desirabilityScore(10, 12, 1))
+	assert.Equal(t, 0, desirabilityScore(0, 0, 1))
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{CapacityLabelPrefix + "performance": "4"}}}
+	capacity, advertised := advertisedCores(node, "performance")
+	assert.True(t, advertised)
+	assert.Equal(t, int64(4), capacity)
+	_, advertised = advertisedCores(node, "balance")
+	assert.False(t, advertised)
+}
@@ -0,0 +1,236 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+	"github.com/intel/kubernetes-power-manager/pkg/freqdomain"
+)
+
+const FrequencyDomainConflictReason = "FrequencyDomainConflict"
+
+// reservedPoolProfile stands for the reserved CPUs in the frequency domains, which keep their own frequency
+const reservedPoolProfile = "reserved"
+
+// DomainReader reports the groups of CPUs whose frequency is scaled together
+type DomainReader interface {
+	Domains() ([]cpuset.CPUSet, error)
+}
+
+// FrequencyDomainReconciler records the Node's frequency domains in its PowerNode and, on every interval, reports
+// on each PowerWorkload the domains its cores share with cores of a different PowerProfile, as those cores can't
+// run at the frequencies of both
+type FrequencyDomainReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	Domains      DomainReader
+	Recorder     record.EventRecorder
+	Interval     time.Duration
+
+	domains []cpuset.CPUSet
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloads/status,verbs=get;update;patch
+
+// Start reads the frequency domains once, then checks the PowerWorkloads against them on every interval until the
+// context is cancelled. Nodes whose cores are all scaled on their own have nothing to check
+func (r *FrequencyDomainReconciler) Start(ctx context.Context) error {
+	domains, err := r.Domains.Domains()
+	if err != nil {
+		r.Log.Error(err, "error reading the frequency domains, not checking them")
+		return nil
+	}
+	r.domains = domains
+	if len(r.domains) == 0 {
+		r.Log.Info("every core's frequency is scaled on its own, not checking frequency domains")
+		return nil
+	}
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := r.Check(ctx)
+			if err != nil {
+				r.Log.Error(err, "error checking frequency domains")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent has to look after its own Node
+func (r *FrequencyDomainReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Check records the frequency domains in the PowerNode and the conflicting ones in the status of each exclusive
+// PowerWorkload on this Node, raising a warning event when a PowerWorkload gains a conflict
+func (r *FrequencyDomainReconciler) Check(ctx context.Context) error {
+	logger := r.Log.WithName("frequencyDomain")
+	nodeName := os.Getenv("NODE_NAME")
+
+	err := r.updateNodeDomains(ctx, nodeName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	conflicts := freqdomain.Conflicts(r.domains, r.cpuProfiles())
+
+	workloads := &powerv1.PowerWorkloadList{}
+	logger.V(5).Info("Retrieving PowerWorkloadList")
+	err = r.Client.List(ctx, workloads, client.InNamespace(IntelPowerNamespace), client.MatchingFields{WorkloadNodeNameIndex: nodeName})
+	if err != nil {
+		return err
+	}
+
+	results := new(multierror.Error)
+	for i := range workloads.Items {
+		workload := &workloads.Items[i]
+		if workload.Spec.AllCores {
+			continue
+		}
+		cpus := make([]int, 0, len(workload.Spec.Node.CpuIds))
+		for _, cpu := range workload.Spec.Node.CpuIds {
+			cpus = append(cpus, int(cpu))
+		}
+		workloadConflicts := workloadDomainConflicts(conflicts, cpuset.NewCPUSet(cpus...))
+		if reflect.DeepEqual(workloadConflicts, workload.Status.FrequencyDomainConflicts) {
+			continue
+		}
+
+		previous := make(map[string]bool)
+		for _, conflict := range workload.Status.FrequencyDomainConflicts {
+			previous[conflict.CPUs] = true
+		}
+		err = r.setWorkloadConflicts(ctx, client.ObjectKeyFromObject(workload), workloadConflicts)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("updating PowerWorkload %s: %w", workload.Name, err))
+			continue
+		}
+		for _, conflict := range workloadConflicts {
+			if previous[conflict.CPUs] {
+				continue
+			}
+			logger.Info("cores share a frequency domain with another PowerProfile", "workload", workload.Name, "cpus", conflict.CPUs, "profiles", conflict.Profiles)
+			if r.Recorder != nil {
+				r.Recorder.Event(workload, corev1.EventTypeWarning, FrequencyDomainConflictReason,
+					fmt.Sprintf("Cores %s share a frequency domain, their PowerProfiles %s can't be applied independently", conflict.CPUs, strings.Join(conflict.Profiles, ", ")))
+			}
+		}
+	}
+
+	return results.ErrorOrNil()
+}
+
+// cpuProfiles is the PowerProfile of each CPU in the Power Library's pools, the reserved CPUs counting as one
+func (r *FrequencyDomainReconciler) cpuProfiles() map[uint]string {
+	profiles := make(map[uint]string)
+	for _, id := range r.PowerLibrary.GetReservedPool().Cpus().IDs() {
+		profiles[id] = reservedPoolProfile
+	}
+	sharedPool := r.PowerLibrary.GetSharedPool()
+	sharedProfile := sharedPool.Name()
+	if profile := sharedPool.GetPowerProfile(); profile != nil {
+		sharedProfile = profile.Name()
+	}
+	for _, id := range sharedPool.Cpus().IDs() {
+		profiles[id] = sharedProfile
+	}
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		for _, id := range pool.Cpus().IDs() {
+			profiles[id] = pool.Name()
+		}
+	}
+
+	return profiles
+}
+
+func (r *FrequencyDomainReconciler) updateNodeDomains(ctx context.Context, nodeName string) error {
+	domains := make([]string, 0, len(r.domains))
+	for _, domain := range r.domains {
+		domains = append(domains, domain.String())
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(ctx, client.ObjectKey{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		}, powerNode)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(powerNode.Status.FrequencyDomains, domains) {
+			return nil
+		}
+
+		powerNode.Status.FrequencyDomains = domains
+		return r.Client.Status().Update(ctx, powerNode)
+	})
+}
+
+func (r *FrequencyDomainReconciler) setWorkloadConflicts(ctx context.Context, key client.ObjectKey, conflicts []powerv1.FrequencyDomainConflict) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		workload := &powerv1.PowerWorkload{}
+		err := r.Client.Get(ctx, key, workload)
+		if err != nil {
+			return err
+		}
+
+		workload.Status.FrequencyDomainConflicts = conflicts
+		return r.Client.Status().Update(ctx, workload)
+	})
+}
+
+// workloadDomainConflicts returns the conflicts whose domain holds any of the cpus, nil if there are none
+func workloadDomainConflicts(conflicts []freqdomain.Conflict, cpus cpuset.CPUSet) []powerv1.FrequencyDomainConflict {
+	var workloadConflicts []powerv1.FrequencyDomainConflict
+	for _, conflict := range conflicts {
+		shared := conflict.Domain.Intersection(cpus)
+		if shared.IsEmpty() {
+			continue
+		}
+		workloadConflicts = append(workloadConflicts, powerv1.FrequencyDomainConflict{
+			CPUs:     conflict.Domain.String(),
+			Profiles: conflict.Profiles,
+		})
+	}
+
+	return workloadConflicts
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+	"github.com/intel/kubernetes-power-manager/pkg/freqdomain"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFrequencyDomainReader(t *testing.T) {
+	cpuPath := t.TempDir()
+	policies := map[string]string{
+		"cpu0": "0\n",
+		"cpu1": "1\n",
+		"cpu2": "2 3 4 5\n",
+		"cpu3": "2 3 4 5\n",
+		"cpu4": "2 3 4 5\n",
+		"cpu5": "2 3 4 5\n",
+	}
+	for cpu, related := range policies {
+		dir := filepath.Join(cpuPath, cpu, "cpufreq")
+		assert.NoError(t, os.MkdirAll(dir, 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "related_cpus"), []byte(related), 0644))
+	}
+
+	domains, err := (&freqdomain.Reader{CpuPath: cpuPath}).Domains()
+	assert.NoError(t, err)
+	assert.Len(t, domains, 1)
+	assert.Equal(t, "2-5", domains[0].String())
+
+	_, err = (&freqdomain.Reader{CpuPath: t.TempDir()}).Domains()
+	assert.Error(t, err)
+
+	conflicts := freqdomain.Conflicts(domains, map[uint]string{2: "performance", 3: "performance", 4: "shared"})
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, []string{"performance", "shared"}, conflicts[0].Profiles)
+	assert.Empty(t, freqdomain.Conflicts(domains, map[uint]string{2: "performance", 3: "performance"}))
+}
+
+func TestFrequencyDomainReconciler_Check(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	workload := func(profile string, cpus ...uint) *powerv1.PowerWorkload {
+		return &powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: profile + "-TestNode", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				Name:         profile + "-TestNode",
+				PowerProfile: profile,
+				Node:         powerv1.WorkloadNode{Name: nodeName, CpuIds: cpus},
+			},
+		}
+	}
+	objs := []runtime.Object{
+		&powerv1.PowerNode{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: IntelPowerNamespace}},
+		workload("performance", 4),
+		workload("balance-power", 6, 7),
+	}
+	schm := runtime.NewScheme()
+	assert.NoError(t, powerv1.AddToScheme(schm))
+	c := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(schm).
+		WithIndex(&powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer).Build()
+
+	cores := make([]*coreMock, 8)
+	for i := range cores {
+		cores[i] = new(coreMock)
+		cores[i].On("GetID").Return(uint(i))
+	}
+	reservedPool := new(poolMock)
+	reservedPool.On("Cpus").Return(&power.CpuList{cores[0]})
+	sharedProfile := new(profMock)
+	sharedProfile.On("Name").Return("shared")
+	sharedPool := new(poolMock)
+	sharedPool.On("Name").Return("sharedPool")
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	sharedPool.On("Cpus").Return(&power.CpuList{cores[1], cores[5]})
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{cores[4]})
+	balancePool := new(poolMock)
+	balancePool.On("Name").Return("balance-power")
+	balancePool.On("Cpus").Return(&power.CpuList{cores[6], cores[7]})
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetReservedPool").Return(reservedPool)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool, balancePool})
+
+	recorder := record.NewFakeRecorder(10)
+	r := &FrequencyDomainReconciler{
+		Client:       c,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Recorder:     recorder,
+		domains:      []cpuset.CPUSet{cpuset.NewCPUSet(4, 5), cpuset.NewCPUSet(6, 7)},
+	}
+	assert.NoError(t, r.Check(context.Background()))
+
+	powerNode := &powerv1.PowerNode{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode))
+	assert.Equal(t, []string{"4-5", "6-7"}, powerNode.Status.FrequencyDomains)
+
+	performance := &powerv1.PowerWorkload{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}, performance))
+	assert.Equal(t, []powerv1.FrequencyDomainConflict{{CPUs: "4-5", Profiles: []string{"performance", "shared"}}}, performance.Status.FrequencyDomainConflicts)
+	balance := &powerv1.PowerWorkload{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "balance-power-TestNode", Namespace: IntelPowerNamespace}, balance))
+	assert.Empty(t, balance.Status.FrequencyDomainConflicts)
+	assert.Len(t, recorder.Events, 1)
+
+	// a conflict already reported isn't raised again
+	assert.NoError(t, r.Check(context.Background()))
+	assert.Len(t, recorder.Events, 1)
+
+	// the conflict clears once the shared core leaves the domain
+	sharedPool.ExpectedCalls = nil
+	sharedPool.On("Name").Return("sharedPool")
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	sharedPool.On("Cpus").Return(&power.CpuList{cores[1]})
+	assert.NoError(t, r.Check(context.Background()))
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}, performance))
+	assert.Empty(t, performance.Status.FrequencyDomainConflicts)
+}
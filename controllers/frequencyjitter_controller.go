@@ -0,0 +1,194 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetry"
+)
+
+// FrequencyJitterReason is the reason of the event recorded when a PowerWorkload becomes Degraded by jitter
+const FrequencyJitterReason = "FrequencyJitter"
+
+// FrequencyJitterReconciler samples the frequency of the busy CPUs of every exclusive pool on this Node, publishes how
+// much it varies over the window and marks the PowerWorkloads of pools whose PowerProfile pins the frequency
+// Degraded while it varies by more than the threshold, as thermal or power limits are then overriding the profile
+type FrequencyJitterReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	Source       telemetry.Source
+	Tracker      *telemetry.JitterTracker
+	Interval     time.Duration
+	// Threshold is the standard deviation, relative to the mean frequency, a pinned pool is Degraded above
+	Threshold float64
+	Recorder  record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloads/status,verbs=get;update;patch
+
+// Start samples the pools on every interval until the context is cancelled
+func (r *FrequencyJitterReconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			err := r.Sample(ctx, now)
+			if err != nil {
+				r.Log.Error(err, "error measuring frequency jitter")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent measures its own Node
+func (r *FrequencyJitterReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Sample adds the frequency of each exclusive pool's busy CPUs to the window, then publishes the pools' jitter and
+// updates the Degraded condition of their PowerWorkloads
+func (r *FrequencyJitterReconciler) Sample(ctx context.Context, now time.Time) error {
+	nodeName := os.Getenv("NODE_NAME")
+	results := new(multierror.Error)
+
+	pools := make(map[string]power.Pool)
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		samples, err := r.Source.Read(pool.Cpus().IDs())
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("sampling pool %s: %w", pool.Name(), err))
+			continue
+		}
+		r.Tracker.Add(pool.Name(), now, samples)
+		pools[pool.Name()] = pool
+	}
+	for _, name := range r.Tracker.Pools() {
+		if _, exists := pools[name]; !exists {
+			r.Tracker.Forget(name)
+			metrics.PoolFrequencyJitter.DeleteLabelValues(nodeName, name)
+			metrics.PoolFrequencyJitterRatio.DeleteLabelValues(nodeName, name)
+		}
+	}
+
+	workloads := &powerv1.PowerWorkloadList{}
+	err := r.Client.List(ctx, workloads, client.InNamespace(IntelPowerNamespace), client.MatchingFields{WorkloadNodeNameIndex: nodeName})
+	if err != nil {
+		return multierror.Append(results, err).ErrorOrNil()
+	}
+	for name, pool := range pools {
+		jitter, measured := r.Tracker.Jitter(name)
+		if !measured {
+			continue
+		}
+		metrics.PoolFrequencyJitter.WithLabelValues(nodeName, name).Set(jitter.StdDev)
+		metrics.PoolFrequencyJitterRatio.WithLabelValues(nodeName, name).Set(jitter.Ratio())
+
+		condition := r.degradedCondition(pool, jitter)
+		for i := range workloads.Items {
+			workload := &workloads.Items[i]
+			if workload.Spec.AllCores || workload.Spec.PowerProfile != name {
+				continue
+			}
+			err = r.setDegraded(ctx, workload, condition)
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("updating PowerWorkload %s: %w", workload.Name, err))
+			}
+		}
+	}
+
+	return results.ErrorOrNil()
+}
+
+// degradedCondition is the Degraded condition of the pool's PowerWorkloads, nil when the pool's PowerProfile lets the
+// frequency scale so jitter is expected
+func (r *FrequencyJitterReconciler) degradedCondition(pool power.Pool, jitter telemetry.Jitter) *metav1.Condition {
+	profile := pool.GetPowerProfile()
+	if profile == nil || profile.MaxFreq() == 0 || profile.MinFreq() != profile.MaxFreq() {
+		return nil
+	}
+	window := r.Tracker.Window.String()
+	if jitter.Ratio() > r.Threshold {
+		return &metav1.Condition{
+			Type:   powerv1.ConditionDegraded,
+			Status: metav1.ConditionTrue,
+			Reason: powerv1.ReasonFrequencyJitter,
+			Message: fmt.Sprintf("Frequency of the busy CPUs varied by %.0f MHz (%.1f%%) around %.0f MHz over %s while pinned at %d MHz, thermal or power limits are throttling them",
+				jitter.StdDev, jitter.Ratio()*100, jitter.Mean, window, profile.MaxFreq()),
+		}
+	}
+
+	return &metav1.Condition{
+		Type:    powerv1.ConditionDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  powerv1.ReasonFrequencySteady,
+		Message: fmt.Sprintf("Frequency of the busy CPUs held at %.0f MHz over %s", jitter.Mean, window),
+	}
+}
+
+// setDegraded sets or, without a condition, removes the PowerWorkload's Degraded condition, recording an event when
+// it becomes Degraded. The message is only updated with the status so the jitter figures don't rewrite it every sample
+func (r *FrequencyJitterReconciler) setDegraded(ctx context.Context, workload *powerv1.PowerWorkload, condition *metav1.Condition) error {
+	current := meta.FindStatusCondition(workload.Status.Conditions, powerv1.ConditionDegraded)
+	if condition == nil && current == nil || condition != nil && current != nil && current.Status == condition.Status {
+		return nil
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &powerv1.PowerWorkload{}
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(workload), latest)
+		if err != nil {
+			return err
+		}
+		if condition == nil {
+			meta.RemoveStatusCondition(&latest.Status.Conditions, powerv1.ConditionDegraded)
+		} else {
+			meta.SetStatusCondition(&latest.Status.Conditions, *condition)
+		}
+		return r.Client.Status().Update(ctx, latest)
+	})
+	if err != nil {
+		return err
+	}
+	if condition != nil && condition.Status == metav1.ConditionTrue {
+		r.Log.Info("PowerWorkload degraded by frequency jitter", "workload", workload.Name, "message", condition.Message)
+		if r.Recorder != nil {
+			r.Recorder.Event(workload, corev1.EventTypeWarning, FrequencyJitterReason, condition.Message)
+		}
+	}
+
+	return nil
+}
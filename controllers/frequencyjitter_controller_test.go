@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetry"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestJitterTracker(t *testing.T) {
+	tracker := telemetry.NewJitterTracker(time.Minute)
+	start := time.Now()
+	tracker.Add("performance", start, []telemetry.Sample{{Core: 2, Utilization: 0.9, Frequency: 3000}, {Core: 3, Utilization: 0.1, Frequency: 800}})
+	_, measured := tracker.Jitter("performance")
+	assert.False(t, measured)
+
+	tracker.Add("performance", start.Add(10*time.Second), []telemetry.Sample{{Core: 2, Utilization: 0.9, Frequency: 2600}})
+	tracker.Add("performance", start.Add(20*time.Second), []telemetry.Sample{{Core: 2, Utilization: 0.9, Frequency: 3400}})
+	jitter, measured := tracker.Jitter("performance")
+	assert.True(t, measured)
+	assert.Equal(t, 3, jitter.Samples)
+	assert.InDelta(t, 3000, jitter.Mean, 0.1)
+	assert.InDelta(t, 326.6, jitter.StdDev, 0.1)
+
+	// the samples fall out of the window
+	tracker.Add("performance", start.Add(2*time.Minute), nil)
+	_, measured = tracker.Jitter("performance")
+	assert.False(t, measured)
+}
+
+func TestFrequencyJitterReconciler_Sample(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance-TestNode", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerWorkloadSpec{
+			Name:         "performance-TestNode",
+			PowerProfile: "performance",
+			Node:         powerv1.WorkloadNode{Name: nodeName, CpuIds: []uint{2}},
+		},
+	}
+	schm := runtime.NewScheme()
+	assert.NoError(t, powerv1.AddToScheme(schm))
+	c := fake.NewClientBuilder().WithRuntimeObjects(workload).WithScheme(schm).
+		WithIndex(&powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer).Build()
+
+	core2 := new(coreMock)
+	core2.On("GetID").Return(uint(2))
+	profile := new(profMock)
+	profile.On("MinFreq").Return(uint(3000))
+	profile.On("MaxFreq").Return(uint(3000))
+	pool := new(poolMock)
+	pool.On("Name").Return("performance")
+	pool.On("Cpus").Return(&power.CpuList{core2})
+	pool.On("GetPowerProfile").Return(profile)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{pool})
+
+	source := &fakeTelemetrySource{samples: make(map[uint]telemetry.Sample)}
+	recorder := record.NewFakeRecorder(10)
+	r := &FrequencyJitterReconciler{
+		Client:       c,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Source:       source,
+		Tracker:      telemetry.NewJitterTracker(time.Minute),
+		Threshold:    telemetry.DefaultJitterThreshold,
+		Recorder:     recorder,
+	}
+	degraded := func() *metav1.Condition {
+		current := &powerv1.PowerWorkload{}
+		assert.NoError(t, c.Get(context.TODO(), client.ObjectKeyFromObject(workload), current))
+		return meta.FindStatusCondition(current.Status.Conditions, powerv1.ConditionDegraded)
+	}
+	sample := func(now time.Time, frequencies ...uint) {
+		for _, frequency := range frequencies {
+			source.samples[2] = telemetry.Sample{Core: 2, Utilization: 1, Frequency: frequency}
+			assert.NoError(t, r.Sample(context.TODO(), now))
+			now = now.Add(time.Second)
+		}
+	}
+
+	// a pinned pool holding its frequency is steady
+	now := time.Now()
+	sample(now, 3000, 2990, 3000)
+	assert.Equal(t, metav1.ConditionFalse, degraded().Status)
+	assert.Equal(t, powerv1.ReasonFrequencySteady, degraded().Reason)
+	assert.Len(t, recorder.Events, 0)
+
+	// throttled below the pinned frequency it's Degraded, with one event
+	sample(now.Add(10*time.Second), 2200, 2400, 2300)
+	assert.Equal(t, metav1.ConditionTrue, degraded().Status)
+	assert.Equal(t, powerv1.ReasonFrequencyJitter, degraded().Reason)
+	assert.Len(t, recorder.Events, 1)
+	sample(now.Add(20*time.Second), 3000)
+	assert.Len(t, recorder.Events, 1)
+
+	// a profile that lets the frequency scale doesn't count jitter against its PowerWorkloads
+	profile.ExpectedCalls = nil
+	profile.On("MinFreq").Return(uint(1000))
+	profile.On("MaxFreq").Return(uint(3000))
+	sample(now.Add(30*time.Second), 1500)
+	assert.Nil(t, degraded())
+}
@@ -0,0 +1,252 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/nicstats"
+	"github.com/intel/kubernetes-power-manager/pkg/util"
+)
+
+// FrequencyFloor raises a CPU's min frequency in MHz above the one its PowerProfile sets
+type FrequencyFloor interface {
+	SetMinFrequency(cpu uint, mhz uint) error
+}
+
+// NetworkBoostReconciler watches the drops of the network interfaces in this Node's networkBoost and, while they
+// are over the threshold, holds every CPU of the pools handling the interfaces' interrupts at its PowerProfile's
+// max frequency. The PowerProfiles themselves are left alone, so a Shared pool step down still lowers the max
+type NetworkBoostReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	Source       nicstats.Source
+	Floor        FrequencyFloor
+	Interval     time.Duration
+
+	previousDrops uint64
+	previousRead  time.Time
+	lastDropping  time.Time
+	// the CPUs currently boosted
+	boosted map[uint]bool
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+
+// Start checks the drops on every interval until the context is cancelled
+func (r *NetworkBoostReconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			err := r.Adjust(ctx, now)
+			if err != nil {
+				r.Log.Error(err, "error adjusting the network boost")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent has to look after its own interfaces
+func (r *NetworkBoostReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Adjust works out the drop rate since the previous read and boosts or restores the pools holding the interfaces'
+// interrupt CPUs. The boost is written on every adjustment as the Power Library puts the PowerProfile's min
+// frequency back whenever it reapplies a pool
+func (r *NetworkBoostReconciler) Adjust(ctx context.Context, now time.Time) error {
+	logger := r.Log.WithName("networkBoost")
+	nodeName := os.Getenv("NODE_NAME")
+	if r.boosted == nil {
+		r.boosted = make(map[uint]bool)
+	}
+
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Name:      nodeName,
+		Namespace: IntelPowerNamespace,
+	}, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	config := powerNode.Spec.NetworkBoost
+
+	pools := r.profiledPools()
+	if config == nil || len(config.Interfaces) == 0 {
+		r.previousRead, r.lastDropping = time.Time{}, time.Time{}
+		metrics.NetworkDropRate.DeleteLabelValues(nodeName)
+		err = r.boost(pools, nil)
+		if err != nil {
+			return err
+		}
+		return r.updateStatus(ctx, nodeName, nil)
+	}
+
+	stats, err := r.Source.Read(config.Interfaces)
+	if err != nil {
+		return err
+	}
+	if !r.previousRead.IsZero() && now.After(r.previousRead) {
+		drops := uint64(0)
+		// counters going backwards were reset, such as by the driver reloading
+		if stats.Drops >= r.previousDrops {
+			drops = stats.Drops - r.previousDrops
+		}
+		rate := float64(drops) / now.Sub(r.previousRead).Seconds()
+		metrics.NetworkDropRate.WithLabelValues(nodeName).Set(rate)
+		if rate > float64(config.DropsPerSecond) {
+			if r.lastDropping.IsZero() {
+				logger.Info("Network interfaces are dropping packets, boosting the pools handling their interrupts", "dropsPerSecond", rate, "cpus", stats.IRQCPUs)
+			}
+			r.lastDropping = now
+		}
+		logger.V(5).Info("Network drop rate", "dropsPerSecond", rate, "threshold", config.DropsPerSecond)
+	}
+	r.previousDrops, r.previousRead = stats.Drops, now
+
+	if !r.lastDropping.IsZero() && now.Sub(r.lastDropping) > time.Duration(config.HoldSeconds)*time.Second {
+		logger.Info("Network drops stopped, restoring the boosted pools")
+		r.lastDropping = time.Time{}
+	}
+
+	boostedPools := make([]power.Pool, 0)
+	if !r.lastDropping.IsZero() {
+		for _, pool := range pools {
+			cpus := pool.Cpus().IDs()
+			for _, cpu := range stats.IRQCPUs {
+				if util.CPUInCPUList(cpu, cpus) {
+					boostedPools = append(boostedPools, pool)
+					break
+				}
+			}
+		}
+	}
+	err = r.boost(pools, boostedPools)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(boostedPools))
+	for _, pool := range boostedPools {
+		names = append(names, pool.Name())
+	}
+	sort.Strings(names)
+	return r.updateStatus(ctx, nodeName, names)
+}
+
+// boost holds the CPUs of the boosted pools at their max frequency and gives every other CPU that was boosted
+// back the min frequency of the pool it is in now
+func (r *NetworkBoostReconciler) boost(pools []power.Pool, boostedPools []power.Pool) error {
+	results := new(multierror.Error)
+	active := make(map[uint]bool)
+	for _, pool := range boostedPools {
+		profile := pool.GetPowerProfile()
+		for _, cpu := range pool.Cpus().IDs() {
+			err := r.Floor.SetMinFrequency(cpu, profile.MaxFreq())
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("boosting cpu %d: %w", cpu, err))
+				continue
+			}
+			active[cpu] = true
+			r.boosted[cpu] = true
+		}
+	}
+
+	minFrequencies := make(map[uint]uint)
+	for _, pool := range pools {
+		for _, cpu := range pool.Cpus().IDs() {
+			minFrequencies[cpu] = pool.GetPowerProfile().MinFreq()
+		}
+	}
+	for cpu := range r.boosted {
+		if active[cpu] {
+			continue
+		}
+		// a CPU no longer in a profiled pool gets its frequencies from wherever it went
+		if minFrequency, found := minFrequencies[cpu]; found {
+			err := r.Floor.SetMinFrequency(cpu, minFrequency)
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("restoring cpu %d: %w", cpu, err))
+				continue
+			}
+		}
+		delete(r.boosted, cpu)
+	}
+
+	return results.ErrorOrNil()
+}
+
+// profiledPools returns the Shared and exclusive pools that currently have a PowerProfile applied
+func (r *NetworkBoostReconciler) profiledPools() []power.Pool {
+	pools := make([]power.Pool, 0)
+	if sharedPool := r.PowerLibrary.GetSharedPool(); sharedPool.GetPowerProfile() != nil {
+		pools = append(pools, sharedPool)
+	}
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		if pool.GetPowerProfile() != nil {
+			pools = append(pools, pool)
+		}
+	}
+
+	return pools
+}
+
+func (r *NetworkBoostReconciler) updateStatus(ctx context.Context, nodeName string, boostedPools []string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(ctx, client.ObjectKey{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		}, powerNode)
+		if err != nil {
+			return err
+		}
+		if len(boostedPools) == 0 {
+			boostedPools = nil
+		}
+		if reflect.DeepEqual(powerNode.Status.NetworkBoostedPools, boostedPools) {
+			return nil
+		}
+
+		powerNode.Status.NetworkBoostedPools = boostedPools
+		return r.Client.Status().Update(ctx, powerNode)
+	})
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/nicstats"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type nicSourceMock struct {
+	stats nicstats.Stats
+}
+
+func (m *nicSourceMock) Read(interfaces []string) (nicstats.Stats, error) {
+	return m.stats, nil
+}
+
+type frequencyFloorMock struct {
+	minFreqs map[uint]uint
+}
+
+func (m *frequencyFloorMock) SetMinFrequency(cpu uint, mhz uint) error {
+	m.minFreqs[cpu] = mhz
+	return nil
+}
+
+func TestNetworkBoostReconciler_Adjust(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerNodeSpec{
+			NodeName: nodeName,
+			NetworkBoost: &powerv1.NetworkBoost{
+				Interfaces:     []string{"ens1f0"},
+				DropsPerSecond: 100,
+				HoldSeconds:    30,
+			},
+		},
+	}
+	s := scheme.Scheme
+	assert.NoError(t, powerv1.AddToScheme(s))
+	cl := fake.NewClientBuilder().WithRuntimeObjects([]runtime.Object{powerNode}...).WithScheme(s).Build()
+
+	sharedProfile := new(profMock)
+	sharedProfile.On("MaxFreq").Return(uint(2800))
+	sharedProfile.On("MinFreq").Return(uint(1000))
+	exclusiveProfile := new(profMock)
+	exclusiveProfile.On("MaxFreq").Return(uint(3500))
+	exclusiveProfile.On("MinFreq").Return(uint(3000))
+
+	cores := make(power.CpuList, 0)
+	for id := uint(0); id < 4; id++ {
+		core := new(coreMock)
+		core.On("GetID").Return(id)
+		cores = append(cores, core)
+	}
+	sharedPool := new(poolMock)
+	sharedPool.On("Name").Return("shared")
+	sharedPool.On("Cpus").Return(&power.CpuList{cores[0], cores[1]})
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	exclusivePool := new(poolMock)
+	exclusivePool.On("Name").Return("performance")
+	exclusivePool.On("Cpus").Return(&power.CpuList{cores[2], cores[3]})
+	exclusivePool.On("GetPowerProfile").Return(exclusiveProfile)
+
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{exclusivePool})
+
+	source := &nicSourceMock{stats: nicstats.Stats{Drops: 1000, IRQCPUs: []uint{1}}}
+	floor := &frequencyFloorMock{minFreqs: make(map[uint]uint)}
+	r := &NetworkBoostReconciler{
+		Client:       cl,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Source:       source,
+		Floor:        floor,
+	}
+	status := func() powerv1.PowerNodeStatus {
+		node := &powerv1.PowerNode{}
+		err := cl.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), node)
+		assert.NoError(t, err)
+		return node.Status
+	}
+
+	// the first read only primes the counters
+	now := time.Now()
+	assert.NoError(t, r.Adjust(context.TODO(), now))
+	assert.Empty(t, floor.minFreqs)
+
+	// 600 drops in 5 seconds, the Shared pool holding the interrupt CPU runs at its max
+	source.stats.Drops = 1600
+	now = now.Add(5 * time.Second)
+	assert.NoError(t, r.Adjust(context.TODO(), now))
+	assert.Equal(t, map[uint]uint{0: 2800, 1: 2800}, floor.minFreqs)
+	assert.Equal(t, []string{"shared"}, status().NetworkBoostedPools)
+
+	// the drops stop, the boost holds until HoldSeconds have passed
+	now = now.Add(20 * time.Second)
+	assert.NoError(t, r.Adjust(context.TODO(), now))
+	assert.Equal(t, []string{"shared"}, status().NetworkBoostedPools)
+
+	now = now.Add(20 * time.Second)
+	assert.NoError(t, r.Adjust(context.TODO(), now))
+	assert.Equal(t, map[uint]uint{0: 1000, 1: 1000}, floor.minFreqs)
+	assert.Empty(t, status().NetworkBoostedPools)
+
+	// counters going backwards are a reset, not drops
+	source.stats.Drops = 10
+	now = now.Add(5 * time.Second)
+	assert.NoError(t, r.Adjust(context.TODO(), now))
+	assert.Empty(t, status().NetworkBoostedPools)
+}
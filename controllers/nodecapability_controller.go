@@ -0,0 +1,100 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+// NodeCapabilityReconciler records in this Node's PowerNode status whether the Node Agent runs in a VM and which
+// power controls the Node doesn't provide, the controls are probed once when the Node Agent starts. The model of
+// the Node's CPUs is recorded with them, nil if it couldn't be read
+type NodeCapabilityReconciler struct {
+	client.Client
+	Log                 logr.Logger
+	Virtualized         bool
+	UnavailableControls []string
+	CPUModel            *powerv1.CPUModel
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+
+func (r *NodeCapabilityReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("nodecapability", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+	if req.Name != os.Getenv("NODE_NAME") {
+		// PowerNode is not on this Node
+		return ctrl.Result{}, nil
+	}
+
+	profile := powerv1.CapabilityProfileBareMetal
+	if r.Virtualized {
+		profile = powerv1.CapabilityProfileVirtualized
+	}
+	unavailable := r.UnavailableControls
+	if len(unavailable) == 0 {
+		unavailable = nil
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(c, req.NamespacedName, powerNode)
+		if err != nil {
+			return err
+		}
+		if powerNode.Status.CapabilityProfile == profile && reflect.DeepEqual(powerNode.Status.UnavailableControls, unavailable) &&
+			reflect.DeepEqual(powerNode.Status.CPUModel, r.CPUModel) {
+			return nil
+		}
+
+		powerNode.Status.CapabilityProfile = profile
+		powerNode.Status.UnavailableControls = unavailable
+		powerNode.Status.CPUModel = r.CPUModel
+		return r.Client.Status().Update(c, powerNode)
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error updating PowerNode status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *NodeCapabilityReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("nodecapability").
+		For(&powerv1.PowerNode{}).
+		Complete(r)
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestNodeCapabilityReconciler(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	tcases := []struct {
+		testCase            string
+		virtualized         bool
+		unavailableControls []string
+		expectedProfile     string
+	}{
+		{
+			testCase:        "Test Case 1 - bare metal with every control",
+			expectedProfile: powerv1.CapabilityProfileBareMetal,
+		},
+		{
+			testCase:            "Test Case 2 - VM without MSR and with capped cpufreq",
+			virtualized:         true,
+			unavailableControls: []string{"MSR", "cpufreq (capped at 2100000 kHz)"},
+			expectedProfile:     powerv1.CapabilityProfileVirtualized,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		powerNode := &powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nodeName,
+				Namespace: IntelPowerNamespace,
+			},
+		}
+		s := scheme.Scheme
+		assert.NoError(t, powerv1.AddToScheme(s))
+		cl := fake.NewClientBuilder().WithScheme(s).WithObjects(powerNode).Build()
+		r := &NodeCapabilityReconciler{
+			Client:              cl,
+			Log:                 ctrl.Log.WithName("testing"),
+			Virtualized:         tc.virtualized,
+			UnavailableControls: tc.unavailableControls,
+		}
+
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(powerNode)}
+		_, err := r.Reconcile(context.TODO(), req)
+		assert.NoError(t, err)
+
+		updatedNode := &powerv1.PowerNode{}
+		assert.NoError(t, cl.Get(context.TODO(), req.NamespacedName, updatedNode))
+		assert.Equal(t, tc.expectedProfile, updatedNode.Status.CapabilityProfile)
+		assert.Equal(t, tc.unavailableControls, updatedNode.Status.UnavailableControls)
+	}
+
+	// PowerNodes of other Nodes are left to their own Node Agents
+	r := &NodeCapabilityReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), Log: ctrl.Log.WithName("testing")}
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "OtherNode", Namespace: IntelPowerNamespace}})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,160 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	"github.com/intel/kubernetes-power-manager/pkg/state"
+)
+
+// ExcludeNodeKey exempts a Node from power management even when the PowerConfig selects it, as an annotation
+// or as the key of a taint. An annotation set to "false" doesn't exclude the Node
+const ExcludeNodeKey = "power.intel.com/exclude"
+
+// NodeExclusionReconciler reverts this Node to its default power settings once it's excluded, the other Node
+// Agent controllers leave it alone until the exclusion is removed
+type NodeExclusionReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	// Handoff's state file is discarded, nil if the handoff is disabled
+	Handoff *PoolHandoffReconciler
+
+	excluded bool
+}
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+func (r *NodeExclusionReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("node", req.Name)
+	changes := logging.NewChangeSummary()
+	defer changes.Log(logger)
+
+	node := &corev1.Node{}
+	err := r.Client.Get(c, req.NamespacedName, node)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	excluded := isExcludedNode(node)
+	switch {
+	case excluded && !r.excluded:
+		logger.Info("Node excluded from power management, reverting it to its default power settings")
+		err = (&NodeCleanupReconciler{Client: r.Client, PowerLibrary: r.PowerLibrary, Handoff: r.Handoff}).revert(c, node.Name, changes)
+		if err != nil {
+			logger.Error(err, "error reverting the excluded Node")
+			return ctrl.Result{}, err
+		}
+		r.excluded = true
+	case !excluded && r.excluded:
+		logger.Info("Node no longer excluded, resuming power management")
+		r.excluded = false
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *NodeExclusionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("nodeexclusion").
+		For(&corev1.Node{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetName() == os.Getenv("NODE_NAME")
+		}))).
+		Complete(r)
+}
+
+func isExcludedNode(node *corev1.Node) bool {
+	if value, found := node.Annotations[ExcludeNodeKey]; found && value != "false" {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == ExcludeNodeKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeExcluded is used by the other Node Agent controllers to leave an excluded Node alone
+func nodeExcluded(c context.Context, cl client.Client, nodeName string) (bool, error) {
+	node := &corev1.Node{}
+	err := cl.Get(c, client.ObjectKey{Name: nodeName}, node)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return isExcludedNode(node), nil
+}
+
+// releaseExcludedNode removes what the Power Manager set up for a Node that was configured before it was excluded:
+// its PowerWorkloads and PowerNode, and the Extended Resources, labels and taints on the Node
+func releaseExcludedNode(c context.Context, cl client.Client, node *corev1.Node, nodeData *state.PowerNodeData, changes *logging.ChangeSummary) error {
+	nodeData.DeletePowerNodeData(node.Name)
+	powerNode := &powerv1.PowerNode{}
+	err := cl.Get(c, client.ObjectKey{Name: node.Name, Namespace: IntelPowerNamespace}, powerNode)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	workloads := &powerv1.PowerWorkloadList{}
+	err = cl.List(c, workloads, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return err
+	}
+	for i := range workloads.Items {
+		workload := &workloads.Items[i]
+		if workload.Spec.Node.Name != node.Name {
+			continue
+		}
+		err = cl.Delete(c, workload)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("deleting PowerWorkload %s: %w", workload.Name, err)
+		}
+		changes.ResourceRemoved("PowerWorkload", workload.Name)
+	}
+
+	err = cl.Delete(c, powerNode)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("deleting PowerNode %s: %w", powerNode.Name, err)
+	}
+	changes.ResourceRemoved("PowerNode", powerNode.Name)
+	_, err = cleanUpNode(c, cl, node)
+	if err != nil {
+		return err
+	}
+	changes.NodeTouched(node.Name)
+
+	return nil
+}
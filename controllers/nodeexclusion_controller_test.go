@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	"github.com/intel/kubernetes-power-manager/pkg/state"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestIsExcludedNode(t *testing.T) {
+	tcases := []struct {
+		testCase string
+		node     *corev1.Node
+		excluded bool
+	}{
+		{
+			testCase: "Test Case 1 - exclude annotation",
+			node:     &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{ExcludeNodeKey: "true"}}},
+			excluded: true,
+		},
+		{
+			testCase: "Test Case 2 - exclude annotation set to false",
+			node:     &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{ExcludeNodeKey: "false"}}},
+			excluded: false,
+		},
+		{
+			testCase: "Test Case 3 - exclude taint",
+			node: &corev1.Node{Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: ExcludeNodeKey, Effect: corev1.TaintEffectNoSchedule},
+			}}},
+			excluded: true,
+		},
+		{
+			testCase: "Test Case 4 - neither",
+			node: &corev1.Node{Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: UnconfiguredTaintKey, Effect: corev1.TaintEffectNoSchedule},
+			}}},
+			excluded: false,
+		},
+	}
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		assert.Equal(t, tc.excluded, isExcludedNode(tc.node), tc.testCase)
+	}
+}
+
+func TestReleaseExcludedNode(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node1",
+			Annotations: map[string]string{ExcludeNodeKey: "true"},
+			Labels:      map[string]string{CapacityLabelPrefix + "performance": "true"},
+		},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{ExtendedResourcePrefix + "performance": resource.MustParse("40")},
+		},
+	}
+	powerNode := &powerv1.PowerNode{ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace}}
+	excludedWorkload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance-node1", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerWorkloadSpec{Node: powerv1.WorkloadNode{Name: "node1"}},
+	}
+	otherWorkload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance-node2", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerWorkloadSpec{Node: powerv1.WorkloadNode{Name: "node2"}},
+	}
+	cl, err := createCleanupClient([]runtime.Object{node, powerNode, excludedWorkload, otherWorkload})
+	assert.NoError(t, err)
+	nodeData := &state.PowerNodeData{PowerNodeList: []string{"node1", "node2"}}
+
+	err = releaseExcludedNode(context.TODO(), cl, node, nodeData, logging.NewChangeSummary())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node2"}, nodeData.PowerNodeList)
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), &powerv1.PowerNode{})
+	assert.True(t, errors.IsNotFound(err))
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(excludedWorkload), &powerv1.PowerWorkload{})
+	assert.True(t, errors.IsNotFound(err))
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(otherWorkload), &powerv1.PowerWorkload{})
+	assert.NoError(t, err)
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(node), node)
+	assert.NoError(t, err)
+	assert.Empty(t, node.Labels)
+	assert.Empty(t, node.Status.Capacity)
+	assert.Contains(t, node.Annotations, ExcludeNodeKey)
+
+	// once released there's nothing left to do
+	err = releaseExcludedNode(context.TODO(), cl, node, nodeData, logging.NewChangeSummary())
+	assert.NoError(t, err)
+}
+
+func TestNodeExclusionReconciler(t *testing.T) {
+	t.Setenv("NODE_NAME", "node1")
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	cl, err := createCleanupClient([]runtime.Object{node})
+	assert.NoError(t, err)
+
+	sharedPool := new(poolMock)
+	sharedPool.On("SetPowerProfile", nil).Return(nil)
+	sharedPool.On("SetCStates", power.CStates(nil)).Return(nil)
+	reservedPool := new(poolMock)
+	reservedPool.On("MoveCpuIDs", mock.Anything).Return(nil)
+	topology := new(mockCpuTopology)
+	topology.On("SetUncore", nil).Return(nil)
+	topology.On("Packages").Return(&[]power.Package{})
+	host := new(hostMock)
+	host.On("GetAllExclusivePools").Return(&power.PoolList{})
+	host.On("GetSharedPool").Return(sharedPool)
+	host.On("GetReservedPool").Return(reservedPool)
+	host.On("GetAllCpus").Return(&power.CpuList{})
+	host.On("Topology").Return(topology)
+	r := &NodeExclusionReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), PowerLibrary: host}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(node)}
+
+	// an included Node is left alone
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	host.AssertNotCalled(t, "GetSharedPool")
+	excluded, err := nodeExcluded(context.TODO(), cl, "node1")
+	assert.NoError(t, err)
+	assert.False(t, excluded)
+
+	// excluding it reverts it once
+	node.Spec.Taints = []corev1.Taint{{Key: ExcludeNodeKey, Effect: corev1.TaintEffectNoSchedule}}
+	assert.NoError(t, cl.Update(context.TODO(), node))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	reservedPool.AssertNumberOfCalls(t, "MoveCpuIDs", 1)
+	excluded, err = nodeExcluded(context.TODO(), cl, "node1")
+	assert.NoError(t, err)
+	assert.True(t, excluded)
+
+	// and removing the exclusion resumes power management
+	node.Spec.Taints = nil
+	assert.NoError(t, cl.Update(context.TODO(), node))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.False(t, r.excluded)
+
+	// a Node that isn't in the cache isn't excluded
+	excluded, err = nodeExcluded(context.TODO(), cl, "node2")
+	assert.NoError(t, err)
+	assert.False(t, excluded)
+}
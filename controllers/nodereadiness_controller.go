@@ -0,0 +1,220 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+// UnconfiguredTaintKey keeps Pods off a Node until its Node Agent has applied the PowerProfiles
+const UnconfiguredTaintKey = "power.intel.com/unconfigured"
+
+// how often the PowerProfiles are checked again while some aren't applied yet
+const nodeReadinessRequeueInterval = 5 * time.Second
+
+// NodeReadinessReconciler removes the unconfigured taint from this Node once every PowerProfile has been applied,
+// whether the PowerConfig or the kubelet's --register-with-taints put it there, and keeps the PowerProfiles still
+// pending in the PowerNode status
+type NodeReadinessReconciler struct {
+	client.Client
+	Log          logr.Logger
+	Scheme       *runtime.Scheme
+	PowerLibrary power.Host
+	// RealtimeKernel is whether the Node runs a PREEMPT_RT kernel, realtime profiles are never applied without one
+	RealtimeKernel bool
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
+
+func (r *NodeReadinessReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("nodereadiness", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+	if req.Name != os.Getenv("NODE_NAME") {
+		// PowerNode is not on this Node
+		return ctrl.Result{}, nil
+	}
+
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(c, req.NamespacedName, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error retrieving the PowerNode")
+		return ctrl.Result{}, err
+	}
+	node := &corev1.Node{}
+	err = r.Client.Get(c, client.ObjectKey{Name: req.Name}, node)
+	if err != nil {
+		logger.Error(err, "error retrieving the Node")
+		return ctrl.Result{}, err
+	}
+	pending, err := r.pendingProfiles(c)
+	if err != nil {
+		logger.Error(err, "error retrieving the PowerProfiles")
+		return ctrl.Result{}, err
+	}
+	// the pending PowerProfiles are kept up to date after the Node is configured, so the PowerConfig can tell
+	// when a PowerProfile added later hasn't been applied
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := r.Client.Get(c, req.NamespacedName, powerNode)
+		if err != nil {
+			return err
+		}
+		configuring := powerNode.Status.ConfiguredTime == nil && len(pending) == 0
+		if !configuring && reflect.DeepEqual(powerNode.Status.PendingProfiles, pending) {
+			return nil
+		}
+		if configuring {
+			// the status goes first so the Config Controller doesn't taint the Node again once it is untainted
+			now := metav1.Now()
+			powerNode.Status.ConfiguredTime = &now
+		}
+		powerNode.Status.PendingProfiles = pending
+		return r.Client.Status().Update(c, powerNode)
+	})
+	if err != nil {
+		logger.Error(err, "error recording the PowerProfiles pending on the Node")
+		return ctrl.Result{}, err
+	}
+	if len(pending) > 0 {
+		logger.V(5).Info("Waiting for the PowerProfiles to be applied", "profiles", pending)
+		return ctrl.Result{RequeueAfter: nodeReadinessRequeueInterval}, nil
+	}
+	if hasUnconfiguredTaint(node) {
+		logger.Info("Every PowerProfile is applied, removing the unconfigured taint")
+		err = setUnconfiguredTaint(c, r.Client, node, false)
+		if err != nil {
+			logger.Error(err, "error removing the unconfigured taint")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// pendingProfiles returns the PowerProfiles that don't have a pool with them in the Power Library yet
+func (r *NodeReadinessReconciler) pendingProfiles(c context.Context) ([]string, error) {
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(c, profiles, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, profile := range profiles.Items {
+		if profile.Spec.Realtime && !r.RealtimeKernel {
+			continue
+		}
+		var pool power.Pool
+		if profile.Spec.Epp == "power" {
+			pool = r.PowerLibrary.GetSharedPool()
+		} else {
+			pool = r.PowerLibrary.GetExclusivePool(profile.Spec.Name)
+		}
+		if pool == nil || pool.GetPowerProfile() == nil || pool.GetPowerProfile().Name() != profile.Spec.Name {
+			pending = append(pending, profile.Spec.Name)
+		}
+	}
+	sort.Strings(pending)
+
+	return pending, nil
+}
+
+func hasUnconfiguredTaint(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == UnconfiguredTaintKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setUnconfiguredTaint adds or removes the unconfigured taint with a strategic merge patch, leaving the taints
+// others own alone
+func setUnconfiguredTaint(c context.Context, cl client.Client, node *corev1.Node, tainted bool) error {
+	if hasUnconfiguredTaint(node) == tainted {
+		return nil
+	}
+
+	patch := client.StrategicMergeFrom(node.DeepCopy())
+	if tainted {
+		node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
+			Key:    UnconfiguredTaintKey,
+			Effect: corev1.TaintEffectNoSchedule,
+		})
+	} else {
+		taints := make([]corev1.Taint, 0, len(node.Spec.Taints))
+		for _, taint := range node.Spec.Taints {
+			if taint.Key != UnconfiguredTaintKey {
+				taints = append(taints, taint)
+			}
+		}
+		node.Spec.Taints = taints
+	}
+
+	return cl.Patch(c, node, patch)
+}
+
+// profileReadinessRequests checks this Node's PowerProfiles again when one of them changes
+func (r *NodeReadinessReconciler) profileReadinessRequests(obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: os.Getenv("NODE_NAME"), Namespace: IntelPowerNamespace}}}
+}
+
+// nodeReadinessRequests maps this Node to its PowerNode, so a taint added by the kubelet is seen right away
+func (r *NodeReadinessReconciler) nodeReadinessRequests(obj client.Object) []reconcile.Request {
+	if obj.GetName() != os.Getenv("NODE_NAME") {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: obj.GetName(), Namespace: IntelPowerNamespace}}}
+}
+
+func (r *NodeReadinessReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("nodereadiness").
+		For(&powerv1.PowerNode{}).
+		Watches(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(r.nodeReadinessRequests)).
+		Watches(&source.Kind{Type: &powerv1.PowerProfile{}}, handler.EnqueueRequestsFromMapFunc(r.profileReadinessRequests)).
+		Complete(r)
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createNodeReadinessReconcilerObject(objs []runtime.Object, powerLibMock power.Host) (*NodeReadinessReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &NodeReadinessReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s, PowerLibrary: powerLibMock}, nil
+}
+
+func TestNodeReadinessReconciler(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	dedicated := corev1.Taint{Key: "dedicated", Value: "power", Effect: corev1.TaintEffectNoSchedule}
+	objs := []runtime.Object{
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				dedicated,
+				{Key: UnconfiguredTaintKey, Effect: corev1.TaintEffectNoSchedule},
+			}},
+		},
+		&powerv1.PowerNode{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: IntelPowerNamespace}},
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "performance", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: "performance", Epp: "performance"},
+		},
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: "shared", Epp: "power"},
+		},
+		// never applied without a PREEMPT_RT kernel, so not waited for
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "realtime", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: "realtime", Epp: "performance", Realtime: true},
+		},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}}
+
+	sharedProfile := new(profMock)
+	sharedProfile.On("Name").Return("shared")
+	sharedPool := new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetExclusivePool", "performance").Return(nil)
+
+	r, err := createNodeReadinessReconcilerObject(objs, powerLibMock)
+	assert.NoError(t, err)
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, nodeReadinessRequeueInterval, result.RequeueAfter)
+	node := &corev1.Node{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, node))
+	assert.True(t, hasUnconfiguredTaint(node))
+	powerNode := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, powerNode))
+	assert.Equal(t, []string{"performance"}, powerNode.Status.PendingProfiles)
+	assert.Nil(t, powerNode.Status.ConfiguredTime)
+
+	performanceProfile := new(profMock)
+	performanceProfile.On("Name").Return("performance")
+	performancePool := new(poolMock)
+	performancePool.On("GetPowerProfile").Return(performanceProfile)
+	powerLibMock = new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetExclusivePool", "performance").Return(performancePool)
+	r.PowerLibrary = powerLibMock
+
+	result, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, node))
+	assert.Equal(t, []corev1.Taint{dedicated}, node.Spec.Taints)
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, powerNode))
+	assert.NotNil(t, powerNode.Status.ConfiguredTime)
+	assert.Empty(t, powerNode.Status.PendingProfiles)
+
+	// a PowerProfile added once the Node is configured is pending until it is applied, without tainting the Node
+	assert.NoError(t, r.Client.Create(context.TODO(), &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "balance-performance", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerProfileSpec{Name: "balance-performance", Epp: "balance_performance"},
+	}))
+	powerLibMock.On("GetExclusivePool", "balance-performance").Return(nil)
+	result, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, nodeReadinessRequeueInterval, result.RequeueAfter)
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, powerNode))
+	assert.NotNil(t, powerNode.Status.ConfiguredTime)
+	assert.Equal(t, []string{"balance-performance"}, powerNode.Status.PendingProfiles)
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, node))
+	assert.Equal(t, []corev1.Taint{dedicated}, node.Spec.Taints)
+
+	// a PowerNode of another Node is left to its own agent
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "OtherNode", Namespace: IntelPowerNamespace}})
+	assert.NoError(t, err)
+}
+
+func TestPowerConfigUnconfiguredTaint(t *testing.T) {
+	configured := metav1.Now()
+	tcases := []struct {
+		testCase          string
+		unconfiguredTaint bool
+		configuredTime    *metav1.Time
+		expectedTainted   bool
+	}{
+		{
+			testCase:          "Test Case 1 - Node not configured yet",
+			unconfiguredTaint: true,
+			expectedTainted:   true,
+		},
+		{
+			testCase:          "Test Case 2 - Node already configured",
+			unconfiguredTaint: true,
+			configuredTime:    &configured,
+		},
+		{
+			testCase: "Test Case 3 - taint not requested",
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+		r, err := createConfigReconcilerObject([]runtime.Object{
+			&powerv1.PowerConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: IntelPowerNamespace},
+				Spec: powerv1.PowerConfigSpec{
+					PowerNodeSelector: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+					UnconfiguredTaint: tc.unconfiguredTaint,
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   "TestNode",
+				Labels: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+			}},
+			&powerv1.PowerNode{
+				ObjectMeta: metav1.ObjectMeta{Name: "TestNode", Namespace: IntelPowerNamespace},
+				Status:     powerv1.PowerNodeStatus{ConfiguredTime: tc.configuredTime},
+			},
+		})
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "test-config", Namespace: IntelPowerNamespace}})
+		assert.NoError(t, err)
+		node := &corev1.Node{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "TestNode"}, node))
+		assert.Equal(t, tc.expectedTainted, hasUnconfiguredTaint(node))
+	}
+}
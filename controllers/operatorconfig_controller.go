@@ -0,0 +1,141 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/dryrun"
+)
+
+const OperatorConfigRestartRequiredReason = "RestartRequired"
+
+// OperatorConfigReconciler applies the OperatorConfig's dry run to the manager's controllers as it changes and
+// reports the settings that only take effect once the manager restarts
+type OperatorConfigReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// DryRun is the client the other controllers write with
+	DryRun *dryrun.Client
+	// Started is what the manager started with, the OperatorConfig merged over its flags
+	Started powerv1.OperatorConfigSpec
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=operatorconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=operatorconfigs/status,verbs=get;update;patch
+
+func (r *OperatorConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("operatorconfig", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace || req.Name != powerv1.OperatorConfigName {
+		logger.Error(fmt.Errorf("incorrect name"), fmt.Sprintf("only the OperatorConfig %s in the intel-power namespace is used, ignoring", powerv1.OperatorConfigName))
+		return ctrl.Result{}, nil
+	}
+
+	config := &powerv1.OperatorConfig{}
+	err := r.Client.Get(c, req.NamespacedName, config)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// back to what the manager started with
+			r.setDryRun(&logger, r.Started.DryRun != nil && *r.Started.DryRun)
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error retrieving the OperatorConfig")
+		return ctrl.Result{}, err
+	}
+
+	dryRun := r.Started.DryRun != nil && *r.Started.DryRun
+	if config.Spec.DryRun != nil {
+		dryRun = *config.Spec.DryRun
+	}
+	r.setDryRun(&logger, dryRun)
+
+	pending := pendingRestart(config.Spec, r.Started)
+	if len(pending) > 0 && !reflect.DeepEqual(pending, config.Status.PendingRestart) {
+		r.Recorder.Event(config, corev1.EventTypeNormal, OperatorConfigRestartRequiredReason,
+			fmt.Sprintf("Restart the manager to apply %s", strings.Join(pending, ", ")))
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &powerv1.OperatorConfig{}
+		err := r.Client.Get(c, req.NamespacedName, latest)
+		if err != nil {
+			return err
+		}
+		latest.Status.ObservedGeneration = config.Generation
+		latest.Status.DryRun = dryRun
+		latest.Status.PendingRestart = pending
+		return r.Client.Status().Update(c, latest)
+	})
+	if err != nil {
+		logger.Error(err, "error updating the OperatorConfig status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *OperatorConfigReconciler) setDryRun(logger *logr.Logger, enabled bool) {
+	if r.DryRun.Enabled() == enabled {
+		return
+	}
+	logger.Info("Switching the controllers' dry run", "dryRun", enabled)
+	r.DryRun.SetEnabled(enabled)
+}
+
+// dryRunning is true while the manager dry runs its changes. Only writes to the API server go through the dry run
+// client, controllers acting on machines directly check it before doing so
+func dryRunning(dryRun *dryrun.Client) bool {
+	return dryRun != nil && dryRun.Enabled()
+}
+
+// pendingRestart names the settings the OperatorConfig sets to something other than what the manager started with.
+// Unset settings keep the flag values so never need a restart
+func pendingRestart(spec powerv1.OperatorConfigSpec, started powerv1.OperatorConfigSpec) []string {
+	var pending []string
+	if spec.ResyncPeriodSeconds != 0 && spec.ResyncPeriodSeconds != started.ResyncPeriodSeconds {
+		pending = append(pending, "resyncPeriodSeconds")
+	}
+	if spec.MaxConcurrentReconciles != 0 && spec.MaxConcurrentReconciles != started.MaxConcurrentReconciles {
+		pending = append(pending, "maxConcurrentReconciles")
+	}
+	if spec.MetricsBindAddress != "" && spec.MetricsBindAddress != started.MetricsBindAddress {
+		pending = append(pending, "metricsBindAddress")
+	}
+
+	return pending
+}
+
+func (r *OperatorConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.OperatorConfig{}).
+		Complete(r)
+}
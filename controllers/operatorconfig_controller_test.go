@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/dryrun"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createOperatorConfigReconcilerObject(objs []runtime.Object) (*OperatorConfigReconciler, *record.FakeRecorder, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+	recorder := record.NewFakeRecorder(10)
+	dryRun := false
+
+	return &OperatorConfigReconciler{
+		Client:   cl,
+		Log:      ctrl.Log.WithName("testing"),
+		Scheme:   s,
+		Recorder: recorder,
+		DryRun:   dryrun.NewClient(cl),
+		Started:  powerv1.OperatorConfigSpec{ResyncPeriodSeconds: 36000, MaxConcurrentReconciles: 1, DryRun: &dryRun, MetricsBindAddress: ":8080"},
+	}, recorder, nil
+}
+
+func TestOperatorConfigReconciler(t *testing.T) {
+	dryRun := true
+	config := &powerv1.OperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: powerv1.OperatorConfigName, Namespace: IntelPowerNamespace, Generation: 2},
+		Spec: powerv1.OperatorConfigSpec{
+			ResyncPeriodSeconds:     36000,
+			MaxConcurrentReconciles: 4,
+			DryRun:                  &dryRun,
+		},
+	}
+	r, recorder, err := createOperatorConfigReconcilerObject([]runtime.Object{config})
+	assert.NoError(t, err)
+
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(config)}
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.True(t, r.DryRun.Enabled())
+	updated := &powerv1.OperatorConfig{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	assert.Equal(t, int64(2), updated.Status.ObservedGeneration)
+	assert.True(t, updated.Status.DryRun)
+	// the resync period is what the manager started with, only the concurrency needs a restart
+	assert.Equal(t, []string{"maxConcurrentReconciles"}, updated.Status.PendingRestart)
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Restart the manager to apply maxConcurrentReconciles")
+
+	// writes through the dry run client are validated but not persisted
+	profile := &powerv1.PowerProfile{ObjectMeta: metav1.ObjectMeta{Name: "performance", Namespace: IntelPowerNamespace}}
+	assert.NoError(t, r.DryRun.Create(context.TODO(), profile))
+	err = r.Client.Get(context.TODO(), client.ObjectKeyFromObject(profile), &powerv1.PowerProfile{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// no repeated event while nothing changes, and deleting the OperatorConfig goes back to the flags
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Len(t, recorder.Events, 0)
+	assert.NoError(t, r.Client.Delete(context.TODO(), updated))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.False(t, r.DryRun.Enabled())
+	assert.NoError(t, r.DryRun.Create(context.TODO(), profile))
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(profile), &powerv1.PowerProfile{}))
+
+	// OperatorConfigs under any other name are ignored
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "other", Namespace: IntelPowerNamespace}})
+	assert.NoError(t, err)
+}
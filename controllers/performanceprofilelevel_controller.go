@@ -0,0 +1,231 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+)
+
+// how often a deferred SST-PP level switch is retried while exclusive PowerWorkloads still have cores
+const performanceProfileRequeueInterval = time.Minute
+
+// PerformanceProfileSwitcher switches the SST-PP config level and hotplugs the cores each level enables
+type PerformanceProfileSwitcher interface {
+	CurrentLevel() (int, error)
+	EnabledCpus(level int) ([]uint, error)
+	OnlineCpus() ([]uint, error)
+	SetLevel(level int) error
+	SetOnline(cpus []uint, online bool) error
+}
+
+// PerformanceProfileLevelReconciler moves this Node's packages to the SST-PP config level requested in its PowerNode
+type PerformanceProfileLevelReconciler struct {
+	client.Client
+	Log          logr.Logger
+	Scheme       *runtime.Scheme
+	PowerLibrary power.Host
+	Switcher     PerformanceProfileSwitcher
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+
+func (r *PerformanceProfileLevelReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("performanceprofilelevel", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+	if req.Name != os.Getenv("NODE_NAME") {
+		// PowerNode is not on this Node
+		return ctrl.Result{}, nil
+	}
+
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(c, req.NamespacedName, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error retrieving the PowerNode")
+		return ctrl.Result{}, err
+	}
+	if powerNode.Spec.PerformanceProfileLevel == nil {
+		return ctrl.Result{}, nil
+	}
+	requested := *powerNode.Spec.PerformanceProfileLevel
+
+	current, err := r.Switcher.CurrentLevel()
+	if err != nil {
+		logger.Error(err, "error reading the current SST-PP config level")
+		statusErr := r.updateStatus(c, req.NamespacedName, nil, err.Error())
+		if powererrors.ActionFor(err) != powererrors.Retry {
+			// the platform doesn't support SST-PP, retrying won't help
+			return ctrl.Result{}, statusErr
+		}
+		if statusErr != nil {
+			logger.Error(statusErr, "error updating PowerNode status")
+		}
+		return ctrl.Result{}, err
+	}
+	if current == requested {
+		return ctrl.Result{}, r.updateStatus(c, req.NamespacedName, &current, "")
+	}
+
+	busyPools := r.busyExclusivePools()
+	if len(busyPools) > 0 {
+		message := fmt.Sprintf("switching to level %d is deferred while exclusive pools %v have cores", requested, busyPools)
+		logger.Info(message)
+		return ctrl.Result{RequeueAfter: performanceProfileRequeueInterval}, r.updateStatus(c, req.NamespacedName, &current, message)
+	}
+
+	logger.Info("Switching SST-PP config level", "from", current, "to", requested)
+	err = r.switchLevel(c, req.Name, requested, &logger)
+	if err != nil {
+		logger.Error(err, "error switching SST-PP config level")
+		statusErr := r.updateStatus(c, req.NamespacedName, &current, err.Error())
+		if statusErr != nil {
+			logger.Error(statusErr, "error updating PowerNode status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, r.updateStatus(c, req.NamespacedName, &requested, "")
+}
+
+// busyExclusivePools returns the exclusive pools that have cores, their cores could be taken offline by the switch
+func (r *PerformanceProfileLevelReconciler) busyExclusivePools() []string {
+	busy := make([]string, 0)
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		if len(pool.Cpus().IDs()) > 0 {
+			busy = append(busy, pool.Name())
+		}
+	}
+	sort.Strings(busy)
+
+	return busy
+}
+
+// switchLevel changes the config level and hotplugs the cores to match it. Cores the new level disables are
+// moved into the Reserved pool while still online so the Power Library stops managing them, cores it enables
+// are brought online and handed to the Shared pool, both within the Node's frequency transition limit
+func (r *PerformanceProfileLevelReconciler) switchLevel(c context.Context, nodeName string, level int, logger *logr.Logger) error {
+	limit, err := transitionLimit(c, r.Client, nodeName)
+	if err != nil {
+		return err
+	}
+	enabled, err := r.Switcher.EnabledCpus(level)
+	if err != nil {
+		return err
+	}
+	online, err := r.Switcher.OnlineCpus()
+	if err != nil {
+		return err
+	}
+	toOffline := uintDifference(online, enabled)
+	toOnline := uintDifference(enabled, online)
+	logger.V(5).Info("Hotplugging cores for the new level", "offline", toOffline, "online", toOnline)
+
+	if len(toOffline) > 0 {
+		err = moveInBatches(c, toOffline, limit, r.PowerLibrary.GetReservedPool().MoveCpuIDs)
+		if err != nil {
+			return fmt.Errorf("moving cores %v to the Reserved pool: %w", toOffline, err)
+		}
+	}
+
+	err = r.Switcher.SetLevel(level)
+	if err != nil {
+		return err
+	}
+
+	err = r.Switcher.SetOnline(toOffline, false)
+	if err != nil {
+		return err
+	}
+	err = r.Switcher.SetOnline(toOnline, true)
+	if err != nil {
+		return err
+	}
+
+	if len(toOnline) > 0 {
+		err = moveInBatches(c, toOnline, limit, r.PowerLibrary.GetSharedPool().MoveCpuIDs)
+		if err != nil {
+			return fmt.Errorf("moving cores %v to the Shared pool: %w", toOnline, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *PerformanceProfileLevelReconciler) updateStatus(c context.Context, key client.ObjectKey, level *int, message string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(c, key, powerNode)
+		if err != nil {
+			return err
+		}
+
+		sameLevel := (level == nil && powerNode.Status.PerformanceProfileLevel == nil) ||
+			(level != nil && powerNode.Status.PerformanceProfileLevel != nil && *level == *powerNode.Status.PerformanceProfileLevel)
+		if sameLevel && powerNode.Status.PerformanceProfileMessage == message {
+			return nil
+		}
+
+		powerNode.Status.PerformanceProfileLevel = level
+		powerNode.Status.PerformanceProfileMessage = message
+		return r.Client.Status().Update(c, powerNode)
+	})
+}
+
+// uintDifference returns the elements of a that are not in b
+func uintDifference(a []uint, b []uint) []uint {
+	exclude := make(map[uint]bool, len(b))
+	for _, id := range b {
+		exclude[id] = true
+	}
+
+	difference := make([]uint, 0)
+	for _, id := range a {
+		if !exclude[id] {
+			difference = append(difference, id)
+		}
+	}
+
+	return difference
+}
+
+func (r *PerformanceProfileLevelReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("performanceprofilelevel").
+		For(&powerv1.PowerNode{}).
+		Complete(r)
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type switcherMock struct {
+	mock.Mock
+}
+
+func (m *switcherMock) CurrentLevel() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (m *switcherMock) EnabledCpus(level int) ([]uint, error) {
+	args := m.Called(level)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *switcherMock) OnlineCpus() ([]uint, error) {
+	args := m.Called()
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *switcherMock) SetLevel(level int) error {
+	return m.Called(level).Error(0)
+}
+
+func (m *switcherMock) SetOnline(cpus []uint, online bool) error {
+	return m.Called(cpus, online).Error(0)
+}
+
+func createPerformanceProfileLevelReconcilerObject(objs []runtime.Object) (*PerformanceProfileLevelReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &PerformanceProfileLevelReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s}, nil
+}
+
+func TestPerformanceProfileLevelReconciler(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	level := 1
+	core := new(coreMock)
+	core.On("GetID").Return(uint(5))
+
+	tcases := []struct {
+		testCase        string
+		exclusiveCores  power.CpuList
+		currentLevel    int
+		expectedLevel   int
+		expectedMessage string
+		expectSwitch    bool
+	}{
+		{
+			testCase:      "Test Case 1 - already in the requested level",
+			currentLevel:  1,
+			expectedLevel: 1,
+		},
+		{
+			testCase:        "Test Case 2 - deferred while an exclusive pool has cores",
+			exclusiveCores:  power.CpuList{core},
+			currentLevel:    0,
+			expectedLevel:   0,
+			expectedMessage: "switching to level 1 is deferred while exclusive pools [performance] have cores",
+		},
+		{
+			testCase:      "Test Case 3 - switched with cores hotplugged",
+			currentLevel:  0,
+			expectedLevel: 1,
+			expectSwitch:  true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		powerNode := &powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nodeName,
+				Namespace: IntelPowerNamespace,
+			},
+			Spec: powerv1.PowerNodeSpec{
+				NodeName:                nodeName,
+				PerformanceProfileLevel: &level,
+			},
+		}
+		r, err := createPerformanceProfileLevelReconcilerObject([]runtime.Object{powerNode})
+		assert.NoError(t, err)
+
+		exclusivePool := new(poolMock)
+		exclusivePool.On("Name").Return("performance")
+		exclusivePool.On("Cpus").Return(&tc.exclusiveCores)
+		reservedPool := new(poolMock)
+		reservedPool.On("MoveCpuIDs", []uint{6, 7}).Return(nil)
+		sharedPool := new(poolMock)
+		sharedPool.On("MoveCpuIDs", []uint{4}).Return(nil)
+		powerLibMock := new(hostMock)
+		powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{exclusivePool})
+		powerLibMock.On("GetReservedPool").Return(reservedPool)
+		powerLibMock.On("GetSharedPool").Return(sharedPool)
+		r.PowerLibrary = powerLibMock
+
+		switcher := new(switcherMock)
+		switcher.On("CurrentLevel").Return(tc.currentLevel, nil)
+		switcher.On("EnabledCpus", 1).Return([]uint{0, 1, 2, 3, 4}, nil)
+		switcher.On("OnlineCpus").Return([]uint{0, 1, 2, 3, 6, 7}, nil)
+		switcher.On("SetLevel", 1).Return(nil)
+		switcher.On("SetOnline", mock.Anything, mock.Anything).Return(nil)
+		r.Switcher = switcher
+
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(powerNode)}
+		_, err = r.Reconcile(context.TODO(), req)
+		assert.NoError(t, err)
+
+		if tc.expectSwitch {
+			// disabled cores leave the Power Library's hands before they go offline
+			reservedPool.AssertCalled(t, "MoveCpuIDs", []uint{6, 7})
+			switcher.AssertCalled(t, "SetLevel", 1)
+			switcher.AssertCalled(t, "SetOnline", []uint{6, 7}, false)
+			switcher.AssertCalled(t, "SetOnline", []uint{4}, true)
+			sharedPool.AssertCalled(t, "MoveCpuIDs", []uint{4})
+		} else {
+			switcher.AssertNotCalled(t, "SetLevel", mock.Anything)
+		}
+
+		updatedNode := &powerv1.PowerNode{}
+		err = r.Client.Get(context.TODO(), req.NamespacedName, updatedNode)
+		assert.NoError(t, err)
+		if assert.NotNil(t, updatedNode.Status.PerformanceProfileLevel) {
+			assert.Equal(t, tc.expectedLevel, *updatedNode.Status.PerformanceProfileLevel)
+		}
+		assert.Equal(t, tc.expectedMessage, updatedNode.Status.PerformanceProfileMessage)
+	}
+}
+
+func TestPerformanceProfileLevelReadErrors(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	level := 1
+
+	tcases := []struct {
+		testCase    string
+		readErr     error
+		expectRetry bool
+	}{
+		{
+			testCase: "Test Case 1 - SST-PP unsupported is only recorded in the status",
+			readErr:  powererrors.NewHardwareUnsupported("SST-PP", fmt.Errorf("intel-speed-select output has no 'get-config-current_level'")),
+		},
+		{
+			testCase:    "Test Case 2 - other errors are retried",
+			readErr:     powererrors.NewTransient(fmt.Errorf("running intel-speed-select: signal: killed")),
+			expectRetry: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		powerNode := &powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nodeName,
+				Namespace: IntelPowerNamespace,
+			},
+			Spec: powerv1.PowerNodeSpec{
+				NodeName:                nodeName,
+				PerformanceProfileLevel: &level,
+			},
+		}
+		r, err := createPerformanceProfileLevelReconcilerObject([]runtime.Object{powerNode})
+		assert.NoError(t, err)
+
+		switcher := new(switcherMock)
+		switcher.On("CurrentLevel").Return(0, tc.readErr)
+		r.Switcher = switcher
+
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(powerNode)}
+		_, err = r.Reconcile(context.TODO(), req)
+		if tc.expectRetry {
+			assert.ErrorIs(t, err, tc.readErr)
+		} else {
+			assert.NoError(t, err)
+		}
+
+		updatedNode := &powerv1.PowerNode{}
+		err = r.Client.Get(context.TODO(), req.NamespacedName, updatedNode)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.readErr.Error(), updatedNode.Status.PerformanceProfileMessage)
+	}
+}
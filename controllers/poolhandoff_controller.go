@@ -0,0 +1,122 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+
+	"github.com/intel/kubernetes-power-manager/pkg/handoff"
+	"github.com/intel/kubernetes-power-manager/pkg/poollock"
+)
+
+// PoolHandoffReconciler lets a new Node Agent pick up the pools of the one it replaces during a
+// rolling upgrade. The pools are saved to a state file on the host periodically and on shutdown,
+// and restored before any controller runs. Without it every core starts in the Reserved pool and
+// exclusive cores spend the time until their PowerWorkloads are reconciled on the Shared profile
+type PoolHandoffReconciler struct {
+	Log          logr.Logger
+	PowerLibrary power.Host
+	NodeName     string
+	Path         string
+	Interval     time.Duration
+
+	mutex     sync.Mutex
+	discarded bool
+}
+
+// Restore recreates the pools from the state file if it was written on this Node since it last
+// booted, by an agent using the same state format. It must run before the manager is started
+func (r *PoolHandoffReconciler) Restore() error {
+	state, err := handoff.Load(r.Path)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		r.Log.Info("no pool state to restore", "path", r.Path)
+		return nil
+	}
+	err = state.Check(r.NodeName)
+	if err != nil {
+		r.Log.Info("ignoring pool state", "reason", err.Error(), "agentVersion", state.AgentVersion)
+		return nil
+	}
+
+	err = poollock.Change(func() error { return handoff.Restore(r.PowerLibrary, state) })
+	if err != nil {
+		return err
+	}
+	r.Log.Info("restored pools", "agentVersion", state.AgentVersion, "written", state.Written, "exclusivePools", len(state.Exclusive))
+	return nil
+}
+
+// Start saves the pools on every interval and a last time when the context is cancelled
+func (r *PoolHandoffReconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return r.Save()
+		case <-ticker.C:
+			err := r.Save()
+			if err != nil {
+				r.Log.Error(err, "error saving pool state")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent has to look after its own Node
+func (r *PoolHandoffReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Save writes the current pools to the state file, unless it has been discarded
+func (r *PoolHandoffReconciler) Save() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.discarded {
+		return nil
+	}
+
+	var state *handoff.State
+	// the reconcilers move cores between the pools while they're captured
+	poollock.Read(func() { state = handoff.Capture(r.PowerLibrary, r.NodeName) })
+
+	return handoff.Save(r.Path, state)
+}
+
+// Discard removes the state file and stops saving it, so a Node Agent installed after a clean up starts from
+// the defaults rather than the pools of the one that was removed
+func (r *PoolHandoffReconciler) Discard() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.discarded = true
+
+	err := os.Remove(r.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/intel/kubernetes-power-manager/pkg/handoff"
+)
+
+func TestPoolHandoffReconciler_SaveAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	defer func(bootIDFile string) { handoff.BootIDFile = bootIDFile }(handoff.BootIDFile)
+	handoff.BootIDFile = filepath.Join(dir, "boot_id")
+	assert.NoError(t, os.WriteFile(handoff.BootIDFile, []byte("boot-1\n"), 0600))
+
+	// the outgoing agent with cores 2-3 in the performance pool and 4-5 shared
+	core2, core3, core4, core5 := new(coreMock), new(coreMock), new(coreMock), new(coreMock)
+	core2.On("GetID").Return(uint(2))
+	core3.On("GetID").Return(uint(3))
+	core4.On("GetID").Return(uint(4))
+	core5.On("GetID").Return(uint(5))
+	performanceProfile := new(profMock)
+	performanceProfile.On("Name").Return("performance")
+	performanceProfile.On("MinFreq").Return(uint(3200))
+	performanceProfile.On("MaxFreq").Return(uint(3600))
+	performanceProfile.On("Governor").Return("performance")
+	performanceProfile.On("Epp").Return("performance")
+	sharedProfile := new(profMock)
+	sharedProfile.On("Name").Return("shared")
+	sharedProfile.On("MinFreq").Return(uint(800))
+	sharedProfile.On("MaxFreq").Return(uint(1600))
+	sharedProfile.On("Governor").Return("powersave")
+	sharedProfile.On("Epp").Return("power")
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{core2, core3})
+	performancePool.On("GetPowerProfile").Return(performanceProfile)
+	sharedPool := new(poolMock)
+	sharedPool.On("Name").Return("sharedPool")
+	sharedPool.On("Cpus").Return(&power.CpuList{core4, core5})
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	outgoing := new(hostMock)
+	outgoing.On("GetSharedPool").Return(sharedPool)
+	outgoing.On("GetAllExclusivePools").Return(&power.PoolList{performancePool})
+
+	path := filepath.Join(dir, "pools.json")
+	old := &PoolHandoffReconciler{
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: outgoing,
+		NodeName:     "TestNode",
+		Path:         path,
+		Interval:     time.Hour,
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	// the state is saved on shutdown
+	assert.NoError(t, old.Start(ctx))
+
+	// the incoming agent moves the exclusive cores through the Shared pool carrying their profile
+	var calls []string
+	newSharedPool := new(poolMock)
+	newSharedPool.On("SetPowerProfile", mock.Anything).Run(func(args mock.Arguments) {
+		calls = append(calls, "shared profile "+args.Get(0).(power.Profile).Name())
+	}).Return(nil)
+	newSharedPool.On("MoveCpuIDs", mock.Anything).Return(nil)
+	newPerformancePool := new(poolMock)
+	newPerformancePool.On("SetPowerProfile", mock.Anything).Run(func(args mock.Arguments) {
+		profile := args.Get(0).(power.Profile)
+		assert.Equal(t, uint(3600), profile.MaxFreq())
+		assert.Equal(t, "performance", profile.Governor())
+		calls = append(calls, "performance profile")
+	}).Return(nil)
+	newPerformancePool.On("MoveCpuIDs", []uint{2, 3}).Run(func(args mock.Arguments) {
+		calls = append(calls, "performance cores")
+	}).Return(nil)
+	incoming := new(hostMock)
+	incoming.On("GetSharedPool").Return(newSharedPool)
+	incoming.On("GetExclusivePool", "performance").Return(nil)
+	incoming.On("AddExclusivePool", "performance").Return(newPerformancePool, nil)
+
+	r := &PoolHandoffReconciler{
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: incoming,
+		NodeName:     "TestNode",
+		Path:         path,
+	}
+	assert.NoError(t, r.Restore())
+	assert.Equal(t, []string{"shared profile performance", "performance profile", "performance cores", "shared profile shared"}, calls)
+	newSharedPool.AssertCalled(t, "MoveCpuIDs", []uint{2, 3})
+	newSharedPool.AssertCalled(t, "MoveCpuIDs", []uint{4, 5})
+}
+
+func TestPoolHandoffReconciler_IgnoredState(t *testing.T) {
+	dir := t.TempDir()
+	defer func(bootIDFile string) { handoff.BootIDFile = bootIDFile }(handoff.BootIDFile)
+	handoff.BootIDFile = filepath.Join(dir, "boot_id")
+	assert.NoError(t, os.WriteFile(handoff.BootIDFile, []byte("boot-2"), 0600))
+
+	tcases := []struct {
+		testCase string
+		state    *handoff.State
+	}{
+		{
+			testCase: "Test Case 1 - state written by an agent using another format",
+			state:    &handoff.State{FormatVersion: handoff.FormatVersion + 1, Node: "TestNode", BootID: "boot-2"},
+		},
+		{
+			testCase: "Test Case 2 - state written on another Node",
+			state:    &handoff.State{FormatVersion: handoff.FormatVersion, Node: "OtherNode", BootID: "boot-2"},
+		},
+		{
+			testCase: "Test Case 3 - state written before the Node rebooted",
+			state:    &handoff.State{FormatVersion: handoff.FormatVersion, Node: "TestNode", BootID: "boot-1"},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		path := filepath.Join(dir, "pools.json")
+		assert.NoError(t, handoff.Save(path, tc.state))
+
+		// no calls are set up, touching the Power Library would panic
+		r := &PoolHandoffReconciler{
+			Log:          ctrl.Log.WithName("testing"),
+			PowerLibrary: new(hostMock),
+			NodeName:     "TestNode",
+			Path:         path,
+		}
+		assert.NoError(t, r.Restore())
+	}
+
+	// no state file at all
+	r := &PoolHandoffReconciler{Log: ctrl.Log.WithName("testing"), Path: filepath.Join(dir, "missing.json")}
+	assert.NoError(t, r.Restore())
+}
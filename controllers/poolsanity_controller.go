@@ -0,0 +1,293 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	OrphanedPoolRemovedReason = "OrphanedPoolRemoved"
+	OrphanedCoresResetReason  = "OrphanedCoresReset"
+	PoolsReconstructedReason  = "PoolsReconstructed"
+)
+
+// PoolSanityReconciler periodically walks the exclusive pools in the Power Library and returns
+// any cores that are no longer claimed by a PowerWorkload to the Shared pool, so that they
+// pick up the default profile again. This covers cores left behind by failed or partial deletes.
+// When the Node Agent starts it first reconciles the pools it took over against the Power CRs.
+type PoolSanityReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	Recorder     record.EventRecorder
+	Interval     time.Duration
+}
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Start reconstructs the pools once, then runs the sanity check on every interval until the context is cancelled
+func (r *PoolSanityReconciler) Start(ctx context.Context) error {
+	_, err := r.Reconstruct(ctx)
+	if err != nil {
+		r.Log.Error(err, "error reconstructing pools")
+	}
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := r.RepairOrphans(ctx)
+			if err != nil {
+				r.Log.Error(err, "error repairing orphaned cores")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent has to look after its own Node
+func (r *PoolSanityReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// RepairOrphans removes exclusive pools that have no PowerProfile and moves cores that are not
+// part of any PowerWorkload on this Node back into the Shared pool
+func (r *PoolSanityReconciler) RepairOrphans(ctx context.Context) error {
+	logger := r.Log.WithName("poolSanity")
+	nodeName := os.Getenv("NODE_NAME")
+
+	profiles := &powerv1.PowerProfileList{}
+	logger.V(5).Info("Retrieving PowerProfileList")
+	err := r.Client.List(ctx, profiles, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return err
+	}
+
+	workloads := &powerv1.PowerWorkloadList{}
+	logger.V(5).Info("Retrieving PowerWorkloadList")
+	err = r.Client.List(ctx, workloads, client.InNamespace(IntelPowerNamespace), client.MatchingFields{WorkloadNodeNameIndex: nodeName})
+	if err != nil {
+		return err
+	}
+
+	profileNames := make(map[string]bool)
+	for _, profile := range profiles.Items {
+		profileNames[profile.Spec.Name] = true
+	}
+
+	claimedCores := make(map[string][]uint)
+	for _, workload := range workloads.Items {
+		if workload.Spec.AllCores {
+			continue
+		}
+		claimedCores[workload.Spec.PowerProfile] = append(claimedCores[workload.Spec.PowerProfile], workload.Spec.Node.CpuIds...)
+	}
+
+	limit, err := transitionLimit(ctx, r.Client, nodeName)
+	if err != nil {
+		return err
+	}
+
+	results := new(multierror.Error)
+	// removing a pool changes the list
+	pools := append(power.PoolList{}, *r.PowerLibrary.GetAllExclusivePools()...)
+	for _, pool := range pools {
+		poolName := pool.Name()
+		if !profileNames[poolName] {
+			logger.Info("Removing pool with no PowerProfile", "pool", poolName)
+			err = removeInBatches(ctx, r.PowerLibrary, pool, limit)
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("removing orphaned pool %s: %w", poolName, err))
+				continue
+			}
+			r.recordRepair(ctx, nodeName, OrphanedPoolRemovedReason, fmt.Sprintf("Removed pool '%s' as its PowerProfile no longer exists", poolName))
+			continue
+		}
+
+		orphanedCores := detectCoresRemoved(pool.Cpus().IDs(), claimedCores[poolName], &logger)
+		if len(orphanedCores) == 0 {
+			continue
+		}
+
+		logger.Info("Resetting orphaned cores to the Shared pool", "pool", poolName, "cores", orphanedCores)
+		err = moveInBatches(ctx, orphanedCores, limit, r.PowerLibrary.GetSharedPool().MoveCpuIDs)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("resetting orphaned cores in pool %s: %w", poolName, err))
+			continue
+		}
+		r.recordRepair(ctx, nodeName, OrphanedCoresResetReason, fmt.Sprintf("Moved cores %s from pool '%s' back to the Shared pool", prettifyCoreList(orphanedCores), poolName))
+	}
+
+	return results.ErrorOrNil()
+}
+
+// Reconstruction is what Reconstruct did with each exclusive pool the Node Agent started with
+type Reconstruction struct {
+	// Pools that already matched their PowerWorkloads
+	Adopted []string
+	// Pools whose cores were moved to match their PowerWorkloads
+	Corrected []string
+	// Pools whose PowerProfile no longer exists
+	Deleted []string
+}
+
+// Reconstruct reconciles the exclusive pools the Node Agent started with, such as those taken over from the
+// previous Node Agent, against the PowerProfiles and this Node's PowerWorkloads, the way the kubelet reconciles
+// the containers it finds at startup. Pools without a PowerProfile are deleted, cores no PowerWorkload claims go to
+// the Shared pool and claimed cores are moved into their pool, so the PowerWorkloads find their pools as they left
+// them rather than re-applying them. Cores claimed for more than one PowerProfile are left to the PowerWorkload
+// controller, as are PowerWorkloads whose pool the PowerProfile controller hasn't created yet
+func (r *PoolSanityReconciler) Reconstruct(ctx context.Context) (*Reconstruction, error) {
+	logger := r.Log.WithName("poolReconstruction")
+	nodeName := os.Getenv("NODE_NAME")
+	changes := logging.NewChangeSummary()
+	defer changes.Log(logger)
+
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(ctx, profiles, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, err
+	}
+	workloads := &powerv1.PowerWorkloadList{}
+	err = r.Client.List(ctx, workloads, client.InNamespace(IntelPowerNamespace), client.MatchingFields{WorkloadNodeNameIndex: nodeName})
+	if err != nil {
+		return nil, err
+	}
+
+	profileNames := make(map[string]bool)
+	for _, profile := range profiles.Items {
+		profileNames[profile.Spec.Name] = true
+	}
+	claimedCores := make(map[string][]uint)
+	claims := make(map[uint]int)
+	for _, workload := range workloads.Items {
+		if workload.Spec.AllCores {
+			continue
+		}
+		claimedCores[workload.Spec.PowerProfile] = append(claimedCores[workload.Spec.PowerProfile], workload.Spec.Node.CpuIds...)
+		for _, core := range workload.Spec.Node.CpuIds {
+			claims[core]++
+		}
+	}
+
+	limit, err := transitionLimit(ctx, r.Client, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	reconstruction := &Reconstruction{}
+	results := new(multierror.Error)
+	// removing a pool changes the list
+	pools := append(power.PoolList{}, *r.PowerLibrary.GetAllExclusivePools()...)
+	for _, pool := range pools {
+		poolName := pool.Name()
+		if !profileNames[poolName] {
+			err = removeInBatches(ctx, r.PowerLibrary, pool, limit)
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("removing pool %s: %w", poolName, err))
+				continue
+			}
+			changes.PoolRemoved(poolName)
+			reconstruction.Deleted = append(reconstruction.Deleted, poolName)
+			continue
+		}
+
+		cores := pool.Cpus().IDs()
+		unclaimed := detectCoresRemoved(cores, claimedCores[poolName], &logger)
+		missing := make([]uint, 0)
+		for _, core := range detectCoresAdded(cores, claimedCores[poolName], &logger) {
+			if claims[core] > 1 {
+				logger.Info("Core is claimed for more than one PowerProfile, leaving it to the PowerWorkloads", "core", core)
+				continue
+			}
+			missing = append(missing, core)
+		}
+		if len(unclaimed) == 0 && len(missing) == 0 {
+			reconstruction.Adopted = append(reconstruction.Adopted, poolName)
+			continue
+		}
+
+		if len(unclaimed) > 0 {
+			err = moveInBatches(ctx, unclaimed, limit, r.PowerLibrary.GetSharedPool().MoveCpuIDs)
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("moving unclaimed cores of pool %s to the Shared pool: %w", poolName, err))
+				continue
+			}
+		}
+		if len(missing) > 0 {
+			err = moveInBatches(ctx, missing, limit, pool.MoveCpuIDs)
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("moving claimed cores into pool %s: %w", poolName, err))
+				continue
+			}
+		}
+		changes.PoolModified(poolName, missing, unclaimed)
+		reconstruction.Corrected = append(reconstruction.Corrected, poolName)
+	}
+	for profile := range claimedCores {
+		if r.PowerLibrary.GetExclusivePool(profile) == nil {
+			logger.V(5).Info("PowerWorkload's pool doesn't exist yet, it's created with its PowerProfile", "pool", profile)
+		}
+	}
+
+	logger.Info("Reconstructed pools from the Power CRs", "adopted", reconstruction.Adopted,
+		"corrected", reconstruction.Corrected, "deleted", reconstruction.Deleted)
+	if len(reconstruction.Corrected) > 0 || len(reconstruction.Deleted) > 0 {
+		r.recordRepair(ctx, nodeName, PoolsReconstructedReason, fmt.Sprintf(
+			"Reconstructed pools at startup: corrected %v, deleted %v", reconstruction.Corrected, reconstruction.Deleted))
+	}
+
+	return reconstruction, results.ErrorOrNil()
+}
+
+func (r *PoolSanityReconciler) recordRepair(ctx context.Context, nodeName string, reason string, message string) {
+	if r.Recorder == nil {
+		return
+	}
+
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Name:      nodeName,
+		Namespace: IntelPowerNamespace,
+	}, powerNode)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			r.Log.Error(err, "error retrieving PowerNode for repair event")
+		}
+		return
+	}
+
+	r.Recorder.Event(powerNode, corev1.EventTypeWarning, reason, message)
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func buildPoolSanityReconcilerObject(objs []runtime.Object, powerLibMock power.Host) (*PoolSanityReconciler, *record.FakeRecorder) {
+	schm := runtime.NewScheme()
+	err := powerv1.AddToScheme(schm)
+	if err != nil {
+		return nil, nil
+	}
+	client := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(schm).
+		WithIndex(&powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer).Build()
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &PoolSanityReconciler{
+		Client:       client,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Recorder:     recorder,
+	}
+
+	return reconciler, recorder
+}
+
+func TestPoolSanityReconciler_RepairOrphans(t *testing.T) {
+	nodeName := "TestNode"
+	powerNodeObj := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+	}
+	profileObj := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{
+			Name: "performance",
+			Epp:  "performance",
+		},
+	}
+	workloadObj := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance-TestNode",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerWorkloadSpec{
+			Name:         "performance-TestNode",
+			PowerProfile: "performance",
+			Node: powerv1.WorkloadNode{
+				Name:   nodeName,
+				CpuIds: []uint{2},
+			},
+		},
+	}
+	t.Setenv("NODE_NAME", nodeName)
+
+	core2 := new(coreMock)
+	core2.On("GetID").Return(uint(2))
+	core3 := new(coreMock)
+	core3.On("GetID").Return(uint(3))
+	core4 := new(coreMock)
+	core4.On("GetID").Return(uint(4))
+
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{core2, core3})
+	stalePool := new(poolMock)
+	stalePool.On("Name").Return("balance-power")
+	stalePool.On("Cpus").Return(&power.CpuList{core4})
+	stalePool.On("Remove").Return(nil)
+	sharedPool := new(poolMock)
+	sharedPool.On("MoveCpuIDs", []uint{3}).Return(nil)
+
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool, stalePool})
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+
+	r, recorder := buildPoolSanityReconcilerObject([]runtime.Object{powerNodeObj, profileObj, workloadObj}, powerLibMock)
+	assert.NotNil(t, r)
+
+	err := r.RepairOrphans(context.Background())
+	assert.NoError(t, err)
+
+	sharedPool.AssertCalled(t, "MoveCpuIDs", []uint{3})
+	stalePool.AssertCalled(t, "Remove")
+	performancePool.AssertNotCalled(t, "Remove")
+	assert.Len(t, recorder.Events, 2)
+
+	// a second pass with nothing orphaned must not touch the library
+	performancePool = new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{core2})
+	sharedPool = new(poolMock)
+	powerLibMock = new(hostMock)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool})
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	r.PowerLibrary = powerLibMock
+
+	err = r.RepairOrphans(context.Background())
+	assert.NoError(t, err)
+	sharedPool.AssertNotCalled(t, "MoveCpuIDs", mock.Anything)
+}
+
+func TestPoolSanityReconciler_Reconstruct(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	powerNodeObj := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: IntelPowerNamespace},
+	}
+	profiles := []runtime.Object{
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "performance", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: "performance"},
+		},
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "balance-performance", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: "balance-performance"},
+		},
+	}
+	workload := func(profile string, cpus ...uint) *powerv1.PowerWorkload {
+		return &powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: profile + "-TestNode", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				Name:         profile + "-TestNode",
+				PowerProfile: profile,
+				Node:         powerv1.WorkloadNode{Name: nodeName, CpuIds: cpus},
+			},
+		}
+	}
+
+	cores := make(map[uint]*coreMock)
+	for _, id := range []uint{2, 3, 4, 5, 6} {
+		cores[id] = new(coreMock)
+		cores[id].On("GetID").Return(id)
+	}
+
+	// performance is missing core 4 and has core 3 nobody claims, balance-performance matches its PowerWorkload,
+	// balance-power lost its PowerProfile while the Node Agent was down
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{cores[2], cores[3]})
+	performancePool.On("MoveCpuIDs", []uint{4}).Return(nil)
+	balancedPool := new(poolMock)
+	balancedPool.On("Name").Return("balance-performance")
+	balancedPool.On("Cpus").Return(&power.CpuList{cores[5]})
+	stalePool := new(poolMock)
+	stalePool.On("Name").Return("balance-power")
+	stalePool.On("Cpus").Return(&power.CpuList{cores[6]})
+	stalePool.On("Remove").Return(nil)
+	sharedPool := new(poolMock)
+	sharedPool.On("MoveCpuIDs", []uint{3}).Return(nil)
+
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool, balancedPool, stalePool})
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetExclusivePool", "performance").Return(performancePool)
+	powerLibMock.On("GetExclusivePool", "balance-performance").Return(balancedPool)
+
+	objs := append(profiles, powerNodeObj, workload("performance", 2, 4), workload("balance-performance", 5))
+	r, recorder := buildPoolSanityReconcilerObject(objs, powerLibMock)
+	assert.NotNil(t, r)
+
+	reconstruction, err := r.Reconstruct(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"balance-performance"}, reconstruction.Adopted)
+	assert.Equal(t, []string{"performance"}, reconstruction.Corrected)
+	assert.Equal(t, []string{"balance-power"}, reconstruction.Deleted)
+
+	performancePool.AssertCalled(t, "MoveCpuIDs", []uint{4})
+	sharedPool.AssertCalled(t, "MoveCpuIDs", []uint{3})
+	stalePool.AssertCalled(t, "Remove")
+	balancedPool.AssertNotCalled(t, "MoveCpuIDs", mock.Anything)
+	assert.Len(t, recorder.Events, 1)
+}
@@ -18,24 +18,44 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/capacity"
+	"github.com/intel/kubernetes-power-manager/pkg/drift"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
 	"github.com/intel/kubernetes-power-manager/pkg/state"
 	"github.com/intel/kubernetes-power-manager/pkg/util"
 )
@@ -44,6 +64,22 @@ const (
 	ExtendedResourcePrefix = "power.intel.com/"
 	NodeAgentDSName        = "power-node-agent"
 	IntelPowerNamespace    = "intel-power"
+	// NodeAgentPodLabel and NodeAgentPodLabelValue select the Node Agent Pods, as in the DaemonSet manifest
+	NodeAgentPodLabel      = "name"
+	NodeAgentPodLabelValue = "power-node-agent-pod"
+	// PowerConfigProfileLabel marks the PowerProfiles created from a PowerConfig, it holds the PowerConfig's name
+	PowerConfigProfileLabel = "power.intel.com/powerconfig"
+	// ForceProfileDeletionAnnotation on a PowerConfig deletes the PowerProfiles it no longer lists even while running
+	// Pods request them, it holds their names separated by commas or * for all of them
+	ForceProfileDeletionAnnotation = "power.intel.com/force-profile-deletion"
+	ForcedProfileDeletionReason    = "ForcedProfileDeletion"
+	// maxBlockingPods is how many of the Pods holding back a PowerProfile's deletion are named in the condition
+	maxBlockingPods = 10
+	// maxFailedNodes is how many of the Nodes keeping the PowerConfig from being Ready are named in a condition
+	maxFailedNodes = 10
+	// ExtendedResourcesFinalizer holds a PowerConfig on deletion until the PowerProfile Extended Resources are off
+	// the Nodes
+	ExtendedResourcesFinalizer = "power.intel.com/extended-resources"
 )
 
 var NodeAgentDaemonSetPath = "/power-manifests/power-node-agent-ds.yaml"
@@ -51,17 +87,27 @@ var NodeAgentDaemonSetPath = "/power-manifests/power-node-agent-ds.yaml"
 // PowerConfigReconciler reconciles a PowerConfig object
 type PowerConfigReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
-	State  *state.PowerNodeData
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	State    *state.PowerNodeData
+	Recorder record.EventRecorder
+	// MaxConcurrentReconciles is how many objects are reconciled at once, 1 if 0
+	MaxConcurrentReconciles int
 }
 
+const NoMatchingNodesReason = "NoMatchingNodes"
+
 // +kubebuilder:rbac:groups=power.intel.com,resources=powerconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=power.intel.com,resources=powerconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodegroups,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups="",resources=nodes/status,verbs=get;patch
 
 func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
 	logger := r.Log.WithValues("powerconfig", req.NamespacedName)
+	changes := logging.NewChangeSummary()
+	defer changes.Log(logger)
 
 	if req.Namespace != IntelPowerNamespace {
 		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
@@ -70,7 +116,7 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 
 	configs := &powerv1.PowerConfigList{}
 	logger.V(5).Info("Retrieving PowerConfigList")
-	err := r.Client.List(context.TODO(), configs)
+	err := r.Client.List(c, configs)
 	if err != nil {
 		logger.Error(err, "error retrieving PowerConfigList")
 		return ctrl.Result{}, err
@@ -78,14 +124,15 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 
 	config := &powerv1.PowerConfig{}
 	logger.V(5).Info("Retrieving PowerConfig")
-	err = r.Client.Get(context.TODO(), req.NamespacedName, config)
+	err = r.Client.Get(c, req.NamespacedName, config)
 	if err != nil {
 		logger.V(5).Info("Failed retrieving the PowerConfig, Checking if exist")
 		if errors.IsNotFound(err) {
+			metrics.PowerConfigMatchedNodes.DeleteLabelValues(req.Name)
 			// PowerConfig was deleted, if the number PowerConfigs is > 0, don't delete the PowerProfiles
 			if len(configs.Items) == 0 {
 				powerProfiles := &powerv1.PowerProfileList{}
-				err = r.Client.List(context.TODO(), powerProfiles)
+				err = r.Client.List(c, powerProfiles)
 				logger.V(5).Info("Retrieving all PowerProfiles in the cluster")
 				if err != nil {
 					logger.Error(err, "error retrieving PowerProfiles")
@@ -93,17 +140,18 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 				}
 
 				for _, profile := range powerProfiles.Items {
-					err = r.Client.Delete(context.TODO(), &profile)
+					err = r.Client.Delete(c, &profile)
 					logger.V(5).Info("Deleting Power Profile %s", profile.Name)
 					if err != nil {
 						logger.Error(err, fmt.Sprintf("error deleting Power Profile '%s' from cluster", profile.Name))
 						return ctrl.Result{}, err
 					}
+					changes.ResourceRemoved("PowerProfile", profile.Name)
 				}
 
 				// Make sure all PowerWorkloads have been removed
 				powerWorkloads := &powerv1.PowerWorkloadList{}
-				err = r.Client.List(context.TODO(), powerWorkloads)
+				err = r.Client.List(c, powerWorkloads)
 				logger.V(5).Info("Retrieving all Power Workload in the cluster")
 				if err != nil {
 					logger.Error(err, "error retrieving PowerWorkloads")
@@ -112,15 +160,16 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 
 				for _, workload := range powerWorkloads.Items {
 					logger.V(5).Info("Deleting Power Workload %s", workload.Name)
-					err = r.Client.Delete(context.TODO(), &workload)
+					err = r.Client.Delete(c, &workload)
 					if err != nil {
 						logger.Error(err, fmt.Sprintf("error deleting Power Workload '%s' from cluster", workload.Name))
 						return ctrl.Result{}, err
 					}
+					changes.ResourceRemoved("PowerWorkload", workload.Name)
 				}
 
 				powerNodes := &powerv1.PowerNodeList{}
-				err = r.Client.List(context.TODO(), powerNodes)
+				err = r.Client.List(c, powerNodes)
 				logger.V(5).Info("Retrieving all PowerNodes in the cluster")
 				if err != nil {
 					logger.Error(err, "error retrieving PowerNodes")
@@ -129,16 +178,17 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 
 				for _, node := range powerNodes.Items {
 					logger.V(5).Info("Deleting PowerNodes %s", node.Name)
-					err = r.Client.Delete(context.TODO(), &node)
+					err = r.Client.Delete(c, &node)
 					if err != nil {
 						logger.Error(err, fmt.Sprintf("error deleting PowerNode '%s' from cluster", node.Name))
 						return ctrl.Result{}, err
 					}
+					changes.ResourceRemoved("PowerNode", node.Name)
 				}
 
 				daemonSet := &appsv1.DaemonSet{}
 				logger.V(5).Info("Retrieving PowerNodeAgent DaemonSet")
-				err = r.Client.Get(context.TODO(), client.ObjectKey{
+				err = r.Client.Get(c, client.ObjectKey{
 					Name:      NodeAgentDSName,
 					Namespace: IntelPowerNamespace,
 				}, daemonSet)
@@ -148,11 +198,12 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 						return ctrl.Result{}, err
 					}
 				} else {
-					err = r.Client.Delete(context.TODO(), daemonSet)
+					err = r.Client.Delete(c, daemonSet)
 					if err != nil {
 						logger.Error(err, "error deleting Power Node Agent Daemonset")
 						return ctrl.Result{}, err
 					}
+					changes.ResourceRemoved("DaemonSet", daemonSet.Name)
 				}
 			}
 
@@ -163,23 +214,84 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	if !config.DeletionTimestamp.IsZero() {
+		// a PowerConfig being deleted no longer matches any Node
+		metrics.PowerConfigMatchedNodes.DeleteLabelValues(config.Name)
+		if controllerutil.ContainsFinalizer(config, CleanupFinalizer) {
+			waiting, err := CleanUp(c, r.Client, config, DefaultCleanupTimeout, time.Now(), logger, changes)
+			if err != nil {
+				logger.Error(err, "error cleaning up the cluster")
+				return ctrl.Result{}, err
+			}
+			if len(waiting) > 0 {
+				logger.V(5).Info("Waiting for the Node Agents to revert their Nodes", "nodes", waiting)
+				return ctrl.Result{RequeueAfter: cleanupRequeueInterval}, nil
+			}
+			r.forgetNodes()
+			return ctrl.Result{}, nil
+		}
+		if !controllerutil.ContainsFinalizer(config, ExtendedResourcesFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		// without a PowerConfig none of the PowerProfiles are advertised any more
+		err = removeProfileResources(c, r.Client, func(string) bool { return true }, changes)
+		if err != nil {
+			logger.Error(err, "error removing the PowerProfile Extended Resources from the Nodes")
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(config, ExtendedResourcesFinalizer)
+		err = r.Client.Update(c, config)
+		if err != nil {
+			logger.Error(err, "error removing the Extended Resources finalizer of the PowerConfig")
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		r.forgetNodes()
+		return ctrl.Result{}, nil
+	}
+
 	if len(configs.Items) > 1 {
 		logger.V(5).Info("Checking to make sure there is only one PowerConfig")
-		moreThanOneConfigError := errors.NewServiceUnavailable("Cannot have more than one PowerConfig")
+		moreThanOneConfigError := powererrors.NewConflict("Cannot have more than one PowerConfig")
 		logger.Error(moreThanOneConfigError, "error reconciling PowerConfig")
 
-		err = r.Client.Delete(context.TODO(), config)
+		err = r.Client.Delete(c, config)
 		if err != nil {
 			logger.Error(err, "error deleting PowerConfig")
 			return ctrl.Result{}, err
 		}
+		changes.ResourceRemoved("PowerConfig", config.Name)
 
 		return ctrl.Result{}, nil
 	}
 
+	// the finalizers hold the PowerConfig on deletion until the clean up has finished and the Extended Resources
+	// are off the Nodes
+	if config.Spec.CleanUpAll != controllerutil.ContainsFinalizer(config, CleanupFinalizer) ||
+		!controllerutil.ContainsFinalizer(config, ExtendedResourcesFinalizer) {
+		if config.Spec.CleanUpAll {
+			controllerutil.AddFinalizer(config, CleanupFinalizer)
+		} else {
+			controllerutil.RemoveFinalizer(config, CleanupFinalizer)
+		}
+		controllerutil.AddFinalizer(config, ExtendedResourcesFinalizer)
+		err = r.Client.Update(c, config)
+		if err != nil {
+			logger.Error(err, "error updating the clean up finalizer of the PowerConfig")
+			return ctrl.Result{}, err
+		}
+	}
+
+	groups, err := r.configNodeGroups(c, config, &logger)
+	if err != nil {
+		logger.Error(err, "error retrieving the PowerNodeGroups of the PowerConfig")
+		return ctrl.Result{}, err
+	}
+	expanded := expandNodeGroups(config, groups)
+
 	// Create PowerNodeAgent DaemonSet
 	logger.V(5).Info("Creating PowerNodeAgent DaemonSet")
-	err = r.createDaemonSetIfNotPresent(config, NodeAgentDaemonSetPath, &logger)
+	err = r.createDaemonSetIfNotPresent(c, config, groups, NodeAgentDaemonSetPath, &logger)
 	if err != nil {
 		logger.Error(err, "Error creating Power Node Agent")
 		return ctrl.Result{}, err
@@ -195,19 +307,79 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 			"Custom Devices", CustomDevices)
 	}
 
-	logger.V(5).Info("Confirming desired Nodes match the PowerNodeSelector")
-	err = r.Client.List(context.TODO(), labelledNodeList, client.MatchingLabels(listOption))
+	if len(config.Spec.NodeGroups) > 0 {
+		logger.V(5).Info("Selecting the Nodes of the PowerNodeGroups", "nodeGroups", config.Spec.NodeGroups)
+		labelledNodeList.Items, err = r.groupNodes(c, groups)
+		if err != nil {
+			logger.Error(err, "Failed to list the Nodes of the PowerNodeGroups")
+			return ctrl.Result{}, err
+		}
+	} else {
+		logger.V(5).Info("Confirming desired Nodes match the PowerNodeSelector")
+		err = r.Client.List(c, labelledNodeList, client.MatchingLabels(listOption))
+		if err != nil {
+			logger.Info("Failed to list Nodes with PowerNodeSelector", listOption)
+			return ctrl.Result{}, err
+		}
+	}
+
+	metrics.PowerConfigMatchedNodes.WithLabelValues(config.Name).Set(float64(len(labelledNodeList.Items)))
+	if len(config.Spec.NodeGroups) > 0 && len(labelledNodeList.Items) == 0 {
+		logger.Info("PowerNodeGroups do not match any Nodes", "nodeGroups", config.Spec.NodeGroups)
+		if r.Recorder != nil {
+			r.Recorder.Event(config, corev1.EventTypeWarning, NoMatchingNodesReason,
+				fmt.Sprintf("PowerNodeGroups %v do not match any Nodes in the cluster", config.Spec.NodeGroups))
+		}
+	} else if len(labelledNodeList.Items) == 0 {
+		// Most likely a typo in the selector, without this nothing tells the user why no Node is configured
+		logger.Info("PowerNodeSelector does not match any Nodes", "powerNodeSelector", listOption)
+		if r.Recorder != nil {
+			r.Recorder.Event(config, corev1.EventTypeWarning, NoMatchingNodesReason,
+				fmt.Sprintf("PowerNodeSelector %v does not match any Nodes in the cluster", listOption))
+		}
+	}
+
+	selectedNodes := make([]corev1.Node, 0, len(labelledNodeList.Items))
+	for i := range labelledNodeList.Items {
+		node := &labelledNodeList.Items[i]
+		if !isExcludedNode(node) {
+			selectedNodes = append(selectedNodes, *node)
+			continue
+		}
+		logger.V(5).Info("Node is excluded from power management", "node", node.Name)
+		err = releaseExcludedNode(c, r.Client, node, r.State, changes)
+		if err != nil {
+			logger.Error(err, "error releasing the excluded Node", "node", node.Name)
+			return ctrl.Result{}, err
+		}
+	}
+	labelledNodeList.Items = selectedNodes
+
+	missing, err := r.missingProfiles(c, expanded)
 	if err != nil {
-		logger.Info("Failed to list Nodes with PowerNodeSelector", listOption)
+		logger.Error(err, "error retrieving the PowerProfiles")
 		return ctrl.Result{}, err
 	}
+	if len(missing) > 0 {
+		logger.Info("PowerConfig lists PowerProfiles that don't exist and can't be created, their capacity isn't advertised", "profiles", missing)
+	}
+	setMissingProfilesCondition(config, missing)
 
+	domainShares := failureDomainShares(config.Spec.FailureDomainCapacity, labelledNodeList.Items)
+	for node, shares := range domainShares {
+		for _, profile := range missing {
+			delete(shares, profile)
+		}
+		if len(shares) == 0 {
+			delete(domainShares, node)
+		}
+	}
 	for _, node := range labelledNodeList.Items {
 		logger.V(5).Info("Updating the Node Name")
 		r.State.UpdatePowerNodeData(node.Name)
 
 		powerNode := &powerv1.PowerNode{}
-		err = r.Client.Get(context.TODO(), client.ObjectKey{
+		err = r.Client.Get(c, client.ObjectKey{
 			Namespace: IntelPowerNamespace,
 			Name:      node.Name,
 		}, powerNode)
@@ -228,28 +400,93 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 				}
 
 				powerNode.Spec = *powerNodeSpec
-				err = r.Client.Create(context.TODO(), powerNode)
+				err = r.Client.Create(c, powerNode)
 				if err != nil {
 					logger.Error(err, "Error creating PowerNode CRD")
 					return ctrl.Result{}, err
 				}
+				changes.NodeTouched(node.Name)
+				changes.ResourceAdded("PowerNode", node.Name)
 			} else {
 				return ctrl.Result{}, err
 			}
 		}
 
 		powerNode.Spec.CustomDevices = CustomDevices
-		err := r.Client.Update(context.TODO(), powerNode)
+		err := r.Client.Update(c, powerNode)
 		if err != nil {
 			logger.Error(err, "Failed to update PowerNode with custom Devices.")
 			return ctrl.Result{}, err
 		}
+
+		effective := effectiveProfiles(expanded, &node, &logger)
+		var nodeGroups []string
+		if len(config.Spec.NodeGroups) > 0 {
+			effective = offeredProfiles(effective, config, groups, &node)
+			nodeGroups = nodeGroupNames(groups, &node)
+		}
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			err := r.Client.Get(c, client.ObjectKeyFromObject(powerNode), powerNode)
+			if err != nil {
+				return err
+			}
+			if reflect.DeepEqual(powerNode.Status.EffectiveProfiles, effective) &&
+				reflect.DeepEqual(powerNode.Status.DomainCapacity, domainShares[node.Name]) &&
+				reflect.DeepEqual(powerNode.Status.NodeGroups, nodeGroups) {
+				return nil
+			}
+			powerNode.Status.EffectiveProfiles = effective
+			powerNode.Status.DomainCapacity = domainShares[node.Name]
+			powerNode.Status.NodeGroups = nodeGroups
+			return r.Client.Status().Update(c, powerNode)
+		})
+		if err != nil {
+			logger.Error(err, "Failed to update the effective PowerProfiles of the PowerNode")
+			return ctrl.Result{}, err
+		}
+
+		// the Node Agent removes the taint once it has applied the PowerProfiles
+		if config.Spec.UnconfiguredTaint && powerNode.Status.ConfiguredTime == nil && !hasUnconfiguredTaint(&node) {
+			logger.V(5).Info("Tainting the Node until it is power configured", "node", node.Name)
+			err = setUnconfiguredTaint(c, r.Client, &node, true)
+			if err != nil {
+				logger.Error(err, "Failed to taint the Node as unconfigured")
+				return ctrl.Result{}, err
+			}
+			changes.NodeTouched(node.Name)
+		}
 	}
 
 	config.Status.Nodes = r.State.PowerNodeList
+	config.Status.NodeCount = len(r.State.PowerNodeList)
 	config.Spec.CustomDevices = CustomDevices
 	logger.V(5).Info("Configured PowerNode added to the PowerNodeList")
-	err = r.Client.Status().Update(context.TODO(), config)
+
+	removable, blocked, err := r.unlistedProfiles(c, config, expanded)
+	if err != nil {
+		logger.Error(err, "error checking the PowerProfiles no longer listed")
+		return ctrl.Result{}, err
+	}
+	if len(blocked) > 0 {
+		logger.Info("Not deleting PowerProfiles that running Pods still request", "profiles", blocked)
+	}
+	setBlockedDeletionCondition(config, blocked)
+	powerState, err := drift.ComputeState(c, r.Client, IntelPowerNamespace)
+	if err != nil {
+		logger.Error(err, "error computing the power state of the PowerNodes")
+		return ctrl.Result{}, err
+	}
+	config.Status.DesiredStateChecksum = powerState.DesiredChecksum
+	config.Status.ObservedStateChecksum = powerState.ObservedChecksum
+	config.Status.Converged = powerState.Converged
+	failures, err := r.nodeFailures(c, config, powerState.Converged)
+	if err != nil {
+		logger.Error(err, "error checking the Nodes of the PowerConfig")
+		return ctrl.Result{}, err
+	}
+	config.Status.NodeFailures = failures
+	setNodeConditions(config, failures)
+	err = r.Client.Status().Update(c, config)
 	if err != nil {
 		logger.Error(err, "Failed to update PowerConfig")
 		return ctrl.Result{}, err
@@ -257,10 +494,17 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 
 	// Create the PowerProfiles that were requested in the PowerConfig if it doesn't exist
 	// Delete any PowerProfiles that are not being requested but exist
-	for _, profile := range config.Spec.PowerProfiles {
+	for _, profile := range expanded.Spec.PowerProfiles {
+		profileSpec, err := profileSpecFromPolicy(profile, expanded.Spec.ProfilePolicies)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("error configuring PowerProfile '%s'", profile))
+			continue
+		}
+		applyGroupRollout(&profileSpec, groups)
+
 		logger.V(5).Info("Checking if Power Profile exists %s", profile)
 		profileFromCluster := &powerv1.PowerProfile{}
-		err = r.Client.Get(context.TODO(), client.ObjectKey{
+		err = r.Client.Get(c, client.ObjectKey{
 			Name:      profile,
 			Namespace: IntelPowerNamespace,
 		}, profileFromCluster)
@@ -268,65 +512,806 @@ func (r *PowerConfigReconciler) Reconcile(c context.Context, req ctrl.Request) (
 			if errors.IsNotFound(err) {
 				// PowerProfile does not exist, so we need to create it
 				logger.V(5).Info("Creating Power Profile %s", profile)
-				epp := strings.Replace(profile, "-", "_", 1)
-				powerProfileSpec := &powerv1.PowerProfileSpec{
-					Name: profile,
-					Epp:  epp,
-				}
 				powerProfile := &powerv1.PowerProfile{
 					ObjectMeta: metav1.ObjectMeta{
 						Namespace: IntelPowerNamespace,
 						Name:      profile,
+						Labels:    map[string]string{PowerConfigProfileLabel: config.Name},
 					},
 				}
-				powerProfile.Spec = *powerProfileSpec
-				err = r.Client.Create(context.TODO(), powerProfile)
+				powerProfile.Spec = profileSpec
+				err = r.Client.Create(c, powerProfile)
 				if err != nil {
 					logger.Error(err, fmt.Sprintf("error creating PowerProfile '%s'", profile))
 					return ctrl.Result{}, err
 				}
-			} else {
-				logger.Error(err, fmt.Sprintf("error retrieving PowerProfile '%s'", profile))
+				changes.ResourceAdded("PowerProfile", profile)
+				continue
+			}
+
+			logger.Error(err, fmt.Sprintf("error retrieving PowerProfile '%s'", profile))
+			return ctrl.Result{}, err
+		}
+
+		// Keep PowerProfiles created from the PowerConfig in line with their policy, user-created ones are left alone
+		if !isConfigProfile(profileFromCluster) {
+			continue
+		}
+		if profileSpec.Governor == "" {
+			profileSpec.Governor = profileFromCluster.Spec.Governor
+		}
+		profileSpec.MaxPreset = profileFromCluster.Spec.MaxPreset
+		profileSpec.ManageMin = profileFromCluster.Spec.ManageMin
+		profileSpec.ManageMax = profileFromCluster.Spec.ManageMax
+		if profileSpec.ApplyDeadlineSeconds == 0 {
+			profileSpec.ApplyDeadlineSeconds = profileFromCluster.Spec.ApplyDeadlineSeconds
+			profileSpec.RollbackOnDeadline = profileFromCluster.Spec.RollbackOnDeadline
+		}
+		if !reflect.DeepEqual(profileFromCluster.Spec, profileSpec) {
+			logger.V(5).Info("Updating Power Profile to match its policy", "profile", profile)
+			profileFromCluster.Spec = profileSpec
+			err = r.Client.Update(c, profileFromCluster)
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("error updating PowerProfile '%s'", profile))
 				return ctrl.Result{}, err
 			}
+			changes.ResourceUpdated("PowerProfile", profile)
 		}
+	}
 
-		// If the PowerProfile/PowerWorkload was successfull retrieved, we don't need to do anything
+	// Delete the PowerProfiles created from the PowerConfig that it no longer lists
+	for _, profile := range removable {
+		err = r.Client.Delete(c, &profile)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("error deleting PowerProfile '%s'", profile.Spec.Name))
+			return ctrl.Result{}, err
+		}
+		changes.ResourceRemoved("PowerProfile", profile.Name)
 	}
 
-	powerProfiles := &powerv1.PowerProfileList{}
-	logger.V(5).Info("Retrieving the list of PowerProfiles")
-	err = r.Client.List(context.TODO(), powerProfiles)
+	kept, err := r.keptProfiles(c, expanded, blocked)
 	if err != nil {
-		logger.Error(err, "error retrieving PowerProfile List")
+		logger.Error(err, "error retrieving the PowerProfiles kept on the Nodes")
+		return ctrl.Result{}, err
+	}
+	err = removeProfileResources(c, r.Client, func(profile string) bool { return !kept[profile] }, changes)
+	if err != nil {
+		logger.Error(err, "error removing the Extended Resources of PowerProfiles no longer listed")
 		return ctrl.Result{}, err
 	}
 
-	// Check PowerProfiles for any that are no longer requested; only check base profiles
-	for _, profile := range powerProfiles.Items {
-		logger.V(5).Info("Checking if Power Profile exists and is not requested")
-		convertedName := strings.Replace(profile.Spec.Name, "-", "_", 1)
-		if _, exists := profilePercentages[convertedName]; exists {
-			if !util.StringInStringList(profile.Spec.Name, config.Spec.PowerProfiles) {
-				err = r.Client.Delete(context.TODO(), &profile)
-				if err != nil {
-					logger.Error(err, fmt.Sprintf("error deleting PowerProfile '%s'", profile.Spec.Name))
-					return ctrl.Result{}, err
+	return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+}
+
+// forgetNodes purges the deleted PowerConfig's Nodes from the shared state, so a PowerConfig created after it
+// doesn't report them before it has selected them itself
+func (r *PowerConfigReconciler) forgetNodes() {
+	nodes := append([]string{}, r.State.PowerNodeList...)
+	for _, node := range nodes {
+		r.State.DeletePowerNodeData(node)
+	}
+}
+
+// keptProfiles returns the names of the PowerProfiles whose Extended Resources stay on the Nodes: those the
+// PowerConfig lists, those held back for the Pods requesting them, and the PowerProfiles that exist and aren't being
+// deleted, user-created ones among them
+func (r *PowerConfigReconciler) keptProfiles(c context.Context, expanded *powerv1.PowerConfig, blocked map[string][]string) (map[string]bool, error) {
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(c, profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[string]bool)
+	for _, profile := range expanded.Spec.PowerProfiles {
+		kept[profile] = true
+	}
+	for profile := range blocked {
+		kept[profile] = true
+	}
+	for _, profile := range profiles.Items {
+		if profile.DeletionTimestamp.IsZero() {
+			kept[profile.Spec.Name] = true
+		}
+	}
+
+	return kept, nil
+}
+
+// removeProfileResources takes the Extended Resources of the PowerProfiles stale reports on off the capacity and
+// allocatable of every Node, along with their capacity labels. The Node Agents only give up those of their own Node
+// when they see a PowerProfile deleted, Nodes whose Agent was gone at the time kept them forever
+func removeProfileResources(c context.Context, cl client.Client, stale func(profile string) bool, changes *logging.ChangeSummary) error {
+	nodes := &corev1.NodeList{}
+	err := cl.List(c, nodes)
+	if err != nil {
+		return err
+	}
+
+	results := new(multierror.Error)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		err = removeNodeProfileResources(c, cl, node, stale, changes)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("removing PowerProfile resources from Node %s: %w", node.Name, err))
+		}
+	}
+
+	return results.ErrorOrNil()
+}
+
+func removeNodeProfileResources(c context.Context, cl client.Client, node *corev1.Node, stale func(profile string) bool, changes *logging.ChangeSummary) error {
+	patch := client.MergeFrom(node.DeepCopy())
+	removed := make([]string, 0)
+	for label := range node.Labels {
+		if strings.HasPrefix(label, CapacityLabelPrefix) && stale(strings.TrimPrefix(label, CapacityLabelPrefix)) {
+			delete(node.Labels, label)
+			removed = append(removed, label)
+		}
+	}
+	if len(removed) > 0 {
+		err := cl.Patch(c, node, patch)
+		if err != nil {
+			return err
+		}
+		changes.NodeTouched(node.Name)
+		for _, label := range removed {
+			changes.ResourceRemoved("NodeLabel", label)
+		}
+	}
+
+	operations := make([]map[string]interface{}, 0)
+	for field, resources := range map[string]corev1.ResourceList{
+		"capacity":    node.Status.Capacity,
+		"allocatable": node.Status.Allocatable,
+	} {
+		for name := range resources {
+			if strings.HasPrefix(string(name), ExtendedResourcePrefix) && stale(strings.TrimPrefix(string(name), ExtendedResourcePrefix)) {
+				operations = append(operations, map[string]interface{}{
+					"op":   "remove",
+					"path": "/status/" + field + "/" + jsonPointerEscape(string(name)),
+				})
+				if field == "capacity" {
+					changes.ResourceRemoved("ExtendedResource", string(name))
 				}
 			}
 		}
 	}
+	if len(operations) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(operations)
+	if err != nil {
+		return err
+	}
+	err = cl.Status().Patch(c, node, client.RawPatch(types.JSONPatchType, data))
+	if err != nil {
+		return err
+	}
+	changes.NodeTouched(node.Name)
 
-	return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	return nil
+}
+
+// configNodeGroups returns the PowerNodeGroups the PowerConfig lists, in its order. Groups that don't exist are
+// logged and left out, their Nodes aren't selected until they're created
+func (r *PowerConfigReconciler) configNodeGroups(c context.Context, config *powerv1.PowerConfig, logger *logr.Logger) ([]powerv1.PowerNodeGroup, error) {
+	groups := make([]powerv1.PowerNodeGroup, 0, len(config.Spec.NodeGroups))
+	for _, name := range config.Spec.NodeGroups {
+		group := powerv1.PowerNodeGroup{}
+		err := r.Client.Get(c, client.ObjectKey{Name: name, Namespace: IntelPowerNamespace}, &group)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				logger.Info("PowerConfig lists a PowerNodeGroup that doesn't exist", "nodeGroup", name)
+				continue
+			}
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// expandNodeGroups returns a copy of the PowerConfig with what its PowerNodeGroups add: their PowerProfiles, the
+// policies of those the PowerConfig has none for, and their overrides as node groups after the PowerConfig's own
+func expandNodeGroups(config *powerv1.PowerConfig, groups []powerv1.PowerNodeGroup) *powerv1.PowerConfig {
+	expanded := config.DeepCopy()
+	for _, group := range groups {
+		for _, profile := range group.Spec.PowerProfiles {
+			if !util.StringInStringList(profile, expanded.Spec.PowerProfiles) {
+				expanded.Spec.PowerProfiles = append(expanded.Spec.PowerProfiles, profile)
+			}
+			policy, exists := group.Spec.ProfilePolicies[profile]
+			if _, configured := expanded.Spec.ProfilePolicies[profile]; exists && !configured {
+				if expanded.Spec.ProfilePolicies == nil {
+					expanded.Spec.ProfilePolicies = make(map[string]powerv1.ProfilePolicy)
+				}
+				expanded.Spec.ProfilePolicies[profile] = *policy.DeepCopy()
+			}
+		}
+		if len(group.Spec.ProfilePolicies) > 0 {
+			expanded.Spec.NodeGroupPolicies = append(expanded.Spec.NodeGroupPolicies, powerv1.NodeGroupPolicy{
+				Name:            group.Name,
+				NodeSelector:    group.Spec.NodeSelector,
+				ProfilePolicies: group.Spec.ProfilePolicies,
+			})
+		}
+	}
+
+	return expanded
+}
+
+// groupNodes returns the Nodes in any of the PowerNodeGroups
+func (r *PowerConfigReconciler) groupNodes(c context.Context, groups []powerv1.PowerNodeGroup) ([]corev1.Node, error) {
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	nodes := &corev1.NodeList{}
+	err := r.Client.List(c, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]corev1.Node, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		if len(nodeGroupNames(groups, &node)) > 0 {
+			selected = append(selected, node)
+		}
+	}
+
+	return selected, nil
+}
+
+// offeredProfiles keeps the effective PowerProfiles the Node is offered, those of the PowerConfig and those of
+// the PowerNodeGroups it's in
+func offeredProfiles(effective []powerv1.EffectiveProfile, config *powerv1.PowerConfig, groups []powerv1.PowerNodeGroup, node *corev1.Node) []powerv1.EffectiveProfile {
+	offered := make([]powerv1.EffectiveProfile, 0, len(effective))
+	for _, profile := range effective {
+		if util.StringInStringList(profile.Name, config.Spec.PowerProfiles) {
+			offered = append(offered, profile)
+			continue
+		}
+		for i := range groups {
+			if nodeGroupMatches(&groups[i], node) && util.StringInStringList(profile.Name, groups[i].Spec.PowerProfiles) {
+				offered = append(offered, profile)
+				break
+			}
+		}
+	}
+
+	return offered
+}
+
+// nodeGroupNames returns the names of the PowerNodeGroups the Node is in
+func nodeGroupNames(groups []powerv1.PowerNodeGroup, node *corev1.Node) []string {
+	var names []string
+	for i := range groups {
+		if nodeGroupMatches(&groups[i], node) {
+			names = append(names, groups[i].Name)
+		}
+	}
+
+	return names
+}
+
+// applyGroupRollout gives the PowerProfile the rollout of the first PowerNodeGroup that offers it and has one
+func applyGroupRollout(spec *powerv1.PowerProfileSpec, groups []powerv1.PowerNodeGroup) {
+	for _, group := range groups {
+		if group.Spec.Rollout == nil || !util.StringInStringList(spec.Name, group.Spec.PowerProfiles) {
+			continue
+		}
+		spec.ApplyDeadlineSeconds = group.Spec.Rollout.ApplyDeadlineSeconds
+		spec.RollbackOnDeadline = group.Spec.Rollout.RollbackOnDeadline
+		return
+	}
+}
+
+// unlistedProfiles returns the PowerProfiles created from the PowerConfig that it no longer lists and can be deleted,
+// and the names of the running Pods that still request the others, keyed by profile. Their capacity would be taken
+// away from under the Pods, so they're only deleted once the Pods are gone or the deletion is forced
+func (r *PowerConfigReconciler) unlistedProfiles(c context.Context, config *powerv1.PowerConfig, expanded *powerv1.PowerConfig) ([]powerv1.PowerProfile, map[string][]string, error) {
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(c, profiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	unlisted := make([]powerv1.PowerProfile, 0)
+	for _, profile := range profiles.Items {
+		if isConfigProfile(&profile) && !util.StringInStringList(profile.Spec.Name, expanded.Spec.PowerProfiles) {
+			unlisted = append(unlisted, profile)
+		}
+	}
+	if len(unlisted) == 0 {
+		return nil, nil, nil
+	}
+
+	requesting, err := r.requestingPods(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	forced := strings.Split(config.Annotations[ForceProfileDeletionAnnotation], ",")
+	removable := make([]powerv1.PowerProfile, 0, len(unlisted))
+	blocked := make(map[string][]string)
+	for _, profile := range unlisted {
+		pods := requesting[corev1.ResourceName(ExtendedResourcePrefix+profile.Spec.Name)]
+		if len(pods) > 0 && !util.StringInStringList("*", forced) && !util.StringInStringList(profile.Spec.Name, forced) {
+			blocked[profile.Spec.Name] = pods
+			continue
+		}
+		if len(pods) > 0 && r.Recorder != nil {
+			r.Recorder.Event(config, corev1.EventTypeWarning, ForcedProfileDeletionReason,
+				fmt.Sprintf("Deleting PowerProfile %s while Pods %s still request it", profile.Spec.Name, strings.Join(pods, ", ")))
+		}
+		removable = append(removable, profile)
+	}
+
+	return removable, blocked, nil
+}
+
+// requestingPods returns the Pods that haven't terminated, sorted by namespace and name, keyed by the PowerProfile
+// Extended Resources they request
+func (r *PowerConfigReconciler) requestingPods(c context.Context) (map[corev1.ResourceName][]string, error) {
+	pods := &corev1.PodList{}
+	err := r.Client.List(c, pods)
+	if err != nil {
+		return nil, err
+	}
+
+	requesting := make(map[corev1.ResourceName][]string)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		resources := make(map[corev1.ResourceName]bool)
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			for name := range container.Resources.Requests {
+				resources[name] = true
+			}
+			for name := range container.Resources.Limits {
+				resources[name] = true
+			}
+		}
+		for name := range resources {
+			if strings.HasPrefix(string(name), ExtendedResourcePrefix) {
+				requesting[name] = append(requesting[name], pod.Namespace+"/"+pod.Name)
+			}
+		}
+	}
+	for _, names := range requesting {
+		sort.Strings(names)
+	}
+
+	return requesting, nil
+}
+
+// setBlockedDeletionCondition records in the PowerConfig status which PowerProfiles it no longer lists are kept for
+// the Pods still requesting them
+func setBlockedDeletionCondition(config *powerv1.PowerConfig, blocked map[string][]string) {
+	condition := metav1.Condition{
+		Type:               powerv1.ConditionBlockedDeletion,
+		Status:             metav1.ConditionFalse,
+		Reason:             powerv1.ReasonNoPodsRequestProfiles,
+		Message:            "No running Pods request the PowerProfiles the PowerConfig no longer lists",
+		ObservedGeneration: config.Generation,
+	}
+	if len(blocked) > 0 {
+		profiles := make([]string, 0, len(blocked))
+		for profile := range blocked {
+			profiles = append(profiles, profile)
+		}
+		sort.Strings(profiles)
+		held := make([]string, 0, len(profiles))
+		for _, profile := range profiles {
+			pods := blocked[profile]
+			named := strings.Join(pods, ", ")
+			if len(pods) > maxBlockingPods {
+				named = fmt.Sprintf("%s and %d more", strings.Join(pods[:maxBlockingPods], ", "), len(pods)-maxBlockingPods)
+			}
+			held = append(held, fmt.Sprintf("%s by %s", profile, named))
+		}
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = powerv1.ReasonPodsRequestProfiles
+		condition.Message = fmt.Sprintf("PowerProfiles no longer listed are kept while running Pods request them: %s. "+
+			"Set the %s annotation to delete them anyway", strings.Join(held, "; "), ForceProfileDeletionAnnotation)
+	}
+	meta.SetStatusCondition(&config.Status.Conditions, condition)
+}
+
+// nodeFailures returns why the Nodes the PowerConfig selected aren't configured or have failed, sorted by Node. A
+// Node is configured while its Node Agent has every current PowerProfile applied, and its settings only count as
+// drifted then
+func (r *PowerConfigReconciler) nodeFailures(c context.Context, config *powerv1.PowerConfig, converged bool) ([]powerv1.NodeFailure, error) {
+	var drifted map[string][]string
+	// with converged PowerNodes there are no drifts to compute
+	computed := converged
+
+	nodes := append([]string{}, config.Status.Nodes...)
+	sort.Strings(nodes)
+	failures := make([]powerv1.NodeFailure, 0)
+	for _, nodeName := range nodes {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(c, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, err
+			}
+			failures = append(failures, powerv1.NodeFailure{Node: nodeName, Reason: powerv1.FailurePowerNodeMissing,
+				Message: "No PowerNode has been created for the Node yet"})
+			continue
+		}
+		// the Node Agent keeps the PowerProfiles it hasn't applied in the PowerNode status, checking them again
+		// whenever the PowerProfiles change
+		if len(powerNode.Status.PendingProfiles) > 0 {
+			failures = append(failures, powerv1.NodeFailure{Node: nodeName, Reason: powerv1.FailureProfilesNotApplied,
+				Message: "The Node Agent hasn't applied " + strings.Join(powerNode.Status.PendingProfiles, ", ")})
+			continue
+		}
+		if powerNode.Status.ConfiguredTime == nil {
+			failures = append(failures, powerv1.NodeFailure{Node: nodeName, Reason: powerv1.FailureProfilesNotApplied,
+				Message: "The Node Agent hasn't applied every PowerProfile yet"})
+			continue
+		}
+		if !computed {
+			drifted, err = driftedSettings(c, r.Client)
+			if err != nil {
+				return nil, err
+			}
+			computed = true
+		}
+		if settings := drifted[nodeName]; len(settings) > 0 {
+			failures = append(failures, powerv1.NodeFailure{Node: nodeName, Reason: powerv1.FailureSettingsDrifted,
+				Message: "Settings differ from the Power CRs: " + strings.Join(settings, ", ")})
+		}
+	}
+
+	return failures, nil
+}
+
+// driftedSettings returns the settings of each Node that differ from the Power CRs
+func driftedSettings(c context.Context, cl client.Reader) (map[string][]string, error) {
+	drifts, err := drift.Compute(c, cl, IntelPowerNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	drifted := make(map[string][]string)
+	for _, nodeDrift := range drifts {
+		if nodeDrift.State == drift.StateDrift {
+			drifted[nodeDrift.Node] = append(drifted[nodeDrift.Node], nodeDrift.Setting)
+		}
+	}
+
+	return drifted, nil
+}
+
+// setNodeConditions records in the PowerConfig status whether the Node Agents of its Nodes have applied the
+// PowerProfiles, whether it is Degraded by Nodes that drifted since or by missing PowerProfiles, and whether it is
+// Ready, configured and not Degraded
+func setNodeConditions(config *powerv1.PowerConfig, failures []powerv1.NodeFailure) {
+	unconfigured := make([]string, 0)
+	failed := make([]string, 0)
+	for _, failure := range failures {
+		named := fmt.Sprintf("%s (%s)", failure.Node, failure.Message)
+		if failure.Reason == powerv1.FailureSettingsDrifted {
+			failed = append(failed, named)
+		} else {
+			unconfigured = append(unconfigured, named)
+		}
+	}
+
+	configured := metav1.Condition{
+		Type:               powerv1.ConditionNodesConfigured,
+		Status:             metav1.ConditionTrue,
+		Reason:             powerv1.ReasonAllNodesConfigured,
+		Message:            fmt.Sprintf("The Node Agents of all %d Nodes have applied every PowerProfile", len(config.Status.Nodes)),
+		ObservedGeneration: config.Generation,
+	}
+	switch {
+	case len(config.Status.Nodes) == 0:
+		configured.Status = metav1.ConditionFalse
+		configured.Reason = NoMatchingNodesReason
+		configured.Message = "The PowerConfig doesn't select any Nodes"
+	case len(unconfigured) > 0:
+		configured.Status = metav1.ConditionFalse
+		configured.Reason = powerv1.ReasonNodesNotConfigured
+		configured.Message = "Nodes not configured yet: " + nameNodes(unconfigured)
+	}
+
+	degraded := metav1.Condition{
+		Type:               powerv1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             powerv1.ReasonNoFailures,
+		Message:            "No Node has drifted from the Power CRs and every PowerProfile exists",
+		ObservedGeneration: config.Generation,
+	}
+	if len(failed) > 0 {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = powerv1.ReasonNodesFailed
+		degraded.Message = "Nodes failed: " + nameNodes(failed)
+	} else if missing := meta.FindStatusCondition(config.Status.Conditions, powerv1.ConditionMissingProfiles); missing != nil && missing.Status == metav1.ConditionTrue {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = missing.Reason
+		degraded.Message = missing.Message
+	}
+
+	ready := metav1.Condition{
+		Type:               powerv1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             powerv1.ReasonConfigured,
+		Message:            "Every Node is configured and nothing is degraded",
+		ObservedGeneration: config.Generation,
+	}
+	if configured.Status != metav1.ConditionTrue {
+		ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, configured.Reason, configured.Message
+	} else if degraded.Status == metav1.ConditionTrue {
+		ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, degraded.Reason, degraded.Message
+	}
+
+	meta.SetStatusCondition(&config.Status.Conditions, configured)
+	meta.SetStatusCondition(&config.Status.Conditions, degraded)
+	meta.SetStatusCondition(&config.Status.Conditions, ready)
+}
+
+// nameNodes joins the first maxFailedNodes entries, with how many more there are
+func nameNodes(entries []string) string {
+	if len(entries) > maxFailedNodes {
+		return fmt.Sprintf("%s and %d more", strings.Join(entries[:maxFailedNodes], "; "), len(entries)-maxFailedNodes)
+	}
+
+	return strings.Join(entries, "; ")
+}
+
+// missingProfiles returns the PowerProfiles the PowerConfig lists that have no PowerProfile in the cluster and
+// can't be created from a profile policy either, so no Node could ever apply them
+func (r *PowerConfigReconciler) missingProfiles(c context.Context, config *powerv1.PowerConfig) ([]string, error) {
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(c, profiles, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(profiles.Items))
+	for _, profile := range profiles.Items {
+		existing[profile.Name] = true
+	}
+
+	missing := make([]string, 0)
+	for _, profile := range config.Spec.PowerProfiles {
+		if existing[profile] {
+			continue
+		}
+		if _, err := profileSpecFromPolicy(profile, config.Spec.ProfilePolicies); err != nil {
+			missing = append(missing, profile)
+		}
+	}
+
+	return missing, nil
+}
+
+// setMissingProfilesCondition records in the PowerConfig status which of its PowerProfiles are missing
+func setMissingProfilesCondition(config *powerv1.PowerConfig, missing []string) {
+	condition := metav1.Condition{
+		Type:               powerv1.ConditionMissingProfiles,
+		Status:             metav1.ConditionFalse,
+		Reason:             powerv1.ReasonProfilesFound,
+		Message:            "Every PowerProfile exists or can be created from a profile policy",
+		ObservedGeneration: config.Generation,
+	}
+	if len(missing) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = powerv1.ReasonProfilesNotFound
+		condition.Message = fmt.Sprintf("No PowerProfile exists for %s and none can be created without a profile policy, "+
+			"their capacity isn't advertised", strings.Join(missing, ", "))
+	}
+	meta.SetStatusCondition(&config.Status.Conditions, condition)
+}
+
+// effectiveProfiles works out the settings the PowerConfig's PowerProfiles have on the Node: the cluster-wide
+// policies, overridden by those of the node groups the Node is in, in their order, and finally by the Node's own.
+// An override that leaves a PowerProfile invalid is logged and ignored
+func effectiveProfiles(config *powerv1.PowerConfig, node *corev1.Node, logger *logr.Logger) []powerv1.EffectiveProfile {
+	var effective []powerv1.EffectiveProfile
+	for _, profile := range config.Spec.PowerProfiles {
+		clusterSpec, err := profileSpecFromPolicy(profile, config.Spec.ProfilePolicies)
+		if err != nil {
+			// already reported when creating the PowerProfile
+			continue
+		}
+
+		source := powerv1.EffectiveSourceCluster
+		policy := config.Spec.ProfilePolicies[profile]
+		for _, group := range config.Spec.NodeGroupPolicies {
+			override, exists := group.ProfilePolicies[profile]
+			if !exists || !labels.SelectorFromSet(group.NodeSelector).Matches(labels.Set(node.Labels)) {
+				continue
+			}
+			policy = mergePolicy(policy, override)
+			source = powerv1.EffectiveSourceNodeGroup + group.Name
+		}
+		for _, nodePolicy := range config.Spec.NodePolicies {
+			override, exists := nodePolicy.ProfilePolicies[profile]
+			if !exists || nodePolicy.NodeName != node.Name {
+				continue
+			}
+			policy = mergePolicy(policy, override)
+			source = powerv1.EffectiveSourceNode
+		}
+
+		spec := clusterSpec
+		if source != powerv1.EffectiveSourceCluster {
+			spec, err = profileSpecFromPolicy(profile, map[string]powerv1.ProfilePolicy{profile: policy})
+			if err != nil {
+				logger.Error(err, "ignoring the overrides of the PowerProfile", "node", node.Name, "source", source)
+				spec = clusterSpec
+				source = powerv1.EffectiveSourceCluster
+			}
+		}
+
+		effective = append(effective, powerv1.EffectiveProfile{
+			Name:   profile,
+			Source: source,
+			ProfilePolicy: powerv1.ProfilePolicy{
+				Epp:      spec.Epp,
+				Max:      spec.Max,
+				Min:      spec.Min,
+				Governor: spec.Governor,
+				Capacity: spec.Capacity,
+			},
+		})
+	}
+
+	return effective
+}
+
+// failureDomainShares shares each failure domain's cap of a PowerProfile out between the domain's Nodes in
+// proportion to their CPUs, with the largest remainders rounded up so the shares add up to the cap. It returns
+// the shares of each Node by PowerProfile, Nodes without the topology label have none
+func failureDomainShares(domains *powerv1.FailureDomainCapacity, nodes []corev1.Node) map[string]map[string]int {
+	shares := make(map[string]map[string]int)
+	if domains == nil || domains.TopologyKey == "" || len(domains.MaxPerDomain) == 0 {
+		return shares
+	}
+
+	members := make(map[string][]corev1.Node)
+	for _, node := range nodes {
+		domain, labelled := node.Labels[domains.TopologyKey]
+		if labelled {
+			members[domain] = append(members[domain], node)
+		}
+	}
+
+	for _, domainNodes := range members {
+		sort.Slice(domainNodes, func(i, j int) bool { return domainNodes[i].Name < domainNodes[j].Name })
+		weights := make([]int64, len(domainNodes))
+		var total int64
+		for i, node := range domainNodes {
+			cpus := node.Status.Capacity[corev1.ResourceCPU]
+			weights[i] = cpus.Value()
+			total += weights[i]
+		}
+		if total == 0 {
+			// the CPU counts aren't known yet, share equally
+			for i := range weights {
+				weights[i] = 1
+			}
+			total = int64(len(weights))
+		}
+
+		for profile, domainMax := range domains.MaxPerDomain {
+			if domainMax < 0 {
+				continue
+			}
+			remainders := make([]int, len(domainNodes))
+			assigned := 0
+			for i, node := range domainNodes {
+				share := int(int64(domainMax) * weights[i] / total)
+				remainders[i] = int(int64(domainMax) * weights[i] % total)
+				if shares[node.Name] == nil {
+					shares[node.Name] = make(map[string]int)
+				}
+				shares[node.Name][profile] = share
+				assigned += share
+			}
+			order := make([]int, len(domainNodes))
+			for i := range order {
+				order[i] = i
+			}
+			sort.SliceStable(order, func(i, j int) bool { return remainders[order[i]] > remainders[order[j]] })
+			for _, i := range order[:domainMax-assigned] {
+				shares[domainNodes[i].Name][profile]++
+			}
+		}
+	}
+
+	return shares
+}
+
+// mergePolicy returns the policy with the fields set in the override replaced
+func mergePolicy(policy powerv1.ProfilePolicy, override powerv1.ProfilePolicy) powerv1.ProfilePolicy {
+	merged := *policy.DeepCopy()
+	if override.Epp != "" {
+		merged.Epp = override.Epp
+	}
+	if override.Max != 0 {
+		merged.Max = override.Max
+	}
+	if override.Min != 0 {
+		merged.Min = override.Min
+	}
+	if override.Governor != "" {
+		merged.Governor = override.Governor
+	}
+	if override.Capacity != nil {
+		merged.Capacity = override.Capacity.DeepCopy()
+	}
+
+	return merged
+}
+
+// profileSpecFromPolicy works out the PowerProfile the PowerConfig requests under the given name. Profiles named
+// after an EPP value can go without a policy, any other name needs one with either an EPP value or a frequency range
+func profileSpecFromPolicy(name string, policies map[string]powerv1.ProfilePolicy) (powerv1.PowerProfileSpec, error) {
+	spec := powerv1.PowerProfileSpec{
+		Name: name,
+		Epp:  strings.Replace(name, "-", "_", 1),
+	}
+	_, namedAfterEpp := eppDefaults[spec.Epp]
+	namedAfterEpp = namedAfterEpp && spec.Epp != ""
+
+	policy, exists := policies[name]
+	if !exists {
+		if !namedAfterEpp {
+			return spec, powererrors.NewInvalidProfile(name, "not named after an EPP value and has no profile policy")
+		}
+		return spec, nil
+	}
+
+	if policy.Epp != "" {
+		spec.Epp = strings.Replace(policy.Epp, "-", "_", 1)
+	} else if !namedAfterEpp {
+		spec.Epp = ""
+	}
+	spec.Max = policy.Max
+	spec.Min = policy.Min
+	spec.Governor = policy.Governor
+	spec.Capacity = policy.Capacity.DeepCopy()
+
+	if spec.Epp == "" && (spec.Max == 0 || spec.Min == 0) {
+		return spec, powererrors.NewInvalidProfile(name, "policy needs an EPP value or both a max and min frequency")
+	}
+	if spec.Capacity != nil && spec.Capacity.Expression != "" {
+		if _, err := capacity.CompileExpression(spec.Capacity.Expression); err != nil {
+			return spec, powererrors.NewInvalidProfile(name, "capacity expression: %v", err)
+		}
+	}
+
+	return spec, nil
+}
+
+// isConfigProfile reports whether the PowerProfile was created from the PowerConfig. Base profiles
+// created before profiles were labelled are recognised by being named after an EPP value
+func isConfigProfile(profile *powerv1.PowerProfile) bool {
+	if _, labelled := profile.Labels[PowerConfigProfileLabel]; labelled {
+		return true
+	}
+	convertedName := strings.Replace(profile.Spec.Name, "-", "_", 1)
+	_, namedAfterEpp := eppDefaults[convertedName]
+
+	return namedAfterEpp && convertedName != ""
 }
 
-func (r *PowerConfigReconciler) createDaemonSetIfNotPresent(powerConfig *powerv1.PowerConfig, path string, logger *logr.Logger) error {
+func (r *PowerConfigReconciler) createDaemonSetIfNotPresent(c context.Context, powerConfig *powerv1.PowerConfig, groups []powerv1.PowerNodeGroup, path string, logger *logr.Logger) error {
 	logger.V(5).Info("Creating DaemonSet")
 
 	daemonSet := &appsv1.DaemonSet{}
 	var err error
+	nodeSelector := powerConfig.Spec.PowerNodeSelector
+	var nodeAffinity *corev1.NodeAffinity
+	if len(powerConfig.Spec.NodeGroups) > 0 {
+		nodeSelector = nil
+		nodeAffinity = nodeAgentAffinity(groups)
+	}
 
-	err = r.Client.Get(context.TODO(), client.ObjectKey{
+	err = r.Client.Get(c, client.ObjectKey{
 		Name:      NodeAgentDSName,
 		Namespace: IntelPowerNamespace,
 	}, daemonSet)
@@ -337,10 +1322,11 @@ func (r *PowerConfigReconciler) createDaemonSetIfNotPresent(powerConfig *powerv1
 				logger.Error(err, "Error creating DaemonSet")
 				return err
 			}
-			if len(powerConfig.Spec.PowerNodeSelector) != 0 {
-				daemonSet.Spec.Template.Spec.NodeSelector = powerConfig.Spec.PowerNodeSelector
+			if len(nodeSelector) != 0 {
+				daemonSet.Spec.Template.Spec.NodeSelector = nodeSelector
 			}
-			err = r.Client.Create(context.TODO(), daemonSet)
+			setNodeAffinity(&daemonSet.Spec.Template.Spec, nodeAffinity)
+			err = r.Client.Create(c, daemonSet)
 			if err != nil {
 				logger.Error(err, "Error creating DaemonSet")
 				return err
@@ -351,10 +1337,16 @@ func (r *PowerConfigReconciler) createDaemonSetIfNotPresent(powerConfig *powerv1
 	}
 
 	// If the the DaemonSet already exists and is different than the selected nodes, update it
-	if !reflect.DeepEqual(daemonSet.Spec.Template.Spec.NodeSelector, powerConfig.Spec.PowerNodeSelector) {
+	var currentAffinity *corev1.NodeAffinity
+	if daemonSet.Spec.Template.Spec.Affinity != nil {
+		currentAffinity = daemonSet.Spec.Template.Spec.Affinity.NodeAffinity
+	}
+	if !reflect.DeepEqual(daemonSet.Spec.Template.Spec.NodeSelector, nodeSelector) ||
+		!reflect.DeepEqual(currentAffinity, nodeAffinity) {
 		logger.V(5).Info("Updating existing DeamonSet")
-		daemonSet.Spec.Template.Spec.NodeSelector = powerConfig.Spec.PowerNodeSelector
-		err = r.Client.Update(context.TODO(), daemonSet)
+		daemonSet.Spec.Template.Spec.NodeSelector = nodeSelector
+		setNodeAffinity(&daemonSet.Spec.Template.Spec, nodeAffinity)
+		err = r.Client.Update(c, daemonSet)
 		if err != nil {
 			logger.Error(err, "error updating PowerNodeAgent DaemonSet")
 			return err
@@ -364,6 +1356,50 @@ func (r *PowerConfigReconciler) createDaemonSetIfNotPresent(powerConfig *powerv1
 	return nil
 }
 
+// nodeAgentAffinity schedules the Node Agent on the Nodes of any of the groups, each group's selector is one of
+// the node selector terms. A group matching every Node needs no affinity at all
+func nodeAgentAffinity(groups []powerv1.PowerNodeGroup) *corev1.NodeAffinity {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	terms := make([]corev1.NodeSelectorTerm, 0, len(groups))
+	for _, group := range groups {
+		if len(group.Spec.NodeSelector) == 0 {
+			return nil
+		}
+		keys := make([]string, 0, len(group.Spec.NodeSelector))
+		for key := range group.Spec.NodeSelector {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		term := corev1.NodeSelectorTerm{}
+		for _, key := range keys {
+			term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{group.Spec.NodeSelector[key]},
+			})
+		}
+		terms = append(terms, term)
+	}
+
+	return &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+	}
+}
+
+// setNodeAffinity replaces the node affinity of the Pod spec, leaving any other affinity of the manifest alone
+func setNodeAffinity(spec *corev1.PodSpec, nodeAffinity *corev1.NodeAffinity) {
+	if spec.Affinity == nil {
+		if nodeAffinity == nil {
+			return
+		}
+		spec.Affinity = &corev1.Affinity{}
+	}
+	spec.Affinity.NodeAffinity = nodeAffinity
+}
+
 func newDaemonSet(path string) (*appsv1.DaemonSet, error) {
 	yamlFile, err := os.ReadFile(path)
 	if err != nil {
@@ -383,5 +1419,66 @@ func newDaemonSet(path string) (*appsv1.DaemonSet, error) {
 func (r *PowerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&powerv1.PowerConfig{}).
+		Watches(&source.Kind{Type: &powerv1.PowerNodeGroup{}}, handler.EnqueueRequestsFromMapFunc(r.nodeGroupConfigRequests)).
+		Watches(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(r.nodeConfigRequests),
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
+
+// nodeGroupConfigRequests reconciles the PowerConfigs again when one of the PowerNodeGroups changes
+func (r *PowerConfigReconciler) nodeGroupConfigRequests(obj client.Object) []reconcile.Request {
+	configs := &powerv1.PowerConfigList{}
+	err := r.Client.List(context.TODO(), configs, client.InNamespace(obj.GetNamespace()))
+	if err != nil {
+		r.Log.Error(err, "error listing PowerConfigs")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(configs.Items))
+	for _, config := range configs.Items {
+		if util.StringInStringList(obj.GetName(), config.Spec.NodeGroups) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&config)})
+		}
+	}
+
+	return requests
+}
+
+// nodeConfigRequests reconciles the PowerConfigs selecting a Node when it joins the cluster or its labels change,
+// so a new Node gets its Node Agent and Extended Resources without waiting for the PowerConfig to change. On a label
+// change the Node's old labels are mapped too, so the PowerConfig a Node leaves releases it
+func (r *PowerConfigReconciler) nodeConfigRequests(obj client.Object) []reconcile.Request {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return nil
+	}
+	configs := &powerv1.PowerConfigList{}
+	err := r.Client.List(context.TODO(), configs, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		r.Log.Error(err, "error listing PowerConfigs")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(configs.Items))
+	for i := range configs.Items {
+		config := &configs.Items[i]
+		var selected bool
+		if len(config.Spec.NodeGroups) > 0 {
+			logger := r.Log.WithValues("powerconfig", config.Name)
+			groups, err := r.configNodeGroups(context.TODO(), config, &logger)
+			if err != nil {
+				r.Log.Error(err, "error retrieving the PowerNodeGroups of the PowerConfig", "powerconfig", config.Name)
+				continue
+			}
+			selected = len(nodeGroupNames(groups, node)) > 0
+		} else {
+			selected = labels.SelectorFromSet(config.Spec.PowerNodeSelector).Matches(labels.Set(node.Labels))
+		}
+		if selected {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+		}
+	}
+
+	return requests
+}
@@ -2,19 +2,31 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/preflight"
+	"github.com/intel/kubernetes-power-manager/pkg/profileorder"
 	"github.com/intel/kubernetes-power-manager/pkg/state"
 	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -32,7 +44,7 @@ func createConfigReconcilerObject(objs []runtime.Object) (*PowerConfigReconciler
 
 	state := state.NewPowerNodeData()
 
-	r := &PowerConfigReconciler{cl, ctrl.Log.WithName("testing"), s, state}
+	r := &PowerConfigReconciler{cl, ctrl.Log.WithName("testing"), s, state, nil, 0}
 
 	return r, nil
 }
@@ -695,3 +707,1055 @@ func TestPowerConfigDeletion(t *testing.T) {
 		}
 	}
 }
+
+func TestPowerConfigSelectorMatchesNoNodes(t *testing.T) {
+	tcases := []struct {
+		testCase       string
+		clientObjs     []runtime.Object
+		expectedNodes  float64
+		expectedEvents int
+	}{
+		{
+			testCase: "Test Case 1 - selector matches no Nodes",
+			clientObjs: []runtime.Object{
+				&powerv1.PowerConfig{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-config",
+						Namespace: IntelPowerNamespace,
+					},
+					Spec: powerv1.PowerConfigSpec{
+						PowerNodeSelector: map[string]string{
+							"feature.node.kubernetes.io/power-nod": "true",
+						},
+					},
+				},
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "TestNode",
+						Labels: map[string]string{
+							"feature.node.kubernetes.io/power-node": "true",
+						},
+					},
+				},
+			},
+			expectedNodes:  0,
+			expectedEvents: 1,
+		},
+		{
+			testCase: "Test Case 2 - selector matches a Node",
+			clientObjs: []runtime.Object{
+				&powerv1.PowerConfig{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-config",
+						Namespace: IntelPowerNamespace,
+					},
+					Spec: powerv1.PowerConfigSpec{
+						PowerNodeSelector: map[string]string{
+							"feature.node.kubernetes.io/power-node": "true",
+						},
+					},
+				},
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "TestNode",
+						Labels: map[string]string{
+							"feature.node.kubernetes.io/power-node": "true",
+						},
+					},
+				},
+			},
+			expectedNodes:  1,
+			expectedEvents: 0,
+		},
+	}
+
+	for _, tc := range tcases {
+		NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+
+		r, err := createConfigReconcilerObject(tc.clientObjs)
+		if err != nil {
+			t.Error(err)
+			t.Fatalf("%s - error creating reconciler object", tc.testCase)
+		}
+		recorder := record.NewFakeRecorder(10)
+		r.Recorder = recorder
+
+		req := reconcile.Request{
+			NamespacedName: client.ObjectKey{
+				Name:      "test-config",
+				Namespace: IntelPowerNamespace,
+			},
+		}
+
+		_, err = r.Reconcile(context.TODO(), req)
+		if err != nil {
+			t.Error(err)
+			t.Fatalf("%s - error reconciling object", tc.testCase)
+		}
+
+		matchedNodes := testutil.ToFloat64(metrics.PowerConfigMatchedNodes.WithLabelValues("test-config"))
+		if matchedNodes != tc.expectedNodes {
+			t.Errorf("%s Failed - Expected matched Nodes metric to be %v, got %v", tc.testCase, tc.expectedNodes, matchedNodes)
+		}
+
+		if len(recorder.Events) != tc.expectedEvents {
+			t.Errorf("%s Failed - Expected %v events, got %v", tc.testCase, tc.expectedEvents, len(recorder.Events))
+		}
+
+		// the PowerConfig's series goes with it
+		config := &powerv1.PowerConfig{}
+		if err = r.Client.Get(context.TODO(), req.NamespacedName, config); err != nil {
+			t.Fatalf("%s - error retrieving the PowerConfig: %v", tc.testCase, err)
+		}
+		if err = r.Client.Delete(context.TODO(), config); err != nil {
+			t.Fatalf("%s - error deleting the PowerConfig: %v", tc.testCase, err)
+		}
+		_, err = r.Reconcile(context.TODO(), req)
+		if err != nil {
+			t.Fatalf("%s - error reconciling the deleted PowerConfig: %v", tc.testCase, err)
+		}
+		if metrics.PowerConfigMatchedNodes.DeleteLabelValues("test-config") {
+			t.Errorf("%s Failed - Expected the matched Nodes metric of the deleted PowerConfig to be removed", tc.testCase)
+		}
+	}
+}
+
+func TestPowerConfigUserNamedProfiles(t *testing.T) {
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-config",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerConfigSpec{
+			PowerNodeSelector: map[string]string{
+				"feature.node.kubernetes.io/power-node": "true",
+			},
+			PowerProfiles: []string{"performance", "latency-critical", "batch", "typo"},
+			ProfilePolicies: map[string]powerv1.ProfilePolicy{
+				"latency-critical": {
+					Epp:      "performance",
+					Capacity: &powerv1.ProfileCapacity{Count: 8},
+				},
+				"batch": {
+					Max:      2000,
+					Min:      1200,
+					Capacity: &powerv1.ProfileCapacity{Percent: 50},
+				},
+			},
+		},
+	}
+	clientObjs := []runtime.Object{
+		config,
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "TestNode",
+				Labels: map[string]string{
+					"feature.node.kubernetes.io/power-node": "true",
+				},
+			},
+		},
+		// created by the PowerConfig before but no longer requested
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "old-tier",
+				Namespace: IntelPowerNamespace,
+				Labels:    map[string]string{PowerConfigProfileLabel: "test-config"},
+			},
+			Spec: powerv1.PowerProfileSpec{Name: "old-tier", Max: 2400, Min: 2200},
+		},
+		// created by a user
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "custom",
+				Namespace: IntelPowerNamespace,
+			},
+			Spec: powerv1.PowerProfileSpec{Name: "custom", Max: 2400, Min: 2200},
+		},
+	}
+
+	NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+	r, err := createConfigReconcilerObject(clientObjs)
+	if err != nil {
+		t.Error(err)
+		t.Fatal("error creating reconciler object")
+	}
+
+	req := reconcile.Request{
+		NamespacedName: client.ObjectKey{
+			Name:      "test-config",
+			Namespace: IntelPowerNamespace,
+		},
+	}
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Error(err)
+		t.Fatal("error reconciling PowerConfig object")
+	}
+
+	profiles := &powerv1.PowerProfileList{}
+	err = r.Client.List(context.TODO(), profiles)
+	if err != nil {
+		t.Error(err)
+		t.Fatal("error retrieving PowerProfiles")
+	}
+	created := make(map[string]powerv1.PowerProfile)
+	for _, profile := range profiles.Items {
+		created[profile.Name] = profile
+	}
+
+	assert.Len(t, created, 4)
+	assert.Contains(t, created, "custom")
+	assert.NotContains(t, created, "old-tier")
+	assert.NotContains(t, created, "typo")
+
+	assert.Equal(t, "performance", created["performance"].Spec.Epp)
+	assert.Nil(t, created["performance"].Spec.Capacity)
+	assert.Equal(t, "test-config", created["performance"].Labels[PowerConfigProfileLabel])
+
+	assert.Equal(t, "performance", created["latency-critical"].Spec.Epp)
+	assert.Equal(t, &powerv1.ProfileCapacity{Count: 8}, created["latency-critical"].Spec.Capacity)
+
+	assert.Equal(t, "", created["batch"].Spec.Epp)
+	assert.Equal(t, 2000, created["batch"].Spec.Max)
+	assert.Equal(t, 1200, created["batch"].Spec.Min)
+	assert.Equal(t, &powerv1.ProfileCapacity{Percent: 50}, created["batch"].Spec.Capacity)
+
+	// changing the policy updates the PowerProfile
+	updatedConfig := &powerv1.PowerConfig{}
+	err = r.Client.Get(context.TODO(), req.NamespacedName, updatedConfig)
+	assert.NoError(t, err)
+	batch := updatedConfig.Spec.ProfilePolicies["batch"]
+	batch.Capacity = &powerv1.ProfileCapacity{Percent: 25}
+	updatedConfig.Spec.ProfilePolicies["batch"] = batch
+	err = r.Client.Update(context.TODO(), updatedConfig)
+	assert.NoError(t, err)
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	batchProfile := &powerv1.PowerProfile{}
+	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "batch", Namespace: IntelPowerNamespace}, batchProfile)
+	assert.NoError(t, err)
+	assert.Equal(t, &powerv1.ProfileCapacity{Percent: 25}, batchProfile.Spec.Capacity)
+}
+
+func TestProfileSpecFromPolicy(t *testing.T) {
+	policies := map[string]powerv1.ProfilePolicy{
+		"balance-performance": {Capacity: &powerv1.ProfileCapacity{Percent: 30}},
+		"gold":                {Epp: "balance-power"},
+		"silver":              {Max: 2400, Min: 2000, Governor: "performance"},
+		"bronze":              {Capacity: &powerv1.ProfileCapacity{Count: 4}},
+		"copper":              {Epp: "power", Capacity: &powerv1.ProfileCapacity{Expression: "sockets * 8"}},
+		"iron":                {Epp: "power", Capacity: &powerv1.ProfileCapacity{Expression: "cpuModel"}},
+	}
+
+	tcases := []struct {
+		testCase      string
+		name          string
+		expectedSpec  powerv1.PowerProfileSpec
+		expectedError bool
+	}{
+		{
+			testCase:     "Test Case 1 - EPP named profile without policy",
+			name:         "performance",
+			expectedSpec: powerv1.PowerProfileSpec{Name: "performance", Epp: "performance"},
+		},
+		{
+			testCase:     "Test Case 2 - EPP named profile with capacity",
+			name:         "balance-performance",
+			expectedSpec: powerv1.PowerProfileSpec{Name: "balance-performance", Epp: "balance_performance", Capacity: &powerv1.ProfileCapacity{Percent: 30}},
+		},
+		{
+			testCase:     "Test Case 3 - user named profile with EPP",
+			name:         "gold",
+			expectedSpec: powerv1.PowerProfileSpec{Name: "gold", Epp: "balance_power"},
+		},
+		{
+			testCase:     "Test Case 4 - user named profile with frequencies",
+			name:         "silver",
+			expectedSpec: powerv1.PowerProfileSpec{Name: "silver", Max: 2400, Min: 2000, Governor: "performance"},
+		},
+		{
+			testCase:      "Test Case 5 - user named profile without EPP or frequencies",
+			name:          "bronze",
+			expectedError: true,
+		},
+		{
+			testCase:      "Test Case 6 - user named profile without policy",
+			name:          "platinum",
+			expectedError: true,
+		},
+		{
+			testCase:     "Test Case 7 - capacity expression",
+			name:         "copper",
+			expectedSpec: powerv1.PowerProfileSpec{Name: "copper", Epp: "power", Capacity: &powerv1.ProfileCapacity{Expression: "sockets * 8"}},
+		},
+		{
+			testCase:      "Test Case 8 - capacity expression not giving an int",
+			name:          "iron",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		spec, err := profileSpecFromPolicy(tc.name, policies)
+		if tc.expectedError {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedSpec, spec)
+	}
+}
+
+func TestEffectiveProfiles(t *testing.T) {
+	config := &powerv1.PowerConfig{
+		Spec: powerv1.PowerConfigSpec{
+			PowerProfiles: []string{"performance", "gold", "silver"},
+			ProfilePolicies: map[string]powerv1.ProfilePolicy{
+				"gold":   {Max: 3000, Min: 2000},
+				"silver": {Epp: "balance-power"},
+			},
+			NodeGroupPolicies: []powerv1.NodeGroupPolicy{
+				{
+					Name:            "large",
+					NodeSelector:    map[string]string{"size": "large"},
+					ProfilePolicies: map[string]powerv1.ProfilePolicy{"gold": {Max: 3600}},
+				},
+				{
+					Name:            "edge",
+					NodeSelector:    map[string]string{"zone": "edge"},
+					ProfilePolicies: map[string]powerv1.ProfilePolicy{"gold": {Governor: "powersave"}},
+				},
+			},
+			NodePolicies: []powerv1.NodePolicy{
+				{
+					NodeName:        "TestNode",
+					ProfilePolicies: map[string]powerv1.ProfilePolicy{"performance": {Capacity: &powerv1.ProfileCapacity{Count: 4}}},
+				},
+				{
+					NodeName:        "TestNode",
+					ProfilePolicies: map[string]powerv1.ProfilePolicy{"silver": {Capacity: &powerv1.ProfileCapacity{Count: 2}}},
+				},
+			},
+		},
+	}
+
+	tcases := []struct {
+		testCase string
+		node     *corev1.Node
+		expected []powerv1.EffectiveProfile
+	}{
+		{
+			testCase: "Test Case 1 - Node in no group",
+			node:     &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "OtherNode"}},
+			expected: []powerv1.EffectiveProfile{
+				{Name: "performance", Source: powerv1.EffectiveSourceCluster, ProfilePolicy: powerv1.ProfilePolicy{Epp: "performance"}},
+				{Name: "gold", Source: powerv1.EffectiveSourceCluster, ProfilePolicy: powerv1.ProfilePolicy{Max: 3000, Min: 2000}},
+				{Name: "silver", Source: powerv1.EffectiveSourceCluster, ProfilePolicy: powerv1.ProfilePolicy{Epp: "balance_power"}},
+			},
+		},
+		{
+			testCase: "Test Case 2 - later groups override earlier ones field by field",
+			node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   "OtherNode",
+				Labels: map[string]string{"size": "large", "zone": "edge"},
+			}},
+			expected: []powerv1.EffectiveProfile{
+				{Name: "performance", Source: powerv1.EffectiveSourceCluster, ProfilePolicy: powerv1.ProfilePolicy{Epp: "performance"}},
+				{Name: "gold", Source: "NodeGroup/edge", ProfilePolicy: powerv1.ProfilePolicy{Max: 3600, Min: 2000, Governor: "powersave"}},
+				{Name: "silver", Source: powerv1.EffectiveSourceCluster, ProfilePolicy: powerv1.ProfilePolicy{Epp: "balance_power"}},
+			},
+		},
+		{
+			testCase: "Test Case 3 - Node policies override group policies",
+			node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   "TestNode",
+				Labels: map[string]string{"size": "large"},
+			}},
+			expected: []powerv1.EffectiveProfile{
+				{Name: "performance", Source: powerv1.EffectiveSourceNode, ProfilePolicy: powerv1.ProfilePolicy{Epp: "performance", Capacity: &powerv1.ProfileCapacity{Count: 4}}},
+				{Name: "gold", Source: "NodeGroup/large", ProfilePolicy: powerv1.ProfilePolicy{Max: 3600, Min: 2000}},
+				{Name: "silver", Source: powerv1.EffectiveSourceNode, ProfilePolicy: powerv1.ProfilePolicy{Epp: "balance_power", Capacity: &powerv1.ProfileCapacity{Count: 2}}},
+			},
+		},
+	}
+
+	logger := ctrl.Log.WithName("testing")
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		assert.Equal(t, tc.expected, effectiveProfiles(config, tc.node, &logger))
+	}
+}
+
+func TestProfileOrdering(t *testing.T) {
+	profile := func(name string, max int, min int) *powerv1.PowerProfile {
+		return &powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: name, Max: max, Min: min},
+		}
+	}
+	schm := runtime.NewScheme()
+	assert.NoError(t, powerv1.AddToScheme(schm))
+
+	tcases := []struct {
+		testCase      string
+		objs          []runtime.Object
+		profile       *powerv1.PowerProfile
+		expectedError bool
+	}{
+		{
+			testCase: "Test Case 1 - default ordering holds",
+			objs:     []runtime.Object{profile("gold", 3600, 3000), profile("bronze", 2000, 1000)},
+			profile:  profile("silver", 3000, 2000),
+		},
+		{
+			testCase:      "Test Case 2 - silver faster than gold",
+			objs:          []runtime.Object{profile("gold", 3000, 2000)},
+			profile:       profile("silver", 3200, 2000),
+			expectedError: true,
+		},
+		{
+			testCase:      "Test Case 3 - gold min frequency below bronze",
+			objs:          []runtime.Object{profile("bronze", 2000, 1500)},
+			profile:       profile("gold", 3600, 1200),
+			expectedError: true,
+		},
+		{
+			testCase: "Test Case 4 - updating a profile isn't compared with its old self",
+			objs:     []runtime.Object{profile("gold", 3600, 3000), profile("silver", 3000, 2000)},
+			profile:  profile("gold", 3200, 2400),
+		},
+		{
+			testCase: "Test Case 5 - profiles outside the ordering aren't checked",
+			objs:     []runtime.Object{profile("gold", 3000, 2000)},
+			profile:  profile("performance", 3600, 3200),
+		},
+		{
+			testCase: "Test Case 6 - max presets are resolved per Node",
+			objs: []runtime.Object{&powerv1.PowerProfile{
+				ObjectMeta: metav1.ObjectMeta{Name: "gold", Namespace: IntelPowerNamespace},
+				Spec:       powerv1.PowerProfileSpec{Name: "gold", MaxPreset: "allCoreTurbo", Max: 2000, Min: 2000},
+			}},
+			profile: profile("silver", 3000, 1800),
+		},
+		{
+			testCase: "Test Case 7 - ordering declared in the PowerConfig",
+			objs: []runtime.Object{
+				&powerv1.PowerConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "power-config", Namespace: IntelPowerNamespace},
+					Spec:       powerv1.PowerConfigSpec{ProfileOrdering: []string{"performance", "balance-performance"}},
+				},
+				profile("balance-performance", 3000, 2000),
+				profile("gold", 3600, 3000),
+			},
+			profile:       profile("performance", 2800, 2000),
+			expectedError: true,
+		},
+		{
+			testCase: "Test Case 8 - the declared ordering replaces the default one",
+			objs: []runtime.Object{
+				&powerv1.PowerConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "power-config", Namespace: IntelPowerNamespace},
+					Spec:       powerv1.PowerConfigSpec{ProfileOrdering: []string{"performance", "balance-performance"}},
+				},
+				profile("gold", 3000, 2000),
+			},
+			profile: profile("silver", 3600, 3000),
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		validator := &profileorder.Validator{
+			Client: fake.NewClientBuilder().WithScheme(schm).WithRuntimeObjects(tc.objs...).Build(),
+		}
+		err := validator.ValidateCreate(context.TODO(), tc.profile)
+		assert.Equal(t, tc.expectedError, err != nil, err)
+		assert.Equal(t, err, validator.ValidateUpdate(context.TODO(), tc.profile, tc.profile))
+	}
+}
+
+func TestFailureDomainShares(t *testing.T) {
+	node := func(name string, rack string, cpus int64) corev1.Node {
+		n := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}},
+			Status: corev1.NodeStatus{Capacity: corev1.ResourceList{
+				corev1.ResourceCPU: *resource.NewQuantity(cpus, resource.DecimalSI),
+			}},
+		}
+		if rack != "" {
+			n.Labels["rack"] = rack
+		}
+		return n
+	}
+	nodes := []corev1.Node{
+		node("node1", "rack-a", 32),
+		node("node2", "rack-a", 64),
+		node("node3", "rack-a", 32),
+		node("node4", "rack-b", 0),
+		node("node5", "rack-b", 0),
+		node("node6", "", 64),
+	}
+	domains := &powerv1.FailureDomainCapacity{
+		TopologyKey:  "rack",
+		MaxPerDomain: map[string]int{"gold": 10, "silver": 3},
+	}
+
+	shares := failureDomainShares(domains, nodes)
+	// rack-a splits by CPUs, the largest remainders get the leftover, rack-b's CPU counts aren't known yet
+	assert.Equal(t, map[string]map[string]int{
+		"node1": {"gold": 3, "silver": 1},
+		"node2": {"gold": 5, "silver": 1},
+		"node3": {"gold": 2, "silver": 1},
+		"node4": {"gold": 5, "silver": 2},
+		"node5": {"gold": 5, "silver": 1},
+	}, shares)
+	assert.Empty(t, failureDomainShares(nil, nodes))
+
+	nodes[0].Labels["feature.node.kubernetes.io/power-node"] = "true"
+	r, err := createConfigReconcilerObject([]runtime.Object{
+		&powerv1.PowerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerConfigSpec{
+				PowerNodeSelector:     map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+				FailureDomainCapacity: domains,
+			},
+		},
+		&nodes[0],
+	})
+	assert.NoError(t, err)
+	NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "test-config", Namespace: IntelPowerNamespace}})
+	assert.NoError(t, err)
+	powerNode := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "node1", Namespace: IntelPowerNamespace}, powerNode))
+	assert.Equal(t, map[string]int{"gold": 10, "silver": 3}, powerNode.Status.DomainCapacity)
+}
+
+func TestPowerConfigMissingProfiles(t *testing.T) {
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerConfigSpec{
+			PowerNodeSelector: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+			// custom has no policy but a user created it, typo has neither
+			PowerProfiles: []string{"performance", "custom", "typo"},
+			FailureDomainCapacity: &powerv1.FailureDomainCapacity{
+				TopologyKey:  "topology.kubernetes.io/zone",
+				MaxPerDomain: map[string]int{"performance": 4, "typo": 4},
+			},
+		},
+	}
+	custom := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "custom", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerProfileSpec{Name: "custom", Max: 2400, Min: 2200},
+	}
+	NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+	r, err := createConfigReconcilerObject([]runtime.Object{
+		config,
+		custom,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name: "TestNode",
+			Labels: map[string]string{
+				"feature.node.kubernetes.io/power-node": "true",
+				"topology.kubernetes.io/zone":           "zone-a",
+			},
+		}},
+	})
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "test-config", Namespace: IntelPowerNamespace}}
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	updated := &powerv1.PowerConfig{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	condition := meta.FindStatusCondition(updated.Status.Conditions, powerv1.ConditionMissingProfiles)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, powerv1.ReasonProfilesNotFound, condition.Reason)
+		assert.Contains(t, condition.Message, "typo")
+		assert.NotContains(t, condition.Message, "custom")
+	}
+	powerNode := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "TestNode", Namespace: IntelPowerNamespace}, powerNode))
+	assert.Equal(t, map[string]int{"performance": 4}, powerNode.Status.DomainCapacity)
+
+	// creating the PowerProfile clears the condition
+	assert.NoError(t, r.Client.Create(context.TODO(), &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "typo", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerProfileSpec{Name: "typo", Epp: "performance"},
+	}))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	condition = meta.FindStatusCondition(updated.Status.Conditions, powerv1.ConditionMissingProfiles)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, powerv1.ReasonProfilesFound, condition.Reason)
+	}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "TestNode", Namespace: IntelPowerNamespace}, powerNode))
+	assert.Equal(t, map[string]int{"performance": 4, "typo": 4}, powerNode.Status.DomainCapacity)
+}
+
+func TestPowerConfigNodeConditions(t *testing.T) {
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: IntelPowerNamespace, Generation: 2},
+		Spec: powerv1.PowerConfigSpec{
+			PowerNodeSelector: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+			PowerProfiles:     []string{"performance"},
+		},
+	}
+	nodeLabels := map[string]string{"feature.node.kubernetes.io/power-node": "true"}
+	NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+	r, err := createConfigReconcilerObject([]runtime.Object{
+		config,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: nodeLabels}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: nodeLabels}},
+	})
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "test-config", Namespace: IntelPowerNamespace}}
+
+	// the Node Agents haven't applied the PowerProfiles yet
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	updated := &powerv1.PowerConfig{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	assert.Equal(t, []powerv1.NodeFailure{
+		{Node: "node-a", Reason: powerv1.FailureProfilesNotApplied, Message: "The Node Agent hasn't applied every PowerProfile yet"},
+		{Node: "node-b", Reason: powerv1.FailureProfilesNotApplied, Message: "The Node Agent hasn't applied every PowerProfile yet"},
+	}, updated.Status.NodeFailures)
+	configured := meta.FindStatusCondition(updated.Status.Conditions, powerv1.ConditionNodesConfigured)
+	if assert.NotNil(t, configured) {
+		assert.Equal(t, metav1.ConditionFalse, configured.Status)
+		assert.Equal(t, powerv1.ReasonNodesNotConfigured, configured.Reason)
+		assert.Contains(t, configured.Message, "node-a")
+		assert.Equal(t, int64(2), configured.ObservedGeneration)
+	}
+	ready := meta.FindStatusCondition(updated.Status.Conditions, powerv1.ConditionReady)
+	if assert.NotNil(t, ready) {
+		assert.Equal(t, metav1.ConditionFalse, ready.Status)
+		assert.Equal(t, powerv1.ReasonNodesNotConfigured, ready.Reason)
+	}
+	assert.True(t, meta.IsStatusConditionFalse(updated.Status.Conditions, powerv1.ConditionDegraded))
+
+	// once both have, the PowerConfig is Ready
+	profile := &powerv1.PowerProfile{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "performance", Namespace: IntelPowerNamespace}, profile))
+	for _, nodeName := range []string{"node-a", "node-b"} {
+		powerNode := &powerv1.PowerNode{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode))
+		powerNode.Spec.PowerProfiles = []string{fmt.Sprintf("performance: %d || %d || %s", profile.Spec.Max, profile.Spec.Min, profile.Spec.Epp)}
+		assert.NoError(t, r.Client.Update(context.TODO(), powerNode))
+		now := metav1.Now()
+		powerNode.Status.ConfiguredTime = &now
+		assert.NoError(t, r.Client.Status().Update(context.TODO(), powerNode))
+	}
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	assert.Empty(t, updated.Status.NodeFailures)
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, powerv1.ConditionNodesConfigured))
+	assert.True(t, meta.IsStatusConditionFalse(updated.Status.Conditions, powerv1.ConditionDegraded))
+	ready = meta.FindStatusCondition(updated.Status.Conditions, powerv1.ConditionReady)
+	if assert.NotNil(t, ready) {
+		assert.Equal(t, metav1.ConditionTrue, ready.Status)
+		assert.Equal(t, powerv1.ReasonConfigured, ready.Reason)
+	}
+
+	// a PowerProfile the Node Agent hasn't applied since keeps the PowerConfig from being Ready again
+	powerNode := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "node-b", Namespace: IntelPowerNamespace}, powerNode))
+	powerNode.Status.PendingProfiles = []string{"balance-performance"}
+	assert.NoError(t, r.Client.Status().Update(context.TODO(), powerNode))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	assert.Equal(t, []powerv1.NodeFailure{
+		{Node: "node-b", Reason: powerv1.FailureProfilesNotApplied, Message: "The Node Agent hasn't applied balance-performance"},
+	}, updated.Status.NodeFailures)
+	assert.True(t, meta.IsStatusConditionFalse(updated.Status.Conditions, powerv1.ConditionReady))
+
+	// a drifted Node degrades the PowerConfig, only the first maxFailedNodes are named
+	drifted := &powerv1.PowerConfig{Status: powerv1.PowerConfigStatus{}}
+	failures := make([]powerv1.NodeFailure, 0)
+	for i := 0; i < maxFailedNodes+2; i++ {
+		nodeName := fmt.Sprintf("node-%02d", i)
+		drifted.Status.Nodes = append(drifted.Status.Nodes, nodeName)
+		failures = append(failures, powerv1.NodeFailure{Node: nodeName, Reason: powerv1.FailureSettingsDrifted,
+			Message: "Settings differ from the Power CRs: governor"})
+	}
+	setNodeConditions(drifted, failures)
+	assert.True(t, meta.IsStatusConditionTrue(drifted.Status.Conditions, powerv1.ConditionNodesConfigured))
+	degraded := meta.FindStatusCondition(drifted.Status.Conditions, powerv1.ConditionDegraded)
+	if assert.NotNil(t, degraded) {
+		assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+		assert.Equal(t, powerv1.ReasonNodesFailed, degraded.Reason)
+		assert.Contains(t, degraded.Message, "node-09 (Settings differ from the Power CRs: governor)")
+		assert.NotContains(t, degraded.Message, "node-10")
+		assert.Contains(t, degraded.Message, "and 2 more")
+	}
+	ready = meta.FindStatusCondition(drifted.Status.Conditions, powerv1.ConditionReady)
+	if assert.NotNil(t, ready) {
+		assert.Equal(t, metav1.ConditionFalse, ready.Status)
+		assert.Equal(t, powerv1.ReasonNodesFailed, ready.Reason)
+	}
+}
+
+func TestPowerConfigBlockedProfileDeletion(t *testing.T) {
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerConfigSpec{
+			PowerNodeSelector: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+			PowerProfiles:     []string{"performance"},
+		},
+	}
+	configProfile := func(name string) *powerv1.PowerProfile {
+		return &powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: IntelPowerNamespace,
+				Labels:    map[string]string{PowerConfigProfileLabel: "test-config"},
+			},
+			Spec: powerv1.PowerProfileSpec{Name: name, Epp: name},
+		}
+	}
+	requestingPod := func(name string, profile string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceName(ExtendedResourcePrefix + profile): resource.MustParse("2")},
+				},
+			}}},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+	r, err := createConfigReconcilerObject([]runtime.Object{
+		config,
+		configProfile("performance"),
+		configProfile("balance-performance"),
+		configProfile("balance-power"),
+		// balance-performance is still requested, a completed Pod doesn't hold balance-power back
+		requestingPod("dpdk-0", "balance-performance", corev1.PodRunning),
+		requestingPod("batch-0", "balance-power", corev1.PodSucceeded),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   "TestNode",
+			Labels: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+		}},
+	})
+	assert.NoError(t, err)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "test-config", Namespace: IntelPowerNamespace}}
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	profile := &powerv1.PowerProfile{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "balance-performance", Namespace: IntelPowerNamespace}, profile))
+	assert.True(t, errors.IsNotFound(r.Client.Get(context.TODO(), client.ObjectKey{Name: "balance-power", Namespace: IntelPowerNamespace}, profile)))
+	updated := &powerv1.PowerConfig{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	condition := meta.FindStatusCondition(updated.Status.Conditions, powerv1.ConditionBlockedDeletion)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, powerv1.ReasonPodsRequestProfiles, condition.Reason)
+		assert.Contains(t, condition.Message, "balance-performance by default/dpdk-0")
+		assert.NotContains(t, condition.Message, "balance-power")
+	}
+
+	// forcing the deletion takes the capacity away from under the Pod
+	updated.Annotations = map[string]string{ForceProfileDeletionAnnotation: "balance-performance"}
+	assert.NoError(t, r.Client.Update(context.TODO(), updated))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.True(t, errors.IsNotFound(r.Client.Get(context.TODO(), client.ObjectKey{Name: "balance-performance", Namespace: IntelPowerNamespace}, profile)))
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	condition = meta.FindStatusCondition(updated.Status.Conditions, powerv1.ConditionBlockedDeletion)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	}
+	assert.Contains(t, <-recorder.Events, ForcedProfileDeletionReason)
+}
+
+func TestPowerConfigStaleExtendedResources(t *testing.T) {
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerConfigSpec{
+			PowerNodeSelector: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+			PowerProfiles:     []string{"performance"},
+		},
+	}
+	// the Node Agent of TestNode is gone, balance-power was dropped from the PowerConfig and shared is user-created
+	resources := corev1.ResourceList{
+		CPUResource:                              resource.MustParse("42"),
+		ExtendedResourcePrefix + "performance":   resource.MustParse("10"),
+		ExtendedResourcePrefix + "balance-power": resource.MustParse("20"),
+		ExtendedResourcePrefix + "shared":        resource.MustParse("12"),
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "TestNode",
+			Labels: map[string]string{
+				"feature.node.kubernetes.io/power-node": "true",
+				CapacityLabelPrefix + "balance-power":   "20",
+				CapacityLabelPrefix + "performance":     "10",
+			},
+		},
+		Status: corev1.NodeStatus{Capacity: resources, Allocatable: resources.DeepCopy()},
+	}
+	shared := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerProfileSpec{Name: "shared", Epp: "power"},
+	}
+	NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+	r, err := createConfigReconcilerObject([]runtime.Object{config, node, shared})
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "test-config", Namespace: IntelPowerNamespace}}
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	updated := &corev1.Node{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "TestNode"}, updated))
+	for _, list := range []corev1.ResourceList{updated.Status.Capacity, updated.Status.Allocatable} {
+		assert.NotContains(t, list, corev1.ResourceName(ExtendedResourcePrefix+"balance-power"))
+		assert.Contains(t, list, corev1.ResourceName(ExtendedResourcePrefix+"performance"))
+		assert.Contains(t, list, corev1.ResourceName(ExtendedResourcePrefix+"shared"))
+		assert.Contains(t, list, corev1.ResourceCPU)
+	}
+	assert.NotContains(t, updated.Labels, CapacityLabelPrefix+"balance-power")
+	assert.Contains(t, updated.Labels, CapacityLabelPrefix+"performance")
+
+	// deleting the PowerConfig takes the rest of the PowerProfile resources off before the finalizer
+	current := &powerv1.PowerConfig{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, current))
+	assert.Contains(t, current.Finalizers, ExtendedResourcesFinalizer)
+	assert.Equal(t, []string{"TestNode"}, r.State.PowerNodeList)
+	assert.NoError(t, r.Client.Delete(context.TODO(), current))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "TestNode"}, updated))
+	for _, list := range []corev1.ResourceList{updated.Status.Capacity, updated.Status.Allocatable} {
+		assert.Equal(t, corev1.ResourceList{CPUResource: resource.MustParse("42")}, list)
+	}
+	assert.Equal(t, map[string]string{"feature.node.kubernetes.io/power-node": "true"}, updated.Labels)
+	assert.True(t, errors.IsNotFound(r.Client.Get(context.TODO(), req.NamespacedName, current)))
+	assert.Empty(t, r.State.PowerNodeList)
+}
+
+func TestPowerConfigNodeGroups(t *testing.T) {
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerConfigSpec{
+			NodeGroups:    []string{"rack-a", "rack-b", "rack-z"},
+			PowerProfiles: []string{"balance-power"},
+		},
+	}
+	rackA := &powerv1.PowerNodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack-a", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerNodeGroupSpec{
+			NodeSelector:    map[string]string{"rack": "a"},
+			PowerProfiles:   []string{"gold"},
+			ProfilePolicies: map[string]powerv1.ProfilePolicy{"gold": {Max: 3000, Min: 2000}},
+			Rollout:         &powerv1.NodeGroupRollout{ApplyDeadlineSeconds: 30, RollbackOnDeadline: true},
+		},
+	}
+	rackB := &powerv1.PowerNodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack-b", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerNodeGroupSpec{
+			NodeSelector:  map[string]string{"rack": "b"},
+			PowerProfiles: []string{"performance"},
+		},
+	}
+	objs := []runtime.Object{config, rackA, rackB}
+	for _, rack := range []string{"a", "b", "c"} {
+		objs = append(objs, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-" + rack, Labels: map[string]string{"rack": rack}}})
+	}
+	NodeAgentDaemonSetPath = "../build/manifests/power-node-agent-ds.yaml"
+	r, err := createConfigReconcilerObject(objs)
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "test-config", Namespace: IntelPowerNamespace}}
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	// the Node Agent only runs in the groups, the missing rack-z selects nothing
+	daemonSet := &appsv1.DaemonSet{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: NodeAgentDSName, Namespace: IntelPowerNamespace}, daemonSet))
+	assert.Empty(t, daemonSet.Spec.Template.Spec.NodeSelector)
+	terms := daemonSet.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	assert.Len(t, terms, 2)
+	assert.Equal(t, []string{"a"}, terms[0].MatchExpressions[0].Values)
+	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "node-c", Namespace: IntelPowerNamespace}, &powerv1.PowerNode{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// each Node is offered the PowerConfig's PowerProfiles and those of its group
+	for node, expected := range map[string][]string{"node-a": {"balance-power", "gold"}, "node-b": {"balance-power", "performance"}} {
+		powerNode := &powerv1.PowerNode{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: node, Namespace: IntelPowerNamespace}, powerNode))
+		names := make([]string, 0, len(powerNode.Status.EffectiveProfiles))
+		for _, effective := range powerNode.Status.EffectiveProfiles {
+			names = append(names, effective.Name)
+		}
+		assert.Equal(t, expected, names, node)
+		assert.Len(t, powerNode.Status.NodeGroups, 1)
+	}
+
+	// the group's PowerProfiles are created with its policies and rollout
+	gold := &powerv1.PowerProfile{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "gold", Namespace: IntelPowerNamespace}, gold))
+	assert.Equal(t, 3000, gold.Spec.Max)
+	assert.Equal(t, 30, gold.Spec.ApplyDeadlineSeconds)
+	assert.True(t, gold.Spec.RollbackOnDeadline)
+
+	// a PowerProfile no group offers any more is deleted
+	rackA.Spec.PowerProfiles = nil
+	assert.NoError(t, r.Client.Update(context.TODO(), rackA))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: "gold", Namespace: IntelPowerNamespace}, gold)
+	assert.True(t, errors.IsNotFound(err))
+}
+
+// reviewingClient allows every SubjectAccessReview but those for the denied resource, and maps every Power kind
+// but the uninstalled one
+type reviewingClient struct {
+	client.Client
+	denied      string
+	uninstalled string
+}
+
+func (c *reviewingClient) RESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{powerv1.GroupVersion})
+	for gvk := range scheme.Scheme.AllKnownTypes() {
+		if gvk.GroupVersion() == powerv1.GroupVersion && gvk.Kind != c.uninstalled {
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+		}
+	}
+	return mapper
+}
+
+func (c *reviewingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+		review.Status.Allowed = review.Spec.ResourceAttributes.Resource != c.denied
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestPowerConfigNodeRequests(t *testing.T) {
+	r, err := createConfigReconcilerObject([]runtime.Object{
+		&powerv1.PowerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "selector-config", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerConfigSpec{
+				PowerNodeSelector: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+			},
+		},
+		&powerv1.PowerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "group-config", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerConfigSpec{
+				PowerNodeSelector: map[string]string{"feature.node.kubernetes.io/power-node": "true"},
+				NodeGroups:        []string{"rack-a"},
+			},
+		},
+		&powerv1.PowerNodeGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "rack-a", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerNodeGroupSpec{NodeSelector: map[string]string{"rack": "a"}},
+		},
+	})
+	assert.NoError(t, err)
+	node := func(nodeLabels map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "new-node", Labels: nodeLabels}}
+	}
+	names := func(requests []reconcile.Request) []string {
+		configs := make([]string, 0, len(requests))
+		for _, request := range requests {
+			configs = append(configs, request.Name)
+		}
+		return configs
+	}
+
+	// a PowerConfig with PowerNodeGroups selects its Nodes through them rather than the PowerNodeSelector
+	assert.Equal(t, []string{"selector-config"}, names(r.nodeConfigRequests(node(map[string]string{"feature.node.kubernetes.io/power-node": "true"}))))
+	assert.Equal(t, []string{"group-config"}, names(r.nodeConfigRequests(node(map[string]string{"rack": "a"}))))
+	assert.ElementsMatch(t, []string{"group-config", "selector-config"},
+		names(r.nodeConfigRequests(node(map[string]string{"feature.node.kubernetes.io/power-node": "true", "rack": "a"}))))
+	assert.Empty(t, r.nodeConfigRequests(node(map[string]string{"rack": "b"})))
+	assert.Empty(t, r.nodeConfigRequests(&powerv1.PowerNode{}))
+}
+
+func TestPreflight(t *testing.T) {
+	labelled := map[string]string{"feature.node.kubernetes.io/power-node": "true"}
+	agent := func(node string, ready corev1.ConditionStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent-" + node, Namespace: IntelPowerNamespace,
+				Labels: map[string]string{NodeAgentPodLabel: NodeAgentPodLabelValue}},
+			Spec: corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}},
+		}
+	}
+	r, err := createConfigReconcilerObject([]runtime.Object{
+		&powerv1.PowerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "power-config", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerConfigSpec{PowerNodeSelector: labelled},
+		},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: labelled}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: labelled}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node3"}},
+		agent("node1", corev1.ConditionTrue),
+		agent("node2", corev1.ConditionFalse),
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{CapabilityProfile: powerv1.CapabilityProfileBareMetal},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2", Namespace: IntelPowerNamespace},
+			Status: powerv1.PowerNodeStatus{CapabilityProfile: powerv1.CapabilityProfileVirtualized,
+				UnavailableControls: []string{"MSR"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	policies := map[string]string{"node1": "static", "node2": "none"}
+	options := preflight.Options{
+		Namespace:              IntelPowerNamespace,
+		OperatorServiceAccount: "intel-power-operator",
+		AgentServiceAccount:    "intel-power-node-agent",
+		AgentSelector:          labels.SelectorFromSet(labels.Set{NodeAgentPodLabel: NodeAgentPodLabelValue}),
+		KubeletConfig: func(ctx context.Context, node string) ([]byte, error) {
+			return []byte(fmt.Sprintf(`{"kubeletconfig":{"cpuManagerPolicy":"%s"}}`, policies[node])), nil
+		},
+	}
+
+	checks, err := preflight.Run(context.TODO(), &reviewingClient{Client: r.Client, denied: "daemonsets", uninstalled: "Uncore"}, options)
+	assert.NoError(t, err)
+	results := make(map[string]string)
+	for _, check := range checks {
+		results[check.Name+"@"+check.Node] = check.Result
+	}
+	assert.Equal(t, preflight.ResultPass, results["crd/powerconfig@"])
+	assert.Equal(t, preflight.ResultFail, results["crd/uncore@"])
+	assert.Equal(t, preflight.ResultFail, results["rbac/intel-power-operator/create/daemonsets@"])
+	assert.Equal(t, preflight.ResultPass, results["rbac/intel-power-node-agent/patch/nodes/status@"])
+	assert.NotContains(t, results, "agent@node1")
+	assert.Equal(t, preflight.ResultFail, results["agent@node2"])
+	assert.NotContains(t, results, "agent@node3")
+	assert.Equal(t, preflight.ResultPass, results["drivers@node1"])
+	assert.Equal(t, preflight.ResultFail, results["drivers@node2"])
+	assert.Equal(t, preflight.ResultPass, results["cpu-manager@node1"])
+	assert.Equal(t, preflight.ResultFail, results["cpu-manager@node2"])
+	assert.False(t, preflight.Passed(checks))
+
+	// only the first Node is sampled
+	options.SampleSize = 1
+	checks, err = preflight.Run(context.TODO(), &reviewingClient{Client: r.Client}, options)
+	assert.NoError(t, err)
+	for _, check := range checks {
+		if check.Node == "node2" && check.Name != "agent" {
+			t.Errorf("expected node2 not to be sampled, got check %s", check.Name)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/handoff"
+	"github.com/intel/kubernetes-power-manager/pkg/util"
+)
+
+const (
+	// CordonedByMaintenanceAnnotation marks Nodes that were cordoned by a PowerMaintenance so that only
+	// those are uncordoned once it ends
+	CordonedByMaintenanceAnnotation = "power.intel.com/cordoned-by-maintenance"
+
+	// how often Pods that started during a maintenance are checked again
+	maintenanceRequeueInterval = time.Minute
+)
+
+// PowerMaintenanceReconciler suspends power management on this Node while a PowerMaintenance selects it.
+// The cores of every pool are reverted to their defaults, PowerWorkloads are not applied and new Pods
+// are not admitted to PowerWorkloads until the maintenance ends
+type PowerMaintenanceReconciler struct {
+	client.Client
+	Log          logr.Logger
+	Scheme       *runtime.Scheme
+	PowerLibrary power.Host
+	// SharedBaseline gives the Shared PowerProfile to restore in place of a temporarily lowered one, the
+	// profile the Shared pool has is restored without it
+	SharedBaseline SharedProfileBaseline
+
+	inMaintenance bool
+	// the Shared pool's PowerProfile from before the maintenance, restored once it ends. It's kept in the
+	// PowerNode's status as well for a Node Agent restarted during the maintenance
+	sharedProfile power.Profile
+}
+
+// SharedProfileBaseline is implemented by controllers that temporarily replace the Shared pool's PowerProfile
+type SharedProfileBaseline interface {
+	BaseProfile(profile power.Profile) power.Profile
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powermaintenances,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=power.intel.com,resources=powermaintenances/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+
+func (r *PowerMaintenanceReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("powermaintenance", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+	nodeName := os.Getenv("NODE_NAME")
+
+	logger.V(5).Info("Retrieving the PowerMaintenances selecting this Node")
+	maintenances, err := maintenancesForNode(c, r.Client, nodeName)
+	if err != nil {
+		logger.Error(err, "error retrieving PowerMaintenances")
+		return ctrl.Result{}, err
+	}
+
+	cordon := false
+	for _, maintenance := range maintenances {
+		cordon = cordon || maintenance.Spec.Cordon
+	}
+
+	// a Node Agent restarted during the maintenance finds what its predecessor suspended in the PowerNode
+	suspended, err := r.suspendedMaintenance(c, nodeName)
+	if err != nil {
+		logger.Error(err, "error retrieving the maintenance state of the PowerNode")
+		return ctrl.Result{}, err
+	}
+
+	if len(maintenances) > 0 && !r.inMaintenance {
+		logger.Info("Node entering maintenance, reverting cores to defaults")
+		err = r.suspend(c, nodeName, suspended)
+		if err != nil {
+			logger.Error(err, "error reverting cores for maintenance")
+			return ctrl.Result{}, err
+		}
+	} else if len(maintenances) == 0 && (r.inMaintenance || suspended != nil) {
+		logger.Info("Node leaving maintenance, resuming power management")
+		err = r.resume(c, nodeName, suspended)
+		if err != nil {
+			logger.Error(err, "error resuming power management after maintenance")
+			return ctrl.Result{}, err
+		}
+	}
+
+	err = r.setCordon(c, nodeName, cordon)
+	if err != nil {
+		logger.Error(err, "error updating Node schedulability")
+		return ctrl.Result{}, err
+	}
+
+	err = r.updateStatus(c, req.NamespacedName, nodeName, &logger)
+	if err != nil {
+		logger.Error(err, "error updating PowerMaintenance status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// suspend moves every core out of the exclusive pools and removes the Shared pool's PowerProfile so
+// the whole Node runs at its default frequencies, within the Node's frequency transition limit. The Shared
+// PowerProfile is recorded in the PowerNode first, unless a previous Node Agent already suspended the Node
+func (r *PowerMaintenanceReconciler) suspend(c context.Context, nodeName string, suspended *powerv1.MaintenanceStatus) error {
+	limit, err := transitionLimit(c, r.Client, nodeName)
+	if err != nil {
+		return err
+	}
+	sharedPool := r.PowerLibrary.GetSharedPool()
+	if suspended != nil {
+		r.sharedProfile = suspendedProfile(suspended)
+	} else {
+		r.sharedProfile = sharedPool.GetPowerProfile()
+		if r.SharedBaseline != nil {
+			// a stepped down profile restored afterwards would be taken for the Shared pool's baseline
+			r.sharedProfile = r.SharedBaseline.BaseProfile(r.sharedProfile)
+		}
+		err = r.recordMaintenance(c, nodeName, &powerv1.MaintenanceStatus{SharedProfile: poolProfile(r.sharedProfile)})
+		if err != nil {
+			return fmt.Errorf("recording the Shared profile in the PowerNode: %w", err)
+		}
+	}
+
+	results := new(multierror.Error)
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		cores := pool.Cpus().IDs()
+		if len(cores) == 0 {
+			continue
+		}
+		err := moveInBatches(c, cores, limit, sharedPool.MoveCpuIDs)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("reverting cores of pool %s: %w", pool.Name(), err))
+		}
+	}
+
+	err = limitTransition(c, limit, func() error { return sharedPool.SetPowerProfile(nil) })
+	if err != nil {
+		results = multierror.Append(results, fmt.Errorf("removing Shared pool profile: %w", err))
+	}
+	r.inMaintenance = true
+
+	return results.ErrorOrNil()
+}
+
+// resume puts the Shared pool's PowerProfile back, the PowerWorkload controller moves the
+// exclusive cores back into their pools as it is notified of the same PowerMaintenance change. Both are
+// spaced out under the Node's frequency transition limit. The Shared PowerProfile comes from the PowerNode when
+// the Node was suspended by a previous Node Agent
+func (r *PowerMaintenanceReconciler) resume(c context.Context, nodeName string, suspended *powerv1.MaintenanceStatus) error {
+	if !r.inMaintenance && suspended != nil {
+		r.sharedProfile = suspendedProfile(suspended)
+	}
+	if r.sharedProfile != nil {
+		limit, err := transitionLimit(c, r.Client, nodeName)
+		if err != nil {
+			return err
+		}
+		err = limitTransition(c, limit, func() error { return r.PowerLibrary.GetSharedPool().SetPowerProfile(r.sharedProfile) })
+		if err != nil {
+			return err
+		}
+	}
+	err := r.recordMaintenance(c, nodeName, nil)
+	if err != nil {
+		return fmt.Errorf("clearing the maintenance state of the PowerNode: %w", err)
+	}
+	r.sharedProfile = nil
+	r.inMaintenance = false
+
+	return nil
+}
+
+// suspendedMaintenance is what the PowerNode records as suspended for a PowerMaintenance, nil if nothing is or
+// the Node has no PowerNode
+func (r *PowerMaintenanceReconciler) suspendedMaintenance(c context.Context, nodeName string) (*powerv1.MaintenanceStatus, error) {
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(c, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+	if err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	return powerNode.Status.Maintenance, nil
+}
+
+// recordMaintenance sets what is suspended in the PowerNode's status, nil once the maintenance ends. A Node without
+// a PowerNode only has it in memory
+func (r *PowerMaintenanceReconciler) recordMaintenance(c context.Context, nodeName string, maintenance *powerv1.MaintenanceStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(c, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if reflect.DeepEqual(powerNode.Status.Maintenance, maintenance) {
+			return nil
+		}
+
+		powerNode.Status.Maintenance = maintenance
+		return r.Client.Status().Update(c, powerNode)
+	})
+}
+
+// poolProfile records the Power Library profile for the PowerNode's status
+func poolProfile(profile power.Profile) *powerv1.PoolProfile {
+	saved := handoff.CaptureProfile(profile)
+	if saved == nil {
+		return nil
+	}
+
+	return &powerv1.PoolProfile{Name: saved.Name, Min: int(saved.Min), Max: int(saved.Max), Governor: saved.Governor, Epp: saved.Epp}
+}
+
+// suspendedProfile gives the Shared profile recorded in the PowerNode back to the Power Library
+func suspendedProfile(suspended *powerv1.MaintenanceStatus) power.Profile {
+	saved := suspended.SharedProfile
+	if saved == nil {
+		return nil
+	}
+
+	return (&handoff.Profile{Name: saved.Name, Min: uint(saved.Min), Max: uint(saved.Max), Governor: saved.Governor, Epp: saved.Epp}).Power()
+}
+
+// setCordon cordons the Node if requested and uncordons it again only if it was cordoned by a PowerMaintenance.
+// Only the changed fields are patched, large Nodes aren't written back whole
+func (r *PowerMaintenanceReconciler) setCordon(c context.Context, nodeName string, cordon bool) error {
+	node := &corev1.Node{}
+	err := r.Client.Get(c, client.ObjectKey{Name: nodeName}, node)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	_, cordonedByUs := node.Annotations[CordonedByMaintenanceAnnotation]
+	switch {
+	case cordon && !node.Spec.Unschedulable:
+		node.Spec.Unschedulable = true
+		if node.Annotations == nil {
+			node.Annotations = make(map[string]string)
+		}
+		node.Annotations[CordonedByMaintenanceAnnotation] = "true"
+	case !cordon && cordonedByUs:
+		node.Spec.Unschedulable = false
+		delete(node.Annotations, CordonedByMaintenanceAnnotation)
+	default:
+		return nil
+	}
+
+	return r.Client.Patch(c, node, patch)
+}
+
+// updateStatus lists this Node in the PowerMaintenance while it is suspended for it
+func (r *PowerMaintenanceReconciler) updateStatus(c context.Context, key client.ObjectKey, nodeName string, logger *logr.Logger) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		maintenance := &powerv1.PowerMaintenance{}
+		err := r.Client.Get(c, key, maintenance)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		node := &corev1.Node{}
+		err = r.Client.Get(c, client.ObjectKey{Name: nodeName}, node)
+		if err != nil {
+			return err
+		}
+
+		listed := util.StringInStringList(nodeName, maintenance.Status.Nodes)
+		selected := maintenanceSelectsNode(maintenance, node) && r.inMaintenance
+		switch {
+		case selected && !listed:
+			maintenance.Status.Nodes = append(maintenance.Status.Nodes, nodeName)
+		case !selected && listed:
+			maintenance.Status.Nodes = util.RemoveStringFromStringList(nodeName, maintenance.Status.Nodes)
+		default:
+			return nil
+		}
+
+		logger.V(5).Info("Updating the Nodes in maintenance", "nodes", maintenance.Status.Nodes)
+		return r.Client.Status().Update(c, maintenance)
+	})
+}
+
+// maintenancesForNode returns the PowerMaintenances that select the Node
+func maintenancesForNode(c context.Context, cl client.Client, nodeName string) ([]powerv1.PowerMaintenance, error) {
+	maintenanceList := &powerv1.PowerMaintenanceList{}
+	err := cl.List(c, maintenanceList, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, err
+	}
+	if len(maintenanceList.Items) == 0 {
+		return nil, nil
+	}
+
+	node := &corev1.Node{}
+	err = cl.Get(c, client.ObjectKey{Name: nodeName}, node)
+	if err != nil {
+		return nil, err
+	}
+
+	maintenances := make([]powerv1.PowerMaintenance, 0)
+	for _, maintenance := range maintenanceList.Items {
+		if maintenanceSelectsNode(&maintenance, node) {
+			maintenances = append(maintenances, maintenance)
+		}
+	}
+
+	return maintenances, nil
+}
+
+// nodeUnderMaintenance is used by the other Node Agent controllers to hold off while the Node is in maintenance
+func nodeUnderMaintenance(c context.Context, cl client.Client, nodeName string) (bool, error) {
+	maintenances, err := maintenancesForNode(c, cl, nodeName)
+	if err != nil {
+		return false, err
+	}
+
+	return len(maintenances) > 0, nil
+}
+
+func maintenanceSelectsNode(maintenance *powerv1.PowerMaintenance, node *corev1.Node) bool {
+	if maintenance.Spec.NodeName != "" {
+		return maintenance.Spec.NodeName == node.Name
+	}
+	if len(maintenance.Spec.NodeSelector) == 0 {
+		return false
+	}
+
+	return labels.SelectorFromSet(maintenance.Spec.NodeSelector).Matches(labels.Set(node.Labels))
+}
+
+func (r *PowerMaintenanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.PowerMaintenance{}).
+		Complete(r)
+}
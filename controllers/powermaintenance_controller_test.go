@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createMaintenanceReconcilerObject(objs []runtime.Object) (*PowerMaintenanceReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &PowerMaintenanceReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s}, nil
+}
+
+func TestPowerMaintenanceReconciler(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   nodeName,
+			Labels: map[string]string{"rack": "a"},
+		},
+	}
+	maintenance := &powerv1.PowerMaintenance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "firmware-update",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerMaintenanceSpec{
+			NodeSelector: map[string]string{"rack": "a"},
+			Cordon:       true,
+			Reason:       "BIOS update",
+		},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(maintenance)}
+
+	r, err := createMaintenanceReconcilerObject([]runtime.Object{node, maintenance})
+	assert.NoError(t, err)
+
+	core2 := new(coreMock)
+	core2.On("GetID").Return(uint(2))
+	core3 := new(coreMock)
+	core3.On("GetID").Return(uint(3))
+	sharedProfile := new(profMock)
+	sharedProfile.On("Name").Return("shared")
+	sharedProfile.On("MinFreq").Return(uint(800))
+	sharedProfile.On("MaxFreq").Return(uint(1500))
+	sharedProfile.On("Governor").Return("powersave")
+	sharedProfile.On("Epp").Return("power")
+
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{core2, core3})
+	sharedPool := new(poolMock)
+	sharedPool.On("MoveCpuIDs", []uint{2, 3}).Return(nil)
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	sharedPool.On("SetPowerProfile", nil).Return(nil)
+	sharedPool.On("SetPowerProfile", sharedProfile).Return(nil)
+
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool})
+	r.PowerLibrary = powerLibMock
+
+	// entering maintenance reverts the cores and cordons the Node
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	sharedPool.AssertCalled(t, "MoveCpuIDs", []uint{2, 3})
+	sharedPool.AssertCalled(t, "SetPowerProfile", nil)
+
+	underMaintenance, err := nodeUnderMaintenance(context.TODO(), r.Client, nodeName)
+	assert.NoError(t, err)
+	assert.True(t, underMaintenance)
+
+	updatedNode := &corev1.Node{}
+	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, updatedNode)
+	assert.NoError(t, err)
+	assert.True(t, updatedNode.Spec.Unschedulable)
+	assert.Contains(t, updatedNode.Annotations, CordonedByMaintenanceAnnotation)
+
+	updatedMaintenance := &powerv1.PowerMaintenance{}
+	err = r.Client.Get(context.TODO(), req.NamespacedName, updatedMaintenance)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{nodeName}, updatedMaintenance.Status.Nodes)
+
+	// reconciling again while in maintenance changes nothing
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	sharedPool.AssertNumberOfCalls(t, "MoveCpuIDs", 1)
+
+	// ending the maintenance restores the Shared profile and uncordons the Node
+	err = r.Client.Delete(context.TODO(), updatedMaintenance)
+	assert.NoError(t, err)
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	sharedPool.AssertCalled(t, "SetPowerProfile", sharedProfile)
+
+	underMaintenance, err = nodeUnderMaintenance(context.TODO(), r.Client, nodeName)
+	assert.NoError(t, err)
+	assert.False(t, underMaintenance)
+
+	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, updatedNode)
+	assert.NoError(t, err)
+	assert.False(t, updatedNode.Spec.Unschedulable)
+	assert.NotContains(t, updatedNode.Annotations, CordonedByMaintenanceAnnotation)
+}
+
+func TestPowerMaintenanceReconciler_AgentRestart(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+	powerNode := &powerv1.PowerNode{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: IntelPowerNamespace}}
+	maintenance := &powerv1.PowerMaintenance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "firmware-update",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerMaintenanceSpec{NodeName: nodeName},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(maintenance)}
+	r, err := createMaintenanceReconcilerObject([]runtime.Object{node, powerNode, maintenance})
+	assert.NoError(t, err)
+
+	sharedProfile := new(profMock)
+	sharedProfile.On("Name").Return("shared")
+	sharedProfile.On("MinFreq").Return(uint(800))
+	sharedProfile.On("MaxFreq").Return(uint(1500))
+	sharedProfile.On("Governor").Return("powersave")
+	sharedProfile.On("Epp").Return("power")
+	sharedPool := new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	sharedPool.On("SetPowerProfile", nil).Return(nil)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{})
+	r.PowerLibrary = powerLibMock
+
+	// the Shared profile suspended for the maintenance is kept in the PowerNode
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	expected := &powerv1.MaintenanceStatus{
+		SharedProfile: &powerv1.PoolProfile{Name: "shared", Min: 800, Max: 1500, Governor: "powersave", Epp: "power"},
+	}
+	updatedPowerNode := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), updatedPowerNode))
+	assert.Equal(t, expected, updatedPowerNode.Status.Maintenance)
+
+	// a restarted Node Agent suspends the Node again without taking the reapplied profile for the one to restore
+	restarted := &PowerMaintenanceReconciler{Client: r.Client, Log: r.Log, Scheme: r.Scheme}
+	reappliedPool := new(poolMock)
+	reappliedPool.On("SetPowerProfile", nil).Return(nil)
+	reappliedPool.On("SetPowerProfile", mock.Anything).Return(nil)
+	restartedLibMock := new(hostMock)
+	restartedLibMock.On("GetSharedPool").Return(reappliedPool)
+	restartedLibMock.On("GetAllExclusivePools").Return(&power.PoolList{})
+	restarted.PowerLibrary = restartedLibMock
+
+	_, err = restarted.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	reappliedPool.AssertCalled(t, "SetPowerProfile", nil)
+	reappliedPool.AssertNotCalled(t, "GetPowerProfile")
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), updatedPowerNode))
+	assert.Equal(t, expected, updatedPowerNode.Status.Maintenance)
+
+	// ending the maintenance restores the profile recorded by the previous Node Agent
+	assert.NoError(t, r.Client.Delete(context.TODO(), maintenance))
+	_, err = restarted.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	restored := false
+	for _, call := range reappliedPool.Calls {
+		if profile, ok := call.Arguments.Get(0).(power.Profile); ok && call.Method == "SetPowerProfile" && profile != nil {
+			restored = profile.Name() == "shared" && profile.MinFreq() == 800 && profile.MaxFreq() == 1500 &&
+				profile.Governor() == "powersave" && profile.Epp() == "power"
+		}
+	}
+	assert.True(t, restored)
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), updatedPowerNode))
+	assert.Nil(t, updatedPowerNode.Status.Maintenance)
+}
+
+func TestPowerMaintenanceReconciler_UncordonOnlyOwnCordon(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	// cordoned by an admin before the maintenance
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	}
+	maintenance := &powerv1.PowerMaintenance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "firmware-update",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerMaintenanceSpec{NodeName: nodeName, Cordon: true},
+	}
+	r, err := createMaintenanceReconcilerObject([]runtime.Object{node, maintenance})
+	assert.NoError(t, err)
+
+	sharedPool := new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(nil)
+	sharedPool.On("SetPowerProfile", mock.Anything).Return(nil)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{})
+	r.PowerLibrary = powerLibMock
+
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(maintenance)}
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	err = r.Client.Delete(context.TODO(), maintenance)
+	assert.NoError(t, err)
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	updatedNode := &corev1.Node{}
+	err = r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, updatedNode)
+	assert.NoError(t, err)
+	assert.True(t, updatedNode.Spec.Unschedulable)
+}
+
+func TestMaintenanceSelectsNode(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "TestNode",
+			Labels: map[string]string{"rack": "a", "zone": "1"},
+		},
+	}
+
+	tcases := []struct {
+		testCase string
+		spec     powerv1.PowerMaintenanceSpec
+		expected bool
+	}{
+		{"Test Case 1 - node name", powerv1.PowerMaintenanceSpec{NodeName: "TestNode"}, true},
+		{"Test Case 2 - other node name", powerv1.PowerMaintenanceSpec{NodeName: "OtherNode"}, false},
+		{"Test Case 3 - matching selector", powerv1.PowerMaintenanceSpec{NodeSelector: map[string]string{"rack": "a"}}, true},
+		{"Test Case 4 - partially matching selector", powerv1.PowerMaintenanceSpec{NodeSelector: map[string]string{"rack": "a", "zone": "2"}}, false},
+		{"Test Case 5 - node name takes precedence", powerv1.PowerMaintenanceSpec{NodeName: "OtherNode", NodeSelector: map[string]string{"rack": "a"}}, false},
+		{"Test Case 6 - empty spec selects nothing", powerv1.PowerMaintenanceSpec{}, false},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		maintenance := &powerv1.PowerMaintenance{Spec: tc.spec}
+		assert.Equal(t, tc.expected, maintenanceSelectsNode(maintenance, node))
+	}
+}
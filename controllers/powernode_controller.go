@@ -30,6 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/objectsize"
 	"github.com/intel/power-optimization-library/pkg/power"
 )
 
@@ -45,7 +46,6 @@ type PowerNodeReconciler struct {
 // +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
 
 func (r *PowerNodeReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
 
 	logger := r.Log.WithValues("powernode", req.NamespacedName)
 	logger.V(5).Info("Checking if PowerNode and Node Name match")
@@ -62,7 +62,7 @@ func (r *PowerNodeReconciler) Reconcile(c context.Context, req ctrl.Request) (ct
 
 	powerNode := &powerv1.PowerNode{}
 	logger.V(5).Info("Retrieving Power Node instance")
-	err := r.Client.Get(context.TODO(), req.NamespacedName, powerNode)
+	err := r.Client.Get(c, req.NamespacedName, powerNode)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			logger.V(5).Info("Power Node not found, requeueing")
@@ -79,7 +79,7 @@ func (r *PowerNodeReconciler) Reconcile(c context.Context, req ctrl.Request) (ct
 
 	powerProfiles := &powerv1.PowerProfileList{}
 	logger.V(5).Info("Retrieving PowerProfileList")
-	err = r.Client.List(context.TODO(), powerProfiles)
+	err = r.Client.List(c, powerProfiles)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
@@ -105,7 +105,7 @@ func (r *PowerNodeReconciler) Reconcile(c context.Context, req ctrl.Request) (ct
 
 	powerWorkloads := &powerv1.PowerWorkloadList{}
 	logger.V(5).Info("Retrieving PowerWorkloadList")
-	err = r.Client.List(context.TODO(), powerWorkloads)
+	err = r.Client.List(c, powerWorkloads, client.MatchingFields{WorkloadNodeNameIndex: nodeName})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
@@ -120,7 +120,7 @@ func (r *PowerNodeReconciler) Reconcile(c context.Context, req ctrl.Request) (ct
 			continue
 		}
 
-		poolFromLibrary := r.PowerLibrary.GetExclusivePool(workload.Spec.Name)
+		poolFromLibrary := r.PowerLibrary.GetExclusivePool(workload.Spec.PowerProfile)
 		logger.V(5).Info("Retrieving workload information from Power Library")
 		if poolFromLibrary == nil {
 			continue
@@ -167,7 +167,8 @@ func (r *PowerNodeReconciler) Reconcile(c context.Context, req ctrl.Request) (ct
 		powerNode.Spec.CustomDevices = CustomDevices
 	}
 
-	err = r.Client.Update(context.TODO(), powerNode)
+	objectsize.Warn(logger, "PowerNode", powerNode)
+	err = r.Client.Update(c, powerNode)
 	if err != nil {
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
 	}
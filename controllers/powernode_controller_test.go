@@ -1,9 +1,17 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -13,6 +21,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/drift"
+	"github.com/intel/kubernetes-power-manager/pkg/savings"
+	"github.com/intel/kubernetes-power-manager/pkg/stats"
 	//"github.com/stretchr/testify/mock"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,7 +39,7 @@ func createPowerNodeReconcilerObject(objs []runtime.Object) (*PowerNodeReconcile
 	}
 
 	// Create a fake client to mock API calls.
-	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).Build()
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithIndex(&powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer).Build()
 
 	// Create a ReconcileNode object with the scheme and fake client.
 	r := &PowerNodeReconciler{cl, ctrl.Log.WithName("testing"), s, nil}
@@ -93,3 +104,341 @@ func TestPowerNodeNotCorrectNode(t *testing.T) {
 		}
 	}
 }
+
+func TestStatsServer(t *testing.T) {
+	workload := func(name string, profile string, node string, cpus []uint, containers int) *powerv1.PowerWorkload {
+		w := &powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				Name:         name,
+				PowerProfile: profile,
+				Node:         powerv1.WorkloadNode{Name: node, CpuIds: cpus},
+			},
+		}
+		for i := 0; i < containers; i++ {
+			w.Spec.Node.Containers = append(w.Spec.Node.Containers, powerv1.Container{Name: fmt.Sprintf("c%d", i)})
+		}
+		return w
+	}
+	powerNode := func(name string, packageWatts int, chassisWatts int) *powerv1.PowerNode {
+		return &powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{PackagePowerWatts: packageWatts, ChassisPowerWatts: chassisWatts},
+		}
+	}
+	r, err := createPowerNodeReconcilerObject([]runtime.Object{
+		workload("performance-node1", "performance", "node1", []uint{2, 3}, 1),
+		workload("performance-node2", "performance", "node2", []uint{4, 5, 6}, 2),
+		workload("balance-power-node1", "balance-power", "node1", nil, 0),
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-node1", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerWorkloadSpec{Name: "shared-node1", AllCores: true},
+		},
+		powerNode("node1", 200, 0),
+		powerNode("node2", 150, 400),
+		powerNode("node3", 0, 0),
+	})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	server := &stats.Server{Client: r.Client, Log: r.Log, History: 2}
+
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err = server.Sample(context.TODO(), now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("error sampling: %v", err)
+		}
+	}
+
+	summary, err := server.Stats(context.TODO(), 1, now)
+	if err != nil {
+		t.Fatalf("error gathering stats: %v", err)
+	}
+	assert.Equal(t, map[string]stats.PoolStats{
+		"performance":   {Nodes: 2, CPUs: 5, Containers: 3},
+		"balance-power": {},
+	}, summary.Pools)
+	assert.Equal(t, []stats.PowerSample{
+		{Time: now.Add(time.Minute), PackageWatts: 350, ChassisWatts: 400},
+		{Time: now.Add(2 * time.Minute), PackageWatts: 350, ChassisWatts: 400},
+	}, summary.EnergyTrend)
+	assert.Equal(t, []stats.NodePower{{Node: "node2", Watts: 400, PackageWatts: 150, ChassisWatts: 400}}, summary.TopConsumers)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	served := &stats.Stats{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), served))
+	assert.Len(t, served.TopConsumers, 2)
+	assert.Equal(t, "node1", served.TopConsumers[1].Node)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stats?top=many", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/stats", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestStatsCapacityReport(t *testing.T) {
+	node := func(name string, gold string, cpu string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				ExtendedResourcePrefix + "gold": resource.MustParse(gold),
+				corev1.ResourceCPU:              resource.MustParse(cpu),
+			}},
+		}
+	}
+	pod := func(name string, node string, gold string, cpu string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+					ExtendedResourcePrefix + "gold": resource.MustParse(gold),
+					corev1.ResourceCPU:              resource.MustParse(cpu),
+				}},
+			}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	labelled := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{CapacityLabelPrefix + "gold": "2"}},
+		Status:     corev1.NodeStatus{Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("64")}},
+	}
+	finished := pod("finished", "node1", "8", "8")
+	finished.Status.Phase = corev1.PodSucceeded
+	r, err := createPowerNodeReconcilerObject([]runtime.Object{
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "gold", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: "gold", Epp: "performance", Max: 3600, Min: 3200},
+		},
+		node("node1", "16", "64"),
+		node("node2", "16", "10"),
+		labelled,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node4"}},
+		pod("app1", "node1", "4", "4"),
+		pod("app2", "node2", "2", "2"),
+		finished,
+	})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	server := &stats.Server{Client: r.Client, Log: r.Log, Namespace: IntelPowerNamespace,
+		ResourcePrefix: ExtendedResourcePrefix, LabelPrefix: CapacityLabelPrefix}
+
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	report, err := server.Capacity(context.TODO(), "gold", resource.MustParse("4"), now)
+	if err != nil {
+		t.Fatalf("error planning capacity: %v", err)
+	}
+	assert.Equal(t, int64(5), report.Pods)
+	assert.Equal(t, 3600, report.MaxFrequency)
+	assert.Equal(t, []stats.NodeCapacity{
+		{Node: "node1", Pods: 3, Limit: "profile", FreeProfile: 12, FreeCPUMillis: 60000},
+		{Node: "node2", Pods: 2, Limit: "cpu", FreeProfile: 14, FreeCPUMillis: 8000},
+		{Node: "node3", Pods: 0, Limit: "profile", FreeProfile: 2, FreeCPUMillis: 64000},
+	}, report.Nodes)
+
+	_, err = server.Capacity(context.TODO(), "platinum", resource.MustParse("1"), now)
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestSavingsReport(t *testing.T) {
+	r, err := createPowerNodeReconcilerObject([]runtime.Object{
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{PackagePowerWatts: 200},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{PackagePowerWatts: 150, ChassisPowerWatts: 400},
+		},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:        "node2",
+			Annotations: map[string]string{savings.BaselineWattsAnnotation: "500"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	newReporter := func() *savings.Reporter {
+		return &savings.Reporter{Client: r.Client, Log: r.Log, Namespace: IntelPowerNamespace, Name: "power-savings-report",
+			Interval: time.Hour, Period: 2 * time.Hour}
+	}
+	readReport := func(key string) *savings.Report {
+		configMap := &corev1.ConfigMap{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "power-savings-report", Namespace: IntelPowerNamespace}, configMap))
+		report := &savings.Report{}
+		assert.NoError(t, json.Unmarshal([]byte(configMap.Data[key]), report))
+		return report
+	}
+	reporter := newReporter()
+	start := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, reporter.Sample(context.TODO(), start))
+	assert.NoError(t, reporter.Sample(context.TODO(), start.Add(time.Hour)))
+	powerNode := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "node1", Namespace: IntelPowerNamespace}, powerNode))
+	powerNode.Status.PackagePowerWatts = 100
+	assert.NoError(t, r.Client.Status().Update(context.TODO(), powerNode))
+	assert.NoError(t, reporter.Sample(context.TODO(), start.Add(2*time.Hour)))
+
+	// node1 is measured against its peak, node2 against its annotated baseline
+	report := readReport(savings.ReportKey)
+	assert.Equal(t, start, report.Start)
+	assert.Equal(t, start.Add(2*time.Hour), report.End)
+	assert.Equal(t, []savings.NodeSavings{
+		{Node: "node1", Hours: 2, PeakWatts: 200, BaselineWatts: 200,
+			Savings: savings.Savings{EnergyWh: 300, BaselineWh: 400, SavedWh: 100, SavedPercent: 25}},
+		{Node: "node2", Hours: 2, PeakWatts: 400, BaselineWatts: 500,
+			Savings: savings.Savings{EnergyWh: 800, BaselineWh: 1000, SavedWh: 200, SavedPercent: 20}},
+	}, report.Nodes)
+	assert.Equal(t, savings.Savings{EnergyWh: 1100, BaselineWh: 1400, SavedWh: 300, SavedPercent: 21.4}, report.Cluster)
+
+	// the next period carries on from the ConfigMap after a restart, time nothing sampled isn't counted
+	reporter = newReporter()
+	reporter.Period = 24 * time.Hour
+	assert.NoError(t, reporter.Sample(context.TODO(), start.Add(3*time.Hour)))
+	assert.NoError(t, reporter.Sample(context.TODO(), start.Add(10*time.Hour)))
+	progress := readReport(savings.ProgressKey)
+	assert.Equal(t, start.Add(2*time.Hour), progress.Start)
+	assert.Equal(t, 500.0, progress.Cluster.EnergyWh)
+	assert.Equal(t, 1.0, progress.Nodes[0].Hours)
+}
+
+func TestConfigurationDrift(t *testing.T) {
+	profile := func(name string, epp string, max int, min int) *powerv1.PowerProfile {
+		return &powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: name, Epp: epp, Max: max, Min: min},
+		}
+	}
+	workload := func(name string, profile string, node string, cpus []uint, phase string) *powerv1.PowerWorkload {
+		return &powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				Name:         name,
+				PowerProfile: profile,
+				Node:         powerv1.WorkloadNode{Name: node, CpuIds: cpus},
+			},
+			Status: powerv1.PowerWorkloadStatus{Phase: phase},
+		}
+	}
+	r, err := createPowerNodeReconcilerObject([]runtime.Object{
+		profile("performance", "performance", 3500, 3300),
+		profile("balance-power", "balance_power", 0, 0),
+		profile("shared", "power", 1500, 1000),
+		workload("performance-node1", "performance", "node1", []uint{2, 3, 4, 5}, powerv1.WorkloadPhaseValidating),
+		workload("balance-power-node1", "balance-power", "node1", []uint{8}, powerv1.WorkloadPhaseFailed),
+		workload("performance-node2", "performance", "node2", []uint{2, 3}, ""),
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerNodeSpec{
+				PowerProfiles:  []string{"performance: 3200 || 3000 || performance", "balance-power: 2800 || 2600 || balance_power"},
+				PowerWorkloads: []string{"performance: performance || 2-3", "balance-power: balance-power || 8"},
+				SharedPool:     "shared || 1200 || 1000 || 0-1,6-7",
+			},
+			Status: powerv1.PowerNodeStatus{
+				SharedPoolStep:         1,
+				SharedPoolMaxFrequency: 1200,
+				EffectiveProfiles: []powerv1.EffectiveProfile{{
+					Name:          "performance",
+					Source:        powerv1.EffectiveSourceNode,
+					ProfilePolicy: powerv1.ProfilePolicy{Min: 3000},
+				}},
+			},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerNodeSpec{
+				PowerProfiles:  []string{"performance: 3500 || 3300 || performance", "balance-power: 2800 || 2600 || balance_power"},
+				PowerWorkloads: []string{"performance: performance || 2-3"},
+				SharedPool:     "other || 1500 || 1000 || 0-1",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+
+	drifts, err := drift.Compute(context.TODO(), r.Client, IntelPowerNamespace, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []drift.Drift{
+		{Node: "node1", Cores: "2-3", Setting: "profile/performance/max", Desired: "3500", Actual: "3200", State: drift.StateDrift},
+		{Node: "node1", Cores: "8", Setting: "workload/balance-power-node1/phase", Desired: powerv1.WorkloadPhaseSucceeded, Actual: powerv1.WorkloadPhaseFailed, State: drift.StateDrift},
+		{Node: "node1", Cores: "2-5", Setting: "workload/performance-node1/cores", Desired: "2-5", Actual: "2-3", State: drift.StatePartial},
+		{Node: "node2", Cores: "0-1", Setting: "sharedPool/profile", Desired: "shared", Actual: "other", State: drift.StateDrift},
+	}, drifts)
+
+	drifts, err = drift.Compute(context.TODO(), r.Client, IntelPowerNamespace, "node2")
+	assert.NoError(t, err)
+	assert.Len(t, drifts, 1)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, drift.Print(out, drifts))
+	assert.Contains(t, out.String(), "sharedPool/profile")
+	out.Reset()
+	assert.NoError(t, drift.Print(out, nil))
+	assert.Equal(t, "No drift between the Power CRs and the PowerNodes\n", out.String())
+}
+
+func TestClusterPowerState(t *testing.T) {
+	performance := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerProfileSpec{Name: "performance", Epp: "performance", Max: 3500, Min: 3300},
+	}
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerNodeSpec{
+			PowerProfiles:  []string{"performance: 3500 || 3300 || performance"},
+			PowerWorkloads: []string{"performance: performance || 2-3"},
+		},
+	}
+	r, err := createPowerNodeReconcilerObject([]runtime.Object{
+		performance,
+		powerNode,
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "performance-node1", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				Name:         "performance-node1",
+				PowerProfile: "performance",
+				Node:         powerv1.WorkloadNode{Name: "node1", CpuIds: []uint{3, 2}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+
+	converged, err := drift.ComputeState(context.TODO(), r.Client, IntelPowerNamespace)
+	assert.NoError(t, err)
+	assert.True(t, converged.Converged)
+	assert.Len(t, converged.DesiredChecksum, 64)
+	assert.Len(t, converged.ObservedChecksum, 64)
+
+	// the order a Node Agent reports its pools in doesn't change the checksum
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), powerNode))
+	powerNode.Spec.PowerWorkloads = []string{"shared: shared || 0-1", "performance: performance || 2-3"}
+	assert.NoError(t, r.Client.Update(context.TODO(), powerNode))
+	withShared, err := drift.ComputeState(context.TODO(), r.Client, IntelPowerNamespace)
+	assert.NoError(t, err)
+	powerNode.Spec.PowerWorkloads = []string{"performance: performance || 2-3", "shared: shared || 0-1"}
+	assert.NoError(t, r.Client.Update(context.TODO(), powerNode))
+	reordered, err := drift.ComputeState(context.TODO(), r.Client, IntelPowerNamespace)
+	assert.NoError(t, err)
+	assert.Equal(t, withShared.ObservedChecksum, reordered.ObservedChecksum)
+	assert.NotEqual(t, converged.ObservedChecksum, reordered.ObservedChecksum)
+
+	// a new max frequency changes the desired state, the Node hasn't applied it yet
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(performance), performance))
+	performance.Spec.Max = 3600
+	assert.NoError(t, r.Client.Update(context.TODO(), performance))
+	diverged, err := drift.ComputeState(context.TODO(), r.Client, IntelPowerNamespace)
+	assert.NoError(t, err)
+	assert.False(t, diverged.Converged)
+	assert.NotEqual(t, converged.DesiredChecksum, diverged.DesiredChecksum)
+	assert.Equal(t, reordered.ObservedChecksum, diverged.ObservedChecksum)
+}
@@ -0,0 +1,202 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+const NodeGroupOverBudgetReason = "NodeGroupOverBudget"
+
+// PowerNodeGroupReconciler reports which Nodes are in each PowerNodeGroup and the power they draw together,
+// against the group's budget. The PowerConfig Controller deploys the Node Agent and PowerProfiles to the groups
+type PowerNodeGroupReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// MaxConcurrentReconciles is how many objects are reconciled at once, 1 if 0
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodegroups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodegroups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+func (r *PowerNodeGroupReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("powernodegroup", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+
+	group := &powerv1.PowerNodeGroup{}
+	err := r.Client.Get(c, req.NamespacedName, group)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error retrieving the PowerNodeGroup")
+		return ctrl.Result{}, err
+	}
+
+	nodeList := &corev1.NodeList{}
+	err = r.Client.List(c, nodeList, client.MatchingLabels(group.Spec.NodeSelector))
+	if err != nil {
+		logger.Error(err, "error retrieving the Nodes of the group")
+		return ctrl.Result{}, err
+	}
+	powerNodes := &powerv1.PowerNodeList{}
+	err = r.Client.List(c, powerNodes, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		logger.Error(err, "error retrieving the PowerNodes")
+		return ctrl.Result{}, err
+	}
+	agentNodes := make(map[string]*powerv1.PowerNode, len(powerNodes.Items))
+	for i := range powerNodes.Items {
+		agentNodes[powerNodes.Items[i].Name] = &powerNodes.Items[i]
+	}
+
+	nodeNames := make([]string, 0, len(nodeList.Items))
+	watts := 0
+	for _, node := range nodeList.Items {
+		powerNode, exists := agentNodes[node.Name]
+		if !exists {
+			continue
+		}
+		nodeNames = append(nodeNames, node.Name)
+		if powerNode.Status.ChassisPowerWatts > 0 {
+			watts += powerNode.Status.ChassisPowerWatts
+		} else {
+			watts += powerNode.Status.PackagePowerWatts
+		}
+	}
+	sort.Strings(nodeNames)
+
+	wasOverBudget := meta.IsStatusConditionTrue(group.Status.Conditions, powerv1.ConditionOverBudget)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &powerv1.PowerNodeGroup{}
+		err := r.Client.Get(c, req.NamespacedName, latest)
+		if err != nil {
+			return err
+		}
+		status := latest.Status.DeepCopy()
+		status.Nodes = nodeNames
+		status.NodeCount = len(nodeNames)
+		status.PowerWatts = watts
+		setBudgetCondition(status, latest.Spec.PowerBudgetWatts, latest.Generation)
+		if reflect.DeepEqual(&latest.Status, status) {
+			return nil
+		}
+		latest.Status = *status
+		return r.Client.Status().Update(c, latest)
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error updating the PowerNodeGroup status")
+		return ctrl.Result{}, err
+	}
+
+	if !wasOverBudget && group.Spec.PowerBudgetWatts > 0 && watts > group.Spec.PowerBudgetWatts {
+		logger.Info("PowerNodeGroup is over its power budget", "powerWatts", watts, "powerBudgetWatts", group.Spec.PowerBudgetWatts)
+		if r.Recorder != nil {
+			r.Recorder.Event(group, corev1.EventTypeWarning, NodeGroupOverBudgetReason,
+				fmt.Sprintf("%d Nodes draw %dW against a budget of %dW", len(nodeNames), watts, group.Spec.PowerBudgetWatts))
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// setBudgetCondition reports whether the group draws more than its budget, groups without one have no condition
+func setBudgetCondition(status *powerv1.PowerNodeGroupStatus, budget int, generation int64) {
+	if budget <= 0 {
+		meta.RemoveStatusCondition(&status.Conditions, powerv1.ConditionOverBudget)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               powerv1.ConditionOverBudget,
+		Status:             metav1.ConditionFalse,
+		Reason:             powerv1.ReasonWithinBudget,
+		Message:            fmt.Sprintf("The Nodes draw %dW of a %dW budget", status.PowerWatts, budget),
+		ObservedGeneration: generation,
+	}
+	if status.PowerWatts > budget {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = powerv1.ReasonBudgetExceeded
+		condition.Message = fmt.Sprintf("The Nodes draw %dW, %dW over the %dW budget", status.PowerWatts,
+			status.PowerWatts-budget, budget)
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// nodeGroupMatches reports whether the Node is in the PowerNodeGroup
+func nodeGroupMatches(group *powerv1.PowerNodeGroup, node *corev1.Node) bool {
+	return labels.SelectorFromSet(group.Spec.NodeSelector).Matches(labels.Set(node.Labels))
+}
+
+func (r *PowerNodeGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.PowerNodeGroup{}).
+		Watches(&source.Kind{Type: &powerv1.PowerNode{}}, handler.EnqueueRequestsFromMapFunc(r.nodeGroupRequests)).
+		Watches(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(r.nodeGroupRequests)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+// nodeGroupRequests reconciles every PowerNodeGroup again when a Node's labels or power change, as any of them
+// may gain or lose it
+func (r *PowerNodeGroupReconciler) nodeGroupRequests(obj client.Object) []reconcile.Request {
+	groups := &powerv1.PowerNodeGroupList{}
+	err := r.Client.List(context.TODO(), groups, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		r.Log.Error(err, "error listing PowerNodeGroups")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(groups.Items))
+	for _, group := range groups.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&group)})
+	}
+
+	return requests
+}
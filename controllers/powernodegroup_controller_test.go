@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createNodeGroupReconcilerObject(objs []runtime.Object) (*PowerNodeGroupReconciler, *record.FakeRecorder, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	return &PowerNodeGroupReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s, Recorder: recorder}, recorder, nil
+}
+
+func TestPowerNodeGroupReconciler(t *testing.T) {
+	objs := []runtime.Object{
+		&powerv1.PowerNodeGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "rack-a", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerNodeGroupSpec{NodeSelector: map[string]string{"rack": "a"}, PowerBudgetWatts: 500},
+		},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"rack": "a"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"rack": "a"}}},
+		// no Node Agent runs on node3 yet
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"rack": "a"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node4", Labels: map[string]string{"rack": "b"}}},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{ChassisPowerWatts: 300, PackagePowerWatts: 200},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{PackagePowerWatts: 150},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node4", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{ChassisPowerWatts: 400},
+		},
+	}
+	r, recorder, err := createNodeGroupReconcilerObject(objs)
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "rack-a", Namespace: IntelPowerNamespace}}
+	getGroup := func() *powerv1.PowerNodeGroup {
+		group := &powerv1.PowerNodeGroup{}
+		assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, group))
+		return group
+	}
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	group := getGroup()
+	assert.Equal(t, []string{"node1", "node2"}, group.Status.Nodes)
+	assert.Equal(t, 2, group.Status.NodeCount)
+	assert.Equal(t, 450, group.Status.PowerWatts)
+	condition := meta.FindStatusCondition(group.Status.Conditions, powerv1.ConditionOverBudget)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, powerv1.ReasonWithinBudget, condition.Reason)
+	}
+
+	// going over the budget is reported once
+	powerNode := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "node2", Namespace: IntelPowerNamespace}, powerNode))
+	powerNode.Status.PackagePowerWatts = 250
+	assert.NoError(t, r.Client.Status().Update(context.TODO(), powerNode))
+	for i := 0; i < 2; i++ {
+		_, err = r.Reconcile(context.TODO(), req)
+		assert.NoError(t, err)
+	}
+	group = getGroup()
+	assert.Equal(t, 550, group.Status.PowerWatts)
+	assert.True(t, meta.IsStatusConditionTrue(group.Status.Conditions, powerv1.ConditionOverBudget))
+	assert.Len(t, recorder.Events, 1)
+
+	// without a budget there's nothing to report
+	group.Spec.PowerBudgetWatts = 0
+	assert.NoError(t, r.Client.Update(context.TODO(), group))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Empty(t, getGroup().Status.Conditions)
+
+	assert.Len(t, r.nodeGroupRequests(powerNode), 1)
+}
@@ -0,0 +1,454 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/dryrun"
+	"github.com/intel/kubernetes-power-manager/pkg/parking"
+)
+
+const (
+	// ParkedAnnotation marks Nodes that were cordoned and put into a low power state by a PowerParking,
+	// its value is the power state
+	ParkedAnnotation = "power.intel.com/parked"
+	// IdleSinceAnnotation records when a Node was first seen running nothing but DaemonSet Pods
+	IdleSinceAnnotation = "power.intel.com/idle-since"
+
+	NodeParkedReason     = "NodeParked"
+	NodeWokenReason      = "NodeWoken"
+	NodeParkFailedReason = "NodeParkFailed"
+
+	// how often Nodes are checked for idleness and Pods for unmet demand
+	parkingInterval = time.Minute
+)
+
+// PowerParkingReconciler parks the selected Nodes once they are idle and wakes them again when Pods cannot be scheduled
+type PowerParkingReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// APIReader reads the BMC credentials without caching every Secret in the cluster
+	APIReader client.Reader
+	// PowerControl overrides the one built from the PowerParking's spec
+	PowerControl parking.PowerControl
+	// DryRun keeps Nodes from being powered off or on while it is enabled
+	DryRun *dryrun.Client
+	// MaxConcurrentReconciles is how many objects are reconciled at once, 1 if 0
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerparkings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerparkings/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+func (r *PowerParkingReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("powerparking", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+
+	powerParking := &powerv1.PowerParking{}
+	err := r.Client.Get(c, req.NamespacedName, powerParking)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error retrieving the PowerParking")
+		return ctrl.Result{}, err
+	}
+
+	if len(powerParking.Spec.NodeSelector) == 0 {
+		// the CRD rejects this, but an empty selector matches every Node in the cluster so is never acted on
+		err = fmt.Errorf("the nodeSelector is empty")
+		logger.Error(err, "refusing to park Nodes")
+		return ctrl.Result{}, r.updateStatus(c, powerParking, nil, err.Error())
+	}
+
+	powerControl, err := r.powerControl(c, powerParking)
+	if err != nil {
+		logger.Error(err, "error setting up power control")
+		return ctrl.Result{}, r.updateStatus(c, powerParking, nil, err.Error())
+	}
+
+	nodeList := &corev1.NodeList{}
+	err = r.Client.List(c, nodeList, client.MatchingLabels(powerParking.Spec.NodeSelector))
+	if err != nil {
+		logger.Error(err, "error retrieving the selected Nodes")
+		return ctrl.Result{}, err
+	}
+	podList := &corev1.PodList{}
+	err = r.Client.List(c, podList)
+	if err != nil {
+		logger.Error(err, "error retrieving Pods")
+		return ctrl.Result{}, err
+	}
+
+	busyNodes := make(map[string]bool)
+	unschedulable := make([]corev1.Pod, 0)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == "" {
+			if podUnschedulable(&pod) {
+				unschedulable = append(unschedulable, pod)
+			}
+			continue
+		}
+		if !podAllowsParking(&pod) {
+			busyNodes[pod.Spec.NodeName] = true
+		}
+	}
+
+	parked := make([]corev1.Node, 0)
+	awake := make([]corev1.Node, 0)
+	for _, node := range nodeList.Items {
+		if _, isParked := node.Annotations[ParkedAnnotation]; isParked {
+			parked = append(parked, node)
+		} else {
+			awake = append(awake, node)
+		}
+	}
+	sort.Slice(parked, func(i, j int) bool { return parked[i].Name < parked[j].Name })
+
+	message := ""
+	if len(unschedulable) > 0 {
+		// one Node at a time, the next reconcile wakes another if Pods are still waiting
+		for i := range parked {
+			if !nodeFitsAnyPod(&parked[i], unschedulable) {
+				continue
+			}
+			if dryRunning(r.DryRun) {
+				logger.Info("Dry run, would wake parked Node for unschedulable Pods", "node", parked[i].Name, "pods", len(unschedulable))
+				break
+			}
+			logger.Info("Waking parked Node for unschedulable Pods", "node", parked[i].Name, "pods", len(unschedulable))
+			err = r.wake(c, powerControl, &parked[i])
+			if err != nil {
+				logger.Error(err, "error waking Node", "node", parked[i].Name)
+				message = err.Error()
+				break
+			}
+			r.recordEvent(powerParking, corev1.EventTypeNormal, NodeWokenReason, fmt.Sprintf("Woke Node %s for %d unschedulable Pods", parked[i].Name, len(unschedulable)))
+			parked = append(parked[:i], parked[i+1:]...)
+			break
+		}
+		// capacity is short, nothing is parked while Pods are waiting
+		return ctrl.Result{RequeueAfter: parkingInterval}, r.updateStatus(c, powerParking, parked, message)
+	}
+
+	idleFor := time.Duration(powerParking.Spec.IdleSeconds) * time.Second
+	awakeCount := len(awake)
+	for i := range awake {
+		node := &awake[i]
+		if node.Spec.Unschedulable || busyNodes[node.Name] || !nodeReady(node) || controlPlaneNode(node) {
+			err = r.setIdleSince(c, node, false)
+			if err != nil {
+				logger.Error(err, "error clearing idle time", "node", node.Name)
+				return ctrl.Result{}, err
+			}
+			continue
+		}
+
+		idleSince, err := time.Parse(time.RFC3339, node.Annotations[IdleSinceAnnotation])
+		if err != nil {
+			err = r.setIdleSince(c, node, true)
+			if err != nil {
+				logger.Error(err, "error recording idle time", "node", node.Name)
+				return ctrl.Result{}, err
+			}
+			continue
+		}
+		if time.Since(idleSince) < idleFor || awakeCount <= powerParking.Spec.MinAwake {
+			continue
+		}
+
+		if dryRunning(r.DryRun) {
+			// the cordon would be dry run too, so the Node would be powered off without being recorded as parked
+			logger.Info("Dry run, would park idle Node", "node", node.Name, "idleSince", idleSince, "powerState", powerParking.Spec.PowerState)
+			continue
+		}
+		logger.Info("Parking idle Node", "node", node.Name, "idleSince", idleSince, "powerState", powerParking.Spec.PowerState)
+		err = r.park(c, powerControl, node, powerParking.Spec.PowerState)
+		if err != nil {
+			logger.Error(err, "error parking Node", "node", node.Name)
+			r.recordEvent(powerParking, corev1.EventTypeWarning, NodeParkFailedReason, fmt.Sprintf("Could not park Node %s: %v", node.Name, err))
+			message = err.Error()
+			continue
+		}
+		r.recordEvent(powerParking, corev1.EventTypeNormal, NodeParkedReason, fmt.Sprintf("Parked Node %s idle since %s", node.Name, idleSince.Format(time.RFC3339)))
+		parked = append(parked, *node)
+		awakeCount--
+	}
+
+	return ctrl.Result{RequeueAfter: parkingInterval}, r.updateStatus(c, powerParking, parked, message)
+}
+
+// powerControl builds the PowerControl the PowerParking asks for, reading the BMC credentials for Redfish
+func (r *PowerParkingReconciler) powerControl(c context.Context, powerParking *powerv1.PowerParking) (parking.PowerControl, error) {
+	if r.PowerControl != nil {
+		return r.PowerControl, nil
+	}
+
+	switch {
+	case powerParking.Spec.Webhook != nil:
+		return &parking.Webhook{URL: powerParking.Spec.Webhook.URL}, nil
+	case powerParking.Spec.Redfish != nil:
+		secret := &corev1.Secret{}
+		err := r.APIReader.Get(c, client.ObjectKey{
+			Name:      powerParking.Spec.Redfish.CredentialsSecret,
+			Namespace: IntelPowerNamespace,
+		}, secret)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving BMC credentials: %w", err)
+		}
+
+		return &parking.Redfish{
+			Username: string(secret.Data["username"]),
+			Password: string(secret.Data["password"]),
+			Insecure: powerParking.Spec.Redfish.InsecureSkipVerify,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("PowerParking has neither redfish nor webhook power control")
+}
+
+// park cordons the Node before powering it down so nothing is scheduled on it in between. Pods scheduled before the
+// cordon took effect keep the Node awake
+func (r *PowerParkingReconciler) park(c context.Context, powerControl parking.PowerControl, node *corev1.Node, powerState string) error {
+	err := r.updateNode(c, node.Name, func(node *corev1.Node) {
+		node.Spec.Unschedulable = true
+		node.Annotations[ParkedAnnotation] = powerState
+		delete(node.Annotations, IdleSinceAnnotation)
+	})
+	if err != nil {
+		return err
+	}
+
+	// the Node is still up, put it back into service
+	uncordon := func() {
+		revertErr := r.updateNode(c, node.Name, func(node *corev1.Node) {
+			node.Spec.Unschedulable = false
+			delete(node.Annotations, ParkedAnnotation)
+		})
+		if revertErr != nil {
+			r.Log.Error(revertErr, "error uncordoning Node after failing to park it", "node", node.Name)
+		}
+	}
+
+	busyPod, err := r.busyPod(c, node.Name)
+	if err == nil && busyPod != nil {
+		err = fmt.Errorf("pod %s/%s was scheduled on the Node while it was being cordoned", busyPod.Namespace, busyPod.Name)
+	}
+	if err != nil {
+		uncordon()
+		return err
+	}
+
+	err = powerControl.Park(c, node, powerState)
+	if err != nil {
+		uncordon()
+		return err
+	}
+
+	return nil
+}
+
+// busyPod returns a Pod keeping the Node awake, nil if there is none
+func (r *PowerParkingReconciler) busyPod(c context.Context, nodeName string) (*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	err := r.Client.List(c, podList)
+	if err != nil {
+		return nil, err
+	}
+	for i := range podList.Items {
+		if podList.Items[i].Spec.NodeName == nodeName && !podAllowsParking(&podList.Items[i]) {
+			return &podList.Items[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// wake powers the Node on and uncordons it, the scheduler holds off until its kubelet reports Ready
+func (r *PowerParkingReconciler) wake(c context.Context, powerControl parking.PowerControl, node *corev1.Node) error {
+	err := powerControl.Wake(c, node)
+	if err != nil {
+		return err
+	}
+
+	return r.updateNode(c, node.Name, func(node *corev1.Node) {
+		node.Spec.Unschedulable = false
+		delete(node.Annotations, ParkedAnnotation)
+	})
+}
+
+func (r *PowerParkingReconciler) setIdleSince(c context.Context, node *corev1.Node, idle bool) error {
+	_, recorded := node.Annotations[IdleSinceAnnotation]
+	if idle == recorded {
+		return nil
+	}
+
+	return r.updateNode(c, node.Name, func(node *corev1.Node) {
+		if idle {
+			node.Annotations[IdleSinceAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		} else {
+			delete(node.Annotations, IdleSinceAnnotation)
+		}
+	})
+}
+
+// updateNode patches only what mutate changes, large Nodes aren't written back whole
+func (r *PowerParkingReconciler) updateNode(c context.Context, nodeName string, mutate func(*corev1.Node)) error {
+	node := &corev1.Node{}
+	err := r.Client.Get(c, client.ObjectKey{Name: nodeName}, node)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	mutate(node)
+
+	return r.Client.Patch(c, node, patch)
+}
+
+func (r *PowerParkingReconciler) updateStatus(c context.Context, powerParking *powerv1.PowerParking, parked []corev1.Node, message string) error {
+	parkedNames := make([]string, 0, len(parked))
+	for _, node := range parked {
+		parkedNames = append(parkedNames, node.Name)
+	}
+	sort.Strings(parkedNames)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &powerv1.PowerParking{}
+		err := r.Client.Get(c, client.ObjectKeyFromObject(powerParking), latest)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if equalStringLists(latest.Status.ParkedNodes, parkedNames) && latest.Status.Message == message {
+			return nil
+		}
+
+		latest.Status.ParkedNodes = parkedNames
+		latest.Status.Message = message
+		return r.Client.Status().Update(c, latest)
+	})
+}
+
+func (r *PowerParkingReconciler) recordEvent(powerParking *powerv1.PowerParking, eventType string, reason string, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(powerParking, eventType, reason, message)
+	}
+}
+
+// podAllowsParking is true for Pods that don't keep a Node awake: finished Pods and DaemonSet Pods. Static Pods,
+// such as etcd and the kube-apiserver, keep their Node awake
+func podAllowsParking(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return true
+	}
+	if _, isMirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; isMirror {
+		return false
+	}
+	owner := metav1.GetControllerOf(pod)
+
+	return owner != nil && owner.Kind == "DaemonSet"
+}
+
+// controlPlaneNode is true for Nodes with the control-plane role, they are never parked
+func controlPlaneNode(node *corev1.Node) bool {
+	_, controlPlane := node.Labels["node-role.kubernetes.io/control-plane"]
+	_, master := node.Labels["node-role.kubernetes.io/master"]
+
+	return controlPlane || master
+}
+
+func podUnschedulable(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == corev1.PodReasonUnschedulable {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeFitsAnyPod checks the Pods' node selectors, a parked Node that none of them could land on is left asleep
+func nodeFitsAnyPod(node *corev1.Node, pods []corev1.Pod) bool {
+	for _, pod := range pods {
+		if labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+func equalStringLists(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *PowerParkingReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.PowerParking{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
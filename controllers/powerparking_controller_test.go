@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/dryrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type powerControlMock struct {
+	mock.Mock
+}
+
+func (m *powerControlMock) Park(ctx context.Context, node *corev1.Node, powerState string) error {
+	return m.Called(node.Name, powerState).Error(0)
+}
+
+func (m *powerControlMock) Wake(ctx context.Context, node *corev1.Node) error {
+	return m.Called(node.Name).Error(0)
+}
+
+func createParkingReconcilerObject(objs []runtime.Object) (*PowerParkingReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &PowerParkingReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s, APIReader: cl}, nil
+}
+
+func parkingNode(name string, annotations map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{"pool": "batch"},
+			Annotations: annotations,
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func TestPowerParkingReconciler(t *testing.T) {
+	longIdle := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	isController := true
+	daemonSetPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "node-agent",
+			Namespace: IntelPowerNamespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "power-node-agent", UID: "1", Controller: &isController},
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-1"},
+	}
+	busyPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-2"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	pendingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "batch-job", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeSelector: map[string]string{"pool": "batch"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable},
+			},
+		},
+	}
+
+	staticPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "etcd-node-1",
+			Namespace:   "kube-system",
+			Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "hash"},
+		},
+		Spec:   corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	controlPlane := parkingNode("node-1", map[string]string{IdleSinceAnnotation: longIdle})
+	controlPlane.Labels["node-role.kubernetes.io/control-plane"] = ""
+
+	tcases := []struct {
+		testCase       string
+		nodes          []*corev1.Node
+		pods           []runtime.Object
+		parkErr        error
+		dryRun         bool
+		expectedParked []string
+		expectedCalls  []string
+		checkNode      func(t *testing.T, cl client.Client)
+		expectedStatus string
+	}{
+		{
+			testCase:       "Test Case 1 - newly idle Node only has its idle time recorded",
+			nodes:          []*corev1.Node{parkingNode("node-1", nil), parkingNode("node-2", nil)},
+			pods:           []runtime.Object{daemonSetPod, busyPod},
+			expectedParked: []string{},
+			checkNode: func(t *testing.T, cl client.Client) {
+				node := &corev1.Node{}
+				assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "node-1"}, node))
+				assert.Contains(t, node.Annotations, IdleSinceAnnotation)
+				assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "node-2"}, node))
+				assert.NotContains(t, node.Annotations, IdleSinceAnnotation)
+			},
+		},
+		{
+			testCase: "Test Case 2 - long idle Node is cordoned and parked",
+			nodes: []*corev1.Node{
+				parkingNode("node-1", map[string]string{IdleSinceAnnotation: longIdle}),
+				parkingNode("node-2", nil),
+			},
+			pods:           []runtime.Object{daemonSetPod, busyPod},
+			expectedParked: []string{"node-1"},
+			expectedCalls:  []string{"Park"},
+			checkNode: func(t *testing.T, cl client.Client) {
+				node := &corev1.Node{}
+				assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "node-1"}, node))
+				assert.True(t, node.Spec.Unschedulable)
+				assert.Equal(t, "off", node.Annotations[ParkedAnnotation])
+			},
+		},
+		{
+			testCase: "Test Case 3 - the last awake Node is never parked",
+			nodes: []*corev1.Node{
+				parkingNode("node-1", map[string]string{IdleSinceAnnotation: longIdle}),
+				parkingNode("node-2", map[string]string{ParkedAnnotation: "off"}),
+			},
+			pods:           []runtime.Object{daemonSetPod},
+			expectedParked: []string{"node-2"},
+		},
+		{
+			testCase: "Test Case 4 - parked Node is woken for unschedulable Pods",
+			nodes: []*corev1.Node{
+				parkingNode("node-1", nil),
+				parkingNode("node-2", map[string]string{ParkedAnnotation: "off"}),
+			},
+			pods:           []runtime.Object{pendingPod},
+			expectedParked: []string{},
+			expectedCalls:  []string{"Wake"},
+			checkNode: func(t *testing.T, cl client.Client) {
+				node := &corev1.Node{}
+				assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "node-2"}, node))
+				assert.False(t, node.Spec.Unschedulable)
+				assert.NotContains(t, node.Annotations, ParkedAnnotation)
+			},
+		},
+		{
+			testCase: "Test Case 5 - Node is put back into service when parking fails",
+			nodes: []*corev1.Node{
+				parkingNode("node-1", map[string]string{IdleSinceAnnotation: longIdle}),
+				parkingNode("node-2", nil),
+			},
+			pods:           []runtime.Object{busyPod},
+			parkErr:        fmt.Errorf("BMC unreachable"),
+			expectedParked: []string{},
+			expectedCalls:  []string{"Park"},
+			expectedStatus: "BMC unreachable",
+			checkNode: func(t *testing.T, cl client.Client) {
+				node := &corev1.Node{}
+				assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "node-1"}, node))
+				assert.False(t, node.Spec.Unschedulable)
+				assert.NotContains(t, node.Annotations, ParkedAnnotation)
+			},
+		},
+		{
+			testCase: "Test Case 6 - dry run never powers an idle Node off",
+			nodes: []*corev1.Node{
+				parkingNode("node-1", map[string]string{IdleSinceAnnotation: longIdle}),
+				parkingNode("node-2", nil),
+			},
+			pods:           []runtime.Object{daemonSetPod, busyPod},
+			dryRun:         true,
+			expectedParked: []string{},
+			checkNode: func(t *testing.T, cl client.Client) {
+				node := &corev1.Node{}
+				assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "node-1"}, node))
+				assert.False(t, node.Spec.Unschedulable)
+				assert.NotContains(t, node.Annotations, ParkedAnnotation)
+			},
+		},
+		{
+			testCase: "Test Case 7 - dry run never powers a parked Node on",
+			nodes: []*corev1.Node{
+				parkingNode("node-1", nil),
+				parkingNode("node-2", map[string]string{ParkedAnnotation: "off"}),
+			},
+			pods:           []runtime.Object{pendingPod},
+			dryRun:         true,
+			expectedParked: []string{"node-2"},
+		},
+		{
+			testCase: "Test Case 8 - static Pods keep a Node awake",
+			nodes: []*corev1.Node{
+				parkingNode("node-1", map[string]string{IdleSinceAnnotation: longIdle}),
+				parkingNode("node-2", nil),
+			},
+			pods:           []runtime.Object{staticPod, busyPod},
+			expectedParked: []string{},
+			checkNode: func(t *testing.T, cl client.Client) {
+				node := &corev1.Node{}
+				assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "node-1"}, node))
+				assert.False(t, node.Spec.Unschedulable)
+				assert.NotContains(t, node.Annotations, IdleSinceAnnotation)
+			},
+		},
+		{
+			testCase:       "Test Case 9 - control plane Nodes are never parked",
+			nodes:          []*corev1.Node{controlPlane, parkingNode("node-2", nil)},
+			pods:           []runtime.Object{busyPod},
+			expectedParked: []string{},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		powerParking := &powerv1.PowerParking{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "batch-nodes",
+				Namespace: IntelPowerNamespace,
+			},
+			Spec: powerv1.PowerParkingSpec{
+				NodeSelector: map[string]string{"pool": "batch"},
+				IdleSeconds:  1800,
+				MinAwake:     1,
+				PowerState:   "off",
+			},
+		}
+		objs := []runtime.Object{powerParking}
+		for _, node := range tc.nodes {
+			objs = append(objs, node)
+		}
+		objs = append(objs, tc.pods...)
+		r, err := createParkingReconcilerObject(objs)
+		assert.NoError(t, err)
+
+		powerControl := new(powerControlMock)
+		powerControl.On("Park", mock.Anything, "off").Return(tc.parkErr)
+		powerControl.On("Wake", mock.Anything).Return(nil)
+		r.PowerControl = powerControl
+		if tc.dryRun {
+			r.DryRun = dryrun.NewClient(r.Client)
+			r.DryRun.SetEnabled(true)
+		}
+
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(powerParking)}
+		result, err := r.Reconcile(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, parkingInterval, result.RequeueAfter)
+
+		assert.Len(t, powerControl.Calls, len(tc.expectedCalls))
+		for i, call := range powerControl.Calls {
+			assert.Equal(t, tc.expectedCalls[i], call.Method)
+		}
+		if tc.checkNode != nil {
+			tc.checkNode(t, r.Client)
+		}
+
+		updated := &powerv1.PowerParking{}
+		assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+		assert.ElementsMatch(t, tc.expectedParked, updated.Status.ParkedNodes)
+		assert.Equal(t, tc.expectedStatus, updated.Status.Message)
+	}
+}
+
+// scheduleOnCordonClient creates the Pod once the Node is patched, as the scheduler could before the cordon is seen
+type scheduleOnCordonClient struct {
+	client.Client
+	pod *corev1.Pod
+}
+
+func (c *scheduleOnCordonClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	if err != nil || c.pod == nil {
+		return err
+	}
+	pod := c.pod
+	c.pod = nil
+
+	return c.Client.Create(ctx, pod)
+}
+
+func TestPowerParkingSafety(t *testing.T) {
+	longIdle := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	busyPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-2"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	powerParking := &powerv1.PowerParking{
+		ObjectMeta: metav1.ObjectMeta{Name: "batch-nodes", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerParkingSpec{
+			NodeSelector: map[string]string{"pool": "batch"},
+			IdleSeconds:  1800,
+			MinAwake:     1,
+			PowerState:   "off",
+		},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(powerParking)}
+
+	// a Pod scheduled while the Node is being cordoned keeps it awake
+	r, err := createParkingReconcilerObject([]runtime.Object{
+		powerParking,
+		parkingNode("node-1", map[string]string{IdleSinceAnnotation: longIdle}),
+		parkingNode("node-2", nil),
+		busyPod,
+	})
+	assert.NoError(t, err)
+	r.Client = &scheduleOnCordonClient{Client: r.Client, pod: &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "late", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}}
+	powerControl := new(powerControlMock)
+	r.PowerControl = powerControl
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	powerControl.AssertNotCalled(t, "Park", mock.Anything, mock.Anything)
+	node := &corev1.Node{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "node-1"}, node))
+	assert.False(t, node.Spec.Unschedulable)
+	assert.NotContains(t, node.Annotations, ParkedAnnotation)
+	updated := &powerv1.PowerParking{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	assert.Equal(t, "pod default/late was scheduled on the Node while it was being cordoned", updated.Status.Message)
+
+	// an empty selector would select every Node, nothing is parked
+	everyNode := powerParking.DeepCopy()
+	everyNode.Spec.NodeSelector = map[string]string{}
+	r, err = createParkingReconcilerObject([]runtime.Object{
+		everyNode,
+		parkingNode("node-1", map[string]string{IdleSinceAnnotation: longIdle}),
+		parkingNode("node-2", nil),
+	})
+	assert.NoError(t, err)
+	powerControl = new(powerControlMock)
+	r.PowerControl = powerControl
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	powerControl.AssertNotCalled(t, "Park", mock.Anything, mock.Anything)
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	assert.Equal(t, "the nodeSelector is empty", updated.Status.Message)
+}
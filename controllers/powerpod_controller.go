@@ -27,13 +27,23 @@ import (
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
 	"github.com/intel/kubernetes-power-manager/pkg/podresourcesclient"
 	"github.com/intel/kubernetes-power-manager/pkg/podstate"
 	"github.com/intel/kubernetes-power-manager/pkg/util"
@@ -44,6 +54,15 @@ const (
 	ResourcePrefix         = "power.intel.com/"
 	CPUResource            = "cpu"
 	PowerNamespace         = "intel-power"
+	// WorkloadTemplateAnnotation names the PowerWorkloadTemplate in the Pod's namespace that gives its
+	// exclusive CPUs a PowerProfile without requesting it as a resource
+	WorkloadTemplateAnnotation = "power.intel.com/workload-template"
+	// BoostAnnotation on a batch/v1 Job names the PowerProfile its Pods' exclusive CPUs get until the Job finishes
+	BoostAnnotation = "power.intel.com/boost"
+	// FastpathAnnotation set to "true" marks the Pod's exclusive CPUs as busy-polling, such as a DPDK data plane's
+	FastpathAnnotation = "power.intel.com/fastpath"
+	// ProfileRequestRejectedReason is the event reason for a container requesting a PowerProfile without exclusive CPUs
+	ProfileRequestRejectedReason = "PowerProfileRequestRejected"
 )
 
 // PowerPodReconciler reconciles a PowerPod object
@@ -51,19 +70,49 @@ type PowerPodReconciler struct {
 	client.Client
 	Log                logr.Logger
 	Scheme             *runtime.Scheme
-	State              podstate.State
+	State              *podstate.State
 	PodResourcesClient podresourcesclient.PodResourcesClient
+	// Cgroups reads the cpusets the Pod's containers really run on, pinning isn't verified without it
+	Cgroups  CgroupReader
+	Recorder record.EventRecorder
+	// Placer chooses which of a container's exclusive CPUs are given its PowerProfile, all of them without one
+	Placer CorePlacer
+}
+
+// CgroupReader reads the CPUs a container's cgroup cpuset lets it run on
+type CgroupReader interface {
+	ContainerCPUs(podUID string, containerID string) ([]uint, error)
+}
+
+// CorePlacer chooses which of the exclusive CPUs the kubelet gave a container join its PowerProfile's PowerWorkload,
+// so placement can be customized without patching the controller, for example keeping the CPUs handling NIC
+// interrupts out of a low power profile. The CPUs it leaves out stay in the Shared pool, CPUs the container wasn't
+// given are ignored
+type CorePlacer interface {
+	Place(ctx context.Context, pod *corev1.Pod, container string, profile string, cpus []uint) ([]uint, error)
+}
+
+// ExclusiveCorePlacer gives the PowerProfile to all of the container's exclusive CPUs, the placement used without a
+// CorePlacer
+type ExclusiveCorePlacer struct{}
+
+func (ExclusiveCorePlacer) Place(ctx context.Context, pod *corev1.Pod, container string, profile string, cpus []uint) ([]uint, error) {
+	return cpus, nil
 }
 
 // +kubebuilder:rbac:groups=power.intel.com,resources=powerpods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=power.intel.com,resources=powerpods/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloadtemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *PowerPodReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
 	logger := r.Log.WithValues("powerpod", req.NamespacedName)
+	changes := logging.NewChangeSummary()
+	defer changes.Log(logger)
 	pod := &corev1.Pod{}
 	logger.V(5).Info("Retrieving pod instance")
-	err := r.Get(context.TODO(), req.NamespacedName, pod)
+	err := r.Get(c, req.NamespacedName, pod)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Delete the Pod from the internal state in case it was never deleted
@@ -71,7 +120,6 @@ func (r *PowerPodReconciler) Reconcile(c context.Context, req ctrl.Request) (ctr
 			if err != nil {
 				return ctrl.Result{}, err
 			}
-
 			return ctrl.Result{}, nil
 		}
 
@@ -90,10 +138,12 @@ func (r *PowerPodReconciler) Reconcile(c context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, nil
 	}
 
-	if !pod.ObjectMeta.DeletionTimestamp.IsZero() || pod.Status.Phase == corev1.PodSucceeded {
+	if !pod.ObjectMeta.DeletionTimestamp.IsZero() || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
 		// If the Pod's DeletionTimestamp is not zero then the Pod has been deleted
 
 		powerPodState := r.State.GetPodFromState(pod.GetName())
+		// a Pod recreated under the same name will be given other CPUs
+		r.PodResourcesClient.Invalidate(pod.GetName())
 
 		logger.V(5).Info("Removing Pod from internal state")
 		err = r.State.DeletePodFromState(pod.GetName())
@@ -116,31 +166,25 @@ func (r *PowerPodReconciler) Reconcile(c context.Context, req ctrl.Request) (ctr
 		}
 
 		for workloadName, cpus := range workloadToCPUsRemoved {
-			logger.V(5).Info("Retrieving workload instance %s", workloadName)
-			workload := &powerv1.PowerWorkload{}
-			err = r.Get(context.TODO(), client.ObjectKey{
-				Namespace: IntelPowerNamespace,
-				Name:      workloadName,
-			}, workload)
+			logger.V(5).Info("Updating CPUs workload list with their CPUIDs and container informantion ", "workload", workloadName)
+			err = r.patchWorkloadIfChanged(c, workloadName, func(workload *powerv1.PowerWorkload) {
+				workload.Spec.Node.CpuIds = getNewWorkloadCPUList(cpus, workload.Spec.Node.CpuIds, &logger)
+				workload.Spec.Node.Containers = getNewWorkloadContainerList(workload.Spec.Node.Containers, powerPodState.Containers, &logger)
+			}, &logger, changes)
 			if err != nil {
 				if errors.IsNotFound(err) {
 					return ctrl.Result{}, nil
 				}
-				logger.Error(err, "error while trying to retrieve PowerWorkload")
-				return ctrl.Result{}, err
-			}
-
-			logger.V(5).Info("Updating CPUs workload list with their CPUIDs and container informantion ")
-			updatedWorkloadCPUList := getNewWorkloadCPUList(cpus, workload.Spec.Node.CpuIds, &logger)
-			workload.Spec.Node.CpuIds = updatedWorkloadCPUList
-			updatedWorkloadContainerList := getNewWorkloadContainerList(workload.Spec.Node.Containers, powerPodState.Containers, &logger)
-			workload.Spec.Node.Containers = updatedWorkloadContainerList
-
-			err = r.Client.Update(context.TODO(), workload)
-			if err != nil {
 				logger.Error(err, "Failed updating PowerWorkload")
 				return ctrl.Result{}, err
 			}
+			if r.Cgroups != nil {
+				err = r.setPinningMismatches(c, workloadName, pod.GetName(), nil)
+				if err != nil {
+					logger.Error(err, "error clearing the Pod's pinning mismatches")
+					return ctrl.Result{}, err
+				}
+			}
 		}
 
 		return ctrl.Result{}, nil
@@ -155,10 +199,29 @@ func (r *PowerPodReconciler) Reconcile(c context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, podNotRunningErr
 	}
 
+	underMaintenance, err := nodeUnderMaintenance(c, r.Client, nodeName)
+	if err != nil {
+		logger.Error(err, "error checking if the Node is under maintenance")
+		return ctrl.Result{}, err
+	}
+	if underMaintenance {
+		logger.Info("Node is under maintenance, Pod will be added to its PowerWorkloads once it ends")
+		return ctrl.Result{RequeueAfter: maintenanceRequeueInterval}, nil
+	}
+	excluded, err := nodeExcluded(c, r.Client, nodeName)
+	if err != nil {
+		logger.Error(err, "error checking if the Node is excluded")
+		return ctrl.Result{}, err
+	}
+	if excluded {
+		logger.V(5).Info("Node is excluded from power management, Pod will be added to its PowerWorkloads once it's included")
+		return ctrl.Result{RequeueAfter: maintenanceRequeueInterval}, nil
+	}
+
 	// Get customDevices that need to be considered in the pod
 	logger.V(5).Info("Retrivieng custom resources from PowerNode")
 	powernode := &powerv1.PowerNode{}
-	err = r.Get(context.TODO(), client.ObjectKey{
+	err = r.Get(c, client.ObjectKey{
 		Namespace: IntelPowerNamespace,
 		Name:      nodeName,
 	}, powernode)
@@ -173,6 +236,7 @@ func (r *PowerPodReconciler) Reconcile(c context.Context, req ctrl.Request) (ctr
 	// Get the Containers of the Pod that are requesting exclusive CPUs
 	logger.V(5).Info("Retrieving the containers requested for the exclusive CPUs or this/these Custom Devices", "Custom Devices", powernode.Spec.CustomDevices)
 	admissibleContainers := getAdmissibleContainers(pod, &logger, powernode.Spec.CustomDevices)
+	r.rejectSharedProfileRequests(pod, admissibleContainers, &logger)
 	if len(admissibleContainers) == 0 {
 		logger.Info("No containers are requesting exclusive CPUs or Custom Resources")
 		return ctrl.Result{}, nil
@@ -184,71 +248,87 @@ func (r *PowerPodReconciler) Reconcile(c context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, errors.NewServiceUnavailable("pod UID not found")
 	}
 
+	template, err := r.podWorkloadTemplate(c, pod, nodeName, &logger)
+	if err != nil {
+		logger.Error(err, "error retrieving the Pod's PowerWorkloadTemplate")
+		return ctrl.Result{}, err
+	}
+	if template == nil {
+		template, err = r.jobBoostTemplate(c, pod, &logger)
+		if err != nil {
+			logger.Error(err, "error retrieving the Pod's Job")
+			return ctrl.Result{}, err
+		}
+	}
+	if template == nil {
+		template, err = r.podSelectorTemplate(c, pod, &logger)
+		if err != nil {
+			logger.Error(err, "error retrieving the PowerWorkloads selecting the Pod")
+			return ctrl.Result{}, err
+		}
+	}
+
 	powerProfileCRs := &powerv1.PowerProfileList{}
 	logger.V(5).Info("Retrieving Power Profiles from the Cluster")
-	err = r.Client.List(context.TODO(), powerProfileCRs)
+	err = r.Client.List(c, powerProfileCRs)
 	if err != nil {
 		logger.Error(err, "Error retrieving Power Profiles from Cluster")
 		return ctrl.Result{}, nil
 	}
-	powerProfilesFromContainers, powerContainers, err := r.getPowerProfileRequestsFromContainers(admissibleContainers, powerProfileCRs.Items, pod, &logger, powernode.Spec.CustomDevices)
+	powerProfilesFromContainers, powerContainers, err := r.getPowerProfileRequestsFromContainers(c, admissibleContainers, powerProfileCRs.Items, pod, &logger, powernode.Spec.CustomDevices, template)
 	logger.V(5).Info("Retrieving Power Profiles and cores from Pods requests")
 	if err != nil {
 		logger.Error(err, "Error retrieving Power Profile from Pod requests")
 		return ctrl.Result{}, err
 	}
 	for profile, cores := range powerProfilesFromContainers {
-		logger.V(5).Info("Retrieving workload for Power Profile")
 		workloadName := fmt.Sprintf("%s-%s", profile, nodeName)
-		workload := &powerv1.PowerWorkload{}
-		err = r.Client.Get(context.TODO(), client.ObjectKey{
-			Namespace: PowerNamespace,
-			Name:      workloadName,
-		}, workload)
-		if err != nil {
-			if errors.IsNotFound(err) {
-				logger.Error(err, "no Power Workload exists for this Profile")
-			} else {
-				logger.Error(err, fmt.Sprintf("Error retrieving PowerWorkload '%s'", profile))
-			}
-
-			continue
-		}
-
-		// PowerWorkload already exists so need to update it. If the Node already
-		// exists in the Workload, we update the Node's CPU list, if not we create
-		// the entry for the node
-
-		workload.Spec.Node.CpuIds = appendIfUnique(workload.Spec.Node.CpuIds, cores, &logger)
-		sort.Slice(workload.Spec.Node.CpuIds, func(i, j int) bool { return workload.Spec.Node.CpuIds[i] < workload.Spec.Node.CpuIds[j] })
-
-		containerList := make([]powerv1.Container, 0)
+		podContainers := make([]powerv1.Container, 0)
 		for i, container := range powerContainers {
 			logger.V(5).Info("Updating the Power Container list")
 			powerContainers[i].Workload = workloadName
 
 			workloadContainer := container
 			workloadContainer.Pod = pod.Name
-			containerList = append(containerList, workloadContainer)
-		}
-		for i, newContainer := range containerList {
-			logger.V(5).Info("Confirming that Containers are not duplicated")
-			for _, oldContainer := range workload.Spec.Node.Containers {
-				if newContainer.Name == oldContainer.Name && reflect.DeepEqual(newContainer.ExclusiveCPUs, oldContainer.ExclusiveCPUs) {
-					containerList[i] = containerList[len(containerList)-1]
-					containerList = containerList[:len(containerList)-1]
+			podContainers = append(podContainers, workloadContainer)
+		}
+
+		// PowerWorkload already exists so need to update it. If the Node already
+		// exists in the Workload, we update the Node's CPU list, if not we create
+		// the entry for the node
+		logger.V(5).Info("Ammending the workload in the container list", "workload", workloadName)
+		err = r.patchWorkloadIfChanged(c, workloadName, func(workload *powerv1.PowerWorkload) {
+			workload.Spec.Node.CpuIds = appendIfUnique(workload.Spec.Node.CpuIds, cores, &logger)
+			sort.Slice(workload.Spec.Node.CpuIds, func(i, j int) bool { return workload.Spec.Node.CpuIds[i] < workload.Spec.Node.CpuIds[j] })
+
+			containerList := append([]powerv1.Container{}, podContainers...)
+			for i, newContainer := range containerList {
+				logger.V(5).Info("Confirming that Containers are not duplicated")
+				for _, oldContainer := range workload.Spec.Node.Containers {
+					if newContainer.Name == oldContainer.Name && reflect.DeepEqual(newContainer.ExclusiveCPUs, oldContainer.ExclusiveCPUs) {
+						containerList[i] = containerList[len(containerList)-1]
+						containerList = containerList[:len(containerList)-1]
+					}
 				}
 			}
-		}
-		workload.Spec.Node.Containers = append(workload.Spec.Node.Containers, containerList...)
-		err = r.Client.Update(context.TODO(), workload)
-		logger.V(5).Info("Ammending the workload in the container list")
+			workload.Spec.Node.Containers = append(workload.Spec.Node.Containers, containerList...)
+		}, &logger, changes)
 		if err != nil {
+			if errors.IsNotFound(err) {
+				logger.Error(err, "no Power Workload exists for this Profile")
+				continue
+			}
 			logger.Error(err, "error while trying to update PowerWorkload")
 			return ctrl.Result{}, err
 		}
 	}
 
+	err = r.verifyPinning(c, pod, powerContainers, &logger)
+	if err != nil {
+		logger.Error(err, "error recording the Pod's pinning mismatches")
+		return ctrl.Result{}, err
+	}
+
 	// Finally, update the controller's State
 
 	logger.V(5).Info("Updating the Controller's internal State")
@@ -267,10 +347,252 @@ func (r *PowerPodReconciler) Reconcile(c context.Context, req ctrl.Request) (ctr
 	return ctrl.Result{}, nil
 }
 
-func (r *PowerPodReconciler) getPowerProfileRequestsFromContainers(containers []corev1.Container, profileCRs []powerv1.PowerProfile, pod *corev1.Pod, logger *logr.Logger, CustomDevices []string) (map[string][]uint, []powerv1.Container, error) {
+// patchWorkloadIfChanged applies mutate to the PowerWorkload and sends only the difference to the API server,
+// skipping the request altogether when the Pod didn't change the Workload. A merge patch replaces whole lists, such
+// as the CPUs and containers, so the patch is locked to the resourceVersion it was made from and mutate is applied
+// to the Workload read again when another Pod changed it in between
+func (r *PowerPodReconciler) patchWorkloadIfChanged(c context.Context, workloadName string, mutate func(*powerv1.PowerWorkload), logger *logr.Logger, changes *logging.ChangeSummary) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		workload := &powerv1.PowerWorkload{}
+		err := r.Client.Get(c, client.ObjectKey{Name: workloadName, Namespace: IntelPowerNamespace}, workload)
+		if err != nil {
+			return err
+		}
+		original := workload.DeepCopy()
+		mutate(workload)
+		if reflect.DeepEqual(original.Spec, workload.Spec) {
+			logger.V(5).Info("PowerWorkload unchanged, skipping update", "workload", workloadName)
+			return nil
+		}
+
+		logger.V(5).Info("Patching PowerWorkload", "workload", workloadName,
+			"added", detectCoresAdded(original.Spec.Node.CpuIds, workload.Spec.Node.CpuIds, logger),
+			"removed", detectCoresRemoved(original.Spec.Node.CpuIds, workload.Spec.Node.CpuIds, logger))
+		err = r.Client.Patch(c, workload, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+		if err != nil {
+			return err
+		}
+		changes.ResourceUpdated("PowerWorkload", workloadName)
+
+		return nil
+	})
+}
+
+// verifyPinning compares the CPUs each container is given the PowerProfile on with its cgroup cpuset, recording
+// those that differ in the PowerWorkload's status. The CPU Manager can move a container to other cores, and the
+// PowerProfile would then silently be applied to cores the container doesn't run on
+func (r *PowerPodReconciler) verifyPinning(c context.Context, pod *corev1.Pod, containers []powerv1.Container, logger *logr.Logger) error {
+	if r.Cgroups == nil {
+		return nil
+	}
+
+	mismatches := make(map[string][]powerv1.PinningMismatch)
+	for _, container := range containers {
+		if container.Workload == "" {
+			continue
+		}
+		if _, exists := mismatches[container.Workload]; !exists {
+			mismatches[container.Workload] = make([]powerv1.PinningMismatch, 0)
+		}
+		cpus, err := r.Cgroups.ContainerCPUs(string(pod.GetUID()), container.Id)
+		if err != nil {
+			logger.Info("unable to read the container's cgroup cpuset, pinning not verified", "container", container.Name, "error", err.Error())
+			continue
+		}
+		expected := cpusetOf(container.ExclusiveCPUs)
+		actual := cpusetOf(cpus)
+		if expected.Equals(actual) {
+			continue
+		}
+		logger.Info("container isn't pinned to the CPUs given its PowerProfile", "container", container.Name,
+			"expected", expected.String(), "actual", actual.String())
+		mismatches[container.Workload] = append(mismatches[container.Workload], powerv1.PinningMismatch{
+			Pod:       pod.GetName(),
+			Container: container.Name,
+			Expected:  expected.String(),
+			Actual:    actual.String(),
+		})
+	}
+
+	for workloadName, podMismatches := range mismatches {
+		err := r.setPinningMismatches(c, workloadName, pod.GetName(), podMismatches)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setPinningMismatches replaces the Pod's entries in the PowerWorkload's pinning mismatches
+func (r *PowerPodReconciler) setPinningMismatches(c context.Context, workloadName string, podName string, podMismatches []powerv1.PinningMismatch) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		workload := &powerv1.PowerWorkload{}
+		err := r.Client.Get(c, client.ObjectKey{Name: workloadName, Namespace: IntelPowerNamespace}, workload)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		updated := make([]powerv1.PinningMismatch, 0, len(workload.Status.PinningMismatches)+len(podMismatches))
+		for _, mismatch := range workload.Status.PinningMismatches {
+			if mismatch.Pod != podName {
+				updated = append(updated, mismatch)
+			}
+		}
+		updated = append(updated, podMismatches...)
+		if len(updated) == 0 {
+			if len(workload.Status.PinningMismatches) == 0 {
+				return nil
+			}
+			updated = nil
+		} else if reflect.DeepEqual(updated, workload.Status.PinningMismatches) {
+			return nil
+		}
+
+		workload.Status.PinningMismatches = updated
+		return r.Client.Status().Update(c, workload)
+	})
+}
+
+func cpusetOf(cpus []uint) cpuset.CPUSet {
+	ids := make([]int, 0, len(cpus))
+	for _, cpu := range cpus {
+		ids = append(ids, int(cpu))
+	}
+
+	return cpuset.NewCPUSet(ids...)
+}
+
+// podWorkloadTemplate returns the PowerWorkloadTemplate the Pod is annotated with, or nil if it has none or the
+// template doesn't apply to this Node
+func (r *PowerPodReconciler) podWorkloadTemplate(c context.Context, pod *corev1.Pod, nodeName string, logger *logr.Logger) (*powerv1.PowerWorkloadTemplateSpec, error) {
+	templateName := pod.GetAnnotations()[WorkloadTemplateAnnotation]
+	if templateName == "" {
+		return nil, nil
+	}
+
+	template := &powerv1.PowerWorkloadTemplate{}
+	err := r.Client.Get(c, client.ObjectKey{Name: templateName, Namespace: pod.Namespace}, template)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("PowerWorkloadTemplate not found, the Pod runs without it", "template", templateName)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if len(template.Spec.NodeSelector) > 0 {
+		node := &corev1.Node{}
+		err = r.Client.Get(c, client.ObjectKey{Name: nodeName}, node)
+		if err != nil {
+			return nil, err
+		}
+		if !labels.SelectorFromSet(template.Spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+			logger.V(5).Info("PowerWorkloadTemplate doesn't apply to this Node", "template", templateName)
+			return nil, nil
+		}
+	}
+
+	return &template.Spec, nil
+}
+
+// jobBoostTemplate gives the exclusive CPUs of a Pod owned by a boosted Job the Job's PowerProfile while the Job
+// is running. The CPUs go back to the Shared pool when the Pod finishes or is deleted, like any other Pod's
+func (r *PowerPodReconciler) jobBoostTemplate(c context.Context, pod *corev1.Pod, logger *logr.Logger) (*powerv1.PowerWorkloadTemplateSpec, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "Job" || owner.APIVersion != batchv1.SchemeGroupVersion.String() {
+		return nil, nil
+	}
+
+	job := &batchv1.Job{}
+	err := r.Client.Get(c, client.ObjectKey{Name: owner.Name, Namespace: pod.Namespace}, job)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	profile := job.GetAnnotations()[BoostAnnotation]
+	if profile == "" {
+		return nil, nil
+	}
+	if jobFinished(job) {
+		logger.V(5).Info("Job has finished, its Pods are no longer boosted", "job", job.Name)
+		return nil, nil
+	}
+
+	return &powerv1.PowerWorkloadTemplateSpec{PowerProfile: profile}, nil
+}
+
+// podSelectorTemplate gives the Pod's exclusive CPUs the PowerProfile of the first PowerWorkload, by name, whose
+// podSelector selects it, limited to the containers the selector names
+func (r *PowerPodReconciler) podSelectorTemplate(c context.Context, pod *corev1.Pod, logger *logr.Logger) (*powerv1.PowerWorkloadTemplateSpec, error) {
+	workloads := &powerv1.PowerWorkloadList{}
+	err := r.Client.List(c, workloads, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(workloads.Items, func(i, j int) bool { return workloads.Items[i].Name < workloads.Items[j].Name })
+
+	for _, workload := range workloads.Items {
+		if !podSelected(workload.Spec.PodSelector, pod) {
+			continue
+		}
+		logger.V(5).Info("Pod selected by PowerWorkload", "workload", workload.Name, "containers", workload.Spec.PodSelector.Containers)
+		return &powerv1.PowerWorkloadTemplateSpec{
+			PowerProfile: workload.Spec.PowerProfile,
+			Containers:   workload.Spec.PodSelector.Containers,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// podSelected reports whether the selector selects the Pod, a nil selector selects nothing
+func podSelected(selector *powerv1.WorkloadPodSelector, pod *corev1.Pod) bool {
+	if selector == nil {
+		return false
+	}
+	if selector.Namespace != "" && selector.Namespace != pod.Namespace {
+		return false
+	}
+
+	return labels.SelectorFromSet(selector.MatchLabels).Matches(labels.Set(pod.Labels))
+}
+
+func jobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// templateProfile is the PowerProfile a PowerWorkloadTemplate gives the container, empty if it gives none
+func templateProfile(template *powerv1.PowerWorkloadTemplateSpec, containerName string) string {
+	if template == nil {
+		return ""
+	}
+	if len(template.Containers) == 0 {
+		return template.PowerProfile
+	}
+	for _, name := range template.Containers {
+		if name == containerName {
+			return template.PowerProfile
+		}
+	}
+
+	return ""
+}
+
+func (r *PowerPodReconciler) getPowerProfileRequestsFromContainers(c context.Context, containers []corev1.Container, profileCRs []powerv1.PowerProfile, pod *corev1.Pod, logger *logr.Logger, CustomDevices []string, template *powerv1.PowerWorkloadTemplateSpec) (map[string][]uint, []powerv1.Container, error) {
 
 	logger.V(5).Info("Get PowerProfiles from containers")
-	_ = context.Background()
 
 	profiles := make(map[string][]uint)
 	powerContainers := make([]powerv1.Container, 0)
@@ -282,6 +604,11 @@ func (r *PowerPodReconciler) getPowerProfileRequestsFromContainers(containers []
 			return map[string][]uint{}, []powerv1.Container{}, err
 		}
 
+		// A PowerProfile requested as a resource takes precedence over the Pod's template
+		if profile == "" {
+			profile = templateProfile(template, container.Name)
+		}
+
 		// If there was no Profile requested in this container we can move onto the next one
 		if profile == "" {
 			logger.V(5).Info("No Profile was requested by the Container")
@@ -299,6 +626,10 @@ func (r *PowerPodReconciler) getPowerProfileRequestsFromContainers(containers []
 			return map[string][]uint{}, []powerv1.Container{}, err
 		}
 		cleanCoreList := getCleanCoreList(coreIDs)
+		placedCores, err := r.placeCores(c, pod, container.Name, profile, cleanCoreList)
+		if err != nil {
+			return map[string][]uint{}, []powerv1.Container{}, err
+		}
 
 		logger.V(5).Info("Creating Power Container")
 		powerContainer := &powerv1.Container{}
@@ -306,12 +637,13 @@ func (r *PowerPodReconciler) getPowerProfileRequestsFromContainers(containers []
 		powerContainer.Id = strings.TrimPrefix(containerID, "docker://")
 		powerContainer.ExclusiveCPUs = cleanCoreList
 		powerContainer.PowerProfile = profile
+		powerContainer.Fastpath = pod.GetAnnotations()[FastpathAnnotation] == "true"
 		powerContainers = append(powerContainers, *powerContainer)
 
 		if _, exists := profiles[profile]; exists {
-			profiles[profile] = append(profiles[profile], cleanCoreList...)
+			profiles[profile] = append(profiles[profile], placedCores...)
 		} else {
-			profiles[profile] = cleanCoreList
+			profiles[profile] = placedCores
 		}
 	}
 
@@ -324,6 +656,27 @@ func (r *PowerPodReconciler) getPowerProfileRequestsFromContainers(containers []
 	return profiles, powerContainers, nil
 }
 
+// placeCores returns the container's exclusive CPUs the Placer gives the PowerProfile, dropping any it returns that
+// the container wasn't given so a placer can never tune another container's CPUs
+func (r *PowerPodReconciler) placeCores(c context.Context, pod *corev1.Pod, container string, profile string, cpus []uint) ([]uint, error) {
+	var placer CorePlacer = ExclusiveCorePlacer{}
+	if r.Placer != nil {
+		placer = r.Placer
+	}
+	placed, err := placer.Place(c, pod, container, profile, append([]uint{}, cpus...))
+	if err != nil {
+		return nil, fmt.Errorf("placing the CPUs of container %s: %w", container, err)
+	}
+
+	cores := make([]uint, 0, len(placed))
+	for _, cpu := range placed {
+		if util.CPUInCPUList(cpu, cpus) && !util.CPUInCPUList(cpu, cores) {
+			cores = append(cores, cpu)
+		}
+	}
+	return cores, nil
+}
+
 func profileExists(profile string, powerProfiles []powerv1.PowerProfile, logger *logr.Logger) bool {
 	logger.V(5).Info("Confirming the Power Profile exists in Cluster")
 	for _, powerProfile := range powerProfiles {
@@ -436,6 +789,32 @@ func getContainerProfileFromRequests(container corev1.Container, logger *logr.Lo
 	return profileName, nil
 }
 
+// rejectSharedProfileRequests records an event for each container requesting a PowerProfile without exclusive
+// CPUs. Such a container runs on the kubelet's shared CPUs, which the Node Agent can't pin it to, so it's scheduled
+// against the profile's capacity but never given the profile
+func (r *PowerPodReconciler) rejectSharedProfileRequests(pod *corev1.Pod, admissibleContainers []corev1.Container, logger *logr.Logger) {
+	admissible := make(map[string]bool, len(admissibleContainers))
+	for _, container := range admissibleContainers {
+		admissible[container.Name] = true
+	}
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if admissible[container.Name] {
+			continue
+		}
+		for resource := range container.Resources.Requests {
+			if !strings.HasPrefix(string(resource), ResourcePrefix) {
+				continue
+			}
+			logger.Info("Container requests a PowerProfile without exclusive CPUs, it won't be given the profile", "container", container.Name, "resource", resource)
+			if r.Recorder != nil {
+				r.Recorder.Event(pod, corev1.EventTypeWarning, ProfileRequestRejectedReason,
+					fmt.Sprintf("Container %s requests %s without exclusive CPUs, only containers of Guaranteed Pods with whole CPUs are given a PowerProfile", container.Name, resource))
+			}
+			break
+		}
+	}
+}
+
 func getAdmissibleContainers(pod *corev1.Pod, logger *logr.Logger, CustomDevices []string) []corev1.Container {
 
 	logger.V(5).Info("Receiving Containers requesting Exclusive CPUs or Custom Devices")
@@ -516,5 +895,32 @@ func getCleanCoreList(coreIDs string) []uint {
 func (r *PowerPodReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Pod{}).
+		Watches(&source.Kind{Type: &powerv1.PowerWorkload{}}, handler.EnqueueRequestsFromMapFunc(r.selectedPodRequests)).
 		Complete(r)
 }
+
+// selectedPodRequests reconciles the Pods on this Node that a PowerWorkload's podSelector selects, so Pods
+// started before the PowerWorkload was created get its PowerProfile too
+func (r *PowerPodReconciler) selectedPodRequests(obj client.Object) []reconcile.Request {
+	workload, ok := obj.(*powerv1.PowerWorkload)
+	if !ok || workload.Spec.PodSelector == nil {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	err := r.Client.List(context.TODO(), pods, client.MatchingLabels(workload.Spec.PodSelector.MatchLabels))
+	if err != nil {
+		r.Log.Error(err, "error listing Pods selected by PowerWorkload", "workload", workload.Name)
+		return nil
+	}
+	nodeName := os.Getenv("NODE_NAME")
+	requests := make([]reconcile.Request, 0)
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != nodeName || !podSelected(workload.Spec.PodSelector, &pod) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pod)})
+	}
+
+	return requests
+}
@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,15 +26,18 @@ import (
 	"github.com/intel/kubernetes-power-manager/pkg/podstate"
 
 	//"github.com/stretchr/testify/mock"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type fakePodResourcesClient struct {
 	listResponse *podresourcesapi.ListPodResourcesResponse
+	lists        int
 }
 
 func (f *fakePodResourcesClient) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	f.lists++
 	return f.listResponse, nil
 }
 
@@ -67,7 +73,7 @@ func createPodReconcilerObject(objs []runtime.Object, podResourcesClient *podres
 	}
 
 	// Create a ReconcileNode object with the scheme and fake client.
-	r := &PowerPodReconciler{cl, ctrl.Log.WithName("testing"), s, *state, *podResourcesClient}
+	r := &PowerPodReconciler{cl, ctrl.Log.WithName("testing"), s, state, *podResourcesClient, nil, nil, nil}
 
 	return r, nil
 }
@@ -1297,3 +1303,934 @@ func TestPodDeletion(t *testing.T) {
 		}
 	}
 }
+
+func TestPatchWorkloadIfChanged(t *testing.T) {
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance-TestNode",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerWorkloadSpec{
+			Name:         "performance-TestNode",
+			PowerProfile: "performance",
+			Node: powerv1.WorkloadNode{
+				Name:   "TestNode",
+				CpuIds: []uint{1, 2},
+			},
+		},
+	}
+
+	r, err := createPodReconcilerObject([]runtime.Object{workload}, createFakePodResourcesListerClient(nil))
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	logger := r.Log
+
+	current := &powerv1.PowerWorkload{}
+	err = r.Get(context.TODO(), client.ObjectKeyFromObject(workload), current)
+	if err != nil {
+		t.Fatalf("error retrieving PowerWorkload: %v", err)
+	}
+	resourceVersion := current.ResourceVersion
+
+	// nothing changed so no request should be made
+	err = r.patchWorkloadIfChanged(context.TODO(), workload.Name, func(*powerv1.PowerWorkload) {}, &logger, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = r.Get(context.TODO(), client.ObjectKeyFromObject(workload), current)
+	if err != nil {
+		t.Fatalf("error retrieving PowerWorkload: %v", err)
+	}
+	if current.ResourceVersion != resourceVersion {
+		t.Errorf("expected unchanged PowerWorkload not to be written")
+	}
+
+	// adding a single core only patches the node's CPU list
+	err = r.patchWorkloadIfChanged(context.TODO(), workload.Name, func(workload *powerv1.PowerWorkload) {
+		workload.Spec.Node.CpuIds = append(workload.Spec.Node.CpuIds, 3)
+	}, &logger, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = r.Get(context.TODO(), client.ObjectKeyFromObject(workload), current)
+	if err != nil {
+		t.Fatalf("error retrieving PowerWorkload: %v", err)
+	}
+	if !reflect.DeepEqual(current.Spec.Node.CpuIds, []uint{1, 2, 3}) {
+		t.Errorf("expected CPU list %v, got %v", []uint{1, 2, 3}, current.Spec.Node.CpuIds)
+	}
+	if current.Spec.PowerProfile != "performance" {
+		t.Errorf("expected the rest of the PowerWorkload to be untouched")
+	}
+}
+
+// patchBetweenClient runs between once, after the PowerWorkload was read and before the first patch is sent
+type patchBetweenClient struct {
+	client.Client
+	between func()
+}
+
+func (c *patchBetweenClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if between := c.between; between != nil {
+		c.between = nil
+		between()
+	}
+
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func TestPatchWorkloadConcurrentPods(t *testing.T) {
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance-TestNode",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerWorkloadSpec{
+			Name:         "performance-TestNode",
+			PowerProfile: "performance",
+			Node: powerv1.WorkloadNode{
+				Name:       "TestNode",
+				CpuIds:     []uint{1},
+				Containers: []powerv1.Container{{Name: "first", Pod: "first-pod", ExclusiveCPUs: []uint{1}}},
+			},
+		},
+	}
+	r, err := createPodReconcilerObject([]runtime.Object{workload}, createFakePodResourcesListerClient(nil))
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	logger := r.Log
+	addPod := func(pod string, cpu uint) func(*powerv1.PowerWorkload) {
+		return func(workload *powerv1.PowerWorkload) {
+			workload.Spec.Node.CpuIds = append(workload.Spec.Node.CpuIds, cpu)
+			workload.Spec.Node.Containers = append(workload.Spec.Node.Containers,
+				powerv1.Container{Name: pod, Pod: pod + "-pod", ExclusiveCPUs: []uint{cpu}})
+		}
+	}
+
+	// the second Pod's change lands after the first Pod's reconcile read the PowerWorkload
+	plain := r.Client
+	r.Client = &patchBetweenClient{Client: plain, between: func() {
+		second := &PowerPodReconciler{Client: plain, Log: r.Log}
+		if err := second.patchWorkloadIfChanged(context.TODO(), workload.Name, addPod("third", 3), &logger, nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}}
+	err = r.patchWorkloadIfChanged(context.TODO(), workload.Name, addPod("second", 2), &logger, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	current := &powerv1.PowerWorkload{}
+	err = plain.Get(context.TODO(), client.ObjectKeyFromObject(workload), current)
+	if err != nil {
+		t.Fatalf("error retrieving PowerWorkload: %v", err)
+	}
+	if !reflect.DeepEqual(current.Spec.Node.CpuIds, []uint{1, 3, 2}) {
+		t.Errorf("expected both Pods' CPUs %v, got %v", []uint{1, 3, 2}, current.Spec.Node.CpuIds)
+	}
+	if len(current.Spec.Node.Containers) != 3 {
+		t.Errorf("expected both Pods' containers, got %v", current.Spec.Node.Containers)
+	}
+}
+
+func TestPodWorkloadTemplate(t *testing.T) {
+	tcases := []struct {
+		testCase       string
+		nodeLabels     map[string]string
+		expectedCpuIds []uint
+	}{
+		{
+			testCase:       "Test Case 1 - template gives the listed container its PowerProfile",
+			nodeLabels:     map[string]string{"power": "true"},
+			expectedCpuIds: []uint{1, 2},
+		},
+		{
+			testCase:       "Test Case 2 - template doesn't apply to the Node",
+			nodeLabels:     map[string]string{},
+			expectedCpuIds: []uint{},
+		},
+	}
+
+	container := func(name string) corev1.Container {
+		resources := map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceCPU:    *resource.NewQuantity(2, resource.DecimalSI),
+			corev1.ResourceMemory: *resource.NewQuantity(200, resource.DecimalSI),
+		}
+		return corev1.Container{
+			Name:      name,
+			Resources: corev1.ResourceRequirements{Limits: resources, Requests: resources},
+		}
+	}
+	podResources := []*podresourcesapi.PodResources{
+		{
+			Name:      "templated-pod",
+			Namespace: "app",
+			Containers: []*podresourcesapi.ContainerResources{
+				{Name: "app", CpuIds: []int64{1, 2}},
+				{Name: "sidecar", CpuIds: []int64{3, 4}},
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Setenv("NODE_NAME", "TestNode")
+		clientObjs := []runtime.Object{
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "TestNode",
+					Labels: tc.nodeLabels,
+				},
+			},
+			&powerv1.PowerNode{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+			},
+			&powerv1.PowerProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerProfileSpec{
+					Name: "performance",
+				},
+			},
+			&powerv1.PowerWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance-TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerWorkloadSpec{
+					Name: "performance-TestNode",
+					Node: powerv1.WorkloadNode{
+						Name:   "TestNode",
+						CpuIds: []uint{},
+					},
+				},
+			},
+			&powerv1.PowerWorkloadTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "latency-critical",
+					Namespace: "app",
+				},
+				Spec: powerv1.PowerWorkloadTemplateSpec{
+					PowerProfile: "performance",
+					Containers:   []string{"app"},
+					NodeSelector: map[string]string{"power": "true"},
+				},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "templated-pod",
+					Namespace:   "app",
+					UID:         "abcdefg",
+					Annotations: map[string]string{WorkloadTemplateAnnotation: "latency-critical"},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:   "TestNode",
+					Containers: []corev1.Container{container("app"), container("sidecar")},
+				},
+				Status: corev1.PodStatus{
+					Phase:    corev1.PodRunning,
+					QOSClass: corev1.PodQOSGuaranteed,
+				},
+			},
+		}
+
+		r, err := createPodReconcilerObject(clientObjs, createFakePodResourcesListerClient(podResources))
+		if err != nil {
+			t.Fatalf("%s - error creating reconciler object: %v", tc.testCase, err)
+		}
+
+		req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "templated-pod", Namespace: "app"}}
+		_, err = r.Reconcile(context.TODO(), req)
+		if err != nil {
+			t.Fatalf("%s - error reconciling object: %v", tc.testCase, err)
+		}
+
+		workload := &powerv1.PowerWorkload{}
+		err = r.Get(context.TODO(), client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}, workload)
+		if err != nil {
+			t.Fatalf("%s - error retrieving PowerWorkload: %v", tc.testCase, err)
+		}
+		if len(workload.Spec.Node.CpuIds) != len(tc.expectedCpuIds) ||
+			(len(tc.expectedCpuIds) > 0 && !reflect.DeepEqual(workload.Spec.Node.CpuIds, tc.expectedCpuIds)) {
+			t.Errorf("%s - expected CPU IDs %v, got %v", tc.testCase, tc.expectedCpuIds, workload.Spec.Node.CpuIds)
+		}
+	}
+}
+
+func TestPodResourcesCache(t *testing.T) {
+	lister := &fakePodResourcesClient{listResponse: &podresourcesapi.ListPodResourcesResponse{
+		PodResources: []*podresourcesapi.PodResources{
+			{
+				Name:       "test-pod-1",
+				Containers: []*podresourcesapi.ContainerResources{{Name: "test-container-1", CpuIds: []int64{1, 2}}},
+			},
+		},
+	}}
+	podResourcesClient := &podresourcesclient.PodResourcesClient{Client: lister}
+	podResourcesClient.EnableCache(time.Hour)
+
+	cpus, err := podResourcesClient.GetContainerCPUs("test-pod-1", "test-container-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cpus != "1-2" || lister.lists != 1 {
+		t.Errorf("expected CPUs 1-2 from one List call, got %s from %d", cpus, lister.lists)
+	}
+
+	// answered from the cache
+	_, err = podResourcesClient.GetContainerCPUs("test-pod-1", "test-container-1")
+	if err != nil || lister.lists != 1 {
+		t.Errorf("expected the cached response to be used, got %d List calls and error %v", lister.lists, err)
+	}
+
+	// a Pod the cached response doesn't know about is listed again
+	lister.listResponse = &podresourcesapi.ListPodResourcesResponse{PodResources: []*podresourcesapi.PodResources{
+		{
+			Name:       "test-pod-1",
+			Containers: []*podresourcesapi.ContainerResources{{Name: "test-container-1", CpuIds: []int64{1, 2}}},
+		},
+		{
+			Name:       "test-pod-2",
+			Containers: []*podresourcesapi.ContainerResources{{Name: "test-container-1", CpuIds: []int64{3}}},
+		},
+	}}
+	cpus, err = podResourcesClient.GetContainerCPUs("test-pod-2", "test-container-1")
+	if err != nil || cpus != "3" || lister.lists != 2 {
+		t.Errorf("expected CPUs 3 from a second List call, got %s from %d and error %v", cpus, lister.lists, err)
+	}
+
+	// a deleted Pod recreated with the same name is given other CPUs
+	podResourcesClient.Invalidate("test-pod-1")
+	lister.listResponse = &podresourcesapi.ListPodResourcesResponse{PodResources: []*podresourcesapi.PodResources{
+		{
+			Name:       "test-pod-1",
+			Containers: []*podresourcesapi.ContainerResources{{Name: "test-container-1", CpuIds: []int64{5}}},
+		},
+	}}
+	cpus, err = podResourcesClient.GetContainerCPUs("test-pod-1", "test-container-1")
+	if err != nil || cpus != "5" || lister.lists != 3 {
+		t.Errorf("expected CPUs 5 from a third List call, got %s from %d and error %v", cpus, lister.lists, err)
+	}
+}
+
+func TestPodJobBoost(t *testing.T) {
+	tcases := []struct {
+		testCase       string
+		annotations    map[string]string
+		conditions     []batchv1.JobCondition
+		expectedCpuIds []uint
+	}{
+		{
+			testCase:       "Test Case 1 - running boosted Job",
+			annotations:    map[string]string{BoostAnnotation: "performance"},
+			expectedCpuIds: []uint{1, 2},
+		},
+		{
+			testCase:       "Test Case 2 - Job without boost",
+			expectedCpuIds: []uint{},
+		},
+		{
+			testCase:       "Test Case 3 - finished boosted Job",
+			annotations:    map[string]string{BoostAnnotation: "performance"},
+			conditions:     []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+			expectedCpuIds: []uint{},
+		},
+	}
+
+	resources := map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceCPU:    *resource.NewQuantity(2, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(200, resource.DecimalSI),
+	}
+	podResources := []*podresourcesapi.PodResources{
+		{
+			Name:       "build-xyz12",
+			Namespace:  "ci",
+			Containers: []*podresourcesapi.ContainerResources{{Name: "build", CpuIds: []int64{1, 2}}},
+		},
+	}
+	isController := true
+
+	for _, tc := range tcases {
+		t.Setenv("NODE_NAME", "TestNode")
+		clientObjs := []runtime.Object{
+			&powerv1.PowerNode{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+			},
+			&powerv1.PowerProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerProfileSpec{
+					Name: "performance",
+				},
+			},
+			&powerv1.PowerWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance-TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerWorkloadSpec{
+					Name: "performance-TestNode",
+					Node: powerv1.WorkloadNode{
+						Name:   "TestNode",
+						CpuIds: []uint{},
+					},
+				},
+			},
+			&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "build",
+					Namespace:   "ci",
+					Annotations: tc.annotations,
+				},
+				Status: batchv1.JobStatus{Conditions: tc.conditions},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "build-xyz12",
+					Namespace: "ci",
+					UID:       "abcdefg",
+					OwnerReferences: []metav1.OwnerReference{
+						{APIVersion: "batch/v1", Kind: "Job", Name: "build", UID: "hijklmn", Controller: &isController},
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: "TestNode",
+					Containers: []corev1.Container{
+						{Name: "build", Resources: corev1.ResourceRequirements{Limits: resources, Requests: resources}},
+					},
+				},
+				Status: corev1.PodStatus{
+					Phase:    corev1.PodRunning,
+					QOSClass: corev1.PodQOSGuaranteed,
+				},
+			},
+		}
+
+		r, err := createPodReconcilerObject(clientObjs, createFakePodResourcesListerClient(podResources))
+		if err != nil {
+			t.Fatalf("%s - error creating reconciler object: %v", tc.testCase, err)
+		}
+
+		req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "build-xyz12", Namespace: "ci"}}
+		_, err = r.Reconcile(context.TODO(), req)
+		if err != nil {
+			t.Fatalf("%s - error reconciling object: %v", tc.testCase, err)
+		}
+
+		workload := &powerv1.PowerWorkload{}
+		err = r.Get(context.TODO(), client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}, workload)
+		if err != nil {
+			t.Fatalf("%s - error retrieving PowerWorkload: %v", tc.testCase, err)
+		}
+		if len(workload.Spec.Node.CpuIds) != len(tc.expectedCpuIds) ||
+			(len(tc.expectedCpuIds) > 0 && !reflect.DeepEqual(workload.Spec.Node.CpuIds, tc.expectedCpuIds)) {
+			t.Errorf("%s - expected CPU IDs %v, got %v", tc.testCase, tc.expectedCpuIds, workload.Spec.Node.CpuIds)
+		}
+	}
+}
+
+func TestPodSelectorWorkload(t *testing.T) {
+	tcases := []struct {
+		testCase       string
+		selector       *powerv1.WorkloadPodSelector
+		expectedCpuIds []uint
+	}{
+		{
+			testCase: "Test Case 1 - selected container",
+			selector: &powerv1.WorkloadPodSelector{
+				MatchLabels: map[string]string{"app": "trading"},
+				Containers:  []string{"engine"},
+			},
+			expectedCpuIds: []uint{1, 2},
+		},
+		{
+			testCase: "Test Case 2 - all containers",
+			selector: &powerv1.WorkloadPodSelector{
+				Namespace:   "finance",
+				MatchLabels: map[string]string{"app": "trading"},
+			},
+			expectedCpuIds: []uint{1, 2, 3, 4},
+		},
+		{
+			testCase: "Test Case 3 - labels not matching",
+			selector: &powerv1.WorkloadPodSelector{
+				MatchLabels: map[string]string{"app": "batch"},
+			},
+			expectedCpuIds: []uint{},
+		},
+		{
+			testCase: "Test Case 4 - namespace not matching",
+			selector: &powerv1.WorkloadPodSelector{
+				Namespace:   "default",
+				MatchLabels: map[string]string{"app": "trading"},
+			},
+			expectedCpuIds: []uint{},
+		},
+	}
+
+	resources := map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceCPU:    *resource.NewQuantity(2, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(200, resource.DecimalSI),
+	}
+	podResources := []*podresourcesapi.PodResources{
+		{
+			Name:      "trading-xyz12",
+			Namespace: "finance",
+			Containers: []*podresourcesapi.ContainerResources{
+				{Name: "engine", CpuIds: []int64{1, 2}},
+				{Name: "proxy", CpuIds: []int64{3, 4}},
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Setenv("NODE_NAME", "TestNode")
+		clientObjs := []runtime.Object{
+			&powerv1.PowerNode{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+			},
+			&powerv1.PowerProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerProfileSpec{
+					Name: "performance",
+				},
+			},
+			&powerv1.PowerWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance-TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerWorkloadSpec{
+					Name: "performance-TestNode",
+					Node: powerv1.WorkloadNode{
+						Name:   "TestNode",
+						CpuIds: []uint{},
+					},
+				},
+			},
+			&powerv1.PowerWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "trading",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerWorkloadSpec{
+					Name:         "trading",
+					PowerProfile: "performance",
+					PodSelector:  tc.selector,
+				},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "trading-xyz12",
+					Namespace: "finance",
+					UID:       "abcdefg",
+					Labels:    map[string]string{"app": "trading"},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: "TestNode",
+					Containers: []corev1.Container{
+						{Name: "engine", Resources: corev1.ResourceRequirements{Limits: resources, Requests: resources}},
+						{Name: "proxy", Resources: corev1.ResourceRequirements{Limits: resources, Requests: resources}},
+					},
+				},
+				Status: corev1.PodStatus{
+					Phase:    corev1.PodRunning,
+					QOSClass: corev1.PodQOSGuaranteed,
+				},
+			},
+		}
+
+		r, err := createPodReconcilerObject(clientObjs, createFakePodResourcesListerClient(podResources))
+		if err != nil {
+			t.Fatalf("%s - error creating reconciler object: %v", tc.testCase, err)
+		}
+
+		requests := r.selectedPodRequests(clientObjs[3].(*powerv1.PowerWorkload))
+		if len(requests) != 0 && len(tc.expectedCpuIds) == 0 || len(requests) != 1 && len(tc.expectedCpuIds) > 0 {
+			t.Errorf("%s - unexpected Pod requests %v", tc.testCase, requests)
+		}
+
+		req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "trading-xyz12", Namespace: "finance"}}
+		_, err = r.Reconcile(context.TODO(), req)
+		if err != nil {
+			t.Fatalf("%s - error reconciling object: %v", tc.testCase, err)
+		}
+
+		workload := &powerv1.PowerWorkload{}
+		err = r.Get(context.TODO(), client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}, workload)
+		if err != nil {
+			t.Fatalf("%s - error retrieving PowerWorkload: %v", tc.testCase, err)
+		}
+		if len(workload.Spec.Node.CpuIds) != len(tc.expectedCpuIds) ||
+			(len(tc.expectedCpuIds) > 0 && !reflect.DeepEqual(workload.Spec.Node.CpuIds, tc.expectedCpuIds)) {
+			t.Errorf("%s - expected CPU IDs %v, got %v", tc.testCase, tc.expectedCpuIds, workload.Spec.Node.CpuIds)
+		}
+	}
+}
+
+type fakeCgroupReader struct {
+	cpus map[string][]uint
+}
+
+func (f *fakeCgroupReader) ContainerCPUs(podUID string, containerID string) ([]uint, error) {
+	cpus, exists := f.cpus[containerID]
+	if !exists {
+		return nil, fmt.Errorf("no cgroup found for container %s", containerID)
+	}
+	return cpus, nil
+}
+
+func TestPodPinningVerification(t *testing.T) {
+	tcases := []struct {
+		testCase           string
+		cgroupCPUs         map[string][]uint
+		existing           []powerv1.PinningMismatch
+		expectedMismatches []powerv1.PinningMismatch
+	}{
+		{
+			testCase:   "Test Case 1 - container pinned to its CPUs",
+			cgroupCPUs: map[string][]uint{"containerd://abc123": {2, 1}},
+		},
+		{
+			testCase:   "Test Case 2 - CPU Manager moved the container",
+			cgroupCPUs: map[string][]uint{"containerd://abc123": {3, 4}},
+			existing:   []powerv1.PinningMismatch{{Pod: "other-pod", Container: "app", Expected: "5", Actual: "6"}},
+			expectedMismatches: []powerv1.PinningMismatch{
+				{Pod: "other-pod", Container: "app", Expected: "5", Actual: "6"},
+				{Pod: "test-pod-1", Container: "test-container-1", Expected: "1-2", Actual: "3-4"},
+			},
+		},
+		{
+			testCase:   "Test Case 3 - mismatch fixed",
+			cgroupCPUs: map[string][]uint{"containerd://abc123": {1, 2}},
+			existing:   []powerv1.PinningMismatch{{Pod: "test-pod-1", Container: "test-container-1", Expected: "1-2", Actual: "3-4"}},
+		},
+		{
+			testCase: "Test Case 4 - cgroup not found",
+			existing: []powerv1.PinningMismatch{{Pod: "test-pod-1", Container: "test-container-1", Expected: "1-2", Actual: "3-4"}},
+		},
+	}
+
+	resources := map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceCPU:    *resource.NewQuantity(2, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(200, resource.DecimalSI),
+		corev1.ResourceName(ResourcePrefix + "performance"): *resource.NewQuantity(2, resource.DecimalSI),
+	}
+	podResources := []*podresourcesapi.PodResources{
+		{
+			Name:       "test-pod-1",
+			Namespace:  IntelPowerNamespace,
+			Containers: []*podresourcesapi.ContainerResources{{Name: "test-container-1", CpuIds: []int64{1, 2}}},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Setenv("NODE_NAME", "TestNode")
+		clientObjs := []runtime.Object{
+			&powerv1.PowerNode{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+			},
+			&powerv1.PowerProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerProfileSpec{
+					Name: "performance",
+				},
+			},
+			&powerv1.PowerWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance-TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerWorkloadSpec{
+					Name: "performance-TestNode",
+					Node: powerv1.WorkloadNode{
+						Name:   "TestNode",
+						CpuIds: []uint{},
+					},
+				},
+				Status: powerv1.PowerWorkloadStatus{
+					PinningMismatches: tc.existing,
+				},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-1",
+					Namespace: IntelPowerNamespace,
+					UID:       "abcdefg",
+				},
+				Spec: corev1.PodSpec{
+					NodeName: "TestNode",
+					Containers: []corev1.Container{
+						{Name: "test-container-1", Resources: corev1.ResourceRequirements{Limits: resources, Requests: resources}},
+					},
+				},
+				Status: corev1.PodStatus{
+					Phase:    corev1.PodRunning,
+					QOSClass: corev1.PodQOSGuaranteed,
+					ContainerStatuses: []corev1.ContainerStatus{
+						{Name: "test-container-1", ContainerID: "containerd://abc123"},
+					},
+				},
+			},
+		}
+
+		r, err := createPodReconcilerObject(clientObjs, createFakePodResourcesListerClient(podResources))
+		if err != nil {
+			t.Fatalf("%s - error creating reconciler object: %v", tc.testCase, err)
+		}
+		r.Cgroups = &fakeCgroupReader{cpus: tc.cgroupCPUs}
+
+		req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "test-pod-1", Namespace: IntelPowerNamespace}}
+		_, err = r.Reconcile(context.TODO(), req)
+		if err != nil {
+			t.Fatalf("%s - error reconciling object: %v", tc.testCase, err)
+		}
+
+		workload := &powerv1.PowerWorkload{}
+		err = r.Get(context.TODO(), client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}, workload)
+		if err != nil {
+			t.Fatalf("%s - error retrieving PowerWorkload: %v", tc.testCase, err)
+		}
+		if !reflect.DeepEqual(workload.Spec.Node.CpuIds, []uint{1, 2}) {
+			t.Errorf("%s - expected CPU IDs %v, got %v", tc.testCase, []uint{1, 2}, workload.Spec.Node.CpuIds)
+		}
+		if len(workload.Status.PinningMismatches) != len(tc.expectedMismatches) ||
+			(len(tc.expectedMismatches) > 0 && !reflect.DeepEqual(workload.Status.PinningMismatches, tc.expectedMismatches)) {
+			t.Errorf("%s - expected pinning mismatches %v, got %v", tc.testCase, tc.expectedMismatches, workload.Status.PinningMismatches)
+		}
+	}
+}
+
+type corePlacerFunc func(pod *corev1.Pod, container string, profile string, cpus []uint) ([]uint, error)
+
+func (f corePlacerFunc) Place(ctx context.Context, pod *corev1.Pod, container string, profile string, cpus []uint) ([]uint, error) {
+	return f(pod, container, profile, cpus)
+}
+
+func TestPodCorePlacer(t *testing.T) {
+	tcases := []struct {
+		testCase       string
+		placer         CorePlacer
+		expectedCpuIds []uint
+		expectedErr    bool
+	}{
+		{
+			testCase:       "Test Case 1 - default placement gives every exclusive CPU the profile",
+			expectedCpuIds: []uint{1, 2, 3},
+		},
+		{
+			testCase: "Test Case 2 - CPUs left out stay shared and CPUs the container wasn't given are ignored",
+			placer: corePlacerFunc(func(pod *corev1.Pod, container string, profile string, cpus []uint) ([]uint, error) {
+				if container != "app" || profile != "performance" {
+					return nil, fmt.Errorf("unexpected container %s or profile %s", container, profile)
+				}
+				// CPU 2 handles the NIC's interrupts
+				return []uint{1, 3, 9}, nil
+			}),
+			expectedCpuIds: []uint{1, 3},
+		},
+		{
+			testCase: "Test Case 3 - placement error",
+			placer: corePlacerFunc(func(pod *corev1.Pod, container string, profile string, cpus []uint) ([]uint, error) {
+				return nil, fmt.Errorf("topology unavailable")
+			}),
+			expectedCpuIds: []uint{},
+			expectedErr:    true,
+		},
+	}
+
+	resources := map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceCPU:    *resource.NewQuantity(3, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(200, resource.DecimalSI),
+		corev1.ResourceName(ResourcePrefix + "performance"): *resource.NewQuantity(3, resource.DecimalSI),
+	}
+	podResources := []*podresourcesapi.PodResources{
+		{
+			Name:       "placed-pod",
+			Namespace:  IntelPowerNamespace,
+			Containers: []*podresourcesapi.ContainerResources{{Name: "app", CpuIds: []int64{1, 2, 3}}},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Setenv("NODE_NAME", "TestNode")
+		clientObjs := []runtime.Object{
+			&powerv1.PowerNode{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+			},
+			&powerv1.PowerProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerProfileSpec{
+					Name: "performance",
+				},
+			},
+			&powerv1.PowerWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "performance-TestNode",
+					Namespace: IntelPowerNamespace,
+				},
+				Spec: powerv1.PowerWorkloadSpec{
+					Name: "performance-TestNode",
+					Node: powerv1.WorkloadNode{
+						Name:   "TestNode",
+						CpuIds: []uint{},
+					},
+				},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "placed-pod",
+					Namespace: IntelPowerNamespace,
+					UID:       "abcdefg",
+				},
+				Spec: corev1.PodSpec{
+					NodeName: "TestNode",
+					Containers: []corev1.Container{
+						{Name: "app", Resources: corev1.ResourceRequirements{Limits: resources, Requests: resources}},
+					},
+				},
+				Status: corev1.PodStatus{
+					Phase:    corev1.PodRunning,
+					QOSClass: corev1.PodQOSGuaranteed,
+				},
+			},
+		}
+
+		r, err := createPodReconcilerObject(clientObjs, createFakePodResourcesListerClient(podResources))
+		if err != nil {
+			t.Fatalf("%s - error creating reconciler object: %v", tc.testCase, err)
+		}
+		r.Placer = tc.placer
+
+		req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "placed-pod", Namespace: IntelPowerNamespace}}
+		_, err = r.Reconcile(context.TODO(), req)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%s - expected error %v, got %v", tc.testCase, tc.expectedErr, err)
+		}
+
+		workload := &powerv1.PowerWorkload{}
+		err = r.Get(context.TODO(), client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}, workload)
+		if err != nil {
+			t.Fatalf("%s - error retrieving PowerWorkload: %v", tc.testCase, err)
+		}
+		if len(workload.Spec.Node.CpuIds) != len(tc.expectedCpuIds) ||
+			(len(tc.expectedCpuIds) > 0 && !reflect.DeepEqual(workload.Spec.Node.CpuIds, tc.expectedCpuIds)) {
+			t.Errorf("%s - expected CPU IDs %v, got %v", tc.testCase, tc.expectedCpuIds, workload.Spec.Node.CpuIds)
+		}
+		if !tc.expectedErr && (len(workload.Spec.Node.Containers) != 1 || !reflect.DeepEqual(workload.Spec.Node.Containers[0].ExclusiveCPUs, []uint{1, 2, 3})) {
+			t.Errorf("%s - expected the container with all its exclusive CPUs, got %v", tc.testCase, workload.Spec.Node.Containers)
+		}
+	}
+}
+
+func TestPodSharedProfileRequest(t *testing.T) {
+	t.Setenv("NODE_NAME", "TestNode")
+	clientObjs := []runtime.Object{
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "TestNode",
+				Namespace: IntelPowerNamespace,
+			},
+		},
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "performance-TestNode",
+				Namespace: IntelPowerNamespace,
+			},
+			Spec: powerv1.PowerWorkloadSpec{
+				Name: "performance-TestNode",
+				Node: powerv1.WorkloadNode{
+					Name:   "TestNode",
+					CpuIds: []uint{},
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "burstable-pod",
+				Namespace: IntelPowerNamespace,
+				UID:       "abcdefg",
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "TestNode",
+				Containers: []corev1.Container{
+					{
+						Name: "app",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU: *resource.NewMilliQuantity(500, resource.DecimalSI),
+								corev1.ResourceName(ResourcePrefix + "performance"): *resource.NewQuantity(1, resource.DecimalSI),
+							},
+							Limits: corev1.ResourceList{
+								corev1.ResourceName(ResourcePrefix + "performance"): *resource.NewQuantity(1, resource.DecimalSI),
+							},
+						},
+					},
+					{Name: "sidecar"},
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase:    corev1.PodRunning,
+				QOSClass: corev1.PodQOSBurstable,
+			},
+		},
+	}
+	r, err := createPodReconcilerObject(clientObjs, createFakePodResourcesListerClient(nil))
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+
+	// the Burstable container runs on the shared CPUs, so it's turned away rather than given the profile
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "burstable-pod", Namespace: IntelPowerNamespace}}
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("error reconciling object: %v", err)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one %s event, got %d", ProfileRequestRejectedReason, len(recorder.Events))
+	}
+	event := <-recorder.Events
+	if !strings.Contains(event, ProfileRequestRejectedReason) || !strings.Contains(event, "Container app requests power.intel.com/performance without exclusive CPUs") {
+		t.Errorf("expected the app container's request to be rejected, got event %q", event)
+	}
+
+	workload := &powerv1.PowerWorkload{}
+	err = r.Get(context.TODO(), client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}, workload)
+	if err != nil {
+		t.Fatalf("error retrieving PowerWorkload: %v", err)
+	}
+	if len(workload.Spec.Node.CpuIds) != 0 || len(workload.Spec.Node.Containers) != 0 {
+		t.Errorf("expected the PowerWorkload to be left without CPUs or containers, got %v and %v", workload.Spec.Node.CpuIds, workload.Spec.Node.Containers)
+	}
+}
@@ -18,20 +18,43 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
 	rt "runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/adopt"
+	"github.com/intel/kubernetes-power-manager/pkg/capacity"
+	"github.com/intel/kubernetes-power-manager/pkg/correlation"
+	"github.com/intel/kubernetes-power-manager/pkg/cpudefaults"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/objectsize"
+	"github.com/intel/kubernetes-power-manager/pkg/poollock"
+	"github.com/intel/kubernetes-power-manager/pkg/turbo"
 	"github.com/intel/power-optimization-library/pkg/power"
 
 	corev1 "k8s.io/api/core/v1"
@@ -41,32 +64,43 @@ import (
 const (
 	MaxFrequencyFile = "/sys/devices/system/cpu/cpu0/cpufreq/cpuinfo_max_freq"
 	MinFrequencyFile = "/sys/devices/system/cpu/cpu0/cpufreq/cpuinfo_min_freq"
+	// BaseFrequencyFile is the highest frequency all cores can hold without turbo, only intel_pstate provides it
+	BaseFrequencyFile = "/sys/devices/system/cpu/cpu0/cpufreq/base_frequency"
+	// CapacityLabelPrefix labels a Node with a PowerProfile's capacity when resources are advertised with labels
+	CapacityLabelPrefix = "capacity.power.intel.com/"
+	// LendHeadroomUntilAnnotation lends a PowerProfile's burst headroom out until the RFC 3339 time it holds, the
+	// headroom is advertised with the rest of the capacity until then and held back again afterwards
+	LendHeadroomUntilAnnotation = "power.intel.com/lend-headroom-until"
 )
 
+// readTurboPresets reads the turbo ratio table used to resolve frequency presets
+var readTurboPresets = func() (*turbo.Presets, error) {
+	return turbo.ReadPresets(turbo.MsrFile)
+}
+
+// readBaseFrequency reads the base frequency in MHz that realtime profiles are capped at
+var readBaseFrequency = func() (int, error) {
+	return readFrequencyFile(BaseFrequencyFile)
+}
+
 // performance          ===>  priority level 0
 // balance_performance  ===>  priority level 1
 // balance_power        ===>  priority level 2
 // power                ===>  priority level 3
 
-var profilePercentages map[string]map[string]float64 = map[string]map[string]float64{
-	"performance": map[string]float64{
-		"resource":   .40,
-		"difference": 0.0,
-	},
-	"balance_performance": map[string]float64{
-		"resource":   .60,
-		"difference": .25,
-	},
-	"balance_power": map[string]float64{
-		"resource":   .80,
-		"difference": .50,
-	},
-	"power": map[string]float64{
-		"resource":   1.0,
-		"difference": 0.0,
-	},
+// eppDefault is what a PowerProfile gets from its EPP value when it doesn't set its own frequencies. The
+// difference is the fraction of the Node's frequency range taken off the max frequency
+type eppDefault struct {
+	difference float64
+}
+
+var eppDefaults = map[string]eppDefault{
+	"performance":         {difference: 0.0},
+	"balance_performance": {difference: .25},
+	"balance_power":       {difference: .50},
+	"power":               {difference: 0.0},
 	// We have the empty string here so users can create Power Profiles that are not associated with SST-CP
-	"": map[string]float64{},
+	"": {},
 }
 
 // PowerProfileReconciler reconciles a PowerProfile object
@@ -75,123 +109,192 @@ type PowerProfileReconciler struct {
 	Log          logr.Logger
 	Scheme       *runtime.Scheme
 	PowerLibrary power.Host
+	// RealtimeKernel is whether the Node runs a PREEMPT_RT kernel, realtime profiles are refused without one
+	RealtimeKernel bool
+	// CpuDefaults resolve the frequencies of profiles with the auto preset, which are refused without them
+	CpuDefaults *cpudefaults.Database
+	// advertised holds the PowerProfiles whose Extended Resources this agent has put in the Node status, an
+	// entry found missing for one of them was wiped by a kubelet restart
+	advertised sync.Map
 }
 
 // +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=demandresponses,verbs=get;list;watch
 
 // Reconcile method that implements the reconcile loop
 func (r *PowerProfileReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
 	logger := r.Log.WithValues("powerprofile", req.NamespacedName)
 	if req.Namespace != IntelPowerNamespace {
 		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
 		return ctrl.Result{}, nil
 	}
 	logger.Info("Reconciling PowerProfile")
+	changes := logging.NewChangeSummary()
+	defer changes.Log(logger)
 
 	// Node name is passed down via the downwards API and used to make sure the PowerProfile is for this node
 	nodeName := os.Getenv("NODE_NAME")
 
 	profile := &powerv1.PowerProfile{}
-	err := r.Client.Get(context.TODO(), req.NamespacedName, profile)
+	err := r.Client.Get(c, req.NamespacedName, profile)
 	logger.V(5).Info("Retrieving Power Profile instances")
 	if err != nil {
 		if errors.IsNotFound(err) {
-			// First we need to remove the profile from the Power library, this will in turn remove the pool,
-			// which will also move the cores back to the Shared/Default pool and reconfigure them. We then
-			// need to remove the Power Workload from the cluster, which in this case will do nothing as
-			// everything has already been removed. Finally, we remove the Extended Resources from the Node
-			pool := r.PowerLibrary.GetExclusivePool(req.Name)
-			if pool == nil {
-				logger.Info("Attempted to remove non existing pool", "pool", req.Name)
-			}
-			err = pool.Remove()
+			err = r.removeFromNode(c, nodeName, req.Name, &logger, changes)
 			if err != nil {
-				logger.Error(err, "error deleting Power Profile From Library")
 				return ctrl.Result{}, err
 			}
+			return ctrl.Result{}, nil
+		}
 
-			powerWorkloadName := fmt.Sprintf("%s-%s", req.NamespacedName.Name, nodeName)
-			powerWorkload := &powerv1.PowerWorkload{}
-			err = r.Client.Get(context.TODO(), client.ObjectKey{
-				Name:      powerWorkloadName,
-				Namespace: req.NamespacedName.Namespace,
-			}, powerWorkload)
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					logger.Error(err, fmt.Sprintf("error deleting PowerWorkload '%s' from cluster", powerWorkloadName))
-					return ctrl.Result{}, err
-				}
-			} else {
-				err = r.Client.Delete(context.TODO(), powerWorkload)
-				if err != nil {
-					logger.Error(err, fmt.Sprintf("error deleting Power Workload '%s' from cluster", powerWorkloadName))
-					return ctrl.Result{}, err
-				}
-			}
+		// Requeue the request
+		return ctrl.Result{}, err
+	}
+	logger = logger.WithValues(correlation.LogKey, correlation.ID(profile))
+
+	excluded, err := nodeExcluded(c, r.Client, nodeName)
+	if err != nil {
+		logger.Error(err, "error checking if the Node is excluded")
+		return ctrl.Result{}, err
+	}
+	if excluded {
+		logger.V(5).Info("Node is excluded from power management, leaving it at its default power settings")
+		return ctrl.Result{}, nil
+	}
 
-			// Remove the Extended Resources for this PowerProfile from the Node
-			err = r.removeExtendedResources(nodeName, req.NamespacedName.Name, &logger)
+	// the cores of an adopted profile already have its settings, they're left alone until it's edited
+	if adopt.Pending(profile) {
+		logger.V(5).Info("PowerProfile was adopted from the Node's existing settings and hasn't been edited, leaving it as found")
+		return ctrl.Result{}, nil
+	}
+
+	if isConfigProfile(profile) {
+		offered, err := r.applyEffectiveProfile(c, nodeName, profile, &logger)
+		if err != nil {
+			logger.Error(err, "error reading the effective PowerProfiles of the PowerNode")
+			return ctrl.Result{}, err
+		}
+		if !offered {
+			logger.V(5).Info("PowerProfile isn't offered to the PowerNodeGroups of this Node, removing it")
+			err = r.removeFromNode(c, nodeName, profile.Name, &logger, changes)
 			if err != nil {
-				logger.Error(err, "error removing Extended Resources from node")
 				return ctrl.Result{}, err
 			}
-
 			return ctrl.Result{}, nil
 		}
-
-		// Requeue the request
-		return ctrl.Result{}, err
 	}
 
 	// Make sure the EPP value is one of the four correct ones or empty in the case of a user-created profile
 	logger.V(5).Info("Confirming EPP value is one of the correct values")
-	if _, exists := profilePercentages[profile.Spec.Epp]; !exists {
-		incorrectEppErr := errors.NewServiceUnavailable(fmt.Sprintf("EPP value not allowed: %v - deleting PowerProfile CRD", profile.Spec.Epp))
+	if _, exists := eppDefaults[profile.Spec.Epp]; !exists {
+		incorrectEppErr := powererrors.NewInvalidProfile(profile.Spec.Name, "EPP value not allowed: %v - deleting PowerProfile CRD", profile.Spec.Epp)
 		logger.Error(incorrectEppErr, "error reconciling PowerProfile")
 
-		err = r.Client.Delete(context.TODO(), profile)
+		err = r.Client.Delete(c, profile)
 		if err != nil {
 			logger.Error(err, fmt.Sprintf("error deleting PowerProfile %s with incorrect EPP value %s", profile.Spec.Name, profile.Spec.Epp))
 			return ctrl.Result{}, err
 		}
+		changes.ResourceRemoved("PowerProfile", profile.Name)
 
 		return ctrl.Result{}, nil
 	}
 
+	if profile.Spec.Realtime {
+		if !r.RealtimeKernel {
+			notRealtimeError := powererrors.NewInvalidProfile(profile.Spec.Name, "realtime profiles need a PREEMPT_RT kernel")
+			return reconcileError(&logger, notRealtimeError, fmt.Sprintf("error creating Profile '%s'", profile.Spec.Name))
+		}
+		if profile.Spec.Epp == "power" {
+			sharedRealtimeError := powererrors.NewInvalidProfile(profile.Spec.Name, "the Shared pool's profile cannot be realtime")
+			return reconcileError(&logger, sharedRealtimeError, fmt.Sprintf("error creating Profile '%s'", profile.Spec.Name))
+		}
+	}
+	if profile.Spec.Fastpath {
+		if profile.Spec.Realtime {
+			bothError := powererrors.NewInvalidProfile(profile.Spec.Name, "a profile can't be both realtime and fastpath")
+			return reconcileError(&logger, bothError, fmt.Sprintf("error creating Profile '%s'", profile.Spec.Name))
+		}
+		if profile.Spec.Epp == "power" {
+			sharedFastpathError := powererrors.NewInvalidProfile(profile.Spec.Name, "the Shared pool's profile cannot be fastpath")
+			return reconcileError(&logger, sharedFastpathError, fmt.Sprintf("error creating Profile '%s'", profile.Spec.Name))
+		}
+	}
+	if (profile.Spec.Realtime || profile.Spec.Fastpath) && (!managesFrequency(profile.Spec.ManageMin) || !managesFrequency(profile.Spec.ManageMax)) {
+		unmanagedError := powererrors.NewInvalidProfile(profile.Spec.Name, "realtime and fastpath profiles fix the frequency, they must manage both min and max")
+		return reconcileError(&logger, unmanagedError, fmt.Sprintf("error creating Profile '%s'", profile.Spec.Name))
+	}
+
+	if profile.Spec.MaxPreset == cpudefaults.Auto {
+		logger.V(5).Info("Resolving frequencies from the CPU defaults", "epp", profile.Spec.Epp)
+		err = r.applyCpuDefaults(profile)
+		if err != nil {
+			return reconcileError(&logger, err, fmt.Sprintf("error resolving CPU defaults for Profile '%s'", profile.Spec.Name))
+		}
+	} else if profile.Spec.MaxPreset != "" {
+		logger.V(5).Info("Resolving max frequency preset from the turbo ratio table", "preset", profile.Spec.MaxPreset)
+		presetFrequency, err := getPresetFrequency(profile.Spec.MaxPreset)
+		statusErr := r.setPresetCondition(c, req.NamespacedName, nodeName, err)
+		if statusErr != nil {
+			logger.Error(statusErr, "error recording the preset resolution in the PowerProfile status")
+		}
+		if err != nil {
+			return reconcileError(&logger, err, fmt.Sprintf("error resolving frequency preset for Profile '%s'", profile.Spec.Name))
+		}
+		profile.Spec.Max = presetFrequency
+	}
+
 	logger.V(5).Info("Making sure max value is higher than the min value")
-	if profile.Spec.Max < profile.Spec.Min {
-		maxLowerThanMaxError := errors.NewServiceUnavailable("Max frequency value cannot be lower than Minimum frequency value")
-		logger.Error(maxLowerThanMaxError, fmt.Sprintf("error creating Profile '%s'", profile.Spec.Name))
-		return ctrl.Result{}, nil
+	if managesFrequency(profile.Spec.ManageMin) && managesFrequency(profile.Spec.ManageMax) && profile.Spec.Max < profile.Spec.Min {
+		maxLowerThanMinError := powererrors.NewInvalidProfile(profile.Spec.Name, "Max frequency value cannot be lower than Minimum frequency value")
+		return reconcileError(&logger, maxLowerThanMinError, fmt.Sprintf("error creating Profile '%s'", profile.Spec.Name))
 	}
 
 	absoluteMaximumFrequency, absoluteMinimumFrequency, err := getMaxMinFrequencyValues()
 	logger.V(5).Info("Retrieving the Maximum possible Frequency and Minimum possible Frequency from the system")
 	if err != nil {
-		logger.Error(err, "error retrieving frequency values from Node")
-		return ctrl.Result{}, nil
+		return reconcileError(&logger, err, "error retrieving frequency values from Node")
+	}
+
+	if profile.Spec.MaxPreset != "" && profile.Spec.Min == 0 {
+		profile.Spec.Min = absoluteMinimumFrequency
+	}
+	if profile.Spec.MaxPreset == cpudefaults.Auto {
+		// the defaults cover every SKU of the model, which don't all reach the same frequencies
+		profile.Spec.Max = clampFrequency(profile.Spec.Max, absoluteMinimumFrequency, absoluteMaximumFrequency)
+		profile.Spec.Min = clampFrequency(profile.Spec.Min, absoluteMinimumFrequency, absoluteMaximumFrequency)
 	}
 
+	result := ctrl.Result{}
+	poolChanged := false
 	// If the Profile is shared (epp == power) then the associated Pool will not be created in the Power Library
 	if profile.Spec.Epp == "power" {
-
+		profile.Spec.Min, profile.Spec.Max = unmanagedFrequencies(&profile.Spec, profile.Spec.Min, profile.Spec.Max, absoluteMinimumFrequency, absoluteMaximumFrequency)
 		if profile.Spec.Max < absoluteMinimumFrequency || profile.Spec.Min < absoluteMinimumFrequency {
-			frequencyTooLowError := errors.NewServiceUnavailable(fmt.Sprintf("Maximum or Minimum frequency value cannot be below %d", absoluteMinimumFrequency))
-			logger.Error(frequencyTooLowError, "error creating Shared Power Profile")
-			return ctrl.Result{}, nil
+			frequencyTooLowError := powererrors.NewInvalidProfile(profile.Spec.Name, "Maximum or Minimum frequency value cannot be below %d", absoluteMinimumFrequency)
+			return reconcileError(&logger, frequencyTooLowError, "error creating Shared Power Profile")
 		}
 		actualEpp := profile.Spec.Epp
 		if isEppSupported() {
 			actualEpp = ""
 		}
 		powerProfile, _ := power.NewPowerProfile(profile.Spec.Name, uint(profile.Spec.Min), uint(profile.Spec.Max), profile.Spec.Governor, actualEpp)
-		err = r.PowerLibrary.GetSharedPool().SetPowerProfile(powerProfile)
+		err = poollock.Change(func() error { return r.PowerLibrary.GetSharedPool().SetPowerProfile(powerProfile) })
 		if err != nil {
 			logger.Error(err, "could not set power profile for shared pool")
 			return ctrl.Result{}, nil
 		}
+		changes.PoolModified("shared", nil, nil)
+
+		if profile.Spec.ApplyDeadlineSeconds > 0 {
+			err = r.recordAppliedGeneration(c, nodeName, profile.Name, profile.Generation)
+			if err != nil {
+				logger.Error(err, "error recording the applied generation on the PowerNode")
+				return ctrl.Result{}, err
+			}
+		}
 
 		logger.V(5).Info("Shared Power Profile successfully created: Name - %s Max - %d Min - %d EPP - %s", profile.Spec.Name, profile.Spec.Max, profile.Spec.Min, profile.Spec.Epp)
 		return ctrl.Result{}, nil
@@ -199,16 +302,16 @@ func (r *PowerProfileReconciler) Reconcile(c context.Context, req ctrl.Request)
 		var profileMaxFreq int
 		var profileMinFreq int
 		if profile.Spec.Epp != "" && profile.Spec.Max == 0 && profile.Spec.Min == 0 {
-			profileMaxFreq = int(float64(absoluteMaximumFrequency) - (float64((absoluteMaximumFrequency - absoluteMinimumFrequency)) * profilePercentages[profile.Spec.Epp]["difference"]))
+			profileMaxFreq = int(float64(absoluteMaximumFrequency) - (float64((absoluteMaximumFrequency - absoluteMinimumFrequency)) * eppDefaults[profile.Spec.Epp].difference))
 			profileMinFreq = int(profileMaxFreq) - 200
 		} else {
 			profileMaxFreq = profile.Spec.Max
 			profileMinFreq = profile.Spec.Min
 		}
+		profileMinFreq, profileMaxFreq = unmanagedFrequencies(&profile.Spec, profileMinFreq, profileMaxFreq, absoluteMinimumFrequency, absoluteMaximumFrequency)
 		if profileMaxFreq == 0 || profileMinFreq == 0 {
-			cannotBeZeroError := errors.NewServiceUnavailable("max or Min frequency cannot be zero")
-			logger.Error(cannotBeZeroError, "error creating Profile '%s'", profile.Spec.Name)
-			return ctrl.Result{}, nil
+			cannotBeZeroError := powererrors.NewInvalidProfile(profile.Spec.Name, "max or Min frequency cannot be zero")
+			return reconcileError(&logger, cannotBeZeroError, fmt.Sprintf("error creating Profile '%s'", profile.Spec.Name))
 		}
 
 		profileFromLibrary := r.PowerLibrary.GetExclusivePool(profile.Spec.Name)
@@ -216,32 +319,97 @@ func (r *PowerProfileReconciler) Reconcile(c context.Context, req ctrl.Request)
 		if isEppSupported() {
 			actualEpp = ""
 		}
-		powerProfile, _ := power.NewPowerProfile(profile.Spec.Name, uint(profileMinFreq), uint(profileMaxFreq), profile.Spec.Governor, actualEpp)
+		governor := profile.Spec.Governor
+		if profile.Spec.Realtime {
+			// the performance governor only takes the performance EPP
+			profileMaxFreq = realtimeFrequency(profileMaxFreq)
+			profileMinFreq = profileMaxFreq
+			governor = "performance"
+			actualEpp = ""
+		} else if profile.Spec.Fastpath {
+			// polling cores are always busy, anything below the max only slows the data plane down
+			profileMinFreq = profileMaxFreq
+			governor = "performance"
+			actualEpp = ""
+		}
+		reduction, nextDemandResponse, err := activeDemandReduction(c, r.Client, nodeName, profile.Spec.Name, time.Now())
+		if err != nil {
+			logger.Error(err, "error retrieving the DemandResponses")
+			return ctrl.Result{}, err
+		}
+		demotion, err := throttleDemotionPercent(c, r.Client, nodeName, profile.Spec.Name)
+		if err != nil {
+			logger.Error(err, "error checking if the Node is demoted for throttling")
+			return ctrl.Result{}, err
+		}
+		if demotion > reduction.capacityPercent {
+			logger.V(5).Info("Withholding capacity while the Node is throttling", "percent", demotion)
+			reduction.capacityPercent = demotion
+		}
+		if reduction.maxFrequency > 0 && profileMaxFreq > reduction.maxFrequency {
+			logger.V(5).Info("Capping max frequency for a DemandResponse", "maxFrequency", reduction.maxFrequency)
+			profileMaxFreq = clampFrequency(reduction.maxFrequency, absoluteMinimumFrequency, profileMaxFreq)
+			if profileMinFreq > profileMaxFreq {
+				profileMinFreq = profileMaxFreq
+			}
+		}
+		powerProfile, _ := power.NewPowerProfile(profile.Spec.Name, uint(profileMinFreq), uint(profileMaxFreq), governor, actualEpp)
 		if profileFromLibrary == nil {
-			pool, err := r.PowerLibrary.AddExclusivePool(profile.Spec.Name)
+			var pool power.Pool
+			err := poollock.Change(func() (err error) {
+				pool, err = r.PowerLibrary.AddExclusivePool(profile.Spec.Name)
+				return err
+			})
 			if err != nil {
 				logger.Error(err, "failed to create power profile")
 				return ctrl.Result{}, err
 			}
-			err = pool.SetPowerProfile(powerProfile)
+			changes.PoolCreated(profile.Spec.Name)
+			err = poollock.Change(func() error { return pool.SetPowerProfile(powerProfile) })
 			if err != nil {
 				logger.Error(err, fmt.Sprintf("error adding Profile '%s' to Power Library for Host '%s'", profile.Spec.Name, nodeName))
 				return ctrl.Result{}, err
 			}
 
-			// Create the Extended Resources for the profile
-			err = r.createExtendedResources(nodeName, profile.Spec.Name, profile.Spec.Epp, &logger)
-			if err != nil {
-				logger.Error(err, "error creating extended resources for base profile")
-				return ctrl.Result{}, err
-			}
 		} else {
-			err = r.PowerLibrary.GetExclusivePool(profile.Spec.Name).SetPowerProfile(powerProfile)
+			poolChanged = profileChanged(profileFromLibrary.GetPowerProfile(), powerProfile)
+			err = poollock.Change(func() error { return r.PowerLibrary.GetExclusivePool(profile.Spec.Name).SetPowerProfile(powerProfile) })
 			logger.V(5).Info("Updating Power Profile '%s' to the Power Library for Node '%s'", profile.Spec.Name, nodeName)
 			if err != nil {
 				logger.Error(err, fmt.Sprintf("error updating Profile '%s' to Power Library for Node '%s'", profile.Spec.Name, nodeName))
 				return ctrl.Result{}, err
 			}
+			changes.PoolModified(profile.Spec.Name, nil, nil)
+		}
+
+		if profile.Spec.Realtime {
+			err = r.PowerLibrary.GetExclusivePool(profile.Spec.Name).SetCStates(realtimeCStates(r.PowerLibrary.AvailableCStates()))
+			if err != nil {
+				// the cores are still at a fixed frequency, C-states may just not be supported
+				logger.Error(err, fmt.Sprintf("error limiting C-states of realtime Profile '%s'", profile.Spec.Name))
+			}
+		} else if profile.Spec.Fastpath {
+			err = r.PowerLibrary.GetExclusivePool(profile.Spec.Name).SetCStates(fastpathCStates(r.PowerLibrary.AvailableCStates()))
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("error limiting C-states of fastpath Profile '%s'", profile.Spec.Name))
+			}
+		}
+
+		// Create or resize the Extended Resources for the profile
+		err = r.createExtendedResources(c, nodeName, profile, reduction.capacityPercent, &logger, changes)
+		if err != nil {
+			// a capacity expression that can't be evaluated on this Node isn't retried until the profile changes
+			return reconcileError(&logger, err, "error creating extended resources for profile")
+		}
+		// come back when lent headroom is due to be held back again, or a DemandResponse starts or ends
+		result.RequeueAfter = minDuration(headroomLentFor(profile, time.Now()), nextDemandResponse)
+
+		if profile.Spec.ApplyDeadlineSeconds > 0 {
+			err = r.recordAppliedGeneration(c, nodeName, profile.Name, profile.Generation)
+			if err != nil {
+				logger.Error(err, "error recording the applied generation on the PowerNode")
+				return ctrl.Result{}, err
+			}
 		}
 
 		logger.V(5).Info("Power Profile successfully created: Name - %s Max - %d Min - %d EPP - %s", profile.Spec.Name, profileMaxFreq, profileMinFreq, profile.Spec.Epp)
@@ -250,7 +418,7 @@ func (r *PowerProfileReconciler) Reconcile(c context.Context, req ctrl.Request)
 	workloadName := fmt.Sprintf("%s-%s", profile.Spec.Name, nodeName)
 	logger.V(5).Info("Configuring workload name: %s", workloadName)
 	workload := &powerv1.PowerWorkload{}
-	err = r.Client.Get(context.TODO(), client.ObjectKey{
+	err = r.Client.Get(c, client.ObjectKey{
 		Name:      workloadName,
 		Namespace: req.NamespacedName.Namespace,
 	}, workload)
@@ -272,79 +440,395 @@ func (r *PowerProfileReconciler) Reconcile(c context.Context, req ctrl.Request)
 			}
 			powerWorkload.Spec = *powerWorkloadSpec
 
-			err = r.Client.Create(context.TODO(), powerWorkload)
+			err = r.Client.Create(c, powerWorkload)
 			if err != nil {
 				logger.Error(err, fmt.Sprintf("error creating Power Workload '%s'", workloadName))
 				return ctrl.Result{}, err
 			}
+			changes.ResourceAdded("PowerWorkload", workloadName)
 
 			logger.V(5).Info("Power Workload successfully created", "name", workloadName, "profile", profile.Spec.Name)
-			return ctrl.Result{}, nil
+			return result, nil
 		}
 
 		return ctrl.Result{}, err
 	}
 
 	// If the workload already exists then the Power Profile was just updated and the Power Library will take care of reconfiguring cores
-	return ctrl.Result{}, nil
+	if poolChanged && len(workload.Spec.Node.Containers) > 0 {
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			err := r.Client.Get(c, client.ObjectKeyFromObject(workload), workload)
+			if err != nil {
+				return err
+			}
+			startTransition(&workload.Status, profile.Spec.Name, time.Now())
+			return r.Client.Status().Update(c, workload)
+		})
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("error starting the PowerProfile transition of Power Workload '%s'", workloadName))
+			return ctrl.Result{}, err
+		}
+		changes.ResourceUpdated("PowerWorkload", workloadName)
+	}
+
+	return result, nil
+}
+
+// setPresetCondition records on the PowerProfile whether this Node resolved its maxPreset. A Node resolving it
+// leaves the failure of another Node in place, that Node clears it once it resolves the preset itself
+func (r *PowerProfileReconciler) setPresetCondition(c context.Context, key client.ObjectKey, nodeName string, presetErr error) error {
+	nodePrefix := fmt.Sprintf("Node %s: ", nodeName)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		profile := &powerv1.PowerProfile{}
+		err := r.Client.Get(c, key, profile)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		current := meta.FindStatusCondition(profile.Status.Conditions, powerv1.ConditionPresetResolved)
+		condition := metav1.Condition{
+			Type:               powerv1.ConditionPresetResolved,
+			Status:             metav1.ConditionTrue,
+			Reason:             powerv1.ReasonPresetResolved,
+			Message:            "Resolved from the turbo ratio table of the Nodes",
+			ObservedGeneration: profile.Generation,
+		}
+		if presetErr != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = powerv1.ReasonPresetUnresolvable
+			if powererrors.IsHardwareUnsupported(presetErr) {
+				condition.Reason = powerv1.ReasonTurboPresetsUnsupported
+			}
+			condition.Message = nodePrefix + presetErr.Error()
+		} else if current != nil && current.Status == metav1.ConditionFalse && !strings.HasPrefix(current.Message, nodePrefix) {
+			return nil
+		}
+		if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+			current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+			return nil
+		}
+
+		meta.SetStatusCondition(&profile.Status.Conditions, condition)
+		return r.Client.Status().Update(c, profile)
+	})
+}
+
+// recordAppliedGeneration sets the generation of the PowerProfile the Node applied on its PowerNode, for the
+// applyDeadlineSeconds to be checked against. A zero generation removes the profile
+func (r *PowerProfileReconciler) recordAppliedGeneration(c context.Context, nodeName string, profileName string, generation int64) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(c, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		applied, found := powerNode.Status.AppliedProfiles[profileName]
+		if generation == 0 {
+			if !found {
+				return nil
+			}
+			delete(powerNode.Status.AppliedProfiles, profileName)
+		} else {
+			if found && applied == generation {
+				return nil
+			}
+			if powerNode.Status.AppliedProfiles == nil {
+				powerNode.Status.AppliedProfiles = make(map[string]int64)
+			}
+			powerNode.Status.AppliedProfiles[profileName] = generation
+		}
+
+		return r.Client.Status().Update(c, powerNode)
+	})
+}
+
+// profileChanged reports whether applying the updated profile changes the pool's frequencies, governor or EPP
+func profileChanged(current power.Profile, updated power.Profile) bool {
+	if current == nil || updated == nil {
+		return current != updated
+	}
+
+	return current.MaxFreq() != updated.MaxFreq() || current.MinFreq() != updated.MinFreq() ||
+		current.Governor() != updated.Governor() || current.Epp() != updated.Epp()
 }
 
-func (r *PowerProfileReconciler) createExtendedResources(nodeName string, profileName string, eppValue string, logger *logr.Logger) error {
+func (r *PowerProfileReconciler) createExtendedResources(c context.Context, nodeName string, profile *powerv1.PowerProfile, demandReductionPercent int, logger *logr.Logger, changes *logging.ChangeSummary) error {
 	node := &corev1.Node{}
-	err := r.Client.Get(context.TODO(), client.ObjectKey{
+	err := r.Client.Get(c, client.ObjectKey{
 		Name: nodeName,
 	}, node)
 	if err != nil {
 		return err
 	}
+	mode, err := r.resourceAdvertisement(c)
+	if err != nil {
+		return err
+	}
+
+	logger.V(5).Info("Configuring based on the capacity of the specific power profile")
+	domainShares, err := r.domainShares(c, nodeName)
+	if err != nil {
+		return err
+	}
+	capacityNode := capacity.Node{NumCPUs: nodeCPUs(node), DomainShares: domainShares}
+	if profile.Spec.Capacity != nil && profile.Spec.Capacity.Expression != "" {
+		capacityNode.Facts, err = r.nodeFacts(c, node)
+		if err != nil {
+			return err
+		}
+	}
+	numExtendedResources, err := capacity.Quantity(profile, capacityNode,
+		capacity.Reservation{HeadroomLent: headroomLentFor(profile, time.Now()) > 0, WithheldPercent: demandReductionPercent})
+	if err != nil {
+		return err
+	}
+	if mode == powerv1.AdvertiseNodeLabels {
+		return r.setCapacityLabel(c, node, profile.Spec.Name, strconv.FormatInt(numExtendedResources, 10), changes)
+	}
+	// a label left over from the other mode would keep the scheduler extender admitting Pods
+	err = r.setCapacityLabel(c, node, profile.Spec.Name, "", changes)
+	if err != nil {
+		return err
+	}
 
-	numCPUsOnNode := float64(rt.NumCPU())
-	logger.V(5).Info("Configuring based on the percentage associated to the specific power profile")
-	numExtendedResources := int64(numCPUsOnNode * profilePercentages[eppValue]["resource"])
 	profilesAvailable := resource.NewQuantity(numExtendedResources, resource.DecimalSI)
-	extendedResourceName := corev1.ResourceName(fmt.Sprintf("%s%s", ExtendedResourcePrefix, profileName))
-	node.Status.Capacity[extendedResourceName] = *profilesAvailable
+	extendedResourceName := corev1.ResourceName(fmt.Sprintf("%s%s", ExtendedResourcePrefix, profile.Spec.Name))
+	current, exists := node.Status.Capacity[extendedResourceName]
+	if exists && current.Equal(*profilesAvailable) {
+		return nil
+	}
 
-	err = r.Client.Status().Update(context.TODO(), node)
+	objectsize.Warn(*logger, "Node", node)
+	err = patchNodeCapacity(c, r.Client, node, extendedResourceName, profilesAvailable)
 	if err != nil {
 		return err
 	}
+	if _, advertised := r.advertised.Swap(profile.Spec.Name, true); advertised && !exists {
+		logger.Info("Restored Extended Resource missing from the Node", "resource", extendedResourceName)
+		metrics.ExtendedResourcesRestored.WithLabelValues(nodeName, profile.Spec.Name).Inc()
+	}
+	changes.NodeTouched(nodeName)
+	if exists {
+		changes.ResourceUpdated("ExtendedResource", string(extendedResourceName))
+	} else {
+		changes.ResourceAdded("ExtendedResource", string(extendedResourceName))
+	}
 
 	return nil
 }
 
-func (r *PowerProfileReconciler) removeExtendedResources(nodeName string, profileName string, logger *logr.Logger) error {
+// domainShares is the Node's share of its failure domain's cap of each capped PowerProfile, as the PowerConfig
+// Controller worked it out
+func (r *PowerProfileReconciler) domainShares(c context.Context, nodeName string) (map[string]int, error) {
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(c, client.ObjectKey{
+		Name:      nodeName,
+		Namespace: IntelPowerNamespace,
+	}, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return powerNode.Status.DomainCapacity, nil
+}
+
+// nodeCPUs is how many CPUs the kubelet reports in the Node's capacity. The Node Agent's own count is only used
+// until the kubelet has reported one, as it is limited to the CPUs of the agent's cpuset
+func nodeCPUs(node *corev1.Node) int {
+	if cpus, found := node.Status.Capacity[corev1.ResourceCPU]; found && cpus.Value() > 0 {
+		return int(cpus.Value())
+	}
+
+	return rt.NumCPU()
+}
+
+// nodeFacts are what the capacity expressions of the PowerProfiles read of the Node, the CPU model is the one the
+// Node Agent recorded in the PowerNode status
+func (r *PowerProfileReconciler) nodeFacts(c context.Context, node *corev1.Node) (capacity.Facts, error) {
+	facts := capacity.Facts{
+		Sockets: len(*r.PowerLibrary.Topology().Packages()),
+		Labels:  node.Labels,
+	}
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(c, client.ObjectKey{Name: node.Name, Namespace: IntelPowerNamespace}, powerNode)
+	if err != nil && !errors.IsNotFound(err) {
+		return facts, err
+	}
+	if powerNode.Status.CPUModel != nil {
+		facts.CPUModel = powerNode.Status.CPUModel.Name
+	}
+
+	return facts, nil
+}
+
+// resourceAdvertisement is how the PowerConfig says PowerProfile capacity is advertised, Node status if there is none
+func (r *PowerProfileReconciler) resourceAdvertisement(c context.Context) (string, error) {
+	configs := &powerv1.PowerConfigList{}
+	err := r.Client.List(c, configs, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return "", err
+	}
+	for _, config := range configs.Items {
+		if config.Spec.ResourceAdvertisement != "" {
+			return config.Spec.ResourceAdvertisement, nil
+		}
+	}
+
+	return powerv1.AdvertiseNodeStatus, nil
+}
+
+// setCapacityLabel sets the Node's capacity label for a PowerProfile, or removes it if capacity is empty
+func (r *PowerProfileReconciler) setCapacityLabel(c context.Context, node *corev1.Node, profileName string, capacity string, changes *logging.ChangeSummary) error {
+	label := CapacityLabelPrefix + profileName
+	if node.Labels[label] == capacity {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	if capacity == "" {
+		delete(node.Labels, label)
+	} else {
+		if node.Labels == nil {
+			node.Labels = make(map[string]string)
+		}
+		node.Labels[label] = capacity
+	}
+
+	err := r.Client.Patch(c, node, patch)
+	if err != nil {
+		return err
+	}
+	changes.NodeTouched(node.Name)
+	changes.ResourceUpdated("NodeLabel", label)
+
+	return nil
+}
+
+// headroomLentFor is how much longer the PowerProfile's headroom is lent out, zero if it isn't or the
+// annotation can't be parsed
+func headroomLentFor(profile *powerv1.PowerProfile, now time.Time) time.Duration {
+	until, err := time.Parse(time.RFC3339, profile.Annotations[LendHeadroomUntilAnnotation])
+	if err != nil || !until.After(now) {
+		return 0
+	}
+
+	return until.Sub(now)
+}
+
+func (r *PowerProfileReconciler) removeExtendedResources(c context.Context, nodeName string, profileName string, logger *logr.Logger, changes *logging.ChangeSummary) error {
 	node := &corev1.Node{}
-	err := r.Client.Get(context.TODO(), client.ObjectKey{
+	err := r.Client.Get(c, client.ObjectKey{
 		Name: nodeName,
 	}, node)
 	if err != nil {
 		return err
 	}
 
+	mode, err := r.resourceAdvertisement(c)
+	if err != nil {
+		return err
+	}
+	r.advertised.Delete(profileName)
+	err = r.setCapacityLabel(c, node, profileName, "", changes)
+	if err != nil || mode == powerv1.AdvertiseNodeLabels {
+		return err
+	}
+
 	logger.V(5).Info("Removing Extended Resources")
-	newNodeCapacityList := make(map[corev1.ResourceName]resource.Quantity)
 	extendedResourceName := corev1.ResourceName(fmt.Sprintf("%s%s", ExtendedResourcePrefix, profileName))
-	for resourceFromNode, numberOfResources := range node.Status.Capacity {
-		if resourceFromNode == extendedResourceName {
-			continue
-		}
-		newNodeCapacityList[resourceFromNode] = numberOfResources
+	if _, exists := node.Status.Capacity[extendedResourceName]; !exists {
+		return nil
 	}
 
-	node.Status.Capacity = newNodeCapacityList
-	err = r.Client.Status().Update(context.TODO(), node)
+	err = patchNodeCapacity(c, r.Client, node, extendedResourceName, nil)
 	if err != nil {
 		return err
 	}
+	changes.NodeTouched(nodeName)
+	changes.ResourceRemoved("ExtendedResource", string(extendedResourceName))
 
 	return nil
 }
 
+// patchNodeCapacity sets a single resource in the Node's status capacity, or removes it if quantity is nil, with a
+// JSON patch. Nodes with many labels and resources are large, a patch of the one key keeps the write small and
+// leaves the keys the kubelet and device plugins own alone
+func patchNodeCapacity(c context.Context, cl client.Client, node *corev1.Node, name corev1.ResourceName, quantity *resource.Quantity) error {
+	path := "/status/capacity/" + jsonPointerEscape(string(name))
+	var operations []map[string]interface{}
+	switch {
+	case quantity == nil:
+		operations = []map[string]interface{}{{"op": "remove", "path": path}}
+	case node.Status.Capacity == nil:
+		operations = []map[string]interface{}{{"op": "add", "path": "/status/capacity",
+			"value": corev1.ResourceList{name: *quantity}}}
+	default:
+		operations = []map[string]interface{}{{"op": "add", "path": path, "value": *quantity}}
+	}
+	data, err := json.Marshal(operations)
+	if err != nil {
+		return err
+	}
+
+	return cl.Status().Patch(c, node, client.RawPatch(types.JSONPatchType, data))
+}
+
+// jsonPointerEscape escapes a key for use in a JSON patch path, resource names contain a /
+func jsonPointerEscape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// realtimeFrequency is the fixed frequency of a realtime profile, its max frequency capped at the base frequency
+// so turbo doesn't make it vary with the load on the other cores
+func realtimeFrequency(maxFrequency int) int {
+	baseFrequency, err := readBaseFrequency()
+	if err != nil || baseFrequency == 0 || baseFrequency > maxFrequency {
+		return maxFrequency
+	}
+
+	return baseFrequency
+}
+
+// realtimeCStates enables POLL and C1 only, the deeper C-states take too long to exit for realtime workloads
+func realtimeCStates(available []string) power.CStates {
+	states := power.CStates{}
+	for _, name := range available {
+		states[name] = name == "POLL" || name == "C1"
+	}
+
+	return states
+}
+
+// fastpathCStates enables POLL only, a polling core never idles and any wakeup latency shows up as dropped packets
+func fastpathCStates(available []string) power.CStates {
+	states := power.CStates{}
+	for _, name := range available {
+		states[name] = name == "POLL"
+	}
+
+	return states
+}
+
+func readFrequencyFile(file string) (int, error) {
+	frequency, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	kHz, err := strconv.Atoi(strings.TrimSpace(string(frequency)))
+	if err != nil {
+		return 0, err
+	}
+
+	return kHz / 1000, nil
+}
+
 func getMaxMinFrequencyValues() (int, int, error) {
 	absoluteMaximumFrequencyByte, err := os.ReadFile(MaxFrequencyFile)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, powererrors.NewHardwareUnsupported("cpufreq", err)
 	}
 	absoluteMaximumFrequencyString := string(absoluteMaximumFrequencyByte)
 	absoluteMaximumFrequency, err := strconv.Atoi(strings.Split(absoluteMaximumFrequencyString, "\n")[0])
@@ -354,7 +838,7 @@ func getMaxMinFrequencyValues() (int, int, error) {
 
 	absoluteMinimumFrequencyByte, err := os.ReadFile(MinFrequencyFile)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, powererrors.NewHardwareUnsupported("cpufreq", err)
 	}
 	absoluteMinimumFrequencyString := string(absoluteMinimumFrequencyByte)
 	absoluteMinimumFrequency, err := strconv.Atoi(strings.Split(absoluteMinimumFrequencyString, "\n")[0])
@@ -368,13 +852,261 @@ func getMaxMinFrequencyValues() (int, int, error) {
 	return absoluteMaximumFrequency, absoluteMinimumFrequency, nil
 }
 
+// applyCpuDefaults sets the frequencies recommended for the profile's EPP value on the Node's CPU model. A min
+// frequency set in the profile is kept, and a profile without an EPP value is given the recommended one
+func (r *PowerProfileReconciler) applyCpuDefaults(profile *powerv1.PowerProfile) error {
+	if r.CpuDefaults == nil {
+		return powererrors.NewInvalidProfile(profile.Spec.Name, "the %s preset needs CPU defaults, none are loaded", cpudefaults.Auto)
+	}
+	model, err := r.CpuDefaults.Local()
+	if err != nil {
+		return err
+	}
+	frequencies, exists := model.Profiles[profile.Spec.Epp]
+	if !exists {
+		return powererrors.NewInvalidProfile(profile.Spec.Name, "%s has no CPU defaults for EPP value '%s'", model.Name, profile.Spec.Epp)
+	}
+
+	profile.Spec.Max = frequencies.Max
+	if profile.Spec.Min == 0 {
+		profile.Spec.Min = frequencies.Min
+	}
+	if profile.Spec.Epp == "" {
+		profile.Spec.Epp = frequencies.Epp
+	}
+
+	return nil
+}
+
+// managesFrequency is whether a frequency limit is set by the profile, limits are managed unless turned off
+func managesFrequency(manage *bool) bool {
+	return manage == nil || *manage
+}
+
+// unmanagedFrequencies opens the limits the profile doesn't manage to the hardware's, the Power Library always
+// writes both limits so this is how one is left to the governor
+func unmanagedFrequencies(spec *powerv1.PowerProfileSpec, minimum int, maximum int, absoluteMinimum int, absoluteMaximum int) (int, int) {
+	if !managesFrequency(spec.ManageMin) {
+		minimum = absoluteMinimum
+	}
+	if !managesFrequency(spec.ManageMax) {
+		maximum = absoluteMaximum
+	}
+
+	return minimum, maximum
+}
+
+func clampFrequency(frequency int, minimum int, maximum int) int {
+	if frequency < minimum {
+		return minimum
+	}
+	if frequency > maximum {
+		return maximum
+	}
+	return frequency
+}
+
+func getPresetFrequency(preset string) (int, error) {
+	presets, err := readTurboPresets()
+	if err != nil {
+		return 0, powererrors.NewHardwareUnsupported("turbo frequency presets", err)
+	}
+
+	frequency, err := presets.Resolve(preset)
+	if err != nil {
+		return 0, powererrors.NewInvalidProfile("", "%v", err)
+	}
+
+	return int(frequency), nil
+}
+
+// reconcileError logs err and decides from its kind whether the request is retried. Errors meant for the status
+// are dropped as fail-fast ones are, callers record them first where the status has a place for them, such as
+// the PowerProfile's PresetResolved condition
+func reconcileError(logger *logr.Logger, err error, msg string) (ctrl.Result, error) {
+	logger.Error(err, msg)
+	if powererrors.ActionFor(err) == powererrors.Retry {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// removeFromNode takes a PowerProfile off this Node: its pool, its PowerWorkload, its Extended Resources and its
+// applied generation
+func (r *PowerProfileReconciler) removeFromNode(c context.Context, nodeName string, profileName string, logger *logr.Logger, changes *logging.ChangeSummary) error {
+	// First we need to remove the profile from the Power library, this will in turn remove the pool,
+	// which will also move the cores back to the Shared/Default pool and reconfigure them. We then
+	// need to remove the Power Workload from the cluster, which in this case will do nothing as
+	// everything has already been removed. Finally, we remove the Extended Resources from the Node
+	pool := r.PowerLibrary.GetExclusivePool(profileName)
+	if pool == nil {
+		logger.Info("Attempted to remove non existing pool", "pool", profileName)
+	}
+	err := poollock.Change(func() error { return pool.Remove() })
+	if err != nil {
+		logger.Error(err, "error deleting Power Profile From Library")
+		return err
+	}
+	if pool != nil {
+		changes.PoolRemoved(profileName)
+	}
+
+	powerWorkloadName := fmt.Sprintf("%s-%s", profileName, nodeName)
+	powerWorkload := &powerv1.PowerWorkload{}
+	err = r.Client.Get(c, client.ObjectKey{
+		Name:      powerWorkloadName,
+		Namespace: IntelPowerNamespace,
+	}, powerWorkload)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, fmt.Sprintf("error deleting PowerWorkload '%s' from cluster", powerWorkloadName))
+			return err
+		}
+	} else {
+		err = r.Client.Delete(c, powerWorkload)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("error deleting Power Workload '%s' from cluster", powerWorkloadName))
+			return err
+		}
+		changes.ResourceRemoved("PowerWorkload", powerWorkloadName)
+	}
+
+	// Remove the Extended Resources for this PowerProfile from the Node
+	err = r.removeExtendedResources(c, nodeName, profileName, logger, changes)
+	if err != nil {
+		logger.Error(err, "error removing Extended Resources from node")
+		return err
+	}
+
+	err = r.recordAppliedGeneration(c, nodeName, profileName, 0)
+	if err != nil {
+		logger.Error(err, "error removing the applied generation from the PowerNode")
+		return err
+	}
+
+	return nil
+}
+
+// applyEffectiveProfile replaces the settings of a PowerProfile created from the PowerConfig with the ones the
+// node group or Node policies give it on this Node, as the PowerConfig Controller worked them out. It reports
+// false for a PowerProfile the PowerNodeGroups of this Node don't offer
+func (r *PowerProfileReconciler) applyEffectiveProfile(c context.Context, nodeName string, profile *powerv1.PowerProfile, logger *logr.Logger) (bool, error) {
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(c, client.ObjectKey{
+		Name:      nodeName,
+		Namespace: IntelPowerNamespace,
+	}, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if !profileOffered(powerNode, profile) {
+		return false, nil
+	}
+	for _, effective := range powerNode.Status.EffectiveProfiles {
+		if effective.Name != profile.Spec.Name || effective.Source == powerv1.EffectiveSourceCluster {
+			continue
+		}
+		logger.V(5).Info("Applying the PowerProfile's overrides for this Node", "source", effective.Source)
+		if effective.Epp != "" {
+			profile.Spec.Epp = effective.Epp
+		}
+		if effective.Max != 0 {
+			profile.Spec.Max = effective.Max
+		}
+		if effective.Min != 0 {
+			profile.Spec.Min = effective.Min
+		}
+		if effective.Governor != "" {
+			profile.Spec.Governor = effective.Governor
+		}
+		if effective.Capacity != nil {
+			profile.Spec.Capacity = effective.Capacity.DeepCopy()
+		}
+	}
+
+	return true, nil
+}
+
 // SetupWithManager specifies how the controller is built and watch a CR and other resources that are owned and managed by the controller
 func (r *PowerProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&powerv1.PowerProfile{}).
+		Watches(&source.Kind{Type: &powerv1.PowerConfig{}}, handler.EnqueueRequestsFromMapFunc(r.configProfileRequests)).
+		Watches(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(r.nodeCapacityRequests)).
+		Watches(&source.Kind{Type: &powerv1.DemandResponse{}}, handler.EnqueueRequestsFromMapFunc(r.configProfileRequests),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &powerv1.PowerNode{}}, handler.EnqueueRequestsFromMapFunc(r.effectiveProfileRequests),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					oldNode, oldOk := e.ObjectOld.(*powerv1.PowerNode)
+					newNode, newOk := e.ObjectNew.(*powerv1.PowerNode)
+					return oldOk && newOk && (!reflect.DeepEqual(oldNode.Status.EffectiveProfiles, newNode.Status.EffectiveProfiles) ||
+						!reflect.DeepEqual(oldNode.Status.NodeGroups, newNode.Status.NodeGroups) ||
+						!reflect.DeepEqual(oldNode.Status.DomainCapacity, newNode.Status.DomainCapacity) ||
+						(oldNode.Status.Throttling == nil) != (newNode.Status.Throttling == nil))
+				},
+			})).
 		Complete(r)
 }
 
+// effectiveProfileRequests reconciles every PowerProfile again when the overrides or failure domain shares that
+// apply to this Node change, or the Node is demoted for throttling or restored
+func (r *PowerProfileReconciler) effectiveProfileRequests(obj client.Object) []reconcile.Request {
+	if obj.GetName() != os.Getenv("NODE_NAME") {
+		return nil
+	}
+
+	return r.configProfileRequests(obj)
+}
+
+// nodeCapacityRequests reconciles the PowerProfiles whose Extended Resources are missing from this Node's
+// capacity, a restarting kubelet wipes the ones it doesn't manage itself. The Node's frequent heartbeats
+// enqueue nothing while its capacity is intact
+func (r *PowerProfileReconciler) nodeCapacityRequests(obj client.Object) []reconcile.Request {
+	node, ok := obj.(*corev1.Node)
+	if !ok || node.Name != os.Getenv("NODE_NAME") {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0)
+	r.advertised.Range(func(key, _ interface{}) bool {
+		profileName := key.(string)
+		extendedResourceName := corev1.ResourceName(ExtendedResourcePrefix + profileName)
+		if _, exists := node.Status.Capacity[extendedResourceName]; !exists {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{
+				Name:      profileName,
+				Namespace: IntelPowerNamespace,
+			}})
+		}
+		return true
+	})
+
+	return requests
+}
+
+// configProfileRequests advertises every PowerProfile again when the PowerConfig changes, in case the
+// advertisement mode did
+func (r *PowerProfileReconciler) configProfileRequests(obj client.Object) []reconcile.Request {
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(context.TODO(), profiles, client.InNamespace(obj.GetNamespace()))
+	if err != nil {
+		r.Log.Error(err, "error listing PowerProfiles")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(profiles.Items))
+	for _, profile := range profiles.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&profile)})
+	}
+
+	return requests
+}
+
 func isEppSupported() bool {
 	_, err := os.Stat("/sys/devices/system/cpu/cpu0/cpufreq/energy_performance_preference")
 	return !os.IsNotExist(err)
@@ -17,10 +17,17 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -28,6 +35,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/capacity"
+	"github.com/intel/kubernetes-power-manager/pkg/cpudefaults"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+	"github.com/intel/kubernetes-power-manager/pkg/extender"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/objectsize"
+	"github.com/intel/kubernetes-power-manager/pkg/turbo"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/mock"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -46,7 +63,7 @@ func createProfileReconcilerObject(objs []runtime.Object) (*PowerProfileReconcil
 	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
 
 	// Create a ReconcileNode object with the scheme and fake client.
-	r := &PowerProfileReconciler{cl, ctrl.Log.WithName("testing"), s, nil}
+	r := &PowerProfileReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s}
 
 	return r, nil
 }
@@ -949,3 +966,969 @@ func TestProfileReturnsErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestGetPresetFrequency(t *testing.T) {
+	tcases := []struct {
+		testCase      string
+		ratios        uint64
+		coreCounts    uint64
+		preset        string
+		expectedFreq  int
+		expectedError bool
+	}{
+		{
+			testCase:     "Test Case 1 - Single core turbo",
+			ratios:       0x1C1E2023,
+			coreCounts:   0x20100402,
+			preset:       "singleCoreTurbo",
+			expectedFreq: 3500,
+		},
+		{
+			testCase:     "Test Case 2 - All core turbo",
+			ratios:       0x1C1E2023,
+			coreCounts:   0x20100402,
+			preset:       "allCoreTurbo",
+			expectedFreq: 2800,
+		},
+		{
+			testCase:     "Test Case 3 - All core turbo without core count table",
+			ratios:       0x1E2023,
+			preset:       "allCoreTurbo",
+			expectedFreq: 3000,
+		},
+		{
+			testCase:      "Test Case 4 - Empty ratio table",
+			preset:        "allCoreTurbo",
+			expectedError: true,
+		},
+		{
+			testCase:      "Test Case 5 - Unknown preset",
+			ratios:        0x1E2023,
+			preset:        "maxTurbo",
+			expectedError: true,
+		},
+	}
+
+	originalReadTurboPresets := readTurboPresets
+	defer func() { readTurboPresets = originalReadTurboPresets }()
+
+	for _, tc := range tcases {
+		readTurboPresets = func() (*turbo.Presets, error) {
+			return turbo.ParsePresets(tc.ratios, tc.coreCounts), nil
+		}
+
+		freq, err := getPresetFrequency(tc.preset)
+		if tc.expectedError {
+			if err == nil {
+				t.Errorf("%s Failed - expected an error", tc.testCase)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s Failed - unexpected error: %v", tc.testCase, err)
+		}
+		if freq != tc.expectedFreq {
+			t.Errorf("%s Failed - expected frequency %d, got %d", tc.testCase, tc.expectedFreq, freq)
+		}
+	}
+}
+
+func TestPresetCondition(t *testing.T) {
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "turbo", Namespace: IntelPowerNamespace, Generation: 1},
+		Spec:       powerv1.PowerProfileSpec{Name: "turbo", MaxPreset: "allCoreTurbo"},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{profile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := client.ObjectKeyFromObject(profile)
+	condition := func() *metav1.Condition {
+		latest := &powerv1.PowerProfile{}
+		if err := r.Client.Get(context.TODO(), key, latest); err != nil {
+			t.Fatal(err)
+		}
+		return meta.FindStatusCondition(latest.Status.Conditions, powerv1.ConditionPresetResolved)
+	}
+
+	unsupported := powererrors.NewHardwareUnsupported("turbo frequency presets", fmt.Errorf("no msr"))
+	if err = r.setPresetCondition(context.TODO(), key, "node-a", unsupported); err != nil {
+		t.Fatal(err)
+	}
+	if current := condition(); current == nil || current.Status != metav1.ConditionFalse ||
+		current.Reason != powerv1.ReasonTurboPresetsUnsupported || !strings.HasPrefix(current.Message, "Node node-a: ") {
+		t.Errorf("expected the Node's failure to be recorded, got %+v", current)
+	}
+
+	// another Node resolving the preset doesn't hide the failure
+	if err = r.setPresetCondition(context.TODO(), key, "node-b", nil); err != nil {
+		t.Fatal(err)
+	}
+	if current := condition(); current.Status != metav1.ConditionFalse {
+		t.Errorf("expected node-a's failure to be kept, got %+v", current)
+	}
+
+	unknown := powererrors.NewInvalidProfile("", "unknown frequency preset 'maxTurbo'")
+	if err = r.setPresetCondition(context.TODO(), key, "node-a", unknown); err != nil {
+		t.Fatal(err)
+	}
+	if current := condition(); current.Reason != powerv1.ReasonPresetUnresolvable {
+		t.Errorf("expected reason %s, got %s", powerv1.ReasonPresetUnresolvable, current.Reason)
+	}
+
+	if err = r.setPresetCondition(context.TODO(), key, "node-a", nil); err != nil {
+		t.Fatal(err)
+	}
+	if current := condition(); current.Status != metav1.ConditionTrue || current.Reason != powerv1.ReasonPresetResolved {
+		t.Errorf("expected the preset to be resolved once the failing Node resolves it, got %+v", current)
+	}
+}
+
+func TestExtendedResourceQuantity(t *testing.T) {
+	tcases := []struct {
+		testCase string
+		spec     powerv1.PowerProfileSpec
+		expected int64
+	}{
+		{"Test Case 1 - EPP default", powerv1.PowerProfileSpec{Epp: "performance"}, 16},
+		{"Test Case 2 - no EPP and no capacity", powerv1.PowerProfileSpec{Max: 2400, Min: 2000}, 0},
+		{"Test Case 3 - percent overrides EPP default", powerv1.PowerProfileSpec{Epp: "performance", Capacity: &powerv1.ProfileCapacity{Percent: 25}}, 10},
+		{"Test Case 4 - count overrides percent", powerv1.PowerProfileSpec{Capacity: &powerv1.ProfileCapacity{Percent: 25, Count: 6}}, 6},
+		{"Test Case 5 - count is capped at the Node's CPUs", powerv1.PowerProfileSpec{Capacity: &powerv1.ProfileCapacity{Count: 64}}, 40},
+	}
+
+	for _, tc := range tcases {
+		profile := &powerv1.PowerProfile{Spec: tc.spec}
+		quantity := capacity.Base(profile, 40)
+		if quantity != tc.expected {
+			t.Errorf("%s failed: expected %d extended resources, got %d", tc.testCase, tc.expected, quantity)
+		}
+	}
+}
+
+func TestCapacityQuantity(t *testing.T) {
+	profiles := []powerv1.PowerProfile{
+		{Spec: powerv1.PowerProfileSpec{Name: "performance", Epp: "performance"}},
+		{Spec: powerv1.PowerProfileSpec{Name: "burst", Capacity: &powerv1.ProfileCapacity{Count: 20, HeadroomPercent: 25}}},
+		{Spec: powerv1.PowerProfileSpec{Name: "capped", Capacity: &powerv1.ProfileCapacity{Count: 20}}},
+	}
+	node := capacity.Node{NumCPUs: 40, DomainShares: map[string]int{"capped": 8}}
+	tcases := []struct {
+		testCase     string
+		reservations map[string]capacity.Reservation
+		expected     map[string]int64
+	}{
+		{
+			testCase: "Test Case 1 - headroom held back and the failure domain's cap",
+			expected: map[string]int64{"performance": 16, "burst": 15, "capped": 8},
+		},
+		{
+			testCase:     "Test Case 2 - headroom lent out",
+			reservations: map[string]capacity.Reservation{"burst": {HeadroomLent: true}},
+			expected:     map[string]int64{"performance": 16, "burst": 20, "capped": 8},
+		},
+		{
+			testCase:     "Test Case 3 - withheld capacity is rounded up",
+			reservations: map[string]capacity.Reservation{"performance": {WithheldPercent: 10}, "capped": {WithheldPercent: 50}},
+			expected:     map[string]int64{"performance": 14, "burst": 15, "capped": 8},
+		},
+	}
+
+	for _, tc := range tcases {
+		capacities := make(map[string]int64, len(profiles))
+		for i := range profiles {
+			quantity, err := capacity.Quantity(&profiles[i], node, tc.reservations[profiles[i].Spec.Name])
+			if err != nil {
+				t.Fatalf("%s failed: %v", tc.testCase, err)
+			}
+			capacities[profiles[i].Spec.Name] = quantity
+		}
+		if !reflect.DeepEqual(capacities, tc.expected) {
+			t.Errorf("%s failed: expected capacities %v, got %v", tc.testCase, tc.expected, capacities)
+		}
+	}
+}
+
+func TestCapacityExpression(t *testing.T) {
+	node := capacity.Node{NumCPUs: 64, Facts: capacity.Facts{
+		Sockets:  2,
+		CPUModel: "Intel(R) Xeon(R) Platinum 8480+",
+		Labels:   map[string]string{"tier": "premium", "gold-cpus": "12"},
+	}}
+	tcases := []struct {
+		testCase   string
+		expression string
+		expected   int64
+		invalid    bool
+	}{
+		{"Test Case 1 - share of the cores", "cores * 40 / 100", 25, false},
+		{"Test Case 2 - per socket", "sockets * 8", 16, false},
+		{"Test Case 3 - by SKU", `cpuModel.contains("8480") ? cores / 2 : cores / 4`, 32, false},
+		{"Test Case 4 - from a label value", `"gold-cpus" in labels ? int(labels["gold-cpus"]) : 0`, 12, false},
+		{"Test Case 5 - capped at the Node's CPUs", "cores * 2", 64, false},
+		{"Test Case 6 - negative gives none", "sockets - 8", 0, false},
+		{"Test Case 7 - missing label", `int(labels["silver-cpus"])`, 0, true},
+		{"Test Case 8 - not an int", `labels["tier"]`, 0, true},
+		{"Test Case 9 - syntax error", "cores *", 0, true},
+	}
+
+	for _, tc := range tcases {
+		profile := &powerv1.PowerProfile{Spec: powerv1.PowerProfileSpec{
+			Name:     "gold",
+			Capacity: &powerv1.ProfileCapacity{Count: 4, Expression: tc.expression},
+		}}
+		quantity, err := capacity.Quantity(profile, node, capacity.Reservation{})
+		if tc.invalid {
+			if !powererrors.IsInvalidProfile(err) {
+				t.Errorf("%s failed: expected an invalid profile error, got %v", tc.testCase, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s failed: %v", tc.testCase, err)
+			continue
+		}
+		if quantity != tc.expected {
+			t.Errorf("%s failed: expected %d extended resources, got %d", tc.testCase, tc.expected, quantity)
+		}
+	}
+}
+
+func TestExtendedResourcesAdvertisement(t *testing.T) {
+	nodeName := "TestNode"
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeName,
+		},
+	}
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "power-config",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerConfigSpec{
+			ResourceAdvertisement: powerv1.AdvertiseNodeLabels,
+		},
+	}
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{
+			Name:     "performance",
+			Epp:      "performance",
+			Capacity: &powerv1.ProfileCapacity{Count: 1},
+		},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{node, config})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	logger := r.Log
+	resourceName := corev1.ResourceName(ExtendedResourcePrefix + "performance")
+	label := CapacityLabelPrefix + "performance"
+	getNode := func() *corev1.Node {
+		updated := &corev1.Node{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, updated); err != nil {
+			t.Fatalf("error retrieving Node: %v", err)
+		}
+		return updated
+	}
+
+	// labels only, the Node status is left alone
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, nil); err != nil {
+		t.Fatalf("error advertising with labels: %v", err)
+	}
+	updated := getNode()
+	if updated.Labels[label] != "1" {
+		t.Errorf("expected capacity label 1, got '%s'", updated.Labels[label])
+	}
+	if _, exists := updated.Status.Capacity[resourceName]; exists {
+		t.Errorf("expected no Extended Resource in the Node status")
+	}
+
+	// switching to the Node status drops the label
+	config.Spec.ResourceAdvertisement = powerv1.AdvertiseNodeStatus
+	if err = r.Client.Update(context.TODO(), config); err != nil {
+		t.Fatalf("error updating PowerConfig: %v", err)
+	}
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, nil); err != nil {
+		t.Fatalf("error advertising in the Node status: %v", err)
+	}
+	updated = getNode()
+	if _, exists := updated.Labels[label]; exists {
+		t.Errorf("expected the capacity label to be removed")
+	}
+	if quantity := updated.Status.Capacity[resourceName]; quantity.Value() != 1 {
+		t.Errorf("expected 1 Extended Resource in the Node status, got %d", quantity.Value())
+	}
+}
+
+func TestExtendedResourcesFromNodeCPUs(t *testing.T) {
+	nodeName := "TestNode"
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeName,
+		},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("96")},
+		},
+	}
+	config := &powerv1.PowerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "power-config",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerConfigSpec{
+			ResourceAdvertisement: powerv1.AdvertiseNodeLabels,
+		},
+	}
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{
+			Name:     "performance",
+			Epp:      "performance",
+			Capacity: &powerv1.ProfileCapacity{Percent: 25},
+		},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{node, config})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	logger := r.Log
+
+	// the share is of the CPUs the kubelet reports, not those of the agent's cpuset
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, nil); err != nil {
+		t.Fatalf("error advertising the capacity: %v", err)
+	}
+	updated := &corev1.Node{}
+	if err = r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, updated); err != nil {
+		t.Fatalf("error retrieving Node: %v", err)
+	}
+	if label := updated.Labels[CapacityLabelPrefix+"performance"]; label != "24" {
+		t.Errorf("expected capacity label 24, got '%s'", label)
+	}
+}
+
+func TestSchedulerExtenderFilter(t *testing.T) {
+	labelledNode := func(name string, capacity string) corev1.Node {
+		node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if capacity != "" {
+			node.Labels[CapacityLabelPrefix+"performance"] = capacity
+		}
+		return node
+	}
+	powerPod := func(name string, nodeName string, count int64) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+				Containers: []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceName(ExtendedResourcePrefix + "performance"): *resource.NewQuantity(count, resource.DecimalSI),
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	nodes := []corev1.Node{labelledNode("full", "2"), labelledNode("free", "4"), labelledNode("unlabelled", "")}
+	r, err := createProfileReconcilerObject([]runtime.Object{powerPod("running", "full", 2), powerPod("other", "free", 1)})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	server := &extender.Server{
+		Client:         r.Client,
+		Log:            r.Log,
+		ResourcePrefix: ExtendedResourcePrefix,
+		LabelPrefix:    CapacityLabelPrefix,
+	}
+
+	result, err := server.Filter(context.TODO(), &extender.Args{
+		Pod:   powerPod("pending", "", 2),
+		Nodes: &corev1.NodeList{Items: nodes},
+	})
+	if err != nil {
+		t.Fatalf("error filtering Nodes: %v", err)
+	}
+	if len(result.Nodes.Items) != 1 || result.Nodes.Items[0].Name != "free" {
+		t.Errorf("expected only Node 'free' to pass, got %v", result.Nodes.Items)
+	}
+	if result.FailedNodes["full"] != "Insufficient power.intel.com/performance" {
+		t.Errorf("unexpected reason for Node 'full': %s", result.FailedNodes["full"])
+	}
+	if _, failed := result.FailedNodes["unlabelled"]; !failed {
+		t.Errorf("expected Node 'unlabelled' to fail")
+	}
+
+	// Pods without PowerProfile resources go anywhere
+	result, err = server.Filter(context.TODO(), &extender.Args{
+		Pod:   &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "plain"}},
+		Nodes: &corev1.NodeList{Items: nodes},
+	})
+	if err != nil {
+		t.Fatalf("error filtering Nodes: %v", err)
+	}
+	if len(result.Nodes.Items) != len(nodes) {
+		t.Errorf("expected every Node to pass, got %d", len(result.Nodes.Items))
+	}
+}
+
+func TestBurstHeadroom(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tcases := []struct {
+		testCase         string
+		capacity         *powerv1.ProfileCapacity
+		lentUntil        string
+		expectedHeadroom int64
+		expectedLentFor  time.Duration
+	}{
+		{"Test Case 1 - no headroom", &powerv1.ProfileCapacity{Count: 10}, "", 0, 0},
+		{"Test Case 2 - headroom held back", &powerv1.ProfileCapacity{Count: 10, HeadroomPercent: 20}, "", 2, 0},
+		{"Test Case 3 - headroom lent out", &powerv1.ProfileCapacity{Count: 10, HeadroomPercent: 20}, "2026-10-16T12:05:00Z", 2, 5 * time.Minute},
+		{"Test Case 4 - lending has ended", &powerv1.ProfileCapacity{Count: 10, HeadroomPercent: 20}, "2026-10-16T11:55:00Z", 2, 0},
+		{"Test Case 5 - malformed lending time", &powerv1.ProfileCapacity{Count: 10, HeadroomPercent: 20}, "in five minutes", 2, 0},
+	}
+
+	for _, tc := range tcases {
+		profile := &powerv1.PowerProfile{Spec: powerv1.PowerProfileSpec{Capacity: tc.capacity}}
+		if tc.lentUntil != "" {
+			profile.Annotations = map[string]string{LendHeadroomUntilAnnotation: tc.lentUntil}
+		}
+
+		headroom := capacity.Headroom(profile, capacity.Base(profile, 40))
+		if headroom != tc.expectedHeadroom {
+			t.Errorf("%s failed: expected %d CPUs of headroom, got %d", tc.testCase, tc.expectedHeadroom, headroom)
+		}
+		lentFor := headroomLentFor(profile, now)
+		if lentFor != tc.expectedLentFor {
+			t.Errorf("%s failed: expected headroom lent for %v, got %v", tc.testCase, tc.expectedLentFor, lentFor)
+		}
+	}
+}
+
+func TestRealtimeProfile(t *testing.T) {
+	t.Setenv("NODE_NAME", "TestNode")
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "realtime",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{
+			Name:     "realtime",
+			Max:      3200,
+			Min:      2800,
+			Realtime: true,
+		},
+	}
+
+	// refused on a kernel that isn't PREEMPT_RT without touching the Power Library
+	r, err := createProfileReconcilerObject([]runtime.Object{profile})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	powerLibMock := new(hostMock)
+	r.PowerLibrary = powerLibMock
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(profile)}
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Errorf("expected a realtime profile on a non-RT kernel not to be retried, got %v", err)
+	}
+	powerLibMock.AssertNotCalled(t, "AddExclusivePool", mock.Anything)
+
+	originalReadBaseFrequency := readBaseFrequency
+	defer func() { readBaseFrequency = originalReadBaseFrequency }()
+	readBaseFrequency = func() (int, error) { return 2400, nil }
+	if freq := realtimeFrequency(3200); freq != 2400 {
+		t.Errorf("expected the frequency to be capped at the base frequency 2400, got %d", freq)
+	}
+	if freq := realtimeFrequency(2000); freq != 2000 {
+		t.Errorf("expected a max frequency below the base frequency to be kept, got %d", freq)
+	}
+	readBaseFrequency = func() (int, error) { return 0, fmt.Errorf("no base_frequency") }
+	if freq := realtimeFrequency(3200); freq != 3200 {
+		t.Errorf("expected the max frequency without a base frequency, got %d", freq)
+	}
+
+	states := realtimeCStates([]string{"POLL", "C1", "C1E", "C6"})
+	expected := power.CStates{"POLL": true, "C1": true, "C1E": false, "C6": false}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected C-states %v, got %v", expected, states)
+	}
+}
+
+func TestUnmanagedFrequencies(t *testing.T) {
+	unmanaged := false
+	tcases := []struct {
+		testCase    string
+		spec        powerv1.PowerProfileSpec
+		expectedMin int
+		expectedMax int
+	}{
+		{
+			testCase:    "Test Case 1 - both limits managed by default",
+			spec:        powerv1.PowerProfileSpec{Name: "performance", Epp: "performance", Max: 3000, Min: 2800},
+			expectedMin: 2800,
+			expectedMax: 3000,
+		},
+		{
+			testCase:    "Test Case 2 - max left to the governor",
+			spec:        powerv1.PowerProfileSpec{Name: "floor", Epp: "performance", Min: 2800, ManageMax: &unmanaged},
+			expectedMin: 2800,
+			expectedMax: 3700,
+		},
+		{
+			testCase:    "Test Case 3 - min left to the governor",
+			spec:        powerv1.PowerProfileSpec{Name: "ceiling", Epp: "power", Max: 2000, Min: 1900, ManageMin: &unmanaged},
+			expectedMin: 800,
+			expectedMax: 2000,
+		},
+	}
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		minimum, maximum := unmanagedFrequencies(&tc.spec, tc.spec.Min, tc.spec.Max, 800, 3700)
+		if minimum != tc.expectedMin || maximum != tc.expectedMax {
+			t.Errorf("%s - expected %d-%d, got %d-%d", tc.testCase, tc.expectedMin, tc.expectedMax, minimum, maximum)
+		}
+	}
+
+	// a fastpath profile fixes the frequency so it can't leave a limit unmanaged
+	t.Setenv("NODE_NAME", "TestNode")
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dpdk",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{Name: "dpdk", Epp: "performance", Max: 3000, Min: 3000, Fastpath: true, ManageMin: &unmanaged},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{profile})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	powerLibMock := new(hostMock)
+	r.PowerLibrary = powerLibMock
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(profile)})
+	if err != nil {
+		t.Errorf("expected an invalid profile not to be retried, got %v", err)
+	}
+	powerLibMock.AssertNotCalled(t, "AddExclusivePool", mock.Anything)
+}
+
+func TestChangeSummary(t *testing.T) {
+	nodeName := "TestNode"
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeName,
+		},
+	}
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{
+			Name:     "performance",
+			Epp:      "performance",
+			Capacity: &powerv1.ProfileCapacity{Count: 1},
+		},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{node})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	records := make([]string, 0)
+	logger := funcr.New(func(prefix, args string) {
+		records = append(records, args)
+	}, funcr.Options{})
+
+	changes := logging.NewChangeSummary()
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, changes); err != nil {
+		t.Fatalf("error creating Extended Resources: %v", err)
+	}
+	changes.PoolModified("performance", []uint{1, 2}, nil)
+	changes.Log(logger)
+	if len(records) != 1 {
+		t.Fatalf("expected one record, got %d: %v", len(records), records)
+	}
+	for _, expected := range []string{`"nodesTouched"=["TestNode"]`, `"resourcesAdded"=["ExtendedResource/power.intel.com/performance"]`, `"cpusAdded":[1,2]`} {
+		if !strings.Contains(records[0], expected) {
+			t.Errorf("expected record to contain %s, got %s", expected, records[0])
+		}
+	}
+
+	// advertising the same capacity again changes nothing, so nothing is logged
+	records = records[:0]
+	changes = logging.NewChangeSummary()
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, changes); err != nil {
+		t.Fatalf("error creating Extended Resources: %v", err)
+	}
+	changes.Log(logger)
+	if len(records) != 0 {
+		t.Errorf("expected no record for an unchanged Node, got %v", records)
+	}
+}
+
+func TestExtendedResourcesRestoration(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeName,
+		},
+	}
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerProfileSpec{
+			Name:     "performance",
+			Epp:      "performance",
+			Capacity: &powerv1.ProfileCapacity{Count: 1},
+		},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{node})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	logger := r.Log
+	getNode := func() *corev1.Node {
+		updated := &corev1.Node{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, updated); err != nil {
+			t.Fatalf("error retrieving Node: %v", err)
+		}
+		return updated
+	}
+	restored := func() float64 {
+		return testutil.ToFloat64(metrics.ExtendedResourcesRestored.WithLabelValues(nodeName, "performance"))
+	}
+
+	// nothing advertised yet, so nothing is missing
+	if requests := r.nodeCapacityRequests(getNode()); len(requests) != 0 {
+		t.Errorf("expected no requests before advertising, got %v", requests)
+	}
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, nil); err != nil {
+		t.Fatalf("error creating Extended Resources: %v", err)
+	}
+	if requests := r.nodeCapacityRequests(getNode()); len(requests) != 0 {
+		t.Errorf("expected no requests while the capacity is intact, got %v", requests)
+	}
+
+	// a kubelet restart wipes the Extended Resource
+	wiped := getNode()
+	wiped.Status.Capacity = corev1.ResourceList{}
+	if err = r.Client.Status().Update(context.TODO(), wiped); err != nil {
+		t.Fatalf("error wiping Node capacity: %v", err)
+	}
+	requests := r.nodeCapacityRequests(getNode())
+	expected := []reconcile.Request{{NamespacedName: client.ObjectKey{Name: "performance", Namespace: IntelPowerNamespace}}}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected %v, got %v", expected, requests)
+	}
+	otherNode := getNode()
+	otherNode.Name = "OtherNode"
+	if requests := r.nodeCapacityRequests(otherNode); len(requests) != 0 {
+		t.Errorf("expected other Nodes to be ignored, got %v", requests)
+	}
+
+	before := restored()
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, nil); err != nil {
+		t.Fatalf("error restoring Extended Resources: %v", err)
+	}
+	if quantity := getNode().Status.Capacity[corev1.ResourceName(ExtendedResourcePrefix+"performance")]; quantity.Value() != 1 {
+		t.Errorf("expected the Extended Resource to be restored, got %d", quantity.Value())
+	}
+	if restored()-before != 1 {
+		t.Errorf("expected one restoration to be counted, got %v", restored()-before)
+	}
+
+	// removed profiles are no longer restored
+	if err = r.removeExtendedResources(context.TODO(), nodeName, "performance", &logger, nil); err != nil {
+		t.Fatalf("error removing Extended Resources: %v", err)
+	}
+	if requests := r.nodeCapacityRequests(getNode()); len(requests) != 0 {
+		t.Errorf("expected no requests after the profile was removed, got %v", requests)
+	}
+}
+
+func TestApplyEffectiveProfile(t *testing.T) {
+	nodeName := "TestNode"
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+		Status: powerv1.PowerNodeStatus{
+			EffectiveProfiles: []powerv1.EffectiveProfile{
+				{Name: "performance", Source: powerv1.EffectiveSourceCluster, ProfilePolicy: powerv1.ProfilePolicy{Epp: "performance"}},
+				{Name: "gold", Source: "NodeGroup/large", ProfilePolicy: powerv1.ProfilePolicy{Max: 3600, Min: 2000, Governor: "powersave"}},
+			},
+		},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{powerNode})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	logger := r.Log
+
+	gold := &powerv1.PowerProfile{Spec: powerv1.PowerProfileSpec{Name: "gold", Max: 3000, Min: 2000, Governor: "performance"}}
+	if _, err = r.applyEffectiveProfile(context.TODO(), nodeName, gold, &logger); err != nil {
+		t.Fatalf("error applying effective profile: %v", err)
+	}
+	expected := powerv1.PowerProfileSpec{Name: "gold", Max: 3600, Min: 2000, Governor: "powersave"}
+	if !reflect.DeepEqual(gold.Spec, expected) {
+		t.Errorf("expected the node group overrides %v, got %v", expected, gold.Spec)
+	}
+
+	performance := &powerv1.PowerProfile{Spec: powerv1.PowerProfileSpec{Name: "performance", Epp: "performance"}}
+	if _, err = r.applyEffectiveProfile(context.TODO(), nodeName, performance, &logger); err != nil {
+		t.Fatalf("error applying effective profile: %v", err)
+	}
+	expected = powerv1.PowerProfileSpec{Name: "performance", Epp: "performance"}
+	if !reflect.DeepEqual(performance.Spec, expected) {
+		t.Errorf("expected the cluster settings %v, got %v", expected, performance.Spec)
+	}
+
+	// Nodes without a PowerNode yet keep the cluster settings
+	offered, err := r.applyEffectiveProfile(context.TODO(), "OtherNode", gold, &logger)
+	if err != nil || !offered {
+		t.Errorf("expected a missing PowerNode to be ignored, got %v", err)
+	}
+
+	// Nodes in PowerNodeGroups are only offered the PowerProfiles with an effective profile
+	powerNode.Status.NodeGroups = []string{"large"}
+	if err = r.Client.Status().Update(context.TODO(), powerNode); err != nil {
+		t.Fatalf("error updating the PowerNode: %v", err)
+	}
+	silver := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{PowerConfigProfileLabel: "power-config"}},
+		Spec:       powerv1.PowerProfileSpec{Name: "silver", Max: 2400, Min: 1800},
+	}
+	offered, err = r.applyEffectiveProfile(context.TODO(), nodeName, silver, &logger)
+	if err != nil || offered {
+		t.Errorf("expected silver not to be offered to the node group, got %v, %v", offered, err)
+	}
+	offered, err = r.applyEffectiveProfile(context.TODO(), nodeName, gold, &logger)
+	if err != nil || !offered {
+		t.Errorf("expected gold to be offered to the node group, got %v, %v", offered, err)
+	}
+}
+
+func TestCpuDefaults(t *testing.T) {
+	for _, channel := range cpudefaults.Channels() {
+		database, err := cpudefaults.Load(channel)
+		if err != nil {
+			t.Fatalf("error loading channel %s: %v", channel, err)
+		}
+		for _, model := range database.Models {
+			for epp, frequencies := range model.Profiles {
+				if _, exists := eppDefaults[epp]; !exists || epp == "power" {
+					t.Errorf("%s %s: EPP value '%s' can't have defaults", channel, model.Name, epp)
+				}
+				if frequencies.Max < frequencies.Min || frequencies.Min <= 0 {
+					t.Errorf("%s %s: invalid frequencies for '%s': %+v", channel, model.Name, epp, frequencies)
+				}
+				if _, exists := eppDefaults[frequencies.Epp]; (epp == "") != (frequencies.Epp != "" && exists) {
+					t.Errorf("%s %s: only the defaults for profiles without an EPP value give one, got '%s' for '%s'", channel, model.Name, frequencies.Epp, epp)
+				}
+			}
+		}
+	}
+	if _, err := cpudefaults.Load("nightly"); err == nil {
+		t.Error("expected an unknown channel to fail")
+	}
+
+	cpuInfo := t.TempDir() + "/cpuinfo"
+	originalCpuInfo := cpudefaults.CpuInfoFile
+	cpudefaults.CpuInfoFile = cpuInfo
+	defer func() { cpudefaults.CpuInfoFile = originalCpuInfo }()
+	database, err := cpudefaults.Load(cpudefaults.DefaultChannel)
+	if err != nil {
+		t.Fatalf("error loading the default channel: %v", err)
+	}
+	expected, _ := database.Lookup(6, 143)
+
+	tcases := []struct {
+		testCase      string
+		cpuInfo       string
+		defaults      *cpudefaults.Database
+		spec          powerv1.PowerProfileSpec
+		expectedSpec  powerv1.PowerProfileSpec
+		expectedError bool
+	}{
+		{
+			testCase:     "Test Case 1 - EPP named profile",
+			cpuInfo:      "processor\t: 0\ncpu family\t: 6\nmodel\t\t: 143\n",
+			defaults:     database,
+			spec:         powerv1.PowerProfileSpec{Name: "performance", Epp: "performance", MaxPreset: cpudefaults.Auto},
+			expectedSpec: powerv1.PowerProfileSpec{Name: "performance", Epp: "performance", MaxPreset: cpudefaults.Auto, Max: expected.Profiles["performance"].Max, Min: expected.Profiles["performance"].Min},
+		},
+		{
+			testCase:     "Test Case 2 - profile without EPP value keeps its min frequency",
+			cpuInfo:      "processor\t: 0\ncpu family\t: 6\nmodel\t\t: 143\n",
+			defaults:     database,
+			spec:         powerv1.PowerProfileSpec{Name: "gold", MaxPreset: cpudefaults.Auto, Min: 1500},
+			expectedSpec: powerv1.PowerProfileSpec{Name: "gold", Epp: expected.Profiles[""].Epp, MaxPreset: cpudefaults.Auto, Max: expected.Profiles[""].Max, Min: 1500},
+		},
+		{
+			testCase:      "Test Case 3 - unknown CPU model",
+			cpuInfo:       "processor\t: 0\ncpu family\t: 6\nmodel\t\t: 1\n",
+			defaults:      database,
+			spec:          powerv1.PowerProfileSpec{Name: "performance", Epp: "performance", MaxPreset: cpudefaults.Auto},
+			expectedError: true,
+		},
+		{
+			testCase:      "Test Case 4 - no defaults loaded",
+			cpuInfo:       "processor\t: 0\ncpu family\t: 6\nmodel\t\t: 143\n",
+			spec:          powerv1.PowerProfileSpec{Name: "performance", Epp: "performance", MaxPreset: cpudefaults.Auto},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		if err = os.WriteFile(cpuInfo, []byte(tc.cpuInfo), 0644); err != nil {
+			t.Fatalf("error writing cpuinfo: %v", err)
+		}
+		r := &PowerProfileReconciler{CpuDefaults: tc.defaults}
+		profile := &powerv1.PowerProfile{Spec: tc.spec}
+		err = r.applyCpuDefaults(profile)
+		if tc.expectedError {
+			if err == nil {
+				t.Errorf("%s - expected an error", tc.testCase)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s - unexpected error: %v", tc.testCase, err)
+		}
+		if !reflect.DeepEqual(profile.Spec, tc.expectedSpec) {
+			t.Errorf("%s - expected %+v, got %+v", tc.testCase, tc.expectedSpec, profile.Spec)
+		}
+	}
+}
+
+func TestPatchNodeCapacity(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "TestNode",
+			Labels: make(map[string]string),
+		},
+	}
+	for i := 0; i < 500; i++ {
+		node.Labels[fmt.Sprintf("feature.node.kubernetes.io/label-%d", i)] = "true"
+	}
+	schm := runtime.NewScheme()
+	if err := scheme.AddToScheme(schm); err != nil {
+		t.Fatal(err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(schm).WithObjects(node).Build()
+	resourceName := corev1.ResourceName(ExtendedResourcePrefix + "performance")
+
+	patch := func(quantity *resource.Quantity) {
+		if err := patchNodeCapacity(context.TODO(), cl, node, resourceName, quantity); err != nil {
+			t.Fatalf("error patching the Node's capacity: %v", err)
+		}
+		if err := cl.Get(context.TODO(), client.ObjectKeyFromObject(node), node); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a Node without any capacity yet
+	quantity := resource.MustParse("4")
+	patch(&quantity)
+	if current := node.Status.Capacity[resourceName]; !current.Equal(quantity) {
+		t.Errorf("expected capacity %s, got %s", quantity.String(), current.String())
+	}
+	if len(node.Labels) != 500 {
+		t.Errorf("expected the labels to be left alone, got %d", len(node.Labels))
+	}
+
+	// capacity other controllers own is left alone
+	node.Status.Capacity[corev1.ResourceCPU] = resource.MustParse("8")
+	if err := cl.Status().Update(context.TODO(), node); err != nil {
+		t.Fatal(err)
+	}
+	quantity = resource.MustParse("2")
+	patch(&quantity)
+	if current := node.Status.Capacity[resourceName]; !current.Equal(quantity) {
+		t.Errorf("expected capacity %s, got %s", quantity.String(), current.String())
+	}
+	if cpus := node.Status.Capacity[corev1.ResourceCPU]; cpus.Value() != 8 {
+		t.Errorf("expected the CPU capacity to be left alone, got %s", cpus.String())
+	}
+
+	patch(nil)
+	if _, exists := node.Status.Capacity[resourceName]; exists {
+		t.Error("expected the Extended Resource to be removed")
+	}
+	if _, exists := node.Status.Capacity[corev1.ResourceCPU]; !exists {
+		t.Error("expected the CPU capacity to be left alone")
+	}
+
+	if _, near := objectsize.NearLimit(node); near {
+		t.Error("expected a Node with 500 labels to be well within the etcd limit")
+	}
+	for i := 0; i < 20000; i++ {
+		node.Labels[fmt.Sprintf("feature.node.kubernetes.io/extra-label-%d", i)] = strings.Repeat("x", 50)
+	}
+	if size, near := objectsize.NearLimit(node); !near {
+		t.Errorf("expected a Node of %d bytes to be near the etcd limit", size)
+	}
+}
+
+func TestExtendedResourcesDomainCapacity(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "gold", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerProfileSpec{Name: "gold", Epp: "performance", Capacity: &powerv1.ProfileCapacity{Count: 1}},
+	}
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: IntelPowerNamespace},
+		Status:     powerv1.PowerNodeStatus{DomainCapacity: map[string]int{"gold": 0, "silver": 4}},
+	}
+	r, err := createProfileReconcilerObject([]runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}},
+		powerNode,
+	})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	logger := r.Log
+	capacity := func() int64 {
+		node := &corev1.Node{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, node); err != nil {
+			t.Fatalf("error retrieving Node: %v", err)
+		}
+		quantity := node.Status.Capacity[corev1.ResourceName(ExtendedResourcePrefix+"gold")]
+		return quantity.Value()
+	}
+
+	// the rest of the failure domain already advertises all of its gold capacity
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, nil); err != nil {
+		t.Fatalf("error creating Extended Resources: %v", err)
+	}
+	if got := capacity(); got != 0 {
+		t.Errorf("expected the capacity to be capped at the Node's share of 0, got %d", got)
+	}
+
+	// a share above the profile's own capacity doesn't raise it
+	powerNode.Status.DomainCapacity["gold"] = 8
+	if err = r.Client.Status().Update(context.TODO(), powerNode); err != nil {
+		t.Fatalf("error updating PowerNode: %v", err)
+	}
+	if err = r.createExtendedResources(context.TODO(), nodeName, profile, 0, &logger, nil); err != nil {
+		t.Fatalf("error creating Extended Resources: %v", err)
+	}
+	if got := capacity(); got != 1 {
+		t.Errorf("expected the profile's capacity of 1, got %d", got)
+	}
+}
@@ -0,0 +1,336 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetry"
+)
+
+// SharedPoolInterferenceReason is the reason of the event recorded when an exclusive pool's frequency drops
+// during a Shared pool spike
+const SharedPoolInterferenceReason = "SharedPoolInterference"
+
+// PowerRecommendationReconciler samples the utilization of the cores in every pool on this Node and
+// periodically publishes, per PowerProfile, the lowest max frequency that would still have covered
+// the recorded demand as a PowerRecommendation
+type PowerRecommendationReconciler struct {
+	client.Client
+	Log            logr.Logger
+	PowerLibrary   power.Host
+	Source         telemetry.Source
+	Window         *telemetry.Window
+	SampleInterval time.Duration
+	ReportInterval time.Duration
+	// Fraction of the recorded demand the recommended frequency has to cover, e.g. 0.99
+	Percentile float64
+	// Interference, when set, watches the exclusive pools for frequency drops during Shared pool spikes
+	Interference *telemetry.InterferenceDetector
+	Recorder     record.EventRecorder
+	// DetectBusyPoll also leaves cores that look to be polling out of the recommendations and interference
+	// detection, not just the fastpath PowerProfiles' pools and the CPUs of Pods annotated as fastpath
+	DetectBusyPoll bool
+	// accountedPools are the pools with interrupt accounting published, for removing the metrics of pools that are gone
+	accountedPools map[string]bool
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerrecommendations,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerrecommendations/status,verbs=get;update;patch
+
+// Start samples and reports on their intervals until the context is cancelled
+func (r *PowerRecommendationReconciler) Start(ctx context.Context) error {
+	sampleTicker := time.NewTicker(r.SampleInterval)
+	defer sampleTicker.Stop()
+	reportTicker := time.NewTicker(r.ReportInterval)
+	defer reportTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-sampleTicker.C:
+			err := r.Sample(ctx, now)
+			if err != nil {
+				r.Log.Error(err, "error sampling core utilization")
+			}
+		case now := <-reportTicker.C:
+			err := r.Report(ctx, now)
+			if err != nil {
+				r.Log.Error(err, "error publishing PowerRecommendations")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent records its own Node
+func (r *PowerRecommendationReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Sample records the demand of the cores in each pool against the pool's PowerProfile and publishes the
+// pool's interrupt and context switch accounting, and any Shared pool interference. Fastpath cores are
+// accounted but not recorded, their polling would read as demand
+func (r *PowerRecommendationReconciler) Sample(ctx context.Context, now time.Time) error {
+	nodeName := os.Getenv("NODE_NAME")
+	accounted := make(map[string]bool)
+	results := new(multierror.Error)
+	fastpathProfiles, fastpathCores, err := r.fastpath(ctx, nodeName)
+	if err != nil {
+		return err
+	}
+	sharedPool := r.PowerLibrary.GetSharedPool()
+	var sharedSamples []telemetry.Sample
+	exclusiveSamples := make(map[string][]telemetry.Sample)
+	for _, pool := range r.profiledPools() {
+		samples, err := r.Source.Read(pool.Cpus().IDs())
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("sampling pool %s: %w", pool.Name(), err))
+			continue
+		}
+		profileName := pool.GetPowerProfile().Name()
+		if fastpathProfiles[profileName] {
+			// the whole pool polls, there is nothing to recommend or interfere with
+			r.Window.Remove(profileName)
+		} else {
+			tunable := r.tunableSamples(samples, fastpathCores)
+			r.Window.Add(profileName, now, tunable)
+			if pool == sharedPool {
+				sharedSamples = tunable
+			} else {
+				exclusiveSamples[pool.Name()] = tunable
+			}
+		}
+
+		if len(samples) == 0 {
+			continue
+		}
+		accounting := telemetry.Accounting(samples)
+		metrics.PoolIRQRatio.WithLabelValues(nodeName, pool.Name()).Set(accounting.IRQ)
+		metrics.PoolSoftIRQRatio.WithLabelValues(nodeName, pool.Name()).Set(accounting.SoftIRQ)
+		metrics.PoolContextSwitchRate.WithLabelValues(nodeName, pool.Name()).Set(accounting.ContextSwitchRate)
+		accounted[pool.Name()] = true
+	}
+	for pool := range r.accountedPools {
+		if !accounted[pool] {
+			metrics.PoolIRQRatio.DeleteLabelValues(nodeName, pool)
+			metrics.PoolSoftIRQRatio.DeleteLabelValues(nodeName, pool)
+			metrics.PoolContextSwitchRate.DeleteLabelValues(nodeName, pool)
+		}
+	}
+	r.accountedPools = accounted
+	if r.Interference != nil {
+		r.detectInterference(ctx, nodeName, sharedSamples, exclusiveSamples)
+	}
+
+	return results.ErrorOrNil()
+}
+
+// fastpath returns the fastpath PowerProfiles and the exclusive CPUs of the fastpath containers on this Node
+func (r *PowerRecommendationReconciler) fastpath(ctx context.Context, nodeName string) (map[string]bool, map[uint]bool, error) {
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(ctx, profiles, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, nil, err
+	}
+	fastpathProfiles := make(map[string]bool)
+	for _, profile := range profiles.Items {
+		if profile.Spec.Fastpath {
+			fastpathProfiles[profile.Spec.Name] = true
+		}
+	}
+
+	workloads := &powerv1.PowerWorkloadList{}
+	err = r.Client.List(ctx, workloads, client.InNamespace(IntelPowerNamespace), client.MatchingFields{WorkloadNodeNameIndex: nodeName})
+	if err != nil {
+		return nil, nil, err
+	}
+	fastpathCores := make(map[uint]bool)
+	for _, workload := range workloads.Items {
+		for _, container := range workload.Spec.Node.Containers {
+			if !container.Fastpath {
+				continue
+			}
+			for _, cpu := range container.ExclusiveCPUs {
+				fastpathCores[cpu] = true
+			}
+		}
+	}
+
+	return fastpathProfiles, fastpathCores, nil
+}
+
+// tunableSamples leaves out the samples of fastpath cores, and of polling cores if busy-poll detection is on
+func (r *PowerRecommendationReconciler) tunableSamples(samples []telemetry.Sample, fastpathCores map[uint]bool) []telemetry.Sample {
+	tunable := make([]telemetry.Sample, 0, len(samples))
+	for _, sample := range samples {
+		if fastpathCores[sample.Core] || (r.DetectBusyPoll && sample.Polling()) {
+			continue
+		}
+		tunable = append(tunable, sample)
+	}
+
+	return tunable
+}
+
+// detectInterference compares each exclusive pool's frequency with its baseline while the Shared pool spikes,
+// counting and recording an event for each drop
+func (r *PowerRecommendationReconciler) detectInterference(ctx context.Context, nodeName string, shared []telemetry.Sample, exclusive map[string][]telemetry.Sample) {
+	for _, pool := range r.Interference.Pools() {
+		if _, exists := exclusive[pool]; !exists {
+			r.Interference.Forget(pool)
+			metrics.PoolSharedInterference.DeleteLabelValues(nodeName, pool)
+		}
+	}
+
+	for pool, samples := range exclusive {
+		interference, detected := r.Interference.Observe(pool, shared, samples)
+		if !detected {
+			continue
+		}
+		metrics.PoolSharedInterference.WithLabelValues(nodeName, pool).Inc()
+		message := fmt.Sprintf("Pool '%s' dropped from %dMHz to %dMHz while the Shared pool was %.0f%% busy, "+
+			"the Shared pool's load is likely limiting its turbo", pool, interference.BaselineFrequency,
+			interference.Frequency, interference.SharedUtilization*100)
+		r.Log.Info(message)
+		r.recordInterference(ctx, nodeName, message)
+	}
+}
+
+func (r *PowerRecommendationReconciler) recordInterference(ctx context.Context, nodeName string, message string) {
+	if r.Recorder == nil {
+		return
+	}
+
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Name:      nodeName,
+		Namespace: IntelPowerNamespace,
+	}, powerNode)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			r.Log.Error(err, "error retrieving PowerNode for interference event")
+		}
+		return
+	}
+
+	r.Recorder.Event(powerNode, corev1.EventTypeWarning, SharedPoolInterferenceReason, message)
+}
+
+// Report creates or updates a PowerRecommendation for every PowerProfile with recorded demand
+func (r *PowerRecommendationReconciler) Report(ctx context.Context, now time.Time) error {
+	logger := r.Log.WithName("report")
+	nodeName := os.Getenv("NODE_NAME")
+
+	profiles := make(map[string]power.Profile)
+	for _, pool := range r.profiledPools() {
+		profiles[pool.GetPowerProfile().Name()] = pool.GetPowerProfile()
+	}
+
+	results := new(multierror.Error)
+	for _, profileName := range r.Window.Keys() {
+		profile, exists := profiles[profileName]
+		if !exists {
+			// the profile is gone from this Node, so is anything recorded against it
+			r.Window.Remove(profileName)
+			continue
+		}
+
+		histogram, coverage := r.Window.Histogram(profileName, now)
+		recommendation := telemetry.Recommend(histogram, profile.MaxFreq(), profile.MinFreq(), r.Percentile)
+		logger.V(5).Info("Computed frequency recommendation", "profile", profileName, "current", recommendation.CurrentMax, "recommended", recommendation.RecommendedMax)
+
+		err := r.publish(ctx, nodeName, profileName, recommendation, coverage, now)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("publishing recommendation for %s: %w", profileName, err))
+		}
+	}
+
+	return results.ErrorOrNil()
+}
+
+func (r *PowerRecommendationReconciler) publish(ctx context.Context, nodeName string, profileName string, recommendation telemetry.Recommendation, coverage time.Duration, now time.Time) error {
+	name := fmt.Sprintf("%s-%s", profileName, nodeName)
+	powerRecommendation := &powerv1.PowerRecommendation{}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Name:      name,
+		Namespace: IntelPowerNamespace,
+	}, powerRecommendation)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		powerRecommendation = &powerv1.PowerRecommendation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: IntelPowerNamespace,
+			},
+			Spec: powerv1.PowerRecommendationSpec{
+				NodeName:     nodeName,
+				PowerProfile: profileName,
+			},
+		}
+		err = r.Client.Create(ctx, powerRecommendation)
+		if err != nil {
+			return err
+		}
+	}
+
+	powerRecommendation.Status = powerv1.PowerRecommendationStatus{
+		CurrentMax:       int(recommendation.CurrentMax),
+		RecommendedMax:   int(recommendation.RecommendedMax),
+		Percentile:       strconv.FormatFloat(r.Percentile, 'f', -1, 64),
+		PercentileDemand: int(recommendation.PercentileValue),
+		Samples:          int64(recommendation.Samples),
+		Window:           coverage.Round(time.Minute).String(),
+		Message:          recommendation.Message(profileName, r.Percentile),
+		LastUpdated:      metav1.NewTime(now),
+	}
+
+	return r.Client.Status().Update(ctx, powerRecommendation)
+}
+
+// profiledPools returns the Shared and exclusive pools that currently have a PowerProfile applied
+func (r *PowerRecommendationReconciler) profiledPools() []power.Pool {
+	pools := make([]power.Pool, 0)
+	if sharedPool := r.PowerLibrary.GetSharedPool(); sharedPool.GetPowerProfile() != nil {
+		pools = append(pools, sharedPool)
+	}
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		if pool.GetPowerProfile() != nil {
+			pools = append(pools, pool)
+		}
+	}
+
+	return pools
+}
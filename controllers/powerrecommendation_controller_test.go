@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetry"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeTelemetrySource struct {
+	samples map[uint]telemetry.Sample
+}
+
+func (f *fakeTelemetrySource) Read(cores []uint) ([]telemetry.Sample, error) {
+	samples := make([]telemetry.Sample, 0)
+	for _, core := range cores {
+		if sample, exists := f.samples[core]; exists {
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples, nil
+}
+
+func buildPowerRecommendationReconcilerObject(objs []runtime.Object, powerLibMock power.Host, source telemetry.Source) *PowerRecommendationReconciler {
+	schm := runtime.NewScheme()
+	err := powerv1.AddToScheme(schm)
+	if err != nil {
+		return nil
+	}
+	client := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(schm).
+		WithIndex(&powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer).Build()
+	reconciler := &PowerRecommendationReconciler{
+		Client:       client,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Source:       source,
+		Window:       telemetry.NewWindow(time.Hour, 7*24*time.Hour),
+		Percentile:   0.99,
+	}
+
+	return reconciler
+}
+
+func TestPowerRecommendationReconciler_Report(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	core2 := new(coreMock)
+	core2.On("GetID").Return(uint(2))
+	core3 := new(coreMock)
+	core3.On("GetID").Return(uint(3))
+
+	profile := new(profMock)
+	profile.On("Name").Return("performance")
+	profile.On("MaxFreq").Return(uint(3600))
+	profile.On("MinFreq").Return(uint(1000))
+
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{core2, core3})
+	performancePool.On("GetPowerProfile").Return(profile)
+	sharedPool := new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(nil)
+
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool})
+
+	// both cores only ever need half of the max frequency
+	source := &fakeTelemetrySource{samples: map[uint]telemetry.Sample{
+		2: {Core: 2, Utilization: 0.5, Frequency: 3600},
+		3: {Core: 3, Utilization: 0.4, Frequency: 3600},
+	}}
+
+	r := buildPowerRecommendationReconcilerObject(nil, powerLibMock, source)
+	assert.NotNil(t, r)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, r.Sample(context.TODO(), now.Add(time.Duration(i)*time.Minute)))
+	}
+	assert.NoError(t, r.Report(context.TODO(), now.Add(10*time.Minute)))
+
+	recommendation := &powerv1.PowerRecommendation{}
+	err := r.Client.Get(context.TODO(), client.ObjectKey{
+		Name:      "performance-TestNode",
+		Namespace: IntelPowerNamespace,
+	}, recommendation)
+	assert.NoError(t, err)
+	assert.Equal(t, nodeName, recommendation.Spec.NodeName)
+	assert.Equal(t, 3600, recommendation.Status.CurrentMax)
+	assert.Equal(t, 1800, recommendation.Status.RecommendedMax)
+	assert.Equal(t, int64(20), recommendation.Status.Samples)
+
+	// once the profile is gone from the Node its data is dropped
+	powerLibMock = new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{})
+	r.PowerLibrary = powerLibMock
+	assert.NoError(t, r.Report(context.TODO(), now.Add(20*time.Minute)))
+	assert.Empty(t, r.Window.Keys())
+}
+
+func TestPowerRecommendationReconciler_Accounting(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	core2 := new(coreMock)
+	core2.On("GetID").Return(uint(2))
+	core3 := new(coreMock)
+	core3.On("GetID").Return(uint(3))
+
+	profile := new(profMock)
+	profile.On("Name").Return("performance")
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{core2, core3})
+	performancePool.On("GetPowerProfile").Return(profile)
+	sharedPool := new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(nil)
+
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool})
+
+	// core 3 is taking a NIC's interrupts
+	source := &fakeTelemetrySource{samples: map[uint]telemetry.Sample{
+		2: {Core: 2, Utilization: 0.9, Frequency: 3600, ContextSwitchRate: 10},
+		3: {Core: 3, Utilization: 0.9, Frequency: 3600, IRQ: 0.1, SoftIRQ: 0.3, ContextSwitchRate: 2000},
+	}}
+	r := buildPowerRecommendationReconcilerObject(nil, powerLibMock, source)
+	assert.NotNil(t, r)
+
+	assert.NoError(t, r.Sample(context.TODO(), time.Now()))
+	assert.InDelta(t, 0.05, testutil.ToFloat64(metrics.PoolIRQRatio.WithLabelValues(nodeName, "performance")), 1e-9)
+	assert.InDelta(t, 0.15, testutil.ToFloat64(metrics.PoolSoftIRQRatio.WithLabelValues(nodeName, "performance")), 1e-9)
+	assert.InDelta(t, 1005, testutil.ToFloat64(metrics.PoolContextSwitchRate.WithLabelValues(nodeName, "performance")), 1e-9)
+
+	// the metrics of a pool that is gone are removed
+	powerLibMock = new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{})
+	r.PowerLibrary = powerLibMock
+	assert.NoError(t, r.Sample(context.TODO(), time.Now()))
+	assert.False(t, metrics.PoolIRQRatio.DeleteLabelValues(nodeName, "performance"))
+}
+
+func TestTelemetryReaderInterrupts(t *testing.T) {
+	dir := t.TempDir()
+	freqPath := filepath.Join(dir, "cpu%d_freq")
+	assert.NoError(t, os.WriteFile(fmt.Sprintf(freqPath, 0), []byte("3000000\n"), 0644))
+	reader := telemetry.NewReader()
+	reader.ProcStatFile = filepath.Join(dir, "stat")
+	reader.CpuFreqPath = freqPath
+	reader.SchedStatFile = filepath.Join(dir, "schedstat")
+
+	write := func(stat string, schedstat string) {
+		assert.NoError(t, os.WriteFile(reader.ProcStatFile, []byte(stat), 0644))
+		assert.NoError(t, os.WriteFile(reader.SchedStatFile, []byte(schedstat), 0644))
+	}
+	//                     user nice system idle iowait irq softirq steal
+	write("cpu  100 0 100 800 0 0 0 0\ncpu0 100 0 100 800 0 0 0 0\n",
+		"version 15\ntimestamp 100\ncpu0 0 0 5000 100 0 0 0 0 0\ndomain0 1 0 0 0\n")
+	samples, err := reader.Read([]uint{0})
+	assert.NoError(t, err)
+	assert.Empty(t, samples)
+
+	// two seconds later, at 100 ticks a second
+	write("cpu  150 0 150 850 0 0 0 0\ncpu0 150 0 150 850 0 20 30 0\n",
+		"version 15\ntimestamp 300\ncpu0 0 0 7000 100 0 0 0 0 0\ndomain0 1 0 0 0\n")
+	samples, err = reader.Read([]uint{0})
+	assert.NoError(t, err)
+	assert.Len(t, samples, 1)
+	assert.InDelta(t, 0.1, samples[0].IRQ, 1e-9)
+	assert.InDelta(t, 0.15, samples[0].SoftIRQ, 1e-9)
+	assert.InDelta(t, 1000, samples[0].ContextSwitchRate, 1e-9)
+	assert.Equal(t, uint(3000), samples[0].Frequency)
+
+	// kernels without schedstats still report interrupt time
+	assert.NoError(t, os.Remove(reader.SchedStatFile))
+	assert.NoError(t, os.WriteFile(reader.ProcStatFile, []byte("cpu0 200 0 200 900 0 40 60 0\n"), 0644))
+	samples, err = reader.Read([]uint{0})
+	assert.NoError(t, err)
+	assert.Len(t, samples, 1)
+	assert.InDelta(t, 0.1, samples[0].IRQ, 1e-9)
+	assert.Zero(t, samples[0].ContextSwitchRate)
+}
+
+func TestPowerRecommendationReconciler_SharedInterference(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	cores := make([]*coreMock, 4)
+	for i := range cores {
+		cores[i] = new(coreMock)
+		cores[i].On("GetID").Return(uint(i))
+	}
+	sharedProfile := new(profMock)
+	sharedProfile.On("Name").Return("shared")
+	sharedPool := new(poolMock)
+	sharedPool.On("Name").Return("sharedPool")
+	sharedPool.On("Cpus").Return(&power.CpuList{cores[0], cores[1]})
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	profile := new(profMock)
+	profile.On("Name").Return("performance")
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{cores[2], cores[3]})
+	performancePool.On("GetPowerProfile").Return(profile)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool})
+
+	source := &fakeTelemetrySource{}
+	sample := func(sharedUtilization float64, exclusiveFrequency uint) map[uint]telemetry.Sample {
+		return map[uint]telemetry.Sample{
+			0: {Core: 0, Utilization: sharedUtilization, Frequency: 2000},
+			1: {Core: 1, Utilization: sharedUtilization, Frequency: 2000},
+			2: {Core: 2, Utilization: 0.9, Frequency: exclusiveFrequency},
+			// an idle core clocks down on its own and isn't counted
+			3: {Core: 3, Utilization: 0.1, Frequency: 800},
+		}
+	}
+	r := buildPowerRecommendationReconcilerObject([]runtime.Object{
+		&powerv1.PowerNode{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: IntelPowerNamespace}},
+	}, powerLibMock, source)
+	assert.NotNil(t, r)
+	recorder := record.NewFakeRecorder(10)
+	r.Interference = telemetry.NewInterferenceDetector()
+	r.Recorder = recorder
+
+	// the frequency reached while the Shared pool is quiet is the baseline
+	source.samples = sample(0.2, 3600)
+	assert.NoError(t, r.Sample(context.TODO(), time.Now()))
+	// a Shared pool spike the exclusive pool doesn't notice
+	source.samples = sample(0.9, 3500)
+	assert.NoError(t, r.Sample(context.TODO(), time.Now()))
+	assert.Zero(t, testutil.ToFloat64(metrics.PoolSharedInterference.WithLabelValues(nodeName, "performance")))
+	assert.Empty(t, recorder.Events)
+
+	source.samples = sample(0.95, 3000)
+	assert.NoError(t, r.Sample(context.TODO(), time.Now()))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PoolSharedInterference.WithLabelValues(nodeName, "performance")))
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Pool 'performance' dropped from 3600MHz to 3000MHz while the Shared pool was 95% busy")
+
+	// once the pool is gone so is its baseline and counter
+	powerLibMock = new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{})
+	r.PowerLibrary = powerLibMock
+	assert.NoError(t, r.Sample(context.TODO(), time.Now()))
+	assert.Empty(t, r.Interference.Pools())
+	assert.False(t, metrics.PoolSharedInterference.DeleteLabelValues(nodeName, "performance"))
+}
+
+func TestPowerRecommendationReconciler_Fastpath(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	cores := make([]*coreMock, 6)
+	for i := range cores {
+		cores[i] = new(coreMock)
+		cores[i].On("GetID").Return(uint(i))
+	}
+	profile := new(profMock)
+	profile.On("Name").Return("performance")
+	profile.On("MaxFreq").Return(uint(3600))
+	profile.On("MinFreq").Return(uint(1000))
+	performancePool := new(poolMock)
+	performancePool.On("Name").Return("performance")
+	performancePool.On("Cpus").Return(&power.CpuList{cores[2], cores[3], cores[5]})
+	performancePool.On("GetPowerProfile").Return(profile)
+	dpdkProfile := new(profMock)
+	dpdkProfile.On("Name").Return("dpdk")
+	dpdkPool := new(poolMock)
+	dpdkPool.On("Name").Return("dpdk")
+	dpdkPool.On("Cpus").Return(&power.CpuList{cores[4]})
+	dpdkPool.On("GetPowerProfile").Return(dpdkProfile)
+	sharedPool := new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(nil)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{performancePool, dpdkPool})
+
+	// core 3 belongs to a Pod annotated as fastpath and core 5 spins without context switches
+	source := &fakeTelemetrySource{samples: map[uint]telemetry.Sample{
+		2: {Core: 2, Utilization: 0.5, Frequency: 3600, ContextSwitchRate: 400},
+		3: {Core: 3, Utilization: 1, Frequency: 3600},
+		4: {Core: 4, Utilization: 1, Frequency: 3600},
+		5: {Core: 5, Utilization: 1, Frequency: 3600, ContextSwitchRate: 2},
+	}}
+	r := buildPowerRecommendationReconcilerObject([]runtime.Object{
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "dpdk", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: "dpdk", Epp: "performance", Fastpath: true},
+		},
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "performance-TestNode", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				Name:         "performance-TestNode",
+				PowerProfile: "performance",
+				Node: powerv1.WorkloadNode{
+					Name: nodeName,
+					Containers: []powerv1.Container{
+						{Name: "l3fwd", Pod: "l3fwd", ExclusiveCPUs: []uint{3}, PowerProfile: "performance", Fastpath: true},
+					},
+				},
+			},
+		},
+	}, powerLibMock, source)
+	assert.NotNil(t, r)
+	r.DetectBusyPoll = true
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, r.Sample(context.TODO(), now.Add(time.Duration(i)*time.Minute)))
+	}
+	assert.Equal(t, []string{"performance"}, r.Window.Keys())
+	assert.NoError(t, r.Report(context.TODO(), now.Add(10*time.Minute)))
+
+	recommendation := &powerv1.PowerRecommendation{}
+	err := r.Client.Get(context.TODO(), client.ObjectKey{
+		Name:      "performance-TestNode",
+		Namespace: IntelPowerNamespace,
+	}, recommendation)
+	assert.NoError(t, err)
+	assert.Equal(t, 1800, recommendation.Status.RecommendedMax)
+	assert.Equal(t, int64(10), recommendation.Status.Samples)
+
+	// a fastpath pool's cores only ever poll
+	assert.Equal(t, power.CStates{"POLL": true, "C1": false, "C6": false}, fastpathCStates([]string{"POLL", "C1", "C6"}))
+}
@@ -0,0 +1,310 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/profileorder"
+	"github.com/intel/kubernetes-power-manager/pkg/redfish"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetrystream"
+)
+
+const (
+	PowerSourceRAPL    = "rapl"
+	PowerSourceRedfish = "redfish"
+	PowerSourceDCMI    = "dcmi"
+)
+
+// ChassisPowerSource reports the power drawn by the whole chassis, as measured by its BMC
+type ChassisPowerSource interface {
+	ChassisWatts(ctx context.Context) (float64, error)
+}
+
+// TelemetryPublisher pushes the readings to whoever is subscribed to the Node's telemetry stream
+type TelemetryPublisher interface {
+	Publish(update telemetrystream.Update)
+}
+
+// PowerTelemetryReconciler periodically publishes this Node's package power from RAPL and, when BMC credentials
+// are configured, its chassis power from Redfish, as metrics and in the PowerNode's status. Where RAPL can't be read
+// and Redfish gives no reading, the chassis power is read over IPMI DCMI instead. With a FastInterval or an
+// IdleInterval the readings are taken more often on Nodes running the highest priority workloads and less often
+// on idle ones
+type PowerTelemetryReconciler struct {
+	client.Client
+	Log       logr.Logger
+	APIReader client.Reader
+	Interval  time.Duration
+	// FastInterval is used while the Node has CPUs in a PowerWorkload of the first PowerProfile of the
+	// profileOrdering, IdleInterval while it has none in any PowerWorkload. Each is the Interval if 0
+	FastInterval time.Duration
+	IdleInterval time.Duration
+
+	PackageSource PowerSource
+	// ChassisSource is built from the Node's BMC address annotation and the credentials Secret when not set
+	ChassisSource ChassisPowerSource
+	// FallbackSource reads the platform power when the PackageSource can't, it is dropped once it turns out to be
+	// unsupported on the Node
+	FallbackSource PowerSource
+	// Secret in the intel-power namespace with the BMC username and password, Redfish is not used without it
+	RedfishCredentialsSecret string
+	RedfishInsecure          bool
+	// Publisher streams every set of readings as it is taken, nothing is streamed if nil
+	Publisher TelemetryPublisher
+
+	packagePrimed bool
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// Start collects power readings on every interval until the context is cancelled, the interval is worked out
+// again after each collection
+func (r *PowerTelemetryReconciler) Start(ctx context.Context) error {
+	interval := r.Interval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			err := r.Collect(ctx)
+			if err != nil {
+				r.Log.Error(err, "error collecting power telemetry")
+			}
+			next, err := r.NextInterval(ctx, os.Getenv("NODE_NAME"))
+			if err != nil {
+				r.Log.Error(err, "error working out the power telemetry interval, keeping the current one")
+				next = interval
+			}
+			if next != interval {
+				r.Log.V(5).Info("Changed the power telemetry interval", "from", interval, "to", next)
+				interval = next
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// NextInterval is how long to wait before the next readings of the Node: the FastInterval while it runs workloads
+// of the highest priority PowerProfile, the IdleInterval while it runs none with a PowerProfile, the Interval
+// otherwise
+func (r *PowerTelemetryReconciler) NextInterval(ctx context.Context, nodeName string) (time.Duration, error) {
+	if r.FastInterval <= 0 && r.IdleInterval <= 0 {
+		return r.Interval, nil
+	}
+
+	workloads := &powerv1.PowerWorkloadList{}
+	err := r.Client.List(ctx, workloads, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return 0, err
+	}
+	active := make(map[string]bool)
+	for _, workload := range workloads.Items {
+		if workload.Spec.AllCores || workload.Spec.Node.Name != nodeName {
+			continue
+		}
+		if len(workload.Spec.Node.CpuIds) > 0 {
+			active[workload.Spec.PowerProfile] = true
+		}
+	}
+	if len(active) == 0 {
+		return durationOr(r.IdleInterval, r.Interval), nil
+	}
+
+	configs := &powerv1.PowerConfigList{}
+	err = r.Client.List(ctx, configs, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return 0, err
+	}
+	ordering := profileorder.DefaultOrdering
+	for _, config := range configs.Items {
+		if len(config.Spec.ProfileOrdering) > 0 {
+			ordering = config.Spec.ProfileOrdering
+			break
+		}
+	}
+	if active[ordering[0]] {
+		return durationOr(r.FastInterval, r.Interval), nil
+	}
+
+	return r.Interval, nil
+}
+
+func durationOr(duration time.Duration, fallback time.Duration) time.Duration {
+	if duration <= 0 {
+		return fallback
+	}
+
+	return duration
+}
+
+// NeedLeaderElection is false as every Node Agent reads its own Node's power
+func (r *PowerTelemetryReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Collect reads every configured source once. A source that fails is logged and left out, the others are
+// still published
+func (r *PowerTelemetryReconciler) Collect(ctx context.Context) error {
+	logger := r.Log.WithName("powerTelemetry")
+	nodeName := os.Getenv("NODE_NAME")
+
+	packageWatts := -1.0
+	packageUnavailable := r.PackageSource == nil
+	if r.PackageSource != nil {
+		watts, err := r.PackageSource.Watts()
+		switch {
+		case err != nil:
+			packageUnavailable = true
+			if r.FallbackSource == nil {
+				logger.Error(err, "error reading package power")
+			} else {
+				logger.V(5).Info("package power unavailable, falling back to IPMI DCMI", "error", err.Error())
+			}
+		case !r.packagePrimed:
+			// the first RAPL reading only primes the energy counters
+			r.packagePrimed = true
+		default:
+			packageWatts = watts
+			metrics.NodePowerWatts.WithLabelValues(nodeName, PowerSourceRAPL).Set(watts)
+		}
+	}
+
+	chassisWatts := -1.0
+	chassisSource, err := r.chassisPowerSource(ctx, nodeName)
+	if err != nil {
+		logger.Error(err, "error setting up Redfish")
+	} else if chassisSource != nil {
+		watts, err := chassisSource.ChassisWatts(ctx)
+		if err != nil {
+			logger.Error(err, "error reading chassis power")
+		} else {
+			chassisWatts = watts
+			metrics.NodePowerWatts.WithLabelValues(nodeName, PowerSourceRedfish).Set(watts)
+		}
+	}
+	if packageUnavailable && chassisWatts < 0 && r.FallbackSource != nil {
+		watts, err := r.FallbackSource.Watts()
+		switch {
+		case powererrors.IsHardwareUnsupported(err):
+			logger.Info("IPMI DCMI power readings unsupported on this Node, not trying them again", "error", err.Error())
+			r.FallbackSource = nil
+		case err != nil:
+			logger.Error(err, "error reading power over IPMI DCMI")
+		default:
+			chassisWatts = watts
+			metrics.NodePowerWatts.WithLabelValues(nodeName, PowerSourceDCMI).Set(watts)
+		}
+	}
+	logger.V(5).Info("Collected power readings", "package", packageWatts, "chassis", chassisWatts)
+	if r.Publisher != nil {
+		r.Publisher.Publish(telemetrystream.Update{
+			Node:         nodeName,
+			Time:         time.Now().UTC(),
+			PackageWatts: packageWatts,
+			ChassisWatts: chassisWatts,
+		})
+	}
+
+	return r.updateStatus(ctx, nodeName, packageWatts, chassisWatts)
+}
+
+// chassisPowerSource returns nil without an error when Redfish is not configured
+func (r *PowerTelemetryReconciler) chassisPowerSource(ctx context.Context, nodeName string) (ChassisPowerSource, error) {
+	if r.ChassisSource != nil || r.RedfishCredentialsSecret == "" {
+		return r.ChassisSource, nil
+	}
+
+	redfishClient, err := newRedfishClient(ctx, r.Client, r.APIReader, nodeName, r.RedfishCredentialsSecret, r.RedfishInsecure)
+	if err != nil {
+		return nil, err
+	}
+	r.ChassisSource = redfishClient
+	return r.ChassisSource, nil
+}
+
+// newRedfishClient creates a client for the BMC at the Node's address annotation with the credentials in the Secret
+func newRedfishClient(ctx context.Context, c client.Client, apiReader client.Reader, nodeName string, secretName string, insecure bool) (*redfish.Client, error) {
+	node := &corev1.Node{}
+	err := c.Get(ctx, client.ObjectKey{Name: nodeName}, node)
+	if err != nil {
+		return nil, err
+	}
+	address := node.Annotations[redfish.BMCAddressAnnotation]
+	if address == "" {
+		return nil, fmt.Errorf("Node %s has no %s annotation", nodeName, redfish.BMCAddressAnnotation)
+	}
+
+	secret := &corev1.Secret{}
+	err = apiReader.Get(ctx, client.ObjectKey{
+		Name:      secretName,
+		Namespace: IntelPowerNamespace,
+	}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving BMC credentials: %w", err)
+	}
+
+	return redfish.NewClient(address, string(secret.Data["username"]), string(secret.Data["password"]), insecure), nil
+}
+
+// updateStatus keeps the last good reading of a source that failed this time
+func (r *PowerTelemetryReconciler) updateStatus(ctx context.Context, nodeName string, packageWatts float64, chassisWatts float64) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(ctx, client.ObjectKey{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		}, powerNode)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		status := powerNode.Status
+		if packageWatts >= 0 {
+			powerNode.Status.PackagePowerWatts = int(math.Round(packageWatts))
+		}
+		if chassisWatts >= 0 {
+			powerNode.Status.ChassisPowerWatts = int(math.Round(chassisWatts))
+		}
+		if status.PackagePowerWatts == powerNode.Status.PackagePowerWatts &&
+			status.ChassisPowerWatts == powerNode.Status.ChassisPowerWatts {
+			return nil
+		}
+
+		return r.Client.Status().Update(ctx, powerNode)
+	})
+}
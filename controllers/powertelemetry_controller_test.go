@@ -0,0 +1,530 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/agentpods"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+	"github.com/intel/kubernetes-power-manager/pkg/ipmi"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/otlp"
+	"github.com/intel/kubernetes-power-manager/pkg/redfish"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetrystream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type chassisSourceMock struct {
+	watts float64
+	err   error
+}
+
+func (m *chassisSourceMock) ChassisWatts(ctx context.Context) (float64, error) {
+	return m.watts, m.err
+}
+
+type telemetryPublisherMock struct {
+	updates []telemetrystream.Update
+}
+
+func (m *telemetryPublisherMock) Publish(update telemetrystream.Update) {
+	m.updates = append(m.updates, update)
+}
+
+func createTelemetryReconcilerObject(objs []runtime.Object) (*PowerTelemetryReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &PowerTelemetryReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), APIReader: cl}, nil
+}
+
+func TestPowerTelemetryReconciler_Collect(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+	}
+	r, err := createTelemetryReconcilerObject([]runtime.Object{powerNode})
+	assert.NoError(t, err)
+	packageSource := &powerSourceMock{watts: 181.6}
+	chassisSource := &chassisSourceMock{watts: 412}
+	r.PackageSource = packageSource
+	r.ChassisSource = chassisSource
+	status := func() powerv1.PowerNodeStatus {
+		node := &powerv1.PowerNode{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), node))
+		return node.Status
+	}
+
+	// the first RAPL reading is left out
+	assert.NoError(t, r.Collect(context.TODO()))
+	assert.Equal(t, 0, status().PackagePowerWatts)
+	assert.Equal(t, 412, status().ChassisPowerWatts)
+
+	assert.NoError(t, r.Collect(context.TODO()))
+	assert.Equal(t, 182, status().PackagePowerWatts)
+
+	// a failing source keeps its last reading
+	chassisSource.err = fmt.Errorf("BMC unreachable")
+	packageSource.watts = 150
+	assert.NoError(t, r.Collect(context.TODO()))
+	assert.Equal(t, 150, status().PackagePowerWatts)
+	assert.Equal(t, 412, status().ChassisPowerWatts)
+}
+
+func TestPowerTelemetryReconciler_NextInterval(t *testing.T) {
+	workload := func(profile string, node string, cpus ...uint) *powerv1.PowerWorkload {
+		return &powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: profile + "-" + node, Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				Name:         profile + "-" + node,
+				PowerProfile: profile,
+				Node:         powerv1.WorkloadNode{Name: node, CpuIds: cpus},
+			},
+		}
+	}
+	tcases := []struct {
+		testCase string
+		objs     []runtime.Object
+		fast     time.Duration
+		idle     time.Duration
+		expected time.Duration
+	}{
+		{
+			testCase: "Test Case 1 - not adaptive",
+			objs:     []runtime.Object{workload("gold", "TestNode", 2, 3)},
+			expected: 30 * time.Second,
+		},
+		{
+			testCase: "Test Case 2 - gold workload",
+			objs:     []runtime.Object{workload("gold", "TestNode", 2, 3), workload("silver", "TestNode", 4)},
+			fast:     5 * time.Second,
+			idle:     2 * time.Minute,
+			expected: 5 * time.Second,
+		},
+		{
+			testCase: "Test Case 3 - lower priority workload only",
+			objs:     []runtime.Object{workload("gold", "TestNode"), workload("silver", "TestNode", 4)},
+			fast:     5 * time.Second,
+			idle:     2 * time.Minute,
+			expected: 30 * time.Second,
+		},
+		{
+			testCase: "Test Case 4 - idle, gold only on another Node",
+			objs:     []runtime.Object{workload("gold", "TestNode"), workload("gold", "OtherNode", 2)},
+			fast:     5 * time.Second,
+			idle:     2 * time.Minute,
+			expected: 2 * time.Minute,
+		},
+		{
+			testCase: "Test Case 5 - idle without an idle interval",
+			fast:     5 * time.Second,
+			expected: 30 * time.Second,
+		},
+		{
+			testCase: "Test Case 6 - first of the PowerConfig's profileOrdering",
+			objs: []runtime.Object{
+				&powerv1.PowerConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "power-config", Namespace: IntelPowerNamespace},
+					Spec:       powerv1.PowerConfigSpec{ProfileOrdering: []string{"platinum", "gold"}},
+				},
+				workload("gold", "TestNode", 2),
+				workload("platinum", "TestNode", 3),
+			},
+			fast:     5 * time.Second,
+			idle:     2 * time.Minute,
+			expected: 5 * time.Second,
+		},
+	}
+
+	for _, tc := range tcases {
+		r, err := createTelemetryReconcilerObject(tc.objs)
+		if err != nil {
+			t.Fatalf("%s - error creating reconciler object: %v", tc.testCase, err)
+		}
+		r.Interval = 30 * time.Second
+		r.FastInterval = tc.fast
+		r.IdleInterval = tc.idle
+
+		interval, err := r.NextInterval(context.TODO(), "TestNode")
+		assert.NoError(t, err, tc.testCase)
+		assert.Equal(t, tc.expected, interval, tc.testCase)
+	}
+}
+
+func TestPowerTelemetryReconciler_Publish(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+	}
+	r, err := createTelemetryReconcilerObject([]runtime.Object{powerNode})
+	assert.NoError(t, err)
+	publisher := &telemetryPublisherMock{}
+	r.PackageSource = &powerSourceMock{watts: 181.6}
+	r.ChassisSource = &chassisSourceMock{err: fmt.Errorf("BMC unreachable")}
+	r.Publisher = publisher
+
+	assert.NoError(t, r.Collect(context.TODO()))
+	assert.NoError(t, r.Collect(context.TODO()))
+
+	// readings that couldn't be taken are streamed as -1
+	assert.Len(t, publisher.updates, 2)
+	assert.Equal(t, nodeName, publisher.updates[0].Node)
+	assert.Equal(t, -1.0, publisher.updates[0].PackageWatts)
+	assert.Equal(t, 181.6, publisher.updates[1].PackageWatts)
+	assert.Equal(t, -1.0, publisher.updates[1].ChassisWatts)
+}
+
+func TestPowerTelemetryReconciler_DCMIFallback(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+	}
+	r, err := createTelemetryReconcilerObject([]runtime.Object{powerNode})
+	assert.NoError(t, err)
+	fallback := &powerSourceMock{watts: 264}
+	r.PackageSource = &powerSourceMock{err: fmt.Errorf("no RAPL package zones")}
+	r.FallbackSource = fallback
+	status := func() powerv1.PowerNodeStatus {
+		node := &powerv1.PowerNode{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), node))
+		return node.Status
+	}
+
+	// without RAPL the platform power is read over DCMI and labelled with its source
+	assert.NoError(t, r.Collect(context.TODO()))
+	assert.Equal(t, 0, status().PackagePowerWatts)
+	assert.Equal(t, 264, status().ChassisPowerWatts)
+	assert.Equal(t, 264.0, testutil.ToFloat64(metrics.NodePowerWatts.WithLabelValues(nodeName, PowerSourceDCMI)))
+
+	// Redfish is preferred when it gives a reading
+	r.ChassisSource = &chassisSourceMock{watts: 301}
+	fallback.watts = 250
+	assert.NoError(t, r.Collect(context.TODO()))
+	assert.Equal(t, 301, status().ChassisPowerWatts)
+
+	// a BMC without DCMI power management isn't asked again
+	r.ChassisSource = &chassisSourceMock{err: fmt.Errorf("BMC unreachable")}
+	fallback.err = powererrors.NewHardwareUnsupported("IPMI DCMI", fmt.Errorf("no instantaneous power reading"))
+	assert.NoError(t, r.Collect(context.TODO()))
+	assert.Nil(t, r.FallbackSource)
+	assert.Equal(t, 301, status().ChassisPowerWatts)
+
+	reading, err := ipmi.ParseReading(`
+    Instantaneous power reading:                   212 Watts
+    Minimum during sampling period:                 98 Watts
+    Maximum during sampling period:                388 Watts
+    Average power reading over sample period:      205 Watts
+    Power reading state is:                   activated
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, 212.0, reading)
+	_, err = ipmi.ParseReading("Power reading state is: deactivated")
+	assert.True(t, powererrors.IsHardwareUnsupported(err))
+}
+
+func TestPowerTelemetryReconciler_Redfish(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	bmc := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		username, password, _ := req.BasicAuth()
+		if username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/redfish/v1/Systems":
+			fmt.Fprint(w, `{"Members": [{"@odata.id": "/redfish/v1/Systems/1"}]}`)
+		case "/redfish/v1/Systems/1":
+			fmt.Fprint(w, `{"Links": {"Chassis": [{"@odata.id": "/redfish/v1/Chassis/1"}]}}`)
+		case "/redfish/v1/Chassis/1/Power":
+			// no total reading, the power supplies are summed up
+			fmt.Fprint(w, `{"PowerControl": [{}], "PowerSupplies": [{"PowerInputWatts": 210}, {"PowerInputWatts": 205.5}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer bmc.Close()
+
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        nodeName,
+			Annotations: map[string]string{redfish.BMCAddressAnnotation: bmc.URL},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bmc-credentials",
+			Namespace: IntelPowerNamespace,
+		},
+		Data: map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+	}
+	r, err := createTelemetryReconcilerObject([]runtime.Object{powerNode, node, secret})
+	assert.NoError(t, err)
+	r.RedfishCredentialsSecret = "bmc-credentials"
+	r.RedfishInsecure = true
+
+	assert.NoError(t, r.Collect(context.TODO()))
+
+	updated := &powerv1.PowerNode{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), updated))
+	assert.Equal(t, 416, updated.Status.ChassisPowerWatts)
+}
+
+func TestRedfishVersionSchemas(t *testing.T) {
+	tcases := []struct {
+		name     string
+		version  string
+		paths    map[string]string
+		expected float64
+	}{
+		{
+			name:    "legacy service reads Power",
+			version: "1.6.0",
+			paths: map[string]string{
+				"/redfish/v1/Chassis/1/Power":              `{"PowerControl": [{"PowerConsumedWatts": 300}]}`,
+				"/redfish/v1/Chassis/1/EnvironmentMetrics": `{"PowerWatts": {"Reading": 320}}`,
+			},
+			expected: 300,
+		},
+		{
+			name:    "newer service reads EnvironmentMetrics",
+			version: "1.15.1",
+			paths: map[string]string{
+				"/redfish/v1/Chassis/1/Power":              `{"PowerControl": [{"PowerConsumedWatts": 300}]}`,
+				"/redfish/v1/Chassis/1/EnvironmentMetrics": `{"PowerWatts": {"Reading": 320}}`,
+			},
+			expected: 320,
+		},
+		{
+			name:    "newer service without EnvironmentMetrics falls back to Power",
+			version: "1.11",
+			paths: map[string]string{
+				"/redfish/v1/Chassis/1/Power": `{"PowerControl": [{"PowerConsumedWatts": 300}]}`,
+			},
+			expected: 300,
+		},
+		{
+			name:    "service without a version is taken as legacy",
+			version: "",
+			paths: map[string]string{
+				"/redfish/v1/Chassis/1/EnvironmentMetrics": `{"PowerWatts": {"Reading": 320}}`,
+			},
+			expected: 320,
+		},
+	}
+
+	for _, tc := range tcases {
+		roots := 0
+		bmc := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/redfish/v1":
+				roots++
+				if tc.version == "" {
+					fmt.Fprint(w, `{}`)
+				} else {
+					fmt.Fprintf(w, `{"RedfishVersion": %q}`, tc.version)
+				}
+			case "/redfish/v1/Systems":
+				fmt.Fprint(w, `{"Members": [{"@odata.id": "/redfish/v1/Systems/1"}]}`)
+			case "/redfish/v1/Systems/1":
+				fmt.Fprint(w, `{"Links": {"Chassis": [{"@odata.id": "/redfish/v1/Chassis/1"}]}}`)
+			default:
+				body, found := tc.paths[req.URL.Path]
+				if !found {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, body)
+			}
+		}))
+
+		redfishClient := redfish.NewClient(bmc.URL, "admin", "secret", true)
+		for i := 0; i < 2; i++ {
+			watts, err := redfishClient.ChassisWatts(context.TODO())
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, tc.expected, watts, tc.name)
+		}
+		// the version is only read once
+		assert.Equal(t, 1, roots, tc.name)
+		bmc.Close()
+	}
+
+	version, err := redfish.ParseVersion("1.15.1")
+	assert.NoError(t, err)
+	assert.Equal(t, redfish.Version{Major: 1, Minor: 15, Errata: 1}, version)
+	assert.True(t, version.AtLeast(1, 11))
+	assert.False(t, version.AtLeast(2, 0))
+	for _, invalid := range []string{"1", "v1.2", "1.2.3.4", "1.-2"} {
+		_, err = redfish.ParseVersion(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRedfishBaseURL(t *testing.T) {
+	tcases := []struct {
+		address  string
+		expected string
+	}{
+		{address: "https://10.0.0.5", expected: "https://10.0.0.5"},
+		{address: "https://10.0.0.5/", expected: "https://10.0.0.5"},
+		{address: "10.0.0.5", expected: "https://10.0.0.5"},
+		{address: "bmc-1.example.com:8443", expected: "https://bmc-1.example.com:8443"},
+		{address: "https://[fd00::5]", expected: "https://[fd00::5]"},
+		{address: "https://[fd00::5]:8443/", expected: "https://[fd00::5]:8443"},
+		{address: "https://fd00::5", expected: "https://[fd00::5]"},
+		{address: "fd00::5", expected: "https://[fd00::5]"},
+		{address: "[fd00::5]:8443", expected: "https://[fd00::5]:8443"},
+		{address: "http://::ffff:10.0.0.5", expected: "http://[::ffff:10.0.0.5]"},
+	}
+
+	for _, tc := range tcases {
+		assert.Equal(t, tc.expected, redfish.BaseURL(tc.address), tc.address)
+	}
+}
+
+func TestOTLPExport(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	watts := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "power_node_watts", Help: "watts"}, []string{"node", "source"})
+	restored := prometheus.NewCounter(prometheus.CounterOpts{Name: "power_extended_resources_restored_total", Help: "restored"})
+	// only the Power Manager's own metrics are exported
+	other := prometheus.NewGauge(prometheus.GaugeOpts{Name: "workqueue_depth", Help: "depth"})
+	registry.MustRegister(watts, restored, other)
+	watts.WithLabelValues("TestNode", "rapl").Set(180.5)
+	restored.Add(2)
+	other.Set(3)
+
+	var received map[string]interface{}
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, otlp.MetricsPath, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		authorization = r.Header.Get("Authorization")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	headers, err := otlp.ParseHeaders("Authorization=Bearer token, X-Scope=power")
+	assert.NoError(t, err)
+	exporter := &otlp.Exporter{
+		Log:         ctrl.Log.WithName("testing"),
+		Endpoint:    server.URL,
+		Headers:     headers,
+		ServiceName: "power-node-agent",
+		NodeName:    "TestNode",
+		Gatherer:    registry,
+	}
+	assert.NoError(t, exporter.Export(context.TODO()))
+	assert.Equal(t, "Bearer token", authorization)
+
+	resourceMetrics := received["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	attributes := resourceMetrics["resource"].(map[string]interface{})["attributes"].([]interface{})
+	assert.Len(t, attributes, 2)
+	metrics := resourceMetrics["scopeMetrics"].([]interface{})[0].(map[string]interface{})["metrics"].([]interface{})
+	assert.Len(t, metrics, 2)
+	byName := make(map[string]map[string]interface{})
+	for _, m := range metrics {
+		byName[m.(map[string]interface{})["name"].(string)] = m.(map[string]interface{})
+	}
+	point := byName["power_node_watts"]["gauge"].(map[string]interface{})["dataPoints"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 180.5, point["asDouble"])
+	assert.Len(t, point["attributes"], 2)
+	counter := byName["power_extended_resources_restored_total"]["sum"].(map[string]interface{})
+	assert.Equal(t, true, counter["isMonotonic"])
+	assert.Equal(t, 2.0, counter["dataPoints"].([]interface{})[0].(map[string]interface{})["asDouble"])
+
+	_, err = otlp.ParseHeaders("Authorization")
+	assert.Error(t, err)
+
+	// the collector refusing the export is an error
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer refusing.Close()
+	exporter.Endpoint = refusing.URL
+	assert.Error(t, exporter.Export(context.TODO()))
+}
+
+func TestAgentPodRegistry(t *testing.T) {
+	registry := agentpods.NewRegistry(IntelPowerNamespace, labels.SelectorFromSet(labels.Set{NodeAgentPodLabel: NodeAgentPodLabelValue}))
+	agentPod := func(name string, node string, ip string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: IntelPowerNamespace,
+				Labels:    map[string]string{NodeAgentPodLabel: NodeAgentPodLabelValue},
+			},
+			Spec:   corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{Phase: phase, PodIP: ip},
+		}
+	}
+
+	old := agentPod("agent-a", "TestNode", "10.0.0.1", corev1.PodRunning)
+	registry.OnAdd(old)
+	other := agentPod("workload", "TestNode", "10.0.0.9", corev1.PodRunning)
+	other.Labels = nil
+	registry.OnAdd(other)
+	endpoint, exists := registry.Lookup("TestNode")
+	assert.True(t, exists)
+	assert.Equal(t, agentpods.Endpoint{Pod: "agent-a", IP: "10.0.0.1"}, endpoint)
+	select {
+	case <-registry.Changes():
+	default:
+		t.Error("expected a change to be signalled")
+	}
+
+	// the replacement agent starts before the old Pod is gone, deleting the old Pod keeps it
+	pending := agentPod("agent-b", "TestNode", "", corev1.PodPending)
+	registry.OnAdd(pending)
+	endpoint, _ = registry.Lookup("TestNode")
+	assert.Equal(t, "agent-a", endpoint.Pod)
+	replacement := agentPod("agent-b", "TestNode", "10.0.0.2", corev1.PodRunning)
+	registry.OnUpdate(pending, replacement)
+	registry.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "intel-power/agent-a", Obj: old})
+	endpoint, exists = registry.Lookup("TestNode")
+	assert.True(t, exists)
+	assert.Equal(t, agentpods.Endpoint{Pod: "agent-b", IP: "10.0.0.2"}, endpoint)
+
+	registry.OnDelete(replacement)
+	_, exists = registry.Lookup("TestNode")
+	assert.False(t, exists)
+	assert.Empty(t, registry.Endpoints())
+}
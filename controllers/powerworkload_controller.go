@@ -20,14 +20,35 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/correlation"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+	"github.com/intel/kubernetes-power-manager/pkg/logging"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/objectsize"
+	"github.com/intel/kubernetes-power-manager/pkg/perf"
+	"github.com/intel/kubernetes-power-manager/pkg/plan"
+	"github.com/intel/kubernetes-power-manager/pkg/poollock"
+	"github.com/intel/kubernetes-power-manager/pkg/probe"
 	"github.com/intel/kubernetes-power-manager/pkg/util"
 	"github.com/intel/power-optimization-library/pkg/power"
 
@@ -40,50 +61,110 @@ type PowerWorkloadReconciler struct {
 	Log          logr.Logger
 	Scheme       *runtime.Scheme
 	PowerLibrary power.Host
+	Prober       WorkloadProber
+	// Hooks runs the PowerWorkloads' pre and post apply hooks, hooks are skipped without one
+	Hooks WorkloadHookRunner
+	// Sampler verifies the PowerProfile on the CPUs added to a pool, verification is disabled without one
+	Sampler CounterSampler
+	// TransitionPodDisruptionBudgets also covers the Pods of a PowerWorkload in transition with PodDisruptionBudgets
+	TransitionPodDisruptionBudgets bool
+	// Plans records the plans applied to the Power Library, they are only logged without it
+	Plans *plan.History
+	// Limits reads the frequency limits each CPU runs with, CPUs aren't checked for clamping without it
+	Limits FrequencyLimitReader
 }
 
+// FrequencyLimitReader reads the min and max frequency in MHz a CPU runs with
+type FrequencyLimitReader interface {
+	FrequencyLimits(cpu uint) (uint, uint, error)
+}
+
+// WorkloadProber evaluates the Validation of a PowerWorkload
+type WorkloadProber interface {
+	Probe(ctx context.Context, validation *powerv1.WorkloadValidation) (bool, string, error)
+}
+
+// WorkloadHookRunner runs the hooks of a PowerWorkload
+type WorkloadHookRunner interface {
+	RunHook(ctx context.Context, hook *powerv1.WorkloadHook, event probe.HookEvent) error
+}
+
+// CounterSampler samples the performance counters of CPUs
+type CounterSampler interface {
+	Sample(cpus []uint) ([]perf.Sample, error)
+}
+
+const (
+	// verificationCPUs is how many of the CPUs added to a pool are sampled to verify its PowerProfile
+	verificationCPUs = 3
+	// verificationMinBusy is the share of the window a CPU has to be busy for its frequency to count
+	verificationMinBusy = 0.1
+	// verificationTolerance allows for the frequency changing during the window
+	verificationTolerance = 0.05
+)
+
 const (
 	SharedWorkloadName string = "shared-workload"
 	WorkloadNameSuffix string = "-workload"
+	// WorkloadNodeNameIndex is the cache index mapping PowerWorkloads to the Node they are assigned to
+	WorkloadNodeNameIndex string = "spec.workloadNodes.name"
+	// ProfileTransitionAnnotation marks the Pods of a PowerWorkload whose pool is changing frequencies, with the
+	// PowerProfile it is changing to
+	ProfileTransitionAnnotation string = "power.intel.com/profile-transition"
+	// TransitionWorkloadLabel selects the Pods of a PowerWorkload in transition for its PodDisruptionBudgets
+	TransitionWorkloadLabel string = "power.intel.com/transition-workload"
 )
 
+// transitionSettleTime is how long the frequencies of a pool are given to settle after they change
+const transitionSettleTime = 30 * time.Second
+
 var sharedPowerWorkloadName = ""
 
 // +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloads,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloads/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;delete
 
 func (r *PowerWorkloadReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
 	logger := r.Log.WithValues("powerworkload", req.NamespacedName)
 	if req.Namespace != IntelPowerNamespace {
 		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
 		return ctrl.Result{}, nil
 	}
 	nodeName := os.Getenv("NODE_NAME")
+	changes := logging.NewChangeSummary()
+	defer changes.Log(logger)
 
 	workload := &powerv1.PowerWorkload{}
-	err := r.Client.Get(context.TODO(), req.NamespacedName, workload)
+	err := r.Client.Get(c, req.NamespacedName, workload)
 	logger.V(5).Info("Retriving Power workload instance")
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// If the profile still exists in the Power Library, then only the Power Workloads was deleted
 			// and we need to remove it from the Power Library here. If the profile doesn't exist, then
 			// the Power Library will already have deleted it for us
+			removal := plan.New(nodeName, req.NamespacedName.Name, time.Now())
 			if req.NamespacedName.Name == sharedPowerWorkloadName {
-				err = r.PowerLibrary.GetSharedPool().SetPowerProfile(nil)
+				removal.Add(plan.ClearSharedProfile, "shared", nil)
+				err = r.applyPlan(c, removal, nil, nil, &logger, changes)
 				if err != nil {
-					logger.Error(err, "failed to remove exclusive pool")
 					return ctrl.Result{}, err
 				}
 				sharedPowerWorkloadName = ""
 			} else {
-				pool := r.PowerLibrary.GetExclusivePool(req.NamespacedName.Name)
-				if pool != nil {
-					err = pool.Remove()
-					if err != nil {
-						logger.Error(err, "failed to remove exclusive pool")
-						return ctrl.Result{}, err
-					}
+				if r.PowerLibrary.GetExclusivePool(req.NamespacedName.Name) != nil {
+					removal.Add(plan.RemovePool, req.NamespacedName.Name, nil)
+				}
+				err = r.applyPlan(c, removal, nil, nil, &logger, changes)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				// a transition in progress doesn't protect the Pods any longer
+				err = r.endTransition(c, req.NamespacedName.Name, changes)
+				if err != nil {
+					logger.Error(err, "error lifting the eviction protection of the PowerWorkload's Pods")
+					return ctrl.Result{}, err
 				}
 			}
 
@@ -92,6 +173,7 @@ func (r *PowerWorkloadReconciler) Reconcile(c context.Context, req ctrl.Request)
 
 		return ctrl.Result{}, err
 	}
+	logger = logger.WithValues(correlation.LogKey, correlation.ID(workload))
 
 	// If there are multiple nodes that the Shared PowerWorkload's Node Selector satisfies we need to fail here before anything is done
 	logger.V(5).Info("Checking that the Node Selector is satisfied with the Shared PowerWorkload")
@@ -99,7 +181,7 @@ func (r *PowerWorkloadReconciler) Reconcile(c context.Context, req ctrl.Request)
 		labelledNodeList := &corev1.NodeList{}
 		listOption := workload.Spec.PowerNodeSelector
 
-		err = r.Client.List(context.TODO(), labelledNodeList, client.MatchingLabels(listOption))
+		err = r.Client.List(c, labelledNodeList, client.MatchingLabels(listOption))
 		if err != nil {
 			logger.Error(err, "error retrieving Node with PowerNodeSelector", listOption)
 			return ctrl.Result{}, err
@@ -114,23 +196,34 @@ func (r *PowerWorkloadReconciler) Reconcile(c context.Context, req ctrl.Request)
 		logger.V(5).Info("Verifying that there is only one Shared PowerWorkload and if there is more than one delete this instance")
 		if sharedPowerWorkloadName != "" && sharedPowerWorkloadName != req.NamespacedName.Name {
 			// Delete this Shared PowerWorkload as another already exists
-			err = r.Client.Delete(context.TODO(), workload)
+			err = r.Client.Delete(c, workload)
 			if err != nil {
 				logger.Error(err, "error deleting second Shared PowerWorkload")
 				return ctrl.Result{}, err
 			}
+			changes.ResourceRemoved("PowerWorkload", workload.Name)
 
-			sharedPowerWorkloadAlreadyExists := errors.NewServiceUnavailable("A Shared PowerWorkload already exists for this node")
-			logger.Error(sharedPowerWorkloadAlreadyExists, "error creating Shared PowerWorkload")
+			sharedPowerWorkloadAlreadyExists := powererrors.NewConflict("A Shared PowerWorkload already exists for this node")
+			return reconcileError(&logger, sharedPowerWorkloadAlreadyExists, "error creating Shared PowerWorkload")
+		}
+
+		underMaintenance, err := nodeUnderMaintenance(c, r.Client, nodeName)
+		if err != nil {
+			logger.Error(err, "error checking if the Node is under maintenance")
+			return ctrl.Result{}, err
+		}
+		if underMaintenance {
+			logger.V(5).Info("Node is under maintenance, Shared PowerWorkload will be applied once it ends")
 			return ctrl.Result{}, nil
 		}
 
 		// add cores to shared pool by selecting which cores should be reserved
 		// remaining cores will be moved to the shared pool
 		logger.V(5).Info("Creating Shared Pool in the Power Library")
-		err = r.PowerLibrary.GetReservedPool().SetCpuIDs(workload.Spec.ReservedCPUs)
+		sharedPlan := plan.New(nodeName, workload.Name, time.Now())
+		sharedPlan.Add(plan.SetReservedCPUs, "reserved", workload.Spec.ReservedCPUs)
+		err = r.applyPlan(c, sharedPlan, workload, nil, &logger, changes)
 		if err != nil {
-			logger.Error(err, "error configuring Shared Pool in Power Library")
 			return ctrl.Result{}, err
 		}
 
@@ -140,6 +233,25 @@ func (r *PowerWorkloadReconciler) Reconcile(c context.Context, req ctrl.Request)
 	}
 
 	if workload.Spec.Node.Name == nodeName {
+		underMaintenance, err := nodeUnderMaintenance(c, r.Client, nodeName)
+		if err != nil {
+			logger.Error(err, "error checking if the Node is under maintenance")
+			return ctrl.Result{}, err
+		}
+		if underMaintenance {
+			logger.V(5).Info("Node is under maintenance, PowerWorkload will be applied once it ends")
+			return ctrl.Result{}, nil
+		}
+		excluded, err := nodeExcluded(c, r.Client, nodeName)
+		if err != nil {
+			logger.Error(err, "error checking if the Node is excluded")
+			return ctrl.Result{}, err
+		}
+		if excluded {
+			logger.V(5).Info("Node is excluded from power management, PowerWorkload is not applied")
+			return ctrl.Result{}, nil
+		}
+
 		poolFromLibrary := r.PowerLibrary.GetExclusivePool(workload.Spec.PowerProfile)
 		if poolFromLibrary == nil {
 			poolDoesNotExistError := errors.NewServiceUnavailable(fmt.Sprintf("Pool '%s' does not exists in Power Library", workload.Spec.PowerProfile))
@@ -147,29 +259,707 @@ func (r *PowerWorkloadReconciler) Reconcile(c context.Context, req ctrl.Request)
 			return ctrl.Result{}, nil
 		}
 
+		cpus := append([]uint{}, workload.Spec.Node.CpuIds...)
+		siblings, err := r.siblingCPUs(c, workload)
+		if err != nil {
+			logger.Error(err, "error retrieving the CPUs of the PowerWorkloads sharing the pool")
+			return ctrl.Result{}, err
+		}
+		for _, cpu := range siblings {
+			if !coreInCoreList(cpu, cpus) {
+				cpus = append(cpus, cpu)
+			}
+		}
+
 		logger.V(5).Info("Updating Cpu list in Power Library")
 		cores := poolFromLibrary.Cpus().IDs()
-		coresToRemoveFromLibrary := detectCoresRemoved(cores, workload.Spec.Node.CpuIds, &logger)
-		coresToBeAddedToLibrary := detectCoresAdded(cores, workload.Spec.Node.CpuIds, &logger)
+		coresToRemoveFromLibrary := detectCoresRemoved(cores, cpus, &logger)
+		coresToBeAddedToLibrary := detectCoresAdded(cores, cpus, &logger)
 
-		if len(coresToRemoveFromLibrary) > 0 {
-			err = r.PowerLibrary.GetSharedPool().MoveCpuIDs(coresToRemoveFromLibrary)
-			if err != nil {
-				logger.Error(err, "error updating Power Library Cpu list")
-				return ctrl.Result{}, err
+		changing := len(coresToRemoveFromLibrary) > 0 || len(coresToBeAddedToLibrary) > 0 ||
+			workload.Status.AppliedProfile != workload.Spec.PowerProfile
+		event := probe.HookEvent{
+			Workload:      workload.Name,
+			Node:          nodeName,
+			Profile:       workload.Spec.PowerProfile,
+			AddedCPUs:     coresToBeAddedToLibrary,
+			RemovedCPUs:   coresToRemoveFromLibrary,
+			CorrelationID: correlation.ID(workload),
+		}
+		if workload.Status.AppliedProfile != workload.Spec.PowerProfile {
+			event.PreviousProfile = workload.Status.AppliedProfile
+		}
+		applyPlan := buildWorkloadPlan(workload, event, changing, time.Now())
+		err = r.applyPlan(c, applyPlan, workload, &event, &logger, changes)
+		statusErr := r.recordApplied(c, workload, poolFromLibrary, applyPlan)
+		if statusErr != nil {
+			logger.Error(statusErr, "error updating PowerWorkload status")
+		}
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if statusErr != nil {
+			return ctrl.Result{}, statusErr
+		}
+
+		result, err := r.validateWorkload(c, workload, &logger)
+		if err != nil {
+			return result, err
+		}
+
+		return r.protectTransition(c, workload, result, &logger, changes)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// buildWorkloadPlan lays out how the PowerWorkload's CPUs are moved between its pool and the Shared pool: the CPUs
+// it lost go back to the Shared pool before the new ones are taken, so a CPU moving between PowerWorkloads is never
+// claimed twice, with the hooks around the moves when anything changes
+func buildWorkloadPlan(workload *powerv1.PowerWorkload, event probe.HookEvent, changing bool, now time.Time) *plan.Plan {
+	workloadPlan := plan.New(event.Node, workload.Name, now)
+	workloadPlan.Profile = event.Profile
+	workloadPlan.PreviousProfile = event.PreviousProfile
+	workloadPlan.CorrelationID = event.CorrelationID
+	if changing {
+		workloadPlan.Add(plan.PreApplyHook, "", nil)
+	}
+	if len(event.RemovedCPUs) > 0 {
+		workloadPlan.Add(plan.MoveToShared, workload.Spec.PowerProfile, event.RemovedCPUs)
+	}
+	if len(event.AddedCPUs) > 0 {
+		workloadPlan.Add(plan.MoveToPool, workload.Spec.PowerProfile, event.AddedCPUs)
+		workloadPlan.Add(plan.VerifyProfile, workload.Spec.PowerProfile, event.AddedCPUs)
+	}
+	if changing {
+		workloadPlan.Add(plan.PostApplyHook, "", nil)
+	}
+
+	return workloadPlan
+}
+
+// applyPlan carries out the plan's operations in order and stops at the first that fails. The plan is logged
+// before anything is applied and recorded with how far it got. event is only needed for plans with hooks. A plan
+// whose Node's frequency transition limit can't be read isn't started, nor logged and recorded
+func (r *PowerWorkloadReconciler) applyPlan(c context.Context, applyPlan *plan.Plan, workload *powerv1.PowerWorkload, event *probe.HookEvent, logger *logr.Logger, changes *logging.ChangeSummary) error {
+	limit, err := transitionLimit(c, r.Client, applyPlan.Node)
+	if err != nil {
+		logger.Error(err, "error retrieving the Node's frequency transition limit")
+		return err
+	}
+
+	applyPlan.Log(*logger)
+	defer r.Plans.Record(applyPlan)
+	for _, operation := range applyPlan.Operations {
+		err := r.applyOperation(c, applyPlan.Node, operation, workload, limit, event, logger, changes)
+		if err != nil {
+			logger.Error(err, "error applying plan", "operation", operation.String())
+			applyPlan.Error = err.Error()
+			return err
+		}
+		applyPlan.Applied++
+	}
+
+	return nil
+}
+
+// recordApplied puts how many of the PowerWorkload's CPUs made it into its pool and why the plan failed, if it did,
+// in the PowerWorkload status for kubectl get to show
+func (r *PowerWorkloadReconciler) recordApplied(c context.Context, workload *powerv1.PowerWorkload, pool power.Pool, applyPlan *plan.Plan) error {
+	poolCPUs := pool.Cpus().IDs()
+	applied := 0
+	for _, cpu := range workload.Spec.Node.CpuIds {
+		if coreInCoreList(cpu, poolCPUs) {
+			applied++
+		}
+	}
+	appliedCPUs := fmt.Sprintf("%d/%d", applied, len(workload.Spec.Node.CpuIds))
+	lastError := workload.Status.LastError
+	if applyPlan.Error != "" && applyPlan.Applied < len(applyPlan.Operations) {
+		lastError = fmt.Sprintf("%s: %s", applyPlan.Operations[applyPlan.Applied], applyPlan.Error)
+	} else if applyPlan.Error != "" {
+		lastError = applyPlan.Error
+	} else if !applyPlan.Empty() {
+		lastError = ""
+	}
+	results := r.coreResults(workload.Spec.Node.CpuIds, poolCPUs, pool, lastError)
+	if workload.Status.AppliedCPUs == appliedCPUs && workload.Status.LastError == lastError &&
+		reflect.DeepEqual(workload.Status.CoreResults, results) {
+		return nil
+	}
+
+	workload.Status.AppliedCPUs = appliedCPUs
+	workload.Status.LastError = lastError
+	workload.Status.CoreResults = results
+	return r.Client.Status().Update(c, workload)
+}
+
+// coreResults is the outcome on each of the PowerWorkload's CPUs: Failed when it isn't in the pool, with the plan's
+// error if there was one, Clamped when it runs with other frequency limits than the pool's PowerProfile, such as
+// under a thermal cap or beyond what the hardware allows, and Ok otherwise
+func (r *PowerWorkloadReconciler) coreResults(cpus []uint, poolCPUs []uint, pool power.Pool, lastError string) []powerv1.CoreApplyResult {
+	if len(cpus) == 0 {
+		return nil
+	}
+	var profile power.Profile
+	if r.Limits != nil {
+		profile = pool.GetPowerProfile()
+	}
+
+	sorted := append([]uint{}, cpus...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	results := make([]powerv1.CoreApplyResult, 0, len(sorted))
+	for _, cpu := range sorted {
+		result := powerv1.CoreApplyResult{CPU: cpu, Result: powerv1.CoreApplyOk}
+		if !coreInCoreList(cpu, poolCPUs) {
+			result.Result = powerv1.CoreApplyFailed
+			result.Reason = lastError
+			if result.Reason == "" {
+				result.Reason = "not in the PowerProfile's pool"
+			}
+		} else if profile != nil {
+			min, max, err := r.Limits.FrequencyLimits(cpu)
+			// the PowerProfile's frequencies are in kHz
+			if err == nil && (min != profile.MinFreq()/1000 || max != profile.MaxFreq()/1000) {
+				result.Result = powerv1.CoreApplyClamped
+				result.Reason = fmt.Sprintf("runs at %d-%d MHz rather than %d-%d MHz", min, max, profile.MinFreq()/1000, profile.MaxFreq()/1000)
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// siblingCPUs are the CPUs of the Node's other PowerWorkloads with the same PowerProfile, such as those created
+// by a PowerWorkloadSet, as they share the PowerProfile's pool
+func (r *PowerWorkloadReconciler) siblingCPUs(c context.Context, workload *powerv1.PowerWorkload) ([]uint, error) {
+	workloads := &powerv1.PowerWorkloadList{}
+	err := r.Client.List(c, workloads, client.MatchingFields{WorkloadNodeNameIndex: workload.Spec.Node.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	var cpus []uint
+	for _, other := range workloads.Items {
+		if other.Name == workload.Name || other.Spec.PowerProfile != workload.Spec.PowerProfile || other.DeletionTimestamp != nil {
+			continue
+		}
+		cpus = append(cpus, other.Spec.Node.CpuIds...)
+	}
+
+	return cpus, nil
+}
+
+// transitionLimit is the PowerNode's frequencyTransitionLimit, nil if the Node has none or no PowerNode yet
+func transitionLimit(c context.Context, cl client.Client, nodeName string) (*powerv1.FrequencyTransitionLimit, error) {
+	powerNode := &powerv1.PowerNode{}
+	err := cl.Get(c, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return powerNode.Spec.FrequencyTransitionLimit, nil
+}
+
+// waitTransition waits out the interval between batches of frequency transitions, overridable in tests
+var waitTransition = func(c context.Context, interval time.Duration) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-c.Done():
+		return c.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// transitions is the Node's frequency transition limiter. Every controller changing the frequency of cores goes
+// through it, so their batches are made one at a time and spaced out across the whole Node
+var transitions struct {
+	sync.Mutex
+	last time.Time
+}
+
+// moveInBatches moves the CPUs at most limit's maxCores at a time with its interval in between, as every core
+// moved changes frequency. Without a limit they are moved at once
+func moveInBatches(c context.Context, cpus []uint, limit *powerv1.FrequencyTransitionLimit, move func([]uint) error) error {
+	if limit == nil || limit.MaxCores <= 0 {
+		return poollock.Change(func() error { return move(cpus) })
+	}
+
+	transitions.Lock()
+	defer transitions.Unlock()
+	interval := time.Duration(limit.IntervalMilliseconds) * time.Millisecond
+	for start := 0; start < len(cpus); start += limit.MaxCores {
+		end := start + limit.MaxCores
+		if end > len(cpus) {
+			end = len(cpus)
+		}
+		err := transition(c, start > 0, interval, func() error { return move(cpus[start:end]) })
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// limitTransition makes a change that sets the frequency of many cores at once, such as a pool's PowerProfile,
+// spaced out from the batches of cores moved under the limit
+func limitTransition(c context.Context, limit *powerv1.FrequencyTransitionLimit, change func() error) error {
+	if limit == nil || limit.MaxCores <= 0 {
+		return poollock.Change(change)
+	}
+
+	transitions.Lock()
+	defer transitions.Unlock()
+
+	return transition(c, false, time.Duration(limit.IntervalMilliseconds)*time.Millisecond, change)
+}
+
+// removeInBatches removes the pool once its CPUs are moved back to the Shared pool under the limit, as Remove
+// would hand them all back at once
+func removeInBatches(c context.Context, host power.Host, pool power.Pool, limit *powerv1.FrequencyTransitionLimit) error {
+	if limit != nil {
+		err := moveInBatches(c, pool.Cpus().IDs(), limit, host.GetSharedPool().MoveCpuIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return poollock.Change(func() error { return pool.Remove() })
+}
+
+// transition makes the change once the interval has passed since the Node's last one, the full interval after a
+// batch of the same move. The transitions lock is held
+func transition(c context.Context, sameMove bool, interval time.Duration, change func() error) error {
+	wait := interval
+	if !sameMove {
+		wait = interval - time.Since(transitions.last)
+	}
+	if wait > 0 {
+		err := waitTransition(c, wait)
+		if err != nil {
+			return err
+		}
+	}
+	err := poollock.Change(change)
+	transitions.last = time.Now()
+
+	return err
+}
+
+func (r *PowerWorkloadReconciler) applyOperation(c context.Context, nodeName string, operation plan.Operation, workload *powerv1.PowerWorkload, limit *powerv1.FrequencyTransitionLimit, event *probe.HookEvent, logger *logr.Logger, changes *logging.ChangeSummary) error {
+	switch operation.Kind {
+	case plan.PreApplyHook:
+		event.Hook = probe.HookPreApply
+		return r.runHook(c, workload, *event, logger)
+	case plan.PostApplyHook:
+		// the change is made, a failing post apply hook can't hold it back
+		event.Hook = probe.HookPostApply
+		_ = r.runHook(c, workload, *event, logger)
+	case plan.MoveToShared:
+		err := moveInBatches(c, operation.CPUs, limit, r.PowerLibrary.GetSharedPool().MoveCpuIDs)
+		if err != nil {
+			return err
+		}
+		changes.PoolModified("shared", operation.CPUs, nil)
+		changes.PoolModified(operation.Pool, nil, operation.CPUs)
+	case plan.MoveToPool:
+		pool := r.PowerLibrary.GetExclusivePool(operation.Pool)
+		if pool == nil {
+			return fmt.Errorf("pool '%s' does not exist in Power Library", operation.Pool)
+		}
+		err := moveInBatches(c, operation.CPUs, limit, pool.MoveCpuIDs)
+		if err != nil {
+			return err
+		}
+		changes.PoolModified("shared", nil, operation.CPUs)
+		changes.PoolModified(operation.Pool, operation.CPUs, nil)
+	case plan.VerifyProfile:
+		pool := r.PowerLibrary.GetExclusivePool(operation.Pool)
+		if pool != nil {
+			r.verifyProfile(c, nodeName, pool, operation.CPUs, logger)
+		}
+	case plan.RemovePool:
+		pool := r.PowerLibrary.GetExclusivePool(operation.Pool)
+		if pool == nil {
+			return nil
+		}
+		err := removeInBatches(c, r.PowerLibrary, pool, limit)
+		if err != nil {
+			return err
+		}
+		changes.PoolRemoved(operation.Pool)
+	case plan.ClearSharedProfile:
+		err := limitTransition(c, limit, func() error { return r.PowerLibrary.GetSharedPool().SetPowerProfile(nil) })
+		if err != nil {
+			return err
+		}
+		changes.PoolModified("shared", nil, nil)
+	case plan.SetReservedCPUs:
+		err := poollock.Change(func() error { return r.PowerLibrary.GetReservedPool().SetCpuIDs(operation.CPUs) })
+		if err != nil {
+			return err
+		}
+		changes.PoolModified("reserved", operation.CPUs, nil)
+	default:
+		return fmt.Errorf("unknown plan operation '%s'", operation.Kind)
+	}
+
+	return nil
+}
+
+// runHook runs the PowerWorkload's hook for the event, returning an error only when a failing preApply hook holds
+// the change back
+func (r *PowerWorkloadReconciler) runHook(c context.Context, workload *powerv1.PowerWorkload, event probe.HookEvent, logger *logr.Logger) error {
+	if workload.Spec.Hooks == nil {
+		return nil
+	}
+	hook := workload.Spec.Hooks.PreApply
+	if event.Hook == probe.HookPostApply {
+		hook = workload.Spec.Hooks.PostApply
+	}
+	if hook == nil {
+		return nil
+	}
+	if r.Hooks == nil {
+		logger.Error(fmt.Errorf("no hook runner configured"), "cannot run PowerWorkload hook", "hook", event.Hook)
+		return nil
+	}
+
+	err := r.Hooks.RunHook(c, hook, event)
+	if err == nil {
+		logger.V(5).Info("PowerWorkload hook passed", "hook", event.Hook)
+		return nil
+	}
+	if event.Hook == probe.HookPreApply && hook.FailurePolicy != powerv1.HookFailurePolicyIgnore {
+		logger.Error(err, "PowerWorkload hook failed, holding back the change", "hook", event.Hook)
+		return err
+	}
+	logger.Error(err, "PowerWorkload hook failed", "hook", event.Hook)
+
+	return nil
+}
+
+// validateWorkload records which PowerProfile has been applied to the PowerWorkload and, if the
+// PowerWorkload has a Validation, probes it until the window passes. A failed probe restores the
+// previously applied PowerProfile and marks the PowerWorkload as Failed
+func (r *PowerWorkloadReconciler) validateWorkload(c context.Context, workload *powerv1.PowerWorkload, logger *logr.Logger) (ctrl.Result, error) {
+	validation := workload.Spec.Validation
+	status := &workload.Status
+	now := time.Now()
+
+	if status.AppliedProfile != workload.Spec.PowerProfile {
+		if status.AppliedProfile != "" {
+			status.PreviousProfile = status.AppliedProfile
+			if len(workload.Spec.Node.Containers) > 0 {
+				startTransition(status, workload.Spec.PowerProfile, now)
 			}
 		}
+		status.AppliedProfile = workload.Spec.PowerProfile
+		status.Phase = ""
+		status.ValidationStarted = nil
+		status.Message = ""
+		if validation != nil {
+			logger.V(5).Info("Starting validation of the applied PowerProfile", "profile", status.AppliedProfile)
+			status.Phase = powerv1.WorkloadPhaseValidating
+			started := metav1.NewTime(now)
+			status.ValidationStarted = &started
+		}
+
+		err := r.Client.Status().Update(c, workload)
+		if err != nil {
+			logger.Error(err, "error updating PowerWorkload status")
+			return ctrl.Result{}, err
+		}
+		if validation == nil {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{RequeueAfter: validationPeriod(validation)}, nil
+	}
+
+	if validation == nil || status.Phase != powerv1.WorkloadPhaseValidating {
+		return ctrl.Result{}, nil
+	}
+	if r.Prober == nil {
+		logger.Error(fmt.Errorf("no prober configured"), "cannot validate PowerWorkload")
+		return ctrl.Result{}, nil
+	}
+
+	passed, message, err := r.Prober.Probe(c, validation)
+	if err != nil {
+		message = err.Error()
+	}
+	if err != nil || !passed {
+		logger.Info("PowerWorkload validation failed, rolling back", "profile", status.AppliedProfile, "reason", message)
+		return ctrl.Result{}, r.rollbackWorkload(c, workload, message)
+	}
+
+	window := time.Duration(validation.WindowSeconds) * time.Second
+	if status.ValidationStarted != nil && now.Sub(status.ValidationStarted.Time) < window {
+		return ctrl.Result{RequeueAfter: validationPeriod(validation)}, nil
+	}
+
+	status.Phase = powerv1.WorkloadPhaseSucceeded
+	status.Message = message
+	err = r.Client.Status().Update(c, workload)
+	if err != nil {
+		logger.Error(err, "error updating PowerWorkload status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
 
-		if len(coresToBeAddedToLibrary) > 0 {
-			err = r.PowerLibrary.GetExclusivePool(workload.Spec.PowerProfile).MoveCpuIDs(coresToBeAddedToLibrary)
+func (r *PowerWorkloadReconciler) rollbackWorkload(c context.Context, workload *powerv1.PowerWorkload, reason string) error {
+	status := workload.Status.DeepCopy()
+	failedProfile := status.AppliedProfile
+	status.Phase = powerv1.WorkloadPhaseFailed
+	status.ValidationStarted = nil
+
+	if status.PreviousProfile == "" {
+		status.Message = fmt.Sprintf("validation of '%s' failed: %s, no previous PowerProfile to roll back to", failedProfile, reason)
+	} else {
+		status.Message = fmt.Sprintf("validation of '%s' failed: %s, rolled back to '%s'", failedProfile, reason, status.PreviousProfile)
+		// the previous profile counts as applied so that it isn't validated again
+		status.AppliedProfile = status.PreviousProfile
+		status.PreviousProfile = failedProfile
+
+		workload.Spec.PowerProfile = status.AppliedProfile
+		err := r.Client.Update(c, workload)
+		if err != nil {
+			return err
+		}
+	}
+
+	workload.Status = *status
+	return r.Client.Status().Update(c, workload)
+}
+
+// startTransition starts the settle time of a change to the PowerWorkload's frequencies, again if one is already
+// in progress
+func startTransition(status *powerv1.PowerWorkloadStatus, profile string, now time.Time) {
+	if status.Transition == nil {
+		status.Transition = &powerv1.WorkloadTransition{}
+	}
+	started := metav1.NewTime(now)
+	status.Transition.Profile = profile
+	status.Transition.Started = &started
+}
+
+// protectTransition keeps other controllers from evicting the PowerWorkload's Pods while its transition settles.
+// The Pods are annotated and labelled, and covered by PodDisruptionBudgets if enabled, until the settle time has
+// passed and no validation is running
+func (r *PowerWorkloadReconciler) protectTransition(c context.Context, workload *powerv1.PowerWorkload, result ctrl.Result, logger *logr.Logger, changes *logging.ChangeSummary) (ctrl.Result, error) {
+	transition := workload.Status.Transition
+	if transition == nil {
+		return result, nil
+	}
+
+	var remaining time.Duration
+	if transition.Started != nil {
+		remaining = transitionSettleTime - time.Since(transition.Started.Time)
+	}
+	if remaining <= 0 && workload.Status.Phase != powerv1.WorkloadPhaseValidating {
+		logger.V(5).Info("PowerProfile transition settled, lifting the eviction protection", "profile", transition.Profile)
+		err := r.endTransition(c, workload.Name, changes)
+		if err != nil {
+			logger.Error(err, "error lifting the eviction protection of the PowerWorkload's Pods")
+			return ctrl.Result{}, err
+		}
+		workload.Status.Transition = nil
+		err = r.Client.Status().Update(c, workload)
+		if err != nil {
+			logger.Error(err, "error updating PowerWorkload status")
+			return ctrl.Result{}, err
+		}
+
+		return result, nil
+	}
+
+	pods, err := r.transitionPods(c, workload)
+	if err != nil {
+		logger.Error(err, "error retrieving the PowerWorkload's Pods")
+		return ctrl.Result{}, err
+	}
+	protected := make([]string, 0, len(pods))
+	namespaces := make([]string, 0)
+	for _, pod := range pods {
+		err = r.markTransitionPod(c, client.ObjectKeyFromObject(&pod), workload.Name, transition.Profile)
+		if err != nil {
+			logger.Error(err, "error marking Pod for the PowerProfile transition", "pod", pod.Name)
+			return ctrl.Result{}, err
+		}
+		protected = append(protected, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+		if !util.StringInStringList(pod.Namespace, namespaces) {
+			namespaces = append(namespaces, pod.Namespace)
+		}
+	}
+
+	budgets := make([]string, 0)
+	if r.TransitionPodDisruptionBudgets {
+		for _, namespace := range namespaces {
+			budget, err := r.createTransitionBudget(c, workload.Name, namespace, changes)
 			if err != nil {
-				logger.Error(err, "error updating Power Library Cpu list")
+				logger.Error(err, "error creating PodDisruptionBudget for the PowerProfile transition", "namespace", namespace)
 				return ctrl.Result{}, err
 			}
+			budgets = append(budgets, budget)
 		}
 	}
 
-	return ctrl.Result{}, nil
+	if !reflect.DeepEqual(transition.Pods, protected) || !reflect.DeepEqual(transition.PodDisruptionBudgets, budgets) {
+		transition.Pods = protected
+		transition.PodDisruptionBudgets = budgets
+		err = r.Client.Status().Update(c, workload)
+		if err != nil {
+			logger.Error(err, "error updating PowerWorkload status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if remaining > 0 && (result.RequeueAfter == 0 || remaining < result.RequeueAfter) {
+		result.RequeueAfter = remaining
+	}
+
+	return result, nil
+}
+
+// transitionPods returns the Pods on the PowerWorkload's Node that have containers in it
+func (r *PowerWorkloadReconciler) transitionPods(c context.Context, workload *powerv1.PowerWorkload) ([]corev1.Pod, error) {
+	podNames := make([]string, 0, len(workload.Spec.Node.Containers))
+	for _, container := range workload.Spec.Node.Containers {
+		podNames = append(podNames, container.Pod)
+	}
+
+	podList := &corev1.PodList{}
+	err := r.Client.List(c, podList)
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]corev1.Pod, 0, len(podNames))
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == workload.Spec.Node.Name && util.StringInStringList(pod.Name, podNames) {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods, nil
+}
+
+// markTransitionPod annotates the Pod with the PowerProfile its CPUs are changing to and labels it for the
+// PodDisruptionBudgets, or removes both when profile is empty
+func (r *PowerWorkloadReconciler) markTransitionPod(c context.Context, key client.ObjectKey, workloadName string, profile string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pod := &corev1.Pod{}
+		err := r.Client.Get(c, key, pod)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		if profile == "" {
+			if _, marked := pod.Labels[TransitionWorkloadLabel]; !marked {
+				return nil
+			}
+			delete(pod.Labels, TransitionWorkloadLabel)
+			delete(pod.Annotations, ProfileTransitionAnnotation)
+		} else {
+			if pod.Labels[TransitionWorkloadLabel] == workloadName && pod.Annotations[ProfileTransitionAnnotation] == profile {
+				return nil
+			}
+			if pod.Labels == nil {
+				pod.Labels = make(map[string]string)
+			}
+			if pod.Annotations == nil {
+				pod.Annotations = make(map[string]string)
+			}
+			pod.Labels[TransitionWorkloadLabel] = workloadName
+			pod.Annotations[ProfileTransitionAnnotation] = profile
+		}
+
+		return r.Client.Update(c, pod)
+	})
+}
+
+// createTransitionBudget makes sure a PodDisruptionBudget that allows no disruptions covers the PowerWorkload's
+// Pods in the namespace and returns it as namespace/name
+func (r *PowerWorkloadReconciler) createTransitionBudget(c context.Context, workloadName string, namespace string, changes *logging.ChangeSummary) (string, error) {
+	maxUnavailable := intstr.FromInt(0)
+	budget := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workloadName + "-transition",
+			Namespace: namespace,
+			Labels:    map[string]string{TransitionWorkloadLabel: workloadName},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{TransitionWorkloadLabel: workloadName},
+			},
+		},
+	}
+	name := fmt.Sprintf("%s/%s", namespace, budget.Name)
+
+	err := r.Client.Create(c, budget)
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			return name, nil
+		}
+		return "", err
+	}
+	changes.ResourceAdded("PodDisruptionBudget", name)
+
+	return name, nil
+}
+
+// endTransition removes the marks of the PowerWorkload's transition from its Pods and deletes its
+// PodDisruptionBudgets
+func (r *PowerWorkloadReconciler) endTransition(c context.Context, workloadName string, changes *logging.ChangeSummary) error {
+	selector := client.MatchingLabels{TransitionWorkloadLabel: workloadName}
+
+	pods := &corev1.PodList{}
+	err := r.Client.List(c, pods, selector)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		err = r.markTransitionPod(c, client.ObjectKeyFromObject(&pod), workloadName, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	budgets := &policyv1.PodDisruptionBudgetList{}
+	err = r.Client.List(c, budgets, selector)
+	if err != nil {
+		return err
+	}
+	for _, budget := range budgets.Items {
+		err = r.Client.Delete(c, &budget)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		changes.ResourceRemoved("PodDisruptionBudget", fmt.Sprintf("%s/%s", budget.Namespace, budget.Name))
+	}
+
+	return nil
+}
+
+func validationPeriod(validation *powerv1.WorkloadValidation) time.Duration {
+	if validation.PeriodSeconds <= 0 {
+		return 10 * time.Second
+	}
+
+	return time.Duration(validation.PeriodSeconds) * time.Second
 }
 
 func detectCoresRemoved(originalCoreList []uint, updatedCoreList []uint, logger *logr.Logger) []uint {
@@ -206,8 +996,169 @@ func coreInCoreList(core uint, coreList []uint) bool {
 	return false
 }
 
+// SetupPowerWorkloadIndexer registers the Node name index on PowerWorkloads so that
+// per-Node lookups don't need to list and filter every PowerWorkload in the cluster
+func SetupPowerWorkloadIndexer(ctx context.Context, mgr ctrl.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(ctx, &powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer)
+}
+
+func workloadNodeNameIndexer(obj client.Object) []string {
+	workload, ok := obj.(*powerv1.PowerWorkload)
+	if !ok || workload.Spec.Node.Name == "" {
+		return nil
+	}
+
+	return []string{workload.Spec.Node.Name}
+}
+
+// nodeWorkloadRequests requeues every PowerWorkload on this Node, used to reapply them when a maintenance ends
+func (r *PowerWorkloadReconciler) nodeWorkloadRequests(obj client.Object) []reconcile.Request {
+	workloads := &powerv1.PowerWorkloadList{}
+	err := r.Client.List(context.TODO(), workloads, client.MatchingFields{WorkloadNodeNameIndex: os.Getenv("NODE_NAME")})
+	if err != nil {
+		r.Log.Error(err, "error listing PowerWorkloads on this Node")
+		return nil
+	}
+	// the Shared PowerWorkloads select their Nodes by label rather than by name
+	allWorkloads := &powerv1.PowerWorkloadList{}
+	err = r.Client.List(context.TODO(), allWorkloads, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		r.Log.Error(err, "error listing PowerWorkloads")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(workloads.Items))
+	for _, workload := range workloads.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&workload)})
+	}
+	for _, workload := range allWorkloads.Items {
+		if workload.Spec.AllCores && workload.Spec.Node.Name != os.Getenv("NODE_NAME") {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&workload)})
+		}
+	}
+
+	return requests
+}
+
+// verifyProfile samples a few of the CPUs just added to the pool and records in the PowerNode's status whether they
+// run within the frequency range of the pool's PowerProfile. A failed verification doesn't fail the reconcile
+func (r *PowerWorkloadReconciler) verifyProfile(c context.Context, nodeName string, pool power.Pool, cpus []uint, logger *logr.Logger) {
+	profile := pool.GetPowerProfile()
+	if r.Sampler == nil || profile == nil || len(cpus) == 0 {
+		return
+	}
+	if len(cpus) > verificationCPUs {
+		cpus = cpus[:verificationCPUs]
+	}
+
+	samples, err := r.Sampler.Sample(cpus)
+	if err != nil {
+		logger.Error(err, "error sampling perf counters to verify the PowerProfile", "profile", profile.Name())
+		return
+	}
+	verification := profileVerification(profile, cpus, samples, time.Now())
+	overridden := 0.0
+	if verification.Result == powerv1.VerificationOverridden {
+		overridden = 1
+		logger.Info("CPUs run outside the PowerProfile's frequency range, BIOS settings may override it",
+			"profile", profile.Name(), "measuredFrequency", verification.MeasuredFrequency,
+			"expectedMinFrequency", verification.ExpectedMinFrequency, "expectedMaxFrequency", verification.ExpectedMaxFrequency)
+	}
+	if verification.Result != powerv1.VerificationInconclusive {
+		metrics.ProfileOverridden.WithLabelValues(nodeName, profile.Name()).Set(overridden)
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(c, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+		if err != nil {
+			return err
+		}
+
+		verifications := make([]powerv1.ProfileVerification, 0, len(powerNode.Status.ProfileVerifications)+1)
+		for _, existing := range powerNode.Status.ProfileVerifications {
+			if existing.Profile != verification.Profile {
+				verifications = append(verifications, existing)
+			}
+		}
+		powerNode.Status.ProfileVerifications = append(verifications, verification)
+		objectsize.Warn(*logger, "PowerNode", powerNode)
+		return r.Client.Status().Update(c, powerNode)
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "error recording the PowerProfile verification in the PowerNode status")
+	}
+}
+
+// profileVerification compares the average frequency of the busy samples with the profile's range. Frequencies
+// of mostly idle CPUs are left out as they say little, IPC is recorded alongside to tell memory bound workloads
+// apart from slowed down ones
+func profileVerification(profile power.Profile, cpus []uint, samples []perf.Sample, now time.Time) powerv1.ProfileVerification {
+	verification := powerv1.ProfileVerification{
+		Profile:              profile.Name(),
+		CPUs:                 cpus,
+		ExpectedMinFrequency: int(profile.MinFreq() / 1000),
+		ExpectedMaxFrequency: int(profile.MaxFreq() / 1000),
+		Result:               powerv1.VerificationInconclusive,
+		LastVerified:         metav1.NewTime(now),
+	}
+
+	busy := 0
+	frequency := 0
+	ipc := 0.0
+	for _, sample := range samples {
+		if sample.Busy < verificationMinBusy {
+			continue
+		}
+		busy++
+		frequency += sample.FrequencyMHz
+		ipc += sample.IPC
+	}
+	if busy == 0 {
+		return verification
+	}
+
+	verification.MeasuredFrequency = frequency / busy
+	verification.MeasuredIPC = strconv.FormatFloat(ipc/float64(busy), 'f', 2, 64)
+	lowest := float64(verification.ExpectedMinFrequency) * (1 - verificationTolerance)
+	highest := float64(verification.ExpectedMaxFrequency) * (1 + verificationTolerance)
+	if float64(verification.MeasuredFrequency) < lowest || float64(verification.MeasuredFrequency) > highest {
+		verification.Result = powerv1.VerificationOverridden
+	} else {
+		verification.Result = powerv1.VerificationVerified
+	}
+
+	return verification
+}
+
 func (r *PowerWorkloadReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&powerv1.PowerWorkload{}).
+		Watches(&source.Kind{Type: &powerv1.PowerMaintenance{}}, handler.EnqueueRequestsFromMapFunc(r.nodeWorkloadRequests)).
+		Watches(&source.Kind{Type: &powerv1.PowerWorkload{}}, handler.EnqueueRequestsFromMapFunc(r.siblingWorkloadRequests)).
 		Complete(r)
 }
+
+// siblingWorkloadRequests reconciles the PowerWorkloads sharing a pool with one that changed or was deleted, so
+// the pool keeps the CPUs of all of them and no more
+func (r *PowerWorkloadReconciler) siblingWorkloadRequests(obj client.Object) []reconcile.Request {
+	changed, ok := obj.(*powerv1.PowerWorkload)
+	if !ok || changed.Spec.Node.Name != os.Getenv("NODE_NAME") {
+		return nil
+	}
+	workloads := &powerv1.PowerWorkloadList{}
+	err := r.Client.List(context.TODO(), workloads, client.MatchingFields{WorkloadNodeNameIndex: changed.Spec.Node.Name})
+	if err != nil {
+		r.Log.Error(err, "error listing PowerWorkloads on this Node")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, workload := range workloads.Items {
+		if workload.Name != changed.Name && workload.Spec.PowerProfile == changed.Spec.PowerProfile {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&workload)})
+		}
+	}
+
+	return requests
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
@@ -18,9 +22,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/correlation"
+	"github.com/intel/kubernetes-power-manager/pkg/perf"
+	"github.com/intel/kubernetes-power-manager/pkg/plan"
+	"github.com/intel/kubernetes-power-manager/pkg/probe"
+	"github.com/intel/power-optimization-library/pkg/power"
 	"github.com/stretchr/testify/mock"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 )
 
 func createWorkloadReconcilerObject(objs []runtime.Object) (*PowerWorkloadReconciler, error) {
@@ -33,10 +44,11 @@ func createWorkloadReconcilerObject(objs []runtime.Object) (*PowerWorkloadReconc
 	}
 
 	// Create a fake client to mock API calls.
-	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).
+		WithIndex(&powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer).Build()
 
 	// Create a ReconcileNode object with the scheme and fake client.
-	r := &PowerWorkloadReconciler{cl, ctrl.Log.WithName("testing"), s, nil}
+	r := &PowerWorkloadReconciler{cl, ctrl.Log.WithName("testing"), s, nil, nil, nil, nil, false, nil, nil}
 
 	return r, nil
 }
@@ -181,6 +193,24 @@ func TestPowerWorkload(t *testing.T) {
 	poolmk.AssertExpectations(t)
 	assert.Error(t, err)
 
+	// shared pool held off while the Node is under maintenance
+	maintenance := &powerv1.PowerMaintenance{
+		ObjectMeta: metav1.ObjectMeta{Name: "firmware-update", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerMaintenanceSpec{NodeName: testNode},
+	}
+	r, err = createWorkloadReconcilerObject([]runtime.Object{pwrWorkloadObj, nodesObj, maintenance})
+	assert.NoError(t, err, "Failed to create reconciler object")
+	nodemk = new(hostMock)
+	r.PowerLibrary = nodemk
+
+	sharedPowerWorkloadName = ""
+	req.Name = workloadName
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.Nil(t, err)
+	nodemk.AssertNotCalled(t, "GetReservedPool")
+	assert.Empty(t, sharedPowerWorkloadName)
+	assert.Contains(t, r.nodeWorkloadRequests(maintenance), reconcile.Request{NamespacedName: req.NamespacedName})
+
 	// successful add shared pool
 	r, err = createWorkloadReconcilerObject([]runtime.Object{pwrWorkloadObj, nodesObj})
 	assert.NoError(t, err, "Failed to create reconciler object")
@@ -216,3 +246,743 @@ func Test_detectCoresAdded(t *testing.T) {
 	result := detectCoresAdded(orig, updated, &logr.Logger{})
 	assert.ElementsMatch(t, result, expectedResult)
 }
+
+func Test_workloadNodeNameIndexer(t *testing.T) {
+	workload := &powerv1.PowerWorkload{
+		Spec: powerv1.PowerWorkloadSpec{
+			Node: powerv1.WorkloadNode{Name: "TestNode"},
+		},
+	}
+	assert.Equal(t, []string{"TestNode"}, workloadNodeNameIndexer(workload))
+
+	workload.Spec.Node.Name = ""
+	assert.Empty(t, workloadNodeNameIndexer(workload))
+	assert.Empty(t, workloadNodeNameIndexer(&powerv1.PowerProfile{}))
+}
+
+func Test_buildWorkloadPlan(t *testing.T) {
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance-TestNode"},
+		Spec:       powerv1.PowerWorkloadSpec{PowerProfile: "performance"},
+	}
+	event := probe.HookEvent{
+		Workload:    workload.Name,
+		Node:        "TestNode",
+		Profile:     "performance",
+		AddedCPUs:   []uint{5},
+		RemovedCPUs: []uint{3},
+	}
+
+	// CPUs are given back before new ones are taken, between the hooks
+	applyPlan := buildWorkloadPlan(workload, event, true, time.Now())
+	assert.Equal(t, []string{
+		"1. PreApplyHook",
+		"2. MoveToShared performance [3]",
+		"3. MoveToPool performance [5]",
+		"4. VerifyProfile performance [5]",
+		"5. PostApplyHook",
+	}, applyPlan.Steps())
+	assert.Equal(t, "TestNode", applyPlan.Node)
+
+	event.AddedCPUs = nil
+	event.RemovedCPUs = nil
+	assert.True(t, buildWorkloadPlan(workload, event, false, time.Now()).Empty())
+}
+
+func TestPowerWorkloadPlanHistory(t *testing.T) {
+	r, err := createWorkloadReconcilerObject([]runtime.Object{})
+	assert.NoError(t, err)
+	r.Plans = plan.NewHistory(10)
+
+	sharedPool := new(poolMock)
+	sharedPool.On("MoveCpuIDs", []uint{3}).Return(fmt.Errorf("cpu 3 is busy"))
+	nodemk := new(hostMock)
+	nodemk.On("GetSharedPool").Return(sharedPool)
+	r.PowerLibrary = nodemk
+
+	applyPlan := plan.New("TestNode", "performance-TestNode", time.Now())
+	applyPlan.Add(plan.MoveToShared, "performance", []uint{3})
+	applyPlan.Add(plan.MoveToPool, "performance", []uint{5})
+	logger := r.Log
+	err = r.applyPlan(context.TODO(), applyPlan, nil, nil, &logger, nil)
+	assert.ErrorContains(t, err, "cpu 3 is busy")
+
+	// the failed plan is kept with how far it got
+	plans := r.Plans.Plans()
+	assert.Len(t, plans, 1)
+	assert.Equal(t, 0, plans[0].Applied)
+	assert.Equal(t, "cpu 3 is busy", plans[0].Error)
+}
+
+func TestPowerWorkloadRecordApplied(t *testing.T) {
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance-TestNode",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerWorkloadSpec{
+			PowerProfile: "performance",
+			Node: powerv1.WorkloadNode{
+				Name:   "TestNode",
+				CpuIds: []uint{2, 3, 5},
+			},
+		},
+	}
+	r, err := createWorkloadReconcilerObject([]runtime.Object{workload})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(workload), workload))
+
+	cores := make(power.CpuList, 0)
+	for _, id := range []uint{2, 3} {
+		core := new(coreMock)
+		core.On("GetID").Return(id)
+		cores = append(cores, core)
+	}
+	pool := new(poolMock)
+	pool.On("Cpus").Return(&cores)
+
+	// a failed plan reports the operation it stopped at
+	applyPlan := plan.New("TestNode", workload.Name, time.Now())
+	applyPlan.Add(plan.MoveToPool, "performance", []uint{5})
+	applyPlan.Error = "cpu 5 is busy"
+	assert.NoError(t, r.recordApplied(context.TODO(), workload, pool, applyPlan))
+
+	updated := &powerv1.PowerWorkload{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(workload), updated))
+	assert.Equal(t, "2/3", updated.Status.AppliedCPUs)
+	assert.Equal(t, "MoveToPool performance [5]: cpu 5 is busy", updated.Status.LastError)
+	assert.Equal(t, []powerv1.CoreApplyResult{
+		{CPU: 2, Result: powerv1.CoreApplyOk},
+		{CPU: 3, Result: powerv1.CoreApplyOk},
+		{CPU: 5, Result: powerv1.CoreApplyFailed, Reason: "MoveToPool performance [5]: cpu 5 is busy"},
+	}, updated.Status.CoreResults)
+
+	// the error is cleared once a plan applies in full, a CPU held below the PowerProfile's max is clamped
+	profile := new(profMock)
+	profile.On("MinFreq").Return(uint(3300000))
+	profile.On("MaxFreq").Return(uint(3500000))
+	pool.On("GetPowerProfile").Return(profile)
+	r.Limits = fakeFrequencyLimits{2: {3300, 3500}, 3: {3300, 3400}}
+	workload = updated.DeepCopy()
+	applyPlan.Error = ""
+	applyPlan.Applied = 1
+	assert.NoError(t, r.recordApplied(context.TODO(), workload, pool, applyPlan))
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(workload), updated))
+	assert.Empty(t, updated.Status.LastError)
+	assert.Equal(t, []powerv1.CoreApplyResult{
+		{CPU: 2, Result: powerv1.CoreApplyOk},
+		{CPU: 3, Result: powerv1.CoreApplyClamped, Reason: "runs at 3300-3400 MHz rather than 3300-3500 MHz"},
+		{CPU: 5, Result: powerv1.CoreApplyFailed, Reason: "not in the PowerProfile's pool"},
+	}, updated.Status.CoreResults)
+}
+
+// powerNodeErrorClient fails every Get of a PowerNode, as when the API server times out
+type powerNodeErrorClient struct {
+	client.Client
+}
+
+func (c *powerNodeErrorClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if _, ok := obj.(*powerv1.PowerNode); ok {
+		return fmt.Errorf("the server was unable to return a response in the time allotted")
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func TestPowerWorkloadTransitionLimitError(t *testing.T) {
+	t.Setenv("NODE_NAME", "TestNode")
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance-TestNode",
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerWorkloadSpec{
+			PowerProfile: "performance",
+			Node: powerv1.WorkloadNode{
+				Name:   "TestNode",
+				CpuIds: []uint{2, 3},
+			},
+		},
+		Status: powerv1.PowerWorkloadStatus{AppliedProfile: "performance"},
+	}
+	r, err := createWorkloadReconcilerObject([]runtime.Object{workload})
+	assert.NoError(t, err)
+	r.Client = &powerNodeErrorClient{Client: r.Client}
+	r.Plans = plan.NewHistory(10)
+
+	cores := make(power.CpuList, 0)
+	for _, id := range []uint{2, 3} {
+		core := new(coreMock)
+		core.On("GetID").Return(id)
+		cores = append(cores, core)
+	}
+	pool := new(poolMock)
+	pool.On("Cpus").Return(&cores)
+	nodemk := new(hostMock)
+	nodemk.On("GetExclusivePool", "performance").Return(pool)
+	r.PowerLibrary = nodemk
+
+	// the pool already matches, the empty plan isn't blamed for the failed lookup of the limit
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(workload)}
+	assert.NotPanics(t, func() {
+		_, err = r.Reconcile(context.TODO(), req)
+	})
+	assert.ErrorContains(t, err, "unable to return a response")
+	assert.Empty(t, r.Plans.Plans())
+
+	updated := &powerv1.PowerWorkload{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, updated))
+	assert.Empty(t, updated.Status.LastError)
+}
+
+// fakeFrequencyLimits holds the min and max MHz of each CPU
+type fakeFrequencyLimits map[uint][2]uint
+
+func (f fakeFrequencyLimits) FrequencyLimits(cpu uint) (uint, uint, error) {
+	limits, exists := f[cpu]
+	if !exists {
+		return 0, 0, os.ErrNotExist
+	}
+	return limits[0], limits[1], nil
+}
+
+type proberMock struct {
+	mock.Mock
+}
+
+func (m *proberMock) Probe(ctx context.Context, validation *powerv1.WorkloadValidation) (bool, string, error) {
+	args := m.Called(validation)
+	return args.Bool(0), args.String(1), args.Error(2)
+}
+
+func TestPowerWorkloadValidation(t *testing.T) {
+	validation := &powerv1.WorkloadValidation{
+		PrometheusURL:   "http://prometheus:9090",
+		PrometheusQuery: "job:latency_p99:5m < 0.01",
+		WindowSeconds:   60,
+		PeriodSeconds:   10,
+	}
+	started := metav1.NewTime(time.Now().Add(-30 * time.Second))
+	expired := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+
+	tcases := []struct {
+		testCase        string
+		profile         string
+		validation      *powerv1.WorkloadValidation
+		status          powerv1.PowerWorkloadStatus
+		probePassed     bool
+		probeErr        error
+		requeue         bool
+		expectedProfile string
+		expectedStatus  powerv1.PowerWorkloadStatus
+	}{
+		{
+			testCase:        "Test Case 1 - new profile starts validation",
+			profile:         "balance-power",
+			validation:      validation,
+			status:          powerv1.PowerWorkloadStatus{AppliedProfile: "performance"},
+			requeue:         true,
+			expectedProfile: "balance-power",
+			expectedStatus: powerv1.PowerWorkloadStatus{
+				Phase:           powerv1.WorkloadPhaseValidating,
+				AppliedProfile:  "balance-power",
+				PreviousProfile: "performance",
+			},
+		},
+		{
+			testCase:        "Test Case 2 - probe passing within window keeps validating",
+			profile:         "balance-power",
+			validation:      validation,
+			status:          powerv1.PowerWorkloadStatus{Phase: powerv1.WorkloadPhaseValidating, AppliedProfile: "balance-power", PreviousProfile: "performance", ValidationStarted: &started},
+			probePassed:     true,
+			requeue:         true,
+			expectedProfile: "balance-power",
+			expectedStatus:  powerv1.PowerWorkloadStatus{Phase: powerv1.WorkloadPhaseValidating, AppliedProfile: "balance-power", PreviousProfile: "performance"},
+		},
+		{
+			testCase:        "Test Case 3 - probe passing after window succeeds",
+			profile:         "balance-power",
+			validation:      validation,
+			status:          powerv1.PowerWorkloadStatus{Phase: powerv1.WorkloadPhaseValidating, AppliedProfile: "balance-power", PreviousProfile: "performance", ValidationStarted: &expired},
+			probePassed:     true,
+			expectedProfile: "balance-power",
+			expectedStatus:  powerv1.PowerWorkloadStatus{Phase: powerv1.WorkloadPhaseSucceeded, AppliedProfile: "balance-power", PreviousProfile: "performance"},
+		},
+		{
+			testCase:        "Test Case 4 - failing probe rolls back",
+			profile:         "balance-power",
+			validation:      validation,
+			status:          powerv1.PowerWorkloadStatus{Phase: powerv1.WorkloadPhaseValidating, AppliedProfile: "balance-power", PreviousProfile: "performance", ValidationStarted: &started},
+			expectedProfile: "performance",
+			expectedStatus:  powerv1.PowerWorkloadStatus{Phase: powerv1.WorkloadPhaseFailed, AppliedProfile: "performance", PreviousProfile: "balance-power"},
+		},
+		{
+			testCase:        "Test Case 5 - probe error without previous profile fails in place",
+			profile:         "balance-power",
+			validation:      validation,
+			status:          powerv1.PowerWorkloadStatus{Phase: powerv1.WorkloadPhaseValidating, AppliedProfile: "balance-power", ValidationStarted: &started},
+			probeErr:        errors.New("connection refused"),
+			expectedProfile: "balance-power",
+			expectedStatus:  powerv1.PowerWorkloadStatus{Phase: powerv1.WorkloadPhaseFailed, AppliedProfile: "balance-power"},
+		},
+		{
+			testCase:        "Test Case 6 - no validation only records the applied profile",
+			profile:         "balance-power",
+			status:          powerv1.PowerWorkloadStatus{AppliedProfile: "performance"},
+			expectedProfile: "balance-power",
+			expectedStatus:  powerv1.PowerWorkloadStatus{AppliedProfile: "balance-power", PreviousProfile: "performance"},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		workload := &powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "balance-power-TestNode",
+				Namespace: IntelPowerNamespace,
+			},
+			Spec: powerv1.PowerWorkloadSpec{
+				PowerProfile: tc.profile,
+				Validation:   tc.validation,
+			},
+			Status: tc.status,
+		}
+		r, err := createWorkloadReconcilerObject([]runtime.Object{workload.DeepCopy()})
+		assert.NoError(t, err)
+		prober := new(proberMock)
+		prober.On("Probe", mock.Anything).Return(tc.probePassed, "probe result", tc.probeErr)
+		r.Prober = prober
+
+		err = r.Client.Get(context.TODO(), client.ObjectKeyFromObject(workload), workload)
+		assert.NoError(t, err)
+		logger := r.Log
+		result, err := r.validateWorkload(context.TODO(), workload, &logger)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.requeue, result.RequeueAfter > 0)
+
+		updated := &powerv1.PowerWorkload{}
+		err = r.Client.Get(context.TODO(), client.ObjectKeyFromObject(workload), updated)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedProfile, updated.Spec.PowerProfile)
+		assert.Equal(t, tc.expectedStatus.Phase, updated.Status.Phase)
+		assert.Equal(t, tc.expectedStatus.AppliedProfile, updated.Status.AppliedProfile)
+		assert.Equal(t, tc.expectedStatus.PreviousProfile, updated.Status.PreviousProfile)
+		if tc.expectedStatus.Phase == powerv1.WorkloadPhaseFailed {
+			assert.Contains(t, updated.Status.Message, "validation of 'balance-power' failed")
+		}
+	}
+}
+
+func TestWorkloadProberRestrictions(t *testing.T) {
+	queried := make([]string, 0)
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queried = append(queried, r.URL.Query().Get("query"))
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[1700000000,"1"]}}`)
+	}))
+	defer prometheus.Close()
+	prober := &probe.Prober{
+		HTTPClient: prometheus.Client(),
+		Clientset: k8sfake.NewSimpleClientset(
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "TestNode"}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "OtherNode"}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: "kube-system"}, Spec: corev1.PodSpec{NodeName: "TestNode"}},
+		),
+		NodeName:       "TestNode",
+		ExecNamespaces: []string{"default"},
+		PrometheusURL:  prometheus.URL,
+	}
+
+	// commands only run in allowed namespaces, in Pods on the agent's Node
+	_, _, err := prober.Probe(context.TODO(), &powerv1.WorkloadValidation{
+		Exec: &powerv1.ExecProbe{Namespace: "kube-system", Pod: "etcd", Command: []string{"true"}},
+	})
+	assert.ErrorContains(t, err, "namespace kube-system")
+	_, _, err = prober.Probe(context.TODO(), &powerv1.WorkloadValidation{
+		Exec: &powerv1.ExecProbe{Namespace: "default", Pod: "other", Command: []string{"true"}},
+	})
+	assert.ErrorContains(t, err, "isn't on Node TestNode")
+	err = prober.RunHook(context.TODO(), &powerv1.WorkloadHook{
+		Exec: &powerv1.ExecProbe{Namespace: "kube-system", Pod: "etcd", Command: []string{"true"}},
+	}, probe.HookEvent{Hook: probe.HookPreApply})
+	assert.ErrorContains(t, err, "namespace kube-system")
+
+	// hooks only call out to URLs under the allowed prefixes
+	called := make([]string, 0)
+	hooks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = append(called, r.URL.Path)
+	}))
+	defer hooks.Close()
+	prober.HookURLPrefixes = []string{hooks.URL + "/power/"}
+	err = prober.RunHook(context.TODO(), &powerv1.WorkloadHook{URL: hooks.URL + "/power/flush"}, probe.HookEvent{Hook: probe.HookPreApply})
+	assert.NoError(t, err)
+	err = prober.RunHook(context.TODO(), &powerv1.WorkloadHook{URL: hooks.URL + "/admin"}, probe.HookEvent{Hook: probe.HookPreApply})
+	assert.ErrorContains(t, err, "doesn't start with one of the Node Agent's hook URL prefixes")
+	err = prober.RunHook(context.TODO(), &powerv1.WorkloadHook{URL: "http://169.254.169.254/latest"}, probe.HookEvent{Hook: probe.HookPreApply})
+	assert.ErrorContains(t, err, "doesn't start with one of the Node Agent's hook URL prefixes")
+	assert.Equal(t, []string{"/power/flush"}, called)
+	prober.HookURLPrefixes = nil
+	err = prober.RunHook(context.TODO(), &powerv1.WorkloadHook{URL: hooks.URL + "/power/flush"}, probe.HookEvent{Hook: probe.HookPreApply})
+	assert.ErrorContains(t, err, "doesn't start with one of the Node Agent's hook URL prefixes")
+	assert.Len(t, called, 1)
+
+	// queries only go to the configured Prometheus
+	passed, _, err := prober.Probe(context.TODO(), &powerv1.WorkloadValidation{PrometheusQuery: "up"})
+	assert.NoError(t, err)
+	assert.True(t, passed)
+	passed, _, err = prober.Probe(context.TODO(), &powerv1.WorkloadValidation{PrometheusURL: prometheus.URL + "/", PrometheusQuery: "up"})
+	assert.NoError(t, err)
+	assert.True(t, passed)
+	_, _, err = prober.Probe(context.TODO(), &powerv1.WorkloadValidation{PrometheusURL: "http://169.254.169.254", PrometheusQuery: "up"})
+	assert.ErrorContains(t, err, "isn't the Prometheus server configured")
+	assert.Equal(t, []string{"up", "up"}, queried)
+	prober.PrometheusURL = ""
+	_, _, err = prober.Probe(context.TODO(), &powerv1.WorkloadValidation{PrometheusQuery: "up"})
+	assert.ErrorContains(t, err, "no Prometheus server is configured")
+}
+
+type samplerMock struct {
+	mock.Mock
+}
+
+func (m *samplerMock) Sample(cpus []uint) ([]perf.Sample, error) {
+	args := m.Called(cpus)
+	return args.Get(0).([]perf.Sample), args.Error(1)
+}
+
+func TestProfileVerification(t *testing.T) {
+	nodeName := "TestNode"
+	tcases := []struct {
+		testCase          string
+		samples           []perf.Sample
+		expectedResult    string
+		expectedFrequency int
+		expectedIPC       string
+	}{
+		{
+			testCase:          "Test Case 1 - frequency within the range",
+			samples:           []perf.Sample{{CPU: 4, FrequencyMHz: 2900, IPC: 1.5, Busy: 0.9}, {CPU: 5, FrequencyMHz: 3100, IPC: 0.5, Busy: 0.8}},
+			expectedResult:    powerv1.VerificationVerified,
+			expectedFrequency: 3000,
+			expectedIPC:       "1.00",
+		},
+		{
+			testCase:          "Test Case 2 - frequency locked below the range",
+			samples:           []perf.Sample{{CPU: 4, FrequencyMHz: 2000, IPC: 1.2, Busy: 0.9}, {CPU: 5, FrequencyMHz: 3000, IPC: 1.2, Busy: 0.05}},
+			expectedResult:    powerv1.VerificationOverridden,
+			expectedFrequency: 2000,
+			expectedIPC:       "1.20",
+		},
+		{
+			testCase:       "Test Case 3 - idle CPUs",
+			samples:        []perf.Sample{{CPU: 4, FrequencyMHz: 800, Busy: 0.01}},
+			expectedResult: powerv1.VerificationInconclusive,
+		},
+	}
+
+	for _, tc := range tcases {
+		powerNode := &powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nodeName,
+				Namespace: IntelPowerNamespace,
+			},
+			Status: powerv1.PowerNodeStatus{
+				ProfileVerifications: []powerv1.ProfileVerification{
+					{Profile: "performance", Result: powerv1.VerificationInconclusive},
+					{Profile: "balance-power", Result: powerv1.VerificationVerified},
+				},
+			},
+		}
+		r, err := createWorkloadReconcilerObject([]runtime.Object{powerNode})
+		if err != nil {
+			t.Fatalf("%s - error creating reconciler object: %v", tc.testCase, err)
+		}
+		sampler := new(samplerMock)
+		sampler.On("Sample", []uint{4, 5, 6}).Return(tc.samples, nil)
+		r.Sampler = sampler
+		profile := new(profMock)
+		profile.On("Name").Return("performance")
+		profile.On("MinFreq").Return(uint(2800000))
+		profile.On("MaxFreq").Return(uint(3500000))
+		pool := new(poolMock)
+		pool.On("GetPowerProfile").Return(profile)
+		logger := r.Log
+
+		r.verifyProfile(context.TODO(), nodeName, pool, []uint{4, 5, 6, 7}, &logger)
+		sampler.AssertExpectations(t)
+
+		updated := &powerv1.PowerNode{}
+		if err = r.Client.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), updated); err != nil {
+			t.Fatalf("%s - error retrieving PowerNode: %v", tc.testCase, err)
+		}
+		verifications := updated.Status.ProfileVerifications
+		assert.Len(t, verifications, 2, tc.testCase)
+		assert.Equal(t, "balance-power", verifications[0].Profile, tc.testCase)
+		verification := verifications[1]
+		assert.Equal(t, "performance", verification.Profile, tc.testCase)
+		assert.Equal(t, []uint{4, 5, 6}, verification.CPUs, tc.testCase)
+		assert.Equal(t, tc.expectedResult, verification.Result, tc.testCase)
+		assert.Equal(t, tc.expectedFrequency, verification.MeasuredFrequency, tc.testCase)
+		assert.Equal(t, tc.expectedIPC, verification.MeasuredIPC, tc.testCase)
+		assert.Equal(t, 2800, verification.ExpectedMinFrequency, tc.testCase)
+	}
+
+	// without a sampler verification is disabled
+	r, err := createWorkloadReconcilerObject([]runtime.Object{})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	pool := new(poolMock)
+	pool.On("GetPowerProfile").Return(new(profMock))
+	logger := r.Log
+	r.verifyProfile(context.TODO(), nodeName, pool, []uint{4}, &logger)
+}
+
+func TestProfileTransition(t *testing.T) {
+	nodeName := "TestNode"
+	workloadName := "performance-TestNode"
+	started := metav1.NewTime(time.Now())
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workloadName,
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerWorkloadSpec{
+			Name: workloadName,
+			Node: powerv1.WorkloadNode{
+				Name: nodeName,
+				Containers: []powerv1.Container{
+					{Name: "app", Pod: "app-pod", PowerProfile: "performance"},
+					{Name: "db", Pod: "db-pod", PowerProfile: "performance"},
+				},
+			},
+			PowerProfile: "performance",
+		},
+		Status: powerv1.PowerWorkloadStatus{
+			Transition: &powerv1.WorkloadTransition{Profile: "performance", Started: &started},
+		},
+	}
+	pod := func(name string, namespace string, node string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       corev1.PodSpec{NodeName: node},
+		}
+	}
+	r, err := createWorkloadReconcilerObject([]runtime.Object{
+		workload,
+		pod("app-pod", "default", nodeName),
+		pod("db-pod", "storage", nodeName),
+		// same name on another Node
+		pod("app-pod", "other", "OtherNode"),
+	})
+	if err != nil {
+		t.Fatalf("error creating reconciler object: %v", err)
+	}
+	r.TransitionPodDisruptionBudgets = true
+	logger := r.Log
+	getPod := func(namespace string, name string) *corev1.Pod {
+		updated := &corev1.Pod{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: namespace}, updated); err != nil {
+			t.Fatalf("error retrieving Pod: %v", err)
+		}
+		return updated
+	}
+	listBudgets := func() []policyv1.PodDisruptionBudget {
+		budgets := &policyv1.PodDisruptionBudgetList{}
+		if err := r.Client.List(context.TODO(), budgets); err != nil {
+			t.Fatalf("error listing PodDisruptionBudgets: %v", err)
+		}
+		return budgets.Items
+	}
+
+	result, err := r.protectTransition(context.TODO(), workload, ctrl.Result{}, &logger, nil)
+	assert.NoError(t, err)
+	assert.Greater(t, result.RequeueAfter, time.Duration(0))
+	assert.LessOrEqual(t, result.RequeueAfter, transitionSettleTime)
+	for _, key := range []client.ObjectKey{{Namespace: "default", Name: "app-pod"}, {Namespace: "storage", Name: "db-pod"}} {
+		marked := getPod(key.Namespace, key.Name)
+		assert.Equal(t, "performance", marked.Annotations[ProfileTransitionAnnotation])
+		assert.Equal(t, workloadName, marked.Labels[TransitionWorkloadLabel])
+	}
+	assert.NotContains(t, getPod("other", "app-pod").Labels, TransitionWorkloadLabel)
+	assert.Len(t, listBudgets(), 2)
+	assert.Equal(t, []string{"default/app-pod", "storage/db-pod"}, workload.Status.Transition.Pods)
+	assert.Equal(t, []string{"default/performance-TestNode-transition", "storage/performance-TestNode-transition"},
+		workload.Status.Transition.PodDisruptionBudgets)
+
+	// still protected while a validation is running
+	past := metav1.NewTime(time.Now().Add(-2 * transitionSettleTime))
+	workload.Status.Transition.Started = &past
+	workload.Status.Phase = powerv1.WorkloadPhaseValidating
+	_, err = r.protectTransition(context.TODO(), workload, ctrl.Result{}, &logger, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, workload.Status.Transition)
+	assert.Len(t, listBudgets(), 2)
+
+	workload.Status.Phase = powerv1.WorkloadPhaseSucceeded
+	result, err = r.protectTransition(context.TODO(), workload, ctrl.Result{}, &logger, nil)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	assert.Nil(t, workload.Status.Transition)
+	unmarked := getPod("default", "app-pod")
+	assert.NotContains(t, unmarked.Annotations, ProfileTransitionAnnotation)
+	assert.NotContains(t, unmarked.Labels, TransitionWorkloadLabel)
+	assert.Empty(t, listBudgets())
+}
+
+type hookRunnerMock struct {
+	mock.Mock
+}
+
+func (m *hookRunnerMock) RunHook(ctx context.Context, hook *powerv1.WorkloadHook, event probe.HookEvent) error {
+	args := m.Called(hook, event)
+	return args.Error(0)
+}
+
+func TestPowerWorkloadHooks(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	preApply := &powerv1.WorkloadHook{URL: "http://cache-flusher:8080/pre"}
+	postApply := &powerv1.WorkloadHook{Exec: &powerv1.ExecProbe{Namespace: "default", Pod: "db", Command: []string{"flush-caches"}}}
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "performance-TestNode", Namespace: IntelPowerNamespace}}
+	tcases := []struct {
+		testCase      string
+		failurePolicy string
+		preApplyErr   error
+		expectErr     bool
+		expectApplied bool
+	}{
+		{
+			testCase:      "Test Case 1 - passing preApply hook lets the change through",
+			expectApplied: true,
+		},
+		{
+			testCase:    "Test Case 2 - failing preApply hook holds the change back",
+			preApplyErr: errors.New("connection refused"),
+			expectErr:   true,
+		},
+		{
+			testCase:      "Test Case 3 - failing preApply hook is ignored",
+			failurePolicy: powerv1.HookFailurePolicyIgnore,
+			preApplyErr:   errors.New("connection refused"),
+			expectApplied: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		pre := preApply.DeepCopy()
+		pre.FailurePolicy = tc.failurePolicy
+		workload := &powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        req.Name,
+				Namespace:   IntelPowerNamespace,
+				Annotations: map[string]string{correlation.Annotation: "change-42"},
+			},
+			Spec: powerv1.PowerWorkloadSpec{
+				PowerProfile: "performance",
+				Node:         powerv1.WorkloadNode{Name: nodeName, CpuIds: []uint{2, 3}},
+				Hooks:        &powerv1.WorkloadHooks{PreApply: pre, PostApply: postApply},
+			},
+		}
+		r, err := createWorkloadReconcilerObject([]runtime.Object{
+			workload,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}},
+		})
+		assert.NoError(t, err)
+		pool := new(poolMock)
+		pool.On("Cpus").Return(&power.CpuList{})
+		pool.On("MoveCpuIDs", []uint{2, 3}).Return(nil)
+		pool.On("GetPowerProfile").Return(nil)
+		host := new(hostMock)
+		host.On("GetExclusivePool", "performance").Return(pool)
+		r.PowerLibrary = host
+		hooks := new(hookRunnerMock)
+		expectedEvent := probe.HookEvent{Workload: req.Name, Node: nodeName, Profile: "performance", AddedCPUs: []uint{2, 3}, CorrelationID: "change-42"}
+		preEvent := expectedEvent
+		preEvent.Hook = probe.HookPreApply
+		postEvent := expectedEvent
+		postEvent.Hook = probe.HookPostApply
+		hooks.On("RunHook", pre, preEvent).Return(tc.preApplyErr)
+		hooks.On("RunHook", postApply, postEvent).Return(nil)
+		r.Hooks = hooks
+
+		_, err = r.Reconcile(context.TODO(), req)
+		if tc.expectErr {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+		if tc.expectApplied {
+			pool.AssertCalled(t, "MoveCpuIDs", []uint{2, 3})
+			hooks.AssertCalled(t, "RunHook", postApply, postEvent)
+		} else {
+			pool.AssertNotCalled(t, "MoveCpuIDs", mock.Anything)
+			hooks.AssertNotCalled(t, "RunHook", postApply, mock.Anything)
+		}
+	}
+}
+
+func TestFrequencyTransitionBatches(t *testing.T) {
+	originalWaitTransition := waitTransition
+	defer func() { waitTransition = originalWaitTransition }()
+	waits := make([]time.Duration, 0)
+	waitTransition = func(c context.Context, interval time.Duration) error {
+		waits = append(waits, interval)
+		return nil
+	}
+	transitions.last = time.Time{}
+
+	batches := make([][]uint, 0)
+	move := func(cpus []uint) error {
+		batches = append(batches, append([]uint{}, cpus...))
+		return nil
+	}
+	cpus := []uint{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	// without a limit every core moves at once
+	assert.NoError(t, moveInBatches(context.TODO(), cpus, nil, move))
+	assert.Equal(t, [][]uint{cpus}, batches)
+	assert.Empty(t, waits)
+
+	batches = batches[:0]
+	limit := &powerv1.FrequencyTransitionLimit{MaxCores: 4, IntervalMilliseconds: 100}
+	assert.NoError(t, moveInBatches(context.TODO(), cpus, limit, move))
+	assert.Equal(t, [][]uint{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10}}, batches)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, waits)
+
+	// a change made right after the batches, such as maintenance clearing the Shared profile, waits out the rest
+	// of the interval since the Node's last transition
+	waits = waits[:0]
+	changed := false
+	assert.NoError(t, limitTransition(context.TODO(), limit, func() error {
+		changed = true
+		return nil
+	}))
+	assert.True(t, changed)
+	if assert.Len(t, waits, 1) {
+		assert.True(t, waits[0] > 0 && waits[0] <= 100*time.Millisecond)
+	}
+
+	// a failed batch stops the rest
+	batches = batches[:0]
+	failing := func(cpus []uint) error {
+		batches = append(batches, cpus)
+		return fmt.Errorf("cpu %d offline", cpus[0])
+	}
+	assert.Error(t, moveInBatches(context.TODO(), cpus, limit, failing))
+	assert.Len(t, batches, 1)
+
+	// the limit is read from the Node's PowerNode
+	r, err := createWorkloadReconcilerObject([]runtime.Object{
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "TestNode", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerNodeSpec{FrequencyTransitionLimit: limit},
+		},
+	})
+	assert.NoError(t, err)
+	nodeLimit, err := transitionLimit(context.TODO(), r.Client, "TestNode")
+	assert.NoError(t, err)
+	assert.Equal(t, limit, nodeLimit)
+	nodeLimit, err = transitionLimit(context.TODO(), r.Client, "OtherNode")
+	assert.NoError(t, err)
+	assert.Nil(t, nodeLimit)
+}
@@ -0,0 +1,257 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+// WorkloadSetLabel names the PowerWorkloadSet a PowerWorkload was created for
+const WorkloadSetLabel = "power.intel.com/powerworkloadset"
+
+// defaultWritesPerSecond is used for PowerWorkloadSets created before writesPerSecond had a default
+const defaultWritesPerSecond = 20
+
+// PowerWorkloadSetReconciler expands each PowerWorkloadSet into a PowerWorkload per Node, owned by the set so
+// they're garbage collected with it. Writes are spread over seconds at the set's rate, so a batch system
+// submitting one set for hundreds of Nodes doesn't flood the API server
+type PowerWorkloadSetReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// MaxConcurrentReconciles is how many objects are reconciled at once, 1 if 0
+	MaxConcurrentReconciles int
+
+	mutex   sync.Mutex
+	windows map[types.NamespacedName]*writeWindow
+}
+
+// writeWindow counts a set's writes in the current second, across the reconciles its own PowerWorkloads trigger
+type writeWindow struct {
+	start  time.Time
+	writes int
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloadsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloadsets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloads,verbs=get;list;watch;create;update;patch;delete
+
+func (r *PowerWorkloadSetReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("powerworkloadset", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+
+	set := &powerv1.PowerWorkloadSet{}
+	err := r.Client.Get(c, req.NamespacedName, set)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// the PowerWorkloads are garbage collected through their owner references
+			r.forget(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error retrieving the PowerWorkloadSet")
+		return ctrl.Result{}, err
+	}
+
+	children := &powerv1.PowerWorkloadList{}
+	err = r.Client.List(c, children, client.InNamespace(IntelPowerNamespace), client.MatchingLabels{WorkloadSetLabel: set.Name})
+	if err != nil {
+		logger.Error(err, "error retrieving the PowerWorkloads of the set")
+		return ctrl.Result{}, err
+	}
+	existing := make(map[string]*powerv1.PowerWorkload, len(children.Items))
+	for i := range children.Items {
+		existing[children.Items[i].Name] = &children.Items[i]
+	}
+
+	rate := set.Spec.WritesPerSecond
+	if rate <= 0 {
+		rate = defaultWritesPerSecond
+	}
+	window := r.window(req.NamespacedName)
+	budget := rate - window.writes
+	writes := 0
+	ready := 0
+	conflicting := []string{}
+	entries := append([]powerv1.PowerWorkloadSetEntry{}, set.Spec.Workloads...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Node < entries[j].Node })
+	for _, entry := range entries {
+		name := workloadSetChildName(set, entry.Node)
+		child, exists := existing[name]
+		delete(existing, name)
+		desired := powerv1.PowerWorkloadSpec{
+			Name:         name,
+			Node:         powerv1.WorkloadNode{Name: entry.Node, CpuIds: entry.CpuIds},
+			PowerProfile: set.Spec.PowerProfile,
+		}
+		if exists && reflect.DeepEqual(child.Spec, desired) {
+			ready++
+			continue
+		}
+		if writes >= budget {
+			continue
+		}
+		writes++
+
+		if exists {
+			child.Spec = desired
+			err = r.Client.Update(c, child)
+			if err != nil {
+				logger.Error(err, "error updating the PowerWorkload", "workload", name)
+				return ctrl.Result{}, err
+			}
+			ready++
+			continue
+		}
+		child = &powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: IntelPowerNamespace,
+				Labels:    map[string]string{WorkloadSetLabel: set.Name},
+			},
+			Spec: desired,
+		}
+		err = controllerutil.SetControllerReference(set, child, r.Scheme)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		err = r.Client.Create(c, child)
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				logger.Info("PowerWorkload exists but wasn't created by the set, leaving it alone", "workload", name)
+				conflicting = append(conflicting, name)
+				continue
+			}
+			logger.Error(err, "error creating the PowerWorkload", "workload", name)
+			return ctrl.Result{}, err
+		}
+		ready++
+	}
+
+	// PowerWorkloads of Nodes removed from the set
+	stale := make([]string, 0, len(existing))
+	for name := range existing {
+		stale = append(stale, name)
+	}
+	sort.Strings(stale)
+	for _, name := range stale {
+		if writes >= budget {
+			break
+		}
+		writes++
+		err = r.Client.Delete(c, existing[name])
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "error deleting the PowerWorkload", "workload", name)
+			return ctrl.Result{}, err
+		}
+		delete(existing, name)
+	}
+
+	window.writes += writes
+	pending := len(entries) - ready - len(conflicting) + len(existing)
+	if len(conflicting) == 0 {
+		conflicting = nil
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &powerv1.PowerWorkloadSet{}
+		err := r.Client.Get(c, req.NamespacedName, latest)
+		if err != nil {
+			return err
+		}
+		status := powerv1.PowerWorkloadSetStatus{
+			ObservedGeneration: set.Generation,
+			Workloads:          len(entries),
+			ReadyWorkloads:     ready,
+			Conflicting:        conflicting,
+		}
+		if reflect.DeepEqual(latest.Status, status) {
+			return nil
+		}
+		latest.Status = status
+		return r.Client.Status().Update(c, latest)
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error updating the PowerWorkloadSet status")
+		return ctrl.Result{}, err
+	}
+
+	if pending > 0 {
+		logger.V(5).Info("Write budget used up, continuing in the next second", "pending", pending)
+		return ctrl.Result{RequeueAfter: time.Until(window.start.Add(time.Second))}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// window is the set's write window for the current second, a new one once a second has passed
+func (r *PowerWorkloadSetReconciler) window(name types.NamespacedName) *writeWindow {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.windows == nil {
+		r.windows = make(map[types.NamespacedName]*writeWindow)
+	}
+	window, exists := r.windows[name]
+	if !exists || time.Since(window.start) >= time.Second {
+		window = &writeWindow{start: time.Now()}
+		r.windows[name] = window
+	}
+
+	return window
+}
+
+// forget drops the write window of a deleted set
+func (r *PowerWorkloadSetReconciler) forget(name types.NamespacedName) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.windows, name)
+}
+
+// workloadSetChildName is the name of the set's PowerWorkload on the Node
+func workloadSetChildName(set *powerv1.PowerWorkloadSet, node string) string {
+	return fmt.Sprintf("%s-%s", set.Name, node)
+}
+
+func (r *PowerWorkloadSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.PowerWorkloadSet{}).
+		Owns(&powerv1.PowerWorkload{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createWorkloadSetReconcilerObject(objs []runtime.Object) (*PowerWorkloadSetReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).
+		WithIndex(&powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer).Build()
+
+	return &PowerWorkloadSetReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s}, nil
+}
+
+func TestPowerWorkloadSetReconciler(t *testing.T) {
+	objs := []runtime.Object{
+		&powerv1.PowerWorkloadSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: IntelPowerNamespace, UID: "batch-uid"},
+			Spec: powerv1.PowerWorkloadSetSpec{
+				PowerProfile: "performance",
+				Workloads: []powerv1.PowerWorkloadSetEntry{
+					{Node: "node3", CpuIds: []uint{4, 5}},
+					{Node: "node1", CpuIds: []uint{2, 3}},
+					{Node: "node2", CpuIds: []uint{2}},
+					{Node: "node4", CpuIds: []uint{8}},
+				},
+				WritesPerSecond: 2,
+			},
+		},
+		// created by hand with the name the set wants for node4
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch-node4", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerWorkloadSpec{Name: "batch-node4", PowerProfile: "balance-power"},
+		},
+	}
+	r, err := createWorkloadSetReconcilerObject(objs)
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "batch", Namespace: IntelPowerNamespace}}
+	getSet := func() *powerv1.PowerWorkloadSet {
+		set := &powerv1.PowerWorkloadSet{}
+		assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, set))
+		return set
+	}
+	children := func() []string {
+		workloads := &powerv1.PowerWorkloadList{}
+		assert.NoError(t, r.Client.List(context.TODO(), workloads, client.MatchingLabels{WorkloadSetLabel: "batch"}))
+		names := []string{}
+		for _, workload := range workloads.Items {
+			names = append(names, workload.Name)
+		}
+		return names
+	}
+	nextSecond := func() {
+		r.windows[req.NamespacedName].start = time.Now().Add(-time.Second)
+	}
+
+	// two writes a second, in Node order
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Greater(t, result.RequeueAfter, time.Duration(0))
+	assert.ElementsMatch(t, []string{"batch-node1", "batch-node2"}, children())
+	set := getSet()
+	assert.Equal(t, 4, set.Status.Workloads)
+	assert.Equal(t, 2, set.Status.ReadyWorkloads)
+
+	workload := &powerv1.PowerWorkload{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "batch-node1", Namespace: IntelPowerNamespace}, workload))
+	assert.Equal(t, "node1", workload.Spec.Node.Name)
+	assert.Equal(t, []uint{2, 3}, workload.Spec.Node.CpuIds)
+	assert.Equal(t, "performance", workload.Spec.PowerProfile)
+	if assert.Len(t, workload.OwnerReferences, 1) {
+		assert.Equal(t, "PowerWorkloadSet", workload.OwnerReferences[0].Kind)
+		assert.Equal(t, "batch", workload.OwnerReferences[0].Name)
+	}
+
+	// the reconciles triggered by the set's own PowerWorkloads wait for the next second
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Len(t, children(), 2)
+
+	// the hand made PowerWorkload is left alone
+	nextSecond()
+	result, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	assert.ElementsMatch(t, []string{"batch-node1", "batch-node2", "batch-node3"}, children())
+	set = getSet()
+	assert.Equal(t, 3, set.Status.ReadyWorkloads)
+	assert.Equal(t, []string{"batch-node4"}, set.Status.Conflicting)
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "batch-node4", Namespace: IntelPowerNamespace}, workload))
+	assert.Equal(t, "balance-power", workload.Spec.PowerProfile)
+
+	// Nodes removed from the set lose their PowerWorkload, changed ones are updated
+	set.Spec.Workloads = []powerv1.PowerWorkloadSetEntry{{Node: "node1", CpuIds: []uint{2, 3, 4}}}
+	assert.NoError(t, r.Client.Update(context.TODO(), set))
+	nextSecond()
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"batch-node1", "batch-node3"}, children())
+	nextSecond()
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"batch-node1"}, children())
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: "batch-node1", Namespace: IntelPowerNamespace}, workload))
+	assert.Equal(t, []uint{2, 3, 4}, workload.Spec.Node.CpuIds)
+	set = getSet()
+	assert.Equal(t, 1, set.Status.Workloads)
+	assert.Equal(t, 1, set.Status.ReadyWorkloads)
+	assert.Empty(t, set.Status.Conflicting)
+}
+
+func TestPowerWorkloadSiblingCPUs(t *testing.T) {
+	objs := []runtime.Object{
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "performance-node1", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerWorkloadSpec{PowerProfile: "performance", Node: powerv1.WorkloadNode{Name: "node1", CpuIds: []uint{1}}},
+		},
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch-node1", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerWorkloadSpec{PowerProfile: "performance", Node: powerv1.WorkloadNode{Name: "node1", CpuIds: []uint{2, 3}}},
+		},
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "balance-power-node1", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerWorkloadSpec{PowerProfile: "balance-power", Node: powerv1.WorkloadNode{Name: "node1", CpuIds: []uint{4}}},
+		},
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch-node2", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerWorkloadSpec{PowerProfile: "performance", Node: powerv1.WorkloadNode{Name: "node2", CpuIds: []uint{5}}},
+		},
+	}
+	set, err := createWorkloadSetReconcilerObject(objs)
+	assert.NoError(t, err)
+	r := &PowerWorkloadReconciler{Client: set.Client, Log: ctrl.Log.WithName("testing")}
+
+	workload := objs[0].(*powerv1.PowerWorkload)
+	cpus, err := r.siblingCPUs(context.TODO(), workload)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []uint{2, 3}, cpus)
+
+	t.Setenv("NODE_NAME", "node1")
+	requests := r.siblingWorkloadRequests(workload)
+	assert.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKey{Name: "batch-node1", Namespace: IntelPowerNamespace}}}, requests)
+}
@@ -0,0 +1,178 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+// ProfileCompatibilityReconciler records in each PowerProfile's status the CPU models of the Nodes whose Node Agent
+// has applied it, so admins can tell whether a profile has been tried on a hardware generation before extending it
+// there. Models are kept once recorded, a Node leaving doesn't undo what was validated on it
+type ProfileCompatibilityReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// MaxConcurrentReconciles is how many objects are reconciled at once, 1 if 0
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+
+func (r *ProfileCompatibilityReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("powerprofile", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+
+	powerNodes := &powerv1.PowerNodeList{}
+	err := r.Client.List(c, powerNodes, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		logger.Error(err, "error listing the PowerNodes")
+		return ctrl.Result{}, err
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		profile := &powerv1.PowerProfile{}
+		err := r.Client.Get(c, req.NamespacedName, profile)
+		if err != nil {
+			return err
+		}
+		validated := validatedCPUModels(profile, powerNodes.Items, time.Now())
+		if reflect.DeepEqual(profile.Status.ValidatedCPUModels, validated) {
+			return nil
+		}
+
+		profile.Status.ValidatedCPUModels = validated
+		return r.Client.Status().Update(c, profile)
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "error updating the PowerProfile status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// validatedCPUModels adds the CPU models of the Nodes that have applied the PowerProfile to those already recorded,
+// ordered by family and model. An entry moves on to a newer generation once a Node with the model applies it
+func validatedCPUModels(profile *powerv1.PowerProfile, powerNodes []powerv1.PowerNode, now time.Time) []powerv1.ValidatedCPUModel {
+	validated := make([]powerv1.ValidatedCPUModel, 0, len(profile.Status.ValidatedCPUModels))
+	for _, entry := range profile.Status.ValidatedCPUModels {
+		validated = append(validated, *entry.DeepCopy())
+	}
+
+	for _, powerNode := range powerNodes {
+		generation := powerNode.Status.AppliedProfiles[profile.Name]
+		model := powerNode.Status.CPUModel
+		if generation == 0 || model == nil {
+			continue
+		}
+		found := false
+		for i := range validated {
+			entry := &validated[i]
+			if entry.Family != model.Family || entry.Model != model.Model {
+				continue
+			}
+			found = true
+			if generation > entry.Generation {
+				entry.Generation = generation
+				entry.LastValidated = metav1.Time{Time: now}
+			}
+			if entry.Name == "" {
+				entry.Name = model.Name
+			}
+		}
+		if !found {
+			validated = append(validated, powerv1.ValidatedCPUModel{
+				CPUModel:      *model,
+				Generation:    generation,
+				LastValidated: metav1.Time{Time: now},
+			})
+		}
+	}
+	if len(validated) == 0 {
+		return nil
+	}
+	sort.Slice(validated, func(i, j int) bool {
+		if validated[i].Family != validated[j].Family {
+			return validated[i].Family < validated[j].Family
+		}
+		return validated[i].Model < validated[j].Model
+	})
+
+	return validated
+}
+
+func (r *ProfileCompatibilityReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("profilecompatibility").
+		For(&powerv1.PowerProfile{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &powerv1.PowerNode{}}, handler.EnqueueRequestsFromMapFunc(r.appliedProfileRequests),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					oldNode, oldOk := e.ObjectOld.(*powerv1.PowerNode)
+					newNode, newOk := e.ObjectNew.(*powerv1.PowerNode)
+					return oldOk && newOk && (!reflect.DeepEqual(oldNode.Status.AppliedProfiles, newNode.Status.AppliedProfiles) ||
+						!reflect.DeepEqual(oldNode.Status.CPUModel, newNode.Status.CPUModel))
+				},
+			})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+// appliedProfileRequests checks the PowerProfiles a Node has applied when it applies one or reports its CPU model
+func (r *ProfileCompatibilityReconciler) appliedProfileRequests(obj client.Object) []reconcile.Request {
+	powerNode, ok := obj.(*powerv1.PowerNode)
+	if !ok {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(powerNode.Status.AppliedProfiles))
+	for name := range powerNode.Status.AppliedProfiles {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Name: name, Namespace: IntelPowerNamespace}})
+	}
+
+	return requests
+}
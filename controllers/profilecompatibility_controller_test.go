@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+func TestProfileCompatibilityReconciler(t *testing.T) {
+	assert.NoError(t, powerv1.AddToScheme(scheme.Scheme))
+	sapphireRapids := &powerv1.CPUModel{Family: 6, Model: 143, Name: "Intel(R) Xeon(R) Platinum 8480+"}
+	icelake := &powerv1.CPUModel{Family: 6, Model: 106, Name: "Intel(R) Xeon(R) Gold 6338N"}
+	earlier := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	profile := &powerv1.PowerProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance", Namespace: IntelPowerNamespace, Generation: 3},
+		Spec:       powerv1.PowerProfileSpec{Name: "performance", Epp: "performance"},
+		Status: powerv1.PowerProfileStatus{
+			// validated on Skylake before its Nodes were retired
+			ValidatedCPUModels: []powerv1.ValidatedCPUModel{
+				{CPUModel: powerv1.CPUModel{Family: 6, Model: 85}, Generation: 1, LastValidated: earlier},
+				{CPUModel: *icelake, Generation: 2, LastValidated: earlier},
+			},
+		},
+	}
+	nodes := []client.Object{
+		profile,
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "spr-1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{CPUModel: sapphireRapids, AppliedProfiles: map[string]int64{"performance": 3}},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "icx-1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{CPUModel: icelake, AppliedProfiles: map[string]int64{"performance": 3}},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "icx-2", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{CPUModel: icelake, AppliedProfiles: map[string]int64{"performance": 2}},
+		},
+		// a Node that hasn't applied the profile, and one whose CPU model is unknown, validate nothing
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "emr-1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{CPUModel: &powerv1.CPUModel{Family: 6, Model: 207}},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm-1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{AppliedProfiles: map[string]int64{"performance": 3}},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(nodes...).Build()
+	r := &ProfileCompatibilityReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: scheme.Scheme}
+
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(profile)}
+	_, err := r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cl.Get(context.TODO(), req.NamespacedName, profile))
+	validated := profile.Status.ValidatedCPUModels
+	if assert.Len(t, validated, 3) {
+		assert.Equal(t, 85, validated[0].Model)
+		assert.Equal(t, int64(1), validated[0].Generation)
+		assert.True(t, validated[0].LastValidated.Equal(&earlier))
+		assert.Equal(t, 106, validated[1].Model)
+		assert.Equal(t, int64(3), validated[1].Generation)
+		assert.True(t, validated[1].LastValidated.After(earlier.Time))
+		assert.Equal(t, *sapphireRapids, validated[2].CPUModel)
+		assert.Equal(t, int64(3), validated[2].Generation)
+	}
+
+	// the Nodes that applied a profile are mapped to it
+	requests := r.appliedProfileRequests(nodes[1])
+	assert.Equal(t, []reconcile.Request{req}, requests)
+}
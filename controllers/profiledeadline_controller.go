@@ -0,0 +1,276 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/correlation"
+)
+
+// ProfileDeadlineReconciler gives each change to a PowerProfile with an applyDeadlineSeconds a bounded outcome: the
+// profile is Applied once every Node Agent has applied its generation, or Failed when the deadline passes first, in
+// which case the spec last applied everywhere is restored if the profile asks for a rollback
+type ProfileDeadlineReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// MaxConcurrentReconciles is how many objects are reconciled at once, 1 if 0
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *ProfileDeadlineReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("powerprofile", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+
+	profile := &powerv1.PowerProfile{}
+	err := r.Client.Get(c, req.NamespacedName, profile)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	id := correlation.ID(profile)
+	logger = logger.WithValues(correlation.LogKey, id)
+
+	status := profile.Status.DeepCopy()
+	if profile.Spec.ApplyDeadlineSeconds <= 0 {
+		// the deadline was removed, what was tracked for it no longer means anything
+		status.ObservedGeneration = 0
+		status.ApplyStarted = nil
+		status.PendingNodes = nil
+		meta.RemoveStatusCondition(&status.Conditions, powerv1.ConditionApplied)
+		meta.RemoveStatusCondition(&status.Conditions, powerv1.ConditionFailed)
+		return ctrl.Result{}, r.updateStatus(c, profile, status)
+	}
+
+	now := time.Now()
+	if status.ObservedGeneration != profile.Generation || status.ApplyStarted == nil {
+		startApply(profile, status, now)
+	}
+
+	pending, err := r.pendingNodes(c, profile)
+	if err != nil {
+		logger.Error(err, "error listing the PowerNodes")
+		return ctrl.Result{}, err
+	}
+	status.PendingNodes = pending
+
+	result := ctrl.Result{}
+	deadline := status.ApplyStarted.Add(time.Duration(profile.Spec.ApplyDeadlineSeconds) * time.Second)
+	failed := meta.IsStatusConditionTrue(status.Conditions, powerv1.ConditionFailed)
+	switch {
+	case len(pending) == 0:
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               powerv1.ConditionApplied,
+			Status:             metav1.ConditionTrue,
+			Reason:             powerv1.ReasonAppliedOnAllNodes,
+			Message:            "Every Node has applied the PowerProfile",
+			ObservedGeneration: profile.Generation,
+		})
+		status.LastAppliedSpec = profile.Spec.DeepCopy()
+	case failed:
+		// the outcome of this generation is already decided, the Nodes may still catch up
+	case !now.Before(deadline):
+		message := fmt.Sprintf("Not applied within %d seconds on %s", profile.Spec.ApplyDeadlineSeconds, strings.Join(pending, ", "))
+		condition := metav1.Condition{
+			Type:               powerv1.ConditionFailed,
+			Status:             metav1.ConditionTrue,
+			Reason:             powerv1.ReasonApplyDeadlineExceeded,
+			Message:            message,
+			ObservedGeneration: profile.Generation,
+		}
+		rollback := profile.Spec.RollbackOnDeadline && status.LastAppliedSpec != nil &&
+			!reflect.DeepEqual(profile.Spec, *status.LastAppliedSpec)
+		if rollback && isConfigProfile(profile) {
+			// the PowerConfig would only put its spec back
+			logger.Info("Not rolling back a PowerProfile managed by a PowerConfig")
+			rollback = false
+		}
+		if rollback {
+			condition.Reason = powerv1.ReasonRolledBack
+			condition.Message = message + ", restored the spec last applied on every Node"
+		}
+		meta.SetStatusCondition(&status.Conditions, condition)
+		r.Recorder.AnnotatedEventf(profile, correlation.EventAnnotations(id), corev1.EventTypeWarning, condition.Reason,
+			"%s (correlation ID %s)", condition.Message, id)
+		err = r.updateStatus(c, profile, status)
+		if err != nil {
+			logger.Error(err, "error updating the PowerProfile status")
+			return ctrl.Result{}, err
+		}
+		if rollback {
+			logger.Info("Rolling back the PowerProfile", "pendingNodes", pending)
+			profile.Spec = *status.LastAppliedSpec.DeepCopy()
+			return ctrl.Result{}, r.Client.Update(c, profile)
+		}
+		return ctrl.Result{}, nil
+	default:
+		result.RequeueAfter = deadline.Sub(now)
+	}
+
+	err = r.updateStatus(c, profile, status)
+	if err != nil {
+		logger.Error(err, "error updating the PowerProfile status")
+		return ctrl.Result{}, err
+	}
+
+	return result, nil
+}
+
+// startApply starts the deadline of the PowerProfile's generation. A Failed condition is reset unless the
+// generation is the rollback it reported
+func startApply(profile *powerv1.PowerProfile, status *powerv1.PowerProfileStatus, now time.Time) {
+	failed := meta.FindStatusCondition(status.Conditions, powerv1.ConditionFailed)
+	rolledBack := failed != nil && failed.Status == metav1.ConditionTrue && failed.Reason == powerv1.ReasonRolledBack &&
+		status.LastAppliedSpec != nil && reflect.DeepEqual(profile.Spec, *status.LastAppliedSpec)
+
+	status.ObservedGeneration = profile.Generation
+	status.ApplyStarted = &metav1.Time{Time: now}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               powerv1.ConditionApplied,
+		Status:             metav1.ConditionFalse,
+		Reason:             powerv1.ReasonApplying,
+		Message:            "Waiting for the Nodes to apply the PowerProfile",
+		ObservedGeneration: profile.Generation,
+	})
+	if !rolledBack {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               powerv1.ConditionFailed,
+			Status:             metav1.ConditionFalse,
+			Reason:             powerv1.ReasonWithinDeadline,
+			Message:            fmt.Sprintf("The Nodes have %d seconds to apply the PowerProfile", profile.Spec.ApplyDeadlineSeconds),
+			ObservedGeneration: profile.Generation,
+		})
+	}
+}
+
+// pendingNodes returns the Nodes, sorted, whose Node Agent hasn't applied the PowerProfile's generation yet.
+// Nodes whose PowerNodeGroups don't offer the PowerProfile never apply it and aren't waited for
+func (r *ProfileDeadlineReconciler) pendingNodes(c context.Context, profile *powerv1.PowerProfile) ([]string, error) {
+	powerNodes := &powerv1.PowerNodeList{}
+	err := r.Client.List(c, powerNodes, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, powerNode := range powerNodes.Items {
+		if !profileOffered(&powerNode, profile) {
+			continue
+		}
+		if powerNode.Status.AppliedProfiles[profile.Name] < profile.Generation {
+			pending = append(pending, powerNode.Name)
+		}
+	}
+	sort.Strings(pending)
+
+	return pending, nil
+}
+
+// profileOffered reports whether the Node applies the PowerProfile, which only a PowerConfig's PowerProfile
+// outside the Node's PowerNodeGroups doesn't
+func profileOffered(powerNode *powerv1.PowerNode, profile *powerv1.PowerProfile) bool {
+	if len(powerNode.Status.NodeGroups) == 0 || !isConfigProfile(profile) {
+		return true
+	}
+	for _, effective := range powerNode.Status.EffectiveProfiles {
+		if effective.Name == profile.Spec.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *ProfileDeadlineReconciler) updateStatus(c context.Context, profile *powerv1.PowerProfile, status *powerv1.PowerProfileStatus) error {
+	if reflect.DeepEqual(profile.Status, *status) {
+		return nil
+	}
+	profile.Status = *status
+
+	return r.Client.Status().Update(c, profile)
+}
+
+func (r *ProfileDeadlineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("profiledeadline").
+		For(&powerv1.PowerProfile{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &powerv1.PowerNode{}}, handler.EnqueueRequestsFromMapFunc(r.deadlineProfileRequests),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					oldNode, oldOk := e.ObjectOld.(*powerv1.PowerNode)
+					newNode, newOk := e.ObjectNew.(*powerv1.PowerNode)
+					return oldOk && newOk && !reflect.DeepEqual(oldNode.Status.AppliedProfiles, newNode.Status.AppliedProfiles)
+				},
+			})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+// deadlineProfileRequests checks the PowerProfiles with an applyDeadlineSeconds again when a Node applies a
+// PowerProfile, or joins or leaves the cluster
+func (r *ProfileDeadlineReconciler) deadlineProfileRequests(obj client.Object) []reconcile.Request {
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(context.TODO(), profiles, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		r.Log.Error(err, "error listing PowerProfiles")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0)
+	for _, profile := range profiles.Items {
+		if profile.Spec.ApplyDeadlineSeconds > 0 {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&profile)})
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/correlation"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createProfileDeadlineReconcilerObject(objs []runtime.Object) (*ProfileDeadlineReconciler, *record.FakeRecorder, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	return &ProfileDeadlineReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s, Recorder: recorder}, recorder, nil
+}
+
+func TestProfileDeadlineReconciler(t *testing.T) {
+	lastApplied := powerv1.PowerProfileSpec{Name: "low-latency", Epp: "performance", Max: 3000, Min: 2800,
+		ApplyDeadlineSeconds: 30, RollbackOnDeadline: true}
+	updated := lastApplied
+	updated.Max = 3500
+	objs := []runtime.Object{
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{AppliedProfiles: map[string]int64{"low-latency": 2}},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2", Namespace: IntelPowerNamespace},
+			Status:     powerv1.PowerNodeStatus{AppliedProfiles: map[string]int64{"low-latency": 1}},
+		},
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "low-latency", Namespace: IntelPowerNamespace, Generation: 2},
+			Spec:       updated,
+			Status:     powerv1.PowerProfileStatus{ObservedGeneration: 1, LastAppliedSpec: &lastApplied},
+		},
+	}
+	r, recorder, err := createProfileDeadlineReconcilerObject(objs)
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "low-latency", Namespace: IntelPowerNamespace}}
+	getProfile := func() *powerv1.PowerProfile {
+		profile := &powerv1.PowerProfile{}
+		assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, profile))
+		return profile
+	}
+
+	// node2 hasn't applied the new generation, the deadline is counting down
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.InDelta(t, 30*time.Second, result.RequeueAfter, float64(time.Second))
+	profile := getProfile()
+	assert.Equal(t, int64(2), profile.Status.ObservedGeneration)
+	assert.Equal(t, []string{"node2"}, profile.Status.PendingNodes)
+	assert.False(t, meta.IsStatusConditionTrue(profile.Status.Conditions, powerv1.ConditionApplied))
+	assert.False(t, meta.IsStatusConditionTrue(profile.Status.Conditions, powerv1.ConditionFailed))
+
+	// once the deadline passes the spec last applied everywhere is restored
+	profile.Status.ApplyStarted = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	assert.NoError(t, r.Client.Status().Update(context.TODO(), profile))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	profile = getProfile()
+	assert.Equal(t, 3000, profile.Spec.Max)
+	failed := meta.FindStatusCondition(profile.Status.Conditions, powerv1.ConditionFailed)
+	assert.Equal(t, metav1.ConditionTrue, failed.Status)
+	assert.Equal(t, powerv1.ReasonRolledBack, failed.Reason)
+	assert.Contains(t, failed.Message, "node2")
+	assert.Len(t, recorder.Events, 1)
+
+	// the rollback applied everywhere is Applied, while still reporting the failure
+	profile.Generation = 3
+	assert.NoError(t, r.Client.Update(context.TODO(), profile))
+	for _, name := range []string{"node1", "node2"} {
+		powerNode := &powerv1.PowerNode{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: IntelPowerNamespace}, powerNode))
+		powerNode.Status.AppliedProfiles["low-latency"] = 3
+		assert.NoError(t, r.Client.Status().Update(context.TODO(), powerNode))
+	}
+	result, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	profile = getProfile()
+	assert.Empty(t, profile.Status.PendingNodes)
+	assert.True(t, meta.IsStatusConditionTrue(profile.Status.Conditions, powerv1.ConditionApplied))
+	failed = meta.FindStatusCondition(profile.Status.Conditions, powerv1.ConditionFailed)
+	assert.Equal(t, powerv1.ReasonRolledBack, failed.Reason)
+
+	// without the deadline nothing is tracked
+	profile.Spec.ApplyDeadlineSeconds = 0
+	profile.Generation = 4
+	assert.NoError(t, r.Client.Update(context.TODO(), profile))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	profile = getProfile()
+	assert.Nil(t, profile.Status.ApplyStarted)
+	assert.Empty(t, profile.Status.Conditions)
+}
+
+func TestProfileDeadlineExceeded(t *testing.T) {
+	objs := []runtime.Object{
+		&powerv1.PowerNode{ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: IntelPowerNamespace}},
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "balance-performance", Namespace: IntelPowerNamespace, Generation: 1, UID: "3f2a"},
+			Spec:       powerv1.PowerProfileSpec{Name: "balance-performance", Epp: "balance_performance", ApplyDeadlineSeconds: 10},
+			Status: powerv1.PowerProfileStatus{
+				ObservedGeneration: 1,
+				ApplyStarted:       &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			},
+		},
+	}
+	r, recorder, err := createProfileDeadlineReconcilerObject(objs)
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "balance-performance", Namespace: IntelPowerNamespace}}
+
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	profile := &powerv1.PowerProfile{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, profile))
+	failed := meta.FindStatusCondition(profile.Status.Conditions, powerv1.ConditionFailed)
+	assert.Equal(t, metav1.ConditionTrue, failed.Status)
+	assert.Equal(t, powerv1.ReasonApplyDeadlineExceeded, failed.Reason)
+	assert.Equal(t, []string{"node1"}, profile.Status.PendingNodes)
+	// the event carries the ID the Node Agents log the change under
+	assert.Contains(t, <-recorder.Events, "correlation ID 3f2a-1")
+	assert.Equal(t, "3f2a-1", correlation.ID(profile))
+
+	// the failure is reported once
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Len(t, recorder.Events, 0)
+}
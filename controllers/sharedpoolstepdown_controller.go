@@ -0,0 +1,242 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/poollock"
+)
+
+// PowerSource reports the power drawn by the Node's packages
+type PowerSource interface {
+	Watts() (float64, error)
+}
+
+// newPowerProfile creates the stepped down Shared profile, overridable as the Power Library checks the host
+var newPowerProfile = power.NewPowerProfile
+
+// SharedPoolStepDownReconciler periodically lowers or restores the Shared pool's max frequency according to
+// the SharedPoolStepDown of this Node's PowerNode and reports the current step in the PowerNode's status
+type SharedPoolStepDownReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	PowerSource  PowerSource
+	Interval     time.Duration
+
+	// mutex guards the step and profiles, read by the PowerMaintenance controller through BaseProfile
+	mutex sync.Mutex
+	step  int
+	// the Shared pool's PowerProfile before it was stepped down and the one it was replaced with
+	baseProfile    power.Profile
+	steppedProfile power.Profile
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+
+// Start adjusts the Shared pool on every interval until the context is cancelled
+func (r *SharedPoolStepDownReconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := r.Adjust(ctx)
+			if err != nil {
+				r.Log.Error(err, "error stepping down the Shared pool")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent has to look after its own Node
+func (r *SharedPoolStepDownReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// BaseProfile is the Shared PowerProfile without the step down: the base profile while profile is the stepped down
+// one, profile itself otherwise
+func (r *SharedPoolStepDownReconciler) BaseProfile(profile power.Profile) power.Profile {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if profile != nil && profile == r.steppedProfile {
+		return r.baseProfile
+	}
+
+	return profile
+}
+
+// Adjust moves the Shared pool one step towards where the exclusive subscription and power budget want it
+func (r *SharedPoolStepDownReconciler) Adjust(ctx context.Context) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	logger := r.Log.WithName("sharedPoolStepDown")
+	nodeName := os.Getenv("NODE_NAME")
+
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Name:      nodeName,
+		Namespace: IntelPowerNamespace,
+	}, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	config := powerNode.Spec.SharedPoolStepDown
+
+	sharedPool := r.PowerLibrary.GetSharedPool()
+	current := sharedPool.GetPowerProfile()
+	if current == nil {
+		// the profile was removed for a maintenance, the base profile is kept so a stepped down profile put back
+		// afterwards isn't taken for the baseline
+		return r.updateStatus(ctx, nodeName, 0, 0)
+	}
+	if r.steppedProfile == nil || current != r.steppedProfile {
+		// not stepped down, or the Shared PowerProfile was replaced since, either way it is the new baseline
+		r.step, r.baseProfile, r.steppedProfile = 0, current, nil
+	}
+
+	target := 0
+	stepMHz := 0
+	if config != nil && config.StepMHz > 0 {
+		stepMHz = config.StepMHz
+		subscription := r.exclusiveSubscription()
+		watts := -1.0
+		if config.PowerBudgetWatts > 0 && r.PowerSource != nil {
+			watts, err = r.PowerSource.Watts()
+			if err != nil {
+				logger.Error(err, "error reading package power")
+				watts = -1
+			}
+		}
+		target = nextSharedPoolStep(r.step, *config, subscription, watts)
+
+		// never go below the Shared PowerProfile's min frequency
+		floorSteps := int(r.baseProfile.MaxFreq()-r.baseProfile.MinFreq()) / stepMHz
+		if target > floorSteps {
+			target = floorSteps
+		}
+		logger.V(5).Info("Computed Shared pool step", "subscription", subscription, "watts", watts, "current", r.step, "target", target)
+	}
+
+	if target != r.step {
+		if target == 0 {
+			err = poollock.Change(func() error { return sharedPool.SetPowerProfile(r.baseProfile) })
+			if err != nil {
+				return err
+			}
+			r.steppedProfile = nil
+		} else {
+			maxFreq := r.baseProfile.MaxFreq() - uint(target*stepMHz)
+			stepped, err := newPowerProfile(r.baseProfile.Name(), r.baseProfile.MinFreq(), maxFreq, r.baseProfile.Governor(), r.baseProfile.Epp())
+			if err != nil {
+				return err
+			}
+			err = poollock.Change(func() error { return sharedPool.SetPowerProfile(stepped) })
+			if err != nil {
+				return err
+			}
+			r.steppedProfile = stepped
+		}
+		logger.Info("Shared pool max frequency stepped", "from", r.step, "to", target)
+		r.step = target
+	}
+
+	return r.updateStatus(ctx, nodeName, r.step, int(r.baseProfile.MaxFreq())-r.step*stepMHz)
+}
+
+// exclusiveSubscription is the percentage of the Shared and exclusive cores that are in exclusive pools
+func (r *SharedPoolStepDownReconciler) exclusiveSubscription() float64 {
+	exclusive := 0
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		exclusive += len(pool.Cpus().IDs())
+	}
+	shared := len(r.PowerLibrary.GetSharedPool().Cpus().IDs())
+	if exclusive+shared == 0 {
+		return 0
+	}
+
+	return float64(exclusive) * 100 / float64(exclusive+shared)
+}
+
+func (r *SharedPoolStepDownReconciler) updateStatus(ctx context.Context, nodeName string, step int, maxFrequency int) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		powerNode := &powerv1.PowerNode{}
+		err := r.Client.Get(ctx, client.ObjectKey{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		}, powerNode)
+		if err != nil {
+			return err
+		}
+		if step == 0 {
+			maxFrequency = 0
+		}
+		if powerNode.Status.SharedPoolStep == step && powerNode.Status.SharedPoolMaxFrequency == maxFrequency {
+			return nil
+		}
+
+		powerNode.Status.SharedPoolStep = step
+		powerNode.Status.SharedPoolMaxFrequency = maxFrequency
+		return r.Client.Status().Update(ctx, powerNode)
+	})
+}
+
+// nextSharedPoolStep moves at most one step at a time. Below the subscription threshold the Shared pool is
+// restored, above it the step follows the power budget with a 10% band so it doesn't flap around the budget.
+// A negative watts reading means power is unknown and the step is kept
+func nextSharedPoolStep(current int, config powerv1.SharedPoolStepDown, subscription float64, watts float64) int {
+	next := current
+	budget := float64(config.PowerBudgetWatts)
+	switch {
+	case subscription <= float64(config.SubscriptionThreshold):
+		next = current - 1
+	case config.PowerBudgetWatts <= 0:
+		next = current + 1
+	case watts < 0:
+	case watts > budget:
+		next = current + 1
+	case watts < budget*0.9:
+		next = current - 1
+	}
+
+	if next > config.MaxSteps {
+		next = config.MaxSteps
+	}
+	if next < 0 {
+		next = 0
+	}
+
+	return next
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type powerSourceMock struct {
+	watts float64
+	err   error
+}
+
+func (m *powerSourceMock) Watts() (float64, error) {
+	return m.watts, m.err
+}
+
+func TestNextSharedPoolStep(t *testing.T) {
+	config := powerv1.SharedPoolStepDown{
+		SubscriptionThreshold: 50,
+		PowerBudgetWatts:      200,
+		StepMHz:               100,
+		MaxSteps:              3,
+	}
+	noBudget := config
+	noBudget.PowerBudgetWatts = 0
+
+	tcases := []struct {
+		testCase     string
+		current      int
+		config       powerv1.SharedPoolStepDown
+		subscription float64
+		watts        float64
+		expected     int
+	}{
+		{"under threshold restores", 2, config, 40, 300, 1},
+		{"under threshold at base", 0, config, 40, 300, 0},
+		{"over budget steps down", 0, config, 75, 250, 1},
+		{"over budget stops at max steps", 3, config, 75, 250, 3},
+		{"within band holds", 2, config, 75, 190, 2},
+		{"under band restores", 2, config, 75, 150, 1},
+		{"unknown power holds", 2, config, 75, -1, 2},
+		{"no budget steps down", 1, noBudget, 75, -1, 2},
+	}
+
+	for _, tc := range tcases {
+		t.Log(tc.testCase)
+		assert.Equal(t, tc.expected, nextSharedPoolStep(tc.current, tc.config, tc.subscription, tc.watts))
+	}
+}
+
+func TestSharedPoolStepDownReconciler_Adjust(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: IntelPowerNamespace,
+		},
+		Spec: powerv1.PowerNodeSpec{
+			NodeName: nodeName,
+			SharedPoolStepDown: &powerv1.SharedPoolStepDown{
+				SubscriptionThreshold: 50,
+				PowerBudgetWatts:      200,
+				StepMHz:               100,
+				MaxSteps:              2,
+			},
+		},
+	}
+	s := scheme.Scheme
+	assert.NoError(t, powerv1.AddToScheme(s))
+	cl := fake.NewClientBuilder().WithRuntimeObjects([]runtime.Object{powerNode}...).WithScheme(s).Build()
+
+	baseProfile := new(profMock)
+	baseProfile.On("Name").Return("shared")
+	baseProfile.On("MaxFreq").Return(uint(3000))
+	baseProfile.On("MinFreq").Return(uint(1000))
+	baseProfile.On("Governor").Return("powersave")
+	baseProfile.On("Epp").Return("power")
+
+	var maxFreqs []uint
+	defer func(orig func(string, uint, uint, string, string) (power.Profile, error)) { newPowerProfile = orig }(newPowerProfile)
+	newPowerProfile = func(name string, minFreq uint, maxFreq uint, governor string, epp string) (power.Profile, error) {
+		maxFreqs = append(maxFreqs, maxFreq)
+		return new(profMock), nil
+	}
+
+	cores := make(power.CpuList, 0)
+	for id := uint(0); id < 4; id++ {
+		core := new(coreMock)
+		core.On("GetID").Return(id)
+		cores = append(cores, core)
+	}
+	exclusivePool := new(poolMock)
+	exclusiveCpus := exclusivePool.On("Cpus").Return(&power.CpuList{cores[1], cores[2], cores[3]})
+	sharedPool := new(poolMock)
+	sharedPool.On("Cpus").Return(&power.CpuList{cores[0]})
+	currentProfile := sharedPool.On("GetPowerProfile").Return(baseProfile)
+	sharedPool.On("SetPowerProfile", mock.Anything).Run(func(args mock.Arguments) {
+		currentProfile.ReturnArguments = mock.Arguments{args.Get(0)}
+	}).Return(nil)
+
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{exclusivePool})
+
+	source := &powerSourceMock{watts: 250}
+	r := &SharedPoolStepDownReconciler{
+		Client:       cl,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		PowerSource:  source,
+	}
+	status := func() powerv1.PowerNodeStatus {
+		node := &powerv1.PowerNode{}
+		err := cl.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), node)
+		assert.NoError(t, err)
+		return node.Status
+	}
+
+	// 75% subscribed and over budget, stepped down one step at a time up to MaxSteps
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, r.Adjust(context.TODO()))
+	}
+	assert.Equal(t, []uint{2900, 2800}, maxFreqs)
+	assert.Equal(t, 2, status().SharedPoolStep)
+	assert.Equal(t, 2800, status().SharedPoolMaxFrequency)
+
+	// exclusive demand drops, restored one step at a time back to the original profile
+	exclusiveCpus.ReturnArguments = mock.Arguments{&power.CpuList{}}
+	assert.NoError(t, r.Adjust(context.TODO()))
+	assert.Equal(t, 1, status().SharedPoolStep)
+	assert.NoError(t, r.Adjust(context.TODO()))
+	sharedPool.AssertCalled(t, "SetPowerProfile", baseProfile)
+	assert.Equal(t, 0, status().SharedPoolStep)
+	assert.Equal(t, 0, status().SharedPoolMaxFrequency)
+
+	// a new Shared profile becomes the baseline
+	newBase := new(profMock)
+	newBase.On("Name").Return("shared")
+	newBase.On("MaxFreq").Return(uint(2000))
+	newBase.On("MinFreq").Return(uint(1950))
+	newBase.On("Governor").Return("powersave")
+	newBase.On("Epp").Return("power")
+	currentProfile.ReturnArguments = mock.Arguments{newBase}
+	exclusiveCpus.ReturnArguments = mock.Arguments{&power.CpuList{cores[1], cores[2], cores[3]}}
+	maxFreqs = nil
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, r.Adjust(context.TODO()))
+	}
+	// it is never stepped below the profile's min frequency
+	assert.Empty(t, maxFreqs)
+	assert.Equal(t, 0, status().SharedPoolStep)
+}
+
+func TestSharedPoolStepDownMaintenance(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerNodeSpec{
+			NodeName: nodeName,
+			SharedPoolStepDown: &powerv1.SharedPoolStepDown{
+				SubscriptionThreshold: 50,
+				StepMHz:               100,
+				MaxSteps:              2,
+			},
+		},
+	}
+	maintenance := &powerv1.PowerMaintenance{
+		ObjectMeta: metav1.ObjectMeta{Name: "firmware-update", Namespace: IntelPowerNamespace},
+		Spec:       powerv1.PowerMaintenanceSpec{NodeName: nodeName},
+	}
+	s := scheme.Scheme
+	assert.NoError(t, powerv1.AddToScheme(s))
+	cl := fake.NewClientBuilder().WithRuntimeObjects(powerNode, maintenance,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}).WithScheme(s).Build()
+
+	profile := func(maxFreq uint) *profMock {
+		p := new(profMock)
+		p.On("Name").Return("shared")
+		p.On("MaxFreq").Return(maxFreq)
+		p.On("MinFreq").Return(uint(1000))
+		p.On("Governor").Return("powersave")
+		p.On("Epp").Return("power")
+		return p
+	}
+	baseProfile := profile(3000)
+	defer func(orig func(string, uint, uint, string, string) (power.Profile, error)) { newPowerProfile = orig }(newPowerProfile)
+	newPowerProfile = func(name string, minFreq uint, maxFreq uint, governor string, epp string) (power.Profile, error) {
+		return profile(maxFreq), nil
+	}
+
+	cores := make(power.CpuList, 0)
+	for id := uint(0); id < 4; id++ {
+		core := new(coreMock)
+		core.On("GetID").Return(id)
+		cores = append(cores, core)
+	}
+	exclusivePool := new(poolMock)
+	exclusivePool.On("Name").Return("performance")
+	exclusivePool.On("Cpus").Return(&power.CpuList{cores[1], cores[2], cores[3]})
+	sharedPool := new(poolMock)
+	sharedPool.On("Cpus").Return(&power.CpuList{cores[0]})
+	sharedPool.On("MoveCpuIDs", mock.Anything).Return(nil)
+	currentProfile := sharedPool.On("GetPowerProfile").Return(baseProfile)
+	sharedPool.On("SetPowerProfile", mock.Anything).Run(func(args mock.Arguments) {
+		currentProfile.ReturnArguments = mock.Arguments{args.Get(0)}
+	}).Return(nil)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{exclusivePool})
+
+	stepDown := &SharedPoolStepDownReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), PowerLibrary: powerLibMock}
+	maintenanceReconciler := &PowerMaintenanceReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s,
+		PowerLibrary: powerLibMock, SharedBaseline: stepDown}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(maintenance)}
+	sharedMax := func() uint {
+		return sharedPool.GetPowerProfile().MaxFreq()
+	}
+
+	// stepped down twice, then the maintenance removes the Shared profile
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, stepDown.Adjust(context.TODO()))
+	}
+	assert.Equal(t, uint(2800), sharedMax())
+	_, err := maintenanceReconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Nil(t, sharedPool.GetPowerProfile())
+	assert.NoError(t, stepDown.Adjust(context.TODO()))
+
+	// the maintenance ends with the base profile back, and the step down starts over from it
+	assert.NoError(t, cl.Delete(context.TODO(), maintenance))
+	_, err = maintenanceReconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3000), sharedMax())
+	assert.NoError(t, stepDown.Adjust(context.TODO()))
+	assert.Equal(t, uint(2900), sharedMax())
+
+	// once the exclusive demand drops the full frequency is restored
+	exclusivePool.On("Cpus").Unset()
+	exclusivePool.On("Cpus").Return(&power.CpuList{})
+	assert.NoError(t, stepDown.Adjust(context.TODO()))
+	assert.Equal(t, uint(3000), sharedMax())
+	assert.Equal(t, baseProfile, sharedPool.GetPowerProfile())
+}
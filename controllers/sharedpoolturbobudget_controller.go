@@ -0,0 +1,215 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/thermal"
+)
+
+// SharedPoolTurboBudgetReconciler enforces the SharedPoolTurboBudget of this Node's PowerNode. Only so many of the
+// Shared pool's physical cores per package keep the Shared PowerProfile's max frequency, the rest are capped at
+// the base frequency, and the cores allowed to turbo rotate so the Shared workloads take turns
+type SharedPoolTurboBudgetReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	Cores        thermal.CoreLocator
+	Capper       thermal.FrequencyCapper
+	Interval     time.Duration
+
+	rotation    int
+	lastRotated time.Time
+	// the Shared CPUs currently capped at the base frequency
+	capped map[uint]bool
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes,verbs=get;list;watch
+
+// Start moves the caps on every interval until the context is cancelled
+func (r *SharedPoolTurboBudgetReconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			err := r.Adjust(ctx, now)
+			if err != nil {
+				r.Log.Error(err, "error capping the Shared pool's turbo cores")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent has to look after its own cores
+func (r *SharedPoolTurboBudgetReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Adjust caps the Shared cores that aren't allowed to turbo, rotating to the next cores once the rotation period
+// has passed. Without a budget, or with a Shared PowerProfile that doesn't go above the base frequency, the caps
+// are lifted
+func (r *SharedPoolTurboBudgetReconciler) Adjust(ctx context.Context, now time.Time) error {
+	logger := r.Log.WithName("sharedPoolTurboBudget")
+	nodeName := os.Getenv("NODE_NAME")
+	if r.capped == nil {
+		r.capped = make(map[uint]bool)
+	}
+
+	powerNode := &powerv1.PowerNode{}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Name:      nodeName,
+		Namespace: IntelPowerNamespace,
+	}, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	config := powerNode.Spec.SharedPoolTurboBudget
+
+	sharedPool := r.PowerLibrary.GetSharedPool()
+	profile := sharedPool.GetPowerProfile()
+	cpus := sharedPool.Cpus().IDs()
+	if config == nil || profile == nil {
+		return r.release(cpus, profile)
+	}
+	baseFrequency, err := readBaseFrequency()
+	if err != nil {
+		return err
+	}
+	if baseFrequency <= 0 || uint(baseFrequency) >= profile.MaxFreq() {
+		return r.release(cpus, profile)
+	}
+
+	physicalCores, err := r.Cores.PhysicalCores(cpus)
+	if err != nil {
+		return err
+	}
+	rotationPeriod := time.Duration(config.RotationSeconds) * time.Second
+	if r.lastRotated.IsZero() {
+		r.lastRotated = now
+	} else if rotationPeriod > 0 && now.Sub(r.lastRotated) >= rotationPeriod {
+		r.rotation++
+		r.lastRotated = now
+	}
+	turbo := turboCPUs(physicalCores, config.MaxTurboCores, r.rotation)
+
+	results := new(multierror.Error)
+	inSharedPool := make(map[uint]bool, len(cpus))
+	for _, cpu := range cpus {
+		inSharedPool[cpu] = true
+		if _, located := physicalCores[cpu]; !located || turbo[cpu] {
+			if r.capped[cpu] {
+				err = r.Capper.SetMaxFrequency(cpu, profile.MaxFreq())
+				if err != nil {
+					results = multierror.Append(results, fmt.Errorf("restoring cpu %d: %w", cpu, err))
+					continue
+				}
+				delete(r.capped, cpu)
+			}
+			continue
+		}
+		// the Power Library writes the profile's max frequency back whenever it reapplies the pool, so a cap is
+		// written on every adjustment
+		err = r.Capper.SetMaxFrequency(cpu, uint(baseFrequency))
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("capping cpu %d: %w", cpu, err))
+			continue
+		}
+		r.capped[cpu] = true
+	}
+	// CPUs moved to an exclusive pool were given that pool's frequencies
+	for cpu := range r.capped {
+		if !inSharedPool[cpu] {
+			delete(r.capped, cpu)
+		}
+	}
+	logger.V(5).Info("Capped the Shared cores over the turbo budget", "rotation", r.rotation, "capped", len(r.capped), "baseFrequency", baseFrequency)
+
+	return results.ErrorOrNil()
+}
+
+// release gives the capped CPUs still in the Shared pool its PowerProfile's max frequency back
+func (r *SharedPoolTurboBudgetReconciler) release(cpus []uint, profile power.Profile) error {
+	results := new(multierror.Error)
+	if profile != nil {
+		for _, cpu := range cpus {
+			if !r.capped[cpu] {
+				continue
+			}
+			err := r.Capper.SetMaxFrequency(cpu, profile.MaxFreq())
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("restoring cpu %d: %w", cpu, err))
+				continue
+			}
+			delete(r.capped, cpu)
+		}
+	}
+	if results.ErrorOrNil() == nil {
+		r.capped = make(map[uint]bool)
+	}
+	r.rotation, r.lastRotated = 0, time.Time{}
+
+	return results.ErrorOrNil()
+}
+
+// turboCPUs picks maxTurboCores physical cores per package to turbo, all the CPUs of a core together as siblings
+// share its frequency. Each rotation moves on by maxTurboCores, walking the cores of a package in ID order
+func turboCPUs(physicalCores map[uint][2]uint, maxTurboCores int, rotation int) map[uint]bool {
+	coreCPUs := make(map[[2]uint][]uint)
+	packages := make(map[uint][]uint)
+	for cpu, physicalCore := range physicalCores {
+		if _, seen := coreCPUs[physicalCore]; !seen {
+			packages[physicalCore[0]] = append(packages[physicalCore[0]], physicalCore[1])
+		}
+		coreCPUs[physicalCore] = append(coreCPUs[physicalCore], cpu)
+	}
+
+	turbo := make(map[uint]bool)
+	for pkg, coreIDs := range packages {
+		sort.Slice(coreIDs, func(i, j int) bool { return coreIDs[i] < coreIDs[j] })
+		count := maxTurboCores
+		if count > len(coreIDs) {
+			count = len(coreIDs)
+		}
+		start := rotation * maxTurboCores % len(coreIDs)
+		for i := 0; i < count; i++ {
+			for _, cpu := range coreCPUs[[2]uint{pkg, coreIDs[(start+i)%len(coreIDs)]}] {
+				turbo[cpu] = true
+			}
+		}
+	}
+
+	return turbo
+}
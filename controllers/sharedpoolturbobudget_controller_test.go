@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+type fakeCoreLocator map[uint][2]uint
+
+func (f fakeCoreLocator) PhysicalCores(cpus []uint) (map[uint][2]uint, error) {
+	cores := make(map[uint][2]uint)
+	for _, cpu := range cpus {
+		if core, found := f[cpu]; found {
+			cores[cpu] = core
+		}
+	}
+
+	return cores, nil
+}
+
+func TestTurboCPUs(t *testing.T) {
+	// hyperthread siblings 0 and 2 share core 0 of package 0
+	physicalCores := map[uint][2]uint{0: {0, 0}, 1: {0, 1}, 2: {0, 0}, 3: {0, 4}, 4: {1, 0}}
+
+	assert.Equal(t, map[uint]bool{0: true, 2: true, 1: true, 4: true}, turboCPUs(physicalCores, 2, 0))
+	// the next rotation wraps around to the first core of package 0
+	assert.Equal(t, map[uint]bool{3: true, 0: true, 2: true, 4: true}, turboCPUs(physicalCores, 2, 1))
+	assert.Empty(t, turboCPUs(physicalCores, 0, 3))
+	assert.Len(t, turboCPUs(physicalCores, 10, 0), 5)
+}
+
+func TestSharedPoolTurboBudgetReconciler_Adjust(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	defer func(orig func() (int, error)) { readBaseFrequency = orig }(readBaseFrequency)
+	readBaseFrequency = func() (int, error) { return 2000, nil }
+
+	powerNode := &powerv1.PowerNode{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerNodeSpec{
+			NodeName:              nodeName,
+			SharedPoolTurboBudget: &powerv1.SharedPoolTurboBudget{MaxTurboCores: 1, RotationSeconds: 10},
+		},
+	}
+	s := scheme.Scheme
+	assert.NoError(t, powerv1.AddToScheme(s))
+	cl := fake.NewClientBuilder().WithRuntimeObjects([]runtime.Object{powerNode}...).WithScheme(s).Build()
+
+	cpus := make(power.CpuList, 0)
+	for id := uint(0); id < 8; id++ {
+		cpu := new(coreMock)
+		cpu.On("GetID").Return(id)
+		cpus = append(cpus, cpu)
+	}
+	sharedProfile := new(profMock)
+	sharedProfile.On("MaxFreq").Return(uint(3000))
+	sharedPool := new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	sharedPool.On("Cpus").Return(&cpus)
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+
+	// package 0 has cores 0-2 with siblings 0/3, 1/4 and 2/5, package 1 has cores 0 and 1
+	capper := &fakeThermal{caps: make(map[uint]uint)}
+	r := &SharedPoolTurboBudgetReconciler{
+		Client:       cl,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Cores: fakeCoreLocator{
+			0: {0, 0}, 1: {0, 1}, 2: {0, 2}, 3: {0, 0}, 4: {0, 1}, 5: {0, 2},
+			6: {1, 0}, 7: {1, 1},
+		},
+		Capper: capper,
+	}
+
+	// one core per package keeps turbo, the others are capped at the base frequency
+	now := time.Now()
+	assert.NoError(t, r.Adjust(context.TODO(), now))
+	assert.Equal(t, map[uint]uint{1: 2000, 2: 2000, 4: 2000, 5: 2000, 7: 2000}, capper.caps)
+	assert.NoError(t, r.Adjust(context.TODO(), now.Add(5*time.Second)))
+	assert.Len(t, r.capped, 5)
+
+	// after the rotation period the next cores take their turn
+	capper.caps = make(map[uint]uint)
+	assert.NoError(t, r.Adjust(context.TODO(), now.Add(10*time.Second)))
+	assert.Equal(t, map[uint]uint{0: 2000, 3: 2000, 1: 3000, 4: 3000, 2: 2000, 5: 2000, 6: 2000, 7: 3000}, capper.caps)
+
+	// without a budget every capped core gets the Shared profile's max frequency back
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(powerNode), powerNode))
+	powerNode.Spec.SharedPoolTurboBudget = nil
+	assert.NoError(t, cl.Update(context.TODO(), powerNode))
+	capper.caps = make(map[uint]uint)
+	assert.NoError(t, r.Adjust(context.TODO(), now.Add(15*time.Second)))
+	assert.Equal(t, map[uint]uint{0: 3000, 3: 3000, 2: 3000, 5: 3000, 6: 3000}, capper.caps)
+	assert.Empty(t, r.capped)
+}
@@ -0,0 +1,229 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/strictadmission"
+)
+
+const StrictAdmissionFailedReason = "StrictAdmissionFailed"
+
+// StrictAdmissionReconciler sets the strict admission readiness gate of the Pods on this Node once the PowerWorkloads
+// of their containers are applied, and deletes the Pods whose PowerWorkloads keep failing to apply so they aren't
+// left running without their frequencies
+type StrictAdmissionReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Timeout is how long a failing apply is retried before the Pod is deleted, Pods are never deleted if 0
+	Timeout time.Duration
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups="",resources=pods/status,verbs=get;update;patch
+
+func (r *StrictAdmissionReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("pod", req.NamespacedName)
+	nodeName := os.Getenv("NODE_NAME")
+
+	pod := &corev1.Pod{}
+	err := r.Client.Get(c, req.NamespacedName, pod)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if pod.Spec.NodeName != nodeName || !strictadmission.HasReadinessGate(pod) || !pod.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	workloads := &powerv1.PowerWorkloadList{}
+	err = r.Client.List(c, workloads, client.MatchingFields{WorkloadNodeNameIndex: nodeName})
+	if err != nil {
+		logger.Error(err, "error listing the PowerWorkloads of the Node")
+		return ctrl.Result{}, err
+	}
+	reason, message := podPowerState(pod, workloads.Items)
+
+	now := time.Now()
+	result := ctrl.Result{}
+	current := podCondition(pod, strictadmission.ReadinessGate)
+	if reason == strictadmission.ReasonApplyFailed && r.Timeout > 0 {
+		failingSince := now
+		if current != nil && current.Reason == strictadmission.ReasonApplyFailed {
+			failingSince = current.LastTransitionTime.Time
+		}
+		failing := now.Sub(failingSince)
+		if failing >= r.Timeout {
+			logger.Info("Deleting Pod whose PowerProfile couldn't be applied", "timeout", r.Timeout, "error", message)
+			r.Recorder.Event(pod, corev1.EventTypeWarning, StrictAdmissionFailedReason,
+				fmt.Sprintf("PowerProfile not applied within %s, deleting the Pod: %s", r.Timeout, message))
+			err = r.Client.Delete(c, pod)
+			if err != nil && !errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		result.RequeueAfter = r.Timeout - failing
+	}
+
+	status := corev1.ConditionFalse
+	if reason == strictadmission.ReasonApplied {
+		status = corev1.ConditionTrue
+	}
+	if current != nil && current.Status == status && current.Reason == reason && current.Message == message {
+		return result, nil
+	}
+	condition := corev1.PodCondition{
+		Type:               strictadmission.ReadinessGate,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	if current != nil && current.Status == status && current.Reason == reason {
+		// only the message changed, the failure started when the reason did
+		condition.LastTransitionTime = current.LastTransitionTime
+	}
+	setPodCondition(pod, condition)
+	err = r.Client.Status().Update(c, pod)
+	if err != nil {
+		logger.Error(err, "error updating the Pod's strict admission condition")
+		return ctrl.Result{}, err
+	}
+	logger.V(5).Info("Updated the strict admission condition", "reason", reason)
+
+	return result, nil
+}
+
+// podPowerState is the reason and message of the Pod's strict admission condition: Applied once every PowerWorkload
+// holding its containers has its PowerProfile on all of its CPUs, ApplyFailed while any of them can't be applied
+// and Pending otherwise
+func podPowerState(pod *corev1.Pod, workloads []powerv1.PowerWorkload) (string, string) {
+	var podWorkloads []powerv1.PowerWorkload
+	for _, workload := range workloads {
+		if workloadHoldsPod(&workload, pod.Name) {
+			podWorkloads = append(podWorkloads, workload)
+		}
+	}
+	if len(podWorkloads) == 0 {
+		return strictadmission.ReasonPending, "Waiting for the Pod's containers to be added to their PowerWorkloads"
+	}
+	sort.Slice(podWorkloads, func(i, j int) bool { return podWorkloads[i].Name < podWorkloads[j].Name })
+
+	for _, workload := range podWorkloads {
+		status := workload.Status
+		if status.LastError != "" {
+			return strictadmission.ReasonApplyFailed, fmt.Sprintf("PowerWorkload %s: %s", workload.Name, status.LastError)
+		}
+		if status.Phase == powerv1.WorkloadPhaseFailed {
+			return strictadmission.ReasonApplyFailed, fmt.Sprintf("PowerWorkload %s failed validation: %s", workload.Name, status.Message)
+		}
+	}
+	for _, workload := range podWorkloads {
+		status := workload.Status
+		cpus := len(workload.Spec.Node.CpuIds)
+		if status.AppliedProfile != workload.Spec.PowerProfile || status.AppliedCPUs != fmt.Sprintf("%d/%d", cpus, cpus) ||
+			status.Phase == powerv1.WorkloadPhaseValidating {
+			return strictadmission.ReasonPending, fmt.Sprintf("Waiting for PowerWorkload %s to be applied", workload.Name)
+		}
+	}
+
+	return strictadmission.ReasonApplied, "The PowerProfiles are applied to the Pod's CPUs"
+}
+
+func workloadHoldsPod(workload *powerv1.PowerWorkload, podName string) bool {
+	for _, container := range workload.Spec.Node.Containers {
+		if container.Pod == podName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func podCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == conditionType {
+			return &pod.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+func setPodCondition(pod *corev1.Pod, condition corev1.PodCondition) {
+	if current := podCondition(pod, condition.Type); current != nil {
+		*current = condition
+		return
+	}
+	pod.Status.Conditions = append(pod.Status.Conditions, condition)
+}
+
+func (r *StrictAdmissionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("strictadmission").
+		For(&corev1.Pod{}).
+		Watches(&source.Kind{Type: &powerv1.PowerWorkload{}}, handler.EnqueueRequestsFromMapFunc(r.workloadPodRequests)).
+		Complete(r)
+}
+
+// workloadPodRequests reconciles the strict admission Pods on this Node whose containers the PowerWorkload holds
+func (r *StrictAdmissionReconciler) workloadPodRequests(obj client.Object) []reconcile.Request {
+	workload, ok := obj.(*powerv1.PowerWorkload)
+	if !ok || workload.Spec.Node.Name != os.Getenv("NODE_NAME") {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	err := r.Client.List(context.TODO(), pods)
+	if err != nil {
+		r.Log.Error(err, "error listing Pods held by PowerWorkload", "workload", workload.Name)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName != workload.Spec.Node.Name || !strictadmission.HasReadinessGate(pod) || !workloadHoldsPod(workload, pod.Name) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+	}
+
+	return requests
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/strictadmission"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createStrictAdmissionReconcilerObject(objs []runtime.Object) (*StrictAdmissionReconciler, *record.FakeRecorder, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).
+		WithIndex(&powerv1.PowerWorkload{}, WorkloadNodeNameIndex, workloadNodeNameIndexer).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	return &StrictAdmissionReconciler{Client: cl, Log: ctrl.Log.WithName("testing"), Scheme: s, Recorder: recorder, Timeout: time.Minute}, recorder, nil
+}
+
+func TestStrictAdmissionReconciler(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName:       nodeName,
+			ReadinessGates: []corev1.PodReadinessGate{{ConditionType: strictadmission.ReadinessGate}},
+		},
+	}
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance-TestNode", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerWorkloadSpec{
+			Name:         "performance-TestNode",
+			PowerProfile: "performance",
+			Node: powerv1.WorkloadNode{
+				Name:       nodeName,
+				CpuIds:     []uint{2, 3},
+				Containers: []powerv1.Container{{Name: "db", Pod: "db", ExclusiveCPUs: []uint{2, 3}}},
+			},
+		},
+	}
+	r, recorder, err := createStrictAdmissionReconcilerObject([]runtime.Object{pod, workload})
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)}
+	condition := func() *corev1.PodCondition {
+		current := &corev1.Pod{}
+		assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, current))
+		return podCondition(current, strictadmission.ReadinessGate)
+	}
+	setWorkloadStatus := func(status powerv1.PowerWorkloadStatus) {
+		current := &powerv1.PowerWorkload{}
+		assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKeyFromObject(workload), current))
+		current.Status = status
+		assert.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	}
+
+	// the PowerWorkload hasn't been applied yet
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ConditionFalse, condition().Status)
+	assert.Equal(t, strictadmission.ReasonPending, condition().Reason)
+	assert.Equal(t, []reconcile.Request{req}, r.workloadPodRequests(workload))
+
+	// a failing apply is retried until the timeout
+	setWorkloadStatus(powerv1.PowerWorkloadStatus{AppliedCPUs: "0/2", LastError: "MoveToPool performance [2 3]: device busy"})
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, strictadmission.ReasonApplyFailed, condition().Reason)
+	assert.Contains(t, condition().Message, "device busy")
+	assert.NotZero(t, result.RequeueAfter)
+
+	// applied on every CPU lets the Pod become Ready
+	setWorkloadStatus(powerv1.PowerWorkloadStatus{AppliedProfile: "performance", AppliedCPUs: "2/2"})
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ConditionTrue, condition().Status)
+	assert.Equal(t, strictadmission.ReasonApplied, condition().Reason)
+
+	// errors persisting past the timeout delete the Pod
+	setWorkloadStatus(powerv1.PowerWorkloadStatus{AppliedCPUs: "0/2", LastError: "MoveToPool performance [2 3]: device busy"})
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	current := &corev1.Pod{}
+	assert.NoError(t, r.Client.Get(context.TODO(), req.NamespacedName, current))
+	podCondition(current, strictadmission.ReadinessGate).LastTransitionTime = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	assert.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	err = r.Client.Get(context.TODO(), req.NamespacedName, &corev1.Pod{})
+	assert.True(t, errors.IsNotFound(err))
+	assert.Len(t, recorder.Events, 1)
+}
+
+func TestStrictAdmissionDefaulter(t *testing.T) {
+	namespaces := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "strict", Labels: map[string]string{strictadmission.NamespaceLabel: "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+	}
+	defaulter := &strictadmission.Defaulter{Client: fake.NewClientBuilder().WithRuntimeObjects(namespaces...).Build()}
+	pod := func(namespace string, resourceName corev1.ResourceName) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: namespace},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "db",
+				Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("2"),
+					resourceName:       resource.MustParse("2"),
+				}},
+			}}},
+		}
+	}
+
+	strict := pod("strict", "power.intel.com/performance")
+	assert.NoError(t, defaulter.Default(context.TODO(), strict))
+	assert.True(t, strictadmission.HasReadinessGate(strict))
+	assert.NoError(t, defaulter.Default(context.TODO(), strict))
+	assert.Len(t, strict.Spec.ReadinessGates, 1)
+
+	unlabelled := pod("default", "power.intel.com/performance")
+	assert.NoError(t, defaulter.Default(context.TODO(), unlabelled))
+	assert.False(t, strictadmission.HasReadinessGate(unlabelled))
+
+	noProfile := pod("strict", corev1.ResourceMemory)
+	assert.NoError(t, defaulter.Default(context.TODO(), noProfile))
+	assert.False(t, strictadmission.HasReadinessGate(noProfile))
+}
@@ -387,3 +387,28 @@ func (m *mockCpuDie) Core(id uint) power.Core {
 
 	return r0
 }
+
+type profMock struct {
+	mock.Mock
+	power.Profile
+}
+
+func (m *profMock) Name() string {
+	return m.Called().String(0)
+}
+
+func (m *profMock) MaxFreq() uint {
+	return m.Called().Get(0).(uint)
+}
+
+func (m *profMock) MinFreq() uint {
+	return m.Called().Get(0).(uint)
+}
+
+func (m *profMock) Governor() string {
+	return m.Called().String(0)
+}
+
+func (m *profMock) Epp() string {
+	return m.Called().String(0)
+}
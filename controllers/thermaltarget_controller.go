@@ -0,0 +1,196 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
+	"github.com/intel/kubernetes-power-manager/pkg/thermal"
+)
+
+// ThermalTargetReconciler keeps the cores of PowerProfiles with a temperatureTarget under it by capping each
+// core's max frequency below the profile's, with a PID controller per core
+type ThermalTargetReconciler struct {
+	client.Client
+	Log          logr.Logger
+	PowerLibrary power.Host
+	Sensor       thermal.Sensor
+	Capper       thermal.FrequencyCapper
+	Gains        thermal.Gains
+	Interval     time.Duration
+
+	cores        map[uint]*thermalCore
+	lastAdjusted time.Time
+}
+
+// thermalCore is the state of a core whose frequency is being capped
+type thermalCore struct {
+	pid     thermal.PID
+	profile string
+	cap     uint
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerprofiles,verbs=get;list;watch
+
+// Start adjusts the caps on every interval until the context is cancelled
+func (r *ThermalTargetReconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			err := r.Adjust(ctx, now)
+			if err != nil {
+				r.Log.Error(err, "error adjusting thermal frequency caps")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent has to look after its own cores
+func (r *ThermalTargetReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Adjust reads the temperature of every core with a target and moves its frequency cap accordingly. Cores that
+// no longer have a target are given back the max frequency of the pool they are in
+func (r *ThermalTargetReconciler) Adjust(ctx context.Context, now time.Time) error {
+	logger := r.Log.WithName("thermalTarget")
+	nodeName := os.Getenv("NODE_NAME")
+	if r.cores == nil {
+		r.cores = make(map[uint]*thermalCore)
+	}
+
+	profiles := &powerv1.PowerProfileList{}
+	err := r.Client.List(ctx, profiles, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]int)
+	for _, profile := range profiles.Items {
+		if profile.Spec.TemperatureTarget > 0 {
+			targets[profile.Spec.Name] = profile.Spec.TemperatureTarget
+		}
+	}
+
+	// the profile of every core in a profiled pool, cores with a target are the ones controlled
+	coreProfiles := make(map[uint]power.Profile)
+	controlled := make([]uint, 0)
+	for _, pool := range r.profiledPools() {
+		profile := pool.GetPowerProfile()
+		for _, cpu := range pool.Cpus().IDs() {
+			coreProfiles[cpu] = profile
+			if targets[profile.Name()] > 0 {
+				controlled = append(controlled, cpu)
+			}
+		}
+	}
+
+	seconds := r.Interval.Seconds()
+	if !r.lastAdjusted.IsZero() {
+		seconds = now.Sub(r.lastAdjusted).Seconds()
+	}
+	r.lastAdjusted = now
+
+	results := new(multierror.Error)
+	temperatures := make(map[uint]float64)
+	if len(controlled) > 0 {
+		temperatures, err = r.Sensor.Temperatures(controlled)
+		if err != nil {
+			return err
+		}
+	}
+	active := make(map[uint]bool, len(controlled))
+	for _, cpu := range controlled {
+		profile := coreProfiles[cpu]
+		temperature, found := temperatures[cpu]
+		if !found {
+			continue
+		}
+		active[cpu] = true
+		core, exists := r.cores[cpu]
+		if !exists || core.profile != profile.Name() {
+			core = &thermalCore{pid: thermal.PID{Gains: r.Gains}, profile: profile.Name(), cap: profile.MaxFreq()}
+			r.cores[cpu] = core
+		}
+
+		overTarget := temperature - float64(targets[profile.Name()])
+		reduction := core.pid.Update(overTarget, seconds, float64(profile.MaxFreq()-profile.MinFreq()))
+		capMHz := profile.MaxFreq() - uint(reduction)
+		// the Power Library writes the profile's max frequency back whenever it reapplies the pool, so a cap is
+		// written on every adjustment
+		if capMHz < profile.MaxFreq() || capMHz != core.cap {
+			err = r.Capper.SetMaxFrequency(cpu, capMHz)
+			if err != nil {
+				results = multierror.Append(results, fmt.Errorf("capping cpu %d: %w", cpu, err))
+				continue
+			}
+		}
+		if capMHz != core.cap {
+			logger.V(5).Info("Thermal frequency cap moved", "cpu", cpu, "temperature", temperature, "from", core.cap, "to", capMHz)
+		}
+		core.cap = capMHz
+		metrics.CoreThermalCap.WithLabelValues(nodeName, strconv.FormatUint(uint64(cpu), 10)).Set(float64(capMHz))
+	}
+
+	for cpu, core := range r.cores {
+		if active[cpu] {
+			continue
+		}
+		delete(r.cores, cpu)
+		metrics.CoreThermalCap.DeleteLabelValues(nodeName, strconv.FormatUint(uint64(cpu), 10))
+		profile, found := coreProfiles[cpu]
+		if !found || core.cap == profile.MaxFreq() {
+			continue
+		}
+		err = r.Capper.SetMaxFrequency(cpu, profile.MaxFreq())
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("restoring cpu %d: %w", cpu, err))
+		}
+	}
+
+	return results.ErrorOrNil()
+}
+
+// profiledPools returns the Shared and exclusive pools that currently have a PowerProfile applied
+func (r *ThermalTargetReconciler) profiledPools() []power.Pool {
+	pools := make([]power.Pool, 0)
+	if sharedPool := r.PowerLibrary.GetSharedPool(); sharedPool.GetPowerProfile() != nil {
+		pools = append(pools, sharedPool)
+	}
+	for _, pool := range *r.PowerLibrary.GetAllExclusivePools() {
+		if pool.GetPowerProfile() != nil {
+			pools = append(pools, pool)
+		}
+	}
+
+	return pools
+}
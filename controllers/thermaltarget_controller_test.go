@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/intel/power-optimization-library/pkg/power"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/hostwrite"
+	"github.com/intel/kubernetes-power-manager/pkg/thermal"
+)
+
+type fakeThermal struct {
+	temperatures map[uint]float64
+	caps         map[uint]uint
+}
+
+func (f *fakeThermal) Temperatures(cpus []uint) (map[uint]float64, error) {
+	temperatures := make(map[uint]float64)
+	for _, cpu := range cpus {
+		if temperature, found := f.temperatures[cpu]; found {
+			temperatures[cpu] = temperature
+		}
+	}
+
+	return temperatures, nil
+}
+
+func (f *fakeThermal) SetMaxFrequency(cpu uint, mhz uint) error {
+	f.caps[cpu] = mhz
+	return nil
+}
+
+func TestThermalTargetReconciler(t *testing.T) {
+	t.Setenv("NODE_NAME", "TestNode")
+	s := scheme.Scheme
+	assert.NoError(t, powerv1.AddToScheme(s))
+	cl := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects([]runtime.Object{
+		&powerv1.PowerProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: IntelPowerNamespace},
+			Spec:       powerv1.PowerProfileSpec{Name: "edge", Max: 3000, Min: 1000, Epp: "performance", TemperatureTarget: 80},
+		},
+	}...).Build()
+
+	cores := make([]*coreMock, 4)
+	for i := range cores {
+		cores[i] = new(coreMock)
+		cores[i].On("GetID").Return(uint(i))
+	}
+	sharedProfile := new(profMock)
+	sharedProfile.On("Name").Return("shared")
+	sharedProfile.On("MaxFreq").Return(uint(2000))
+	sharedPool := new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	sharedPool.On("Cpus").Return(&power.CpuList{cores[0], cores[1]})
+	edgeProfile := new(profMock)
+	edgeProfile.On("Name").Return("edge")
+	edgeProfile.On("MaxFreq").Return(uint(3000))
+	edgeProfile.On("MinFreq").Return(uint(1000))
+	edgePool := new(poolMock)
+	edgePool.On("GetPowerProfile").Return(edgeProfile)
+	edgePool.On("Cpus").Return(&power.CpuList{cores[2], cores[3]})
+	powerLibMock := new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{edgePool})
+
+	sensor := &fakeThermal{temperatures: map[uint]float64{0: 95, 1: 95, 2: 84, 3: 70}, caps: make(map[uint]uint)}
+	r := &ThermalTargetReconciler{
+		Client:       cl,
+		Log:          ctrl.Log.WithName("testing"),
+		PowerLibrary: powerLibMock,
+		Sensor:       sensor,
+		Capper:       sensor,
+		Gains:        thermal.Gains{Proportional: 50, Integral: 10},
+		Interval:     time.Second,
+	}
+
+	now := time.Now()
+	assert.NoError(t, r.Adjust(context.TODO(), now))
+	// 4 degrees over: 50*4 + 10*4*1s, the cooler core and the Shared pool without a target are left alone
+	assert.Equal(t, map[uint]uint{2: 2760}, sensor.caps)
+
+	// the integral keeps the cap down while the core is still over the target
+	sensor.temperatures[2] = 81
+	assert.NoError(t, r.Adjust(context.TODO(), now.Add(time.Second)))
+	assert.Equal(t, uint(2900), sensor.caps[2])
+
+	// a core held at the min frequency doesn't wind up the integral
+	sensor.temperatures[2] = 200
+	for i := 2; i < 5; i++ {
+		assert.NoError(t, r.Adjust(context.TODO(), now.Add(time.Duration(i)*time.Second)))
+	}
+	assert.Equal(t, uint(1000), sensor.caps[2])
+	sensor.temperatures[2] = 70
+	assert.NoError(t, r.Adjust(context.TODO(), now.Add(5*time.Second)))
+	assert.Equal(t, uint(3000), sensor.caps[2])
+
+	// a core moved back to the Shared pool gets the Shared profile's max frequency back
+	sensor.temperatures[2] = 90
+	assert.NoError(t, r.Adjust(context.TODO(), now.Add(6*time.Second)))
+	assert.Less(t, sensor.caps[2], uint(3000))
+	sharedPool = new(poolMock)
+	sharedPool.On("GetPowerProfile").Return(sharedProfile)
+	sharedPool.On("Cpus").Return(&power.CpuList{cores[0], cores[1], cores[2]})
+	edgePool = new(poolMock)
+	edgePool.On("GetPowerProfile").Return(edgeProfile)
+	edgePool.On("Cpus").Return(&power.CpuList{cores[3]})
+	powerLibMock = new(hostMock)
+	powerLibMock.On("GetSharedPool").Return(sharedPool)
+	powerLibMock.On("GetAllExclusivePools").Return(&power.PoolList{edgePool})
+	r.PowerLibrary = powerLibMock
+	assert.NoError(t, r.Adjust(context.TODO(), now.Add(7*time.Second)))
+	assert.Equal(t, uint(2000), sensor.caps[2])
+	assert.Len(t, r.cores, 1)
+}
+
+func TestThermalReader(t *testing.T) {
+	root := t.TempDir()
+	write := func(path string, content string) {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0644))
+	}
+	write("platform/coretemp.0/hwmon/hwmon3/temp1_label", "Package id 0\n")
+	write("platform/coretemp.0/hwmon/hwmon3/temp1_input", "61000\n")
+	write("platform/coretemp.0/hwmon/hwmon3/temp2_label", "Core 0\n")
+	write("platform/coretemp.0/hwmon/hwmon3/temp2_input", "58000\n")
+	write("platform/coretemp.0/hwmon/hwmon3/temp3_label", "Core 4\n")
+	write("platform/coretemp.0/hwmon/hwmon3/temp3_input", "72500\n")
+	// cpu0 and its hyperthread sibling cpu2 share core 0, cpu1 is on core 4 and cpu3 on a core without a sensor
+	for cpu, coreID := range map[string]string{"cpu0": "0", "cpu1": "4", "cpu2": "0", "cpu3": "8"} {
+		write("cpu/"+cpu+"/topology/physical_package_id", "0\n")
+		write("cpu/"+cpu+"/topology/core_id", coreID+"\n")
+		write("cpu/"+cpu+"/cpufreq/scaling_max_freq", "3000000\n")
+	}
+
+	reader := &thermal.Reader{HwmonPath: filepath.Join(root, "platform"), CpuPath: filepath.Join(root, "cpu")}
+	temperatures, err := reader.Temperatures([]uint{0, 1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint]float64{0: 58, 1: 72.5, 2: 58, 3: 61}, temperatures)
+
+	assert.NoError(t, reader.SetMaxFrequency(1, 2400))
+	data, err := os.ReadFile(filepath.Join(root, "cpu/cpu1/cpufreq/scaling_max_freq"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2400000", string(data))
+}
+
+func TestThermalReaderWriteHelper(t *testing.T) {
+	cpuPath := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(cpuPath, "cpu2", "cpufreq"), 0755))
+	for _, file := range []string{"scaling_max_freq", "scaling_governor"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(cpuPath, "cpu2", "cpufreq", file), []byte("0"), 0644))
+	}
+	// unix socket paths are limited to about 100 characters
+	socketDir, err := os.MkdirTemp("", "helper")
+	assert.NoError(t, err)
+	defer os.RemoveAll(socketDir)
+	socket := filepath.Join(socketDir, "helper.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+	defer listener.Close()
+	go (&hostwrite.Server{CpuPath: cpuPath, Log: ctrl.Log.WithName("testing")}).Serve(listener)
+
+	hostwrite.Default = hostwrite.NewHelper(socket)
+	defer func() { hostwrite.Default = hostwrite.Direct{} }()
+
+	reader := &thermal.Reader{CpuPath: cpuPath}
+	assert.NoError(t, reader.SetMaxFrequency(2, 2000))
+	data, err := os.ReadFile(filepath.Join(cpuPath, "cpu2", "cpufreq", "scaling_max_freq"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2000000", string(data))
+
+	// files off the allow list are refused, however the path is put
+	helper := hostwrite.NewHelper(socket)
+	assert.ErrorContains(t, helper.Write(filepath.Join(cpuPath, "cpu2", "cpufreq", "scaling_governor"), []byte("performance")), "allow list")
+	assert.ErrorContains(t, helper.Write(cpuPath+"/cpu2/cpufreq/../cpufreq/scaling_max_freq", []byte("1")), "allow list")
+	assert.ErrorContains(t, helper.Write("/etc/passwd", []byte("")), "allow list")
+	// files that don't exist aren't created
+	assert.Error(t, reader.SetMaxFrequency(3, 2000))
+	assert.NoFileExists(t, filepath.Join(cpuPath, "cpu3", "cpufreq", "scaling_max_freq"))
+}
@@ -0,0 +1,263 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/profileorder"
+	"github.com/intel/kubernetes-power-manager/pkg/thermal"
+)
+
+const (
+	ThrottleDemotedReason  = "ThrottleDemoted"
+	ThrottleRestoredReason = "ThrottleRestored"
+
+	defaultThrottleSustained = 60 * time.Second
+	defaultThrottleRecovery  = 5 * time.Minute
+)
+
+// ThrottleDemotionReconciler watches the Node's throttle counters and, under the PowerConfig's throttleDemotion,
+// marks the PowerNode as throttling once they have kept rising for long enough and clears it once they have
+// been still for long enough. The PowerProfile controller withholds capacity while the mark is set
+type ThrottleDemotionReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Counter  thermal.ThrottleCounter
+	Recorder record.EventRecorder
+	Interval time.Duration
+
+	last            thermal.ThrottleCounts
+	lastRead        time.Time
+	throttlingSince time.Time
+	quietSince      time.Time
+}
+
+// +kubebuilder:rbac:groups=power.intel.com,resources=powernodes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerconfigs,verbs=get;list;watch
+
+// Start checks the throttle counters on every interval until the context is cancelled
+func (r *ThrottleDemotionReconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			err := r.Check(ctx, now)
+			if err != nil {
+				r.Log.Error(err, "error checking the Node's throttle counters")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every Node Agent watches its own Node's counters
+func (r *ThrottleDemotionReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// Check reads the throttle counters once and demotes or restores the Node as they call for. The first read
+// after the agent starts, or after throttleDemotion is set, only primes the counters
+func (r *ThrottleDemotionReconciler) Check(ctx context.Context, now time.Time) error {
+	logger := r.Log.WithName("throttleDemotion")
+	nodeName := os.Getenv("NODE_NAME")
+
+	config, err := throttleDemotionConfig(ctx, r.Client)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		r.lastRead, r.throttlingSince, r.quietSince = time.Time{}, time.Time{}, time.Time{}
+		return r.setThrottling(ctx, nodeName, nil, "")
+	}
+	policy := config.Spec.ThrottleDemotion
+
+	counts, err := r.Counter.ThrottleCounts()
+	if err != nil {
+		return err
+	}
+	reason := ""
+	if !r.lastRead.IsZero() {
+		switch {
+		case counts.Thermal > r.last.Thermal:
+			reason = powerv1.ThrottlingThermal
+		case counts.PowerLimit > r.last.PowerLimit:
+			reason = powerv1.ThrottlingPowerLimit
+		}
+	}
+	previousRead := r.lastRead
+	r.last = counts
+	r.lastRead = now
+	if previousRead.IsZero() {
+		return nil
+	}
+
+	// the counters rose, or stayed still, somewhere since the previous read
+	if reason != "" {
+		r.quietSince = time.Time{}
+		if r.throttlingSince.IsZero() {
+			r.throttlingSince = previousRead
+		}
+	} else {
+		r.throttlingSince = time.Time{}
+		if r.quietSince.IsZero() {
+			r.quietSince = previousRead
+		}
+	}
+
+	powerNode := &powerv1.PowerNode{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	demoted := powerNode.Status.Throttling != nil
+
+	sustained := secondsOr(policy.SustainedSeconds, defaultThrottleSustained)
+	if !demoted && reason != "" && now.Sub(r.throttlingSince) >= sustained {
+		logger.Info("Node has kept throttling, withholding PowerProfile capacity", "reason", reason, "for", now.Sub(r.throttlingSince))
+		return r.setThrottling(ctx, nodeName, &powerv1.ThrottlingStatus{Reason: reason, Since: metav1.NewTime(now)},
+			fmt.Sprintf("Node has been throttling (%s) for %s, PowerProfile capacity is withheld", reason, now.Sub(r.throttlingSince).Round(time.Second)))
+	}
+	recovery := secondsOr(policy.RecoverySeconds, defaultThrottleRecovery)
+	if demoted && reason == "" && now.Sub(r.quietSince) >= recovery {
+		logger.Info("Node stopped throttling, restoring PowerProfile capacity", "for", now.Sub(r.quietSince))
+		return r.setThrottling(ctx, nodeName, nil,
+			fmt.Sprintf("Node hasn't throttled for %s, PowerProfile capacity is restored", now.Sub(r.quietSince).Round(time.Second)))
+	}
+
+	return nil
+}
+
+// setThrottling sets or clears the PowerNode's throttling status, recording an event with the message when it
+// changes
+func (r *ThrottleDemotionReconciler) setThrottling(ctx context.Context, nodeName string, throttling *powerv1.ThrottlingStatus, message string) error {
+	powerNode := &powerv1.PowerNode{}
+	changed := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		changed = false
+		err := r.Client.Get(ctx, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if (powerNode.Status.Throttling == nil) == (throttling == nil) {
+			return nil
+		}
+
+		powerNode.Status.Throttling = throttling
+		changed = true
+		return r.Client.Status().Update(ctx, powerNode)
+	})
+	if err != nil || !changed || r.Recorder == nil || message == "" {
+		return err
+	}
+
+	reason := ThrottleRestoredReason
+	eventType := corev1.EventTypeNormal
+	if throttling != nil {
+		reason = ThrottleDemotedReason
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Event(powerNode, eventType, reason, message)
+
+	return nil
+}
+
+// throttleDemotionConfig returns the first PowerConfig with a throttleDemotion, nil if there is none
+func throttleDemotionConfig(c context.Context, cl client.Client) (*powerv1.PowerConfig, error) {
+	configs := &powerv1.PowerConfigList{}
+	err := cl.List(c, configs, client.InNamespace(IntelPowerNamespace))
+	if err != nil {
+		return nil, err
+	}
+	for i := range configs.Items {
+		if configs.Items[i].Spec.ThrottleDemotion != nil {
+			return &configs.Items[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// throttleDemotionPercent is how much of the PowerProfile's capacity the Node withholds while it is demoted for
+// throttling, 0 if it isn't or the profile isn't one the throttleDemotion names
+func throttleDemotionPercent(c context.Context, cl client.Client, nodeName string, profileName string) (int, error) {
+	powerNode := &powerv1.PowerNode{}
+	err := cl.Get(c, client.ObjectKey{Name: nodeName, Namespace: IntelPowerNamespace}, powerNode)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if powerNode.Status.Throttling == nil {
+		return 0, nil
+	}
+	config, err := throttleDemotionConfig(c, cl)
+	if err != nil || config == nil {
+		return 0, err
+	}
+
+	policy := config.Spec.ThrottleDemotion
+	profiles := policy.PowerProfiles
+	if len(profiles) == 0 {
+		ordering := config.Spec.ProfileOrdering
+		if len(ordering) == 0 {
+			ordering = profileorder.DefaultOrdering
+		}
+		profiles = ordering[:1]
+	}
+	for _, name := range profiles {
+		if name != profileName {
+			continue
+		}
+		if policy.CapacityReductionPercent <= 0 {
+			return 100, nil
+		}
+		return policy.CapacityReductionPercent, nil
+	}
+
+	return 0, nil
+}
+
+func secondsOr(seconds int, fallback time.Duration) time.Duration {
+	if seconds <= 0 {
+		return fallback
+	}
+
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/thermal"
+)
+
+type fakeThrottleCounter struct {
+	counts thermal.ThrottleCounts
+}
+
+func (f *fakeThrottleCounter) ThrottleCounts() (thermal.ThrottleCounts, error) {
+	return f.counts, nil
+}
+
+func TestThrottleDemotionReconciler(t *testing.T) {
+	t.Setenv("NODE_NAME", "TestNode")
+	s := scheme.Scheme
+	assert.NoError(t, powerv1.AddToScheme(s))
+	cl := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects([]runtime.Object{
+		&powerv1.PowerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "power-config", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerConfigSpec{
+				ThrottleDemotion: &powerv1.ThrottleDemotion{SustainedSeconds: 30, RecoverySeconds: 60},
+			},
+		},
+		&powerv1.PowerNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "TestNode", Namespace: IntelPowerNamespace},
+		},
+	}...).Build()
+
+	counter := &fakeThrottleCounter{}
+	recorder := record.NewFakeRecorder(10)
+	r := &ThrottleDemotionReconciler{
+		Client:   cl,
+		Log:      ctrl.Log.WithName("testing"),
+		Counter:  counter,
+		Recorder: recorder,
+		Interval: 10 * time.Second,
+	}
+	throttling := func() *powerv1.ThrottlingStatus {
+		powerNode := &powerv1.PowerNode{}
+		assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Name: "TestNode", Namespace: IntelPowerNamespace}, powerNode))
+		return powerNode.Status.Throttling
+	}
+
+	start := time.Now()
+	// the first read only primes the counters
+	counter.counts = thermal.ThrottleCounts{Thermal: 100}
+	assert.NoError(t, r.Check(context.TODO(), start))
+	assert.Nil(t, throttling())
+
+	// throttling for less than sustainedSeconds doesn't demote the Node
+	counter.counts.PowerLimit = 5
+	assert.NoError(t, r.Check(context.TODO(), start.Add(10*time.Second)))
+	counter.counts.PowerLimit = 10
+	assert.NoError(t, r.Check(context.TODO(), start.Add(20*time.Second)))
+	assert.Nil(t, throttling())
+
+	counter.counts.PowerLimit = 15
+	counter.counts.Thermal = 101
+	assert.NoError(t, r.Check(context.TODO(), start.Add(40*time.Second)))
+	status := throttling()
+	if assert.NotNil(t, status) {
+		assert.Equal(t, powerv1.ThrottlingThermal, status.Reason)
+	}
+	assert.Len(t, recorder.Events, 1)
+
+	percent, err := throttleDemotionPercent(context.TODO(), cl, "TestNode", "gold")
+	assert.NoError(t, err)
+	assert.Equal(t, 100, percent)
+	percent, err = throttleDemotionPercent(context.TODO(), cl, "TestNode", "silver")
+	assert.NoError(t, err)
+	assert.Zero(t, percent)
+
+	// a quiet spell shorter than recoverySeconds keeps the Node demoted
+	assert.NoError(t, r.Check(context.TODO(), start.Add(70*time.Second)))
+	counter.counts.Thermal = 102
+	assert.NoError(t, r.Check(context.TODO(), start.Add(80*time.Second)))
+	assert.NoError(t, r.Check(context.TODO(), start.Add(120*time.Second)))
+	assert.NotNil(t, throttling())
+
+	assert.NoError(t, r.Check(context.TODO(), start.Add(150*time.Second)))
+	assert.Nil(t, throttling())
+	assert.Len(t, recorder.Events, 2)
+
+	percent, err = throttleDemotionPercent(context.TODO(), cl, "TestNode", "gold")
+	assert.NoError(t, err)
+	assert.Zero(t, percent)
+}
+
+func TestThrottleCounts(t *testing.T) {
+	cpuPath := t.TempDir()
+	counters := map[string]map[string]string{
+		"cpu0": {"core_throttle_count": "3", "package_throttle_count": "10", "core_power_limit_count": "1", "package_power_limit_count": "2"},
+		"cpu1": {"core_throttle_count": "4\n", "package_throttle_count": "10"},
+	}
+	for cpu, files := range counters {
+		dir := filepath.Join(cpuPath, cpu, "thermal_throttle")
+		assert.NoError(t, os.MkdirAll(dir, 0755))
+		for name, value := range files {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(value), 0644))
+		}
+	}
+
+	reader := &thermal.Reader{CpuPath: cpuPath}
+	counts, err := reader.ThrottleCounts()
+	assert.NoError(t, err)
+	assert.Equal(t, thermal.ThrottleCounts{Thermal: 27, PowerLimit: 3}, counts)
+
+	_, err = (&thermal.Reader{CpuPath: t.TempDir()}).ThrottleCounts()
+	assert.Error(t, err)
+}
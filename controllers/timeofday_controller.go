@@ -59,7 +59,6 @@ type TimeOfDayReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.6.4/pkg/reconcile
 func (r *TimeOfDayReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
 	logger := r.Log.WithValues("timeofday", req.NamespacedName)
 	if req.Namespace != IntelPowerNamespace {
 		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
@@ -202,7 +201,7 @@ func (r *TimeOfDayReconciler) Reconcile(c context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
-	err = r.cleanUpCronJobs(cronJobList.Items, cronJobNames)
+	err = r.cleanUpCronJobs(c, cronJobList.Items, cronJobNames)
 	if err != nil {
 		logger.Error(err, "Error reconciling TimeOfDay")
 		return ctrl.Result{}, err
@@ -211,10 +210,10 @@ func (r *TimeOfDayReconciler) Reconcile(c context.Context, req ctrl.Request) (ct
 	return ctrl.Result{}, nil
 }
 
-func (r *TimeOfDayReconciler) cleanUpCronJobs(cronJobs []powerv1.TimeOfDayCronJob, expectedCronJobs []string) error {
+func (r *TimeOfDayReconciler) cleanUpCronJobs(c context.Context, cronJobs []powerv1.TimeOfDayCronJob, expectedCronJobs []string) error {
 	for _, cronJob := range cronJobs {
 		if !util.StringInStringList(cronJob.Name, expectedCronJobs) {
-			err := r.Client.Delete(context.TODO(), &cronJob)
+			err := r.Client.Delete(c, &cronJob)
 			if err != nil {
 				return err
 			}
@@ -54,7 +54,6 @@ type TimeOfDayCronJobReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.6.4/pkg/reconcile
 func (r *TimeOfDayCronJobReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
 	logger := r.Log.WithValues("timeofdaycronjob", req.NamespacedName)
 	if req.Namespace != IntelPowerNamespace {
 		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
@@ -63,7 +62,7 @@ func (r *TimeOfDayCronJobReconciler) Reconcile(c context.Context, req ctrl.Reque
 	logger.Info("Reconciling TimeOfDayCronJob")
 
 	cronJob := &powerv1.TimeOfDayCronJob{}
-	err := r.Client.Get(context.TODO(), req.NamespacedName, cronJob)
+	err := r.Client.Get(c, req.NamespacedName, cronJob)
 
 	if err != nil {
 		logger.Error(err, "Error retrieving CronJob")
@@ -120,7 +119,7 @@ func (r *TimeOfDayCronJobReconciler) Reconcile(c context.Context, req ctrl.Reque
 				// if not create one
 				logger.V(5).Info("Checking for existing shared workload")
 				workloadList := &powerv1.PowerWorkloadList{}
-				err = r.Client.List(context.TODO(), workloadList)
+				err = r.Client.List(c, workloadList)
 				if err != nil {
 					logger.Error(err, "error retrieving workloads")
 					return ctrl.Result{}, err
@@ -152,7 +151,7 @@ func (r *TimeOfDayCronJobReconciler) Reconcile(c context.Context, req ctrl.Reque
 							PowerProfile: *cronJob.Spec.Profile,
 						},
 					}
-					if err = r.Client.Create(context.TODO(), workload); err != nil {
+					if err = r.Client.Create(c, workload); err != nil {
 						logger.Error(err, "error creating workload")
 						return ctrl.Result{}, err
 					}
@@ -177,7 +176,7 @@ func (r *TimeOfDayCronJobReconciler) Reconcile(c context.Context, req ctrl.Reque
 			}
 			if cronJob.Spec.CState != nil {
 				cstate := &powerv1.CStates{}
-				err = r.Client.Get(context.TODO(), client.ObjectKey{
+				err = r.Client.Get(c, client.ObjectKey{
 					Name:      nodeName,
 					Namespace: IntelPowerNamespace,
 				}, cstate)
@@ -195,7 +194,7 @@ func (r *TimeOfDayCronJobReconciler) Reconcile(c context.Context, req ctrl.Reque
 							IndividualCoreCStates: cronJob.Spec.CState.IndividualCoreCStates,
 						},
 					}
-					if err = r.Client.Create(context.TODO(), newCstate); err != nil {
+					if err = r.Client.Create(c, newCstate); err != nil {
 						logger.Error(err, "error creating workload")
 						return ctrl.Result{}, err
 					}
@@ -227,7 +226,7 @@ func (r *TimeOfDayCronJobReconciler) Reconcile(c context.Context, req ctrl.Reque
 					for from, to := range profToProf {
 						//useful check to see if we've already retrieved the workload in an earlier loop
 						if workloadFrom.Name != from {
-							err = r.Client.Get(context.TODO(), client.ObjectKey{
+							err = r.Client.Get(c, client.ObjectKey{
 								Name:      from + "-" + nodeName,
 								Namespace: IntelPowerNamespace,
 							}, &workloadFrom)
@@ -238,7 +237,7 @@ func (r *TimeOfDayCronJobReconciler) Reconcile(c context.Context, req ctrl.Reque
 						}
 						//same check as before
 						if workloadTo.Name != to {
-							err = r.Client.Get(context.TODO(), client.ObjectKey{
+							err = r.Client.Get(c, client.ObjectKey{
 								Name:      to + "-" + nodeName,
 								Namespace: IntelPowerNamespace,
 							}, &workloadTo)
@@ -53,7 +53,6 @@ type UncoreReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.1/pkg/reconcile
 func (r *UncoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
 	nodeName := os.Getenv("NODE_NAME")
 	// uncore is not for this node
 	if req.Name != nodeName {
@@ -84,7 +83,7 @@ func (r *UncoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			}
 		}
 	}
-	err = r.Client.Get(context.TODO(), req.NamespacedName, uncore)
+	err = r.Client.Get(ctx, req.NamespacedName, uncore)
 	if err != nil {
 		//uncore deleted so we can ignore here since everything is already reset
 		if errors.IsNotFound(err) {
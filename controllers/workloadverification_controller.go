@@ -0,0 +1,277 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/power-optimization-library/pkg/power"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/probe"
+)
+
+const (
+	// VerificationWorkloadLabel marks the probe Jobs and their Pods with the PowerWorkload they verify
+	VerificationWorkloadLabel = "power.intel.com/verification"
+	// VerificationProfileAnnotation records the PowerProfile a probe Job was started for
+	VerificationProfileAnnotation = "power.intel.com/verification-profile"
+
+	// how often a running probe is checked on
+	verificationPollInterval = 5 * time.Second
+	// how long a probe Pod is given on top of its duration to start and report
+	verificationGracePeriod = 2 * time.Minute
+	// how long a probe runs for when its duration isn't set
+	defaultVerificationSeconds = 10
+)
+
+// WorkloadVerificationReconciler runs the latency probe of a PowerWorkload on this Node once a PowerProfile has
+// been applied to its pool. The probe Job asks for one CPU and one of the PowerProfile's Extended Resources, so the
+// CPU Manager gives it an exclusive CPU that joins the pool like any other workload's, and its percentiles are
+// recorded in the PowerWorkload's status
+type WorkloadVerificationReconciler struct {
+	client.Client
+	Log logr.Logger
+	// APIReader reads the probe Jobs and Pods, the agent doesn't cache Jobs
+	APIReader    client.Reader
+	PowerLibrary power.Host
+}
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;create;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=power.intel.com,resources=powerworkloads/status,verbs=get;update;patch
+
+func (r *WorkloadVerificationReconciler) Reconcile(c context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("powerworkload", req.NamespacedName)
+	if req.Namespace != IntelPowerNamespace {
+		logger.Error(fmt.Errorf("incorrect namespace"), "resource is not in the intel-power namespace, ignoring")
+		return ctrl.Result{}, nil
+	}
+	nodeName := os.Getenv("NODE_NAME")
+
+	workload := &powerv1.PowerWorkload{}
+	err := r.Client.Get(c, req.NamespacedName, workload)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	verification := workload.Spec.Verification
+	if verification == nil || workload.Spec.AllCores || workload.Spec.Node.Name != nodeName {
+		return ctrl.Result{}, nil
+	}
+	profile := workload.Status.AppliedProfile
+	if profile == "" || profile != workload.Spec.PowerProfile || r.PowerLibrary.GetExclusivePool(profile) == nil {
+		// verified once the PowerProfile is applied
+		return ctrl.Result{}, nil
+	}
+	result := workload.Status.Verification
+	if result != nil && result.Profile == profile && result.Phase != powerv1.VerificationPhaseRunning {
+		return ctrl.Result{}, nil
+	}
+
+	jobName := verificationJobName(workload.Name)
+	job := &batchv1.Job{}
+	err = r.APIReader.Get(c, client.ObjectKey{Name: jobName, Namespace: IntelPowerNamespace}, job)
+	if errors.IsNotFound(err) {
+		if result != nil && result.Profile == profile && result.Phase == powerv1.VerificationPhaseRunning {
+			return ctrl.Result{}, r.setVerification(c, workload.Name, &powerv1.VerificationResult{
+				Phase:     powerv1.VerificationPhaseFailed,
+				Profile:   profile,
+				Started:   result.Started,
+				Completed: &metav1.Time{Time: time.Now()},
+				Message:   "probe Job was deleted before it reported",
+			})
+		}
+		seconds := verification.DurationSeconds
+		if seconds < 1 {
+			seconds = defaultVerificationSeconds
+		}
+		err = r.Client.Create(c, buildVerificationJob(workload, jobName, profile, nodeName, seconds))
+		if err != nil {
+			logger.Error(err, "error creating the verification probe")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Verifying the PowerProfile's latency on the PowerWorkload's pool", "profile", profile, "job", jobName)
+		err = r.setVerification(c, workload.Name, &powerv1.VerificationResult{
+			Phase:   powerv1.VerificationPhaseRunning,
+			Profile: profile,
+			Job:     jobName,
+			Started: &metav1.Time{Time: time.Now()},
+		})
+		return ctrl.Result{RequeueAfter: time.Duration(seconds)*time.Second + verificationPollInterval}, err
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if job.Annotations[VerificationProfileAnnotation] != profile {
+		// the PowerProfile changed while the probe ran, it's restarted with the new one
+		logger.V(5).Info("Removing the probe of the previous PowerProfile", "job", jobName)
+		return ctrl.Result{RequeueAfter: verificationPollInterval}, r.deleteJob(c, job)
+	}
+	if !jobFinished(job) {
+		return ctrl.Result{RequeueAfter: verificationPollInterval}, nil
+	}
+
+	finished := &powerv1.VerificationResult{
+		Profile:   profile,
+		Completed: &metav1.Time{Time: time.Now()},
+	}
+	if result != nil {
+		finished.Started = result.Started
+	}
+	latencies, err := r.probeLatencies(c, job)
+	if err != nil {
+		finished.Phase = powerv1.VerificationPhaseFailed
+		finished.Message = err.Error()
+	} else {
+		finished.P50Microseconds = latencies.P50
+		finished.P99Microseconds = latencies.P99
+		finished.MaxMicroseconds = latencies.Max
+		finished.Phase = powerv1.VerificationPhasePassed
+		if verification.MaxP99Microseconds > 0 && latencies.P99 > verification.MaxP99Microseconds {
+			finished.Phase = powerv1.VerificationPhaseFailed
+			finished.Message = fmt.Sprintf("99th percentile latency of %dus is above %dus", latencies.P99, verification.MaxP99Microseconds)
+		}
+	}
+	logger.Info("PowerWorkload verified", "profile", profile, "phase", finished.Phase,
+		"p50", finished.P50Microseconds, "p99", finished.P99Microseconds, "max", finished.MaxMicroseconds, "message", finished.Message)
+	err = r.setVerification(c, workload.Name, finished)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, r.deleteJob(c, job)
+}
+
+// probeLatencies reads the latencies the probe container left in its termination message
+func (r *WorkloadVerificationReconciler) probeLatencies(c context.Context, job *batchv1.Job) (probe.Latencies, error) {
+	pods := &corev1.PodList{}
+	err := r.APIReader.List(c, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name})
+	if err != nil {
+		return probe.Latencies{}, err
+	}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated == nil {
+				continue
+			}
+			if status.State.Terminated.ExitCode != 0 {
+				return probe.Latencies{}, fmt.Errorf("probe exited with %d: %s", status.State.Terminated.ExitCode, status.State.Terminated.Reason)
+			}
+			return probe.ParseLatencies(status.State.Terminated.Message)
+		}
+	}
+
+	return probe.Latencies{}, fmt.Errorf("probe Job %s finished without a result", job.Name)
+}
+
+func (r *WorkloadVerificationReconciler) setVerification(c context.Context, workloadName string, result *powerv1.VerificationResult) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		workload := &powerv1.PowerWorkload{}
+		err := r.Client.Get(c, client.ObjectKey{Name: workloadName, Namespace: IntelPowerNamespace}, workload)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if reflect.DeepEqual(workload.Status.Verification, result) {
+			return nil
+		}
+		workload.Status.Verification = result
+		return r.Client.Status().Update(c, workload)
+	})
+}
+
+func (r *WorkloadVerificationReconciler) deleteJob(c context.Context, job *batchv1.Job) error {
+	return client.IgnoreNotFound(r.Client.Delete(c, job, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+}
+
+// verificationJobName keeps the Job's name, and the job-name label of its Pod, within the 63 characters of a label
+func verificationJobName(workloadName string) string {
+	const suffix = "-verify"
+	if len(workloadName) > 63-len(suffix) {
+		workloadName = workloadName[:63-len(suffix)]
+	}
+
+	return workloadName + suffix
+}
+
+// buildVerificationJob makes the probe Job of a PowerWorkload, run on this Node as a Guaranteed Pod with one CPU
+// and one of the PowerProfile's Extended Resources, for the probe to run the given seconds
+func buildVerificationJob(workload *powerv1.PowerWorkload, jobName string, profile string, nodeName string, seconds int) *batchv1.Job {
+	verification := workload.Spec.Verification
+	backoffLimit := int32(0)
+	deadline := int64(seconds) + int64(verificationGracePeriod.Seconds())
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:                            resource.MustParse("1"),
+		corev1.ResourceMemory:                         resource.MustParse("64Mi"),
+		corev1.ResourceName(ResourcePrefix + profile): resource.MustParse("1"),
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        jobName,
+			Namespace:   IntelPowerNamespace,
+			Labels:      map[string]string{VerificationWorkloadLabel: workload.Name},
+			Annotations: map[string]string{VerificationProfileAnnotation: profile},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{VerificationWorkloadLabel: workload.Name},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "probe",
+						Image:   verification.Image,
+						Command: []string{"/bin/sh", "-c", probe.LatencyScript(verification.Tool, seconds)},
+						Resources: corev1.ResourceRequirements{
+							Requests: resources,
+							Limits:   resources,
+						},
+						SecurityContext: &corev1.SecurityContext{
+							Capabilities: &corev1.Capabilities{
+								Add: []corev1.Capability{"SYS_NICE", "IPC_LOCK"},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func (r *WorkloadVerificationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&powerv1.PowerWorkload{}).
+		Complete(r)
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func createWorkloadVerificationReconcilerObject(objs []runtime.Object) (*WorkloadVerificationReconciler, error) {
+	s := scheme.Scheme
+	if err := powerv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	cl := fake.NewClientBuilder().WithRuntimeObjects(objs...).WithScheme(s).Build()
+
+	return &WorkloadVerificationReconciler{Client: cl, APIReader: cl, Log: ctrl.Log.WithName("testing")}, nil
+}
+
+func TestWorkloadVerificationReconciler(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	workload := &powerv1.PowerWorkload{
+		ObjectMeta: metav1.ObjectMeta{Name: "performance-TestNode", Namespace: IntelPowerNamespace},
+		Spec: powerv1.PowerWorkloadSpec{
+			Name:         "performance-TestNode",
+			PowerProfile: "performance",
+			Node:         powerv1.WorkloadNode{Name: nodeName, CpuIds: []uint{2, 3}},
+			Verification: &powerv1.WorkloadVerification{
+				Image:              "registry.example.com/rt-tests:latest",
+				DurationSeconds:    10,
+				MaxP99Microseconds: 20,
+			},
+		},
+		Status: powerv1.PowerWorkloadStatus{AppliedProfile: "performance"},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(workload)}
+	jobKey := client.ObjectKey{Name: "performance-TestNode-verify", Namespace: IntelPowerNamespace}
+
+	r, err := createWorkloadVerificationReconcilerObject([]runtime.Object{workload})
+	assert.NoError(t, err)
+	host := new(hostMock)
+	host.On("GetExclusivePool", "performance").Return(new(poolMock))
+	r.PowerLibrary = host
+
+	// the probe Job is started once the PowerProfile is applied
+	result, err := r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NotZero(t, result.RequeueAfter)
+
+	job := &batchv1.Job{}
+	err = r.Client.Get(context.TODO(), jobKey, job)
+	assert.NoError(t, err)
+	assert.Equal(t, nodeName, job.Spec.Template.Spec.NodeName)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "1", container.Resources.Limits.Cpu().String())
+	assert.Equal(t, "1", container.Resources.Limits.Name(ResourcePrefix+"performance", "").String())
+	assert.Equal(t, container.Resources.Requests, container.Resources.Limits)
+
+	err = r.Client.Get(context.TODO(), req.NamespacedName, workload)
+	assert.NoError(t, err)
+	assert.Equal(t, powerv1.VerificationPhaseRunning, workload.Status.Verification.Phase)
+	assert.Equal(t, "performance", workload.Status.Verification.Profile)
+
+	// still running
+	result, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, verificationPollInterval, result.RequeueAfter)
+
+	// the probe reports a 99th percentile above the limit
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	err = r.Client.Status().Update(context.TODO(), job)
+	assert.NoError(t, err)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "performance-TestNode-verify-abcde",
+			Namespace: IntelPowerNamespace,
+			Labels:    map[string]string{"job-name": jobKey.Name},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "probe",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Message: "p50=4 p99=25 max=40"},
+				},
+			}},
+		},
+	}
+	err = r.Client.Create(context.TODO(), pod)
+	assert.NoError(t, err)
+
+	result, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+
+	err = r.Client.Get(context.TODO(), req.NamespacedName, workload)
+	assert.NoError(t, err)
+	verification := workload.Status.Verification
+	assert.Equal(t, powerv1.VerificationPhaseFailed, verification.Phase)
+	assert.Equal(t, 4, verification.P50Microseconds)
+	assert.Equal(t, 25, verification.P99Microseconds)
+	assert.Equal(t, 40, verification.MaxMicroseconds)
+	assert.Contains(t, verification.Message, "above 20us")
+
+	err = r.Client.Get(context.TODO(), jobKey, &batchv1.Job{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// the result stands until another PowerProfile is applied
+	_, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	err = r.Client.Get(context.TODO(), jobKey, &batchv1.Job{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestWorkloadVerificationReconciler_NotApplied(t *testing.T) {
+	nodeName := "TestNode"
+	t.Setenv("NODE_NAME", nodeName)
+
+	workloads := []runtime.Object{
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				PowerProfile: "performance",
+				Node:         powerv1.WorkloadNode{Name: nodeName},
+				Verification: &powerv1.WorkloadVerification{Image: "rt-tests"},
+			},
+		},
+		&powerv1.PowerWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-node", Namespace: IntelPowerNamespace},
+			Spec: powerv1.PowerWorkloadSpec{
+				PowerProfile: "performance",
+				Node:         powerv1.WorkloadNode{Name: "OtherNode"},
+				Verification: &powerv1.WorkloadVerification{Image: "rt-tests"},
+			},
+			Status: powerv1.PowerWorkloadStatus{AppliedProfile: "performance"},
+		},
+	}
+	r, err := createWorkloadVerificationReconcilerObject(workloads)
+	assert.NoError(t, err)
+	host := new(hostMock)
+	host.On("GetExclusivePool", "performance").Return(new(poolMock))
+	r.PowerLibrary = host
+
+	for _, name := range []string{"pending", "other-node"} {
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKey{Name: name, Namespace: IntelPowerNamespace}})
+		assert.NoError(t, err)
+		err = r.Client.Get(context.TODO(), client.ObjectKey{Name: verificationJobName(name), Namespace: IntelPowerNamespace}, &batchv1.Job{})
+		assert.True(t, errors.IsNotFound(err), name)
+	}
+}
@@ -0,0 +1,155 @@
+// Package adopt takes over the per-core settings a Node was hand tuned with before the Power Manager was
+// installed. Cores with the same settings are described as a PowerProfile and a PowerWorkload marked as adopted,
+// which the controllers leave as they are until a user edits them
+package adopt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intel/kubernetes-power-manager/pkg/handoff"
+	"github.com/intel/power-optimization-library/pkg/power"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotation marks an adopted object, its value is the generation the object was adopted at
+const Annotation = "power.intel.com/adopted"
+
+// CpuPath holds each CPU's cpufreq settings
+const CpuPath = "/sys/devices/system/cpu"
+
+// Pending is true while an adopted object hasn't been edited. Changing its spec or removing the annotation
+// hands it over to the controllers
+func Pending(obj metav1.Object) bool {
+	value, ok := obj.GetAnnotations()[Annotation]
+	return ok && value == strconv.FormatInt(obj.GetGeneration(), 10)
+}
+
+// Settings are the cpufreq settings of a core, frequencies are in kHz as in sysfs
+type Settings struct {
+	Min      uint
+	Max      uint
+	Governor string
+	Epp      string
+}
+
+// Name is the PowerProfile name for the settings
+func (s Settings) Name() string {
+	name := fmt.Sprintf("adopted-%d-%d-%s", s.Min/1000, s.Max/1000, s.Governor)
+	if s.Epp != "" {
+		name += "-" + s.Epp
+	}
+
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+// Group is a set of cores with the same settings
+type Group struct {
+	Settings
+	Cpus []uint
+}
+
+// Reader reads the per-core settings from cpufreq
+type Reader struct {
+	CpuPath string
+}
+
+func NewReader() *Reader {
+	return &Reader{CpuPath: CpuPath}
+}
+
+// Groups returns the cores whose settings differ from the defaults, grouped by settings and ordered by their
+// first core. A core running across its whole hardware frequency range is taken to be untuned whatever its
+// governor, so it's left for the Shared pool
+func (r *Reader) Groups(cpus []uint) ([]Group, error) {
+	groups := make(map[Settings][]uint)
+	for _, cpu := range cpus {
+		dir := filepath.Join(r.CpuPath, fmt.Sprintf("cpu%d", cpu), "cpufreq")
+		values := make(map[string]uint)
+		for _, file := range []string{"scaling_min_freq", "scaling_max_freq", "cpuinfo_min_freq", "cpuinfo_max_freq"} {
+			value, err := readUint(filepath.Join(dir, file))
+			if err != nil {
+				return nil, err
+			}
+			values[file] = value
+		}
+		if values["scaling_min_freq"] == values["cpuinfo_min_freq"] && values["scaling_max_freq"] == values["cpuinfo_max_freq"] {
+			continue
+		}
+
+		governor, err := readString(filepath.Join(dir, "scaling_governor"))
+		if err != nil {
+			return nil, err
+		}
+		// EPP is only there with intel_pstate in active mode
+		epp, err := readString(filepath.Join(dir, "energy_performance_preference"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		settings := Settings{
+			Min:      values["scaling_min_freq"],
+			Max:      values["scaling_max_freq"],
+			Governor: governor,
+			Epp:      epp,
+		}
+		groups[settings] = append(groups[settings], cpu)
+	}
+
+	result := make([]Group, 0, len(groups))
+	for settings, cpus := range groups {
+		sort.Slice(cpus, func(i, j int) bool { return cpus[i] < cpus[j] })
+		result = append(result, Group{Settings: settings, Cpus: cpus})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Cpus[0] < result[j].Cpus[0]
+	})
+
+	return result, nil
+}
+
+// Restore recreates the groups as exclusive pools in a freshly created Power Library, each named after its
+// PowerProfile, without writing the cores anything they don't already have
+func Restore(host power.Host, groups []Group) error {
+	state := &handoff.State{}
+	for _, group := range groups {
+		state.Exclusive = append(state.Exclusive, handoff.Pool{
+			Name: group.Name(),
+			Profile: &handoff.Profile{
+				Name:     group.Name(),
+				Min:      group.Min,
+				Max:      group.Max,
+				Governor: group.Governor,
+				Epp:      group.Epp,
+			},
+			Cpus: group.Cpus,
+		})
+	}
+
+	return handoff.Restore(host, state)
+}
+
+func readString(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readUint(path string) (uint, error) {
+	value, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("malformed value '%s' in %s", value, path)
+	}
+
+	return uint(parsed), nil
+}
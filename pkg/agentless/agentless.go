@@ -0,0 +1,217 @@
+package agentless
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+)
+
+const (
+	defaultPort = "22"
+	dialTimeout = 30 * time.Second
+)
+
+// Credentials are how a pool's hosts are logged into, a private key is preferred over a password
+type Credentials struct {
+	Username   string
+	Password   string
+	PrivateKey []byte
+	// known_hosts formatted host keys the hosts have to present
+	KnownHosts []byte
+	// InsecureIgnoreHostKey accepts any host key when there are no known hosts
+	InsecureIgnoreHostKey bool
+}
+
+// Runner runs a shell script on a host and returns its combined output
+type Runner interface {
+	Run(ctx context.Context, host string, credentials *Credentials, script string) (string, error)
+}
+
+// Address is the host's SSH address, the host with the default port unless it has one. Hosts are names, IPv4
+// addresses or IPv6 addresses, the latter bare or in brackets
+func Address(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+
+	return net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), defaultPort)
+}
+
+// SSHRunner runs scripts over SSH by piping them into sh
+type SSHRunner struct {
+	// Sudo runs the script through sudo, for users other than root
+	Sudo bool
+}
+
+func (s *SSHRunner) Run(ctx context.Context, host string, credentials *Credentials, script string) (string, error) {
+	config, err := clientConfig(credentials)
+	if err != nil {
+		return "", err
+	}
+	host = Address(host)
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return "", err
+	}
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, host, config)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	client := ssh.NewClient(sshConn, channels, requests)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	var output bytes.Buffer
+	session.Stdin = strings.NewReader(script)
+	session.Stdout = &output
+	session.Stderr = &output
+	command := "sh -s"
+	if s.Sudo {
+		command = "sudo -n sh -s"
+	}
+	err = session.Run(command)
+
+	return strings.TrimSpace(output.String()), err
+}
+
+func clientConfig(credentials *Credentials) (*ssh.ClientConfig, error) {
+	config := &ssh.ClientConfig{
+		User:    credentials.Username,
+		Timeout: dialTimeout,
+	}
+
+	if len(credentials.PrivateKey) > 0 {
+		signer, err := ssh.ParsePrivateKey(credentials.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key: %w", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+	}
+	if credentials.Password != "" {
+		config.Auth = append(config.Auth, ssh.Password(credentials.Password))
+	}
+	if len(config.Auth) == 0 {
+		return nil, fmt.Errorf("credentials have neither a private key nor a password")
+	}
+
+	switch {
+	case len(credentials.KnownHosts) > 0:
+		callback, err := knownHostsCallback(credentials.KnownHosts)
+		if err != nil {
+			return nil, err
+		}
+		config.HostKeyCallback = callback
+	case credentials.InsecureIgnoreHostKey:
+		config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, fmt.Errorf("credentials have no known hosts to verify host keys against")
+	}
+
+	return config, nil
+}
+
+// knownHostsCallback checks host keys against known_hosts content, the knownhosts package only reads files
+func knownHostsCallback(knownHosts []byte) (ssh.HostKeyCallback, error) {
+	type entry struct {
+		hosts []string
+		key   ssh.PublicKey
+	}
+	entries := make([]entry, 0)
+	rest := knownHosts
+	for len(rest) > 0 {
+		_, hosts, key, _, next, err := ssh.ParseKnownHosts(rest)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing known hosts: %w", err)
+		}
+		entries = append(entries, entry{hosts: hosts, key: key})
+		rest = next
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		normalized := knownhosts.Normalize(hostname)
+		for _, e := range entries {
+			for _, host := range e.hosts {
+				if knownhosts.Normalize(host) == normalized && bytes.Equal(e.key.Marshal(), key.Marshal()) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("host key of %s is not in the known hosts", hostname)
+	}, nil
+}
+
+// ProfileScript builds the script applying a PowerProfile to the CPUs through cpufreq, or to every CPU when
+// cpus is empty. Frequencies are given in MHz as in the PowerProfile. Only the governor, min and max
+// frequency and EPP are applied
+func ProfileScript(profile *powerv1.PowerProfileSpec, cpus string) (string, error) {
+	if profile.MaxPreset != "" && profile.Max == 0 {
+		return "", fmt.Errorf("max frequency presets are not supported without the Node Agent")
+	}
+
+	cpuGlob := "/sys/devices/system/cpu/cpu[0-9]*"
+	if cpus != "" {
+		set, err := cpuset.Parse(cpus)
+		if err != nil {
+			return "", fmt.Errorf("malformed CPU list '%s': %w", cpus, err)
+		}
+		paths := make([]string, 0, set.Size())
+		for _, cpu := range set.ToSlice() {
+			paths = append(paths, fmt.Sprintf("/sys/devices/system/cpu/cpu%d", cpu))
+		}
+		cpuGlob = strings.Join(paths, " ")
+	}
+
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	fmt.Fprintf(&script, "for cpu in %s; do\n", cpuGlob)
+	script.WriteString("\tfreq=$cpu/cpufreq\n")
+	script.WriteString("\t[ -d $freq ] || continue\n")
+	if profile.Governor != "" {
+		fmt.Fprintf(&script, "\techo %s > $freq/scaling_governor\n", shellQuote(profile.Governor))
+	}
+	if profile.Max > 0 || profile.Min > 0 {
+		// widen the range first so the new min and max are never rejected for crossing the old ones
+		script.WriteString("\tcat $freq/cpuinfo_max_freq > $freq/scaling_max_freq\n")
+		script.WriteString("\tcat $freq/cpuinfo_min_freq > $freq/scaling_min_freq\n")
+	}
+	if profile.Min > 0 {
+		fmt.Fprintf(&script, "\techo %d > $freq/scaling_min_freq\n", profile.Min*1000)
+	}
+	if profile.Max > 0 {
+		fmt.Fprintf(&script, "\techo %d > $freq/scaling_max_freq\n", profile.Max*1000)
+	}
+	// the EPP can't be changed while the performance governor is in use
+	if profile.Epp != "" && profile.Governor != "performance" {
+		script.WriteString("\tif [ -f $freq/energy_performance_preference ]; then\n")
+		fmt.Fprintf(&script, "\t\techo %s > $freq/energy_performance_preference\n", shellQuote(profile.Epp))
+		script.WriteString("\tfi\n")
+	}
+	script.WriteString("done\n")
+
+	return script.String(), nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,144 @@
+// Package agentpods keeps the Node Agent Pod of every Node from the manager's Pod informer, so finding a Node's
+// agent is a map lookup rather than a scan of every Pod in the cluster
+package agentpods
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// Endpoint is the running Node Agent Pod of a Node
+type Endpoint struct {
+	Pod string
+	IP  string
+}
+
+// Registry holds the Endpoint of each Node, keyed by Node name. It's kept up to date by the Pod events it's given
+// as an informer's event handler
+type Registry struct {
+	// Namespace and Selector find the Node Agent Pods
+	Namespace string
+	Selector  labels.Selector
+
+	mutex     sync.RWMutex
+	endpoints map[string]Endpoint
+	changes   chan struct{}
+}
+
+func NewRegistry(namespace string, selector labels.Selector) *Registry {
+	return &Registry{
+		Namespace: namespace,
+		Selector:  selector,
+		endpoints: make(map[string]Endpoint),
+		changes:   make(chan struct{}, 1),
+	}
+}
+
+// Register adds the Registry to the Pod informer of the cache, the Pods already synced are replayed to it
+func (r *Registry) Register(ctx context.Context, c cache.Cache) error {
+	informer, err := c.GetInformer(ctx, &corev1.Pod{})
+	if err != nil {
+		return fmt.Errorf("getting the Pod informer: %w", err)
+	}
+	_, err = informer.AddEventHandler(r)
+	if err != nil {
+		return fmt.Errorf("adding the Pod event handler: %w", err)
+	}
+
+	return nil
+}
+
+// Lookup returns the Endpoint of a Node's agent, false while the Node has no running agent
+func (r *Registry) Lookup(node string) (Endpoint, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	endpoint, exists := r.endpoints[node]
+
+	return endpoint, exists
+}
+
+// Endpoints returns a copy of every Node's Endpoint
+func (r *Registry) Endpoints() map[string]Endpoint {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	endpoints := make(map[string]Endpoint, len(r.endpoints))
+	for node, endpoint := range r.endpoints {
+		endpoints[node] = endpoint
+	}
+
+	return endpoints
+}
+
+// Changes receives after any Endpoint is added, moved or removed. Changes in a burst are coalesced
+func (r *Registry) Changes() <-chan struct{} {
+	return r.changes
+}
+
+func (r *Registry) OnAdd(obj interface{}) {
+	r.update(obj)
+}
+
+func (r *Registry) OnUpdate(_, obj interface{}) {
+	r.update(obj)
+}
+
+func (r *Registry) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || !r.selects(pod) {
+		return
+	}
+	r.remove(pod)
+}
+
+func (r *Registry) update(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || !r.selects(pod) || pod.Spec.NodeName == "" {
+		return
+	}
+	if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+		r.remove(pod)
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	endpoint := Endpoint{Pod: pod.Name, IP: pod.Status.PodIP}
+	if r.endpoints[pod.Spec.NodeName] == endpoint {
+		return
+	}
+	r.endpoints[pod.Spec.NodeName] = endpoint
+	r.changed()
+}
+
+// remove drops the Node's Endpoint only if it's still the Pod's, a replacement agent that's already running
+// isn't lost to the old Pod going away after it
+func (r *Registry) remove(pod *corev1.Pod) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	endpoint, exists := r.endpoints[pod.Spec.NodeName]
+	if !exists || endpoint.Pod != pod.Name {
+		return
+	}
+	delete(r.endpoints, pod.Spec.NodeName)
+	r.changed()
+}
+
+func (r *Registry) changed() {
+	select {
+	case r.changes <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Registry) selects(pod *corev1.Pod) bool {
+	return pod.Namespace == r.Namespace && r.Selector.Matches(labels.Set(pod.Labels))
+}
@@ -0,0 +1,170 @@
+package bios
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	// MsrFile is read for the HWP and turbo enable bits the BIOS leaves behind
+	MsrFile = "/dev/cpu/0/msr"
+	// CpuInfoFile lists the CPU flags, used for HWP when the MSRs can't be read
+	CpuInfoFile = "/proc/cpuinfo"
+	// NoTurboFile is 1 when intel_pstate found turbo disabled, used when the MSRs can't be read
+	NoTurboFile = "/sys/devices/system/cpu/intel_pstate/no_turbo"
+	// DmiPath holds the BIOS vendor and version the kernel read from the SMBIOS tables
+	DmiPath = "/sys/class/dmi/id"
+)
+
+const (
+	Enabled  = "Enabled"
+	Disabled = "Disabled"
+
+	pmEnableMsr     = 0x770
+	miscEnableMsr   = 0x1a0
+	turboDisableBit = 1 << 38
+)
+
+// PowerProfileAttributes are the Redfish BIOS attributes vendors use for the platform's power profile,
+// in the order they are looked for
+var PowerProfileAttributes = []string{
+	"SysProfile",
+	"WorkloadProfile",
+	"PowerRegulator",
+	"OperatingModes_ChooseOperatingMode",
+	"PowerPerformanceTuning",
+}
+
+// Settings are the BIOS settings that decide how much of power management the operator is left with,
+// a setting that couldn't be read is empty
+type Settings struct {
+	Version      string
+	HWP          string
+	Turbo        string
+	PowerProfile string
+}
+
+// ReadLocal reads what the Node itself shows of the BIOS settings: HWP and turbo from the MSRs, or the
+// CPU flags and intel_pstate when the MSRs can't be read, and the BIOS version from the SMBIOS tables
+func ReadLocal() Settings {
+	settings := Settings{
+		Version: readVersion(),
+	}
+
+	msr, err := os.Open(MsrFile)
+	if err == nil {
+		defer msr.Close()
+		if pmEnable, err := readMsr(msr, pmEnableMsr); err == nil {
+			settings.HWP = enabled(pmEnable&1 == 1)
+		}
+		if miscEnable, err := readMsr(msr, miscEnableMsr); err == nil {
+			settings.Turbo = enabled(miscEnable&turboDisableBit == 0)
+		}
+	}
+	if settings.HWP == "" {
+		if hwp, err := hasCpuFlag("hwp"); err == nil {
+			settings.HWP = enabled(hwp)
+		}
+	}
+	if settings.Turbo == "" {
+		if noTurbo, err := os.ReadFile(NoTurboFile); err == nil {
+			settings.Turbo = enabled(strings.TrimSpace(string(noTurbo)) == "0")
+		}
+	}
+
+	return settings
+}
+
+// PowerProfileFromAttributes returns the power profile among the Redfish BIOS attributes as name=value,
+// empty if the BIOS has none of the known attributes
+func PowerProfileFromAttributes(attributes map[string]interface{}) string {
+	for _, name := range PowerProfileAttributes {
+		if value, exists := attributes[name]; exists {
+			return fmt.Sprintf("%s=%v", name, value)
+		}
+	}
+
+	return ""
+}
+
+// Advisories explain the settings that keep PowerProfiles from taking full effect
+func Advisories(settings Settings) []string {
+	advisories := make([]string, 0)
+	if settings.HWP == Disabled {
+		advisories = append(advisories, "HWP is disabled in the BIOS, the EPP values of PowerProfiles have no effect")
+	}
+	if settings.Turbo == Disabled {
+		advisories = append(advisories, "Turbo is disabled in the BIOS, PowerProfiles can't go above the base frequency")
+	}
+	if settings.PowerProfile != "" && !osControlled(settings.PowerProfile) {
+		advisories = append(advisories, fmt.Sprintf("the BIOS power profile %s may override PowerProfiles, "+
+			"set it to an OS controlled or custom profile", settings.PowerProfile))
+	}
+	sort.Strings(advisories)
+
+	return advisories
+}
+
+// osControlled reports whether the power profile leaves frequency control to the OS, vendors name those
+// profiles after the OS, e.g. PerfPerWattOptimizedOs or OsControl, or let every setting be customized
+func osControlled(profile string) bool {
+	_, value, _ := strings.Cut(profile, "=")
+	value = strings.ToLower(value)
+	return strings.Contains(value, "os") || strings.Contains(value, "custom")
+}
+
+func enabled(on bool) string {
+	if on {
+		return Enabled
+	}
+	return Disabled
+}
+
+func readVersion() string {
+	vendor, vendorErr := os.ReadFile(DmiPath + "/bios_vendor")
+	version, versionErr := os.ReadFile(DmiPath + "/bios_version")
+	if vendorErr != nil || versionErr != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(vendor)) + " " + strings.TrimSpace(string(version))
+}
+
+func hasCpuFlag(flag string) (bool, error) {
+	file, err := os.Open(CpuInfoFile)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(key) != "flags" {
+			continue
+		}
+		// every CPU has the same flags
+		for _, cpuFlag := range strings.Fields(value) {
+			if cpuFlag == flag {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return false, scanner.Err()
+}
+
+func readMsr(file *os.File, msr int64) (uint64, error) {
+	buf := make([]byte, 8)
+	_, err := file.ReadAt(buf, msr)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(buf), nil
+}
@@ -0,0 +1,134 @@
+// Package capacity works out how many of each PowerProfile's Extended Resources a Node advertises, and how many more
+// Pods of a shape fit in what's left. It only reads what it's given and holds no state, so the Node Agent's
+// reconciles can call it concurrently
+package capacity
+
+import (
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+)
+
+// defaultPercents is the share of a Node's CPUs advertised for a PowerProfile without a capacity, by EPP value
+var defaultPercents = map[string]int{
+	"performance":         40,
+	"balance_performance": 60,
+	"balance_power":       80,
+	"power":               100,
+}
+
+// Node is the Node's side of the capacity
+type Node struct {
+	// NumCPUs is how many CPUs the Node has
+	NumCPUs int
+	// DomainShares is the Node's share of its failure domain's cap of each capped PowerProfile
+	DomainShares map[string]int
+	// Facts are read by the capacity expressions of the PowerProfiles
+	Facts Facts
+}
+
+// Reservation is what a PowerProfile withholds of its capacity on the Node
+type Reservation struct {
+	// HeadroomLent advertises the profile's burst headroom rather than holding it back
+	HeadroomLent bool
+	// WithheldPercent is taken off for the active DemandResponses or the Node's throttle demotion, rounded up
+	WithheldPercent int
+}
+
+// Quantity is how many of the PowerProfile's Extended Resources the Node advertises once the reservation and the
+// failure domain's cap are taken off. A capacity expression that can't be evaluated on the
+// Node is an InvalidProfileError
+func Quantity(profile *powerv1.PowerProfile, node Node, reservation Reservation) (int64, error) {
+	quantity := Base(profile, node.NumCPUs)
+	if profile.Spec.Capacity != nil && profile.Spec.Capacity.Expression != "" {
+		var err error
+		quantity, err = Evaluate(profile.Spec.Capacity.Expression, node.NumCPUs, node.Facts)
+		if err != nil {
+			return 0, powererrors.NewInvalidProfile(profile.Spec.Name, "capacity expression: %v", err)
+		}
+	}
+	if !reservation.HeadroomLent {
+		quantity -= Headroom(profile, quantity)
+	}
+	// rounded up to meet the commitment, Pods already holding the withheld capacity keep it and only new ones
+	// are turned away
+	quantity -= (quantity*int64(reservation.WithheldPercent) + 99) / 100
+	if share, capped := node.DomainShares[profile.Spec.Name]; capped && quantity > int64(share) {
+		quantity = int64(share)
+	}
+
+	return quantity, nil
+}
+
+// Base is how many CPUs of the PowerProfile a Node with numCPUs has before anything is withheld, taken from the
+// profile's count or percent or, if it has neither, the default for its EPP value. A capacity expression
+// replaces it where the Node's facts are known
+func Base(profile *powerv1.PowerProfile, numCPUs int) int64 {
+	capacity := profile.Spec.Capacity
+	if capacity != nil && capacity.Count > 0 {
+		if capacity.Count > numCPUs {
+			return int64(numCPUs)
+		}
+		return int64(capacity.Count)
+	}
+
+	percent := defaultPercents[profile.Spec.Epp]
+	if capacity != nil && capacity.Percent > 0 {
+		percent = capacity.Percent
+	}
+
+	return int64(numCPUs * percent / 100)
+}
+
+// Headroom is how many of the capacity's CPUs the PowerProfile holds back as burst headroom
+func Headroom(profile *powerv1.PowerProfile, capacity int64) int64 {
+	if profile.Spec.Capacity == nil || profile.Spec.Capacity.HeadroomPercent <= 0 {
+		return 0
+	}
+
+	return capacity * int64(profile.Spec.Capacity.HeadroomPercent) / 100
+}
+
+// Shape is what each Pod of a workload being planned for requests
+type Shape struct {
+	// Profile is the PowerProfile the Pods request
+	Profile string
+	// Quantity is how many of the profile's Extended Resources each Pod requests
+	Quantity int64
+	// CPUMillis is each Pod's CPU request
+	CPUMillis int64
+}
+
+// Free is what's left of a Node's capacity once its Pods' requests are taken off
+type Free struct {
+	// Quantity is the PowerProfile's Extended Resources not requested yet
+	Quantity int64
+	// CPUMillis is the allocatable CPU not requested yet
+	CPUMillis int64
+}
+
+// Limit names what stops more Pods of a shape fitting on a Node
+const (
+	LimitProfile = "profile"
+	LimitCPU     = "cpu"
+)
+
+// Fit is how many more Pods of the shape fit in what's free on a Node, and which of the PowerProfile's capacity and
+// the allocatable CPU runs out first
+func Fit(shape Shape, free Free) (int64, string) {
+	if shape.Quantity <= 0 || shape.CPUMillis <= 0 {
+		return 0, LimitProfile
+	}
+	byProfile := free.Quantity / shape.Quantity
+	byCPU := free.CPUMillis / shape.CPUMillis
+	if byProfile < 0 {
+		byProfile = 0
+	}
+	if byCPU < 0 {
+		byCPU = 0
+	}
+	if byCPU < byProfile {
+		return byCPU, LimitCPU
+	}
+
+	return byProfile, LimitProfile
+}
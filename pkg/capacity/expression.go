@@ -0,0 +1,81 @@
+package capacity
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// expressionCostLimit bounds how much work evaluating a capacity expression can take, far more than any
+// arithmetic over the Node's facts needs
+const expressionCostLimit = 10000
+
+// Facts are what a capacity expression can read of the Node: cores, sockets, cpuModel and labels
+type Facts struct {
+	// Sockets is how many CPU packages the Node has
+	Sockets int
+	// CPUModel is the model name the Node's CPUs report, which includes the SKU
+	CPUModel string
+	// Labels are the Node's labels
+	Labels map[string]string
+}
+
+func expressionEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("cores", cel.IntType),
+		cel.Variable("sockets", cel.IntType),
+		cel.Variable("cpuModel", cel.StringType),
+		cel.Variable("labels", cel.MapType(cel.StringType, cel.StringType)),
+	)
+}
+
+// CompileExpression checks the capacity expression is valid CEL giving an integer
+func CompileExpression(expression string) (cel.Program, error) {
+	env, err := expressionEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if !cel.IntType.IsAssignableType(ast.OutputType()) {
+		return nil, fmt.Errorf("gives %s rather than an int", ast.OutputType())
+	}
+
+	return env.Program(ast, cel.CostLimit(expressionCostLimit))
+}
+
+// Evaluate is how many CPUs the capacity expression gives a Node with numCPUs and the facts, at most numCPUs and
+// at least none
+func Evaluate(expression string, numCPUs int, facts Facts) (int64, error) {
+	program, err := CompileExpression(expression)
+	if err != nil {
+		return 0, err
+	}
+	labels := facts.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	value, _, err := program.Eval(map[string]interface{}{
+		"cores":    numCPUs,
+		"sockets":  facts.Sockets,
+		"cpuModel": facts.CPUModel,
+		"labels":   labels,
+	})
+	if err != nil {
+		return 0, err
+	}
+	quantity, ok := value.Value().(int64)
+	if !ok {
+		return 0, fmt.Errorf("gave %v rather than an int", value.Value())
+	}
+	if quantity > int64(numCPUs) {
+		return int64(numCPUs), nil
+	}
+	if quantity < 0 {
+		return 0, nil
+	}
+
+	return quantity, nil
+}
@@ -0,0 +1,115 @@
+package cgroup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+)
+
+const CgroupPath = "/sys/fs/cgroup"
+
+// errFound stops the walk once the container's cgroup is found
+var errFound = errors.New("found")
+
+// Reader reads the cpuset of containers from the cgroup filesystem. It finds the container's cgroup by its ID
+// under the kubepods hierarchy, so works with both the cgroupfs and systemd drivers and cgroup v1 and v2
+type Reader struct {
+	CgroupPath string
+}
+
+func NewReader() *Reader {
+	return &Reader{CgroupPath: CgroupPath}
+}
+
+// ContainerCPUs returns the CPUs the container's cgroup cpuset lets it run on. The container ID may carry the
+// runtime prefix of the Pod status, such as containerd://
+func (r *Reader) ContainerCPUs(podUID string, containerID string) ([]uint, error) {
+	if _, id, found := strings.Cut(containerID, "://"); found {
+		containerID = id
+	}
+	if containerID == "" {
+		return nil, fmt.Errorf("container has no ID yet")
+	}
+	// the systemd driver writes the Pod UID with underscores
+	podUIDs := []string{podUID, strings.ReplaceAll(podUID, "-", "_")}
+
+	// cgroup v2 has a single hierarchy, v1 keeps the cpuset controller in its own
+	for _, root := range []string{r.CgroupPath, filepath.Join(r.CgroupPath, "cpuset")} {
+		path, err := findContainer(root, podUIDs, containerID)
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			continue
+		}
+		for _, file := range []string{"cpuset.cpus.effective", "cpuset.cpus"} {
+			data, err := os.ReadFile(filepath.Join(path, file))
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			cpus, err := cpuset.Parse(strings.TrimSpace(string(data)))
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", filepath.Join(path, file), err)
+			}
+			ids := make([]uint, 0, cpus.Size())
+			for _, cpu := range cpus.ToSlice() {
+				ids = append(ids, uint(cpu))
+			}
+			return ids, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no cgroup found for container %s", containerID)
+}
+
+// findContainer walks the kubepods hierarchy under root for the directory of the container in the Pod, returning
+// an empty path if there is none
+func findContainer(root string, podUIDs []string, containerID string) (string, error) {
+	var found string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		relative, _ := filepath.Rel(root, path)
+		if !strings.Contains(relative, "kubepods") {
+			return filepath.SkipDir
+		}
+		if strings.Contains(entry.Name(), containerID) && inPod(relative, podUIDs) {
+			found = path
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return "", err
+	}
+
+	return found, nil
+}
+
+func inPod(path string, podUIDs []string) bool {
+	for _, uid := range podUIDs {
+		if uid != "" && strings.Contains(path, uid) {
+			return true
+		}
+	}
+
+	return false
+}
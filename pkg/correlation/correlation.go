@@ -0,0 +1,36 @@
+// Package correlation ties what the manager and the Node Agents log, record and send for one change of a Power CR
+// to a single ID, so a change that failed on a Node can be traced through every component from that ID. The
+// components don't call each other but act on the same objects, so the ID is taken from the object changed: the
+// correlation-id annotation when the client making the change set one, otherwise its UID and generation, which
+// every component sees alike
+package correlation
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Annotation on a Power CR gives the change the ID of the request that made it, such as a CI run or ticket.
+	// It is kept until changed, so it should be updated with every change it is meant to trace
+	Annotation = "power.intel.com/correlation-id"
+	// Header carries the ID on the HTTP requests sent for a change
+	Header = "X-Correlation-ID"
+	// LogKey is the key the ID is logged under
+	LogKey = "correlationID"
+)
+
+// ID returns the correlation ID of the object's current generation
+func ID(obj metav1.Object) string {
+	if id := obj.GetAnnotations()[Annotation]; id != "" {
+		return id
+	}
+
+	return fmt.Sprintf("%s-%d", obj.GetUID(), obj.GetGeneration())
+}
+
+// EventAnnotations are added to the events recorded for a change, so they can be found by the ID
+func EventAnnotations(id string) map[string]string {
+	return map[string]string{Annotation: id}
+}
@@ -0,0 +1,166 @@
+package cpudefaults
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+)
+
+// CpuInfoFile is read for the family and model of the Node's CPUs
+var CpuInfoFile = "/proc/cpuinfo"
+
+// Auto is the max frequency preset that takes a PowerProfile's frequencies from the defaults
+const Auto = "auto"
+
+// DefaultChannel only holds defaults for CPU families that have been validated across releases,
+// other channels may add newer families
+const DefaultChannel = "stable"
+
+//go:embed channels/*.json
+var channels embed.FS
+
+// Frequencies are the recommended settings for one EPP value on a CPU model, in MHz
+type Frequencies struct {
+	Max int `json:"max"`
+	Min int `json:"min"`
+	// Epp is only set on the entry for profiles without an EPP value, and is the one they are given
+	Epp string `json:"epp,omitempty"`
+}
+
+// Model holds the defaults of one CPU family and model
+type Model struct {
+	Family int    `json:"family"`
+	Model  int    `json:"model"`
+	Name   string `json:"name"`
+	// Profiles are keyed by EPP value, the empty key applies to profiles without one
+	Profiles map[string]Frequencies `json:"profiles"`
+}
+
+// Database is the set of defaults of one release channel
+type Database struct {
+	Channel string  `json:"channel"`
+	Models  []Model `json:"models"`
+}
+
+// Channels lists the release channels embedded in the binary
+func Channels() []string {
+	entries, err := channels.ReadDir("channels")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Load reads the defaults of the release channel
+func Load(channel string) (*Database, error) {
+	content, err := channels.ReadFile(path.Join("channels", channel+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown CPU defaults channel '%s', available channels are %v", channel, Channels())
+	}
+
+	database := &Database{}
+	err = json.Unmarshal(content, database)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CPU defaults channel '%s': %w", channel, err)
+	}
+
+	return database, nil
+}
+
+// Lookup returns the defaults of the CPU family and model, if the channel has any
+func (d *Database) Lookup(family int, model int) (*Model, bool) {
+	for i := range d.Models {
+		if d.Models[i].Family == family && d.Models[i].Model == model {
+			return &d.Models[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Local returns the defaults of the Node's CPUs, a model the channel has no defaults for is a
+// HardwareUnsupportedError
+func (d *Database) Local() (*Model, error) {
+	family, model, err := ReadCPUModel()
+	if err != nil {
+		return nil, powererrors.NewHardwareUnsupported("CPU model", err)
+	}
+	defaults, exists := d.Lookup(family, model)
+	if !exists {
+		return nil, powererrors.NewHardwareUnsupported("CPU defaults",
+			fmt.Errorf("the %s channel has no defaults for CPU family %d model %d", d.Channel, family, model))
+	}
+
+	return defaults, nil
+}
+
+// ReadCPUModel reads the family and model of the first CPU, every CPU of a Node has the same
+func ReadCPUModel() (int, int, error) {
+	file, err := os.Open(CpuInfoFile)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	family, model := -1, -1
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() && (family < 0 || model < 0) {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "cpu family":
+			family, err = strconv.Atoi(strings.TrimSpace(value))
+		case "model":
+			model, err = strconv.Atoi(strings.TrimSpace(value))
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("error parsing %s: %w", CpuInfoFile, err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	if family < 0 || model < 0 {
+		return 0, 0, fmt.Errorf("no CPU family and model in %s", CpuInfoFile)
+	}
+
+	return family, model, nil
+}
+
+// ReadCPUModelName reads the model name of the first CPU, such as "Intel(R) Xeon(R) Platinum 8480+"
+func ReadCPUModelName() (string, error) {
+	file, err := os.Open(CpuInfoFile)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if found && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("no CPU model name in %s", CpuInfoFile)
+}
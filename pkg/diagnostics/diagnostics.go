@@ -0,0 +1,82 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/intel/kubernetes-power-manager/pkg/util"
+)
+
+// StateDumper returns a snapshot of some internal state that can be encoded as JSON
+type StateDumper func() interface{}
+
+// Server serves pprof profiles, goroutine dumps and internal state dumps on a unix socket.
+// It is opt-in and only reachable from inside the Pod or by root on the host
+type Server struct {
+	Endpoint string
+	Log      logr.Logger
+	Dumpers  map[string]StateDumper
+}
+
+// Start serves the debug endpoints until the context is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := util.CreateListener(s.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// full stack of every goroutine, the quickest way to find a deadlock
+	mux.Handle("/debug/goroutines", http.RedirectHandler("/debug/pprof/goroutine?debug=2", http.StatusFound))
+	for name, dumper := range s.Dumpers {
+		mux.HandleFunc("/debug/state/"+name, dumpHandler(dumper))
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	s.Log.Info("serving debug endpoints", "endpoint", s.Endpoint)
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// NeedLeaderElection is false as the debug server has to run in every replica
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func dumpHandler(dumper StateDumper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(dumper())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package drift
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+)
+
+// State sums up the power state of the whole cluster: a checksum of what the Power CRs ask of every PowerNode, a
+// checksum of what the Node Agents report, and whether the two have converged. The checksums are of different
+// things and don't match when converged, each only changes when its side of the state does
+type State struct {
+	DesiredChecksum  string
+	ObservedChecksum string
+	// Converged is whether no PowerNode has a setting drifted or still being applied
+	Converged bool
+}
+
+// ComputeState lists the Power CRs in the namespace and returns the state of all their PowerNodes
+func ComputeState(ctx context.Context, c client.Reader, namespace string) (State, error) {
+	nodes, profiles, workloads, err := listPowerCRs(ctx, c, namespace)
+	if err != nil {
+		return State{}, err
+	}
+
+	desired := make([]string, 0)
+	observed := make([]string, 0)
+	converged := true
+	for i := range nodes.Items {
+		powerNode := &nodes.Items[i]
+		desired = append(desired, desiredEntries(powerNode, profiles.Items, workloads.Items)...)
+		observed = append(observed, observedEntries(powerNode)...)
+		if len(nodeDrift(powerNode, profiles.Items, workloads.Items)) > 0 {
+			converged = false
+		}
+	}
+
+	return State{
+		DesiredChecksum:  checksum(desired),
+		ObservedChecksum: checksum(observed),
+		Converged:        converged,
+	}, nil
+}
+
+// desiredEntries are the PowerProfiles, with the Node's overrides, and the PowerWorkloads the Power CRs ask of the
+// Node, one "node/kind/name: settings" entry each
+func desiredEntries(powerNode *powerv1.PowerNode, profiles []powerv1.PowerProfile, workloads []powerv1.PowerWorkload) []string {
+	entries := make([]string, 0, len(profiles))
+	for i := range profiles {
+		profile := effectiveProfile(powerNode, &profiles[i])
+		maxFrequency := strconv.Itoa(profile.Spec.Max)
+		if profile.Spec.MaxPreset != "" {
+			maxFrequency = profile.Spec.MaxPreset
+		}
+		entries = append(entries, fmt.Sprintf("%s/profile/%s: %s || %d || %s || %s || %t", powerNode.Name, profile.Spec.Name,
+			maxFrequency, profile.Spec.Min, profile.Spec.Epp, profile.Spec.Governor, profile.Spec.Realtime))
+	}
+	for i := range workloads {
+		workload := &workloads[i]
+		if workload.Spec.AllCores || workload.Spec.Node.Name != powerNode.Name {
+			continue
+		}
+		cpus := make([]int, 0, len(workload.Spec.Node.CpuIds))
+		for _, cpu := range workload.Spec.Node.CpuIds {
+			cpus = append(cpus, int(cpu))
+		}
+		cores := cpuset.NewCPUSet(cpus...)
+		entries = append(entries, fmt.Sprintf("%s/workload/%s: %s || %s", powerNode.Name, workload.Name,
+			workload.Spec.PowerProfile, cores.String()))
+	}
+
+	return entries
+}
+
+// observedEntries are the PowerProfiles and pools the Node Agent reports in the PowerNode spec
+func observedEntries(powerNode *powerv1.PowerNode) []string {
+	entries := make([]string, 0, len(powerNode.Spec.PowerProfiles)+len(powerNode.Spec.PowerWorkloads)+1)
+	for _, entry := range powerNode.Spec.PowerProfiles {
+		entries = append(entries, powerNode.Name+"/profile/"+entry)
+	}
+	for _, entry := range powerNode.Spec.PowerWorkloads {
+		entries = append(entries, powerNode.Name+"/pool/"+entry)
+	}
+	if powerNode.Spec.SharedPool != "" {
+		entries = append(entries, powerNode.Name+"/sharedPool: "+powerNode.Spec.SharedPool)
+	}
+
+	return entries
+}
+
+// checksum is the SHA-256 of the entries in order, so neither the order the CRs are listed in nor the order a
+// Node Agent reports its pools in changes it
+func checksum(entries []string) string {
+	sorted := append([]string{}, entries...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,316 @@
+// Package drift compares the state the Power CRs ask for with the state each Node Agent reports in its
+// PowerNode, so settings that never made it to a Node, or only partly did, can be found across the fleet
+package drift
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+)
+
+const (
+	// StateDrift is a setting the Node doesn't have and isn't working towards
+	StateDrift = "drift"
+	// StatePartial is a setting the Node is still applying, such as a PowerWorkload being validated or in
+	// transition, or a core range the Node only has some of the cores of
+	StatePartial = "partial"
+
+	missing = "<missing>"
+)
+
+// Drift is a setting where the desired and the observed state of a Node differ
+type Drift struct {
+	Node    string `json:"node"`
+	Cores   string `json:"cores,omitempty"`
+	Setting string `json:"setting"`
+	Desired string `json:"desired"`
+	Actual  string `json:"actual"`
+	State   string `json:"state"`
+}
+
+// observedProfile is a PowerProfile as the Node Agent writes it in the PowerNode spec
+type observedProfile struct {
+	max string
+	min string
+	epp string
+}
+
+// observedPool is the PowerProfile and cores of a pool as the Node Agent writes it in the PowerNode spec
+type observedPool struct {
+	profile string
+	max     string
+	min     string
+	cores   string
+}
+
+// Compute lists the Power CRs in the namespace and returns the drift of every PowerNode, or only of the given
+// Node when node isn't empty, sorted by Node and setting
+func Compute(ctx context.Context, c client.Reader, namespace string, node string) ([]Drift, error) {
+	nodes, profiles, workloads, err := listPowerCRs(ctx, c, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := make([]Drift, 0)
+	for i := range nodes.Items {
+		if node != "" && nodes.Items[i].Name != node {
+			continue
+		}
+		drifts = append(drifts, nodeDrift(&nodes.Items[i], profiles.Items, workloads.Items)...)
+	}
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].Node != drifts[j].Node {
+			return drifts[i].Node < drifts[j].Node
+		}
+		return drifts[i].Setting < drifts[j].Setting
+	})
+
+	return drifts, nil
+}
+
+// listPowerCRs lists the PowerNodes, PowerProfiles and PowerWorkloads in the namespace
+func listPowerCRs(ctx context.Context, c client.Reader, namespace string) (*powerv1.PowerNodeList, *powerv1.PowerProfileList, *powerv1.PowerWorkloadList, error) {
+	nodes := &powerv1.PowerNodeList{}
+	profiles := &powerv1.PowerProfileList{}
+	workloads := &powerv1.PowerWorkloadList{}
+	for _, list := range []client.ObjectList{nodes, profiles, workloads} {
+		err := c.List(ctx, list, client.InNamespace(namespace))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("listing %T: %w", list, err)
+		}
+	}
+
+	return nodes, profiles, workloads, nil
+}
+
+func nodeDrift(powerNode *powerv1.PowerNode, profiles []powerv1.PowerProfile, workloads []powerv1.PowerWorkload) []Drift {
+	nodeName := powerNode.Name
+	drifts := make([]Drift, 0)
+	add := func(cores string, setting string, desired string, actual string, state string) {
+		drifts = append(drifts, Drift{Node: nodeName, Cores: cores, Setting: setting, Desired: desired, Actual: actual, State: state})
+	}
+
+	observedProfiles := parseProfiles(powerNode.Spec.PowerProfiles)
+	observedPools := parseWorkloads(powerNode.Spec.PowerWorkloads)
+	sharedPool, sharedObserved := parseSharedPool(powerNode.Spec.SharedPool)
+
+	sharedProfiles := make([]string, 0)
+	for i := range profiles {
+		profile := effectiveProfile(powerNode, &profiles[i])
+		if profile.Spec.Epp == "power" {
+			sharedProfiles = append(sharedProfiles, profile.Spec.Name)
+			if !sharedObserved || sharedPool.profile != profile.Spec.Name {
+				continue
+			}
+			maxFrequency := profile.Spec.Max
+			if powerNode.Status.SharedPoolStep > 0 {
+				// a step down lowers the max frequency on purpose
+				maxFrequency = powerNode.Status.SharedPoolMaxFrequency
+			}
+			if profile.Spec.MaxPreset == "" {
+				compareFrequency(add, sharedPool.cores, "sharedPool/max", maxFrequency, sharedPool.max)
+			}
+			compareFrequency(add, sharedPool.cores, "sharedPool/min", profile.Spec.Min, sharedPool.min)
+			continue
+		}
+
+		observed, found := observedProfiles[profile.Spec.Name]
+		if !found {
+			add("", "profile/"+profile.Spec.Name, "applied", missing, StateDrift)
+			continue
+		}
+		cores := observedPools[profile.Spec.Name].cores
+		// realtime profiles are pinned to a fixed frequency and presets are resolved by each Node, so there
+		// is no frequency in the CR to compare with
+		if !profile.Spec.Realtime {
+			if profile.Spec.MaxPreset == "" {
+				compareFrequency(add, cores, "profile/"+profile.Spec.Name+"/max", profile.Spec.Max, observed.max)
+			}
+			compareFrequency(add, cores, "profile/"+profile.Spec.Name+"/min", profile.Spec.Min, observed.min)
+		}
+		// the Power Library leaves the EPP empty when the Node doesn't take one
+		if profile.Spec.Epp != "" && observed.epp != "" && observed.epp != profile.Spec.Epp {
+			add(cores, "profile/"+profile.Spec.Name+"/epp", profile.Spec.Epp, observed.epp, StateDrift)
+		}
+	}
+
+	// only one shared profile can be applied, the Node has drifted when it has none of them
+	if len(sharedProfiles) > 0 {
+		if !sharedObserved {
+			add("", "sharedPool/profile", strings.Join(sharedProfiles, ","), missing, StateDrift)
+		} else if !contains(sharedProfiles, sharedPool.profile) {
+			add(sharedPool.cores, "sharedPool/profile", strings.Join(sharedProfiles, ","), sharedPool.profile, StateDrift)
+		}
+	}
+
+	for i := range workloads {
+		workload := &workloads[i]
+		if workload.Spec.AllCores || workload.Spec.Node.Name != nodeName {
+			continue
+		}
+		drifts = append(drifts, workloadDrift(nodeName, workload, observedPools)...)
+	}
+
+	return drifts
+}
+
+func workloadDrift(nodeName string, workload *powerv1.PowerWorkload, observedPools map[string]observedPool) []Drift {
+	drifts := make([]Drift, 0)
+	state := StateDrift
+	if workload.Status.Phase == powerv1.WorkloadPhaseValidating || workload.Status.Transition != nil {
+		state = StatePartial
+	}
+
+	desiredCPUs := make([]int, 0, len(workload.Spec.Node.CpuIds))
+	for _, cpu := range workload.Spec.Node.CpuIds {
+		desiredCPUs = append(desiredCPUs, int(cpu))
+	}
+	desired := cpuset.NewCPUSet(desiredCPUs...)
+	desiredCores := desired.String()
+	setting := "workload/" + workload.Name
+
+	if workload.Status.Phase == powerv1.WorkloadPhaseFailed {
+		drifts = append(drifts, Drift{Node: nodeName, Cores: desiredCores, Setting: setting + "/phase",
+			Desired: powerv1.WorkloadPhaseSucceeded, Actual: powerv1.WorkloadPhaseFailed, State: StateDrift})
+	}
+
+	observed, found := observedPools[workload.Spec.PowerProfile]
+	if !found {
+		if !desired.IsEmpty() {
+			drifts = append(drifts, Drift{Node: nodeName, Cores: desiredCores, Setting: setting + "/cores",
+				Desired: desiredCores, Actual: missing, State: state})
+		}
+		return drifts
+	}
+	actual, err := cpuset.Parse(observed.cores)
+	if err != nil {
+		drifts = append(drifts, Drift{Node: nodeName, Cores: desiredCores, Setting: setting + "/cores",
+			Desired: desiredCores, Actual: observed.cores, State: StateDrift})
+		return drifts
+	}
+	if !actual.Equals(desired) {
+		coresState := state
+		if !actual.IsEmpty() && actual.IsSubsetOf(desired) {
+			coresState = StatePartial
+		}
+		drifts = append(drifts, Drift{Node: nodeName, Cores: desiredCores, Setting: setting + "/cores",
+			Desired: desiredCores, Actual: actual.String(), State: coresState})
+	}
+
+	return drifts
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compareFrequency adds a drift when a frequency set in the CR isn't the one observed, unset frequencies are
+// worked out by each Node from its EPP and aren't compared
+func compareFrequency(add func(string, string, string, string, string), cores string, setting string, desired int, actual string) {
+	if desired == 0 || actual == strconv.Itoa(desired) {
+		return
+	}
+	add(cores, setting, strconv.Itoa(desired), actual, StateDrift)
+}
+
+// effectiveProfile applies the node group and Node overrides the PowerConfig Controller worked out for the Node
+func effectiveProfile(powerNode *powerv1.PowerNode, profile *powerv1.PowerProfile) *powerv1.PowerProfile {
+	profile = profile.DeepCopy()
+	for _, effective := range powerNode.Status.EffectiveProfiles {
+		if effective.Name != profile.Spec.Name || effective.Source == powerv1.EffectiveSourceCluster {
+			continue
+		}
+		if effective.Epp != "" {
+			profile.Spec.Epp = effective.Epp
+		}
+		if effective.Max != 0 {
+			profile.Spec.Max = effective.Max
+		}
+		if effective.Min != 0 {
+			profile.Spec.Min = effective.Min
+		}
+	}
+
+	return profile
+}
+
+// parseProfiles reads the "name: max || min || epp" entries of the PowerNode spec
+func parseProfiles(entries []string) map[string]observedProfile {
+	profiles := make(map[string]observedProfile, len(entries))
+	for _, entry := range entries {
+		name, rest, found := strings.Cut(entry, ": ")
+		if !found {
+			continue
+		}
+		fields := strings.Split(rest, " || ")
+		if len(fields) != 3 {
+			continue
+		}
+		profiles[name] = observedProfile{max: fields[0], min: fields[1], epp: fields[2]}
+	}
+
+	return profiles
+}
+
+// parseWorkloads reads the "pool: profile || cores" entries of the PowerNode spec
+func parseWorkloads(entries []string) map[string]observedPool {
+	pools := make(map[string]observedPool, len(entries))
+	for _, entry := range entries {
+		name, rest, found := strings.Cut(entry, ": ")
+		if !found {
+			continue
+		}
+		fields := strings.Split(rest, " || ")
+		if len(fields) != 2 {
+			continue
+		}
+		pools[name] = observedPool{profile: fields[0], cores: fields[1]}
+	}
+
+	return pools
+}
+
+// parseSharedPool reads the "profile || max || min || cores" Shared pool of the PowerNode spec
+func parseSharedPool(entry string) (observedPool, bool) {
+	fields := strings.Split(entry, " || ")
+	if len(fields) != 4 {
+		return observedPool{}, false
+	}
+
+	return observedPool{profile: fields[0], max: fields[1], min: fields[2], cores: fields[3]}, true
+}
+
+// Print writes the drifts as a table, or a line saying there are none
+func Print(w io.Writer, drifts []Drift) error {
+	if len(drifts) == 0 {
+		_, err := fmt.Fprintln(w, "No drift between the Power CRs and the PowerNodes")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tCORES\tSETTING\tDESIRED\tACTUAL\tSTATE")
+	for _, drift := range drifts {
+		cores := drift.Cores
+		if cores == "" {
+			cores = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", drift.Node, cores, drift.Setting, drift.Desired, drift.Actual, drift.State)
+	}
+
+	return tw.Flush()
+}
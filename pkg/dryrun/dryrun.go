@@ -0,0 +1,70 @@
+// Package dryrun lets the manager's controllers be switched into dry running their changes while they run: every
+// write is still sent to the API server, so it is validated and admitted, but as a dry run that isn't persisted
+package dryrun
+
+import (
+	"context"
+	"sync/atomic"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client sends its writes as dry runs while dry running is switched on, reads always go to the wrapped client
+type Client struct {
+	client.Client
+
+	dryRunClient client.Client
+	enabled      atomic.Bool
+}
+
+func NewClient(c client.Client) *Client {
+	return &Client{
+		Client:       c,
+		dryRunClient: client.NewDryRunClient(c),
+	}
+}
+
+// SetEnabled switches dry running on or off for every write made after it
+func (c *Client) SetEnabled(enabled bool) {
+	c.enabled.Store(enabled)
+}
+
+func (c *Client) Enabled() bool {
+	return c.enabled.Load()
+}
+
+func (c *Client) writer() client.Client {
+	if c.enabled.Load() {
+		return c.dryRunClient
+	}
+
+	return c.Client
+}
+
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return c.writer().Create(ctx, obj, opts...)
+}
+
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.writer().Update(ctx, obj, opts...)
+}
+
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.writer().Patch(ctx, obj, patch, opts...)
+}
+
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return c.writer().Delete(ctx, obj, opts...)
+}
+
+func (c *Client) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	return c.writer().DeleteAllOf(ctx, obj, opts...)
+}
+
+func (c *Client) Status() client.SubResourceWriter {
+	return c.writer().Status()
+}
+
+func (c *Client) SubResource(subResource string) client.SubResourceClient {
+	return c.writer().SubResource(subResource)
+}
@@ -0,0 +1,133 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Action is what a controller does with an error it got while reconciling
+type Action int
+
+const (
+	// Retry returns the error so the request is requeued with backoff
+	Retry Action = iota
+	// FailFast logs the error and drops the request, reconciling again can't succeed until the object changes
+	FailFast
+	// SurfaceToStatus drops the request once the caller has recorded the error in the object's status, as a
+	// condition or message, since retrying on the same Node can't succeed
+	SurfaceToStatus
+)
+
+// TransientAgentError is a failure of the Node Agent or the API server that is expected to clear up on a retry
+type TransientAgentError struct {
+	Err error
+}
+
+func (e *TransientAgentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransientAgentError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidProfileError is a PowerProfile, or a request for one, that can't be applied as it is
+type InvalidProfileError struct {
+	Profile string
+	Reason  string
+}
+
+func (e *InvalidProfileError) Error() string {
+	if e.Profile == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("invalid PowerProfile '%s': %s", e.Profile, e.Reason)
+}
+
+// ConflictError is an object that clashes with another one already in place, such as a second PowerConfig
+type ConflictError struct {
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Reason
+}
+
+// HardwareUnsupportedError is a feature the Node's platform or kernel doesn't provide
+type HardwareUnsupportedError struct {
+	Feature string
+	Err     error
+}
+
+func (e *HardwareUnsupportedError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s is not supported on this Node", e.Feature)
+	}
+	return fmt.Sprintf("%s is not supported on this Node: %v", e.Feature, e.Err)
+}
+
+func (e *HardwareUnsupportedError) Unwrap() error {
+	return e.Err
+}
+
+// NewTransient wraps err as a TransientAgentError, nil stays nil
+func NewTransient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TransientAgentError{Err: err}
+}
+
+// NewInvalidProfile returns an InvalidProfileError for the named PowerProfile
+func NewInvalidProfile(profile string, format string, args ...interface{}) error {
+	return &InvalidProfileError{Profile: profile, Reason: fmt.Sprintf(format, args...)}
+}
+
+// NewConflict returns a ConflictError
+func NewConflict(format string, args ...interface{}) error {
+	return &ConflictError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// NewHardwareUnsupported returns a HardwareUnsupportedError for the feature, err is the underlying failure if any
+func NewHardwareUnsupported(feature string, err error) error {
+	return &HardwareUnsupportedError{Feature: feature, Err: err}
+}
+
+// IsTransient reports whether err or any error it wraps is a TransientAgentError
+func IsTransient(err error) bool {
+	var target *TransientAgentError
+	return errors.As(err, &target)
+}
+
+// IsInvalidProfile reports whether err or any error it wraps is an InvalidProfileError
+func IsInvalidProfile(err error) bool {
+	var target *InvalidProfileError
+	return errors.As(err, &target)
+}
+
+// IsConflict reports whether err or any error it wraps is a ConflictError
+func IsConflict(err error) bool {
+	var target *ConflictError
+	return errors.As(err, &target)
+}
+
+// IsHardwareUnsupported reports whether err or any error it wraps is a HardwareUnsupportedError
+func IsHardwareUnsupported(err error) bool {
+	var target *HardwareUnsupportedError
+	return errors.As(err, &target)
+}
+
+// ActionFor decides what a controller does with err. Errors outside the taxonomy are retried, as
+// controllers did with every returned error before it existed
+func ActionFor(err error) Action {
+	switch {
+	case IsTransient(err):
+		return Retry
+	case IsHardwareUnsupported(err):
+		return SurfaceToStatus
+	case IsInvalidProfile(err), IsConflict(err):
+		return FailFast
+	default:
+		return Retry
+	}
+}
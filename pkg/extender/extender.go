@@ -0,0 +1,206 @@
+package extender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Args is what the scheduler sends to the filter endpoint, the field names are those of the scheduler's
+// extender API which has no JSON tags. NodeNames is set instead of Nodes when nodeCacheCapable is true
+type Args struct {
+	Pod       *corev1.Pod
+	Nodes     *corev1.NodeList
+	NodeNames *[]string
+}
+
+// FilterResult is the filter endpoint's reply in the scheduler's extender API
+type FilterResult struct {
+	Nodes       *corev1.NodeList
+	NodeNames   *[]string
+	FailedNodes map[string]string
+	Error       string
+}
+
+// Server is a scheduler extender that admits Pods requesting PowerProfile resources only to Nodes whose capacity
+// labels leave enough room. It stands in for the scheduler's own resource fit check when the Node Agents
+// advertise capacity with labels rather than Extended Resources in the Node status, so the resources have to
+// be set as ignoredByScheduler in the extender's managedResources
+type Server struct {
+	client.Client
+	Log  logr.Logger
+	Addr string
+	// ResourcePrefix is the prefix of the PowerProfile Extended Resources Pods request
+	ResourcePrefix string
+	// LabelPrefix is the prefix of the Node labels holding each PowerProfile's capacity
+	LabelPrefix string
+}
+
+// Start serves the filter endpoint until the context is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/filter", s.handleFilter)
+
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	s.Log.Info("serving scheduler extender", "address", s.Addr)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// NeedLeaderElection is false as the scheduler can be pointed at any replica
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) handleFilter(w http.ResponseWriter, req *http.Request) {
+	args := &Args{}
+	result := &FilterResult{}
+	err := json.NewDecoder(req.Body).Decode(args)
+	if err != nil {
+		result.Error = fmt.Sprintf("malformed extender args: %v", err)
+	} else {
+		result, err = s.Filter(req.Context(), args)
+		if err != nil {
+			s.Log.Error(err, "error filtering Nodes")
+			result = &FilterResult{Error: err.Error()}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		s.Log.Error(err, "error writing filter result")
+	}
+}
+
+// Filter keeps the Nodes with enough capacity left for every PowerProfile resource the Pod requests
+func (s *Server) Filter(ctx context.Context, args *Args) (*FilterResult, error) {
+	if args.Pod == nil {
+		return nil, fmt.Errorf("no Pod to filter Nodes for")
+	}
+
+	nodes := make([]corev1.Node, 0)
+	if args.Nodes != nil {
+		nodes = args.Nodes.Items
+	} else if args.NodeNames != nil {
+		for _, name := range *args.NodeNames {
+			node := corev1.Node{}
+			err := s.Client.Get(ctx, client.ObjectKey{Name: name}, &node)
+			if err != nil {
+				return nil, fmt.Errorf("retrieving Node %s: %w", name, err)
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	requested := s.podRequests(args.Pod)
+	used := make(map[string]map[string]int64)
+	if len(requested) > 0 {
+		pods := &corev1.PodList{}
+		err := s.Client.List(ctx, pods)
+		if err != nil {
+			return nil, fmt.Errorf("listing Pods: %w", err)
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Spec.NodeName == "" || pod.UID == args.Pod.UID ||
+				pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			if used[pod.Spec.NodeName] == nil {
+				used[pod.Spec.NodeName] = make(map[string]int64)
+			}
+			for profile, quantity := range s.podRequests(pod) {
+				used[pod.Spec.NodeName][profile] += quantity
+			}
+		}
+	}
+
+	result := &FilterResult{FailedNodes: make(map[string]string)}
+	passed := make([]corev1.Node, 0, len(nodes))
+	passedNames := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		reason := s.unfit(&node, requested, used[node.Name])
+		if reason != "" {
+			result.FailedNodes[node.Name] = reason
+			continue
+		}
+		passed = append(passed, node)
+		passedNames = append(passedNames, node.Name)
+	}
+
+	if args.Nodes != nil {
+		result.Nodes = &corev1.NodeList{Items: passed}
+	} else {
+		result.NodeNames = &passedNames
+	}
+
+	return result, nil
+}
+
+// unfit returns why the Node can't take the requested PowerProfile resources, or an empty string if it can
+func (s *Server) unfit(node *corev1.Node, requested map[string]int64, used map[string]int64) string {
+	for profile, quantity := range requested {
+		label, exists := node.Labels[s.LabelPrefix+profile]
+		if !exists {
+			return fmt.Sprintf("PowerProfile %s is not available", profile)
+		}
+		capacity, err := strconv.ParseInt(label, 10, 64)
+		if err != nil {
+			return fmt.Sprintf("malformed capacity for PowerProfile %s: %s", profile, label)
+		}
+		if used[profile]+quantity > capacity {
+			return fmt.Sprintf("Insufficient %s%s", s.ResourcePrefix, profile)
+		}
+	}
+
+	return ""
+}
+
+// podRequests sums the PowerProfile resources requested by the Pod's containers, keyed by profile name
+func (s *Server) podRequests(pod *corev1.Pod) map[string]int64 {
+	requests := make(map[string]int64)
+	for _, container := range pod.Spec.Containers {
+		// Extended Resources can be given as limits only, requests default to them
+		resources := make(corev1.ResourceList)
+		for name, quantity := range container.Resources.Limits {
+			resources[name] = quantity
+		}
+		for name, quantity := range container.Resources.Requests {
+			resources[name] = quantity
+		}
+		for name, quantity := range resources {
+			if !strings.HasPrefix(string(name), s.ResourcePrefix) {
+				continue
+			}
+			requests[strings.TrimPrefix(string(name), s.ResourcePrefix)] += quantity.Value()
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,102 @@
+// Package freqdomain finds the CPUs whose frequency can't be set independently. On CPUs that scale a module or
+// cluster of cores together, such as E-core clusters and some ARM designs, the cores of one cpufreq policy run at
+// the frequency the policy picks from what each of them asks for
+package freqdomain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+)
+
+// CpuPath holds each CPU's cpufreq policy
+const CpuPath = "/sys/devices/system/cpu"
+
+// Reader reads the frequency domains from cpufreq
+type Reader struct {
+	CpuPath string
+}
+
+func NewReader() *Reader {
+	return &Reader{CpuPath: CpuPath}
+}
+
+// Domains returns the frequency domains with more than one CPU, ordered by their first CPU. CPUs scaled on their
+// own, as on most server cores, aren't in any
+func (r *Reader) Domains() ([]cpuset.CPUSet, error) {
+	paths, err := filepath.Glob(filepath.Join(r.CpuPath, "cpu[0-9]*", "cpufreq", "related_cpus"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no cpufreq policies found under %s", r.CpuPath)
+	}
+
+	seen := make(map[string]bool)
+	domains := make([]cpuset.CPUSet, 0)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// related_cpus is a space separated list rather than a range list
+		related := strings.Fields(string(data))
+		if len(related) < 2 {
+			continue
+		}
+		builder := cpuset.NewBuilder()
+		for _, value := range related {
+			cpu, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("malformed CPU '%s' in %s", value, path)
+			}
+			builder.Add(cpu)
+		}
+		domain := builder.Result()
+		if seen[domain.String()] {
+			continue
+		}
+		seen[domain.String()] = true
+		domains = append(domains, domain)
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].ToSlice()[0] < domains[j].ToSlice()[0] })
+
+	return domains, nil
+}
+
+// Conflict is a frequency domain whose CPUs are given different PowerProfiles, which can't all take effect
+type Conflict struct {
+	Domain cpuset.CPUSet
+	// Profiles are the PowerProfiles of the domain's CPUs, sorted
+	Profiles []string
+}
+
+// Conflicts returns the domains whose CPUs the profiles, keyed by CPU, don't agree on. CPUs without a profile
+// are left out
+func Conflicts(domains []cpuset.CPUSet, profiles map[uint]string) []Conflict {
+	var conflicts []Conflict
+	for _, domain := range domains {
+		names := make(map[string]bool)
+		for _, cpu := range domain.ToSlice() {
+			if profile, exists := profiles[uint(cpu)]; exists {
+				names[profile] = true
+			}
+		}
+		if len(names) < 2 {
+			continue
+		}
+		conflict := Conflict{Domain: domain}
+		for name := range names {
+			conflict.Profiles = append(conflict.Profiles, name)
+		}
+		sort.Strings(conflict.Profiles)
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts
+}
@@ -0,0 +1,213 @@
+package handoff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/intel/power-optimization-library/pkg/power"
+)
+
+// FormatVersion is bumped whenever State changes in a way older or newer agents can't read
+const FormatVersion = 1
+
+// AgentVersion is recorded in the state file for troubleshooting, set at build time with
+// -ldflags "-X github.com/intel/kubernetes-power-manager/pkg/handoff.AgentVersion=v2.3.0"
+var AgentVersion = "dev"
+
+// BootIDFile identifies the current boot, pools saved before a reboot are not restored as the
+// cores have gone back to their defaults
+var BootIDFile = "/proc/sys/kernel/random/boot_id"
+
+// Profile is the part of a PowerProfile applied to a pool's cores
+type Profile struct {
+	Name     string `json:"name"`
+	Min      uint   `json:"min"`
+	Max      uint   `json:"max"`
+	Governor string `json:"governor,omitempty"`
+	Epp      string `json:"epp,omitempty"`
+}
+
+// Pool is a pool in the Power Library with its profile and cores
+type Pool struct {
+	Name    string   `json:"name"`
+	Profile *Profile `json:"profile,omitempty"`
+	Cpus    []uint   `json:"cpus"`
+}
+
+// State is what a Node Agent hands over to the one replacing it
+type State struct {
+	FormatVersion int       `json:"formatVersion"`
+	AgentVersion  string    `json:"agentVersion"`
+	Node          string    `json:"node"`
+	BootID        string    `json:"bootID,omitempty"`
+	Written       time.Time `json:"written"`
+	Shared        Pool      `json:"shared"`
+	Exclusive     []Pool    `json:"exclusive,omitempty"`
+}
+
+// Capture records the Shared and exclusive pools of the Power Library, the Reserved pool is
+// whatever is left over
+func Capture(host power.Host, nodeName string) *State {
+	state := &State{
+		FormatVersion: FormatVersion,
+		AgentVersion:  AgentVersion,
+		Node:          nodeName,
+		BootID:        bootID(),
+		Written:       time.Now(),
+		Shared:        capturePool(host.GetSharedPool()),
+	}
+	for _, pool := range *host.GetAllExclusivePools() {
+		state.Exclusive = append(state.Exclusive, capturePool(pool))
+	}
+
+	return state
+}
+
+func capturePool(pool power.Pool) Pool {
+	return Pool{Name: pool.Name(), Profile: CaptureProfile(pool.GetPowerProfile()), Cpus: pool.Cpus().IDs()}
+}
+
+// CaptureProfile records the Power Library profile, nil if there is none
+func CaptureProfile(profile power.Profile) *Profile {
+	if profile == nil {
+		return nil
+	}
+	return &Profile{
+		Name:     profile.Name(),
+		Min:      profile.MinFreq(),
+		Max:      profile.MaxFreq(),
+		Governor: profile.Governor(),
+		Epp:      profile.Epp(),
+	}
+}
+
+// Save writes the state through a temporary file so a reader never sees it half written
+func Save(path string, state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Load reads a saved state, a missing file is not an error and returns nil
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := &State{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, fmt.Errorf("malformed state file: %w", err)
+	}
+
+	return state, nil
+}
+
+// Check returns why the state can't be restored on this Node, or nil if it can
+func (s *State) Check(nodeName string) error {
+	if s.FormatVersion != FormatVersion {
+		return fmt.Errorf("state format version %d written by agent %s, expected %d", s.FormatVersion, s.AgentVersion, FormatVersion)
+	}
+	if s.Node != nodeName {
+		return fmt.Errorf("state was written on Node %s", s.Node)
+	}
+	if current := bootID(); s.BootID != "" && current != "" && s.BootID != current {
+		return fmt.Errorf("Node has rebooted since the state was written")
+	}
+
+	return nil
+}
+
+// Restore recreates the saved pools in a freshly created Power Library, where every core is
+// still in the Reserved pool. Cores leaving the Reserved pool can only go to the Shared pool, so
+// the Shared pool carries each exclusive pool's profile while that pool's cores pass through it.
+// That way every core is only ever written the values it already has
+func Restore(host power.Host, state *State) error {
+	shared := host.GetSharedPool()
+	for _, saved := range state.Exclusive {
+		profile := saved.Profile.Power()
+		err := shared.SetPowerProfile(profile)
+		if err != nil {
+			return fmt.Errorf("pool %s: %w", saved.Name, err)
+		}
+		err = shared.MoveCpuIDs(saved.Cpus)
+		if err != nil {
+			return fmt.Errorf("pool %s: %w", saved.Name, err)
+		}
+
+		pool := host.GetExclusivePool(saved.Name)
+		if pool == nil {
+			pool, err = host.AddExclusivePool(saved.Name)
+			if err != nil {
+				return fmt.Errorf("pool %s: %w", saved.Name, err)
+			}
+		}
+		err = pool.SetPowerProfile(profile)
+		if err != nil {
+			return fmt.Errorf("pool %s: %w", saved.Name, err)
+		}
+		err = pool.MoveCpuIDs(saved.Cpus)
+		if err != nil {
+			return fmt.Errorf("pool %s: %w", saved.Name, err)
+		}
+	}
+
+	err := shared.SetPowerProfile(state.Shared.Profile.Power())
+	if err != nil {
+		return fmt.Errorf("shared pool: %w", err)
+	}
+	err = shared.MoveCpuIDs(state.Shared.Cpus)
+	if err != nil {
+		return fmt.Errorf("shared pool: %w", err)
+	}
+
+	return nil
+}
+
+// Power gives the recorded profile back to the Power Library, a missing profile stays a nil interface rather than
+// a typed nil
+func (p *Profile) Power() power.Profile {
+	if p == nil {
+		return nil
+	}
+	return &profile{saved: *p}
+}
+
+// profile implements power.Profile without the P-States feature check in power.NewPowerProfile,
+// the values were accepted by the library when they were first applied
+type profile struct {
+	saved Profile
+}
+
+func (p *profile) Name() string     { return p.saved.Name }
+func (p *profile) Epp() string      { return p.saved.Epp }
+func (p *profile) MaxFreq() uint    { return p.saved.Max }
+func (p *profile) MinFreq() uint    { return p.saved.Min }
+func (p *profile) Governor() string { return p.saved.Governor }
+
+func bootID() string {
+	data, err := os.ReadFile(BootIDFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
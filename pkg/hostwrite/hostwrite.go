@@ -0,0 +1,163 @@
+// Package hostwrite makes the Node Agent's own writes to the host's sysfs, either directly or through the write
+// helper, a small binary running beside the agent that only writes the files on its allow list, so the agent's
+// container needn't be privileged for them
+package hostwrite
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// CpuPath is the sysfs directory every file the write helper writes is under
+const CpuPath = "/sys/devices/system/cpu"
+
+// Writer writes a value to a file on the host
+type Writer interface {
+	Write(path string, value []byte) error
+}
+
+// Direct writes the files itself, the agent's container needs write access to them
+type Direct struct{}
+
+func (Direct) Write(path string, value []byte) error {
+	return os.WriteFile(path, value, 0644)
+}
+
+// Default is the Writer the agent's packages use, the agent replaces it with a Helper when it runs with one
+var Default Writer = Direct{}
+
+// Write writes the value through the Default Writer
+func Write(path string, value []byte) error {
+	return Default.Write(path, value)
+}
+
+// allowed are the files the write helper writes, relative to the CpuPath. sysfs doesn't let anyone create links,
+// so a path that matches can't lead anywhere else
+var allowed = []*regexp.Regexp{
+	regexp.MustCompile(`^cpu[0-9]+/cpufreq/scaling_(max|min)_freq$`),
+	regexp.MustCompile(`^cpu[0-9]+/online$`),
+}
+
+// request is a single write sent to the helper, one per connection
+type request struct {
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+type response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Helper sends the writes to the write helper's unix socket
+type Helper struct {
+	Socket  string
+	Timeout time.Duration
+}
+
+func NewHelper(socket string) *Helper {
+	return &Helper{Socket: socket, Timeout: 5 * time.Second}
+}
+
+func (h *Helper) Write(path string, value []byte) error {
+	conn, err := net.DialTimeout("unix", h.Socket, h.Timeout)
+	if err != nil {
+		return fmt.Errorf("connecting to the write helper: %w", err)
+	}
+	defer conn.Close()
+	err = conn.SetDeadline(time.Now().Add(h.Timeout))
+	if err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(conn).Encode(request{Path: path, Value: string(value)})
+	if err != nil {
+		return fmt.Errorf("sending the write to the write helper: %w", err)
+	}
+	reply := response{}
+	err = json.NewDecoder(conn).Decode(&reply)
+	if err != nil {
+		return fmt.Errorf("reading the write helper's reply: %w", err)
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("write helper: %s", reply.Error)
+	}
+
+	return nil
+}
+
+// Server is the write helper's side, it writes the allowed files under its CpuPath and refuses everything else
+type Server struct {
+	CpuPath string
+	Log     logr.Logger
+}
+
+// Allowed reports whether the helper writes the file
+func (s *Server) Allowed(path string) bool {
+	if path != filepath.Clean(path) {
+		return false
+	}
+	relative, err := filepath.Rel(s.CpuPath, path)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range allowed {
+		if pattern.MatchString(relative) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Serve handles connections until the listener is closed
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	err := conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err != nil {
+		return
+	}
+
+	req := request{}
+	reply := response{}
+	err = json.NewDecoder(conn).Decode(&req)
+	if err != nil {
+		reply.Error = fmt.Sprintf("malformed request: %v", err)
+	} else if !s.Allowed(req.Path) {
+		reply.Error = fmt.Sprintf("%s is not on the allow list", req.Path)
+		s.Log.Info("refused write", "path", req.Path)
+	} else if err = writeExisting(req.Path, []byte(req.Value)); err != nil {
+		reply.Error = err.Error()
+	}
+	_ = json.NewEncoder(conn).Encode(reply)
+}
+
+// writeExisting writes a file without creating it, sysfs files always exist
+func writeExisting(path string, value []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(value)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,72 @@
+package hypervisor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// CpuInfoFile lists the CPU flags, hypervisors set the hypervisor flag for their guests
+	CpuInfoFile = "/proc/cpuinfo"
+	// MsrFile is read for the turbo ratio table and uncore frequencies, hypervisors rarely pass it through
+	MsrFile = "/dev/cpu/0/msr"
+	// CpufreqPath is missing when the guest can't scale frequencies, or has a range of one frequency
+	CpufreqPath = "/sys/devices/system/cpu/cpu0/cpufreq"
+)
+
+const (
+	ControlMsr     = "MSR"
+	ControlCpufreq = "cpufreq"
+)
+
+// Detect reports whether the Node runs under a hypervisor
+func Detect() (bool, error) {
+	file, err := os.Open(CpuInfoFile)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(key) != "flags" {
+			continue
+		}
+		// every CPU has the same flags
+		for _, flag := range strings.Fields(value) {
+			if flag == "hypervisor" {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return false, scanner.Err()
+}
+
+// UnavailableControls lists the controls the Node Agent reads or writes directly that the Node doesn't provide,
+// features of the Power Library are reported by the library itself
+func UnavailableControls() []string {
+	unavailable := make([]string, 0)
+	msr, err := os.Open(MsrFile)
+	if err != nil {
+		unavailable = append(unavailable, ControlMsr)
+	} else {
+		msr.Close()
+	}
+
+	maxFrequency, maxErr := os.ReadFile(filepath.Join(CpufreqPath, "cpuinfo_max_freq"))
+	minFrequency, minErr := os.ReadFile(filepath.Join(CpufreqPath, "cpuinfo_min_freq"))
+	switch {
+	case maxErr != nil || minErr != nil:
+		unavailable = append(unavailable, ControlCpufreq)
+	case strings.TrimSpace(string(maxFrequency)) == strings.TrimSpace(string(minFrequency)):
+		unavailable = append(unavailable, fmt.Sprintf("%s (capped at %s kHz)", ControlCpufreq, strings.TrimSpace(string(maxFrequency))))
+	}
+
+	return unavailable
+}
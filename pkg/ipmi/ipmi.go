@@ -0,0 +1,70 @@
+// Package ipmi reads the Node's power from its BMC through the IPMI DCMI power reading command, for Nodes whose
+// RAPL counters aren't available, such as VMs given an IPMI device or hosts with powercap locked down
+package ipmi
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+)
+
+// ToolPath is the ipmitool binary, it talks to the BMC through /dev/ipmi0
+const ToolPath = "ipmitool"
+
+const instantaneousReading = "Instantaneous power reading"
+
+// DCMIReader reads the platform power the BMC measures
+type DCMIReader struct {
+	ToolPath string
+
+	run func(name string, args ...string) ([]byte, error)
+}
+
+func NewDCMIReader(toolPath string) *DCMIReader {
+	return &DCMIReader{
+		ToolPath: toolPath,
+		run: func(name string, args ...string) ([]byte, error) {
+			return exec.Command(name, args...).Output()
+		},
+	}
+}
+
+// Watts returns the instantaneous power reading of the whole platform. A missing ipmitool, or a BMC without DCMI
+// power management, is a HardwareUnsupportedError
+func (d *DCMIReader) Watts() (float64, error) {
+	output, err := d.run(d.ToolPath, "dcmi", "power", "reading")
+	if errors.Is(err, exec.ErrNotFound) {
+		return 0, powererrors.NewHardwareUnsupported("IPMI DCMI", err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("running %s dcmi power reading: %w", d.ToolPath, err)
+	}
+
+	return ParseReading(string(output))
+}
+
+// ParseReading takes the instantaneous reading out of the dcmi power reading output, which has a line like
+// "Instantaneous power reading:   212 Watts"
+func ParseReading(output string) (float64, error) {
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(key) != instantaneousReading {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			break
+		}
+		watts, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed power reading '%s'", strings.TrimSpace(value))
+		}
+		return watts, nil
+	}
+
+	return 0, powererrors.NewHardwareUnsupported("IPMI DCMI", fmt.Errorf("no %s in the dcmi power reading output", strings.ToLower(instantaneousReading)))
+}
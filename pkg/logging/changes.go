@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+)
+
+// PoolChange is what a reconcile did to one pool in the Power Library
+type PoolChange struct {
+	Created     bool   `json:"created,omitempty"`
+	Removed     bool   `json:"removed,omitempty"`
+	Modified    bool   `json:"modified,omitempty"`
+	CpusAdded   []uint `json:"cpusAdded,omitempty"`
+	CpusRemoved []uint `json:"cpusRemoved,omitempty"`
+}
+
+// ChangeSummary collects what a single reconcile changed so it can be logged as one record once the
+// reconcile is done, which makes the controllers' behaviour auditable from the logs alone. All methods
+// can be called on a nil summary and do nothing
+type ChangeSummary struct {
+	nodes   map[string]bool
+	added   []string
+	removed []string
+	updated []string
+	pools   map[string]*PoolChange
+}
+
+func NewChangeSummary() *ChangeSummary {
+	return &ChangeSummary{
+		nodes: make(map[string]bool),
+		pools: make(map[string]*PoolChange),
+	}
+}
+
+// NodeTouched records that the reconcile changed the Node, its status or its labels
+func (s *ChangeSummary) NodeTouched(name string) {
+	if s == nil || name == "" {
+		return
+	}
+	s.nodes[name] = true
+}
+
+// ResourceAdded records an object or Extended Resource the reconcile created
+func (s *ChangeSummary) ResourceAdded(kind string, name string) {
+	if s == nil {
+		return
+	}
+	s.added = append(s.added, fmt.Sprintf("%s/%s", kind, name))
+}
+
+// ResourceRemoved records an object or Extended Resource the reconcile deleted
+func (s *ChangeSummary) ResourceRemoved(kind string, name string) {
+	if s == nil {
+		return
+	}
+	s.removed = append(s.removed, fmt.Sprintf("%s/%s", kind, name))
+}
+
+// ResourceUpdated records an object or Extended Resource the reconcile changed
+func (s *ChangeSummary) ResourceUpdated(kind string, name string) {
+	if s == nil {
+		return
+	}
+	s.updated = append(s.updated, fmt.Sprintf("%s/%s", kind, name))
+}
+
+// PoolCreated records a pool added to the Power Library
+func (s *ChangeSummary) PoolCreated(pool string) {
+	if s == nil {
+		return
+	}
+	s.pool(pool).Created = true
+}
+
+// PoolRemoved records a pool removed from the Power Library, its CPUs go back to the Shared pool
+func (s *ChangeSummary) PoolRemoved(pool string) {
+	if s == nil {
+		return
+	}
+	s.pool(pool).Removed = true
+}
+
+// PoolModified records a change to the pool's PowerProfile or its CPUs
+func (s *ChangeSummary) PoolModified(pool string, cpusAdded []uint, cpusRemoved []uint) {
+	if s == nil {
+		return
+	}
+	change := s.pool(pool)
+	change.Modified = true
+	change.CpusAdded = append(change.CpusAdded, cpusAdded...)
+	change.CpusRemoved = append(change.CpusRemoved, cpusRemoved...)
+}
+
+func (s *ChangeSummary) pool(name string) *PoolChange {
+	change, exists := s.pools[name]
+	if !exists {
+		change = &PoolChange{}
+		s.pools[name] = change
+	}
+	return change
+}
+
+// Empty reports whether the reconcile changed nothing
+func (s *ChangeSummary) Empty() bool {
+	return s == nil || len(s.nodes) == 0 && len(s.added) == 0 && len(s.removed) == 0 && len(s.updated) == 0 && len(s.pools) == 0
+}
+
+// Log writes the summary as one record, a reconcile that changed nothing isn't logged
+func (s *ChangeSummary) Log(logger logr.Logger) {
+	if s.Empty() {
+		return
+	}
+
+	nodes := make([]string, 0, len(s.nodes))
+	for node := range s.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	pools := make(map[string]PoolChange, len(s.pools))
+	for name, change := range s.pools {
+		pools[name] = *change
+	}
+
+	logger.Info("Reconcile changes",
+		"nodesTouched", nodes,
+		"resourcesAdded", s.added,
+		"resourcesRemoved", s.removed,
+		"resourcesUpdated", s.updated,
+		"poolsModified", pools)
+}
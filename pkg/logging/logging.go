@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"flag"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// SuppressedMessages counts the log lines dropped by sampling, so dropped volume stays visible
+var SuppressedMessages = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "power_log_messages_suppressed_total",
+		Help: "Number of log messages dropped by log sampling",
+	},
+	[]string{"level"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(SuppressedMessages)
+}
+
+// SamplingOptions configures deduplication of repeated log messages. Within every Tick the first
+// First messages with the same level and text are logged, after that only every Thereafter-th one
+type SamplingOptions struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+// BindFlags registers the sampling flags, sampling is disabled when log-sampling-initial is 0
+func (o *SamplingOptions) BindFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.Tick, "log-sampling-tick", time.Second,
+		"The interval over which repeated log messages are counted for sampling.")
+	fs.IntVar(&o.First, "log-sampling-initial", 100,
+		"Number of identical log messages logged per tick before sampling starts. 0 disables sampling.")
+	fs.IntVar(&o.Thereafter, "log-sampling-thereafter", 100,
+		"Once sampling has started, only every Nth identical log message is logged for the rest of the tick.")
+}
+
+// UseSampling returns the logger option wrapping the zap core with the sampler
+func UseSampling(o *SamplingOptions) crzap.Opts {
+	return crzap.RawZapOpts(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if o.First <= 0 {
+			return core
+		}
+
+		return zapcore.NewSamplerWithOptions(core, o.Tick, o.First, o.Thereafter,
+			zapcore.SamplerHook(func(entry zapcore.Entry, decision zapcore.SamplingDecision) {
+				if decision&zapcore.LogDropped != 0 {
+					SuppressedMessages.WithLabelValues(entry.Level.String()).Inc()
+				}
+			}),
+		)
+	}))
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// PowerConfigMatchedNodes is the number of Nodes matched by each PowerConfig's PowerNodeSelector
+	PowerConfigMatchedNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_config_matched_nodes",
+			Help: "Number of Nodes matched by the PowerNodeSelector of a PowerConfig",
+		},
+		[]string{"powerconfig"},
+	)
+
+	// NodePowerWatts is the power drawn by a Node as measured by each telemetry source, RAPL for the
+	// packages and Redfish or, where RAPL is unavailable, IPMI DCMI for the whole chassis
+	NodePowerWatts = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_node_watts",
+			Help: "Power drawn by a Node in watts, by telemetry source",
+		},
+		[]string{"node", "source"},
+	)
+
+	// PodResourcesLookups counts the container CPU lookups of the Node Agent by where they were answered from,
+	// the cached List response or a new List call to the kubelet
+	PodResourcesLookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "power_podresources_lookups_total",
+			Help: "Container CPU lookups by the Node Agent, by whether the kubelet was asked or the cache answered",
+		},
+		[]string{"source"},
+	)
+
+	// ExtendedResourcesRestored counts the PowerProfile Extended Resources the Node Agent put back in the Node
+	// status after they went missing, usually wiped by a kubelet restart
+	ExtendedResourcesRestored = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "power_extended_resources_restored_total",
+			Help: "Number of PowerProfile Extended Resources restored after going missing from the Node status",
+		},
+		[]string{"node", "profile"},
+	)
+
+	// ProfileOverridden is 1 for the PowerProfiles whose CPUs perf counters found running outside the profile's
+	// frequency range, usually because of BIOS settings
+	ProfileOverridden = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_profile_overridden",
+			Help: "Whether the last verification found a PowerProfile's CPUs running outside its frequency range",
+		},
+		[]string{"node", "profile"},
+	)
+
+	// PoolIRQRatio and PoolSoftIRQRatio are the shares of a pool's CPU time spent servicing hardware interrupts
+	// and softirqs, averaged over its CPUs. An exclusive pool taking interrupts isn't isolated from the rest of the Node
+	PoolIRQRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_pool_irq_ratio",
+			Help: "Share of a pool's CPU time spent servicing hardware interrupts, averaged over its CPUs",
+		},
+		[]string{"node", "pool"},
+	)
+	PoolSoftIRQRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_pool_softirq_ratio",
+			Help: "Share of a pool's CPU time spent servicing softirqs, averaged over its CPUs",
+		},
+		[]string{"node", "pool"},
+	)
+
+	// PoolContextSwitchRate is how often each of a pool's CPUs switches tasks
+	PoolContextSwitchRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_pool_context_switches_per_second",
+			Help: "Context switches per second on each of a pool's CPUs, averaged over its CPUs",
+		},
+		[]string{"node", "pool"},
+	)
+
+	// PoolSharedInterference counts the samples where an exclusive pool's frequency dropped below its baseline
+	// while the Shared pool was spiking, a sign the Shared pool's load is taking its turbo
+	PoolSharedInterference = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "power_pool_shared_interference_total",
+			Help: "Samples where an exclusive pool's frequency dropped while the Shared pool was spiking",
+		},
+		[]string{"node", "pool"},
+	)
+
+	// PoolFrequencyJitter is the standard deviation of the frequency of an exclusive pool's busy CPUs over the
+	// jitter window
+	PoolFrequencyJitter = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_pool_frequency_jitter_mhz",
+			Help: "Standard deviation in MHz of the frequency of an exclusive pool's busy CPUs over the jitter window",
+		},
+		[]string{"node", "pool"},
+	)
+
+	// PoolFrequencyJitterRatio is the PoolFrequencyJitter relative to the pool's mean frequency
+	PoolFrequencyJitterRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_pool_frequency_jitter_ratio",
+			Help: "Standard deviation of the frequency of an exclusive pool's busy CPUs relative to their mean frequency",
+		},
+		[]string{"node", "pool"},
+	)
+
+	// NetworkDropRate is the packets per second the interfaces of a Node's networkBoost drop on receive
+	NetworkDropRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_network_drops_per_second",
+			Help: "Packets per second dropped by the network interfaces a PowerNode's networkBoost watches",
+		},
+		[]string{"node"},
+	)
+
+	// CoreThermalCap is the max frequency a core with a temperature target is currently capped at
+	CoreThermalCap = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "power_core_thermal_cap_mhz",
+			Help: "Max frequency in MHz a core is capped at to keep it under its PowerProfile's temperature target",
+		},
+		[]string{"node", "cpu"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(PowerConfigMatchedNodes, NodePowerWatts, PodResourcesLookups, ExtendedResourcesRestored,
+		ProfileOverridden, PoolIRQRatio, PoolSoftIRQRatio, PoolContextSwitchRate,
+		PoolSharedInterference, PoolFrequencyJitter, PoolFrequencyJitterRatio, CoreThermalCap, NetworkDropRate)
+}
@@ -0,0 +1,185 @@
+// Package nicstats reads how many packets network interfaces drop on receive and which CPUs handle their
+// interrupts, for boosting the pools those CPUs are in while the Node can't keep up with its traffic
+package nicstats
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+)
+
+const (
+	// SoftnetStatFile has a line of hex counters per online CPU, the second is packets dropped from its backlog
+	SoftnetStatFile = "/proc/net/softnet_stat"
+	// NetClassPath holds each interface's statistics and, for physical ones, its device's MSI interrupts
+	NetClassPath = "/sys/class/net"
+	// IrqPath holds the CPUs each interrupt is delivered to
+	IrqPath = "/proc/irq"
+
+	// softnetCpuColumn is where kernels from 5.10 put the CPU a softnet_stat line belongs to, older ones leave
+	// it out and the lines follow the CPU numbers
+	softnetCpuColumn = 12
+)
+
+// dropCounters are the interface statistics counting packets lost on receive: dropped by the driver, missed
+// for lack of buffers and overrun in the NIC's FIFO
+var dropCounters = []string{"rx_dropped", "rx_missed_errors", "rx_fifo_errors"}
+
+// Stats are the drop counters of a set of interfaces, only the changes between two reads mean anything
+type Stats struct {
+	// Drops is the packets the interfaces dropped on receive and those dropped from the backlog of the CPUs
+	// handling their interrupts
+	Drops uint64
+	// IRQCPUs are the CPUs the interfaces' interrupts are delivered to, sorted
+	IRQCPUs []uint
+}
+
+// Source provides the drop counters of network interfaces, the Reader implements it on top of procfs and sysfs
+type Source interface {
+	Read(interfaces []string) (Stats, error)
+}
+
+// Reader reads the interfaces' drop counters from sysfs, their interrupts' CPUs from procfs and the CPUs'
+// backlog drops from softnet_stat
+type Reader struct {
+	SoftnetStatFile string
+	NetClassPath    string
+	IrqPath         string
+}
+
+func NewReader() *Reader {
+	return &Reader{
+		SoftnetStatFile: SoftnetStatFile,
+		NetClassPath:    NetClassPath,
+		IrqPath:         IrqPath,
+	}
+}
+
+// Read sums the drop counters of the interfaces. An interface without a device, such as a bridge, has no
+// interrupts of its own and only its statistics are counted
+func (r *Reader) Read(interfaces []string) (Stats, error) {
+	stats := Stats{}
+	irqCPUs := make(map[uint]bool)
+	for _, iface := range interfaces {
+		for _, counter := range dropCounters {
+			value, err := readCounter(filepath.Join(r.NetClassPath, iface, "statistics", counter))
+			if err != nil {
+				if os.IsNotExist(err) && counter != "rx_dropped" {
+					// not every driver has the error counters
+					continue
+				}
+				return Stats{}, fmt.Errorf("reading %s of %s: %w", counter, iface, err)
+			}
+			stats.Drops += value
+		}
+
+		cpus, err := r.interruptCPUs(iface)
+		if err != nil {
+			return Stats{}, err
+		}
+		for _, cpu := range cpus {
+			irqCPUs[cpu] = true
+		}
+	}
+
+	for cpu := range irqCPUs {
+		stats.IRQCPUs = append(stats.IRQCPUs, cpu)
+	}
+	sort.Slice(stats.IRQCPUs, func(i, j int) bool {
+		return stats.IRQCPUs[i] < stats.IRQCPUs[j]
+	})
+
+	backlogDrops, err := r.readSoftnetDrops()
+	if err != nil {
+		return Stats{}, err
+	}
+	for cpu := range irqCPUs {
+		stats.Drops += backlogDrops[cpu]
+	}
+
+	return stats, nil
+}
+
+// interruptCPUs returns the CPUs the interface's MSI interrupts are delivered to
+func (r *Reader) interruptCPUs(iface string) ([]uint, error) {
+	entries, err := os.ReadDir(filepath.Join(r.NetClassPath, iface, "device", "msi_irqs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing interrupts of %s: %w", iface, err)
+	}
+
+	cpus := make([]uint, 0)
+	for _, entry := range entries {
+		// the affinity the kernel actually applied, or the requested one on kernels that don't report it
+		data, err := os.ReadFile(filepath.Join(r.IrqPath, entry.Name(), "effective_affinity_list"))
+		if os.IsNotExist(err) {
+			data, err = os.ReadFile(filepath.Join(r.IrqPath, entry.Name(), "smp_affinity_list"))
+		}
+		if err != nil {
+			if os.IsNotExist(err) {
+				// the interrupt went away while the device was being reconfigured
+				continue
+			}
+			return nil, fmt.Errorf("reading affinity of interrupt %s: %w", entry.Name(), err)
+		}
+		set, err := cpuset.Parse(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("parsing affinity of interrupt %s: %w", entry.Name(), err)
+		}
+		for _, cpu := range set.ToSlice() {
+			cpus = append(cpus, uint(cpu))
+		}
+	}
+
+	return cpus, nil
+}
+
+// readSoftnetDrops returns the backlog drops of each online CPU
+func (r *Reader) readSoftnetDrops() (map[uint]uint64, error) {
+	file, err := os.Open(r.SoftnetStatFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	drops := make(map[uint]uint64)
+	scanner := bufio.NewScanner(file)
+	for line := uint(0); scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		cpu := line
+		if len(fields) >= softnetCpuColumn+1 {
+			parsed, err := strconv.ParseUint(fields[softnetCpuColumn], 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", r.SoftnetStatFile, err)
+			}
+			cpu = uint(parsed)
+		}
+		dropped, err := strconv.ParseUint(fields[1], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", r.SoftnetStatFile, err)
+		}
+		drops[cpu] = dropped
+	}
+
+	return drops, scanner.Err()
+}
+
+func readCounter(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
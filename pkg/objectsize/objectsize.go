@@ -0,0 +1,48 @@
+package objectsize
+
+import (
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// EtcdRequestLimit is etcd's default --max-request-bytes, the API server can't store an object larger than it
+	EtcdRequestLimit = 1536 * 1024
+	// WarningPercent of the EtcdRequestLimit is where objects are warned about, leaving room to act before
+	// writes start failing
+	WarningPercent = 75
+)
+
+// Size is the size of the object's JSON, an upper bound of what etcd stores for it
+func Size(obj client.Object) (int, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// NearLimit reports the object's size and whether it is past WarningPercent of the EtcdRequestLimit
+func NearLimit(obj client.Object) (int, bool) {
+	size, err := Size(obj)
+	if err != nil {
+		return 0, false
+	}
+
+	return size, size > EtcdRequestLimit*WarningPercent/100
+}
+
+// Warn logs a warning when the object is close to the EtcdRequestLimit, before it is written. The write still goes
+// ahead, the warning is there so the labels, resources or status entries piling up can be looked at in time
+func Warn(logger logr.Logger, kind string, obj client.Object) {
+	size, near := NearLimit(obj)
+	if !near {
+		return
+	}
+
+	logger.Info("object is approaching the etcd size limit, writes will fail once it is reached",
+		"kind", kind, "name", obj.GetName(), "bytes", size, "limit", EtcdRequestLimit)
+}
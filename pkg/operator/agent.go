@@ -0,0 +1,797 @@
+package operator
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/intel/power-optimization-library/pkg/power"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/controllers"
+	"github.com/intel/kubernetes-power-manager/pkg/adopt"
+	"github.com/intel/kubernetes-power-manager/pkg/cgroup"
+	"github.com/intel/kubernetes-power-manager/pkg/cpudefaults"
+	"github.com/intel/kubernetes-power-manager/pkg/diagnostics"
+	"github.com/intel/kubernetes-power-manager/pkg/freqdomain"
+	"github.com/intel/kubernetes-power-manager/pkg/hostwrite"
+	"github.com/intel/kubernetes-power-manager/pkg/hypervisor"
+	"github.com/intel/kubernetes-power-manager/pkg/ipmi"
+	"github.com/intel/kubernetes-power-manager/pkg/nicstats"
+	"github.com/intel/kubernetes-power-manager/pkg/otlp"
+	"github.com/intel/kubernetes-power-manager/pkg/perf"
+	"github.com/intel/kubernetes-power-manager/pkg/plan"
+	"github.com/intel/kubernetes-power-manager/pkg/podresourcesclient"
+	"github.com/intel/kubernetes-power-manager/pkg/podstate"
+	"github.com/intel/kubernetes-power-manager/pkg/poollock"
+	"github.com/intel/kubernetes-power-manager/pkg/probe"
+	"github.com/intel/kubernetes-power-manager/pkg/rapl"
+	"github.com/intel/kubernetes-power-manager/pkg/realtime"
+	"github.com/intel/kubernetes-power-manager/pkg/sst"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetry"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetrystream"
+	"github.com/intel/kubernetes-power-manager/pkg/thermal"
+	"github.com/intel/kubernetes-power-manager/pkg/turbo"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+// AgentOptions configures the Node Agent's controllers for the Node the manager runs on
+type AgentOptions struct {
+	// NodeName is the Node the agent manages, the controllers also read it from the NODE_NAME environment variable
+	NodeName                 string
+	OrphanCheckInterval      time.Duration
+	SharedPoolStepInterval   time.Duration
+	SpeedSelectTool          string
+	IPMITool                 string
+	PowerTelemetryInterval   time.Duration
+	ThermalInterval          time.Duration
+	ThrottleInterval         time.Duration
+	NetworkBoostInterval     time.Duration
+	RedfishCredentialsSecret string
+	RedfishInsecure          bool
+	BiosSettingsInterval     time.Duration
+	// DebugSocket is the unix socket pprof and the state dumps are served on, disabled if empty
+	DebugSocket              string
+	EnableRecommendations    bool
+	TelemetrySampleInterval  time.Duration
+	RecommendationInterval   time.Duration
+	RecommendationWindow     time.Duration
+	RecommendationPercentile float64
+	// DetectSharedInterference watches the exclusive pools for frequency drops during Shared pool spikes, it
+	// uses the recommendation samples so needs EnableRecommendations
+	DetectSharedInterference bool
+	// DetectBusyPoll leaves cores that look to be polling out of the recommendations, on top of the fastpath ones
+	DetectBusyPoll bool
+	// Adopt takes over the Node's hand tuned settings as PowerProfiles and PowerWorkloads on first install
+	Adopt bool
+	// HandoffStateFile is where the pools are saved for an upgraded agent to take over, disabled if empty
+	HandoffStateFile     string
+	HandoffSaveInterval  time.Duration
+	PodResourcesCacheTTL time.Duration
+	// ProfileVerificationWindow is how long perf counters are sampled to verify PowerProfiles, 0 disables it
+	ProfileVerificationWindow      time.Duration
+	CpuDefaultsChannel             string
+	TransitionPodDisruptionBudgets bool
+	// TelemetryStreamAddr is the address the power readings are streamed from over gRPC, disabled if empty
+	TelemetryStreamAddr string
+	// OTLPEndpoint is the OpenTelemetry collector the metrics are pushed to over OTLP/HTTP, disabled if empty
+	OTLPEndpoint string
+	OTLPInterval time.Duration
+	// OTLPHeaders are comma separated name=value pairs sent with every export
+	OTLPHeaders string
+	// FrequencyDomainInterval is how often PowerWorkloads are checked for cores sharing a frequency domain
+	FrequencyDomainInterval time.Duration
+	// StrictAdmissionTimeout is how long a strict admission Pod's PowerWorkload may fail to apply before the Pod is
+	// deleted, 0 never deletes it
+	StrictAdmissionTimeout time.Duration
+	// FrequencyJitterInterval is how often the exclusive pools' frequency is sampled for jitter, disabled if 0
+	FrequencyJitterInterval  time.Duration
+	FrequencyJitterWindow    time.Duration
+	FrequencyJitterThreshold float64
+	// OutageCheckInterval is how often the API server is checked for the PowerConfig's outage policy,
+	// disabled if 0
+	OutageCheckInterval time.Duration
+	// WriteHelperSocket is the write helper the agent's own sysfs writes go through, written directly if empty
+	WriteHelperSocket string
+	// PowerTelemetryFastInterval and PowerTelemetryIdleInterval adapt the PowerTelemetryInterval to the Node's
+	// workloads, it is used throughout if both are 0
+	PowerTelemetryFastInterval time.Duration
+	PowerTelemetryIdleInterval time.Duration
+	// SharedPoolTurboInterval is how often the caps of a sharedPoolTurboBudget are written and rotated
+	SharedPoolTurboInterval time.Duration
+	// ExecNamespaces are the comma separated namespaces PowerWorkload validation and hook commands may run in,
+	// always in Pods on the agent's Node, none if empty
+	ExecNamespaces string
+	// HookURLPrefixes are the comma separated prefixes a PowerWorkload hook URL must start with, hooks can't call
+	// out if empty
+	HookURLPrefixes string
+	// PrometheusURL is the Prometheus server PowerWorkload validation queries are sent to, queries fail if empty
+	PrometheusURL string
+	// CorePlacer chooses which of a container's exclusive CPUs are given its PowerProfile, all of them if nil. It
+	// has no flag, only binaries embedding the agent can set it
+	CorePlacer controllers.CorePlacer
+}
+
+// DefaultAgentOptions returns the AgentOptions the Node Agent runs with when no flags are given
+func DefaultAgentOptions() AgentOptions {
+	return AgentOptions{
+		NodeName:                 os.Getenv("NODE_NAME"),
+		OrphanCheckInterval:      time.Minute,
+		SharedPoolStepInterval:   30 * time.Second,
+		SpeedSelectTool:          sst.ToolPath,
+		IPMITool:                 ipmi.ToolPath,
+		PowerTelemetryInterval:   30 * time.Second,
+		ThermalInterval:          5 * time.Second,
+		ThrottleInterval:         10 * time.Second,
+		NetworkBoostInterval:     5 * time.Second,
+		BiosSettingsInterval:     time.Hour,
+		TelemetrySampleInterval:  time.Minute,
+		RecommendationInterval:   time.Hour,
+		RecommendationWindow:     7 * 24 * time.Hour,
+		RecommendationPercentile: 0.99,
+		HandoffStateFile:         "/var/lib/power-node-agent/pools.json",
+		HandoffSaveInterval:      30 * time.Second,
+		PodResourcesCacheTTL:     30 * time.Second,
+		CpuDefaultsChannel:       cpudefaults.DefaultChannel,
+		OTLPInterval:             30 * time.Second,
+		FrequencyDomainInterval:  time.Minute,
+		StrictAdmissionTimeout:   2 * time.Minute,
+		FrequencyJitterInterval:  10 * time.Second,
+		FrequencyJitterWindow:    5 * time.Minute,
+		FrequencyJitterThreshold: telemetry.DefaultJitterThreshold,
+		OutageCheckInterval:      10 * time.Second,
+		SharedPoolTurboInterval:  2 * time.Second,
+	}
+}
+
+// BindFlags registers the Node Agent's flags, defaulting to the current values
+func (o *AgentOptions) BindFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.OrphanCheckInterval, "orphan-check-interval", o.OrphanCheckInterval,
+		"How often to check for cores left in exclusive pools that no PowerWorkload claims.")
+	fs.DurationVar(&o.SharedPoolStepInterval, "shared-pool-step-interval", o.SharedPoolStepInterval,
+		"How often the Shared pool's max frequency is stepped down or restored when the PowerNode has a sharedPoolStepDown.")
+	fs.StringVar(&o.SpeedSelectTool, "intel-speed-select", o.SpeedSelectTool,
+		"Path to the intel-speed-select tool used to switch SST-PP config levels.")
+	fs.StringVar(&o.IPMITool, "ipmitool", o.IPMITool,
+		"Path to the ipmitool used to read the Node's power over IPMI DCMI when RAPL is unavailable. Disabled if empty.")
+	fs.DurationVar(&o.PowerTelemetryInterval, "power-telemetry-interval", o.PowerTelemetryInterval,
+		"How often package and chassis power are published in the PowerNode status and metrics.")
+	fs.DurationVar(&o.PowerTelemetryFastInterval, "power-telemetry-fast-interval", o.PowerTelemetryFastInterval,
+		"How often power is published while the Node runs workloads of the first PowerProfile of the profileOrdering. The power-telemetry-interval if 0.")
+	fs.DurationVar(&o.PowerTelemetryIdleInterval, "power-telemetry-idle-interval", o.PowerTelemetryIdleInterval,
+		"How often power is published while the Node runs no workloads with a PowerProfile. The power-telemetry-interval if 0.")
+	fs.DurationVar(&o.SharedPoolTurboInterval, "shared-pool-turbo-interval", o.SharedPoolTurboInterval,
+		"How often the Shared cores over the PowerNode's sharedPoolTurboBudget are capped, and rotated once its rotationSeconds pass.")
+	fs.DurationVar(&o.ThermalInterval, "thermal-interval", o.ThermalInterval,
+		"How often the frequency caps of cores whose PowerProfile has a temperatureTarget are adjusted.")
+	fs.DurationVar(&o.ThrottleInterval, "throttle-interval", o.ThrottleInterval,
+		"How often the throttle counters are read when the PowerConfig has a throttleDemotion.")
+	fs.DurationVar(&o.NetworkBoostInterval, "network-boost-interval", o.NetworkBoostInterval,
+		"How often network drops are read when the PowerNode has a networkBoost.")
+	fs.StringVar(&o.RedfishCredentialsSecret, "redfish-credentials-secret", o.RedfishCredentialsSecret,
+		"Secret with the username and password of the Node's BMC to read chassis power over Redfish. Disabled if empty.")
+	fs.BoolVar(&o.RedfishInsecure, "redfish-insecure", o.RedfishInsecure, "Skip verifying the BMC's TLS certificate.")
+	fs.DurationVar(&o.BiosSettingsInterval, "bios-settings-interval", o.BiosSettingsInterval,
+		"How often the BIOS settings that affect power management are collected into the PowerNode status.")
+	fs.StringVar(&o.DebugSocket, "debug-socket", o.DebugSocket,
+		"Unix socket to serve pprof, goroutine and internal state dumps on. Disabled if empty.")
+	fs.BoolVar(&o.EnableRecommendations, "enable-recommendations", o.EnableRecommendations,
+		"Record core utilization and publish PowerRecommendations with suggested max frequencies per PowerProfile.")
+	fs.DurationVar(&o.TelemetrySampleInterval, "telemetry-sample-interval", o.TelemetrySampleInterval,
+		"How often core utilization and frequency are sampled for recommendations.")
+	fs.DurationVar(&o.RecommendationInterval, "recommendation-interval", o.RecommendationInterval,
+		"How often PowerRecommendations are updated.")
+	fs.DurationVar(&o.RecommendationWindow, "recommendation-window", o.RecommendationWindow,
+		"How much utilization history recommendations are based on.")
+	fs.Float64Var(&o.RecommendationPercentile, "recommendation-percentile", o.RecommendationPercentile,
+		"Fraction of the recorded demand a recommended max frequency has to cover.")
+	fs.BoolVar(&o.DetectSharedInterference, "detect-shared-interference", o.DetectSharedInterference,
+		"Raise an event and metric when an exclusive pool's frequency drops while the Shared pool is spiking. Needs --enable-recommendations.")
+	fs.BoolVar(&o.DetectBusyPoll, "detect-busy-poll", o.DetectBusyPoll,
+		"Leave cores busy the whole sample interval without context switches, such as DPDK's, out of the recommendations and interference detection. Needs schedstats.")
+	fs.StringVar(&o.HandoffStateFile, "handoff-state-file", o.HandoffStateFile,
+		"File on the host the pools are saved to so an upgraded Node Agent can take them over. Disabled if empty.")
+	fs.DurationVar(&o.HandoffSaveInterval, "handoff-save-interval", o.HandoffSaveInterval,
+		"How often the pools are saved to the handoff state file, they are also saved on shutdown.")
+	fs.BoolVar(&o.Adopt, "adopt", o.Adopt,
+		"On a Node without PowerWorkloads, take over cores tuned by hand as adopted PowerProfiles and PowerWorkloads that are left as found until edited.")
+	fs.DurationVar(&o.PodResourcesCacheTTL, "pod-resources-cache-ttl", o.PodResourcesCacheTTL,
+		"How long a PodResources List response from the kubelet is reused for, 0 lists on every lookup.")
+	fs.DurationVar(&o.ProfileVerificationWindow, "profile-verification-window", o.ProfileVerificationWindow,
+		"How long perf counters are sampled for to verify a PowerProfile on the CPUs added to its pool, 0 disables verification.")
+	fs.StringVar(&o.CpuDefaultsChannel, "cpu-defaults-channel", o.CpuDefaultsChannel,
+		fmt.Sprintf("Release channel of the per CPU model defaults PowerProfiles with the auto preset take their frequencies from, one of %v.", cpudefaults.Channels()))
+	fs.BoolVar(&o.TransitionPodDisruptionBudgets, "transition-pod-disruption-budgets", o.TransitionPodDisruptionBudgets,
+		"Cover the Pods of a PowerWorkload whose frequencies are changing with PodDisruptionBudgets until the change settles.")
+	fs.StringVar(&o.TelemetryStreamAddr, "telemetry-stream-addr", o.TelemetryStreamAddr,
+		"The address the gRPC stream pushing power readings to the manager binds to. Disabled if empty.")
+	fs.StringVar(&o.OTLPEndpoint, "otlp-endpoint", o.OTLPEndpoint,
+		"OTLP/HTTP URL of the OpenTelemetry collector the power and frequency metrics are pushed to, such as http://otel-collector:4318. Disabled if empty.")
+	fs.DurationVar(&o.OTLPInterval, "otlp-interval", o.OTLPInterval,
+		"How often the metrics are pushed to the OpenTelemetry collector.")
+	fs.StringVar(&o.OTLPHeaders, "otlp-headers", o.OTLPHeaders,
+		"Comma separated name=value headers sent to the OpenTelemetry collector, for its authentication.")
+	fs.DurationVar(&o.FrequencyDomainInterval, "frequency-domain-interval", o.FrequencyDomainInterval,
+		"How often PowerWorkloads are checked for cores sharing a frequency domain with cores of another PowerProfile.")
+	fs.DurationVar(&o.StrictAdmissionTimeout, "strict-admission-timeout", o.StrictAdmissionTimeout,
+		"How long the PowerWorkload of a Pod with the strict admission readiness gate may fail to apply before the Pod is deleted. Never deleted if 0.")
+	fs.DurationVar(&o.FrequencyJitterInterval, "frequency-jitter-interval", o.FrequencyJitterInterval,
+		"How often the frequency of the exclusive pools' busy CPUs is sampled for jitter. Disabled if 0.")
+	fs.DurationVar(&o.FrequencyJitterWindow, "frequency-jitter-window", o.FrequencyJitterWindow,
+		"How much frequency history the jitter of each exclusive pool is measured over.")
+	fs.Float64Var(&o.FrequencyJitterThreshold, "frequency-jitter-threshold", o.FrequencyJitterThreshold,
+		"Standard deviation of a pinned pool's frequency, relative to its mean, its PowerWorkloads are Degraded above.")
+	fs.DurationVar(&o.OutageCheckInterval, "outage-check-interval", o.OutageCheckInterval,
+		"How often the API server is checked so the PowerConfig's controlPlaneOutage policy can be applied while it's unreachable. Disabled if 0.")
+	fs.StringVar(&o.WriteHelperSocket, "write-helper-socket", o.WriteHelperSocket,
+		"Unix socket of the write helper the agent's own sysfs writes are sent to, for running the agent unprivileged. Written directly if empty.")
+	fs.StringVar(&o.ExecNamespaces, "exec-namespaces", o.ExecNamespaces,
+		"Comma separated namespaces PowerWorkload validation and hook commands may run in, only ever in Pods on the agent's Node. None if empty.")
+	fs.StringVar(&o.HookURLPrefixes, "hook-url-prefixes", o.HookURLPrefixes,
+		"Comma separated prefixes a PowerWorkload hook URL must start with, such as http://hooks.power-manager.svc/. Hooks can't call out if empty.")
+	fs.StringVar(&o.PrometheusURL, "prometheus-url", o.PrometheusURL,
+		"Address of the Prometheus server PowerWorkload validation queries are sent to, such as http://prometheus.monitoring:9090. Queries fail if empty.")
+}
+
+// AddAgentToManager creates the Power Library instance for the Node and adds the Node Agent's controllers to the
+// manager. A VM without any power controls only gets the NodeCapability controller, so it still reports what it
+// is. The manager must not use leader election, as every Node runs its own agent, and its cache should come from
+// NewAgentCache
+func AddAgentToManager(mgr ctrl.Manager, options AgentOptions) error {
+	if err := prewarmNodeCache(mgr); err != nil {
+		return err
+	}
+	virtualized, err := hypervisor.Detect()
+	if err != nil {
+		setupLog.Info("unable to tell if the Node is a VM, assuming bare metal", "error", err.Error())
+	}
+	unavailableControls := hypervisor.UnavailableControls()
+
+	if options.WriteHelperSocket != "" {
+		hostwrite.Default = hostwrite.NewHelper(options.WriteHelperSocket)
+	}
+	power.SetLogger(ctrl.Log.WithName("powerLibrary"))
+	powerLibrary, err := power.CreateInstance(options.NodeName)
+	if powerLibrary == nil {
+		if !virtualized {
+			return fmt.Errorf("unable to create Power Library instance: %w", err)
+		}
+		setupLog.Info("no power controls are available in this VM, only reporting the Node's capabilities", "error", err.Error())
+		return addNodeCapability(mgr, virtualized, append(unavailableControls, "Power Library"))
+	}
+
+	features := make([]featureDump, 0)
+	for id, feature := range powerLibrary.GetFeaturesInfo() {
+		entry := featureDump{Name: feature.Name(), Driver: feature.Driver(), Available: power.IsFeatureSupported(id)}
+		if feature.FeatureError() != nil {
+			entry.Error = feature.FeatureError().Error()
+		}
+		features = append(features, entry)
+		setupLog.Info(
+			"feature status",
+			"feature", feature.Name(),
+			"driver", feature.Driver(),
+			"error", feature.FeatureError(),
+			"available", power.IsFeatureSupported(id))
+		if !power.IsFeatureSupported(id) {
+			unavailableControls = append(unavailableControls, feature.Name())
+		}
+	}
+	sort.Strings(unavailableControls)
+	sort.Slice(features, func(i, j int) bool {
+		return features[i].Name < features[j].Name
+	})
+	setupLog.Info("node capabilities", "virtualized", virtualized, "unavailable", unavailableControls)
+
+	var poolHandoff *controllers.PoolHandoffReconciler
+	if options.HandoffStateFile != "" {
+		poolHandoff = &controllers.PoolHandoffReconciler{
+			Log:          ctrl.Log.WithName("controllers").WithName("PoolHandoff"),
+			PowerLibrary: powerLibrary,
+			NodeName:     options.NodeName,
+			Path:         options.HandoffStateFile,
+			Interval:     options.HandoffSaveInterval,
+		}
+		// the controllers would otherwise start from every core in the Reserved pool
+		if err = poolHandoff.Restore(); err != nil {
+			setupLog.Error(err, "unable to restore pools from the previous Node Agent, starting from defaults")
+		}
+	}
+
+	if options.Adopt {
+		adoption := &controllers.AdoptionReconciler{
+			Client:       mgr.GetClient(),
+			Reader:       mgr.GetAPIReader(),
+			Log:          ctrl.Log.WithName("controllers").WithName("Adoption"),
+			PowerLibrary: powerLibrary,
+			NodeName:     options.NodeName,
+			Settings:     adopt.NewReader(),
+		}
+		if err = adoption.Adopt(context.Background()); err != nil {
+			setupLog.Error(err, "unable to adopt the Node's existing settings")
+		}
+	}
+
+	realtimeKernel, err := realtime.Detect()
+	if err != nil {
+		setupLog.Info("unable to tell if the kernel is PREEMPT_RT, realtime profiles are refused", "error", err.Error())
+	}
+	setupLog.Info("kernel preemption", "realtime", realtimeKernel)
+
+	turboPresets, err := turbo.ReadPresets(turbo.MsrFile)
+	if err != nil {
+		setupLog.Info("turbo frequency presets unavailable", "error", err.Error())
+	} else {
+		setupLog.Info(
+			"turbo frequency presets",
+			turbo.SingleCoreTurbo, turboPresets.SingleCoreTurbo(),
+			turbo.AllCoreTurbo, turboPresets.AllCoreTurbo())
+	}
+
+	powerNodeState, err := podstate.NewState()
+	if err != nil {
+		return fmt.Errorf("unable to create internal state: %w", err)
+	}
+
+	podResourcesClient, err := podresourcesclient.NewCachingPodResourcesClient(options.PodResourcesCacheTTL)
+	if err != nil {
+		return fmt.Errorf("unable to create internal client: %w", err)
+	}
+
+	if err = controllers.SetupPowerWorkloadIndexer(context.Background(), mgr); err != nil {
+		return fmt.Errorf("unable to create field index %s: %w", controllers.WorkloadNodeNameIndex, err)
+	}
+
+	cpuDefaults, err := cpudefaults.Load(options.CpuDefaultsChannel)
+	if err != nil {
+		return fmt.Errorf("unable to load CPU defaults: %w", err)
+	}
+	if err = (&controllers.PowerProfileReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("PowerProfile"),
+		Scheme:         mgr.GetScheme(),
+		PowerLibrary:   powerLibrary,
+		RealtimeKernel: realtimeKernel,
+		CpuDefaults:    cpuDefaults,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerProfile controller: %w", err)
+	}
+	workloadProber, err := probe.NewProber(mgr.GetConfig())
+	if err != nil {
+		return fmt.Errorf("unable to create PowerWorkload validation prober: %w", err)
+	}
+	workloadProber.NodeName = options.NodeName
+	workloadProber.PrometheusURL = options.PrometheusURL
+	for _, namespace := range strings.Split(options.ExecNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			workloadProber.ExecNamespaces = append(workloadProber.ExecNamespaces, namespace)
+		}
+	}
+	for _, prefix := range strings.Split(options.HookURLPrefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			workloadProber.HookURLPrefixes = append(workloadProber.HookURLPrefixes, prefix)
+		}
+	}
+	var counterSampler controllers.CounterSampler
+	if options.ProfileVerificationWindow > 0 {
+		sampler, err := perf.NewSampler(options.ProfileVerificationWindow, controllers.BaseFrequencyFile)
+		if err != nil {
+			// PowerProfiles are still applied, just not verified
+			setupLog.Error(err, "unable to set up PowerProfile verification")
+		} else {
+			counterSampler = sampler
+		}
+	}
+	// enough of the recent plans to see what the last few reconciles did to the pools
+	plans := plan.NewHistory(50)
+	if err = (&controllers.PowerWorkloadReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("PowerWorkload"),
+		Scheme:       mgr.GetScheme(),
+		PowerLibrary: powerLibrary,
+		Prober:       workloadProber,
+		Hooks:        workloadProber,
+		Sampler:      counterSampler,
+		Plans:        plans,
+		Limits:       thermal.NewReader(),
+
+		TransitionPodDisruptionBudgets: options.TransitionPodDisruptionBudgets,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerWorkload controller: %w", err)
+	}
+	if err = (&controllers.PowerNodeReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("PowerNode"),
+		Scheme:       mgr.GetScheme(),
+		PowerLibrary: powerLibrary,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerNode controller: %w", err)
+	}
+	powerPodReconciler := &controllers.PowerPodReconciler{
+		Client:             mgr.GetClient(),
+		Log:                ctrl.Log.WithName("controllers").WithName("PowerPod"),
+		Scheme:             mgr.GetScheme(),
+		State:              powerNodeState,
+		PodResourcesClient: *podResourcesClient,
+		Cgroups:            cgroup.NewReader(),
+		Recorder:           mgr.GetEventRecorderFor("power-node-agent"),
+		Placer:             options.CorePlacer,
+	}
+	if err = powerPodReconciler.SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerPod controller: %w", err)
+	}
+	if err = (&controllers.StrictAdmissionReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("StrictAdmission"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("power-node-agent"),
+		Timeout:  options.StrictAdmissionTimeout,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create StrictAdmission controller: %w", err)
+	}
+	if err = (&controllers.CStatesReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("CState"),
+		Scheme:       mgr.GetScheme(),
+		PowerLibrary: powerLibrary,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create CStates controller: %w", err)
+	}
+	if err = (&controllers.TimeOfDayReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("TimeOfDay"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create TimeOfDay controller: %w", err)
+	}
+	if err = (&controllers.TimeOfDayCronJobReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("TimeOfDayCronJob"),
+		Scheme:       mgr.GetScheme(),
+		PowerLibrary: powerLibrary,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create TimeOfDayCronJob controller: %w", err)
+	}
+	if err = (&controllers.UncoreReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("Uncore"),
+		Scheme:       mgr.GetScheme(),
+		PowerLibrary: powerLibrary,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create Uncore controller: %w", err)
+	}
+	sharedPoolStepDown := &controllers.SharedPoolStepDownReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("SharedPoolStepDown"),
+		PowerLibrary: powerLibrary,
+		PowerSource:  rapl.NewReader(),
+		Interval:     options.SharedPoolStepInterval,
+	}
+	if err = (&controllers.PowerMaintenanceReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("PowerMaintenance"),
+		Scheme:         mgr.GetScheme(),
+		PowerLibrary:   powerLibrary,
+		SharedBaseline: sharedPoolStepDown,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerMaintenance controller: %w", err)
+	}
+	if err = (&controllers.NodeCleanupReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("NodeCleanup"),
+		PowerLibrary: powerLibrary,
+		Handoff:      poolHandoff,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create NodeCleanup controller: %w", err)
+	}
+	if err = (&controllers.NodeExclusionReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("NodeExclusion"),
+		PowerLibrary: powerLibrary,
+		Handoff:      poolHandoff,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create NodeExclusion controller: %w", err)
+	}
+	if err = (&controllers.WorkloadVerificationReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("WorkloadVerification"),
+		APIReader:    mgr.GetAPIReader(),
+		PowerLibrary: powerLibrary,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create WorkloadVerification controller: %w", err)
+	}
+	if err = (&controllers.NodeReadinessReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("NodeReadiness"),
+		Scheme:         mgr.GetScheme(),
+		PowerLibrary:   powerLibrary,
+		RealtimeKernel: realtimeKernel,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create NodeReadiness controller: %w", err)
+	}
+	if err = (&controllers.PerformanceProfileLevelReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("PerformanceProfileLevel"),
+		Scheme:       mgr.GetScheme(),
+		PowerLibrary: powerLibrary,
+		Switcher:     sst.NewPerfProfile(options.SpeedSelectTool),
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PerformanceProfileLevel controller: %w", err)
+	}
+	if err = mgr.Add(&controllers.PoolSanityReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("PoolSanity"),
+		PowerLibrary: powerLibrary,
+		Recorder:     mgr.GetEventRecorderFor("power-node-agent"),
+		Interval:     options.OrphanCheckInterval,
+	}); err != nil {
+		return fmt.Errorf("unable to create PoolSanity controller: %w", err)
+	}
+	if err = mgr.Add(&controllers.FrequencyDomainReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("FrequencyDomain"),
+		PowerLibrary: powerLibrary,
+		Domains:      freqdomain.NewReader(),
+		Recorder:     mgr.GetEventRecorderFor("power-node-agent"),
+		Interval:     options.FrequencyDomainInterval,
+	}); err != nil {
+		return fmt.Errorf("unable to create FrequencyDomain controller: %w", err)
+	}
+	if options.FrequencyJitterInterval > 0 {
+		if err = mgr.Add(&controllers.FrequencyJitterReconciler{
+			Client:       mgr.GetClient(),
+			Log:          ctrl.Log.WithName("controllers").WithName("FrequencyJitter"),
+			PowerLibrary: powerLibrary,
+			Source:       telemetry.NewReader(),
+			Tracker:      telemetry.NewJitterTracker(options.FrequencyJitterWindow),
+			Interval:     options.FrequencyJitterInterval,
+			Threshold:    options.FrequencyJitterThreshold,
+			Recorder:     mgr.GetEventRecorderFor("power-node-agent"),
+		}); err != nil {
+			return fmt.Errorf("unable to create FrequencyJitter controller: %w", err)
+		}
+	}
+	if options.OutageCheckInterval > 0 {
+		if err = mgr.Add(&controllers.ControlPlaneOutageReconciler{
+			Reader:       mgr.GetAPIReader(),
+			Log:          ctrl.Log.WithName("controllers").WithName("ControlPlaneOutage"),
+			PowerLibrary: powerLibrary,
+			Interval:     options.OutageCheckInterval,
+		}); err != nil {
+			return fmt.Errorf("unable to create ControlPlaneOutage controller: %w", err)
+		}
+	}
+	if err = mgr.Add(sharedPoolStepDown); err != nil {
+		return fmt.Errorf("unable to create SharedPoolStepDown controller: %w", err)
+	}
+	thermalReader := thermal.NewReader()
+	if err = mgr.Add(&controllers.SharedPoolTurboBudgetReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("SharedPoolTurboBudget"),
+		PowerLibrary: powerLibrary,
+		Cores:        thermalReader,
+		Capper:       thermalReader,
+		Interval:     options.SharedPoolTurboInterval,
+	}); err != nil {
+		return fmt.Errorf("unable to create SharedPoolTurboBudget controller: %w", err)
+	}
+	if err = mgr.Add(&controllers.ThermalTargetReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("ThermalTarget"),
+		PowerLibrary: powerLibrary,
+		Sensor:       thermalReader,
+		Capper:       thermalReader,
+		Gains:        thermal.DefaultGains,
+		Interval:     options.ThermalInterval,
+	}); err != nil {
+		return fmt.Errorf("unable to create ThermalTarget controller: %w", err)
+	}
+	if err = mgr.Add(&controllers.NetworkBoostReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("NetworkBoost"),
+		PowerLibrary: powerLibrary,
+		Source:       nicstats.NewReader(),
+		Floor:        thermalReader,
+		Interval:     options.NetworkBoostInterval,
+	}); err != nil {
+		return fmt.Errorf("unable to create NetworkBoost controller: %w", err)
+	}
+	if err = mgr.Add(&controllers.ThrottleDemotionReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("ThrottleDemotion"),
+		Counter:  thermalReader,
+		Recorder: mgr.GetEventRecorderFor("power-node-agent"),
+		Interval: options.ThrottleInterval,
+	}); err != nil {
+		return fmt.Errorf("unable to create ThrottleDemotion controller: %w", err)
+	}
+	if err = mgr.Add(&controllers.DesirabilityScoreReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("DesirabilityScore"),
+		PowerLibrary: powerLibrary,
+		Sensor:       thermalReader,
+	}); err != nil {
+		return fmt.Errorf("unable to create DesirabilityScore controller: %w", err)
+	}
+	var telemetryStream *telemetrystream.Server
+	if options.TelemetryStreamAddr != "" {
+		telemetryStream = &telemetrystream.Server{
+			Log:  ctrl.Log.WithName("telemetryStream"),
+			Addr: options.TelemetryStreamAddr,
+		}
+		if err = mgr.Add(telemetryStream); err != nil {
+			return fmt.Errorf("unable to create telemetry stream: %w", err)
+		}
+	}
+	if options.OTLPEndpoint != "" {
+		headers, err := otlp.ParseHeaders(options.OTLPHeaders)
+		if err != nil {
+			return fmt.Errorf("unable to create OTLP exporter: %w", err)
+		}
+		if err = mgr.Add(&otlp.Exporter{
+			Log:         ctrl.Log.WithName("otlp"),
+			Endpoint:    options.OTLPEndpoint,
+			Interval:    options.OTLPInterval,
+			Headers:     headers,
+			ServiceName: "power-node-agent",
+			NodeName:    options.NodeName,
+			Gatherer:    metrics.Registry,
+		}); err != nil {
+			return fmt.Errorf("unable to create OTLP exporter: %w", err)
+		}
+	}
+	powerTelemetry := &controllers.PowerTelemetryReconciler{
+		Client:                   mgr.GetClient(),
+		Log:                      ctrl.Log.WithName("controllers").WithName("PowerTelemetry"),
+		APIReader:                mgr.GetAPIReader(),
+		Interval:                 options.PowerTelemetryInterval,
+		FastInterval:             options.PowerTelemetryFastInterval,
+		IdleInterval:             options.PowerTelemetryIdleInterval,
+		PackageSource:            rapl.NewReader(),
+		RedfishCredentialsSecret: options.RedfishCredentialsSecret,
+		RedfishInsecure:          options.RedfishInsecure,
+	}
+	if options.IPMITool != "" {
+		powerTelemetry.FallbackSource = ipmi.NewDCMIReader(options.IPMITool)
+	}
+	if telemetryStream != nil {
+		// a nil *Server in the interface would not compare equal to nil
+		powerTelemetry.Publisher = telemetryStream
+	}
+	if err = mgr.Add(powerTelemetry); err != nil {
+		return fmt.Errorf("unable to create PowerTelemetry controller: %w", err)
+	}
+	if err = mgr.Add(&controllers.BIOSSettingsReconciler{
+		Client:                   mgr.GetClient(),
+		Log:                      ctrl.Log.WithName("controllers").WithName("BIOSSettings"),
+		APIReader:                mgr.GetAPIReader(),
+		Interval:                 options.BiosSettingsInterval,
+		RedfishCredentialsSecret: options.RedfishCredentialsSecret,
+		RedfishInsecure:          options.RedfishInsecure,
+	}); err != nil {
+		return fmt.Errorf("unable to create BIOSSettings controller: %w", err)
+	}
+	if poolHandoff != nil {
+		if err = mgr.Add(poolHandoff); err != nil {
+			return fmt.Errorf("unable to create PoolHandoff controller: %w", err)
+		}
+	}
+	if options.EnableRecommendations {
+		var interference *telemetry.InterferenceDetector
+		if options.DetectSharedInterference {
+			interference = telemetry.NewInterferenceDetector()
+		}
+		if err = mgr.Add(&controllers.PowerRecommendationReconciler{
+			Client:         mgr.GetClient(),
+			Log:            ctrl.Log.WithName("controllers").WithName("PowerRecommendation"),
+			PowerLibrary:   powerLibrary,
+			Source:         telemetry.NewReader(),
+			Window:         telemetry.NewWindow(time.Hour, options.RecommendationWindow),
+			SampleInterval: options.TelemetrySampleInterval,
+			ReportInterval: options.RecommendationInterval,
+			Percentile:     options.RecommendationPercentile,
+			Interference:   interference,
+			Recorder:       mgr.GetEventRecorderFor("power-node-agent"),
+			DetectBusyPoll: options.DetectBusyPoll,
+		}); err != nil {
+			return fmt.Errorf("unable to create PowerRecommendation controller: %w", err)
+		}
+	}
+	if err = addNodeCapability(mgr, virtualized, unavailableControls); err != nil {
+		return err
+	}
+	if options.DebugSocket != "" {
+		if err = mgr.Add(&diagnostics.Server{
+			Endpoint: options.DebugSocket,
+			Log:      ctrl.Log.WithName("diagnostics"),
+			Dumpers: map[string]diagnostics.StateDumper{
+				"pods":  func() interface{} { return powerPodReconciler.State.Pods() },
+				"pools": func() interface{} { return dumpPools(powerLibrary) },
+				"plans": func() interface{} { return plans.Plans() },
+				"capabilities": func() interface{} {
+					return capabilityDump{Virtualized: virtualized, UnavailableControls: unavailableControls, Features: features}
+				},
+			},
+		}); err != nil {
+			return fmt.Errorf("unable to create debug server: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func addNodeCapability(mgr ctrl.Manager, virtualized bool, unavailableControls []string) error {
+	var cpuModel *powerv1.CPUModel
+	family, model, err := cpudefaults.ReadCPUModel()
+	if err != nil {
+		setupLog.Info("unable to read the CPU model, PowerProfiles applied here aren't recorded as validated on it", "error", err.Error())
+	} else {
+		// the name is only for reading, the family and model identify the CPU
+		name, _ := cpudefaults.ReadCPUModelName()
+		cpuModel = &powerv1.CPUModel{Family: family, Model: model, Name: name}
+	}
+
+	if err := (&controllers.NodeCapabilityReconciler{
+		Client:              mgr.GetClient(),
+		Log:                 ctrl.Log.WithName("controllers").WithName("NodeCapability"),
+		Virtualized:         virtualized,
+		UnavailableControls: unavailableControls,
+		CPUModel:            cpuModel,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create NodeCapability controller: %w", err)
+	}
+
+	return nil
+}
+
+// capabilityDump is the Node's capability matrix, which power features the Power Library found and why the
+// unavailable ones aren't
+type capabilityDump struct {
+	Virtualized         bool          `json:"virtualized"`
+	UnavailableControls []string      `json:"unavailableControls,omitempty"`
+	Features            []featureDump `json:"features"`
+}
+
+type featureDump struct {
+	Name      string `json:"name"`
+	Driver    string `json:"driver,omitempty"`
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
+}
+
+type poolDump struct {
+	Name    string `json:"name"`
+	Profile string `json:"profile,omitempty"`
+	Cpus    []uint `json:"cpus"`
+}
+
+// dumpPools lists every pool in the Power Library with its profile and cores
+func dumpPools(powerLibrary power.Host) []poolDump {
+	var dump []poolDump
+	// the reconcilers move CPUs between the pools while the dump is taken
+	poollock.Read(func() {
+		pools := []power.Pool{powerLibrary.GetReservedPool(), powerLibrary.GetSharedPool()}
+		pools = append(pools, *powerLibrary.GetAllExclusivePools()...)
+
+		dump = make([]poolDump, 0, len(pools))
+		for _, pool := range pools {
+			entry := poolDump{
+				Name: pool.Name(),
+				Cpus: pool.Cpus().IDs(),
+			}
+			if profile := pool.GetPowerProfile(); profile != nil {
+				entry.Profile = profile.Name()
+			}
+			dump = append(dump, entry)
+		}
+	})
+
+	return dump
+}
@@ -0,0 +1,52 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// NewCache returns the cache for the Power Operator's manager. With a NodeCacheSelector only the Nodes matching it
+// are watched, so huge clusters don't have to keep every Node in memory. Nodes outside the selector can't be read
+// by the controllers at all, so it has to match at least every Node the PowerConfig's powerNodeSelector does
+func NewCache(options Options) (cache.NewCacheFunc, error) {
+	if options.NodeCacheSelector == "" {
+		return cache.New, nil
+	}
+	selector, err := labels.Parse(options.NodeCacheSelector)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the node cache selector: %w", err)
+	}
+
+	return cache.BuilderWithOptions(cache.Options{
+		SelectorsByObject: cache.SelectorsByObject{
+			&corev1.Node{}: {Label: selector},
+		},
+	}), nil
+}
+
+// NewAgentCache returns the cache for the Node Agent's manager. The agent only ever reads the Node it runs on, so
+// its Node informer is limited to that Node by name rather than holding every Node of the cluster
+func NewAgentCache(options AgentOptions) cache.NewCacheFunc {
+	return cache.BuilderWithOptions(cache.Options{
+		SelectorsByObject: cache.SelectorsByObject{
+			&corev1.Node{}: {Field: fields.OneTermEqualSelector("metadata.name", options.NodeName)},
+		},
+	})
+}
+
+// prewarmNodeCache starts the Node informer with the cache, so the manager waits for the Nodes to be synced before
+// any controller runs rather than the first reconcile reading them blocking on it
+func prewarmNodeCache(mgr ctrl.Manager) error {
+	_, err := mgr.GetCache().GetInformer(context.Background(), &corev1.Node{})
+	if err != nil {
+		return fmt.Errorf("unable to start the Node informer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,340 @@
+// Package operator adds the Power Operator's controllers, or the Node Agent's, to a manager the caller owns, so
+// platform teams can ship them in one binary with their own controllers
+package operator
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/controllers"
+	"github.com/intel/kubernetes-power-manager/pkg/agentpods"
+	"github.com/intel/kubernetes-power-manager/pkg/dryrun"
+	"github.com/intel/kubernetes-power-manager/pkg/extender"
+	"github.com/intel/kubernetes-power-manager/pkg/profileorder"
+	"github.com/intel/kubernetes-power-manager/pkg/savings"
+	"github.com/intel/kubernetes-power-manager/pkg/state"
+	"github.com/intel/kubernetes-power-manager/pkg/stats"
+	"github.com/intel/kubernetes-power-manager/pkg/strictadmission"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetrystream"
+)
+
+// SavingsReportConfigMap is the ConfigMap in the intel-power namespace the energy savings reports are written to
+const SavingsReportConfigMap = "power-savings-report"
+
+// Options configures the Power Operator's controllers. The manager itself, its metrics, webhooks, leader election
+// and client limits, is left to the caller
+type Options struct {
+	// SchedulerExtenderAddr is the address the scheduler extender binds to, disabled if empty
+	SchedulerExtenderAddr string
+	// StatsAddr is the address the JSON stats endpoint binds to, disabled if empty
+	StatsAddr     string
+	StatsInterval time.Duration
+	StatsHistory  int
+	// EnableWebhooks serves the admission webhooks, which need the manager's webhook server to have a certificate
+	EnableWebhooks bool
+	// NodeCacheSelector is a label selector limiting the Nodes the manager's cache holds, see NewCache
+	NodeCacheSelector string
+	// TelemetryStreamPort is the port the Node Agents stream their power readings on, 0 if they don't
+	TelemetryStreamPort   int
+	TelemetryResyncPeriod time.Duration
+	// SavingsReportPeriod is how much time each energy savings report covers, disabled if 0
+	SavingsReportPeriod   time.Duration
+	SavingsReportInterval time.Duration
+	// SyncPeriod is how often the manager's cache resyncs every object, it is passed to the manager by the caller
+	SyncPeriod              time.Duration
+	MaxConcurrentReconciles int
+	// DryRun sends the controllers' writes as dry runs, the OperatorConfig switches it while running
+	DryRun bool
+	// MetricsBindAddress is the caller's metrics address, kept to tell when the OperatorConfig changes it
+	MetricsBindAddress string
+	// NodeAgentManifest is the DaemonSet manifest the Node Agent is deployed from
+	NodeAgentManifest string
+}
+
+// DefaultOptions returns the Options the Power Operator runs with when no flags are given
+func DefaultOptions() Options {
+	return Options{
+		StatsInterval: time.Minute,
+		StatsHistory:  60,
+
+		TelemetryResyncPeriod: time.Minute,
+
+		SavingsReportInterval: 5 * time.Minute,
+
+		SyncPeriod:              10 * time.Hour,
+		MaxConcurrentReconciles: 1,
+
+		NodeAgentManifest: controllers.NodeAgentDaemonSetPath,
+	}
+}
+
+// BindFlags registers the Power Operator's flags, defaulting to the current values
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.SchedulerExtenderAddr, "scheduler-extender-addr", o.SchedulerExtenderAddr,
+		"The address the scheduler extender for label advertised PowerProfile capacity binds to. Disabled if empty.")
+	fs.StringVar(&o.StatsAddr, "stats-addr", o.StatsAddr,
+		"The address the JSON stats endpoint for dashboards binds to. Disabled if empty.")
+	fs.DurationVar(&o.StatsInterval, "stats-interval", o.StatsInterval,
+		"How often the cluster's power is sampled for the energy trend of the stats endpoint.")
+	fs.IntVar(&o.StatsHistory, "stats-history", o.StatsHistory, "How many samples the energy trend of the stats endpoint keeps.")
+	fs.BoolVar(&o.EnableWebhooks, "enable-webhooks", o.EnableWebhooks,
+		"Serve the admission webhooks, such as the one keeping PowerProfiles in the PowerConfig's profileOrdering and the one adding the strict admission readiness gate to Pods.")
+	fs.StringVar(&o.NodeCacheSelector, "node-cache-selector", o.NodeCacheSelector,
+		"Label selector limiting the Nodes kept in memory, such as the PowerConfig's powerNodeSelector. All Nodes if empty.")
+	fs.IntVar(&o.TelemetryStreamPort, "telemetry-stream-port", o.TelemetryStreamPort,
+		"The port of the Node Agents' --telemetry-stream-addr, their power readings are subscribed to and used by the stats endpoint. Disabled if 0.")
+	fs.DurationVar(&o.TelemetryResyncPeriod, "telemetry-resync-period", o.TelemetryResyncPeriod,
+		"How often the telemetry streams are checked against the Node Agent Pods, on top of following the Pod events.")
+	fs.DurationVar(&o.SavingsReportPeriod, "savings-report-period", o.SavingsReportPeriod,
+		"How much time each report of the energy saved against the Nodes running at max covers, such as 168h for weekly reports. Disabled if 0.")
+	fs.DurationVar(&o.SavingsReportInterval, "savings-report-interval", o.SavingsReportInterval,
+		"How often the Nodes' power is sampled for the energy savings report.")
+	fs.DurationVar(&o.SyncPeriod, "sync-period", o.SyncPeriod,
+		"How often every watched object is reconciled again. Overridden by the OperatorConfig's resyncPeriodSeconds.")
+	fs.IntVar(&o.MaxConcurrentReconciles, "max-concurrent-reconciles", o.MaxConcurrentReconciles,
+		"How many objects of each kind the controllers reconcile at once. Overridden by the OperatorConfig's maxConcurrentReconciles.")
+	fs.BoolVar(&o.DryRun, "dry-run", o.DryRun,
+		"Send every change the controllers make as a dry run, validated but not persisted. Overridden by the OperatorConfig's dryRun.")
+	fs.StringVar(&o.NodeAgentManifest, "node-agent-manifest", o.NodeAgentManifest,
+		"The DaemonSet manifest the Node Agent is deployed from, such as /power-manifests/power-node-agent-restricted-ds.yaml to run it unprivileged with the write helper.")
+}
+
+// LoadConfig reads the OperatorConfig from the intel-power namespace, nil if there is none or its CRD isn't installed
+func LoadConfig(ctx context.Context, reader client.Reader) (*powerv1.OperatorConfig, error) {
+	config := &powerv1.OperatorConfig{}
+	err := reader.Get(ctx, client.ObjectKey{Name: powerv1.OperatorConfigName, Namespace: controllers.IntelPowerNamespace}, config)
+	if err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// ApplyConfig overrides the options with the settings the OperatorConfig sets, the rest keep their flag values
+func (o *Options) ApplyConfig(spec powerv1.OperatorConfigSpec) {
+	if spec.ResyncPeriodSeconds > 0 {
+		o.SyncPeriod = time.Duration(spec.ResyncPeriodSeconds) * time.Second
+	}
+	if spec.MaxConcurrentReconciles > 0 {
+		o.MaxConcurrentReconciles = spec.MaxConcurrentReconciles
+	}
+	if spec.DryRun != nil {
+		o.DryRun = *spec.DryRun
+	}
+	if spec.MetricsBindAddress != "" {
+		o.MetricsBindAddress = spec.MetricsBindAddress
+	}
+}
+
+// startedConfig is the OperatorConfig spec equivalent of the options the manager started with
+func (o *Options) startedConfig() powerv1.OperatorConfigSpec {
+	dryRun := o.DryRun
+	return powerv1.OperatorConfigSpec{
+		ResyncPeriodSeconds:     int(o.SyncPeriod / time.Second),
+		MaxConcurrentReconciles: o.MaxConcurrentReconciles,
+		DryRun:                  &dryRun,
+		MetricsBindAddress:      o.MetricsBindAddress,
+	}
+}
+
+// AddToScheme adds the Kubernetes and power.intel.com types the controllers use to the scheme
+func AddToScheme(scheme *runtime.Scheme) error {
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+
+	return powerv1.AddToScheme(scheme)
+}
+
+// AddToManager adds the Power Operator's controllers, and the scheduler extender, stats endpoint, savings reports and
+// webhooks when enabled, to the manager. The manager's scheme needs the types from AddToScheme, its cache should come
+// from NewCache and the controllers only run on the leader
+func AddToManager(mgr ctrl.Manager, options Options) error {
+	if err := prewarmNodeCache(mgr); err != nil {
+		return err
+	}
+	writeClient := dryrun.NewClient(mgr.GetClient())
+	writeClient.SetEnabled(options.DryRun)
+	if options.NodeAgentManifest != "" {
+		controllers.NodeAgentDaemonSetPath = options.NodeAgentManifest
+	}
+	if err := (&controllers.OperatorConfigReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("OperatorConfig"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("power-operator"),
+		DryRun:   writeClient,
+		Started:  options.startedConfig(),
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create OperatorConfig controller: %w", err)
+	}
+	if err := (&controllers.PowerConfigReconciler{
+		Client:                  writeClient,
+		Log:                     ctrl.Log.WithName("controllers").WithName("PowerConfig"),
+		Scheme:                  mgr.GetScheme(),
+		State:                   state.NewPowerNodeData(),
+		Recorder:                mgr.GetEventRecorderFor("power-operator"),
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerConfig controller: %w", err)
+	}
+	if err := (&controllers.PowerParkingReconciler{
+		Client:                  writeClient,
+		DryRun:                  writeClient,
+		Log:                     ctrl.Log.WithName("controllers").WithName("PowerParking"),
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("power-operator"),
+		APIReader:               mgr.GetAPIReader(),
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerParking controller: %w", err)
+	}
+	if err := (&controllers.AgentlessPoolReconciler{
+		Client:                  writeClient,
+		DryRun:                  writeClient,
+		Log:                     ctrl.Log.WithName("controllers").WithName("AgentlessPool"),
+		Scheme:                  mgr.GetScheme(),
+		APIReader:               mgr.GetAPIReader(),
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create AgentlessPool controller: %w", err)
+	}
+	if err := (&controllers.DemandResponseReconciler{
+		Client:                  writeClient,
+		Log:                     ctrl.Log.WithName("controllers").WithName("DemandResponse"),
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("power-operator"),
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create DemandResponse controller: %w", err)
+	}
+	if err := (&controllers.ProfileDeadlineReconciler{
+		Client:                  writeClient,
+		Log:                     ctrl.Log.WithName("controllers").WithName("ProfileDeadline"),
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("power-operator"),
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create ProfileDeadline controller: %w", err)
+	}
+	if err := (&controllers.ProfileCompatibilityReconciler{
+		Client:                  writeClient,
+		Log:                     ctrl.Log.WithName("controllers").WithName("ProfileCompatibility"),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create ProfileCompatibility controller: %w", err)
+	}
+	if err := (&controllers.PowerNodeGroupReconciler{
+		Client:                  writeClient,
+		Log:                     ctrl.Log.WithName("controllers").WithName("PowerNodeGroup"),
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("power-operator"),
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerNodeGroup controller: %w", err)
+	}
+	if err := (&controllers.PowerWorkloadSetReconciler{
+		Client:                  writeClient,
+		Log:                     ctrl.Log.WithName("controllers").WithName("PowerWorkloadSet"),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create PowerWorkloadSet controller: %w", err)
+	}
+	if options.EnableWebhooks {
+		if err := ctrl.NewWebhookManagedBy(mgr).
+			For(&powerv1.PowerProfile{}).
+			WithValidator(&profileorder.Validator{Client: mgr.GetClient()}).
+			Complete(); err != nil {
+			return fmt.Errorf("unable to create PowerProfile webhook: %w", err)
+		}
+		if err := ctrl.NewWebhookManagedBy(mgr).
+			For(&corev1.Pod{}).
+			WithDefaulter(&strictadmission.Defaulter{Client: mgr.GetClient()}).
+			Complete(); err != nil {
+			return fmt.Errorf("unable to create Pod strict admission webhook: %w", err)
+		}
+	}
+	if options.SchedulerExtenderAddr != "" {
+		if err := mgr.Add(&extender.Server{
+			Client:         mgr.GetClient(),
+			Log:            ctrl.Log.WithName("schedulerExtender"),
+			Addr:           options.SchedulerExtenderAddr,
+			ResourcePrefix: controllers.ExtendedResourcePrefix,
+			LabelPrefix:    controllers.CapacityLabelPrefix,
+		}); err != nil {
+			return fmt.Errorf("unable to create scheduler extender: %w", err)
+		}
+	}
+	var collector *telemetrystream.Collector
+	if options.TelemetryStreamPort != 0 {
+		agentSelector := labels.SelectorFromSet(labels.Set{
+			controllers.NodeAgentPodLabel: controllers.NodeAgentPodLabelValue,
+		})
+		agents := agentpods.NewRegistry(controllers.IntelPowerNamespace, agentSelector)
+		if err := agents.Register(context.Background(), mgr.GetCache()); err != nil {
+			return fmt.Errorf("unable to create Node Agent Pod registry: %w", err)
+		}
+		collector = &telemetrystream.Collector{
+			Reader:         mgr.GetAPIReader(),
+			Log:            ctrl.Log.WithName("telemetryStream"),
+			Name:           "power-operator",
+			Namespace:      controllers.IntelPowerNamespace,
+			Selector:       agentSelector,
+			Port:           options.TelemetryStreamPort,
+			ResyncInterval: options.TelemetryResyncPeriod,
+			Agents:         agents,
+		}
+		if err := mgr.Add(collector); err != nil {
+			return fmt.Errorf("unable to create telemetry collector: %w", err)
+		}
+	}
+	if options.StatsAddr != "" {
+		statsServer := &stats.Server{
+			Client:         mgr.GetClient(),
+			Log:            ctrl.Log.WithName("stats"),
+			Addr:           options.StatsAddr,
+			Interval:       options.StatsInterval,
+			History:        options.StatsHistory,
+			Namespace:      controllers.IntelPowerNamespace,
+			ResourcePrefix: controllers.ExtendedResourcePrefix,
+			LabelPrefix:    controllers.CapacityLabelPrefix,
+		}
+		if collector != nil {
+			statsServer.Live = collector
+		}
+		if err := mgr.Add(statsServer); err != nil {
+			return fmt.Errorf("unable to create stats endpoint: %w", err)
+		}
+	}
+	if options.SavingsReportPeriod > 0 {
+		if err := mgr.Add(&savings.Reporter{
+			Client:    writeClient,
+			Log:       ctrl.Log.WithName("savingsReport"),
+			Namespace: controllers.IntelPowerNamespace,
+			Name:      SavingsReportConfigMap,
+			Interval:  options.SavingsReportInterval,
+			Period:    options.SavingsReportPeriod,
+		}); err != nil {
+			return fmt.Errorf("unable to create savings reporter: %w", err)
+		}
+	}
+
+	return nil
+}
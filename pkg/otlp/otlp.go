@@ -0,0 +1,286 @@
+// Package otlp pushes the Power Manager's metrics to an OpenTelemetry collector over OTLP/HTTP, for pipelines that
+// don't scrape Prometheus endpoints. The metrics are read from the Prometheus registry they're already kept in and
+// sent in OTLP's JSON encoding, so no OpenTelemetry SDK is needed
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// MetricsPath is where the collector's OTLP/HTTP receiver takes metrics, appended to endpoints without a path
+	MetricsPath = "/v1/metrics"
+	// MetricPrefix picks the Power Manager's own metrics out of the registry
+	MetricPrefix = "power_"
+
+	scopeName = "github.com/intel/kubernetes-power-manager"
+
+	// aggregationTemporalityCumulative is how Prometheus counters count, from when the process started
+	aggregationTemporalityCumulative = 2
+)
+
+// Exporter sends the power and frequency metrics to the collector on every interval
+type Exporter struct {
+	Log logr.Logger
+	// Endpoint is the collector's OTLP/HTTP URL, such as http://otel-collector:4318
+	Endpoint string
+	Interval time.Duration
+	// Headers are added to every request, for the collector's authentication
+	Headers map[string]string
+	// ServiceName and NodeName are the resource attributes the metrics are reported under
+	ServiceName string
+	NodeName    string
+	Gatherer    prometheus.Gatherer
+	// Client defaults to an http.Client with a timeout of the interval
+	Client *http.Client
+
+	start time.Time
+}
+
+// Start exports the metrics on the interval until the context is cancelled
+func (e *Exporter) Start(ctx context.Context) error {
+	e.start = time.Now()
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := e.Export(ctx)
+			if err != nil {
+				e.Log.Error(err, "error exporting metrics to the OpenTelemetry collector", "endpoint", e.Endpoint)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false as every process exports its own metrics
+func (e *Exporter) NeedLeaderElection() bool {
+	return false
+}
+
+// Export sends the current value of the metrics to the collector once
+func (e *Exporter) Export(ctx context.Context) error {
+	families, err := e.Gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	start := e.start
+	if start.IsZero() {
+		start = now
+	}
+	metrics := convert(families, start, now)
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	request := exportRequest{ResourceMetrics: []resourceMetrics{{
+		Resource: resource{Attributes: e.resourceAttributes()},
+		ScopeMetrics: []scopeMetrics{{
+			Scope:   scope{Name: scopeName},
+			Metrics: metrics,
+		}},
+	}}}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, metricsURL(e.Endpoint), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	for name, value := range e.Headers {
+		httpRequest.Header.Set(name, value)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: e.Interval}
+	}
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("collector answered %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
+
+func (e *Exporter) resourceAttributes() []keyValue {
+	attributes := []keyValue{stringAttribute("service.name", e.ServiceName)}
+	if e.NodeName != "" {
+		attributes = append(attributes, stringAttribute("k8s.node.name", e.NodeName))
+	}
+
+	return attributes
+}
+
+// ParseHeaders reads headers given as comma separated name=value pairs, as OTEL_EXPORTER_OTLP_HEADERS takes them
+func ParseHeaders(headers string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, pair := range strings.Split(headers, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected name=value", pair)
+		}
+		parsed[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	return parsed, nil
+}
+
+// metricsURL appends the metrics path to an endpoint given without one
+func metricsURL(endpoint string) string {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	schemeEnd := strings.Index(endpoint, "://")
+	if schemeEnd >= 0 && !strings.Contains(endpoint[schemeEnd+3:], "/") {
+		return endpoint + MetricsPath
+	}
+
+	return endpoint
+}
+
+// convert turns the Power Manager's gauges and counters into OTLP metrics, other metric types aren't exported
+func convert(families []*dto.MetricFamily, start time.Time, now time.Time) []metric {
+	var metrics []metric
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), MetricPrefix) {
+			continue
+		}
+		var points []dataPoint
+		for _, m := range family.GetMetric() {
+			point := dataPoint{Attributes: labelAttributes(m.GetLabel()), TimeUnixNano: unixNano(now)}
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				point.AsDouble = m.GetGauge().GetValue()
+			case dto.MetricType_UNTYPED:
+				point.AsDouble = m.GetUntyped().GetValue()
+			case dto.MetricType_COUNTER:
+				point.AsDouble = m.GetCounter().GetValue()
+				point.StartTimeUnixNano = unixNano(start)
+			default:
+				continue
+			}
+			points = append(points, point)
+		}
+		if len(points) == 0 {
+			continue
+		}
+
+		exported := metric{Name: family.GetName(), Description: family.GetHelp()}
+		if family.GetType() == dto.MetricType_COUNTER {
+			exported.Sum = &sum{
+				DataPoints:             points,
+				AggregationTemporality: aggregationTemporalityCumulative,
+				IsMonotonic:            true,
+			}
+		} else {
+			exported.Gauge = &gauge{DataPoints: points}
+		}
+		metrics = append(metrics, exported)
+	}
+
+	return metrics
+}
+
+func labelAttributes(labels []*dto.LabelPair) []keyValue {
+	attributes := make([]keyValue, 0, len(labels))
+	for _, label := range labels {
+		attributes = append(attributes, stringAttribute(label.GetName(), label.GetValue()))
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Key < attributes[j].Key
+	})
+
+	return attributes
+}
+
+// unixNano is a timestamp as OTLP's JSON encoding takes 64 bit integers, a decimal string
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// The OTLP ExportMetricsServiceRequest in its JSON encoding, only with what the exporter sends
+
+type exportRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Gauge       *gauge `json:"gauge,omitempty"`
+	Sum         *sum   `json:"sum,omitempty"`
+}
+
+type gauge struct {
+	DataPoints []dataPoint `json:"dataPoints"`
+}
+
+type sum struct {
+	DataPoints             []dataPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality"`
+	IsMonotonic            bool        `json:"isMonotonic"`
+}
+
+type dataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsDouble          float64    `json:"asDouble"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func stringAttribute(key string, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: value}}
+}
@@ -0,0 +1,123 @@
+package parking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/intel/kubernetes-power-manager/pkg/redfish"
+)
+
+const (
+	PowerStateOff     = "off"
+	PowerStateSuspend = "suspend"
+
+	ActionPark = "park"
+	ActionWake = "wake"
+)
+
+// PowerControl puts whole Nodes into a low power state and brings them back
+type PowerControl interface {
+	Park(ctx context.Context, node *corev1.Node, powerState string) error
+	Wake(ctx context.Context, node *corev1.Node) error
+}
+
+// Redfish parks Nodes by shutting the host down through its BMC and wakes them by powering it on.
+// Redfish has no reset type for suspend, so only the off power state is supported
+type Redfish struct {
+	Username string
+	Password string
+	Insecure bool
+}
+
+func (r *Redfish) Park(ctx context.Context, node *corev1.Node, powerState string) error {
+	if powerState != PowerStateOff {
+		return fmt.Errorf("Redfish cannot put Nodes into the %s power state", powerState)
+	}
+	client, err := r.client(node)
+	if err != nil {
+		return err
+	}
+
+	return client.Reset(ctx, redfish.ResetGracefulShutdown)
+}
+
+func (r *Redfish) Wake(ctx context.Context, node *corev1.Node) error {
+	client, err := r.client(node)
+	if err != nil {
+		return err
+	}
+
+	return client.Reset(ctx, redfish.ResetOn)
+}
+
+func (r *Redfish) client(node *corev1.Node) (*redfish.Client, error) {
+	address := node.Annotations[redfish.BMCAddressAnnotation]
+	if address == "" {
+		return nil, fmt.Errorf("Node %s has no %s annotation", node.Name, redfish.BMCAddressAnnotation)
+	}
+
+	return redfish.NewClient(address, r.Username, r.Password, r.Insecure), nil
+}
+
+// Webhook hands parking and waking over to an external service, e.g. one driving IPMI or wake-on-LAN
+type Webhook struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// WebhookRequest is the JSON body posted to the webhook
+type WebhookRequest struct {
+	Node       string `json:"node"`
+	Action     string `json:"action"`
+	PowerState string `json:"powerState,omitempty"`
+	BMCAddress string `json:"bmcAddress,omitempty"`
+}
+
+func (w *Webhook) Park(ctx context.Context, node *corev1.Node, powerState string) error {
+	return w.post(ctx, WebhookRequest{
+		Node:       node.Name,
+		Action:     ActionPark,
+		PowerState: powerState,
+		BMCAddress: node.Annotations[redfish.BMCAddressAnnotation],
+	})
+}
+
+func (w *Webhook) Wake(ctx context.Context, node *corev1.Node) error {
+	return w.post(ctx, WebhookRequest{
+		Node:       node.Name,
+		Action:     ActionWake,
+		BMCAddress: node.Annotations[redfish.BMCAddressAnnotation],
+	})
+}
+
+func (w *Webhook) post(ctx context.Context, body WebhookRequest) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	httpClient := w.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s for %s of Node %s", w.URL, response.Status, body.Action, body.Node)
+	}
+
+	return nil
+}
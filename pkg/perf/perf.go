@@ -0,0 +1,65 @@
+package perf
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+)
+
+// Sample is what one CPU's counters measured over the sampling window
+type Sample struct {
+	CPU uint
+	// IPC is the instructions retired per cycle while the CPU wasn't idle
+	IPC float64
+	// FrequencyMHz is the average frequency while the CPU wasn't idle
+	FrequencyMHz int
+	// Busy is the share of the window the CPU wasn't idle, the counters of a mostly idle CPU say little
+	Busy float64
+}
+
+// Sampler reads the cycles, instructions and reference cycles counters of CPUs with perf_event_open
+type Sampler struct {
+	Window time.Duration
+	// BaseFrequencyMHz is the rate reference cycles are counted at
+	BaseFrequencyMHz int
+}
+
+// NewSampler returns a Sampler that counts over window, with the base frequency read in kHz from
+// baseFrequencyFile
+func NewSampler(window time.Duration, baseFrequencyFile string) (*Sampler, error) {
+	content, err := os.ReadFile(baseFrequencyFile)
+	if err != nil {
+		return nil, powererrors.NewHardwareUnsupported("base frequency", err)
+	}
+	kHz, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base frequency: %w", err)
+	}
+
+	return &Sampler{
+		Window:           window,
+		BaseFrequencyMHz: kHz / 1000,
+	}, nil
+}
+
+// sample works the counter deltas of one CPU out into a Sample. Reference cycles tick at the base frequency
+// while the CPU isn't halted, so their share of the window is how busy it was and the ratio of cycles to
+// them is how far above or below the base frequency it ran
+func (s *Sampler) sample(cpu uint, cycles, instructions, refCycles uint64) Sample {
+	sample := Sample{CPU: cpu}
+	if refCycles == 0 || cycles == 0 {
+		return sample
+	}
+
+	sample.IPC = float64(instructions) / float64(cycles)
+	sample.FrequencyMHz = int(float64(s.BaseFrequencyMHz) * float64(cycles) / float64(refCycles))
+	if windowCycles := float64(s.BaseFrequencyMHz) * 1e6 * s.Window.Seconds(); windowCycles > 0 {
+		sample.Busy = float64(refCycles) / windowCycles
+	}
+
+	return sample
+}
@@ -0,0 +1,105 @@
+//go:build linux
+// +build linux
+
+package perf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+)
+
+type cpuCounters struct {
+	cycles       int
+	instructions int
+	refCycles    int
+}
+
+// Sample counts on all cpus over one window. Platforms without a PMU, such as most VMs, return a
+// HardwareUnsupportedError
+func (s *Sampler) Sample(cpus []uint) ([]Sample, error) {
+	counters := make([]cpuCounters, 0, len(cpus))
+	defer func() {
+		for _, c := range counters {
+			unix.Close(c.cycles)
+			unix.Close(c.instructions)
+			unix.Close(c.refCycles)
+		}
+	}()
+
+	for _, cpu := range cpus {
+		var c cpuCounters
+		var err error
+		if c.cycles, err = openCounter(cpu, unix.PERF_COUNT_HW_CPU_CYCLES); err != nil {
+			return nil, err
+		}
+		if c.instructions, err = openCounter(cpu, unix.PERF_COUNT_HW_INSTRUCTIONS); err != nil {
+			unix.Close(c.cycles)
+			return nil, err
+		}
+		if c.refCycles, err = openCounter(cpu, unix.PERF_COUNT_HW_REF_CPU_CYCLES); err != nil {
+			unix.Close(c.cycles)
+			unix.Close(c.instructions)
+			return nil, err
+		}
+		counters = append(counters, c)
+	}
+
+	start := make([][3]uint64, len(counters))
+	for i, c := range counters {
+		values, err := readCounters(c)
+		if err != nil {
+			return nil, err
+		}
+		start[i] = values
+	}
+	time.Sleep(s.Window)
+
+	samples := make([]Sample, 0, len(counters))
+	for i, c := range counters {
+		values, err := readCounters(c)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, s.sample(cpus[i], values[0]-start[i][0], values[1]-start[i][1], values[2]-start[i][2]))
+	}
+
+	return samples, nil
+}
+
+func openCounter(cpu uint, config uint64) (int, error) {
+	attr := &unix.PerfEventAttr{
+		Type:   unix.PERF_TYPE_HARDWARE,
+		Size:   uint32(unsafe.Sizeof(unix.PerfEventAttr{})),
+		Config: config,
+	}
+	fd, err := unix.PerfEventOpen(attr, -1, int(cpu), -1, unix.PERF_FLAG_FD_CLOEXEC)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.ENODEV) {
+			return -1, powererrors.NewHardwareUnsupported("perf counters", err)
+		}
+		return -1, fmt.Errorf("error opening perf counter on CPU %d: %w", cpu, err)
+	}
+
+	return fd, nil
+}
+
+func readCounters(c cpuCounters) ([3]uint64, error) {
+	var values [3]uint64
+	for i, fd := range []int{c.cycles, c.instructions, c.refCycles} {
+		buf := make([]byte, 8)
+		_, err := unix.Read(fd, buf)
+		if err != nil {
+			return values, err
+		}
+		values[i] = binary.LittleEndian.Uint64(buf)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package perf
+
+import (
+	"fmt"
+
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+)
+
+// Sample needs perf_event_open, which only Linux has
+func (s *Sampler) Sample(cpus []uint) ([]Sample, error) {
+	return nil, powererrors.NewHardwareUnsupported("perf counters", fmt.Errorf("perf_event_open is only available on Linux"))
+}
@@ -0,0 +1,142 @@
+// Package plan describes the changes a reconcile is about to make to a Node's pools as an ordered list of
+// operations, built before anything is touched, so what the Node Agent does can be logged, inspected and
+// compared before and after the fact
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// The operations a plan can hold, applied in the order they were added
+const (
+	// PreApplyHook runs the PowerWorkload's preApply hook, a failing hook stops the plan
+	PreApplyHook = "PreApplyHook"
+	// MoveToShared moves the CPUs from their exclusive pool back to the Shared pool
+	MoveToShared = "MoveToShared"
+	// MoveToPool moves the CPUs into the exclusive pool
+	MoveToPool = "MoveToPool"
+	// VerifyProfile samples the CPUs to check they run at the pool's PowerProfile
+	VerifyProfile = "VerifyProfile"
+	// PostApplyHook runs the PowerWorkload's postApply hook, a failing hook doesn't stop the plan
+	PostApplyHook = "PostApplyHook"
+	// RemovePool removes the exclusive pool, its CPUs go back to the Shared pool
+	RemovePool = "RemovePool"
+	// ClearSharedProfile takes the PowerProfile off the Shared pool
+	ClearSharedProfile = "ClearSharedProfile"
+	// SetReservedCPUs makes the CPUs the Reserved pool, every other CPU goes to the Shared pool
+	SetReservedCPUs = "SetReservedCPUs"
+)
+
+// Operation is one step of a plan
+type Operation struct {
+	Kind string `json:"kind"`
+	Pool string `json:"pool,omitempty"`
+	CPUs []uint `json:"cpus,omitempty"`
+}
+
+func (o Operation) String() string {
+	var b strings.Builder
+	b.WriteString(o.Kind)
+	if o.Pool != "" {
+		fmt.Fprintf(&b, " %s", o.Pool)
+	}
+	if len(o.CPUs) > 0 {
+		fmt.Fprintf(&b, " %v", o.CPUs)
+	}
+
+	return b.String()
+}
+
+// Plan is the ordered operations a reconcile applies to a Node for one PowerWorkload. Once applied, Applied
+// counts the operations that were carried out and Error is why the next one failed
+type Plan struct {
+	Node            string `json:"node"`
+	Workload        string `json:"workload"`
+	Profile         string `json:"profile,omitempty"`
+	PreviousProfile string `json:"previousProfile,omitempty"`
+	// CorrelationID is the ID of the PowerWorkload change the plan applies
+	CorrelationID string      `json:"correlationID,omitempty"`
+	Created       time.Time   `json:"created"`
+	Operations    []Operation `json:"operations"`
+	Applied       int         `json:"applied"`
+	Error         string      `json:"error,omitempty"`
+}
+
+func New(node string, workload string, now time.Time) *Plan {
+	return &Plan{
+		Node:       node,
+		Workload:   workload,
+		Created:    now.UTC(),
+		Operations: make([]Operation, 0),
+	}
+}
+
+// Add appends an operation to the plan
+func (p *Plan) Add(kind string, pool string, cpus []uint) {
+	p.Operations = append(p.Operations, Operation{Kind: kind, Pool: pool, CPUs: cpus})
+}
+
+// Empty reports whether the plan changes nothing
+func (p *Plan) Empty() bool {
+	return len(p.Operations) == 0
+}
+
+// Steps lists the operations one per line, numbered in the order they are applied
+func (p *Plan) Steps() []string {
+	steps := make([]string, 0, len(p.Operations))
+	for i, operation := range p.Operations {
+		steps = append(steps, fmt.Sprintf("%d. %s", i+1, operation))
+	}
+
+	return steps
+}
+
+// Log writes the plan as one record, an empty plan isn't logged
+func (p *Plan) Log(logger logr.Logger) {
+	if p.Empty() {
+		return
+	}
+	logger.Info("Apply plan",
+		"node", p.Node,
+		"workload", p.Workload,
+		"profile", p.Profile,
+		"previousProfile", p.PreviousProfile,
+		"operations", p.Steps())
+}
+
+// History keeps the most recent plans applied on the Node for the debug endpoint
+type History struct {
+	size  int
+	mutex sync.Mutex
+	plans []Plan
+}
+
+func NewHistory(size int) *History {
+	return &History{size: size}
+}
+
+// Record adds the plan, dropping the oldest beyond the History's size. Recording on a nil History does nothing
+func (h *History) Record(plan *Plan) {
+	if h == nil || plan.Empty() {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.plans = append(h.plans, *plan)
+	if h.size > 0 && len(h.plans) > h.size {
+		h.plans = h.plans[len(h.plans)-h.size:]
+	}
+}
+
+// Plans returns the recorded plans, oldest first
+func (h *History) Plans() []Plan {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return append(make([]Plan, 0, len(h.plans)), h.plans...)
+}
@@ -3,9 +3,11 @@ package podresourcesclient
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+	"github.com/intel/kubernetes-power-manager/pkg/metrics"
 	"github.com/intel/kubernetes-power-manager/pkg/util"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -20,11 +22,28 @@ var timeout = 2 * time.Minute
 // PodResourcesClient stores a client to the Kubelet PodResources API server
 type PodResourcesClient struct {
 	Client podresourcesapi.PodResourcesListerClient
+	// cache is shared by copies of the client, every lookup goes to the kubelet without one
+	cache *responseCache
+}
+
+// responseCache holds the last List response. The kubelet's v1 PodResources API has no Watch, so the response is
+// refreshed when it gets older than the TTL, when a container isn't in it yet, and when a Pod is invalidated
+type responseCache struct {
+	sync.Mutex
+	ttl      time.Duration
+	response *podresourcesapi.ListPodResourcesResponse
+	listed   time.Time
 }
 
 // NewPodResourcesClient returns a new client to the Kubelet PodResources API server
 func NewPodResourcesClient() (*PodResourcesClient, error) {
+	return NewCachingPodResourcesClient(0)
+}
+
+// NewCachingPodResourcesClient returns a client that reuses List responses for up to ttl, zero disables the cache
+func NewCachingPodResourcesClient(ttl time.Duration) (*PodResourcesClient, error) {
 	podResourcesClient := &PodResourcesClient{}
+	podResourcesClient.EnableCache(ttl)
 	client, err := getV1Client(socket, timeout, maxMessage)
 	if err != nil {
 		return podResourcesClient, errors.NewServiceUnavailable("failed to create podresouces client")
@@ -49,8 +68,18 @@ func getV1Client(socket string, connectionTimeout time.Duration, maxMsgSize int)
 	return podresourcesapi.NewPodResourcesListerClient(conn), nil
 }
 
+// EnableCache makes lookups reuse List responses for up to ttl, zero disables the cache
+func (p *PodResourcesClient) EnableCache(ttl time.Duration) {
+	if ttl <= 0 {
+		p.cache = nil
+		return
+	}
+	p.cache = &responseCache{ttl: ttl}
+}
+
 func (p *PodResourcesClient) listPodResources() (*podresourcesapi.ListPodResourcesResponse, error) {
 	req := podresourcesapi.ListPodResourcesRequest{}
+	metrics.PodResourcesLookups.WithLabelValues("kubelet").Inc()
 	resp, err := p.Client.List(context.TODO(), &req)
 	if err != nil {
 		fmt.Println("Can't receive response:", err)
@@ -61,10 +90,54 @@ func (p *PodResourcesClient) listPodResources() (*podresourcesapi.ListPodResourc
 
 // GetContainerCPUs returns a string in cpuset format of CPUs allocated to the container
 func (p *PodResourcesClient) GetContainerCPUs(podName, containerName string) (string, error) {
+	if p.cache == nil {
+		podresourcesResponse, err := p.listPodResources()
+		if err != nil {
+			return "", err
+		}
+		return findContainerCPUs(podresourcesResponse, podName, containerName)
+	}
+
+	p.cache.Lock()
+	defer p.cache.Unlock()
+	if p.cache.response != nil && time.Since(p.cache.listed) < p.cache.ttl {
+		cpus, err := findContainerCPUs(p.cache.response, podName, containerName)
+		if err == nil {
+			metrics.PodResourcesLookups.WithLabelValues("cache").Inc()
+			return cpus, nil
+		}
+	}
+
+	// the container is new or the response is stale
 	podresourcesResponse, err := p.listPodResources()
 	if err != nil {
 		return "", err
 	}
+	p.cache.response = podresourcesResponse
+	p.cache.listed = time.Now()
+	return findContainerCPUs(podresourcesResponse, podName, containerName)
+}
+
+// Invalidate drops the cached response if it holds the Pod, so a Pod recreated with the same name is looked up again
+func (p *PodResourcesClient) Invalidate(podName string) {
+	if p.cache == nil {
+		return
+	}
+
+	p.cache.Lock()
+	defer p.cache.Unlock()
+	if p.cache.response == nil {
+		return
+	}
+	for _, podresource := range p.cache.response.PodResources {
+		if podresource.Name == podName {
+			p.cache.response = nil
+			return
+		}
+	}
+}
+
+func findContainerCPUs(podresourcesResponse *podresourcesapi.ListPodResourcesResponse, podName, containerName string) (string, error) {
 	for _, podresource := range podresourcesResponse.PodResources {
 		if podresource.Name == podName {
 			for _, container := range podresource.Containers {
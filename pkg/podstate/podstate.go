@@ -1,10 +1,15 @@
 package podstate
 
 import (
+	"sync"
+
 	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
 )
 
+// State is the Node Agent's record of the Guaranteed Pods given exclusive CPUs. It's shared by the reconcilers and
+// the debug endpoint, so GuaranteedPods is only to be touched through the methods, which hold the State's lock
 type State struct {
+	mutex          sync.RWMutex
 	GuaranteedPods []powerv1.GuaranteedPod
 }
 
@@ -17,6 +22,8 @@ func NewState() (*State, error) {
 }
 
 func (s *State) UpdateStateGuaranteedPods(guaranteedPod powerv1.GuaranteedPod) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	for i, existingPod := range s.GuaranteedPods {
 		if existingPod.Name == guaranteedPod.Name {
 			s.GuaranteedPods[i] = guaranteedPod
@@ -29,15 +36,29 @@ func (s *State) UpdateStateGuaranteedPods(guaranteedPod powerv1.GuaranteedPod) e
 }
 
 func (s *State) GetPodFromState(podName string) powerv1.GuaranteedPod {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	for _, existingPod := range s.GuaranteedPods {
 		if existingPod.Name == podName {
-			return existingPod
+			return *existingPod.DeepCopy()
 		}
 	}
 
 	return powerv1.GuaranteedPod{}
 }
 
+// Pods returns a copy of the Guaranteed Pods, which can be read while the reconcilers keep changing the State
+func (s *State) Pods() []powerv1.GuaranteedPod {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	pods := make([]powerv1.GuaranteedPod, 0, len(s.GuaranteedPods))
+	for i := range s.GuaranteedPods {
+		pods = append(pods, *s.GuaranteedPods[i].DeepCopy())
+	}
+
+	return pods
+}
+
 func (s *State) GetCPUsFromPodState(podState powerv1.GuaranteedPod) []uint {
 	cpus := make([]uint, 0)
 	for _, container := range podState.Containers {
@@ -48,6 +69,8 @@ func (s *State) GetCPUsFromPodState(podState powerv1.GuaranteedPod) []uint {
 }
 
 func (s *State) DeletePodFromState(podName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	for i, pod := range s.GuaranteedPods {
 		if pod.Name == podName {
 			s.GuaranteedPods = append(s.GuaranteedPods[:i], s.GuaranteedPods[i+1:]...)
@@ -0,0 +1,26 @@
+// Package poollock serializes the changes the Node Agent makes to the Power Library's pools, which doesn't lock
+// them itself, with the readers walking the pools from other goroutines, such as the debug endpoint
+package poollock
+
+import (
+	"sync"
+)
+
+var mutex sync.RWMutex
+
+// Change makes a change to the pools, such as moving CPUs or setting a pool's PowerProfile, while nothing else
+// changes or reads them
+func Change(change func() error) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return change()
+}
+
+// Read reads the pools while nothing changes them
+func Read(read func()) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	read()
+}
@@ -0,0 +1,350 @@
+// Package preflight checks a cluster has what the Power Manager needs before users enable enforcement: its CRDs,
+// the RBAC of its service accounts, a running Node Agent on every selected Node, the power drivers and the
+// kubelet's static CPU Manager policy on a sample of those Nodes
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+const (
+	ResultPass = "pass"
+	// ResultWarn is a check that couldn't be made, such as one the user running it isn't allowed to
+	ResultWarn = "warn"
+	ResultFail = "fail"
+)
+
+// Check is the outcome of one check, of one Node where Node is set
+type Check struct {
+	Name    string `json:"name"`
+	Node    string `json:"node,omitempty"`
+	Result  string `json:"result"`
+	Message string `json:"message"`
+}
+
+// KubeletConfigReader reads the configuration a Node's kubelet is running with, as its /configz endpoint serves it
+type KubeletConfigReader func(ctx context.Context, node string) ([]byte, error)
+
+// Options are what the checks are made against
+type Options struct {
+	// Namespace the Power Manager runs in
+	Namespace string
+	// OperatorServiceAccount and AgentServiceAccount are the names of the manager's and Node Agents' service
+	// accounts in the namespace
+	OperatorServiceAccount string
+	AgentServiceAccount    string
+	// AgentSelector finds the Node Agent Pods
+	AgentSelector labels.Selector
+	// SampleSize is how many of the selected Nodes have their drivers and kubelet checked
+	SampleSize int
+	// KubeletConfig reads the kubelet configuration of the sampled Nodes, the CPU Manager isn't checked if nil
+	KubeletConfig KubeletConfigReader
+}
+
+// permission is an API request a service account must be allowed to make
+type permission struct {
+	group       string
+	resource    string
+	subresource string
+	verb        string
+	// namespaced permissions are checked in the Power Manager's namespace
+	namespaced bool
+}
+
+// operatorPermissions and agentPermissions are a sample of what the manager and Node Agents do, enough to find a
+// missing or out of date binding without checking every rule
+var (
+	operatorPermissions = []permission{
+		{group: powerv1.GroupVersion.Group, resource: "powerconfigs", verb: "watch", namespaced: true},
+		{group: powerv1.GroupVersion.Group, resource: "powerprofiles", verb: "create", namespaced: true},
+		{group: powerv1.GroupVersion.Group, resource: "powernodes", verb: "update", namespaced: true},
+		{group: "apps", resource: "daemonsets", verb: "create", namespaced: true},
+		{resource: "nodes", verb: "watch"},
+		{resource: "pods", verb: "list"},
+	}
+	agentPermissions = []permission{
+		{group: powerv1.GroupVersion.Group, resource: "powerworkloads", verb: "update", namespaced: true},
+		{group: powerv1.GroupVersion.Group, resource: "powernodes", subresource: "status", verb: "update", namespaced: true},
+		{resource: "nodes", subresource: "status", verb: "patch"},
+		{resource: "pods", verb: "list"},
+	}
+)
+
+// crdKinds are the kinds whose CRDs have to be installed for the manager to start
+var crdKinds = []string{
+	"PowerConfig", "PowerProfile", "PowerWorkload", "PowerNode", "PowerPod", "CStates", "Uncore", "TimeOfDay",
+	"TimeOfDayCronJob", "OperatorConfig",
+}
+
+// Run makes every check and returns their outcomes in the order they were made. Checks that fail don't stop the
+// others, an error is only returned where the Nodes to check can't be listed
+func Run(ctx context.Context, c client.Client, options Options) ([]Check, error) {
+	checks := make([]Check, 0)
+	checks = append(checks, checkCRDs(c)...)
+	checks = append(checks, checkRBAC(ctx, c, options.Namespace, options.OperatorServiceAccount, operatorPermissions)...)
+	checks = append(checks, checkRBAC(ctx, c, options.Namespace, options.AgentServiceAccount, agentPermissions)...)
+
+	nodes, err := selectedNodes(ctx, c, options.Namespace)
+	if err != nil {
+		return checks, err
+	}
+	agents, err := checkAgents(ctx, c, options, nodes)
+	if err != nil {
+		return checks, err
+	}
+	checks = append(checks, agents...)
+
+	sample := nodes
+	if options.SampleSize > 0 && len(sample) > options.SampleSize {
+		sample = sample[:options.SampleSize]
+	}
+	for i := range sample {
+		checks = append(checks, checkDrivers(ctx, c, options.Namespace, sample[i].Name))
+		if options.KubeletConfig != nil {
+			checks = append(checks, checkCPUManager(ctx, options.KubeletConfig, sample[i].Name))
+		}
+	}
+
+	return checks, nil
+}
+
+// Passed reports whether none of the checks failed, warnings don't count
+func Passed(checks []Check) bool {
+	for _, check := range checks {
+		if check.Result == ResultFail {
+			return false
+		}
+	}
+
+	return true
+}
+
+func checkCRDs(c client.Client) []Check {
+	checks := make([]Check, 0, len(crdKinds))
+	for _, kind := range crdKinds {
+		check := Check{Name: "crd/" + strings.ToLower(kind), Result: ResultPass, Message: "installed"}
+		_, err := c.RESTMapper().RESTMapping(schema.GroupKind{Group: powerv1.GroupVersion.Group, Kind: kind}, powerv1.GroupVersion.Version)
+		if meta.IsNoMatchError(err) {
+			check.Result = ResultFail
+			check.Message = fmt.Sprintf("the %s CRD isn't installed", kind)
+		} else if err != nil {
+			check.Result = ResultWarn
+			check.Message = err.Error()
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func checkRBAC(ctx context.Context, c client.Client, namespace string, serviceAccount string, permissions []permission) []Check {
+	user := fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount)
+	checks := make([]Check, 0, len(permissions))
+	for _, permission := range permissions {
+		name := permission.resource
+		if permission.subresource != "" {
+			name += "/" + permission.subresource
+		}
+		check := Check{Name: fmt.Sprintf("rbac/%s/%s/%s", serviceAccount, permission.verb, name), Result: ResultPass, Message: "allowed"}
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   user,
+				Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"},
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Group:       permission.group,
+					Resource:    permission.resource,
+					Subresource: permission.subresource,
+					Verb:        permission.verb,
+				},
+			},
+		}
+		if permission.namespaced {
+			review.Spec.ResourceAttributes.Namespace = namespace
+		}
+		err := c.Create(ctx, review)
+		switch {
+		case err != nil:
+			check.Result = ResultWarn
+			check.Message = fmt.Sprintf("couldn't be checked: %v", err)
+		case !review.Status.Allowed:
+			check.Result = ResultFail
+			check.Message = "denied"
+			if review.Status.Reason != "" {
+				check.Message += ": " + review.Status.Reason
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// selectedNodes are the Nodes the PowerConfig's powerNodeSelector selects, every Node if there is no PowerConfig or
+// it has no selector, sorted by name
+func selectedNodes(ctx context.Context, c client.Client, namespace string) ([]corev1.Node, error) {
+	configs := &powerv1.PowerConfigList{}
+	err := c.List(ctx, configs, client.InNamespace(namespace))
+	if err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("listing PowerConfigs: %w", err)
+	}
+	var selector map[string]string
+	if len(configs.Items) > 0 {
+		selector = configs.Items[0].Spec.PowerNodeSelector
+	}
+
+	nodes := &corev1.NodeList{}
+	err = c.List(ctx, nodes, client.MatchingLabels(selector))
+	if err != nil {
+		return nil, fmt.Errorf("listing Nodes: %w", err)
+	}
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+
+	return nodes.Items, nil
+}
+
+// checkAgents passes every Node with a ready Node Agent Pod and fails each one without
+func checkAgents(ctx context.Context, c client.Client, options Options, nodes []corev1.Node) ([]Check, error) {
+	pods := &corev1.PodList{}
+	err := c.List(ctx, pods, client.InNamespace(options.Namespace), client.MatchingLabelsSelector{Selector: options.AgentSelector})
+	if err != nil {
+		return nil, fmt.Errorf("listing Node Agent Pods: %w", err)
+	}
+	ready := make(map[string]bool)
+	for i := range pods.Items {
+		if podReady(&pods.Items[i]) {
+			ready[pods.Items[i].Spec.NodeName] = true
+		}
+	}
+
+	if len(nodes) == 0 {
+		return []Check{{Name: "agents", Result: ResultFail, Message: "no Nodes are selected for power management"}}, nil
+	}
+	checks := make([]Check, 0)
+	for _, node := range nodes {
+		if !ready[node.Name] {
+			checks = append(checks, Check{Name: "agent", Node: node.Name, Result: ResultFail, Message: "no ready Node Agent Pod"})
+		}
+	}
+	if len(checks) == 0 {
+		checks = append(checks, Check{Name: "agents", Result: ResultPass, Message: fmt.Sprintf("a Node Agent is ready on all %d selected Nodes", len(nodes))})
+	}
+
+	return checks, nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// checkDrivers fails a Node whose Node Agent found power controls missing, such as cpufreq without a scaling
+// driver or MSRs without the msr module
+func checkDrivers(ctx context.Context, c client.Client, namespace string, node string) Check {
+	check := Check{Name: "drivers", Node: node, Result: ResultPass, Message: "every power control is available"}
+	powerNode := &powerv1.PowerNode{}
+	err := c.Get(ctx, client.ObjectKey{Name: node, Namespace: namespace}, powerNode)
+	if client.IgnoreNotFound(err) != nil {
+		check.Result = ResultWarn
+		check.Message = fmt.Sprintf("couldn't be checked: %v", err)
+		return check
+	}
+	switch {
+	case err != nil || powerNode.Status.CapabilityProfile == "":
+		check.Result = ResultWarn
+		check.Message = "the Node Agent hasn't probed the Node yet"
+	case len(powerNode.Status.UnavailableControls) > 0:
+		check.Result = ResultFail
+		check.Message = "unavailable: " + strings.Join(powerNode.Status.UnavailableControls, ", ")
+	}
+
+	return check
+}
+
+// checkCPUManager fails a Node whose kubelet doesn't run the static CPU Manager policy, without it no Pod gets
+// exclusive CPUs for a PowerProfile to be applied to
+func checkCPUManager(ctx context.Context, read KubeletConfigReader, node string) Check {
+	check := Check{Name: "cpu-manager", Node: node, Result: ResultPass, Message: "static policy"}
+	data, err := read(ctx, node)
+	if err != nil {
+		check.Result = ResultWarn
+		check.Message = fmt.Sprintf("couldn't be checked: %v", err)
+		return check
+	}
+	configz := struct {
+		KubeletConfig struct {
+			CPUManagerPolicy string `json:"cpuManagerPolicy"`
+		} `json:"kubeletconfig"`
+	}{}
+	err = json.Unmarshal(data, &configz)
+	if err != nil {
+		check.Result = ResultWarn
+		check.Message = fmt.Sprintf("couldn't be checked: %v", err)
+		return check
+	}
+	if policy := configz.KubeletConfig.CPUManagerPolicy; policy != "static" {
+		if policy == "" {
+			policy = "none"
+		}
+		check.Result = ResultFail
+		check.Message = fmt.Sprintf("%s policy, the kubelet needs --cpu-manager-policy=static", policy)
+	}
+
+	return check
+}
+
+// KubeletConfigz reads a Node's kubelet configuration through the API server's proxy to the Node
+func KubeletConfigz(clientset kubernetes.Interface) KubeletConfigReader {
+	return func(ctx context.Context, node string) ([]byte, error) {
+		return clientset.CoreV1().RESTClient().Get().Resource("nodes").Name(node).SubResource("proxy").
+			Suffix("configz").DoRaw(ctx)
+	}
+}
+
+// Print writes the checks as a table followed by the overall outcome
+func Print(w io.Writer, checks []Check) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tNODE\tRESULT\tMESSAGE")
+	for _, check := range checks {
+		node := check.Node
+		if node == "" {
+			node = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", check.Name, node, strings.ToUpper(check.Result), check.Message)
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	if Passed(checks) {
+		_, err = fmt.Fprintln(w, "\nPreflight passed")
+	} else {
+		_, err = fmt.Fprintln(w, "\nPreflight failed")
+	}
+
+	return err
+}
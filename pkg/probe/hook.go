@@ -0,0 +1,104 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/correlation"
+)
+
+const (
+	HookPreApply  = "preApply"
+	HookPostApply = "postApply"
+
+	// defaultHookTimeout is how long a hook without a timeout is given
+	defaultHookTimeout = 10 * time.Second
+)
+
+// HookEvent describes the change to a PowerWorkload a hook is run for, webhooks are sent it as JSON
+type HookEvent struct {
+	Hook     string `json:"hook"`
+	Workload string `json:"workload"`
+	Node     string `json:"node"`
+	// The PowerProfile of the PowerWorkload once the change is made
+	Profile string `json:"profile"`
+	// The PowerProfile applied before the change, empty the first time a PowerProfile is applied
+	PreviousProfile string `json:"previousProfile,omitempty"`
+	AddedCPUs       []uint `json:"addedCPUs,omitempty"`
+	RemovedCPUs     []uint `json:"removedCPUs,omitempty"`
+	// The ID of the PowerWorkload change, also sent as the X-Correlation-ID header
+	CorrelationID string `json:"correlationID,omitempty"`
+}
+
+// RunHook runs a PowerWorkload hook, returning an error when it fails or can't be run. Commands run in the
+// container the hook names through the Pod exec API, never on the Node itself, so they only have the access
+// the container already has
+func (p *Prober) RunHook(ctx context.Context, hook *powerv1.WorkloadHook, event HookEvent) error {
+	timeout := defaultHookTimeout
+	if hook.TimeoutSeconds > 0 {
+		timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if hook.Exec != nil {
+		passed, message, err := p.exec(ctx, hook.Exec)
+		if err != nil {
+			return err
+		}
+		if !passed {
+			return fmt.Errorf("%s hook %s", event.Hook, message)
+		}
+		return nil
+	}
+	if hook.URL != "" {
+		if err := p.allowCallout(hook.URL); err != nil {
+			return fmt.Errorf("%s hook %w", event.Hook, err)
+		}
+		return p.callout(ctx, hook.URL, event)
+	}
+
+	return fmt.Errorf("%s hook has neither an exec command nor a URL", event.Hook)
+}
+
+// allowCallout checks the URL starts with one of the agent's hook URL prefixes. The agent runs privileged on every
+// Node, a PowerWorkload mustn't be able to have it POST to any address it can reach
+func (p *Prober) allowCallout(address string) error {
+	for _, prefix := range p.HookURLPrefixes {
+		if strings.HasPrefix(address, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("URL %s doesn't start with one of the Node Agent's hook URL prefixes", address)
+}
+
+func (p *Prober) callout(ctx context.Context, address string, event HookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if event.CorrelationID != "" {
+		request.Header.Set(correlation.Header, event.CorrelationID)
+	}
+	response, err := p.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s hook answered with status %d", event.Hook, response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package probe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+// Latencies are the wakeup latencies a verification probe measured, in microseconds
+type Latencies struct {
+	P50 int
+	P99 int
+	Max int
+}
+
+// cyclictest's histogram has a line per microsecond with the number of wakeups that took that long, the
+// percentiles are read off it once the run ends
+const cyclictestScript = `cyclictest -q -m -p 95 -t 1 -a -D %d -h 10000 | awk '
+/^#/ { if ($2 == "Max" && $3 == "Latencies:") max = $4 + 0; next }
+{ count[$1 + 0] = $2 + 0; total += $2; if ($1 + 0 > last) last = $1 + 0 }
+function pct(p,   l, n) { for (l = 0; l <= last; l++) { n += count[l]; if (n >= total * p) return l } return last }
+END { if (total == 0) exit 1; printf "p50=%%d p99=%%d max=%%d", pct(0.5), pct(0.99), max }
+' > /dev/termination-log`
+
+// stress-ng reports the percentiles of its cyclic stressor in nanoseconds
+const stressNgScript = `stress-ng --cyclic 1 --cyclic-policy fifo --cyclic-prio 95 --cyclic-method clock_ns --timeout %ds 2>&1 | awk '
+/cyclic: +50\.00%%:/ { p50 = $(NF-1) }
+/cyclic: +99\.00%%:/ { p99 = $(NF-1) }
+/cyclic: +min:/ { for (i = 1; i < NF; i++) if ($i == "max:") max = $(i+1) }
+END { if (p99 == "") exit 1; printf "p50=%%d p99=%%d max=%%d", p50 / 1000, p99 / 1000, max / 1000 }
+' > /dev/termination-log`
+
+// LatencyScript returns the shell script a probe container runs for the tool, it writes the latencies to the
+// container's termination message
+func LatencyScript(tool string, seconds int) string {
+	if tool == powerv1.VerificationToolStressNg {
+		return fmt.Sprintf(stressNgScript, seconds)
+	}
+
+	return fmt.Sprintf(cyclictestScript, seconds)
+}
+
+// ParseLatencies reads the latencies from a probe container's termination message
+func ParseLatencies(message string) (Latencies, error) {
+	values := make(map[string]int)
+	for _, field := range strings.Fields(message) {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return Latencies{}, fmt.Errorf("invalid %s latency %q", key, value)
+		}
+		values[key] = number
+	}
+	for _, key := range []string{"p50", "p99", "max"} {
+		if _, found := values[key]; !found {
+			return Latencies{}, fmt.Errorf("probe reported no %s latency: %q", key, message)
+		}
+	}
+
+	return Latencies{P50: values["p50"], P99: values["p99"], Max: values["max"]}, nil
+}
@@ -0,0 +1,231 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+// Prober evaluates the Validation of a PowerWorkload, either by querying Prometheus or by
+// running a command in a container
+type Prober struct {
+	HTTPClient *http.Client
+	Config     *rest.Config
+	Clientset  kubernetes.Interface
+	// NodeName is the Node the agent runs on, commands are only run in Pods on it
+	NodeName string
+	// ExecNamespaces are the namespaces commands may be run in, none if empty
+	ExecNamespaces []string
+	// HookURLPrefixes are the prefixes a hook URL must start with, hooks can't call out without one
+	HookURLPrefixes []string
+	// PrometheusURL is the Prometheus server queries are sent to, queries fail without one
+	PrometheusURL string
+}
+
+func NewProber(config *rest.Config) (*Prober, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Prober{
+		HTTPClient: http.DefaultClient,
+		Config:     config,
+		Clientset:  clientset,
+	}, nil
+}
+
+// Probe returns whether the validation passed and a message describing the result. An error is
+// returned when the probe could not be evaluated at all
+func (p *Prober) Probe(ctx context.Context, validation *powerv1.WorkloadValidation) (bool, string, error) {
+	if validation.Exec != nil {
+		return p.exec(ctx, validation.Exec)
+	}
+	if validation.PrometheusQuery != "" {
+		// the address comes from the agent, PowerWorkloads can't point it at any URL it reaches
+		if p.PrometheusURL == "" {
+			return false, "", fmt.Errorf("no Prometheus server is configured for the Node Agent")
+		}
+		if validation.PrometheusURL != "" && strings.TrimSuffix(validation.PrometheusURL, "/") != strings.TrimSuffix(p.PrometheusURL, "/") {
+			return false, "", fmt.Errorf("prometheusURL %s isn't the Prometheus server configured for the Node Agent", validation.PrometheusURL)
+		}
+		return p.query(ctx, p.PrometheusURL, validation.PrometheusQuery)
+	}
+
+	return false, "", fmt.Errorf("validation has neither a Prometheus query nor an exec command")
+}
+
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (p *Prober) query(ctx context.Context, address string, query string) (bool, string, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(address, "/") + "/api/v1/query")
+	if err != nil {
+		return false, "", err
+	}
+	endpoint.RawQuery = url.Values{"query": []string{query}}.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return false, "", err
+	}
+	response, err := p.HTTPClient.Do(request)
+	if err != nil {
+		return false, "", err
+	}
+	defer response.Body.Close()
+
+	result := &queryResponse{}
+	err = json.NewDecoder(response.Body).Decode(result)
+	if err != nil {
+		return false, "", fmt.Errorf("decoding Prometheus response: %w", err)
+	}
+	if result.Status != "success" {
+		return false, "", fmt.Errorf("Prometheus query failed: %s", result.Error)
+	}
+
+	values, err := queryValues(result.Data.ResultType, result.Data.Result)
+	if err != nil {
+		return false, "", err
+	}
+	if len(values) == 0 {
+		return false, fmt.Sprintf("query '%s' returned no data", query), nil
+	}
+	for _, value := range values {
+		if value == 0 {
+			return false, fmt.Sprintf("query '%s' returned 0", query), nil
+		}
+	}
+
+	return true, fmt.Sprintf("query '%s' returned %v", query, values), nil
+}
+
+// queryValues extracts the sample values from a scalar or instant vector result
+func queryValues(resultType string, raw json.RawMessage) ([]float64, error) {
+	var samples [][]interface{}
+	switch resultType {
+	case "scalar":
+		var sample []interface{}
+		err := json.Unmarshal(raw, &sample)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	case "vector":
+		var series []struct {
+			Value []interface{} `json:"value"`
+		}
+		err := json.Unmarshal(raw, &series)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range series {
+			samples = append(samples, s.Value)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Prometheus result type '%s'", resultType)
+	}
+
+	values := make([]float64, 0, len(samples))
+	for _, sample := range samples {
+		// samples are [timestamp, "value"]
+		if len(sample) != 2 {
+			return nil, fmt.Errorf("malformed Prometheus sample %v", sample)
+		}
+		str, ok := sample[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("malformed Prometheus sample %v", sample)
+		}
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// allowExec checks the command is for a Pod on the agent's own Node in a namespace commands may be run in. The
+// agent can exec into every Pod in the cluster, a PowerWorkload is only trusted with its own Node
+func (p *Prober) allowExec(ctx context.Context, probe *powerv1.ExecProbe) error {
+	allowed := false
+	for _, namespace := range p.ExecNamespaces {
+		if namespace == probe.Namespace {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("commands can't be run in namespace %s, it isn't one of the Node Agent's exec namespaces", probe.Namespace)
+	}
+
+	pod, err := p.Clientset.CoreV1().Pods(probe.Namespace).Get(ctx, probe.Pod, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if pod.Spec.NodeName != p.NodeName {
+		return fmt.Errorf("pod %s/%s isn't on Node %s", probe.Namespace, probe.Pod, p.NodeName)
+	}
+
+	return nil
+}
+
+func (p *Prober) exec(ctx context.Context, probe *powerv1.ExecProbe) (bool, string, error) {
+	err := p.allowExec(ctx, probe)
+	if err != nil {
+		return false, "", err
+	}
+
+	request := p.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(probe.Namespace).
+		Name(probe.Pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: probe.Container,
+			Command:   probe.Command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(p.Config, http.MethodPost, request.URL())
+	if err != nil {
+		return false, "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if exitError, ok := err.(utilexec.ExitError); ok {
+			return false, fmt.Sprintf("command exited with %d: %s", exitError.ExitStatus(), strings.TrimSpace(stderr.String())), nil
+		}
+		return false, "", err
+	}
+
+	return true, fmt.Sprintf("command succeeded: %s", strings.TrimSpace(stdout.String())), nil
+}
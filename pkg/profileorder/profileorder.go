@@ -0,0 +1,113 @@
+package profileorder
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+// DefaultOrdering is used when no PowerConfig declares a profileOrdering
+var DefaultOrdering = []string{"gold", "silver", "bronze"}
+
+// Validator rejects PowerProfiles whose frequencies would invert the ordering declared in the PowerConfig, so
+// a profile meant to be faster never ends up slower than one ordered after it
+type Validator struct {
+	Client client.Reader
+}
+
+//+kubebuilder:webhook:path=/validate-power-intel-com-v1-powerprofile,mutating=false,failurePolicy=fail,sideEffects=None,groups=power.intel.com,resources=powerprofiles,verbs=create;update,versions=v1,name=vpowerprofile.power.intel.com,admissionReviewVersions=v1
+
+// ValidateCreate checks a new PowerProfile against the other PowerProfiles in its namespace
+func (v *Validator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return v.validate(ctx, obj)
+}
+
+// ValidateUpdate checks the updated PowerProfile against the other PowerProfiles in its namespace
+func (v *Validator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return v.validate(ctx, newObj)
+}
+
+// ValidateDelete allows every deletion, removing a profile can't invert the ordering of the others
+func (v *Validator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (v *Validator) validate(ctx context.Context, obj runtime.Object) error {
+	profile, ok := obj.(*powerv1.PowerProfile)
+	if !ok {
+		return fmt.Errorf("expected a PowerProfile, got %T", obj)
+	}
+
+	ordering, err := v.ordering(ctx, profile.Namespace)
+	if err != nil {
+		return err
+	}
+	profiles := &powerv1.PowerProfileList{}
+	err = v.Client.List(ctx, profiles, client.InNamespace(profile.Namespace))
+	if err != nil {
+		return fmt.Errorf("listing PowerProfiles: %w", err)
+	}
+	others := make([]powerv1.PowerProfile, 0, len(profiles.Items))
+	for _, other := range profiles.Items {
+		if other.Name != profile.Name {
+			others = append(others, other)
+		}
+	}
+
+	return Check(ordering, profile, others)
+}
+
+// ordering is the first profileOrdering declared in a PowerConfig, DefaultOrdering if there is none
+func (v *Validator) ordering(ctx context.Context, namespace string) ([]string, error) {
+	configs := &powerv1.PowerConfigList{}
+	err := v.Client.List(ctx, configs, client.InNamespace(namespace))
+	if err != nil {
+		return nil, fmt.Errorf("listing PowerConfigs: %w", err)
+	}
+	for _, config := range configs.Items {
+		if len(config.Spec.ProfileOrdering) > 0 {
+			return config.Spec.ProfileOrdering, nil
+		}
+	}
+
+	return DefaultOrdering, nil
+}
+
+// Check compares the profile's frequencies, in MHz, with those of the other profiles in the ordering. Profiles
+// missing from the ordering aren't checked, and neither is a frequency that is unset or, for the max frequency,
+// given as a preset each Node resolves for itself, as there is no MHz value to compare
+func Check(ordering []string, profile *powerv1.PowerProfile, others []powerv1.PowerProfile) error {
+	rank := make(map[string]int, len(ordering))
+	for i, name := range ordering {
+		rank[name] = i
+	}
+	profileRank, ordered := rank[profile.Spec.Name]
+	if !ordered {
+		return nil
+	}
+
+	for _, other := range others {
+		otherRank, ordered := rank[other.Spec.Name]
+		if !ordered || otherRank == profileRank {
+			continue
+		}
+		faster, slower := &other.Spec, &profile.Spec
+		if profileRank < otherRank {
+			faster, slower = slower, faster
+		}
+		if faster.MaxPreset == "" && slower.MaxPreset == "" && faster.Max > 0 && faster.Max < slower.Max {
+			return fmt.Errorf("PowerProfile %s is ordered before %s but its max frequency of %dMHz is below %dMHz",
+				faster.Name, slower.Name, faster.Max, slower.Max)
+		}
+		if faster.Min > 0 && faster.Min < slower.Min {
+			return fmt.Errorf("PowerProfile %s is ordered before %s but its min frequency of %dMHz is below %dMHz",
+				faster.Name, slower.Name, faster.Min, slower.Min)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package rapl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const PowercapPath = "/sys/class/powercap"
+
+type zone struct {
+	path        string
+	maxRange    uint64
+	energy      uint64
+	lastSampled time.Time
+}
+
+// Reader works out package power in watts from the RAPL energy counters exposed through powercap
+type Reader struct {
+	PowercapPath string
+
+	mutex sync.Mutex
+	zones []*zone
+	now   func() time.Time
+}
+
+func NewReader() *Reader {
+	return &Reader{
+		PowercapPath: PowercapPath,
+		now:          time.Now,
+	}
+}
+
+// Watts returns the average power drawn by all packages since the previous call. The first call
+// only primes the counters and returns 0
+func (r *Reader) Watts() (float64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.zones == nil {
+		zones, err := r.findPackageZones()
+		if err != nil {
+			return 0, err
+		}
+		r.zones = zones
+	}
+
+	var watts float64
+	now := r.now()
+	for _, z := range r.zones {
+		energy, err := readUint(filepath.Join(z.path, "energy_uj"))
+		if err != nil {
+			return 0, err
+		}
+
+		if !z.lastSampled.IsZero() && now.After(z.lastSampled) {
+			delta := energy - z.energy
+			if energy < z.energy {
+				// the counter wrapped around
+				delta = z.maxRange - z.energy + energy
+			}
+			watts += float64(delta) / 1e6 / now.Sub(z.lastSampled).Seconds()
+		}
+		z.energy = energy
+		z.lastSampled = now
+	}
+
+	return watts, nil
+}
+
+// findPackageZones returns the top level intel-rapl zones, one per package. Sub-zones (core,
+// uncore, dram) are left out as the package zone already includes them
+func (r *Reader) findPackageZones() ([]*zone, error) {
+	paths, err := filepath.Glob(filepath.Join(r.PowercapPath, "intel-rapl:*"))
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]*zone, 0)
+	for _, path := range paths {
+		if strings.Count(filepath.Base(path), ":") != 1 {
+			continue
+		}
+		name, err := os.ReadFile(filepath.Join(path, "name"))
+		if err != nil || !strings.HasPrefix(strings.TrimSpace(string(name)), "package") {
+			continue
+		}
+		maxRange, err := readUint(filepath.Join(path, "max_energy_range_uj"))
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, &zone{path: path, maxRange: maxRange})
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no RAPL package zones found in %s", r.PowercapPath)
+	}
+
+	return zones, nil
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
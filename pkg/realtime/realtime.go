@@ -0,0 +1,31 @@
+package realtime
+
+import (
+	"os"
+	"strings"
+)
+
+var (
+	// RealtimeFile is only present on kernels built with PREEMPT_RT and holds 1
+	RealtimeFile = "/sys/kernel/realtime"
+	// VersionFile names the preemption model in the kernel's build string, checked when RealtimeFile is missing
+	VersionFile = "/proc/version"
+)
+
+// Detect reports whether the running kernel is a PREEMPT_RT kernel
+func Detect() (bool, error) {
+	flag, err := os.ReadFile(RealtimeFile)
+	if err == nil {
+		return strings.TrimSpace(string(flag)) == "1", nil
+	}
+	if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	version, err := os.ReadFile(VersionFile)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.Contains(string(version), "PREEMPT_RT"), nil
+}
@@ -0,0 +1,222 @@
+package redfish
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// BMCAddressAnnotation holds the Redfish address of the Node's BMC, e.g. https://10.0.0.5 or https://[fd00::5]
+	BMCAddressAnnotation = "power.intel.com/bmc-address"
+
+	systemsPath = "/redfish/v1/Systems"
+
+	ResetOn               = "On"
+	ResetGracefulShutdown = "GracefulShutdown"
+	ResetForceOff         = "ForceOff"
+)
+
+// Client talks to the Redfish service of a single BMC, adapting to the Redfish version the service implements
+type Client struct {
+	Address    string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+
+	version *Version
+	schema  schema
+}
+
+// NewClient creates a client for the BMC at address, e.g. https://10.0.0.5, which is taken as BaseURL does. BMCs
+// almost always serve self-signed certificates, insecure skips their verification
+func NewClient(address string, username string, password string, insecure bool) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecure}
+
+	return &Client{
+		Address:  BaseURL(address),
+		Username: username,
+		Password: password,
+		HTTPClient: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+	}
+}
+
+type collection struct {
+	Members []struct {
+		ID string `json:"@odata.id"`
+	} `json:"Members"`
+}
+
+// System returns the path of the first ComputerSystem, a BMC manages a single host
+func (c *Client) System(ctx context.Context) (string, error) {
+	systems := &collection{}
+	err := c.get(ctx, systemsPath, systems)
+	if err != nil {
+		return "", err
+	}
+	if len(systems.Members) == 0 {
+		return "", fmt.Errorf("BMC %s has no systems", c.Address)
+	}
+
+	return systems.Members[0].ID, nil
+}
+
+type system struct {
+	Links struct {
+		Chassis []struct {
+			ID string `json:"@odata.id"`
+		} `json:"Chassis"`
+	} `json:"Links"`
+}
+
+// ChassisWatts returns the power drawn by the chassis the host is in, as measured by the BMC. Unlike RAPL
+// it includes memory, storage, fans and PSU losses. It's read from the chassis' EnvironmentMetrics on services
+// from Redfish 1.11 on and from its deprecated Power resource on older ones
+func (c *Client) ChassisWatts(ctx context.Context) (float64, error) {
+	systemPath, err := c.System(ctx)
+	if err != nil {
+		return 0, err
+	}
+	host := &system{}
+	err = c.get(ctx, systemPath, host)
+	if err != nil {
+		return 0, err
+	}
+	if len(host.Links.Chassis) == 0 {
+		return 0, fmt.Errorf("system %s is not linked to a chassis", systemPath)
+	}
+	chassis := host.Links.Chassis[0].ID
+
+	// a service whose version can't be read is taken as a legacy one until it can
+	schemas := []schema{powerSchema{}, environmentMetricsSchema{}}
+	if _, err = c.Version(ctx); err == nil {
+		if _, current := c.schema.(environmentMetricsSchema); current {
+			schemas[0], schemas[1] = schemas[1], schemas[0]
+		}
+	}
+	// services don't always implement what their version calls for, the other schema is tried if the version's
+	// has no reading
+	for _, adapter := range schemas {
+		watts, found, readErr := adapter.chassisWatts(ctx, c, chassis)
+		if readErr == nil && found {
+			return watts, nil
+		}
+		if readErr != nil {
+			err = readErr
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("chassis %s has no power readings: %w", chassis, err)
+	}
+
+	return 0, fmt.Errorf("chassis %s has no power readings", chassis)
+}
+
+type biosResource struct {
+	Attributes map[string]interface{} `json:"Attributes"`
+}
+
+// BiosAttributes returns the host's current BIOS attributes, their names and values are vendor specific
+func (c *Client) BiosAttributes(ctx context.Context) (map[string]interface{}, error) {
+	system, err := c.System(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bios := &biosResource{}
+	err = c.get(ctx, system+"/Bios", bios)
+	if err != nil {
+		return nil, err
+	}
+
+	return bios.Attributes, nil
+}
+
+// Reset performs a ComputerSystem.Reset of the host with the given reset type
+func (c *Client) Reset(ctx context.Context, resetType string) error {
+	system, err := c.System(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.post(ctx, system+"/Actions/ComputerSystem.Reset", map[string]string{"ResetType": resetType})
+}
+
+func (c *Client) get(ctx context.Context, path string, into interface{}) error {
+	response, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(into)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	response, err := c.do(ctx, http.MethodPost, path, data)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.Address+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(c.Username, c.Password)
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		response.Body.Close()
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, path, response.Status, strings.TrimSpace(string(message)))
+	}
+
+	return response, nil
+}
+
+// BaseURL is the URL of the BMC at address. An address without a scheme is served over https, and an IPv6 address
+// written without brackets, e.g. fd00::5 or https://fd00::5, is bracketed so it isn't mistaken for a host and port
+func BaseURL(address string) string {
+	address = strings.TrimSuffix(address, "/")
+	scheme, host, found := strings.Cut(address, "://")
+	if !found {
+		scheme, host = "https", address
+	}
+	if net.ParseIP(host) != nil && strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	return scheme + "://" + host
+}
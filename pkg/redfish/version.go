@@ -0,0 +1,150 @@
+package redfish
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const serviceRootPath = "/redfish/v1"
+
+// Version is the Redfish protocol version a BMC's service implements, from its service root
+type Version struct {
+	Major  int
+	Minor  int
+	Errata int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Errata)
+}
+
+// AtLeast reports whether the version is major.minor or later
+func (v Version) AtLeast(major int, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// ParseVersion reads a RedfishVersion such as 1.15.0, the errata can be left out
+func ParseVersion(version string) (Version, error) {
+	fields := strings.Split(strings.TrimSpace(version), ".")
+	if len(fields) < 2 || len(fields) > 3 {
+		return Version{}, fmt.Errorf("invalid Redfish version %q", version)
+	}
+	numbers := make([]int, 3)
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 0 {
+			return Version{}, fmt.Errorf("invalid Redfish version %q", version)
+		}
+		numbers[i] = number
+	}
+
+	return Version{Major: numbers[0], Minor: numbers[1], Errata: numbers[2]}, nil
+}
+
+// legacyVersion is taken for services whose service root doesn't say which version they implement
+var legacyVersion = Version{Major: 1}
+
+type serviceRoot struct {
+	RedfishVersion string `json:"RedfishVersion"`
+}
+
+// Version returns the Redfish version of the BMC's service, read from the service root the first time it's
+// needed and kept for the client's lifetime
+func (c *Client) Version(ctx context.Context) (Version, error) {
+	if c.version != nil {
+		return *c.version, nil
+	}
+
+	root := &serviceRoot{}
+	err := c.get(ctx, serviceRootPath, root)
+	if err != nil {
+		return Version{}, err
+	}
+	version := legacyVersion
+	if root.RedfishVersion != "" {
+		version, err = ParseVersion(root.RedfishVersion)
+		if err != nil {
+			return Version{}, err
+		}
+	}
+	c.version = &version
+	c.schema = schemaFor(version)
+
+	return version, nil
+}
+
+// schema adapts the client to the layout of the resources that changed between Redfish versions
+type schema interface {
+	// chassisWatts reads the power drawn by the chassis at the path, reporting false when the resource it reads
+	// has no reading
+	chassisWatts(ctx context.Context, c *Client, chassis string) (float64, bool, error)
+}
+
+// schemaFor picks the schema of the version, services from 1.11 on publish EnvironmentMetrics and deprecate Power
+func schemaFor(version Version) schema {
+	if version.AtLeast(1, 11) {
+		return environmentMetricsSchema{}
+	}
+
+	return powerSchema{}
+}
+
+// powerSchema reads the Power resource of the chassis
+type powerSchema struct{}
+
+type chassisPower struct {
+	PowerControl []struct {
+		PowerConsumedWatts *float64 `json:"PowerConsumedWatts"`
+	} `json:"PowerControl"`
+	PowerSupplies []struct {
+		PowerInputWatts *float64 `json:"PowerInputWatts"`
+	} `json:"PowerSupplies"`
+}
+
+// chassisWatts takes the chassis' total power control reading where there is one, otherwise it sums up the input
+// power of the chassis' power supplies
+func (powerSchema) chassisWatts(ctx context.Context, c *Client, chassis string) (float64, bool, error) {
+	power := &chassisPower{}
+	err := c.get(ctx, chassis+"/Power", power)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, control := range power.PowerControl {
+		if control.PowerConsumedWatts != nil {
+			return *control.PowerConsumedWatts, true, nil
+		}
+	}
+	watts, found := 0.0, false
+	for _, supply := range power.PowerSupplies {
+		if supply.PowerInputWatts != nil {
+			watts += *supply.PowerInputWatts
+			found = true
+		}
+	}
+
+	return watts, found, nil
+}
+
+// environmentMetricsSchema reads the EnvironmentMetrics resource of the chassis
+type environmentMetricsSchema struct{}
+
+type environmentMetrics struct {
+	PowerWatts *struct {
+		Reading *float64 `json:"Reading"`
+	} `json:"PowerWatts"`
+}
+
+func (environmentMetricsSchema) chassisWatts(ctx context.Context, c *Client, chassis string) (float64, bool, error) {
+	metrics := &environmentMetrics{}
+	err := c.get(ctx, chassis+"/EnvironmentMetrics", metrics)
+	if err != nil {
+		return 0, false, err
+	}
+	if metrics.PowerWatts == nil || metrics.PowerWatts.Reading == nil {
+		return 0, false, nil
+	}
+
+	return *metrics.PowerWatts.Reading, true, nil
+}
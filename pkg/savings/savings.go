@@ -0,0 +1,233 @@
+// Package savings reports the energy the Power Manager saved over each period, a week by default, against a
+// baseline of every Node drawing its maximum power throughout. The reports are written to a ConfigMap for
+// management and sustainability teams, with the period in progress kept alongside so a restart loses nothing
+package savings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+const (
+	// BaselineWattsAnnotation on a Node sets the power it would draw at max, such as from its vendor's
+	// specification. Nodes without it are taken to draw the most power they were seen drawing in the period
+	BaselineWattsAnnotation = "power.intel.com/baseline-watts"
+
+	// ReportKey holds the last finished report in the ConfigMap, ProgressKey the period in progress
+	ReportKey   = "report.json"
+	ProgressKey = "progress.json"
+)
+
+// Report is the energy used and saved over one period, by Node and across the cluster
+type Report struct {
+	Start   time.Time     `json:"start"`
+	End     time.Time     `json:"end"`
+	Nodes   []NodeSavings `json:"nodes"`
+	Cluster Savings       `json:"cluster"`
+}
+
+// Savings is the energy used against the baseline, in watt hours
+type Savings struct {
+	EnergyWh     float64 `json:"energyWh"`
+	BaselineWh   float64 `json:"baselineWh"`
+	SavedWh      float64 `json:"savedWh"`
+	SavedPercent float64 `json:"savedPercent"`
+}
+
+// NodeSavings is one Node's share of the report
+type NodeSavings struct {
+	Node string `json:"node"`
+	// Hours is how long the Node reported its power in the period, the baseline only covers those hours
+	Hours         float64 `json:"hours"`
+	PeakWatts     int     `json:"peakWatts"`
+	BaselineWatts int     `json:"baselineWatts"`
+	Savings
+}
+
+// Reporter samples the power the Nodes report on every interval and writes the report when each period ends
+type Reporter struct {
+	client.Client
+	Log logr.Logger
+	// Namespace and Name are the ConfigMap's
+	Namespace string
+	Name      string
+	Interval  time.Duration
+	Period    time.Duration
+
+	current *Report
+}
+
+// Start samples the Nodes' power on the interval until the context is cancelled
+func (r *Reporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			err := r.Sample(ctx, now)
+			if err != nil {
+				r.Log.Error(err, "error updating the savings report")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is true as only one replica may write the ConfigMap
+func (r *Reporter) NeedLeaderElection() bool {
+	return true
+}
+
+// Sample adds the energy the Nodes used since the previous sample to the period, finishing the report once the
+// period has passed. Time the Operator wasn't sampling, longer than two intervals, isn't counted
+func (r *Reporter) Sample(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+	configMap := &corev1.ConfigMap{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: r.Name, Namespace: r.Namespace}, configMap)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("retrieving the ConfigMap: %w", err)
+	}
+	exists := err == nil
+	if r.current == nil {
+		r.current = &Report{Start: now, End: now}
+		if progress, saved := configMap.Data[ProgressKey]; saved {
+			err = json.Unmarshal([]byte(progress), r.current)
+			if err != nil {
+				r.Log.Error(err, "discarding the unreadable savings report in progress")
+				r.current = &Report{Start: now, End: now}
+			}
+		}
+	}
+
+	powerNodes := &powerv1.PowerNodeList{}
+	err = r.Client.List(ctx, powerNodes)
+	if err != nil {
+		return fmt.Errorf("listing PowerNodes: %w", err)
+	}
+	nodes := &corev1.NodeList{}
+	err = r.Client.List(ctx, nodes)
+	if err != nil {
+		return fmt.Errorf("listing Nodes: %w", err)
+	}
+	baselines := make(map[string]int)
+	for _, node := range nodes.Items {
+		value, annotated := node.Annotations[BaselineWattsAnnotation]
+		if !annotated {
+			continue
+		}
+		watts, err := strconv.Atoi(value)
+		if err != nil || watts <= 0 {
+			r.Log.Info("ignoring an invalid baseline", "node", node.Name, BaselineWattsAnnotation, value)
+			continue
+		}
+		baselines[node.Name] = watts
+	}
+
+	elapsed := now.Sub(r.current.End)
+	if elapsed < 0 || elapsed > 2*r.Interval {
+		elapsed = 0
+	}
+	addSample(r.current, powerNodes.Items, elapsed)
+	r.current.End = now
+	summarize(r.current, baselines)
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	if now.Sub(r.current.Start) >= r.Period {
+		report, err := json.MarshalIndent(r.current, "", "  ")
+		if err != nil {
+			return err
+		}
+		configMap.Data[ReportKey] = string(report)
+		r.Log.Info("savings report finished", "start", r.current.Start, "end", r.current.End,
+			"savedWh", r.current.Cluster.SavedWh, "savedPercent", r.current.Cluster.SavedPercent)
+		r.current = &Report{Start: now, End: now}
+	}
+	progress, err := json.MarshalIndent(r.current, "", "  ")
+	if err != nil {
+		return err
+	}
+	configMap.Data[ProgressKey] = string(progress)
+
+	if !exists {
+		configMap.ObjectMeta = metav1.ObjectMeta{Name: r.Name, Namespace: r.Namespace}
+		return r.Client.Create(ctx, configMap)
+	}
+	return r.Client.Update(ctx, configMap)
+}
+
+// addSample adds what each Node drew over the elapsed time, chassis power where the Node reports it and package
+// power where it doesn't. Nodes without a reading aren't counted
+func addSample(report *Report, powerNodes []powerv1.PowerNode, elapsed time.Duration) {
+	index := make(map[string]int, len(report.Nodes))
+	for i, node := range report.Nodes {
+		index[node.Node] = i
+	}
+	for _, powerNode := range powerNodes {
+		watts := powerNode.Status.ChassisPowerWatts
+		if watts <= 0 {
+			watts = powerNode.Status.PackagePowerWatts
+		}
+		if watts <= 0 {
+			continue
+		}
+		i, tracked := index[powerNode.Name]
+		if !tracked {
+			report.Nodes = append(report.Nodes, NodeSavings{Node: powerNode.Name})
+			i = len(report.Nodes) - 1
+			index[powerNode.Name] = i
+		}
+		node := &report.Nodes[i]
+		node.Hours += elapsed.Hours()
+		node.EnergyWh += float64(watts) * elapsed.Hours()
+		if watts > node.PeakWatts {
+			node.PeakWatts = watts
+		}
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Node < report.Nodes[j].Node })
+}
+
+// summarize works out the baseline and savings of every Node and the cluster's totals
+func summarize(report *Report, baselines map[string]int) {
+	report.Cluster = Savings{}
+	for i := range report.Nodes {
+		node := &report.Nodes[i]
+		node.BaselineWatts = node.PeakWatts
+		if watts, annotated := baselines[node.Node]; annotated {
+			node.BaselineWatts = watts
+		}
+		node.BaselineWh = float64(node.BaselineWatts) * node.Hours
+		node.SavedWh = math.Max(node.BaselineWh-node.EnergyWh, 0)
+		node.SavedPercent = percent(node.SavedWh, node.BaselineWh)
+
+		report.Cluster.EnergyWh += node.EnergyWh
+		report.Cluster.BaselineWh += node.BaselineWh
+		report.Cluster.SavedWh += node.SavedWh
+	}
+	report.Cluster.SavedPercent = percent(report.Cluster.SavedWh, report.Cluster.BaselineWh)
+}
+
+// percent is part of whole to one decimal place
+func percent(part float64, whole float64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+
+	return math.Round(part/whole*1000) / 10
+}
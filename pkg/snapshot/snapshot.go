@@ -0,0 +1,197 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+const (
+	SnapshotKind = "PowerSnapshot"
+
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// Snapshot is a single document holding every Power CR in the namespace. PowerNodes and
+// TimeOfDayCronJobs are observed state written by the controllers, they are exported for
+// reference but are not recreated on import
+type Snapshot struct {
+	Kind              string                     `json:"kind"`
+	APIVersion        string                     `json:"apiVersion"`
+	Namespace         string                     `json:"namespace"`
+	Created           metav1.Time                `json:"created"`
+	PowerConfigs      []powerv1.PowerConfig      `json:"powerConfigs,omitempty"`
+	PowerProfiles     []powerv1.PowerProfile     `json:"powerProfiles,omitempty"`
+	PowerWorkloads    []powerv1.PowerWorkload    `json:"powerWorkloads,omitempty"`
+	CStates           []powerv1.CStates          `json:"cStates,omitempty"`
+	TimeOfDays        []powerv1.TimeOfDay        `json:"timeOfDays,omitempty"`
+	Uncores           []powerv1.Uncore           `json:"uncores,omitempty"`
+	PowerNodes        []powerv1.PowerNode        `json:"powerNodes,omitempty"`
+	TimeOfDayCronJobs []powerv1.TimeOfDayCronJob `json:"timeOfDayCronJobs,omitempty"`
+}
+
+// Export lists all Power CRs in the namespace into a Snapshot
+func Export(ctx context.Context, c client.Client, namespace string) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Kind:       SnapshotKind,
+		APIVersion: powerv1.GroupVersion.String(),
+		Namespace:  namespace,
+		Created:    metav1.NewTime(time.Now()),
+	}
+
+	configs := &powerv1.PowerConfigList{}
+	profiles := &powerv1.PowerProfileList{}
+	workloads := &powerv1.PowerWorkloadList{}
+	cStates := &powerv1.CStatesList{}
+	timeOfDays := &powerv1.TimeOfDayList{}
+	uncores := &powerv1.UncoreList{}
+	nodes := &powerv1.PowerNodeList{}
+	cronJobs := &powerv1.TimeOfDayCronJobList{}
+
+	for _, list := range []client.ObjectList{configs, profiles, workloads, cStates, timeOfDays, uncores, nodes, cronJobs} {
+		err := c.List(ctx, list, client.InNamespace(namespace))
+		if err != nil {
+			return nil, fmt.Errorf("listing %T: %w", list, err)
+		}
+	}
+
+	for i := range configs.Items {
+		cleanObjectMeta(&configs.Items[i].ObjectMeta)
+	}
+	for i := range profiles.Items {
+		cleanObjectMeta(&profiles.Items[i].ObjectMeta)
+	}
+	for i := range workloads.Items {
+		cleanObjectMeta(&workloads.Items[i].ObjectMeta)
+	}
+	for i := range cStates.Items {
+		cleanObjectMeta(&cStates.Items[i].ObjectMeta)
+	}
+	for i := range timeOfDays.Items {
+		cleanObjectMeta(&timeOfDays.Items[i].ObjectMeta)
+	}
+	for i := range uncores.Items {
+		cleanObjectMeta(&uncores.Items[i].ObjectMeta)
+	}
+	for i := range nodes.Items {
+		cleanObjectMeta(&nodes.Items[i].ObjectMeta)
+	}
+	for i := range cronJobs.Items {
+		cleanObjectMeta(&cronJobs.Items[i].ObjectMeta)
+	}
+
+	snapshot.PowerConfigs = configs.Items
+	snapshot.PowerProfiles = profiles.Items
+	snapshot.PowerWorkloads = workloads.Items
+	snapshot.CStates = cStates.Items
+	snapshot.TimeOfDays = timeOfDays.Items
+	snapshot.Uncores = uncores.Items
+	snapshot.PowerNodes = nodes.Items
+	snapshot.TimeOfDayCronJobs = cronJobs.Items
+
+	return snapshot, nil
+}
+
+// Import recreates the user-managed CRs of a Snapshot in the namespace. Existing objects are
+// left alone unless overwrite is set, in which case their spec is replaced by the snapshot's
+func Import(ctx context.Context, c client.Client, snapshot *Snapshot, namespace string, overwrite bool) error {
+	if snapshot.Kind != SnapshotKind {
+		return fmt.Errorf("document kind '%s' is not a %s", snapshot.Kind, SnapshotKind)
+	}
+
+	// order matters, PowerProfiles need the PowerConfig and PowerWorkloads need their PowerProfile
+	objects := make([]client.Object, 0)
+	for i := range snapshot.PowerConfigs {
+		objects = append(objects, &snapshot.PowerConfigs[i])
+	}
+	for i := range snapshot.PowerProfiles {
+		objects = append(objects, &snapshot.PowerProfiles[i])
+	}
+	for i := range snapshot.PowerWorkloads {
+		objects = append(objects, &snapshot.PowerWorkloads[i])
+	}
+	for i := range snapshot.CStates {
+		objects = append(objects, &snapshot.CStates[i])
+	}
+	for i := range snapshot.TimeOfDays {
+		objects = append(objects, &snapshot.TimeOfDays[i])
+	}
+	for i := range snapshot.Uncores {
+		objects = append(objects, &snapshot.Uncores[i])
+	}
+
+	results := new(multierror.Error)
+	for _, obj := range objects {
+		obj.SetNamespace(namespace)
+		err := importObject(ctx, c, obj, overwrite)
+		if err != nil {
+			results = multierror.Append(results, fmt.Errorf("importing %T '%s': %w", obj, obj.GetName(), err))
+		}
+	}
+
+	return results.ErrorOrNil()
+}
+
+func importObject(ctx context.Context, c client.Client, obj client.Object, overwrite bool) error {
+	obj.SetResourceVersion("")
+	err := c.Create(ctx, obj)
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+	if !overwrite {
+		return nil
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	err = c.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+
+	return c.Update(ctx, obj)
+}
+
+// Marshal encodes the Snapshot in the given format
+func Marshal(snapshot *Snapshot, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(snapshot)
+	case FormatJSON:
+		return json.MarshalIndent(snapshot, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// Unmarshal decodes a Snapshot from either YAML or JSON
+func Unmarshal(data []byte) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	err := yaml.UnmarshalStrict(data, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// cleanObjectMeta drops the server populated fields so the object can be created again elsewhere
+func cleanObjectMeta(meta *metav1.ObjectMeta) {
+	meta.UID = ""
+	meta.ResourceVersion = ""
+	meta.Generation = 0
+	meta.CreationTimestamp = metav1.Time{}
+	meta.ManagedFields = nil
+	meta.OwnerReferences = nil
+	meta.SelfLink = ""
+}
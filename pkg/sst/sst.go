@@ -0,0 +1,184 @@
+package sst
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intel/kubernetes-power-manager/pkg/cpuset"
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+	"github.com/intel/kubernetes-power-manager/pkg/hostwrite"
+)
+
+const (
+	// ToolPath is the intel-speed-select binary shipped with the kernel tools, it talks to the
+	// SST mailbox through /dev/isst_interface
+	ToolPath = "intel-speed-select"
+	CpuPath  = "/sys/devices/system/cpu"
+
+	currentLevelKey = "get-config-current_level"
+	enableCpusKey   = "enable-cpu-list"
+)
+
+// PerfProfile switches the Intel Speed Select - Performance Profile (SST-PP) config level of every
+// package and brings the cores each level enables online
+type PerfProfile struct {
+	ToolPath string
+	CpuPath  string
+
+	run func(name string, args ...string) ([]byte, error)
+}
+
+func NewPerfProfile(toolPath string) *PerfProfile {
+	return &PerfProfile{
+		ToolPath: toolPath,
+		CpuPath:  CpuPath,
+		run: func(name string, args ...string) ([]byte, error) {
+			return exec.Command(name, args...).Output()
+		},
+	}
+}
+
+// CurrentLevel returns the config level the packages are in, they are expected to agree
+func (p *PerfProfile) CurrentLevel() (int, error) {
+	values, err := p.query(currentLevelKey, "perf-profile", "get-config-current-level")
+	if err != nil {
+		return 0, err
+	}
+
+	level := -1
+	for _, value := range values {
+		current, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("malformed config level '%s'", value)
+		}
+		if level != -1 && current != level {
+			return 0, fmt.Errorf("packages are in different config levels %d and %d", level, current)
+		}
+		level = current
+	}
+
+	return level, nil
+}
+
+// EnabledCpus returns the CPUs that are enabled in the config level across all packages
+func (p *PerfProfile) EnabledCpus(level int) ([]uint, error) {
+	values, err := p.query(enableCpusKey, "perf-profile", "info", "-l", strconv.Itoa(level))
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := cpuset.NewCPUSet()
+	for _, value := range values {
+		cpus, err := cpuset.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("malformed CPU list '%s': %w", value, err)
+		}
+		enabled = enabled.Union(cpus)
+	}
+
+	return toUint(enabled.ToSlice()), nil
+}
+
+// SetLevel switches all packages to the config level, the cores are not onlined or offlined
+func (p *PerfProfile) SetLevel(level int) error {
+	_, err := p.run(p.ToolPath, "perf-profile", "set-config-level", "-l", strconv.Itoa(level))
+	if err != nil {
+		return powererrors.NewTransient(fmt.Errorf("setting config level %d: %w", level, err))
+	}
+
+	return nil
+}
+
+// OnlineCpus returns the CPUs that are currently online
+func (p *PerfProfile) OnlineCpus() ([]uint, error) {
+	data, err := os.ReadFile(filepath.Join(p.CpuPath, "online"))
+	if err != nil {
+		return nil, err
+	}
+	online, err := cpuset.Parse(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	return toUint(online.ToSlice()), nil
+}
+
+// SetOnline brings the CPUs online or takes them offline. CPUs that cannot be hotplugged, usually
+// CPU 0, have no online file and are left alone
+func (p *PerfProfile) SetOnline(cpus []uint, online bool) error {
+	value := []byte("0")
+	if online {
+		value = []byte("1")
+	}
+
+	for _, cpu := range cpus {
+		path := filepath.Join(p.CpuPath, fmt.Sprintf("cpu%d", cpu), "online")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		err := hostwrite.Write(path, value)
+		if err != nil {
+			return fmt.Errorf("setting CPU %d online to %t: %w", cpu, online, err)
+		}
+	}
+
+	return nil
+}
+
+// query runs the tool with JSON output and collects every value of the key, the output nests
+// package, die and cpu objects that each carry their own value
+func (p *PerfProfile) query(key string, args ...string) ([]string, error) {
+	output, err := p.run(p.ToolPath, append([]string{"-f", "json"}, args...)...)
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, powererrors.NewHardwareUnsupported("SST-PP", err)
+	}
+	if err != nil {
+		return nil, powererrors.NewTransient(fmt.Errorf("running %s %s: %w", p.ToolPath, strings.Join(args, " "), err))
+	}
+
+	var parsed map[string]interface{}
+	err = json.Unmarshal(output, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s output: %w", p.ToolPath, err)
+	}
+
+	values := collect(parsed, key)
+	if len(values) == 0 {
+		return nil, powererrors.NewHardwareUnsupported("SST-PP", fmt.Errorf("%s output has no '%s'", p.ToolPath, key))
+	}
+	sort.Strings(values)
+
+	return values, nil
+}
+
+func collect(object map[string]interface{}, key string) []string {
+	values := make([]string, 0)
+	for k, v := range object {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			values = append(values, collect(value, key)...)
+		case string:
+			if k == key {
+				values = append(values, value)
+			}
+		}
+	}
+
+	return values
+}
+
+func toUint(cpus []int) []uint {
+	result := make([]uint, len(cpus))
+	for i, cpu := range cpus {
+		result[i] = uint(cpu)
+	}
+
+	return result
+}
@@ -0,0 +1,209 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/capacity"
+)
+
+// CapacityReport is how many more Pods of a shape the cluster can take, for capacity planning
+type CapacityReport struct {
+	Generated time.Time `json:"generated"`
+	Profile   string    `json:"profile"`
+	// MaxFrequency and MinFrequency are the PowerProfile's, in MHz, 0 where they're resolved on each Node
+	MaxFrequency int `json:"maxFrequency"`
+	MinFrequency int `json:"minFrequency"`
+	// CPUs is each Pod's request, as a quantity
+	CPUs string `json:"cpus"`
+	// Pods is how many more Pods fit across the cluster
+	Pods int64 `json:"pods"`
+	// Nodes are the Nodes offering the PowerProfile, the most Pods first
+	Nodes []NodeCapacity `json:"nodes"`
+}
+
+// NodeCapacity is how many more Pods of the shape fit on a Node and what runs out first
+type NodeCapacity struct {
+	Node string `json:"node"`
+	Pods int64  `json:"pods"`
+	// Limit is profile when the PowerProfile's capacity runs out first and cpu when the allocatable CPU does
+	Limit string `json:"limit"`
+	// FreeProfile is the PowerProfile's Extended Resources not requested yet
+	FreeProfile int64 `json:"freeProfile"`
+	// FreeCPUMillis is the allocatable CPU not requested yet
+	FreeCPUMillis int64 `json:"freeCPUMillis"`
+}
+
+// handleCapacity replies to GET requests with the CapacityReport for the profile and cpus query parameters, cpus is
+// a quantity such as 2 or 500m and defaults to one CPU
+func (s *Server) handleCapacity(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	profile := req.URL.Query().Get("profile")
+	if profile == "" {
+		http.Error(w, "profile is required", http.StatusBadRequest)
+		return
+	}
+	cpus := resource.MustParse("1")
+	if value := req.URL.Query().Get("cpus"); value != "" {
+		parsed, err := resource.ParseQuantity(value)
+		if err != nil || parsed.Sign() <= 0 {
+			http.Error(w, fmt.Sprintf("invalid cpus: %s", value), http.StatusBadRequest)
+			return
+		}
+		cpus = parsed
+	}
+
+	report, err := s.Capacity(req.Context(), profile, cpus, time.Now())
+	if errors.IsNotFound(err) {
+		http.Error(w, fmt.Sprintf("PowerProfile %s not found", profile), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.Log.Error(err, "error planning capacity")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		s.Log.Error(err, "error writing capacity report")
+	}
+}
+
+// Capacity works out how many more Pods requesting cpus of the PowerProfile fit on each Node offering it, from the
+// profile's capacity the Node advertises and its allocatable CPU, less what the Pods on it already request
+func (s *Server) Capacity(ctx context.Context, profileName string, cpus resource.Quantity, now time.Time) (*CapacityReport, error) {
+	profile := &powerv1.PowerProfile{}
+	err := s.Client.Get(ctx, client.ObjectKey{Name: profileName, Namespace: s.Namespace}, profile)
+	if err != nil {
+		return nil, err
+	}
+	shape := capacity.Shape{
+		Profile:   profileName,
+		Quantity:  cpus.Value(),
+		CPUMillis: cpus.MilliValue(),
+	}
+
+	nodes := &corev1.NodeList{}
+	err = s.Client.List(ctx, nodes)
+	if err != nil {
+		return nil, fmt.Errorf("listing Nodes: %w", err)
+	}
+	pods := &corev1.PodList{}
+	err = s.Client.List(ctx, pods)
+	if err != nil {
+		return nil, fmt.Errorf("listing Pods: %w", err)
+	}
+	resourceName := corev1.ResourceName(s.ResourcePrefix + profileName)
+	used := make(map[string]capacity.Free)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		requests := podRequests(pod)
+		podUsed := used[pod.Spec.NodeName]
+		podUsed.Quantity += requests.Name(resourceName, resource.DecimalSI).Value()
+		podUsed.CPUMillis += requests.Cpu().MilliValue()
+		used[pod.Spec.NodeName] = podUsed
+	}
+
+	report := &CapacityReport{
+		Generated:    now.UTC(),
+		Profile:      profileName,
+		MaxFrequency: profile.Spec.Max,
+		MinFrequency: profile.Spec.Min,
+		CPUs:         cpus.String(),
+		Nodes:        make([]NodeCapacity, 0),
+	}
+	if profile.Spec.MaxPreset != "" {
+		report.MaxFrequency = 0
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		advertised, offered := s.advertised(node, profileName)
+		if !offered {
+			continue
+		}
+		free := capacity.Free{
+			Quantity:  advertised - used[node.Name].Quantity,
+			CPUMillis: node.Status.Allocatable.Cpu().MilliValue() - used[node.Name].CPUMillis,
+		}
+		pods, limit := capacity.Fit(shape, free)
+		report.Pods += pods
+		report.Nodes = append(report.Nodes, NodeCapacity{
+			Node:          node.Name,
+			Pods:          pods,
+			Limit:         limit,
+			FreeProfile:   free.Quantity,
+			FreeCPUMillis: free.CPUMillis,
+		})
+	}
+	sort.SliceStable(report.Nodes, func(i, j int) bool {
+		if report.Nodes[i].Pods != report.Nodes[j].Pods {
+			return report.Nodes[i].Pods > report.Nodes[j].Pods
+		}
+		return report.Nodes[i].Node < report.Nodes[j].Node
+	})
+
+	return report, nil
+}
+
+// advertised is the PowerProfile's capacity on the Node, from its allocatable Extended Resources or, where capacity
+// is advertised with labels, its capacity label. False if the Node doesn't offer the profile
+func (s *Server) advertised(node *corev1.Node, profileName string) (int64, bool) {
+	resourceName := corev1.ResourceName(s.ResourcePrefix + profileName)
+	if quantity, found := node.Status.Allocatable[resourceName]; found {
+		return quantity.Value(), true
+	}
+	// the Node Agent patches the capacity, the kubelet copies it to allocatable on its next status update
+	if quantity, found := node.Status.Capacity[resourceName]; found {
+		return quantity.Value(), true
+	}
+	label, found := node.Labels[s.LabelPrefix+profileName]
+	if !found {
+		return 0, false
+	}
+	quantity, err := strconv.ParseInt(label, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return quantity, true
+}
+
+// podRequests sums the requests of the Pod's containers, which default to their limits
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := make(corev1.ResourceList)
+	for _, container := range pod.Spec.Containers {
+		resources := make(corev1.ResourceList)
+		for name, quantity := range container.Resources.Limits {
+			resources[name] = quantity
+		}
+		for name, quantity := range container.Resources.Requests {
+			resources[name] = quantity
+		}
+		for name, quantity := range resources {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+	}
+
+	return requests
+}
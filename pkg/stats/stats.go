@@ -0,0 +1,282 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/telemetrystream"
+)
+
+const defaultTop = 10
+
+// Stats is the summary served to dashboards
+type Stats struct {
+	Generated time.Time `json:"generated"`
+	// Pools are the exclusive pools across the cluster, keyed by PowerProfile
+	Pools map[string]PoolStats `json:"pools"`
+	// EnergyTrend is the power drawn by the whole cluster over the recent samples, oldest first
+	EnergyTrend []PowerSample `json:"energyTrend"`
+	// TopConsumers are the Nodes drawing the most power, highest first
+	TopConsumers []NodePower `json:"topConsumers"`
+}
+
+// PoolStats is how one PowerProfile's pools are spread across the cluster
+type PoolStats struct {
+	Nodes      int `json:"nodes"`
+	CPUs       int `json:"cpus"`
+	Containers int `json:"containers"`
+}
+
+// PowerSample is the power drawn by all the Nodes at one time, in watts
+type PowerSample struct {
+	Time         time.Time `json:"time"`
+	PackageWatts int       `json:"packageWatts"`
+	ChassisWatts int       `json:"chassisWatts"`
+}
+
+// NodePower is the power a Node draws, Watts is the chassis power where the Node reports it and the package
+// power otherwise
+type NodePower struct {
+	Node         string `json:"node"`
+	Watts        int    `json:"watts"`
+	PackageWatts int    `json:"packageWatts"`
+	ChassisWatts int    `json:"chassisWatts"`
+}
+
+// LiveSource has the latest power readings the Node Agents streamed, keyed by Node name
+type LiveSource interface {
+	Latest() map[string]telemetrystream.Update
+}
+
+// Server serves a read-only JSON summary of the cluster's pools and power for dashboards that can't query
+// Prometheus. The pools and top consumers are worked out on each request, the energy trend from the samples
+// the server takes every interval. It also serves capacity reports for planning workloads at /capacity
+type Server struct {
+	client.Client
+	Log      logr.Logger
+	Addr     string
+	Interval time.Duration
+	// History is how many samples the energy trend keeps
+	History int
+	// Live readings are used over the PowerNode status where a Node has streamed them, the status only if nil
+	Live LiveSource
+	// Namespace holds the PowerProfiles and PowerConfig the capacity reports are planned from
+	Namespace string
+	// ResourcePrefix and LabelPrefix are those of the PowerProfile Extended Resources and capacity labels
+	ResourcePrefix string
+	LabelPrefix    string
+
+	mutex   sync.Mutex
+	samples []PowerSample
+}
+
+// Start samples the cluster's power and serves the stats endpoint until the context is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/stats", s)
+	mux.HandleFunc("/capacity", s.handleCapacity)
+
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go s.sample(ctx)
+
+	s.Log.Info("serving stats", "address", s.Addr)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// NeedLeaderElection is false as every replica can serve dashboards, each keeps its own energy trend
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) sample(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.Sample(ctx, time.Now())
+		if err != nil {
+			s.Log.Error(err, "error sampling cluster power")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample adds the power the Nodes currently report to the energy trend, dropping the oldest samples beyond History
+func (s *Server) Sample(ctx context.Context, now time.Time) error {
+	powerNodes := &powerv1.PowerNodeList{}
+	err := s.Client.List(ctx, powerNodes)
+	if err != nil {
+		return fmt.Errorf("listing PowerNodes: %w", err)
+	}
+
+	sample := PowerSample{Time: now.UTC()}
+	live := s.latest()
+	for _, powerNode := range powerNodes.Items {
+		packageWatts, chassisWatts := nodeWatts(powerNode, live)
+		sample.PackageWatts += packageWatts
+		sample.ChassisWatts += chassisWatts
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.samples = append(s.samples, sample)
+	if s.History > 0 && len(s.samples) > s.History {
+		s.samples = s.samples[len(s.samples)-s.History:]
+	}
+
+	return nil
+}
+
+// ServeHTTP replies to GET requests with the Stats, the top query parameter sets how many top consumers are listed
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	top := defaultTop
+	if value := req.URL.Query().Get("top"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, fmt.Sprintf("invalid top: %s", value), http.StatusBadRequest)
+			return
+		}
+		top = parsed
+	}
+
+	stats, err := s.Stats(req.Context(), top, time.Now())
+	if err != nil {
+		s.Log.Error(err, "error gathering stats")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(stats)
+	if err != nil {
+		s.Log.Error(err, "error writing stats")
+	}
+}
+
+// Stats summarizes the exclusive pools from the PowerWorkloads and the top consumers from the PowerNodes
+func (s *Server) Stats(ctx context.Context, top int, now time.Time) (*Stats, error) {
+	stats := &Stats{
+		Generated:    now.UTC(),
+		Pools:        make(map[string]PoolStats),
+		TopConsumers: make([]NodePower, 0),
+	}
+
+	workloads := &powerv1.PowerWorkloadList{}
+	err := s.Client.List(ctx, workloads)
+	if err != nil {
+		return nil, fmt.Errorf("listing PowerWorkloads: %w", err)
+	}
+	for _, workload := range workloads.Items {
+		if workload.Spec.AllCores || workload.Spec.PowerProfile == "" {
+			continue
+		}
+		pool := stats.Pools[workload.Spec.PowerProfile]
+		if len(workload.Spec.Node.CpuIds) > 0 {
+			pool.Nodes++
+		}
+		pool.CPUs += len(workload.Spec.Node.CpuIds)
+		pool.Containers += len(workload.Spec.Node.Containers)
+		stats.Pools[workload.Spec.PowerProfile] = pool
+	}
+
+	powerNodes := &powerv1.PowerNodeList{}
+	err = s.Client.List(ctx, powerNodes)
+	if err != nil {
+		return nil, fmt.Errorf("listing PowerNodes: %w", err)
+	}
+	live := s.latest()
+	for _, powerNode := range powerNodes.Items {
+		packageWatts, chassisWatts := nodeWatts(powerNode, live)
+		consumer := NodePower{
+			Node:         powerNode.Name,
+			Watts:        packageWatts,
+			PackageWatts: packageWatts,
+			ChassisWatts: chassisWatts,
+		}
+		if consumer.ChassisWatts > 0 {
+			consumer.Watts = consumer.ChassisWatts
+		}
+		if consumer.Watts > 0 {
+			stats.TopConsumers = append(stats.TopConsumers, consumer)
+		}
+	}
+	sort.SliceStable(stats.TopConsumers, func(i, j int) bool {
+		if stats.TopConsumers[i].Watts != stats.TopConsumers[j].Watts {
+			return stats.TopConsumers[i].Watts > stats.TopConsumers[j].Watts
+		}
+		return stats.TopConsumers[i].Node < stats.TopConsumers[j].Node
+	})
+	if len(stats.TopConsumers) > top {
+		stats.TopConsumers = stats.TopConsumers[:top]
+	}
+
+	s.mutex.Lock()
+	stats.EnergyTrend = append(make([]PowerSample, 0, len(s.samples)), s.samples...)
+	s.mutex.Unlock()
+
+	return stats, nil
+}
+
+func (s *Server) latest() map[string]telemetrystream.Update {
+	if s.Live == nil {
+		return nil
+	}
+
+	return s.Live.Latest()
+}
+
+// nodeWatts is the Node's package and chassis power, from its streamed readings where it has them and its
+// PowerNode status otherwise. A reading the Node couldn't take is left at the status value
+func nodeWatts(powerNode powerv1.PowerNode, live map[string]telemetrystream.Update) (int, int) {
+	packageWatts := powerNode.Status.PackagePowerWatts
+	chassisWatts := powerNode.Status.ChassisPowerWatts
+	update, found := live[powerNode.Name]
+	if !found {
+		return packageWatts, chassisWatts
+	}
+	if update.PackageWatts >= 0 {
+		packageWatts = int(math.Round(update.PackageWatts))
+	}
+	if update.ChassisWatts >= 0 {
+		chassisWatts = int(math.Round(update.ChassisWatts))
+	}
+
+	return packageWatts, chassisWatts
+}
@@ -0,0 +1,100 @@
+// Package strictadmission holds Pods that request a PowerProfile out of service until the PowerProfile is on their
+// CPUs, for workloads that must not serve without their frequencies. The exclusive CPUs are only known once the
+// kubelet has admitted the Pod, so it can't be held back from scheduling, instead it carries a readiness gate that
+// the Node Agent sets once the PowerWorkload is applied
+package strictadmission
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+)
+
+const (
+	// ReadinessGate is the Pod condition the Pod isn't Ready without. Pods can declare it themselves without the
+	// webhook to opt in one by one
+	ReadinessGate corev1.PodConditionType = "power.intel.com/power-profile-applied"
+	// NamespaceLabel set to "true" on a Namespace has the webhook add the ReadinessGate to its Pods
+	NamespaceLabel = "power.intel.com/strict-admission"
+
+	// The reasons of the ReadinessGate condition
+	ReasonPending     = "Pending"
+	ReasonApplied     = "Applied"
+	ReasonApplyFailed = "ApplyFailed"
+)
+
+// Defaulter adds the ReadinessGate to the Pods requesting a PowerProfile in the Namespaces labelled for it
+type Defaulter struct {
+	Client client.Reader
+}
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+//+kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod.power.intel.com,admissionReviewVersions=v1
+
+// Default adds the ReadinessGate once, Pods without a PowerProfile request or outside a labelled Namespace are left
+// as they are
+func (d *Defaulter) Default(ctx context.Context, obj runtime.Object) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("expected a Pod, got %T", obj)
+	}
+	if !RequestsPowerProfile(pod) || HasReadinessGate(pod) {
+		return nil
+	}
+	namespaceName := pod.Namespace
+	if namespaceName == "" {
+		// Pods created without a namespace take the request's
+		if request, err := admission.RequestFromContext(ctx); err == nil {
+			namespaceName = request.Namespace
+		}
+	}
+	namespace := &corev1.Namespace{}
+	err := d.Client.Get(ctx, client.ObjectKey{Name: namespaceName}, namespace)
+	if err != nil {
+		return fmt.Errorf("retrieving the Pod's Namespace: %w", err)
+	}
+	if namespace.Labels[NamespaceLabel] != "true" {
+		return nil
+	}
+	pod.Spec.ReadinessGates = append(pod.Spec.ReadinessGates, corev1.PodReadinessGate{ConditionType: ReadinessGate})
+
+	return nil
+}
+
+// RequestsPowerProfile reports whether any container requests a PowerProfile's Extended Resource
+func RequestsPowerProfile(pod *corev1.Pod) bool {
+	prefix := powerv1.GroupVersion.Group + "/"
+	for _, container := range pod.Spec.Containers {
+		for name := range container.Resources.Limits {
+			if strings.HasPrefix(string(name), prefix) {
+				return true
+			}
+		}
+		for name := range container.Resources.Requests {
+			if strings.HasPrefix(string(name), prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// HasReadinessGate reports whether the Pod opted into strict admission
+func HasReadinessGate(pod *corev1.Pod) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == ReadinessGate {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,243 @@
+// Package supportbundle collects what is needed to file a bug about the Power Manager with a vendor into a single
+// archive: the Power CRs with their statuses, each Node's capability matrix, recent events and sanitized logs
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	powerv1 "github.com/intel/kubernetes-power-manager/api/v1"
+	"github.com/intel/kubernetes-power-manager/pkg/snapshot"
+)
+
+const redacted = "<redacted>"
+
+// secretPatterns match the credentials that can end up in logs, the first group is kept and the rest redacted
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)((?:password|passwd|token|secret|apikey|api_key|credentials)"?\s*[:=]\s*"?)[^\s",}]+`),
+	regexp.MustCompile(`(?i)(authorization"?\s*[:=]\s*"?(?:bearer|basic)\s+)[^\s",}]+`),
+	regexp.MustCompile(`(?i)(bearer\s+)[a-z0-9\-._~+/]+=*`),
+	regexp.MustCompile(`(-----BEGIN [A-Z ]*PRIVATE KEY-----)[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// Options selects what goes into the bundle
+type Options struct {
+	// Namespace holding the Power CRs and the Power Manager's Pods
+	Namespace string
+	// Since is how far back events and logs go, all of them if 0
+	Since time.Duration
+	// TailLines caps the log lines kept per container, all lines since Since if 0
+	TailLines int64
+}
+
+// Capability is a row of the capability matrix, what a Node Agent found the Node can and can't do
+type Capability struct {
+	Node                string   `json:"node"`
+	CapabilityProfile   string   `json:"capabilityProfile,omitempty"`
+	UnavailableControls []string `json:"unavailableControls,omitempty"`
+	HWP                 string   `json:"hwp,omitempty"`
+	Turbo               string   `json:"turbo,omitempty"`
+	BIOSAdvisories      []string `json:"biosAdvisories,omitempty"`
+}
+
+// manifest describes the bundle so whoever receives it knows what it covers
+type manifest struct {
+	Created   metav1.Time `json:"created"`
+	Namespace string      `json:"namespace"`
+	Since     string      `json:"since"`
+}
+
+// Write collects the bundle and writes it to w as a gzipped tarball. Parts that can't be collected, such as the
+// logs of a Pod that is gone, are listed in errors.txt in the bundle rather than failing it
+func Write(ctx context.Context, w io.Writer, c client.Client, clientset kubernetes.Interface, options Options) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	now := time.Now()
+	problems := make([]string, 0)
+	add := func(name string, data []byte) error {
+		err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: now})
+		if err != nil {
+			return err
+		}
+		_, err = archive.Write(data)
+		return err
+	}
+	addYAML := func(name string, obj interface{}) error {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		return add(name, data)
+	}
+
+	err := addYAML("manifest.yaml", manifest{
+		Created:   metav1.NewTime(now),
+		Namespace: options.Namespace,
+		Since:     options.Since.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	crs, err := snapshot.Export(ctx, c, options.Namespace)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("power CRs: %v", err))
+	} else {
+		if err = addYAML("crs.yaml", crs); err != nil {
+			return err
+		}
+		if err = addYAML("capabilities.yaml", Capabilities(crs.PowerNodes)); err != nil {
+			return err
+		}
+	}
+
+	var since time.Time
+	if options.Since > 0 {
+		since = now.Add(-options.Since)
+	}
+	events, err := recentEvents(ctx, c, options.Namespace, since)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("events: %v", err))
+	} else if err = addYAML("events.yaml", events); err != nil {
+		return err
+	}
+
+	pods := &corev1.PodList{}
+	err = c.List(ctx, pods, client.InNamespace(options.Namespace))
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("pods: %v", err))
+	}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			logs, err := containerLogs(ctx, clientset, &pod, container.Name, options)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("logs of %s/%s: %v", pod.Name, container.Name, err))
+				continue
+			}
+			if err = add(fmt.Sprintf("logs/%s_%s.log", pod.Name, container.Name), logs); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		var buffer bytes.Buffer
+		for _, problem := range problems {
+			fmt.Fprintln(&buffer, problem)
+		}
+		if err = add("errors.txt", buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if err = archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Capabilities builds the capability matrix from what each Node Agent reported in its PowerNode status
+func Capabilities(powerNodes []powerv1.PowerNode) []Capability {
+	capabilities := make([]Capability, 0, len(powerNodes))
+	for _, powerNode := range powerNodes {
+		capability := Capability{
+			Node:                powerNode.Name,
+			CapabilityProfile:   powerNode.Status.CapabilityProfile,
+			UnavailableControls: powerNode.Status.UnavailableControls,
+		}
+		if bios := powerNode.Status.BIOSSettings; bios != nil {
+			capability.HWP = bios.HWP
+			capability.Turbo = bios.Turbo
+			capability.BIOSAdvisories = bios.Advisories
+		}
+		capabilities = append(capabilities, capability)
+	}
+	sort.Slice(capabilities, func(i, j int) bool {
+		return capabilities[i].Node < capabilities[j].Node
+	})
+
+	return capabilities
+}
+
+// recentEvents returns the namespace's events seen since the given time, oldest first
+func recentEvents(ctx context.Context, c client.Client, namespace string, since time.Time) ([]corev1.Event, error) {
+	events := &corev1.EventList{}
+	err := c.List(ctx, events, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	recent := make([]corev1.Event, 0, len(events.Items))
+	for _, event := range events.Items {
+		if lastSeen(&event).Before(since) {
+			continue
+		}
+		event.ManagedFields = nil
+		recent = append(recent, event)
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return lastSeen(&recent[i]).Before(lastSeen(&recent[j]))
+	})
+
+	return recent, nil
+}
+
+func lastSeen(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+
+	return event.CreationTimestamp.Time
+}
+
+func containerLogs(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod, container string, options Options) ([]byte, error) {
+	logOptions := &corev1.PodLogOptions{Container: container}
+	if options.Since > 0 {
+		seconds := int64(options.Since.Seconds())
+		logOptions.SinceSeconds = &seconds
+	}
+	if options.TailLines > 0 {
+		logOptions.TailLines = &options.TailLines
+	}
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var sanitized bytes.Buffer
+	err = Sanitize(stream, &sanitized)
+	return sanitized.Bytes(), err
+}
+
+// Sanitize copies the logs, redacting passwords, tokens and private keys
+func Sanitize(r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range secretPatterns {
+		data = pattern.ReplaceAll(data, []byte("${1}"+redacted))
+	}
+	_, err = w.Write(data)
+
+	return err
+}
@@ -0,0 +1,119 @@
+package telemetry
+
+const (
+	// DefaultSpikeUtilization is the Shared pool utilization counted as a spike
+	DefaultSpikeUtilization = 0.8
+	// DefaultFrequencyDrop is how far below its quiet baseline an exclusive pool's frequency has to fall
+	DefaultFrequencyDrop = 0.1
+
+	// busyUtilization is the utilization a core needs for its frequency to count, idle cores clock down on their own
+	busyUtilization = 0.5
+	// baselineWeight is how much each quiet sample moves the baseline
+	baselineWeight = 0.2
+)
+
+// Interference is a drop in an exclusive pool's frequency seen while the Shared pool was spiking, the busy
+// Shared cores likely used up the package's power budget and took the exclusive cores' turbo with them
+type Interference struct {
+	// Average utilization of the Shared pool's cores, between 0 and 1
+	SharedUtilization float64
+	// Average frequency in MHz of the exclusive pool's busy cores while the Shared pool was quiet
+	BaselineFrequency uint
+	// Average frequency in MHz of the exclusive pool's busy cores during the spike
+	Frequency uint
+}
+
+// Drop is the fraction of the baseline frequency lost
+func (i Interference) Drop() float64 {
+	if i.BaselineFrequency == 0 {
+		return 0
+	}
+
+	return 1 - float64(i.Frequency)/float64(i.BaselineFrequency)
+}
+
+// InterferenceDetector correlates Shared pool utilization with the frequency of each exclusive pool's busy
+// cores. The frequency the cores reach while the Shared pool is quiet is their baseline, a drop from it that
+// only happens during Shared pool spikes is put down to the pools sharing the package's power budget
+type InterferenceDetector struct {
+	// SpikeUtilization is the Shared pool utilization counted as a spike
+	SpikeUtilization float64
+	// FrequencyDrop is the fraction of the baseline an exclusive pool's frequency has to lose during a spike
+	FrequencyDrop float64
+
+	baselines map[string]float64
+}
+
+func NewInterferenceDetector() *InterferenceDetector {
+	return &InterferenceDetector{
+		SpikeUtilization: DefaultSpikeUtilization,
+		FrequencyDrop:    DefaultFrequencyDrop,
+		baselines:        make(map[string]float64),
+	}
+}
+
+// Observe takes samples of the Shared pool and of an exclusive pool taken at the same time. While the Shared
+// pool is quiet they update the exclusive pool's baseline, during a spike they are compared with it
+func (d *InterferenceDetector) Observe(pool string, shared []Sample, exclusive []Sample) (Interference, bool) {
+	frequency, busy := busyFrequency(exclusive)
+	if len(shared) == 0 || !busy {
+		return Interference{}, false
+	}
+	var utilization float64
+	for _, sample := range shared {
+		utilization += sample.Utilization
+	}
+	utilization /= float64(len(shared))
+
+	baseline, known := d.baselines[pool]
+	if utilization < d.SpikeUtilization {
+		if !known {
+			d.baselines[pool] = frequency
+		} else {
+			d.baselines[pool] = baseline + baselineWeight*(frequency-baseline)
+		}
+		return Interference{}, false
+	}
+	if !known || frequency >= baseline*(1-d.FrequencyDrop) {
+		return Interference{}, false
+	}
+
+	return Interference{
+		SharedUtilization: utilization,
+		BaselineFrequency: uint(baseline),
+		Frequency:         uint(frequency),
+	}, true
+}
+
+// Forget drops the baseline of a pool that is gone
+func (d *InterferenceDetector) Forget(pool string) {
+	delete(d.baselines, pool)
+}
+
+// Pools returns the exclusive pools with a baseline
+func (d *InterferenceDetector) Pools() []string {
+	pools := make([]string, 0, len(d.baselines))
+	for pool := range d.baselines {
+		pools = append(pools, pool)
+	}
+
+	return pools
+}
+
+// busyFrequency averages the frequency of the busy cores, reporting false if none are busy
+func busyFrequency(samples []Sample) (float64, bool) {
+	var frequency float64
+	var busy int
+	for _, sample := range samples {
+		if sample.Utilization < busyUtilization {
+			continue
+		}
+		frequency += float64(sample.Frequency)
+		busy++
+	}
+	if busy == 0 {
+		return 0, false
+	}
+
+	return frequency / float64(busy), true
+}
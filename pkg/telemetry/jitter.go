@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultJitterThreshold is the standard deviation, relative to the mean, of a pinned pool's frequency counted
+	// as jitter
+	DefaultJitterThreshold = 0.05
+
+	// minJitterSamples is how many frequencies a pool needs in the window before its jitter means anything
+	minJitterSamples = 3
+)
+
+// Jitter is how much the frequency of a pool's busy cores varied over the window
+type Jitter struct {
+	// Mean and StdDev of the busy cores' frequency in MHz
+	Mean   float64
+	StdDev float64
+	// Samples is how many core frequencies were seen
+	Samples int
+}
+
+// Ratio is the standard deviation relative to the mean
+func (j Jitter) Ratio() float64 {
+	if j.Mean == 0 {
+		return 0
+	}
+
+	return j.StdDev / j.Mean
+}
+
+type frequencySample struct {
+	at        time.Time
+	frequency uint
+}
+
+// JitterTracker keeps the frequency of each pool's busy cores over a sliding window. Idle cores are left out as they
+// clock down on their own, so on a pool whose PowerProfile pins the frequency any variation left is the hardware
+// throttling, from thermal or power limits
+type JitterTracker struct {
+	Window time.Duration
+
+	samples map[string][]frequencySample
+}
+
+func NewJitterTracker(window time.Duration) *JitterTracker {
+	return &JitterTracker{Window: window, samples: make(map[string][]frequencySample)}
+}
+
+// Add records the frequency of the pool's busy cores and drops what fell out of the window
+func (t *JitterTracker) Add(pool string, now time.Time, samples []Sample) {
+	kept := t.samples[pool][:0]
+	for _, sample := range t.samples[pool] {
+		if now.Sub(sample.at) < t.Window {
+			kept = append(kept, sample)
+		}
+	}
+	for _, sample := range samples {
+		if sample.Utilization < busyUtilization || sample.Frequency == 0 {
+			continue
+		}
+		kept = append(kept, frequencySample{at: now, frequency: sample.Frequency})
+	}
+	t.samples[pool] = kept
+}
+
+// Jitter returns the pool's frequency spread over the window, reporting false until it has enough samples
+func (t *JitterTracker) Jitter(pool string) (Jitter, bool) {
+	samples := t.samples[pool]
+	if len(samples) < minJitterSamples {
+		return Jitter{}, false
+	}
+	var sum float64
+	for _, sample := range samples {
+		sum += float64(sample.frequency)
+	}
+	mean := sum / float64(len(samples))
+	var squares float64
+	for _, sample := range samples {
+		squares += (float64(sample.frequency) - mean) * (float64(sample.frequency) - mean)
+	}
+
+	return Jitter{
+		Mean:    mean,
+		StdDev:  math.Sqrt(squares / float64(len(samples))),
+		Samples: len(samples),
+	}, true
+}
+
+// Forget drops the samples of a pool that is gone
+func (t *JitterTracker) Forget(pool string) {
+	delete(t.samples, pool)
+}
+
+// Pools returns the pools with samples
+func (t *JitterTracker) Pools() []string {
+	pools := make([]string, 0, len(t.samples))
+	for pool := range t.samples {
+		pools = append(pools, pool)
+	}
+
+	return pools
+}
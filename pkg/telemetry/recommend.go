@@ -0,0 +1,51 @@
+package telemetry
+
+import (
+	"fmt"
+)
+
+// Recommendation is the max frequency a profile could be clamped to without cores running out of cycles
+// for more than (1 - percentile) of the time
+type Recommendation struct {
+	CurrentMax      uint
+	RecommendedMax  uint
+	PercentileValue uint
+	Samples         uint64
+}
+
+// Recommend works out the lowest max frequency that still covers the given percentile of the recorded
+// demand. It never goes below floor (the lowest frequency the Node supports) or above the current max
+func Recommend(histogram *Histogram, currentMax uint, floor uint, percentile float64) Recommendation {
+	recommendation := Recommendation{
+		CurrentMax:     currentMax,
+		RecommendedMax: currentMax,
+		Samples:        histogram.Total,
+	}
+	if histogram.Total == 0 {
+		return recommendation
+	}
+
+	demand := histogram.Percentile(percentile)
+	recommendation.PercentileValue = demand
+	if demand < floor {
+		demand = floor
+	}
+	if demand < currentMax {
+		recommendation.RecommendedMax = demand
+	}
+
+	return recommendation
+}
+
+// Message describes the recommendation in a form that can be put in front of a user
+func (r Recommendation) Message(profile string, percentile float64) string {
+	if r.Samples == 0 {
+		return fmt.Sprintf("no utilization data recorded for '%s' yet", profile)
+	}
+	if r.RecommendedMax >= r.CurrentMax {
+		return fmt.Sprintf("'%s' max frequency of %dMHz is needed to cover p%g demand", profile, r.CurrentMax, percentile*100)
+	}
+
+	return fmt.Sprintf("'%s' max can drop %dMHz to %dMHz with <%g%% of samples affected",
+		profile, r.CurrentMax-r.RecommendedMax, r.RecommendedMax, (1-percentile)*100)
+}
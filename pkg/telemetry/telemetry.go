@@ -0,0 +1,249 @@
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	ProcStatFile = "/proc/stat"
+	CpuFreqPath  = "/sys/devices/system/cpu/cpu%d/cpufreq/scaling_cur_freq"
+	// SchedStatFile counts the schedule() calls of every core, only kernels built with CONFIG_SCHEDSTATS have it
+	SchedStatFile = "/proc/schedstat"
+
+	// userHZ is the rate /proc/stat counts CPU time at
+	userHZ = 100
+
+	// PollUtilization and PollContextSwitchRate are what a busy-polling core looks like: busy the whole interval
+	// without ever giving the core up to the scheduler
+	PollUtilization       = 0.98
+	PollContextSwitchRate = 50
+)
+
+// Sample is the utilization and frequency of a single core since the previous sample
+type Sample struct {
+	Core uint
+	// Fraction of the interval the core was busy, between 0 and 1
+	Utilization float64
+	// Current frequency in MHz
+	Frequency uint
+	// Fractions of the interval the core spent servicing hardware interrupts and softirqs, between 0 and 1
+	IRQ     float64
+	SoftIRQ float64
+	// ContextSwitchRate is the core's schedule() calls per second, 0 if the kernel has no schedstats
+	ContextSwitchRate float64
+}
+
+// Demand is the frequency in MHz the core would have needed to do the same work while fully busy
+func (s Sample) Demand() uint {
+	return uint(s.Utilization * float64(s.Frequency))
+}
+
+// Polling is whether the core looks to be spinning in a poll loop such as DPDK's. Without schedstats every
+// fully busy core looks like this
+func (s Sample) Polling() bool {
+	return s.Utilization >= PollUtilization && s.ContextSwitchRate < PollContextSwitchRate
+}
+
+// Source provides core samples, the Reader implements it on top of procfs and sysfs
+type Source interface {
+	Read(cores []uint) ([]Sample, error)
+}
+
+type cpuTimes struct {
+	busy      uint64
+	total     uint64
+	irq       uint64
+	softirq   uint64
+	schedules uint64
+}
+
+// Reader samples core utilization and interrupt time from /proc/stat, frequency from cpufreq and context
+// switches from schedstat
+type Reader struct {
+	ProcStatFile  string
+	CpuFreqPath   string
+	SchedStatFile string
+
+	previous map[uint]cpuTimes
+}
+
+func NewReader() *Reader {
+	return &Reader{
+		ProcStatFile:  ProcStatFile,
+		CpuFreqPath:   CpuFreqPath,
+		SchedStatFile: SchedStatFile,
+		previous:      make(map[uint]cpuTimes),
+	}
+}
+
+// Read returns a sample for every requested core, the first read of a core only primes its counters
+func (r *Reader) Read(cores []uint) ([]Sample, error) {
+	times, err := r.readProcStat()
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := r.readSchedStat()
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]Sample, 0, len(cores))
+	for _, core := range cores {
+		current, exists := times[core]
+		if !exists {
+			continue
+		}
+		current.schedules = schedules[core]
+		previous, seen := r.previous[core]
+		r.previous[core] = current
+		if !seen || current.total <= previous.total {
+			continue
+		}
+
+		frequency, err := r.readFrequency(core)
+		if err != nil {
+			return nil, err
+		}
+
+		total := float64(current.total - previous.total)
+		sample := Sample{
+			Core:        core,
+			Utilization: float64(current.busy-previous.busy) / total,
+			Frequency:   frequency,
+			IRQ:         float64(current.irq-previous.irq) / total,
+			SoftIRQ:     float64(current.softirq-previous.softirq) / total,
+		}
+		if current.schedules > previous.schedules && previous.schedules > 0 {
+			sample.ContextSwitchRate = float64(current.schedules-previous.schedules) / (total / userHZ)
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+func (r *Reader) readProcStat() (map[uint]cpuTimes, error) {
+	file, err := os.Open(r.ProcStatFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	times := make(map[uint]cpuTimes)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// the aggregate "cpu" line is skipped, only "cpuN" lines are per core
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		core, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "cpu"), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		var total uint64
+		var idle uint64
+		var irq uint64
+		var softirq uint64
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", r.ProcStatFile, err)
+			}
+			total += value
+			switch i {
+			// idle and iowait
+			case 3, 4:
+				idle += value
+			case 5:
+				irq = value
+			case 6:
+				softirq = value
+			}
+		}
+		times[uint(core)] = cpuTimes{busy: total - idle, total: total, irq: irq, softirq: softirq}
+	}
+
+	return times, scanner.Err()
+}
+
+// readSchedStat returns the schedule() calls of every core, none if the kernel has no schedstats
+func (r *Reader) readSchedStat() (map[uint]uint64, error) {
+	schedules := make(map[uint]uint64)
+	file, err := os.Open(r.SchedStatFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return schedules, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// cpuN yld_count legacy sched_count sched_goidle ttwu_count ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		core, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "cpu"), 10, 32)
+		if err != nil {
+			continue
+		}
+		count, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", r.SchedStatFile, err)
+		}
+		schedules[uint(core)] = count
+	}
+
+	return schedules, scanner.Err()
+}
+
+func (r *Reader) readFrequency(core uint) (uint, error) {
+	data, err := os.ReadFile(fmt.Sprintf(r.CpuFreqPath, core))
+	if err != nil {
+		return 0, err
+	}
+
+	frequency, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	// cpufreq reports kHz
+	return uint(frequency / 1000), nil
+}
+
+// PoolAccounting is how much of a pool's CPU time went to interrupts and how often its CPUs switched tasks,
+// averaged over the pool's CPUs. The CPUs of a well isolated exclusive pool spend next to no time in interrupts
+type PoolAccounting struct {
+	IRQ               float64
+	SoftIRQ           float64
+	ContextSwitchRate float64
+}
+
+// Accounting averages the samples of a pool's CPUs
+func Accounting(samples []Sample) PoolAccounting {
+	accounting := PoolAccounting{}
+	if len(samples) == 0 {
+		return accounting
+	}
+
+	for _, sample := range samples {
+		accounting.IRQ += sample.IRQ
+		accounting.SoftIRQ += sample.SoftIRQ
+		accounting.ContextSwitchRate += sample.ContextSwitchRate
+	}
+	count := float64(len(samples))
+	accounting.IRQ /= count
+	accounting.SoftIRQ /= count
+	accounting.ContextSwitchRate /= count
+
+	return accounting
+}
@@ -0,0 +1,148 @@
+package telemetry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BucketMHz is the resolution frequency demand is recorded at
+const BucketMHz = 100
+
+// Histogram counts frequency demand in BucketMHz wide buckets
+type Histogram struct {
+	Buckets map[uint]uint64
+	Total   uint64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{Buckets: make(map[uint]uint64)}
+}
+
+// Add records a demand in MHz, rounded up to the next bucket
+func (h *Histogram) Add(demand uint) {
+	bucket := (demand + BucketMHz - 1) / BucketMHz * BucketMHz
+	h.Buckets[bucket]++
+	h.Total++
+}
+
+func (h *Histogram) Merge(other *Histogram) {
+	for bucket, count := range other.Buckets {
+		h.Buckets[bucket] += count
+	}
+	h.Total += other.Total
+}
+
+// Percentile returns the lowest bucket that covers the given fraction of the samples
+func (h *Histogram) Percentile(p float64) uint {
+	if h.Total == 0 {
+		return 0
+	}
+
+	buckets := make([]uint, 0, len(h.Buckets))
+	for bucket := range h.Buckets {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	threshold := uint64(p * float64(h.Total))
+	var seen uint64
+	for _, bucket := range buckets {
+		seen += h.Buckets[bucket]
+		if seen >= threshold {
+			return bucket
+		}
+	}
+
+	return buckets[len(buckets)-1]
+}
+
+type slot struct {
+	start     time.Time
+	histogram *Histogram
+}
+
+// Window keeps demand histograms per key (usually a PowerProfile) for the retention period.
+// Samples are folded into one histogram per slot so memory doesn't grow with the sample rate
+type Window struct {
+	Slot      time.Duration
+	Retention time.Duration
+
+	mutex sync.Mutex
+	slots map[string][]slot
+}
+
+func NewWindow(slotLength time.Duration, retention time.Duration) *Window {
+	return &Window{
+		Slot:      slotLength,
+		Retention: retention,
+		slots:     make(map[string][]slot),
+	}
+}
+
+func (w *Window) Add(key string, now time.Time, samples []Sample) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	slots := w.expire(w.slots[key], now)
+	start := now.Truncate(w.Slot)
+	if len(slots) == 0 || !slots[len(slots)-1].start.Equal(start) {
+		slots = append(slots, slot{start: start, histogram: NewHistogram()})
+	}
+	current := slots[len(slots)-1].histogram
+	for _, sample := range samples {
+		current.Add(sample.Demand())
+	}
+	w.slots[key] = slots
+}
+
+// Histogram merges every slot still in the window for the key, it also returns how far back the data goes
+func (w *Window) Histogram(key string, now time.Time) (*Histogram, time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	slots := w.expire(w.slots[key], now)
+	w.slots[key] = slots
+
+	histogram := NewHistogram()
+	if len(slots) == 0 {
+		return histogram, 0
+	}
+	for _, s := range slots {
+		histogram.Merge(s.histogram)
+	}
+
+	return histogram, now.Sub(slots[0].start)
+}
+
+// Keys lists everything that has data in the window
+func (w *Window) Keys() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	keys := make([]string, 0, len(w.slots))
+	for key := range w.slots {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Remove drops all data for a key, e.g. when its PowerProfile is deleted
+func (w *Window) Remove(key string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	delete(w.slots, key)
+}
+
+func (w *Window) expire(slots []slot, now time.Time) []slot {
+	cutoff := now.Add(-w.Retention)
+	i := 0
+	for i < len(slots) && slots[i].start.Add(w.Slot).Before(cutoff) {
+		i++
+	}
+
+	return slots[i:]
+}
@@ -0,0 +1,249 @@
+package telemetrystream
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel/kubernetes-power-manager/pkg/agentpods"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultMinBackoff and DefaultMaxBackoff bound the wait before reconnecting to an agent, it doubles on
+	// every failed attempt and starts over once an update gets through
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+// Client keeps a stream open to one Node Agent, reconnecting with exponential backoff whenever it drops, and
+// hands every update it receives to Handler
+type Client struct {
+	Log  logr.Logger
+	Addr string
+	// Name is sent to the agent as the subscriber
+	Name       string
+	Handler    func(Update)
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Start receives updates until the context is cancelled
+func (c *Client) Start(ctx context.Context) error {
+	backoff := c.MinBackoff
+	if backoff <= 0 {
+		backoff = DefaultMinBackoff
+	}
+	maxBackoff := c.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	wait := backoff
+	for {
+		received, err := c.receive(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if received {
+			wait = backoff
+		}
+		c.Log.V(3).Info("telemetry stream closed, reconnecting", "address", c.Addr, "after", wait, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		wait *= 2
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+	}
+}
+
+// receive opens one stream and reads it until it fails, reporting whether any update came through
+func (c *Client) receive(ctx context.Context) (bool, error) {
+	conn, err := grpc.DialContext(ctx, c.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := conn.NewStream(streamCtx, &serviceDesc.Streams[0], SubscribeMethod, grpc.ForceCodec(codec{}))
+	if err != nil {
+		return false, err
+	}
+	if err = stream.SendMsg(&SubscribeRequest{Subscriber: c.Name}); err != nil {
+		return false, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return false, err
+	}
+
+	received := false
+	for {
+		update := Update{}
+		if err = stream.RecvMsg(&update); err != nil {
+			return received, err
+		}
+		received = true
+		c.Handler(update)
+	}
+}
+
+// Collector subscribes to the telemetry stream of every Node Agent Pod and keeps each Node's latest update.
+// The Pods are listed every ResyncInterval, a stream is opened to each new Pod and closed once its Pod is gone
+type Collector struct {
+	client.Reader
+	Log logr.Logger
+	// Name is sent to the agents as the subscriber
+	Name string
+	// Namespace and Selector find the Node Agent Pods
+	Namespace      string
+	Selector       labels.Selector
+	Port           int
+	ResyncInterval time.Duration
+	// Agents, if set, is used rather than listing the Pods, and the streams are resynced as soon as it changes
+	Agents *agentpods.Registry
+
+	mutex   sync.Mutex
+	updates map[string]Update
+	streams map[string]*agentStream
+}
+
+// agentStream is an open Client, node is learnt from its first update
+type agentStream struct {
+	cancel context.CancelFunc
+	node   string
+	closed bool
+}
+
+// Start follows the Node Agent Pods until the context is cancelled
+func (c *Collector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.ResyncInterval)
+	defer ticker.Stop()
+	var changes <-chan struct{}
+	if c.Agents != nil {
+		changes = c.Agents.Changes()
+	}
+
+	for {
+		if err := c.Resync(ctx); err != nil {
+			c.Log.Error(err, "error listing Node Agent Pods")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-changes:
+		}
+	}
+}
+
+// NeedLeaderElection is false as every replica can serve the readings it collects
+func (c *Collector) NeedLeaderElection() bool {
+	return false
+}
+
+// Resync opens streams to the running Node Agent Pods that don't have one and closes those of Pods that are gone
+func (c *Collector) Resync(ctx context.Context) error {
+	addrs, err := c.agentAddrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.streams == nil {
+		c.streams = make(map[string]*agentStream)
+	}
+	for addr, open := range c.streams {
+		if !addrs[addr] {
+			// the Node's last reading goes with its agent rather than being served forever
+			open.cancel()
+			open.closed = true
+			delete(c.updates, open.node)
+			delete(c.streams, addr)
+		}
+	}
+	for addr := range addrs {
+		if _, open := c.streams[addr]; open {
+			continue
+		}
+		streamCtx, cancel := context.WithCancel(ctx)
+		open := &agentStream{cancel: cancel}
+		c.streams[addr] = open
+		go (&Client{
+			Log:  c.Log,
+			Addr: addr,
+			Name: c.Name,
+			Handler: func(update Update) {
+				c.record(open, update)
+			},
+		}).Start(streamCtx)
+	}
+
+	return nil
+}
+
+// agentAddrs returns the stream addresses of the running Node Agent Pods
+func (c *Collector) agentAddrs(ctx context.Context) (map[string]bool, error) {
+	addrs := make(map[string]bool)
+	if c.Agents != nil {
+		for _, endpoint := range c.Agents.Endpoints() {
+			addrs[net.JoinHostPort(endpoint.IP, strconv.Itoa(c.Port))] = true
+		}
+		return addrs, nil
+	}
+
+	pods := &corev1.PodList{}
+	err := c.List(ctx, pods, client.InNamespace(c.Namespace), client.MatchingLabelsSelector{Selector: c.Selector})
+	if err != nil {
+		return nil, fmt.Errorf("listing Pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		addrs[net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(c.Port))] = true
+	}
+
+	return addrs, nil
+}
+
+func (c *Collector) record(open *agentStream, update Update) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if open.closed {
+		return
+	}
+	open.node = update.Node
+	if c.updates == nil {
+		c.updates = make(map[string]Update)
+	}
+	c.updates[update.Node] = update
+}
+
+// Latest returns each Node's most recent update, keyed by Node name
+func (c *Collector) Latest() map[string]Update {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	latest := make(map[string]Update, len(c.updates))
+	for node, update := range c.updates {
+		latest[node] = update
+	}
+
+	return latest
+}
@@ -0,0 +1,227 @@
+// Package telemetrystream pushes each Node Agent's power readings to the manager over a server-streaming gRPC
+// call, so the manager hears about them as they are taken rather than when the PowerNode status next syncs
+package telemetrystream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/intel/kubernetes-power-manager/pkg/util"
+)
+
+const (
+	// ServiceName is the gRPC service the Node Agent serves
+	ServiceName = "power.intel.com.Telemetry"
+	// SubscribeMethod is the full name of the streaming call
+	SubscribeMethod = "/" + ServiceName + "/Subscribe"
+
+	// DefaultBuffer is how many updates are queued for a subscriber before the oldest are dropped
+	DefaultBuffer = 16
+
+	codecName = "json"
+)
+
+// Update is one set of power readings from a Node, a reading the Node couldn't take is -1
+type Update struct {
+	Node         string    `json:"node"`
+	Time         time.Time `json:"time"`
+	PackageWatts float64   `json:"packageWatts"`
+	ChassisWatts float64   `json:"chassisWatts"`
+	// Dropped is how many updates this subscriber has missed since it was last told, because it didn't keep up
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// SubscribeRequest opens the stream, Subscriber names the caller in the agent's logs
+type SubscribeRequest struct {
+	Subscriber string `json:"subscriber"`
+}
+
+// codec marshals the messages as JSON, so the service needs no generated protobuf code. It is forced on both
+// ends of the call rather than registered, which would change the default codec for every gRPC user in the binary
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return codecName
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       subscribeHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+func subscribeHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(*Server).subscribe(stream)
+}
+
+// subscriber is an open stream's queue. Updates are never blocked on a slow subscriber, once its queue is full
+// the oldest update is dropped to make room and counted in the next one it receives
+type subscriber struct {
+	updates chan Update
+	mutex   sync.Mutex
+	dropped uint64
+}
+
+func (s *subscriber) push(update Update) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for {
+		select {
+		case s.updates <- update:
+			return
+		default:
+		}
+		select {
+		case <-s.updates:
+			s.dropped++
+		default:
+		}
+	}
+}
+
+func (s *subscriber) takeDropped() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+// Server streams the updates published on the Node to every subscriber. A new subscriber gets the latest
+// update straight away so it doesn't wait a whole interval for its first reading
+type Server struct {
+	Log  logr.Logger
+	Addr string
+	// Buffer is how many updates are queued per subscriber, DefaultBuffer if 0
+	Buffer int
+
+	mutex       sync.Mutex
+	subscribers map[*subscriber]struct{}
+	latest      *Update
+}
+
+// Start serves the Telemetry service until the context is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := util.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(codec{}),
+		// dead subscribers are noticed and their streams closed even when no updates are flowing
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: time.Minute, Timeout: 20 * time.Second}),
+	)
+	server.RegisterService(&serviceDesc, s)
+
+	go func() {
+		<-ctx.Done()
+		// open streams only end when their subscriber goes, so don't wait on them for long
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			server.Stop()
+		}
+	}()
+
+	s.Log.Info("serving telemetry stream", "address", s.Addr)
+	err = server.Serve(listener)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+
+	return err
+}
+
+// NeedLeaderElection is false as every Node Agent streams its own Node's readings
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Publish queues the update for every subscriber without waiting on any of them
+func (s *Server) Publish(update Update) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.latest = &update
+	for sub := range s.subscribers {
+		sub.push(update)
+	}
+}
+
+func (s *Server) addSubscriber() *subscriber {
+	buffer := s.Buffer
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	sub := &subscriber{updates: make(chan Update, buffer)}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.subscribers == nil {
+		s.subscribers = make(map[*subscriber]struct{})
+	}
+	s.subscribers[sub] = struct{}{}
+	if s.latest != nil {
+		sub.push(*s.latest)
+	}
+
+	return sub
+}
+
+func (s *Server) removeSubscriber(sub *subscriber) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.subscribers, sub)
+}
+
+func (s *Server) subscribe(stream grpc.ServerStream) error {
+	request := &SubscribeRequest{}
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+	logger := s.Log.WithValues("subscriber", request.Subscriber)
+	sub := s.addSubscriber()
+	defer s.removeSubscriber(sub)
+	logger.V(3).Info("telemetry subscriber connected")
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			logger.V(3).Info("telemetry subscriber disconnected")
+			return nil
+		case update := <-sub.updates:
+			update.Dropped = sub.takeDropped()
+			if update.Dropped > 0 {
+				logger.V(3).Info("telemetry subscriber is falling behind", "dropped", update.Dropped)
+			}
+			if err := stream.SendMsg(&update); err != nil {
+				return err
+			}
+		}
+	}
+}
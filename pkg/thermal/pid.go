@@ -0,0 +1,54 @@
+package thermal
+
+// Gains of the PID controller, in MHz of frequency cap per degree Celsius over the target
+type Gains struct {
+	Proportional float64
+	// per degree second
+	Integral float64
+	// per degree per second
+	Derivative float64
+}
+
+// DefaultGains bring a core a few degrees over its target down within tens of seconds without oscillating, the
+// temperature of a core follows its frequency within a second or two
+var DefaultGains = Gains{Proportional: 50, Integral: 10, Derivative: 0}
+
+// PID works out how far below its PowerProfile's max frequency a core has to be capped to stay under the target
+type PID struct {
+	Gains Gains
+
+	integral      float64
+	previousError float64
+	started       bool
+}
+
+// Update takes the temperature over the target, negative when under it, and the seconds since the previous
+// update. It returns the frequency reduction in MHz, between 0 and limit. The integral stops growing while the
+// reduction is held at either end, so the cap moves as soon as the temperature turns
+func (p *PID) Update(overTarget float64, seconds float64, limit float64) float64 {
+	derivative := 0.0
+	if p.started && seconds > 0 {
+		derivative = (overTarget - p.previousError) / seconds
+	}
+	p.previousError = overTarget
+	p.started = true
+
+	integral := p.integral + overTarget*seconds
+	reduction := p.Gains.Proportional*overTarget + p.Gains.Integral*integral + p.Gains.Derivative*derivative
+	switch {
+	case reduction > limit:
+		reduction = limit
+		if overTarget < 0 {
+			p.integral = integral
+		}
+	case reduction < 0:
+		reduction = 0
+		if overTarget > 0 {
+			p.integral = integral
+		}
+	default:
+		p.integral = integral
+	}
+
+	return reduction
+}
@@ -0,0 +1,179 @@
+package thermal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/intel/kubernetes-power-manager/pkg/hostwrite"
+)
+
+const (
+	// HwmonPath holds a coretemp hwmon device per package, with a temperature per physical core
+	HwmonPath = "/sys/devices/platform"
+	// CpuPath holds each CPU's topology and cpufreq settings
+	CpuPath = "/sys/devices/system/cpu"
+)
+
+// Sensor reports the temperature in degrees Celsius of the physical core each CPU belongs to
+type Sensor interface {
+	Temperatures(cpus []uint) (map[uint]float64, error)
+}
+
+// FrequencyCapper caps a CPU's max frequency in MHz below the one its PowerProfile sets
+type FrequencyCapper interface {
+	SetMaxFrequency(cpu uint, mhz uint) error
+}
+
+// CoreLocator reports the package and core ID of the physical core each CPU belongs to
+type CoreLocator interface {
+	PhysicalCores(cpus []uint) (map[uint][2]uint, error)
+}
+
+// Reader reads core temperatures from the coretemp hwmon devices and sets frequency limits through cpufreq
+type Reader struct {
+	HwmonPath string
+	CpuPath   string
+}
+
+func NewReader() *Reader {
+	return &Reader{
+		HwmonPath: HwmonPath,
+		CpuPath:   CpuPath,
+	}
+}
+
+// Temperatures returns the temperature of each CPU's physical core, or of its package when coretemp has no
+// reading for the core. CPUs without any reading are left out
+func (r *Reader) Temperatures(cpus []uint) (map[uint]float64, error) {
+	cores, packages, err := r.readCoretemp()
+	if err != nil {
+		return nil, err
+	}
+
+	physicalCores, err := r.PhysicalCores(cpus)
+	if err != nil {
+		return nil, err
+	}
+	temperatures := make(map[uint]float64, len(cpus))
+	for cpu, physicalCore := range physicalCores {
+		if temperature, found := cores[physicalCore]; found {
+			temperatures[cpu] = temperature
+		} else if temperature, found := packages[physicalCore[0]]; found {
+			temperatures[cpu] = temperature
+		}
+	}
+
+	return temperatures, nil
+}
+
+// PhysicalCores returns the package and core ID of each CPU from its sysfs topology
+func (r *Reader) PhysicalCores(cpus []uint) (map[uint][2]uint, error) {
+	cores := make(map[uint][2]uint, len(cpus))
+	for _, cpu := range cpus {
+		pkg, err := r.readTopology(cpu, "physical_package_id")
+		if err != nil {
+			return nil, err
+		}
+		coreID, err := r.readTopology(cpu, "core_id")
+		if err != nil {
+			return nil, err
+		}
+		cores[cpu] = [2]uint{pkg, coreID}
+	}
+
+	return cores, nil
+}
+
+// SetMaxFrequency writes the CPU's scaling_max_freq
+func (r *Reader) SetMaxFrequency(cpu uint, mhz uint) error {
+	path := filepath.Join(r.CpuPath, fmt.Sprintf("cpu%d", cpu), "cpufreq", "scaling_max_freq")
+	// cpufreq takes kHz
+	return hostwrite.Write(path, []byte(strconv.FormatUint(uint64(mhz)*1000, 10)))
+}
+
+// SetMinFrequency writes the CPU's scaling_min_freq
+func (r *Reader) SetMinFrequency(cpu uint, mhz uint) error {
+	path := filepath.Join(r.CpuPath, fmt.Sprintf("cpu%d", cpu), "cpufreq", "scaling_min_freq")
+	return hostwrite.Write(path, []byte(strconv.FormatUint(uint64(mhz)*1000, 10)))
+}
+
+// FrequencyLimits reads the CPU's scaling_min_freq and scaling_max_freq in MHz
+func (r *Reader) FrequencyLimits(cpu uint) (uint, uint, error) {
+	limits := make([]uint, 0, 2)
+	for _, file := range []string{"scaling_min_freq", "scaling_max_freq"} {
+		data, err := os.ReadFile(filepath.Join(r.CpuPath, fmt.Sprintf("cpu%d", cpu), "cpufreq", file))
+		if err != nil {
+			return 0, 0, err
+		}
+		khz, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+		limits = append(limits, uint(khz/1000))
+	}
+
+	return limits[0], limits[1], nil
+}
+
+// readCoretemp returns the temperatures keyed by package and core ID, and those of the packages themselves
+func (r *Reader) readCoretemp() (map[[2]uint]float64, map[uint]float64, error) {
+	labels, err := filepath.Glob(filepath.Join(r.HwmonPath, "coretemp.*", "hwmon", "hwmon*", "temp*_label"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cores := make(map[[2]uint]float64)
+	packages := make(map[uint]float64)
+	for _, labelPath := range labels {
+		// the coretemp platform devices are numbered after the package they sit in
+		device := filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(labelPath))))
+		pkg, err := strconv.ParseUint(strings.TrimPrefix(device, "coretemp."), 10, 32)
+		if err != nil {
+			continue
+		}
+		label, err := os.ReadFile(labelPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		input, err := os.ReadFile(strings.TrimSuffix(labelPath, "_label") + "_input")
+		if err != nil {
+			// a sensor can be listed but not readable, e.g. on an offline core
+			continue
+		}
+		millidegrees, err := strconv.ParseInt(strings.TrimSpace(string(input)), 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing %s: %w", labelPath, err)
+		}
+		temperature := float64(millidegrees) / 1000
+
+		fields := strings.Fields(string(label))
+		switch {
+		case len(fields) == 2 && fields[0] == "Core":
+			coreID, err := strconv.ParseUint(fields[1], 10, 32)
+			if err != nil {
+				continue
+			}
+			cores[[2]uint{uint(pkg), uint(coreID)}] = temperature
+		case len(fields) == 3 && fields[0] == "Package":
+			packages[uint(pkg)] = temperature
+		}
+	}
+
+	return cores, packages, nil
+}
+
+func (r *Reader) readTopology(cpu uint, name string) (uint, error) {
+	data, err := os.ReadFile(filepath.Join(r.CpuPath, fmt.Sprintf("cpu%d", cpu), "topology", name))
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(value), nil
+}
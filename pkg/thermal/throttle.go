@@ -0,0 +1,72 @@
+package thermal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ThrottleCounts are the Node's throttle event counters summed over its CPUs. They only ever rise, so it is how
+// much they rose between two reads that tells whether the Node throttled in between
+type ThrottleCounts struct {
+	// Times cores or packages were throttled for running too hot
+	Thermal uint64
+	// Times cores or packages were throttled for going over their power limit
+	PowerLimit uint64
+}
+
+// ThrottleCounter reads the Node's throttle event counters
+type ThrottleCounter interface {
+	ThrottleCounts() (ThrottleCounts, error)
+}
+
+// ThrottleCounts sums the thermal_throttle counters the kernel keeps for each CPU. Package counters are repeated
+// for every CPU of the package, which only scales how much they rise
+func (r *Reader) ThrottleCounts() (ThrottleCounts, error) {
+	dirs, err := filepath.Glob(filepath.Join(r.CpuPath, "cpu[0-9]*", "thermal_throttle"))
+	if err != nil {
+		return ThrottleCounts{}, err
+	}
+	if len(dirs) == 0 {
+		return ThrottleCounts{}, fmt.Errorf("no thermal_throttle counters found under %s", r.CpuPath)
+	}
+
+	counts := ThrottleCounts{}
+	for _, dir := range dirs {
+		for _, name := range []string{"core_throttle_count", "package_throttle_count"} {
+			count, err := readCount(filepath.Join(dir, name))
+			if err != nil {
+				return ThrottleCounts{}, err
+			}
+			counts.Thermal += count
+		}
+		for _, name := range []string{"core_power_limit_count", "package_power_limit_count"} {
+			count, err := readCount(filepath.Join(dir, name))
+			if err != nil {
+				return ThrottleCounts{}, err
+			}
+			counts.PowerLimit += count
+		}
+	}
+
+	return counts, nil
+}
+
+// readCount reads a counter, 0 if the CPU doesn't have it as the power limit counters aren't on every model
+func readCount(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	count, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,91 @@
+package turbo
+
+import "fmt"
+
+const (
+	// MsrFile is the MSR device used to read the turbo ratio table, any CPU on the package will do
+	MsrFile = "/dev/cpu/0/msr"
+
+	// MSR_TURBO_RATIO_LIMIT holds one ratio per byte, ordered from the fewest to the most active cores
+	turboRatioLimitMsr = 0x1AD
+	// MSR_TURBO_RATIO_LIMIT_CORES holds the active core count each byte of MSR_TURBO_RATIO_LIMIT applies to
+	turboRatioLimitCoresMsr = 0x1AE
+
+	busClockMHz = 100
+
+	SingleCoreTurbo = "singleCoreTurbo"
+	AllCoreTurbo    = "allCoreTurbo"
+)
+
+// Bucket is a single entry of the turbo ratio table
+type Bucket struct {
+	// Number of active cores this frequency is available up to
+	ActiveCores uint
+	// Frequency in MHz
+	Frequency uint
+}
+
+// Presets are the symbolic frequencies a PowerProfile can reference, resolved for this SKU
+type Presets struct {
+	Buckets []Bucket
+}
+
+// ParsePresets builds the presets from the raw MSR_TURBO_RATIO_LIMIT and MSR_TURBO_RATIO_LIMIT_CORES values
+func ParsePresets(ratios uint64, coreCounts uint64) *Presets {
+	presets := &Presets{}
+	for i := 0; i < 8; i++ {
+		ratio := uint((ratios >> (8 * i)) & 0xFF)
+		if ratio == 0 {
+			break
+		}
+
+		activeCores := uint(i + 1)
+		if coreCounts != 0 {
+			activeCores = uint((coreCounts >> (8 * i)) & 0xFF)
+		}
+
+		presets.Buckets = append(presets.Buckets, Bucket{
+			ActiveCores: activeCores,
+			Frequency:   ratio * busClockMHz,
+		})
+	}
+
+	return presets
+}
+
+// SingleCoreTurbo is the highest frequency the SKU reaches with a single active core
+func (p *Presets) SingleCoreTurbo() uint {
+	if len(p.Buckets) == 0 {
+		return 0
+	}
+
+	return p.Buckets[0].Frequency
+}
+
+// AllCoreTurbo is the frequency the SKU sustains with all of the cores in the table active
+func (p *Presets) AllCoreTurbo() uint {
+	if len(p.Buckets) == 0 {
+		return 0
+	}
+
+	return p.Buckets[len(p.Buckets)-1].Frequency
+}
+
+// Resolve returns the frequency in MHz for a preset name
+func (p *Presets) Resolve(preset string) (uint, error) {
+	var frequency uint
+	switch preset {
+	case SingleCoreTurbo:
+		frequency = p.SingleCoreTurbo()
+	case AllCoreTurbo:
+		frequency = p.AllCoreTurbo()
+	default:
+		return 0, fmt.Errorf("unknown frequency preset '%s'", preset)
+	}
+
+	if frequency == 0 {
+		return 0, fmt.Errorf("turbo ratio table is empty, cannot resolve '%s'", preset)
+	}
+
+	return frequency, nil
+}
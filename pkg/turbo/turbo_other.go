@@ -0,0 +1,15 @@
+//go:build !amd64 && !386
+// +build !amd64,!386
+
+package turbo
+
+import (
+	"fmt"
+
+	powererrors "github.com/intel/kubernetes-power-manager/pkg/errors"
+)
+
+// ReadPresets has no turbo ratio table to read, MSR_TURBO_RATIO_LIMIT is only found on x86
+func ReadPresets(msrFile string) (*Presets, error) {
+	return nil, powererrors.NewHardwareUnsupported("turbo ratio table", fmt.Errorf("MSRs are only available on x86"))
+}
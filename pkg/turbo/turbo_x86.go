@@ -0,0 +1,42 @@
+//go:build amd64 || 386
+// +build amd64 386
+
+package turbo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// ReadPresets reads the turbo ratio table of the SKU from the given MSR device
+func ReadPresets(msrFile string) (*Presets, error) {
+	file, err := os.Open(msrFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ratios, err := readMsr(file, turboRatioLimitMsr)
+	if err != nil {
+		return nil, fmt.Errorf("reading turbo ratio limit: %w", err)
+	}
+
+	// Older parts don't implement the core count MSR, there byte N of the ratio table applies to N+1 active cores
+	coreCounts, err := readMsr(file, turboRatioLimitCoresMsr)
+	if err != nil {
+		coreCounts = 0
+	}
+
+	return ParsePresets(ratios, coreCounts), nil
+}
+
+func readMsr(file *os.File, msr int64) (uint64, error) {
+	buf := make([]byte, 8)
+	_, err := file.ReadAt(buf, msr)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(buf), nil
+}
@@ -0,0 +1,106 @@
+//go:build freebsd || linux || darwin
+// +build freebsd linux darwin
+
+package util
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// listenFdsStart is the first file descriptor systemd passes sockets on
+const listenFdsStart = 3
+
+var (
+	activationOnce sync.Once
+	activationLock sync.Mutex
+	activated      []net.Listener
+)
+
+// activatedListeners takes the sockets systemd passed to this process through socket activation. They're read
+// once, and the LISTEN_ variables unset so processes started from this one don't take them as well
+func activatedListeners() []net.Listener {
+	activationOnce.Do(func() {
+		defer func() {
+			os.Unsetenv("LISTEN_PID")
+			os.Unsetenv("LISTEN_FDS")
+			os.Unsetenv("LISTEN_FDNAMES")
+		}()
+		pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		if err != nil || pid != os.Getpid() {
+			return
+		}
+		fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil || fds <= 0 {
+			return
+		}
+		for fd := listenFdsStart; fd < listenFdsStart+fds; fd++ {
+			syscall.CloseOnExec(fd)
+			file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+			listener, err := net.FileListener(file)
+			// FileListener dups the descriptor, the original isn't needed any more
+			file.Close()
+			if err != nil {
+				// datagram sockets and the like can't be served on
+				continue
+			}
+			activated = append(activated, listener)
+		}
+	})
+
+	return activated
+}
+
+// takeActivatedListener returns the socket systemd passed for the address, nil if it didn't pass one. Each
+// socket is handed out once
+func takeActivatedListener(protocol string, addr string) net.Listener {
+	activationLock.Lock()
+	defer activationLock.Unlock()
+
+	listeners := activatedListeners()
+	for i, listener := range listeners {
+		if listener == nil || !addressMatches(listener.Addr(), protocol, addr) {
+			continue
+		}
+		listeners[i] = nil
+		return listener
+	}
+
+	return nil
+}
+
+// addressMatches reports whether a socket listens on the address it was asked for. A TCP address without a host
+// matches a socket on any host with the same port
+func addressMatches(listenerAddr net.Addr, protocol string, addr string) bool {
+	switch protocol {
+	case unixProtocol:
+		return listenerAddr.Network() == unixProtocol && listenerAddr.String() == addr
+	case "tcp":
+		if listenerAddr.Network() != "tcp" {
+			return false
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return false
+		}
+		listenerHost, listenerPort, err := net.SplitHostPort(listenerAddr.String())
+		if err != nil || listenerPort != port {
+			return false
+		}
+		return host == "" || host == listenerHost
+	}
+
+	return false
+}
+
+// Listen listens on the address, taking the socket systemd passed for it when the process was socket activated
+func Listen(protocol string, addr string) (net.Listener, error) {
+	if listener := takeActivatedListener(protocol, addr); listener != nil {
+		return listener, nil
+	}
+
+	return net.Listen(protocol, addr)
+}
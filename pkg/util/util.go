@@ -23,6 +23,7 @@ import (
 	"k8s.io/klog/v2"
 	"net"
 	"net/url"
+	"os"
 )
 
 const (
@@ -43,6 +44,41 @@ func GetAddressAndDialer(endpoint string) (string, func(ctx context.Context, add
 	return addr, dial, nil
 }
 
+// CreateListener creates a listener on the given unix socket endpoint, replacing any stale socket
+// left behind by a previous run. The socket is only accessible to the owner. When the process was
+// socket activated by systemd, the socket it passed for the endpoint is used as it is
+func CreateListener(endpoint string) (net.Listener, error) {
+	protocol, addr, err := parseEndpointWithFallbackProtocol(endpoint, unixProtocol)
+	if err != nil {
+		return nil, err
+	}
+	if protocol != unixProtocol {
+		return nil, fmt.Errorf("only support unix socket endpoint")
+	}
+	// systemd owns the socket's file and its permissions
+	if listener := takeActivatedListener(protocol, addr); listener != nil {
+		return listener, nil
+	}
+
+	err = os.Remove(addr)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket %s: %w", addr, err)
+	}
+
+	listener, err := net.Listen(protocol, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(addr, 0600)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
 func dial(ctx context.Context, addr string) (net.Conn, error) {
 	return (&net.Dialer{}).DialContext(ctx, unixProtocol, addr)
 }
@@ -108,3 +144,14 @@ func StringInStringList(item string, itemList []string) bool {
 
 	return false
 }
+
+func RemoveStringFromStringList(item string, itemList []string) []string {
+	updatedList := make([]string, 0, len(itemList))
+	for _, i := range itemList {
+		if i != item {
+			updatedList = append(updatedList, i)
+		}
+	}
+
+	return updatedList
+}